@@ -34,6 +34,7 @@ import (
 	"go.skia.org/infra/go/gerrit"
 	"go.skia.org/infra/go/gitauth"
 	"go.skia.org/infra/go/github"
+	"go.skia.org/infra/go/gitlab"
 	"go.skia.org/infra/go/httputils"
 	"go.skia.org/infra/go/metadata"
 	"go.skia.org/infra/go/skiaversion"
@@ -199,6 +200,7 @@ func main() {
 	// so that we can get rid of these vars and the various conditionals.
 	var g *gerrit.Gerrit
 	var githubClient *github.GitHub
+	var gitlabClient *gitlab.GitLab
 
 	// The rollers use the gitcookie created by gitauth package.
 	if !*local {
@@ -253,6 +255,22 @@ func main() {
 		if err != nil {
 			sklog.Fatalf("Could not create Github client: %s", err)
 		}
+	} else if cfg.Gitlab != nil {
+		pathToGitlabToken := path.Join(user.HomeDir, gitlab.GITLAB_TOKEN_FILENAME)
+		if !*local {
+			pathToGitlabToken = path.Join(gitlab.GITLAB_TOKEN_SERVER_PATH, gitlab.GITLAB_TOKEN_FILENAME)
+		}
+		// Instantiate gitlabClient using the gitlab token secret.
+		gBody, err := ioutil.ReadFile(pathToGitlabToken)
+		if err != nil {
+			sklog.Fatalf("Couldn't find gitlabToken in %s: %s.", pathToGitlabToken, err)
+		}
+		gToken := strings.TrimSpace(string(gBody))
+		gitlabHttpClient := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: gToken}))
+		gitlabClient, err = gitlab.NewGitLab(cfg.Gitlab.URL, cfg.Gitlab.Project, gitlabHttpClient)
+		if err != nil {
+			sklog.Fatalf("Could not create GitLab client: %s", err)
+		}
 	}
 
 	sklog.Info("Creating manual roll DB.")
@@ -270,7 +288,7 @@ func main() {
 		sklog.Fatal(err)
 	}
 
-	arb, err := roller.NewAutoRoller(ctx, cfg, emailer, chatBotConfigReader, g, githubClient, *workdir, *recipesCfgFile, serverURL, gcsClient, client, rollerName, *local, manualRolls)
+	arb, err := roller.NewAutoRoller(ctx, cfg, emailer, chatBotConfigReader, g, githubClient, gitlabClient, *workdir, *recipesCfgFile, serverURL, gcsClient, client, rollerName, *local, manualRolls)
 	if err != nil {
 		sklog.Fatal(err)
 	}
@@ -10,6 +10,7 @@ import (
 	"go.skia.org/infra/go/autoroll"
 	"go.skia.org/infra/go/gerrit"
 	"go.skia.org/infra/go/github"
+	"go.skia.org/infra/go/gitlab"
 )
 
 type CodeReview interface {
@@ -158,3 +159,68 @@ func (c *githubCodeReview) UserEmail() string {
 func (c *githubCodeReview) UserName() string {
 	return c.userName
 }
+
+// gitlabCodeReview is a CodeReview backed by GitLab.
+type gitlabCodeReview struct {
+	cfg            *GitlabConfig
+	fullHistoryUrl string
+	gitlabClient   *gitlab.GitLab
+	issueUrlBase   string
+	userEmail      string
+	userName       string
+}
+
+// Return a gitlabCodeReview instance.
+func newGitlabCodeReview(cfg *GitlabConfig, gitlabClient *gitlab.GitLab) (CodeReview, error) {
+	if gitlabClient == nil {
+		return nil, errors.New("GitLab client is required.")
+	}
+	user, err := gitlabClient.GetAuthenticatedUser(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	if user.Email == "" {
+		return nil, errors.New("Found no email address for gitlab user.")
+	}
+	if user.Username == "" {
+		return nil, errors.New("Found no username for gitlab user.")
+	}
+	return &gitlabCodeReview{
+		cfg:            cfg,
+		fullHistoryUrl: gitlabClient.GetFullHistoryUrl(user.Username),
+		gitlabClient:   gitlabClient,
+		issueUrlBase:   gitlabClient.GetIssueUrlBase(),
+		userEmail:      user.Email,
+		userName:       user.Username,
+	}, nil
+}
+
+// See documentation for CodeReview interface.
+func (c *gitlabCodeReview) Config() CodeReviewConfig {
+	return c.cfg
+}
+
+// See documentation for CodeReview interface.
+func (c *gitlabCodeReview) GetIssueUrlBase() string {
+	return c.issueUrlBase
+}
+
+// See documentation for CodeReview interface.
+func (c *gitlabCodeReview) GetFullHistoryUrl() string {
+	return c.fullHistoryUrl
+}
+
+// See documentation for CodeReview interface.
+func (c *gitlabCodeReview) RetrieveRoll(ctx context.Context, issue *autoroll.AutoRollIssue, recent *recent_rolls.RecentRolls, rollingTo *revision.Revision, finishedCallback func(context.Context, RollImpl) error) (RollImpl, error) {
+	return newGitlabRoll(ctx, issue, c.gitlabClient, recent, c.issueUrlBase, rollingTo, finishedCallback)
+}
+
+// See documentation for CodeReview interface.
+func (c *gitlabCodeReview) UserEmail() string {
+	return c.userEmail
+}
+
+// See documentation for CodeReview interface.
+func (c *gitlabCodeReview) UserName() string {
+	return c.userName
+}
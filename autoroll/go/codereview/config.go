@@ -8,6 +8,7 @@ import (
 
 	"go.skia.org/infra/go/gerrit"
 	"go.skia.org/infra/go/github"
+	"go.skia.org/infra/go/gitlab"
 	"go.skia.org/infra/go/skerr"
 	"go.skia.org/infra/go/util"
 )
@@ -50,7 +51,7 @@ type CodeReviewConfig interface {
 	util.Validator
 
 	// Init creates a CodeReview object based on this CodeReviewConfig.
-	Init(gerrit.GerritInterface, *github.GitHub) (CodeReview, error)
+	Init(gerrit.GerritInterface, *github.GitHub, *gitlab.GitLab) (CodeReview, error)
 }
 
 // GerritConfig provides configuration for Gerrit.
@@ -87,7 +88,7 @@ func (c *GerritConfig) Validate() error {
 }
 
 // See documentation for CodeReviewConfig interface.
-func (c *GerritConfig) Init(gerritClient gerrit.GerritInterface, githubClient *github.GitHub) (CodeReview, error) {
+func (c *GerritConfig) Init(gerritClient gerrit.GerritInterface, githubClient *github.GitHub, gitlabClient *gitlab.GitLab) (CodeReview, error) {
 	return newGerritCodeReview(c, gerritClient)
 }
 
@@ -124,10 +125,38 @@ func (c *GithubConfig) Validate() error {
 }
 
 // See documentation for CodeReviewConfig interface.
-func (c *GithubConfig) Init(gerritClient gerrit.GerritInterface, githubClient *github.GitHub) (CodeReview, error) {
+func (c *GithubConfig) Init(gerritClient gerrit.GerritInterface, githubClient *github.GitHub, gitlabClient *gitlab.GitLab) (CodeReview, error) {
 	return newGithubCodeReview(c, githubClient)
 }
 
+// GitlabConfig provides configuration for GitLab. Rolls are merged using
+// GitLab's "merge when pipeline succeeds" feature; dry runs simply wait for
+// the merge request's pipeline to finish.
+type GitlabConfig struct {
+	// GitLab instance URL, eg. "https://gitlab.com".
+	URL string `json:"url"`
+
+	// Full path of the project for uploaded merge requests, eg.
+	// "group/project".
+	Project string `json:"project"`
+}
+
+// See documentation for util.Validator interface.
+func (c *GitlabConfig) Validate() error {
+	if c.URL == "" {
+		return errors.New("URL is required.")
+	}
+	if c.Project == "" {
+		return errors.New("Project is required.")
+	}
+	return nil
+}
+
+// See documentation for CodeReviewConfig interface.
+func (c *GitlabConfig) Init(gerritClient gerrit.GerritInterface, githubClient *github.GitHub, gitlabClient *gitlab.GitLab) (CodeReview, error) {
+	return newGitlabCodeReview(c, gitlabClient)
+}
+
 // Google3 config is an empty configuration object for Google3.
 type Google3Config struct{}
 
@@ -137,6 +166,6 @@ func (c *Google3Config) Validate() error {
 }
 
 // See documentation for CodeReviewConfig interface.
-func (c *Google3Config) Init(gerrit.GerritInterface, *github.GitHub) (CodeReview, error) {
+func (c *Google3Config) Init(gerrit.GerritInterface, *github.GitHub, *gitlab.GitLab) (CodeReview, error) {
 	return nil, errors.New("Init not implemented for Google3Config.")
 }
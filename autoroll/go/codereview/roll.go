@@ -12,6 +12,7 @@ import (
 	"go.skia.org/infra/go/autoroll"
 	"go.skia.org/infra/go/gerrit"
 	"go.skia.org/infra/go/github"
+	"go.skia.org/infra/go/gitlab"
 	"go.skia.org/infra/go/sklog"
 	"go.skia.org/infra/go/travisci"
 )
@@ -522,3 +523,286 @@ func (r *githubRoll) IssueID() string {
 func (r *githubRoll) IssueURL() string {
 	return r.issueUrl
 }
+
+// gitlabRoll is an implementation of RollImpl.
+type gitlabRoll struct {
+	finishedCallback func(context.Context, RollImpl) error
+	g                *gitlab.GitLab
+	issue            *autoroll.AutoRollIssue
+	issueUrl         string
+	mergeRequest     *gitlab.MergeRequest
+	recent           *recent_rolls.RecentRolls
+	result           string
+	retrieveRoll     func(context.Context) (*gitlab.MergeRequest, error)
+	rollingTo        *revision.Revision
+}
+
+// updateIssueFromGitLab loads details about the merge request from the GitLab
+// API and updates the AutoRollIssue accordingly. GitLab has no commit queue,
+// so unless the roll is a dry run, this also instructs GitLab to merge the
+// merge request when its pipeline succeeds, once there is a pipeline to wait
+// for.
+func updateIssueFromGitLab(ctx context.Context, a *autoroll.AutoRollIssue, g *gitlab.GitLab) (*gitlab.MergeRequest, error) {
+	mr, err := g.GetMergeRequest(ctx, a.Issue)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get merge request %d: %s", a.Issue, err)
+	}
+	p := mr.HeadPipeline
+	if !a.IsDryRun && mr.State == gitlab.MR_STATE_OPENED && !mr.MergeWhenPipelineSucceeds && mr.MergeStatus != gitlab.MERGE_STATUS_CANNOT_BE_MERGED && p != nil && (!gitlab.PipelineFinished(p.Status) || p.Status == gitlab.PIPELINE_STATUS_SUCCESS) {
+		sklog.Infof("Setting merge request %d to merge when pipeline %d succeeds.", a.Issue, p.ID)
+		if err := g.MergeWhenPipelineSucceeds(ctx, a.Issue, mr.SHA); err != nil {
+			return nil, fmt.Errorf("Failed to set merge request %d to merge when pipeline succeeds: %s", a.Issue, err)
+		}
+		mr, err = g.GetMergeRequest(ctx, a.Issue)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get merge request %d: %s", a.Issue, err)
+		}
+	}
+
+	// Get all jobs in the most recent pipeline and convert to try results.
+	a.TryResults = []*autoroll.TryResult{}
+	if mr.HeadPipeline != nil {
+		jobs, err := g.GetPipelineJobs(ctx, mr.HeadPipeline.ID)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get jobs for pipeline %d: %s", mr.HeadPipeline.ID, err)
+		}
+		a.TryResults = autoroll.TryResultsFromGitlabJobs(jobs)
+	}
+
+	versions, err := g.GetMergeRequestVersions(ctx, a.Issue)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get versions of merge request %d: %s", a.Issue, err)
+	}
+	if err := updateIssueFromGitLabMergeRequest(a, mr, len(versions)); err != nil {
+		return nil, fmt.Errorf("Failed to convert issue format: %s", err)
+	}
+	return mr, nil
+}
+
+// updateIssueFromGitLabMergeRequest updates the AutoRollIssue instance based
+// on the given MergeRequest, which has the given number of versions.
+func updateIssueFromGitLabMergeRequest(i *autoroll.AutoRollIssue, mr *gitlab.MergeRequest, numVersions int) error {
+	if i.Issue != mr.IID {
+		return fmt.Errorf("Merge request %d differs from existing issue number %d!", mr.IID, i.Issue)
+	}
+	merged := mr.State == gitlab.MR_STATE_MERGED
+	closed := merged || mr.State == gitlab.MR_STATE_CLOSED
+	conflict := mr.MergeStatus == gitlab.MERGE_STATUS_CANNOT_BE_MERGED
+	pipelineStatus := ""
+	if mr.HeadPipeline != nil {
+		pipelineStatus = mr.HeadPipeline.Status
+	}
+	pipelineFinished := gitlab.PipelineFinished(pipelineStatus)
+
+	if i.IsDryRun {
+		i.CqFinished = false
+		i.CqSuccess = false
+		i.DryRunFinished = closed || conflict || pipelineFinished
+		i.DryRunSuccess = merged || (i.DryRunFinished && pipelineStatus == gitlab.PIPELINE_STATUS_SUCCESS)
+	} else {
+		// A successful pipeline causes GitLab to merge the merge
+		// request, so a finished pipeline on an unmerged merge request
+		// indicates failure.
+		i.CqFinished = closed || conflict || pipelineFinished
+		i.CqSuccess = merged
+		i.DryRunFinished = false
+		i.DryRunSuccess = false
+	}
+
+	ps := make([]int64, 0, numVersions)
+	for i := 1; i <= numVersions; i++ {
+		ps = append(ps, int64(i))
+	}
+	i.Closed = closed
+	i.Committed = merged
+	i.Created = mr.CreatedAt
+	i.Modified = mr.UpdatedAt
+	i.Patchsets = ps
+	i.Subject = mr.Title
+	i.Result = autoroll.RollResult(i)
+	// TODO(borenet): If this validation fails, it's likely that it will
+	// continue to fail indefinitely, resulting in a stuck roller.
+	// Additionally, this AutoRollIssue instance persists in the AutoRoller
+	// for its entire lifetime; it's possible to partially fail to update
+	// it and end up in an inconsistent state.
+	return i.Validate()
+}
+
+// newGitlabRoll obtains a gitlabRoll instance from the given merge request
+// number.
+func newGitlabRoll(ctx context.Context, issue *autoroll.AutoRollIssue, g *gitlab.GitLab, recent *recent_rolls.RecentRolls, issueUrlBase string, rollingTo *revision.Revision, cb func(context.Context, RollImpl) error) (RollImpl, error) {
+	mr, err := updateIssueFromGitLab(ctx, issue, g)
+	if err != nil {
+		return nil, err
+	}
+	return &gitlabRoll{
+		finishedCallback: cb,
+		g:                g,
+		issue:            issue,
+		issueUrl:         fmt.Sprintf("%s%d", issueUrlBase, issue.Issue),
+		mergeRequest:     mr,
+		recent:           recent,
+		retrieveRoll: func(ctx context.Context) (*gitlab.MergeRequest, error) {
+			return updateIssueFromGitLab(ctx, issue, g)
+		},
+		rollingTo: rollingTo,
+	}, nil
+}
+
+// See documentation for state_machine.RollImpl interface.
+func (r *gitlabRoll) InsertIntoDB(ctx context.Context) error {
+	return r.recent.Add(ctx, r.issue)
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) AddComment(ctx context.Context, msg string) error {
+	return r.g.AddComment(ctx, r.issue.Issue, msg)
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) Close(ctx context.Context, result, msg string) error {
+	sklog.Infof("Closing merge request %d (result %q) with message: %s", r.issue.Issue, result, msg)
+	r.result = result
+	return r.withModify(ctx, "close the merge request", func() error {
+		if err := r.g.AddComment(ctx, r.issue.Issue, msg); err != nil {
+			return err
+		}
+		_, err := r.g.CloseMergeRequest(ctx, r.issue.Issue)
+		return err
+	})
+}
+
+// Helper function for modifying a roll CL which might fail due to the CL being
+// closed by a human or some other process, in which case we don't want to error
+// out.
+func (r *gitlabRoll) withModify(ctx context.Context, action string, fn func() error) error {
+	if err := fn(); err != nil {
+		// It's possible that somebody closed the merge request (or it
+		// was merged) while we were working. If that's the case, log an
+		// error and move on.
+		if err2 := r.Update(ctx); err2 != nil {
+			return fmt.Errorf("Failed to %s with error:\n%s\nAnd failed to update it with error:\n%s", action, err, err2)
+		}
+		if r.mergeRequest.State != gitlab.MR_STATE_OPENED {
+			sklog.Errorf("Attempted to %s but it is already closed! Error: %s", action, err)
+			return nil
+		}
+		return err
+	}
+	return r.Update(ctx)
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) Update(ctx context.Context) error {
+	alreadyFinished := r.IsFinished()
+	mr, err := r.retrieveRoll(ctx)
+	if err != nil {
+		return err
+	}
+	r.mergeRequest = mr
+	if r.result != "" {
+		r.issue.Result = r.result
+	}
+	if err := r.recent.Update(ctx, r.issue); err != nil {
+		return err
+	}
+	if r.IsFinished() && !alreadyFinished && r.finishedCallback != nil {
+		return r.finishedCallback(ctx, r)
+	}
+	return nil
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) IsClosed() bool {
+	return r.issue.Closed
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) IsFinished() bool {
+	return r.issue.CqFinished
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) IsSuccess() bool {
+	return r.issue.CqSuccess
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) IsDryRunFinished() bool {
+	return r.issue.DryRunFinished
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) IsDryRunSuccess() bool {
+	return r.issue.DryRunSuccess
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) RollingTo() *revision.Revision {
+	return r.rollingTo
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) SwitchToDryRun(ctx context.Context) error {
+	return r.withModify(ctx, "switch the merge request to dry run", func() error {
+		if r.mergeRequest.MergeWhenPipelineSucceeds {
+			if err := r.g.CancelMergeWhenPipelineSucceeds(ctx, r.issue.Issue); err != nil {
+				return err
+			}
+		}
+		r.issue.IsDryRun = true
+		return nil
+	})
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) SwitchToNormal(ctx context.Context) error {
+	// The merge request is set to merge when its pipeline succeeds as part
+	// of the update.
+	return r.withModify(ctx, "switch the merge request out of dry run", func() error {
+		r.issue.IsDryRun = false
+		return nil
+	})
+}
+
+// retryPipeline retries the failed jobs in the merge request's most recent
+// pipeline.
+func (r *gitlabRoll) retryPipeline(ctx context.Context) error {
+	if r.mergeRequest.HeadPipeline == nil {
+		return fmt.Errorf("Merge request %d has no pipeline to retry.", r.issue.Issue)
+	}
+	_, err := r.g.RetryPipeline(ctx, r.mergeRequest.HeadPipeline.ID)
+	return err
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) RetryCQ(ctx context.Context) error {
+	return r.withModify(ctx, "retry the pipeline and merge when it succeeds", func() error {
+		if err := r.retryPipeline(ctx); err != nil {
+			return err
+		}
+		r.issue.IsDryRun = false
+		return nil
+	})
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) RetryDryRun(ctx context.Context) error {
+	return r.withModify(ctx, "retry the pipeline", func() error {
+		if err := r.retryPipeline(ctx); err != nil {
+			return err
+		}
+		r.issue.IsDryRun = true
+		return nil
+	})
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) IssueID() string {
+	return fmt.Sprintf("%d", r.issue.Issue)
+}
+
+// See documentation for state_machine.RollCLImpl interface.
+func (r *gitlabRoll) IssueURL() string {
+	return r.issueUrl
+}
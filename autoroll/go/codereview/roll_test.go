@@ -21,6 +21,8 @@ import (
 	"go.skia.org/infra/go/gerrit"
 	gerrit_testutils "go.skia.org/infra/go/gerrit/testutils"
 	"go.skia.org/infra/go/github"
+	"go.skia.org/infra/go/gitlab"
+	gitlab_testutils "go.skia.org/infra/go/gitlab/testutils"
 	"go.skia.org/infra/go/mockhttpclient"
 	"go.skia.org/infra/go/testutils"
 	"go.skia.org/infra/go/testutils/unittest"
@@ -716,3 +718,253 @@ func TestUpdateFromGitHubPullRequest(t *testing.T) {
 	require.NoError(t, updateIssueFromGitHubPullRequest(a, pr))
 	assertdeep.Equal(t, expect, a)
 }
+
+func TestUpdateFromGitLabMergeRequest(t *testing.T) {
+	unittest.SmallTest(t)
+
+	now := time.Now()
+	a := &autoroll.AutoRollIssue{
+		Issue:       123,
+		RollingFrom: "abc123",
+		RollingTo:   "def456",
+	}
+
+	// Ensure that we don't overwrite the issue number.
+	require.EqualError(t, updateIssueFromGitLabMergeRequest(a, &gitlab.MergeRequest{}, 0), "Merge request 0 differs from existing issue number 123!")
+
+	// Normal, in-progress CL.
+	mr := &gitlab.MergeRequest{
+		IID:                       a.Issue,
+		State:                     gitlab.MR_STATE_OPENED,
+		Title:                     "roll the deps",
+		CreatedAt:                 now,
+		UpdatedAt:                 now,
+		MergeStatus:               gitlab.MERGE_STATUS_CAN_BE_MERGED,
+		MergeWhenPipelineSucceeds: true,
+		HeadPipeline: &gitlab.Pipeline{
+			ID:     1,
+			Status: gitlab.PIPELINE_STATUS_RUNNING,
+		},
+	}
+	require.NoError(t, updateIssueFromGitLabMergeRequest(a, mr, 2))
+	expect := &autoroll.AutoRollIssue{
+		Created:     now,
+		Issue:       123,
+		Modified:    now,
+		Patchsets:   []int64{1, 2},
+		Result:      autoroll.ROLL_RESULT_IN_PROGRESS,
+		RollingFrom: "abc123",
+		RollingTo:   "def456",
+		Subject:     "roll the deps",
+	}
+	assertdeep.Equal(t, expect, a)
+
+	// Pipeline failed.
+	mr.HeadPipeline.Status = gitlab.PIPELINE_STATUS_FAILED
+	mr.MergeWhenPipelineSucceeds = false
+	expect.CqFinished = true
+	expect.Result = autoroll.ROLL_RESULT_FAILURE
+	require.NoError(t, updateIssueFromGitLabMergeRequest(a, mr, 2))
+	assertdeep.Equal(t, expect, a)
+
+	// Merge conflict.
+	mr.HeadPipeline.Status = gitlab.PIPELINE_STATUS_RUNNING
+	mr.MergeStatus = gitlab.MERGE_STATUS_CANNOT_BE_MERGED
+	require.NoError(t, updateIssueFromGitLabMergeRequest(a, mr, 2))
+	assertdeep.Equal(t, expect, a)
+
+	// Closed.
+	mr.MergeStatus = gitlab.MERGE_STATUS_CAN_BE_MERGED
+	mr.State = gitlab.MR_STATE_CLOSED
+	expect.Closed = true
+	require.NoError(t, updateIssueFromGitLabMergeRequest(a, mr, 2))
+	assertdeep.Equal(t, expect, a)
+
+	// Merged.
+	mr.HeadPipeline.Status = gitlab.PIPELINE_STATUS_SUCCESS
+	mr.State = gitlab.MR_STATE_MERGED
+	expect.Committed = true
+	expect.CqSuccess = true
+	expect.Result = autoroll.ROLL_RESULT_SUCCESS
+	require.NoError(t, updateIssueFromGitLabMergeRequest(a, mr, 2))
+	assertdeep.Equal(t, expect, a)
+
+	// Dry run active.
+	a.IsDryRun = true
+	mr.HeadPipeline.Status = gitlab.PIPELINE_STATUS_PENDING
+	mr.State = gitlab.MR_STATE_OPENED
+	expect.IsDryRun = true
+	expect.Closed = false
+	expect.Committed = false
+	expect.CqFinished = false
+	expect.CqSuccess = false
+	expect.Result = autoroll.ROLL_RESULT_DRY_RUN_IN_PROGRESS
+	require.NoError(t, updateIssueFromGitLabMergeRequest(a, mr, 2))
+	assertdeep.Equal(t, expect, a)
+
+	// Dry run failed.
+	mr.HeadPipeline.Status = gitlab.PIPELINE_STATUS_CANCELED
+	expect.DryRunFinished = true
+	expect.Result = autoroll.ROLL_RESULT_DRY_RUN_FAILURE
+	require.NoError(t, updateIssueFromGitLabMergeRequest(a, mr, 2))
+	assertdeep.Equal(t, expect, a)
+
+	// Dry run succeeded.
+	mr.HeadPipeline.Status = gitlab.PIPELINE_STATUS_SUCCESS
+	expect.DryRunSuccess = true
+	expect.Result = autoroll.ROLL_RESULT_DRY_RUN_SUCCESS
+	require.NoError(t, updateIssueFromGitLabMergeRequest(a, mr, 2))
+	assertdeep.Equal(t, expect, a)
+
+	// No pipeline yet.
+	mr.HeadPipeline = nil
+	expect.DryRunFinished = false
+	expect.DryRunSuccess = false
+	expect.Result = autoroll.ROLL_RESULT_DRY_RUN_IN_PROGRESS
+	require.NoError(t, updateIssueFromGitLabMergeRequest(a, mr, 2))
+	assertdeep.Equal(t, expect, a)
+}
+
+func TestGitlabCodeReview(t *testing.T) {
+	unittest.MediumTest(t)
+
+	f := gitlab_testutils.NewFakeGitLab(t)
+	defer f.Close()
+	cfg := &GitlabConfig{
+		URL:     f.Server.URL,
+		Project: gitlab_testutils.FAKE_PROJECT,
+	}
+	require.NoError(t, cfg.Validate())
+	cr, err := cfg.Init(nil, nil, f.GitLab())
+	require.NoError(t, err)
+	require.Equal(t, cfg, cr.Config())
+	require.Equal(t, gitlab_testutils.FAKE_EMAIL, cr.UserEmail())
+	require.Equal(t, gitlab_testutils.FAKE_USERNAME, cr.UserName())
+	require.Equal(t, f.Server.URL+"/"+gitlab_testutils.FAKE_PROJECT+"/-/merge_requests/", cr.GetIssueUrlBase())
+
+	_, err = cfg.Init(nil, nil, nil)
+	require.EqualError(t, err, "GitLab client is required.")
+	require.EqualError(t, (&GitlabConfig{URL: f.Server.URL}).Validate(), "Project is required.")
+}
+
+func TestGitlabRoll(t *testing.T) {
+	unittest.LargeTest(t)
+
+	testutil.InitDatastore(t, ds.KIND_AUTOROLL_ROLL)
+
+	f := gitlab_testutils.NewFakeGitLab(t)
+	defer f.Close()
+	g := f.GitLab()
+	ctx := context.Background()
+	recent, err := recent_rolls.NewRecentRolls(ctx, "test-roller")
+	require.NoError(t, err)
+
+	// Upload and retrieve the roll.
+	from := "abcde12345abcde12345abcde12345abcde12345"
+	to := "fghij67890fghij67890fghij67890fghij67890"
+	toRev := &revision.Revision{
+		Id:          to,
+		Description: "rolling to fghi",
+	}
+	mr := f.AddMergeRequest(&gitlab.MergeRequest{
+		Title:        fmt.Sprintf("Roll child %s..%s", from[:12], to[:12]),
+		SourceBranch: "roll",
+		TargetBranch: "master",
+		SHA:          "commit1",
+	})
+	issue := &autoroll.AutoRollIssue{
+		Issue:       mr.IID,
+		RollingFrom: from,
+		RollingTo:   to,
+	}
+	gr, err := newGitlabRoll(ctx, issue, g, recent, g.GetIssueUrlBase(), toRev, nil)
+	require.NoError(t, err)
+	require.NoError(t, gr.InsertIntoDB(ctx))
+	require.Equal(t, toRev, gr.RollingTo())
+	require.Equal(t, fmt.Sprintf("%s%d", g.GetIssueUrlBase(), mr.IID), gr.IssueURL())
+	require.False(t, gr.IsFinished())
+	require.False(t, gr.IsClosed())
+	// We can't retry a pipeline which doesn't exist.
+	require.EqualError(t, gr.RetryDryRun(ctx), fmt.Sprintf("Merge request %d has no pipeline to retry.", mr.IID))
+
+	// Once there's a pipeline, we set the merge request to merge when it
+	// succeeds.
+	f.SetPipeline(mr.IID, gitlab.PIPELINE_STATUS_RUNNING, &gitlab.Job{
+		Name:   "test",
+		Status: gitlab.PIPELINE_STATUS_RUNNING,
+	})
+	require.NoError(t, gr.Update(ctx))
+	require.True(t, f.MergeRequest(mr.IID).MergeWhenPipelineSucceeds)
+	require.False(t, gr.IsFinished())
+	require.Len(t, issue.TryResults, 1)
+	require.Equal(t, autoroll.TRYBOT_STATUS_STARTED, issue.TryResults[0].Status)
+
+	// Comment.
+	require.NoError(t, gr.AddComment(ctx, "hello"))
+	require.Equal(t, []string{"hello"}, f.Notes(mr.IID))
+
+	// Switch to dry run and back.
+	require.NoError(t, gr.SwitchToDryRun(ctx))
+	require.True(t, issue.IsDryRun)
+	require.False(t, f.MergeRequest(mr.IID).MergeWhenPipelineSucceeds)
+	require.False(t, gr.IsDryRunFinished())
+	require.NoError(t, gr.SwitchToNormal(ctx))
+	require.False(t, issue.IsDryRun)
+	require.True(t, f.MergeRequest(mr.IID).MergeWhenPipelineSucceeds)
+
+	// The pipeline fails.
+	finished := false
+	gr.(*gitlabRoll).finishedCallback = func(context.Context, RollImpl) error {
+		finished = true
+		return nil
+	}
+	f.SetPipelineStatus(mr.IID, gitlab.PIPELINE_STATUS_FAILED)
+	require.NoError(t, gr.Update(ctx))
+	require.True(t, finished)
+	require.True(t, gr.IsFinished())
+	require.False(t, gr.IsSuccess())
+	require.Equal(t, autoroll.ROLL_RESULT_FAILURE, issue.Result)
+
+	// Retry. The pipeline succeeds and the merge request is merged.
+	require.NoError(t, gr.RetryCQ(ctx))
+	require.False(t, gr.IsFinished())
+	require.True(t, f.MergeRequest(mr.IID).MergeWhenPipelineSucceeds)
+	f.SetPipelineStatus(mr.IID, gitlab.PIPELINE_STATUS_SUCCESS)
+	require.NoError(t, gr.Update(ctx))
+	require.True(t, gr.IsFinished())
+	require.True(t, gr.IsSuccess())
+	require.True(t, gr.IsClosed())
+	issue, err = recent.Get(ctx, mr.IID)
+	require.NoError(t, err)
+	require.Equal(t, autoroll.ROLL_RESULT_SUCCESS, issue.Result)
+
+	// Upload another roll, dry run this time, and close it.
+	mr = f.AddMergeRequest(&gitlab.MergeRequest{
+		Title:        fmt.Sprintf("Roll child %s..%s", from[:12], to[:12]),
+		SourceBranch: "roll2",
+		TargetBranch: "master",
+		SHA:          "commit2",
+	})
+	f.SetPipeline(mr.IID, gitlab.PIPELINE_STATUS_SUCCESS)
+	issue = &autoroll.AutoRollIssue{
+		IsDryRun:    true,
+		Issue:       mr.IID,
+		RollingFrom: from,
+		RollingTo:   to,
+	}
+	gr, err = newGitlabRoll(ctx, issue, g, recent, g.GetIssueUrlBase(), toRev, nil)
+	require.NoError(t, err)
+	require.NoError(t, gr.InsertIntoDB(ctx))
+	require.False(t, f.MergeRequest(mr.IID).MergeWhenPipelineSucceeds)
+	require.True(t, gr.IsDryRunFinished())
+	require.True(t, gr.IsDryRunSuccess())
+	require.NoError(t, gr.Close(ctx, autoroll.ROLL_RESULT_DRY_RUN_SUCCESS, "close it!"))
+	require.Equal(t, gitlab.MR_STATE_CLOSED, f.MergeRequest(mr.IID).State)
+	require.Equal(t, []string{"close it!"}, f.Notes(mr.IID))
+	issue, err = recent.Get(ctx, mr.IID)
+	require.NoError(t, err)
+	require.Equal(t, autoroll.ROLL_RESULT_DRY_RUN_SUCCESS, issue.Result)
+
+	// Closing an already-closed merge request is not an error.
+	require.NoError(t, gr.Close(ctx, autoroll.ROLL_RESULT_DRY_RUN_SUCCESS, "close it again!"))
+}
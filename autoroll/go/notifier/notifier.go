@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"time"
 
+	"go.skia.org/infra/autoroll/go/revision"
 	"go.skia.org/infra/go/chatbot"
 	"go.skia.org/infra/go/email"
 	"go.skia.org/infra/go/notifier"
@@ -16,6 +17,7 @@ import (
 const (
 	// Types of notification message sent by the roller. These can be
 	// whitelisted in the notifier configs.
+	MSG_TYPE_CULPRIT_FOUND        = "culprit found"
 	MSG_TYPE_ISSUE_UPDATE         = "issue update"
 	MSG_TYPE_LAST_N_FAILED        = "last n failed"
 	MSG_TYPE_MODE_CHANGE          = "mode change"
@@ -27,6 +29,9 @@ const (
 	MSG_TYPE_SUCCESS_THROTTLE     = "success throttle"
 
 	// Templates for messages sent by the roller.
+	subjectCulpritFound = "The {{.ChildName}} into {{.ParentName}} AutoRoller found the culprit of failing rolls"
+	bodyCulpritFound    = "Bisection found that rolls fail starting at {{.Culprit}}; the last good revision is {{.LastGood}}. The roller will roll up to {{.LastGood}} and will not bisect further rolls which include {{.Culprit}}."
+
 	subjectIssueUpdate = "The {{.ChildName}} into {{.ParentName}} AutoRoller has uploaded issue {{.IssueID}}"
 
 	bodyModeChange    = "{{.User}} changed the mode to \"{{.Mode}}\" with message: {{.Message}}"
@@ -55,6 +60,9 @@ const (
 )
 
 var (
+	subjectTmplCulpritFound = template.Must(template.New("subjectCulpritFound").Parse(subjectCulpritFound))
+	bodyTmplCulpritFound    = template.Must(template.New("bodyCulpritFound").Parse(bodyCulpritFound))
+
 	subjectTmplIssueUpdate = template.Must(template.New("subjectIssueUpdate").Parse(subjectIssueUpdate))
 
 	subjectTmplModeChange = template.Must(template.New("subjectModeChange").Parse(subjectModeChange))
@@ -86,8 +94,10 @@ var (
 // text templates in the Subject and Body fields of messages.
 type tmplVars struct {
 	ChildName      string
+	Culprit        string
 	IssueID        string
 	IssueURL       string
+	LastGood       string
 	Mode           string
 	Message        string
 	N              int
@@ -250,3 +260,12 @@ func (a *AutoRollNotifier) SendLastNFailed(ctx context.Context, n int, url strin
 		N:        n,
 	}, subjectTmplLastNFailed, bodyTmplLastNFailed, notifier.SEVERITY_ERROR, MSG_TYPE_LAST_N_FAILED)
 }
+
+// Send a notification that bisection found the revision which caused rolls to
+// fail.
+func (a *AutoRollNotifier) SendCulpritFound(ctx context.Context, culprit, lastGood *revision.Revision) {
+	a.send(ctx, &tmplVars{
+		Culprit:  culprit.String(),
+		LastGood: lastGood.String(),
+	}, subjectTmplCulpritFound, bodyTmplCulpritFound, notifier.SEVERITY_ERROR, MSG_TYPE_CULPRIT_FOUND)
+}
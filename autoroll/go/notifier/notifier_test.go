@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/autoroll/go/revision"
 	"go.skia.org/infra/go/notifier"
 	"go.skia.org/infra/go/testutils/unittest"
 )
@@ -64,4 +65,11 @@ func TestNotifier(t *testing.T) {
 	require.Equal(t, fmt.Sprintf("The roller is throttled because it attempted to upload too many CLs in too short a time.  The roller will unthrottle at %s."+footer, now.Format(time.RFC1123)), t1.msgs[2].m.Body)
 	require.Equal(t, notifier.SEVERITY_ERROR, t1.msgs[2].m.Severity)
 	require.Equal(t, 1, len(t2.msgs))
+
+	n.SendCulpritFound(ctx, &revision.Revision{Id: "abc"}, &revision.Revision{Id: "def"})
+	require.Equal(t, 4, len(t1.msgs))
+	require.Equal(t, "The childRepo into parentRepo AutoRoller found the culprit of failing rolls", t1.msgs[3].subject)
+	require.Equal(t, "Bisection found that rolls fail starting at abc; the last good revision is def. The roller will roll up to def and will not bisect further rolls which include abc."+footer, t1.msgs[3].m.Body)
+	require.Equal(t, MSG_TYPE_CULPRIT_FOUND, t1.msgs[3].m.Type)
+	require.Equal(t, 1, len(t2.msgs))
 }
@@ -35,7 +35,7 @@ func androidGerrit(t *testing.T, g gerrit.GerritInterface) codereview.CodeReview
 		URL:     "https://googleplex-android-review.googlesource.com",
 		Project: "platform/external/skia",
 		Config:  codereview.GERRIT_CONFIG_ANDROID,
-	}).Init(g, nil)
+	}).Init(g, nil, nil)
 	require.NoError(t, err)
 	return rv
 }
@@ -29,7 +29,7 @@ func githubCR(t *testing.T, g *github.GitHub) codereview.CodeReview {
 		RepoOwner:     "me",
 		RepoName:      "my-repo",
 		ChecksWaitFor: []string{"a", "b", "c"},
-	}).Init(nil, g)
+	}).Init(nil, g, nil)
 	require.NoError(t, err)
 	return rv
 }
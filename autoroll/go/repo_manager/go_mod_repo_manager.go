@@ -0,0 +1,715 @@
+package repo_manager
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.skia.org/infra/autoroll/go/codereview"
+	"go.skia.org/infra/autoroll/go/revision"
+	"go.skia.org/infra/go/exec"
+	"go.skia.org/infra/go/gerrit"
+	"go.skia.org/infra/go/git"
+	"go.skia.org/infra/go/github"
+	"go.skia.org/infra/go/gitlab"
+	"go.skia.org/infra/go/go_install"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/sklog"
+	"go.skia.org/infra/go/util"
+)
+
+/*
+	Repo manager which rolls a Go module dependency by updating go.mod and
+	go.sum in the parent repo.
+*/
+
+const (
+	GO_MOD_FILE = "go.mod"
+
+	// Name of the remote used to push roll branches to the roller's fork
+	// of the parent repo when using GitHub.
+	GO_MOD_GITHUB_FORK_REMOTE_NAME = "fork"
+
+	TMPL_COMMIT_MSG_GO_MOD = `Roll {{.ChildPath}} {{.RollingFrom.String}}..{{.RollingTo.String}} ({{len .Revisions}} commits)
+
+{{if .IncludeLog}}git log {{.RollingFrom}}..{{.RollingTo}} --date=short --first-parent --format='%ad %ae %s'
+{{range .Revisions}}{{.Timestamp.Format "2006-01-02"}} {{.Author}} {{.Description}}
+{{end}}{{end}}
+Created with:
+  go get -d {{.ChildPath}}@{{.RollingTo}}
+
+If this roll has caused a breakage, revert this CL and stop the roller
+using the controls here:
+{{.ServerURL}}
+Please CC {{stringsJoin .Reviewers ","}} on the revert to ensure that a human
+is aware of the problem.
+
+To report a problem with the AutoRoller itself, please file a bug:
+https://bugs.chromium.org/p/skia/issues/entry?template=Autoroller+Bug
+
+Documentation for the AutoRoller is here:
+https://skia.googlesource.com/buildbot/+/master/autoroll/README.md
+
+{{if .CqExtraTrybots}}Cq-Include-Trybots: {{.CqExtraTrybots}}
+{{end}}Bug: {{if .Bugs}}{{stringsJoin .Bugs ","}}{{else}}None{{end}}
+Tbr: {{stringsJoin .Reviewers ","}}`
+)
+
+var (
+	// Use this function to instantiate a RepoManager. This is able to be
+	// overridden for testing.
+	NewGoModRepoManager func(context.Context, *GoModRepoManagerConfig, string, gerrit.GerritInterface, *github.GitHub, *gitlab.GitLab, string, *http.Client, codereview.CodeReview, bool) (RepoManager, error) = newGoModRepoManager
+
+	// pseudoVersionRegex matches Go module pseudo-versions, eg.
+	// "v0.0.0-20200102150405-abcdef123456". The submatch is the
+	// abbreviated commit hash.
+	pseudoVersionRegex = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+-(?:[0-9A-Za-z-]+\.)*[0-9]{14}-([0-9a-f]{12})(?:\+incompatible)?$`)
+
+	// semVerTagRegex matches release versions of Go modules, eg. "v1.2.3".
+	// Pre-release versions are not matched.
+	semVerTagRegex = regexp.MustCompile(`^v([0-9]+)\.([0-9]+)\.([0-9]+)$`)
+
+	// majorVersionSuffixRegex matches the major version suffix of a Go
+	// module path, eg. "/v2".
+	majorVersionSuffixRegex = regexp.MustCompile(`/v([0-9]+)$`)
+)
+
+// GoModRepoManagerConfig provides configuration for the Go module
+// RepoManager. ChildPath is the path of the Go module, eg.
+// "go.chromium.org/luci".
+type GoModRepoManagerConfig struct {
+	CommonRepoManagerConfig
+
+	// URL of the repo which contains the Go module.
+	ChildRepo string `json:"childRepo"`
+
+	// Optional fields.
+
+	// Directory within the parent repo which contains the go.mod file.
+	// Defaults to the root of the parent repo.
+	GoModDir string `json:"goModDir,omitempty"`
+
+	// If true, only roll to tagged release versions of the module, eg.
+	// "v1.2.3". Otherwise, roll to every commit on ChildBranch using
+	// pseudo-versions.
+	RollToTags bool `json:"rollToTags,omitempty"`
+}
+
+// Validate the config.
+func (c *GoModRepoManagerConfig) Validate() error {
+	if c.ChildRepo == "" {
+		return errors.New("ChildRepo is required.")
+	}
+	if path.IsAbs(c.GoModDir) || strings.HasPrefix(path.Clean(c.GoModDir), "..") {
+		return errors.New("GoModDir must be a relative path within the parent repo.")
+	}
+	return c.CommonRepoManagerConfig.Validate()
+}
+
+// goModRepoManager is a RepoManager which rolls a Go module dependency.
+type goModRepoManager struct {
+	*commonRepoManager
+	childRepoUrl  string
+	gerritConfig  *codereview.GerritConfig
+	githubClient  *github.GitHub
+	gitlabClient  *gitlab.GitLab
+	goEnv         []string
+	goExc         string
+	goModDir      string
+	parentRepo    *git.Checkout
+	parentRepoUrl string
+	rollToTags    bool
+}
+
+// newGoModRepoManager returns a RepoManager instance which rolls a Go module
+// dependency. Rolls are uploaded to Gerrit, GitHub, or GitLab, depending on the
+// CodeReview.
+func newGoModRepoManager(ctx context.Context, c *GoModRepoManagerConfig, workdir string, g gerrit.GerritInterface, githubClient *github.GitHub, gitlabClient *gitlab.GitLab, serverURL string, client *http.Client, cr codereview.CodeReview, local bool) (RepoManager, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	var gerritConfig *codereview.GerritConfig
+	switch cfg := cr.Config().(type) {
+	case *codereview.GerritConfig:
+		gerritConfig = cfg
+		githubClient = nil
+		gitlabClient = nil
+	case *codereview.GithubConfig:
+		g = nil
+		gitlabClient = nil
+		if githubClient == nil {
+			return nil, errors.New("A GitHub client is required to upload rolls to GitHub.")
+		}
+	case *codereview.GitlabConfig:
+		g = nil
+		githubClient = nil
+		if gitlabClient == nil {
+			return nil, errors.New("A GitLab client is required to upload rolls to GitLab.")
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported code review backend %T for Go module rolls.", cfg)
+	}
+
+	wd := path.Join(workdir, "repo_manager")
+	if c.CommitMsgTmpl == "" {
+		c.CommitMsgTmpl = TMPL_COMMIT_MSG_GO_MOD
+	}
+	crm, err := newCommonRepoManager(ctx, c.CommonRepoManagerConfig, wd, serverURL, g, client, cr, local)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create and populate the child directory if needed.
+	if _, err := os.Stat(path.Join(crm.childDir, ".git")); err != nil {
+		if err := os.MkdirAll(crm.childDir, os.ModePerm); err != nil {
+			return nil, err
+		}
+		if _, err := git.GitDir(crm.childDir).Git(ctx, "clone", c.ChildRepo, "."); err != nil {
+			return nil, err
+		}
+	}
+
+	// Use the Go installation from the PATH when running locally; otherwise
+	// install Go from CIPD.
+	goExc := "go"
+	goEnv := []string{"GO111MODULE=on"}
+	if !local {
+		exc, env, err := go_install.EnsureGo(ctx, client, cipdRoot)
+		if err != nil {
+			return nil, err
+		}
+		goExc = exc
+		goEnv = make([]string, 0, len(env))
+		for k, v := range env {
+			if k == "PATH" {
+				v += ":" + os.Getenv("PATH")
+			}
+			goEnv = append(goEnv, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+
+	parentRepo, err := git.NewCheckout(ctx, c.ParentRepo, wd)
+	if err != nil {
+		return nil, err
+	}
+	if githubClient != nil {
+		_, repo := GetUserAndRepo(c.ParentRepo)
+		userFork := fmt.Sprintf("git@github.com:%s/%s.git", cr.UserName(), repo)
+		if err := parentRepo.AddRemote(ctx, GO_MOD_GITHUB_FORK_REMOTE_NAME, userFork); err != nil {
+			return nil, err
+		}
+	}
+
+	return &goModRepoManager{
+		commonRepoManager: crm,
+		childRepoUrl:      c.ChildRepo,
+		gerritConfig:      gerritConfig,
+		githubClient:      githubClient,
+		gitlabClient:      gitlabClient,
+		goEnv:             goEnv,
+		goExc:             goExc,
+		goModDir:          path.Clean(c.GoModDir),
+		parentRepo:        parentRepo,
+		parentRepoUrl:     c.ParentRepo,
+		rollToTags:        c.RollToTags,
+	}, nil
+}
+
+// findModuleVersion returns the version of the given module which is required
+// by the given go.mod file.
+func findModuleVersion(goMod, module string) (string, error) {
+	inRequireBlock := false
+	scanner := bufio.NewScanner(strings.NewReader(goMod))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if inRequireBlock {
+			if fields[0] == ")" {
+				inRequireBlock = false
+				continue
+			}
+		} else if fields[0] == "require" {
+			if len(fields) == 2 && fields[1] == "(" {
+				inRequireBlock = true
+				continue
+			}
+			fields = fields[1:]
+		} else {
+			continue
+		}
+		if len(fields) == 2 && strings.Trim(fields[0], `"`) == module {
+			return strings.Trim(fields[1], `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("Unable to find %s in %s", module, GO_MOD_FILE)
+}
+
+// parseSemVer parses the given release version, eg. "v1.2.3". Returns false
+// if the version is not a release version.
+func parseSemVer(version string) ([3]int, bool) {
+	var rv [3]int
+	m := semVerTagRegex.FindStringSubmatch(version)
+	if m == nil {
+		return rv, false
+	}
+	for i := range rv {
+		v, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return rv, false
+		}
+		rv[i] = v
+	}
+	return rv, true
+}
+
+// validMajorVersion returns true iff a release with the given major version
+// may be used for the given module path. Major versions 2 and up are only
+// valid for module paths with a matching "/vN" suffix.
+func validMajorVersion(module string, major int) bool {
+	if m := majorVersionSuffixRegex.FindStringSubmatch(module); m != nil {
+		return m[1] == strconv.Itoa(major)
+	}
+	return major <= 1
+}
+
+// commitForVersion returns the child repo commit hash corresponding to the
+// given module version, which may be a release tag, a pseudo-version, or a
+// commit hash.
+func (rm *goModRepoManager) commitForVersion(ctx context.Context, version string) (string, error) {
+	ref := version
+	if m := pseudoVersionRegex.FindStringSubmatch(version); m != nil {
+		ref = m[1]
+	} else if tag := strings.TrimSuffix(version, "+incompatible"); semVerTagRegex.MatchString(tag) {
+		ref = fmt.Sprintf("refs/tags/%s", tag)
+	}
+	hash, err := rm.childRepo.RevParse(ctx, ref+"^{commit}")
+	if err != nil {
+		return "", skerr.Wrapf(err, "failed to resolve %s version %s", rm.childPath, version)
+	}
+	return strings.TrimSpace(hash), nil
+}
+
+// getTagRevision returns a Revision for the given release tag.
+func (rm *goModRepoManager) getTagRevision(ctx context.Context, tag string) (*revision.Revision, error) {
+	details, err := rm.childRepo.Details(ctx, fmt.Sprintf("refs/tags/%s", tag))
+	if err != nil {
+		return nil, err
+	}
+	rev := revision.FromLongCommit(rm.childRevLinkTmpl, details)
+	rev.Id = tag
+	rev.Display = tag
+	return rev, nil
+}
+
+// getRevision returns a Revision for the given version, which may be a release
+// tag, a pseudo-version, or a commit hash.
+func (rm *goModRepoManager) getRevision(ctx context.Context, version string) (*revision.Revision, error) {
+	if _, ok := parseSemVer(version); ok {
+		return rm.getTagRevision(ctx, version)
+	}
+	hash := version
+	if pseudoVersionRegex.MatchString(version) {
+		var err error
+		hash, err = rm.commitForVersion(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+	}
+	details, err := rm.childRepo.Details(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return revision.FromLongCommit(rm.childRevLinkTmpl, details), nil
+}
+
+// getTagsNotRolled returns Revisions for the release tags on the child branch
+// which are not included in the given commit, newest first.
+func (rm *goModRepoManager) getTagsNotRolled(ctx context.Context, lastRollHash string) ([]*revision.Revision, error) {
+	listTags := func(ref string) ([]string, error) {
+		output, err := rm.childRepo.Git(ctx, "tag", "--merged", ref, "--list", "v*")
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(output), nil
+	}
+	rolled, err := listTags(lastRollHash)
+	if err != nil {
+		return nil, err
+	}
+	rolledSet := util.NewStringSet(rolled)
+	all, err := listTags(fmt.Sprintf("origin/%s", rm.childBranch))
+	if err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	versions := map[string][3]int{}
+	for _, tag := range all {
+		v, ok := parseSemVer(tag)
+		if !ok || !validMajorVersion(rm.childPath, v[0]) || rolledSet[tag] {
+			continue
+		}
+		tags = append(tags, tag)
+		versions[tag] = v
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		a, b := versions[tags[i]], versions[tags[j]]
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] > b[k]
+			}
+		}
+		return false
+	})
+	rv := make([]*revision.Revision, 0, len(tags))
+	for _, tag := range tags {
+		rev, err := rm.getTagRevision(ctx, tag)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, rev)
+	}
+	return rv, nil
+}
+
+// See documentation for RepoManager interface.
+func (rm *goModRepoManager) Update(ctx context.Context) (*revision.Revision, *revision.Revision, []*revision.Revision, error) {
+	rm.repoMtx.Lock()
+	defer rm.repoMtx.Unlock()
+
+	// Update the repositories.
+	if err := rm.parentRepo.Fetch(ctx); err != nil {
+		return nil, nil, nil, fmt.Errorf("Failed to update parent repo: %s", err)
+	}
+	if _, err := rm.childRepo.Git(ctx, "fetch", "--prune", "--tags", "origin"); err != nil {
+		return nil, nil, nil, fmt.Errorf("Failed to update child repo: %s", err)
+	}
+
+	// Read the currently-rolled version from go.mod.
+	goMod, err := rm.parentRepo.Git(ctx, "show", fmt.Sprintf("origin/%s:%s", rm.parentBranch, path.Join(rm.goModDir, GO_MOD_FILE)))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Failed to read %s: %s", GO_MOD_FILE, err)
+	}
+	lastRollVersion, err := findModuleVersion(goMod, rm.childPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	lastRollRev, err := rm.getRevision(ctx, lastRollVersion)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Find the not-yet-rolled revisions.
+	var tipRev *revision.Revision
+	var notRolledRevs []*revision.Revision
+	if rm.rollToTags {
+		lastRollHash, err := rm.commitForVersion(ctx, lastRollVersion)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		notRolledRevs, err = rm.getTagsNotRolled(ctx, lastRollHash)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		tipRev = lastRollRev
+		if len(notRolledRevs) > 0 {
+			tipRev = notRolledRevs[0]
+		}
+	} else {
+		tipRev, err = rm.getTipRev(ctx)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		notRolledRevs, err = rm.getCommitsNotRolled(ctx, lastRollRev, tipRev)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return lastRollRev, tipRev, notRolledRevs, nil
+}
+
+// See documentation for RepoManager interface.
+func (rm *goModRepoManager) GetRevision(ctx context.Context, id string) (*revision.Revision, error) {
+	rm.repoMtx.RLock()
+	defer rm.repoMtx.RUnlock()
+	return rm.getRevision(ctx, id)
+}
+
+// getCommitLog returns the commits on the child branch between the given
+// Revisions, newest first.
+func (rm *goModRepoManager) getCommitLog(ctx context.Context, from, to *revision.Revision) ([]*revision.Revision, error) {
+	if !rm.rollToTags {
+		return rm.getCommitsNotRolled(ctx, from, to)
+	}
+	fromHash, err := rm.commitForVersion(ctx, from.Id)
+	if err != nil {
+		return nil, err
+	}
+	toHash, err := rm.commitForVersion(ctx, to.Id)
+	if err != nil {
+		return nil, err
+	}
+	return rm.getCommitsNotRolled(ctx, &revision.Revision{Id: fromHash}, &revision.Revision{Id: toHash})
+}
+
+// cleanParent forces the parent checkout into a clean state.
+func (rm *goModRepoManager) cleanParent(ctx context.Context) error {
+	if _, err := rm.parentRepo.Git(ctx, "clean", "-d", "-f", "-f"); err != nil {
+		return err
+	}
+	if _, err := rm.parentRepo.Git(ctx, "checkout", fmt.Sprintf("origin/%s", rm.parentBranch), "-f"); err != nil {
+		return err
+	}
+	_, _ = rm.parentRepo.Git(ctx, "branch", "-D", ROLL_BRANCH)
+	return nil
+}
+
+// changedFiles returns the contents of the files which differ from the given
+// base commit in the parent checkout, keyed by path relative to the root of
+// the parent repo. Deleted files have empty contents.
+func (rm *goModRepoManager) changedFiles(ctx context.Context, baseCommit string) (map[string]string, error) {
+	output, err := rm.parentRepo.Git(ctx, "diff", "--name-only", baseCommit)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := rm.parentRepo.Git(ctx, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	rv := map[string]string{}
+	for _, f := range append(strings.Fields(output), strings.Fields(untracked)...) {
+		contents, err := ioutil.ReadFile(filepath.Join(rm.parentRepo.Dir(), f))
+		if os.IsNotExist(err) {
+			rv[f] = ""
+		} else if err != nil {
+			return nil, err
+		} else {
+			rv[f] = string(contents)
+		}
+	}
+	return rv, nil
+}
+
+// See documentation for RepoManager interface.
+func (rm *goModRepoManager) CreateNewRoll(ctx context.Context, from, to *revision.Revision, rolling []*revision.Revision, emails []string, cqExtraTrybots string, dryRun bool) (int64, error) {
+	rm.repoMtx.Lock()
+	defer rm.repoMtx.Unlock()
+
+	// Clean the checkout, get onto a fresh branch.
+	if err := rm.cleanParent(ctx); err != nil {
+		return 0, err
+	}
+	baseCommit, err := rm.parentRepo.RevParse(ctx, fmt.Sprintf("origin/%s", rm.parentBranch))
+	if err != nil {
+		return 0, err
+	}
+	baseCommit = strings.TrimSpace(baseCommit)
+	if _, err := rm.parentRepo.Git(ctx, "checkout", "-b", ROLL_BRANCH, "-t", fmt.Sprintf("origin/%s", rm.parentBranch), "-f"); err != nil {
+		return 0, err
+	}
+	defer func() {
+		util.LogErr(rm.cleanParent(ctx))
+	}()
+	if !rm.local {
+		if _, err := rm.parentRepo.Git(ctx, "config", "user.name", rm.codereview.UserName()); err != nil {
+			return 0, err
+		}
+		if _, err := rm.parentRepo.Git(ctx, "config", "user.email", rm.codereview.UserEmail()); err != nil {
+			return 0, err
+		}
+	}
+
+	// Roll the dependency.
+	if _, err := exec.RunCommand(ctx, &exec.Command{
+		Dir:        filepath.Join(rm.parentRepo.Dir(), rm.goModDir),
+		Env:        rm.goEnv,
+		InheritEnv: true,
+		Name:       rm.goExc,
+		Args:       []string{"get", "-d", fmt.Sprintf("%s@%s", rm.childPath, to.Id)},
+	}); err != nil {
+		return 0, fmt.Errorf("Failed to update %s: %s", GO_MOD_FILE, err)
+	}
+
+	// Build the commit message, including the commit log of the module.
+	log, err := rm.getCommitLog(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	commitMsg, err := rm.buildCommitMsg(&CommitMsgVars{
+		ChildPath:      rm.childPath,
+		ChildRepo:      rm.childRepoUrl,
+		CqExtraTrybots: cqExtraTrybots,
+		Reviewers:      emails,
+		Revisions:      log,
+		RollingFrom:    from,
+		RollingTo:      to,
+		ServerURL:      rm.serverURL,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Run the pre-upload steps.
+	for _, s := range rm.preUploadSteps {
+		if err := s(ctx, nil, rm.httpClient, rm.parentRepo.Dir()); err != nil {
+			return 0, fmt.Errorf("Failed pre-upload step: %s", err)
+		}
+	}
+
+	if rm.githubClient != nil {
+		return rm.uploadToGithub(ctx, commitMsg, dryRun)
+	}
+	if rm.gitlabClient != nil {
+		return rm.uploadToGitlab(ctx, commitMsg)
+	}
+	return rm.uploadToGerrit(ctx, commitMsg, baseCommit, emails, dryRun)
+}
+
+// uploadToGerrit creates a Gerrit change containing the modified files in the
+// parent checkout.
+func (rm *goModRepoManager) uploadToGerrit(ctx context.Context, commitMsg, baseCommit string, emails []string, dryRun bool) (int64, error) {
+	changes, err := rm.changedFiles(ctx, baseCommit)
+	if err != nil {
+		return 0, err
+	}
+	if len(changes) == 0 {
+		return 0, fmt.Errorf("No changes to %s; is the roll already in progress?", GO_MOD_FILE)
+	}
+	ci, err := gerrit.CreateAndEditChange(ctx, rm.g, rm.gerritConfig.Project, rm.parentBranch, commitMsg, baseCommit, func(ctx context.Context, g gerrit.GerritInterface, ci *gerrit.ChangeInfo) error {
+		for file, contents := range changes {
+			if contents == "" {
+				if err := g.DeleteFile(ctx, ci, file); err != nil {
+					return fmt.Errorf("Failed to delete %s file: %s", file, err)
+				}
+			} else {
+				if err := g.EditFile(ctx, ci, file, contents); err != nil {
+					return fmt.Errorf("Failed to edit %s file: %s", file, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if ci != nil {
+			if err2 := rm.g.Abandon(ctx, ci, "Failed to create roll CL"); err2 != nil {
+				return 0, fmt.Errorf("Failed to create roll with: %s\nAnd failed to abandon the change with: %s", err, err2)
+			}
+		}
+		return 0, err
+	}
+
+	// Mark the change as ready for review, if necessary.
+	if err := rm.unsetWIP(ctx, ci, 0); err != nil {
+		return 0, err
+	}
+
+	// Set the CQ bit as appropriate.
+	labels := rm.g.Config().SetCqLabels
+	if dryRun {
+		labels = rm.g.Config().SetDryRunLabels
+	}
+	labels = gerrit.MergeLabels(labels, rm.g.Config().SelfApproveLabels)
+	if err = rm.g.SetReview(ctx, ci, "", labels, emails); err != nil {
+		return 0, fmt.Errorf("Failed to set review: %s", err)
+	}
+
+	// Manually submit if necessary.
+	if !rm.g.Config().HasCq {
+		if err := rm.g.Submit(ctx, ci); err != nil {
+			return 0, fmt.Errorf("Failed to submit: %s", err)
+		}
+	}
+	return ci.Issue, nil
+}
+
+// uploadToGithub commits the modified files in the parent checkout, pushes
+// them to the roller's fork of the parent repo, and creates a pull request.
+func (rm *goModRepoManager) uploadToGithub(ctx context.Context, commitMsg string, dryRun bool) (int64, error) {
+	if _, err := rm.parentRepo.Git(ctx, "add", "-A"); err != nil {
+		return 0, err
+	}
+	if _, err := rm.parentRepo.Git(ctx, "commit", "-m", commitMsg); err != nil {
+		return 0, fmt.Errorf("Failed to commit; is the roll already in progress? %s", err)
+	}
+	if _, err := rm.parentRepo.Git(ctx, "push", GO_MOD_GITHUB_FORK_REMOTE_NAME, ROLL_BRANCH, "-f"); err != nil {
+		return 0, err
+	}
+
+	// Use the first line of the commit message as the title of the pull
+	// request and the rest as its description, truncated because the
+	// GitHub API cannot handle large descriptions.
+	commitMsgLines := strings.Split(commitMsg, "\n")
+	title := commitMsgLines[0]
+	descComment := commitMsgLines[1:]
+	if len(commitMsgLines) > 50 {
+		descComment = append(commitMsgLines[1:50], "...")
+	}
+	headBranch := fmt.Sprintf("%s:%s", rm.codereview.UserName(), ROLL_BRANCH)
+	pr, err := rm.githubClient.CreatePullRequest(title, rm.parentBranch, headBranch, strings.Join(descComment, "\n"))
+	if err != nil {
+		return 0, err
+	}
+	sklog.Infof("Created pull request %d", pr.GetNumber())
+
+	// Add appropriate label to the pull request.
+	if !dryRun {
+		if err := rm.githubClient.AddLabel(pr.GetNumber(), github.WAITING_FOR_GREEN_TREE_LABEL); err != nil {
+			return 0, err
+		}
+	}
+	return int64(pr.GetNumber()), nil
+}
+
+// uploadToGitlab commits the modified files in the parent checkout, pushes
+// them to a branch of the parent repo, and creates a merge request. The
+// CodeReview takes care of merging the merge request when its pipeline
+// succeeds, unless the roll is a dry run.
+func (rm *goModRepoManager) uploadToGitlab(ctx context.Context, commitMsg string) (int64, error) {
+	if _, err := rm.parentRepo.Git(ctx, "add", "-A"); err != nil {
+		return 0, err
+	}
+	if _, err := rm.parentRepo.Git(ctx, "commit", "-m", commitMsg); err != nil {
+		return 0, fmt.Errorf("Failed to commit; is the roll already in progress? %s", err)
+	}
+	if _, err := rm.parentRepo.Git(ctx, "push", "origin", ROLL_BRANCH, "-f"); err != nil {
+		return 0, err
+	}
+
+	// Use the first line of the commit message as the title of the merge
+	// request and the rest as its description.
+	commitMsgLines := strings.SplitN(commitMsg, "\n", 2)
+	desc := ""
+	if len(commitMsgLines) > 1 {
+		desc = strings.TrimSpace(commitMsgLines[1])
+	}
+	mr, err := rm.gitlabClient.CreateMergeRequest(ctx, &gitlab.CreateMergeRequestOptions{
+		SourceBranch:       ROLL_BRANCH,
+		TargetBranch:       rm.parentBranch,
+		Title:              commitMsgLines[0],
+		Description:        desc,
+		RemoveSourceBranch: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	sklog.Infof("Created merge request %d", mr.IID)
+	return mr.IID, nil
+}
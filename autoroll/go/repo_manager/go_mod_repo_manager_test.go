@@ -0,0 +1,349 @@
+package repo_manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	github_api "github.com/google/go-github/v29/github"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/autoroll/go/codereview"
+	"go.skia.org/infra/go/exec"
+	"go.skia.org/infra/go/gerrit"
+	"go.skia.org/infra/go/gerrit/mocks"
+	"go.skia.org/infra/go/git"
+	git_testutils "go.skia.org/infra/go/git/testutils"
+	"go.skia.org/infra/go/github"
+	"go.skia.org/infra/go/gitlab"
+	gitlab_testutils "go.skia.org/infra/go/gitlab/testutils"
+	"go.skia.org/infra/go/mockhttpclient"
+	"go.skia.org/infra/go/testutils"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+const (
+	goModChildPath = "example.com/child"
+	goModTmpl      = `module example.com/parent
+
+go 1.12
+
+require (
+	example.com/child %s
+	other.com/dep v1.0.0 // indirect
+)
+`
+)
+
+func goModCfg() *GoModRepoManagerConfig {
+	return &GoModRepoManagerConfig{
+		CommonRepoManagerConfig: CommonRepoManagerConfig{
+			ChildBranch:  "master",
+			ChildPath:    goModChildPath,
+			IncludeLog:   true,
+			ParentBranch: "master",
+			ParentRepo:   "https://fake.parent",
+		},
+		ChildRepo: "https://fake.child",
+	}
+}
+
+func TestGoModConfigValidation(t *testing.T) {
+	unittest.SmallTest(t)
+
+	require.NoError(t, goModCfg().Validate())
+
+	cfg := goModCfg()
+	cfg.ChildRepo = ""
+	require.EqualError(t, cfg.Validate(), "ChildRepo is required.")
+
+	cfg = goModCfg()
+	cfg.GoModDir = "../elsewhere"
+	require.EqualError(t, cfg.Validate(), "GoModDir must be a relative path within the parent repo.")
+
+	cfg = goModCfg()
+	cfg.ParentRepo = ""
+	require.EqualError(t, cfg.Validate(), "ParentRepo is required.")
+}
+
+func TestFindModuleVersion(t *testing.T) {
+	unittest.SmallTest(t)
+
+	goMod := fmt.Sprintf(goModTmpl, "v1.2.3") + "\nrequire single.com/mod v0.1.0\n"
+	v, err := findModuleVersion(goMod, goModChildPath)
+	require.NoError(t, err)
+	require.Equal(t, "v1.2.3", v)
+	v, err = findModuleVersion(goMod, "other.com/dep")
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", v)
+	v, err = findModuleVersion(goMod, "single.com/mod")
+	require.NoError(t, err)
+	require.Equal(t, "v0.1.0", v)
+	_, err = findModuleVersion(goMod, "missing.com/mod")
+	require.EqualError(t, err, "Unable to find missing.com/mod in go.mod")
+}
+
+func TestGoModVersions(t *testing.T) {
+	unittest.SmallTest(t)
+
+	for _, v := range []string{
+		"v0.0.0-20200102150405-abcdef123456",
+		"v1.2.4-0.20200102150405-abcdef123456",
+		"v1.2.3-pre.0.20200102150405-abcdef123456",
+		"v2.0.0-20200102150405-abcdef123456+incompatible",
+	} {
+		m := pseudoVersionRegex.FindStringSubmatch(v)
+		require.NotNil(t, m, v)
+		require.Equal(t, "abcdef123456", m[1])
+	}
+	require.False(t, pseudoVersionRegex.MatchString("v1.2.3"))
+
+	v, ok := parseSemVer("v1.22.3")
+	require.True(t, ok)
+	require.Equal(t, [3]int{1, 22, 3}, v)
+	_, ok = parseSemVer("v1.2.3-rc1")
+	require.False(t, ok)
+	_, ok = parseSemVer("1.2.3")
+	require.False(t, ok)
+
+	require.True(t, validMajorVersion("example.com/mod", 0))
+	require.True(t, validMajorVersion("example.com/mod", 1))
+	require.False(t, validMajorVersion("example.com/mod", 2))
+	require.True(t, validMajorVersion("example.com/mod/v2", 2))
+	require.False(t, validMajorVersion("example.com/mod/v2", 3))
+}
+
+// goModPush records a faked push from the parent checkout.
+type goModPush struct {
+	Args      []string
+	CommitMsg string
+	GoMod     string
+}
+
+// setupGoMod creates a goModRepoManager which uploads rolls to GitHub or GitLab
+// if the corresponding client is provided, and to Gerrit otherwise.
+func setupGoMod(t *testing.T, cfg *GoModRepoManagerConfig, githubClient *github.GitHub, gitlabClient *gitlab.GitLab) (context.Context, *goModRepoManager, *git_testutils.GitBuilder, []string, string, *mocks.GerritInterface, *goModPush, func()) {
+	unittest.LargeTest(t)
+
+	wd, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Create the child repo, with some tagged releases.
+	child := git_testutils.GitInit(t, ctx)
+	childCommits := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		childCommits = append(childCommits, child.CommitGen(ctx, "somefile.txt"))
+	}
+	child.Git(ctx, "tag", "v1.0.0", childCommits[1])
+	child.Git(ctx, "tag", "v1.1.0", childCommits[3])
+	child.Git(ctx, "tag", "v1.2.0-rc1", childCommits[4])
+	child.Git(ctx, "tag", "v2.0.0", childCommits[4])
+
+	// Create the parent repo, which requires a pseudo-version of the child.
+	parent := git_testutils.GitInit(t, ctx)
+	parent.Add(ctx, GO_MOD_FILE, fmt.Sprintf(goModTmpl, "v0.0.0-20200102150405-"+childCommits[0][:12]))
+	parent.Commit(ctx)
+	parentMaster, err := git.GitDir(parent.Dir()).RevParse(ctx, "HEAD")
+	require.NoError(t, err)
+
+	g := &mocks.GerritInterface{}
+	g.On("GetUserEmail", mock.Anything).Return(mockUser, nil)
+
+	// "go get" updates the version in go.mod. Pushes to remotes are
+	// faked; we record what would have been pushed.
+	pushed := &goModPush{}
+	mockRun := &exec.CommandCollector{}
+	mockRun.SetDelegateRun(func(ctx context.Context, cmd *exec.Command) error {
+		if cmd.Name == "go" {
+			require.Equal(t, "get", cmd.Args[0])
+			version := strings.SplitN(cmd.Args[2], "@", 2)[1]
+			return ioutil.WriteFile(filepath.Join(cmd.Dir, GO_MOD_FILE), []byte(fmt.Sprintf(goModTmpl, version)), 0644)
+		}
+		if strings.Contains(cmd.Name, "git") && cmd.Args[0] == "push" {
+			// Use a fresh context to run git for real.
+			co := git.GitDir(cmd.Dir)
+			msg, err := co.Git(context.Background(), "log", "-n1", "--format=%B", ROLL_BRANCH)
+			require.NoError(t, err)
+			goMod, err := co.Git(context.Background(), "show", fmt.Sprintf("%s:%s", ROLL_BRANCH, GO_MOD_FILE))
+			require.NoError(t, err)
+			pushed.Args = cmd.Args
+			pushed.CommitMsg = strings.TrimSpace(msg)
+			pushed.GoMod = goMod
+			return nil
+		}
+		return exec.DefaultRun(ctx, cmd)
+	})
+	ctx = exec.NewContext(ctx, mockRun.Run)
+
+	cfg.ChildRepo = child.RepoUrl()
+	cfg.ParentRepo = parent.RepoUrl()
+	var cr codereview.CodeReview
+	if githubClient != nil {
+		cr = githubCR(t, githubClient)
+	} else if gitlabClient != nil {
+		cr, err = (&codereview.GitlabConfig{
+			URL:     gitlabClient.URL,
+			Project: gitlabClient.Project,
+		}).Init(nil, nil, gitlabClient)
+		require.NoError(t, err)
+	} else {
+		cr = gerritCR(t, g)
+	}
+	rm, err := NewGoModRepoManager(ctx, cfg, wd, g, githubClient, gitlabClient, "fake.server.com", nil, cr, true)
+	require.NoError(t, err)
+
+	// Rolls uploaded to GitHub and GitLab are committed locally, which
+	// requires an identity. This is normally configured by the RepoManager
+	// when not running locally.
+	_, err = rm.(*goModRepoManager).parentRepo.Git(ctx, "config", "user.name", cr.UserName())
+	require.NoError(t, err)
+	_, err = rm.(*goModRepoManager).parentRepo.Git(ctx, "config", "user.email", cr.UserEmail())
+	require.NoError(t, err)
+
+	cleanup := func() {
+		testutils.RemoveAll(t, wd)
+		child.Cleanup()
+		parent.Cleanup()
+	}
+	return ctx, rm.(*goModRepoManager), child, childCommits, strings.TrimSpace(parentMaster), g, pushed, cleanup
+}
+
+func TestGoModRepoManagerUpdate(t *testing.T) {
+	ctx, rm, _, childCommits, _, _, _, cleanup := setupGoMod(t, goModCfg(), nil, nil)
+	defer cleanup()
+
+	lastRollRev, tipRev, notRolledRevs, err := rm.Update(ctx)
+	require.NoError(t, err)
+	require.Equal(t, childCommits[0], lastRollRev.Id)
+	require.Equal(t, childCommits[len(childCommits)-1], tipRev.Id)
+	require.Len(t, notRolledRevs, len(childCommits)-1)
+
+	rev, err := rm.GetRevision(ctx, "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", rev.Id)
+	rev, err = rm.GetRevision(ctx, childCommits[2])
+	require.NoError(t, err)
+	require.Equal(t, childCommits[2], rev.Id)
+}
+
+func TestGoModRepoManagerUpdateTags(t *testing.T) {
+	cfg := goModCfg()
+	cfg.RollToTags = true
+	ctx, rm, _, _, _, _, _, cleanup := setupGoMod(t, cfg, nil, nil)
+	defer cleanup()
+
+	// Pre-release and incompatible major versions are ignored.
+	lastRollRev, tipRev, notRolledRevs, err := rm.Update(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "v1.1.0", tipRev.Id)
+	require.Len(t, notRolledRevs, 2)
+	require.Equal(t, "v1.1.0", notRolledRevs[0].Id)
+	require.Equal(t, "v1.0.0", notRolledRevs[1].Id)
+
+	// The commit log between tags is included in the roll.
+	log, err := rm.getCommitLog(ctx, notRolledRevs[1], tipRev)
+	require.NoError(t, err)
+	require.Len(t, log, 2)
+	log, err = rm.getCommitLog(ctx, lastRollRev, notRolledRevs[1])
+	require.NoError(t, err)
+	require.Len(t, log, 1)
+}
+
+func TestGoModRepoManagerCreateNewRoll(t *testing.T) {
+	ctx, rm, _, childCommits, parentMaster, g, _, cleanup := setupGoMod(t, goModCfg(), nil, nil)
+	defer cleanup()
+
+	lastRollRev, tipRev, notRolledRevs, err := rm.Update(ctx)
+	require.NoError(t, err)
+
+	ci := &gerrit.ChangeInfo{
+		Id:    "123",
+		Issue: 123,
+	}
+	g.On("Config").Return(gerrit.CONFIG_CHROMIUM)
+	g.On("CreateChange", mock.Anything, "skia", "master", mock.Anything, parentMaster).Return(ci, nil)
+	var commitMsg string
+	g.On("SetCommitMessage", mock.Anything, ci, mock.Anything).Run(func(args mock.Arguments) {
+		commitMsg = args.String(2)
+	}).Return(nil)
+	g.On("EditFile", mock.Anything, ci, GO_MOD_FILE, fmt.Sprintf(goModTmpl, tipRev.Id)).Return(nil)
+	g.On("PublishChangeEdit", mock.Anything, ci).Return(nil)
+	g.On("GetIssueProperties", mock.Anything, int64(123)).Return(ci, nil)
+	g.On("SetReview", mock.Anything, ci, "", gerrit.MergeLabels(gerrit.CONFIG_CHROMIUM.SetCqLabels, gerrit.CONFIG_CHROMIUM.SelfApproveLabels), emails).Return(nil)
+
+	issue, err := rm.CreateNewRoll(ctx, lastRollRev, tipRev, notRolledRevs, emails, "", false)
+	require.NoError(t, err)
+	require.Equal(t, int64(123), issue)
+	g.AssertExpectations(t)
+
+	require.True(t, strings.HasPrefix(commitMsg, fmt.Sprintf("Roll %s %s..%s (%d commits)", goModChildPath, lastRollRev.Id[:12], tipRev.Id[:12], len(childCommits)-1)), commitMsg)
+	require.Contains(t, commitMsg, fmt.Sprintf("go get -d %s@%s", goModChildPath, tipRev.Id[:12]))
+	for _, rev := range notRolledRevs {
+		require.Contains(t, commitMsg, rev.Description)
+	}
+}
+
+func TestGoModRepoManagerCreateNewRollGithub(t *testing.T) {
+	g, urlMock := setupFakeGithub(t, nil)
+	ctx, rm, _, childCommits, _, _, pushed, cleanup := setupGoMod(t, goModCfg(), g, nil)
+	defer cleanup()
+
+	lastRollRev, tipRev, notRolledRevs, err := rm.Update(ctx)
+	require.NoError(t, err)
+
+	// Mock the creation of the pull request. The roll is not a dry run, so
+	// the pull request is labeled as waiting for the tree to be green; the
+	// issue endpoints for that are mocked by setupFakeGithub.
+	serializedPull, err := json.Marshal(&github_api.PullRequest{
+		Number: &testPullNumber,
+	})
+	require.NoError(t, err)
+	md := mockhttpclient.MockPostDialogueWithResponseCode("application/json", mockhttpclient.DONT_CARE_REQUEST, serializedPull, http.StatusCreated)
+	urlMock.MockOnce(githubApiUrl+"/repos/superman/krypton/pulls", md)
+
+	issue, err := rm.CreateNewRoll(ctx, lastRollRev, tipRev, notRolledRevs, emails, "", false)
+	require.NoError(t, err)
+	require.Equal(t, int64(testPullNumber), issue)
+	require.True(t, urlMock.Empty(), urlMock.List())
+
+	// The roll was committed and pushed to the roller's fork.
+	require.Equal(t, []string{"push", GO_MOD_GITHUB_FORK_REMOTE_NAME, ROLL_BRANCH, "-f"}, pushed.Args)
+	require.Equal(t, fmt.Sprintf(goModTmpl, tipRev.Id), pushed.GoMod)
+	require.True(t, strings.HasPrefix(pushed.CommitMsg, fmt.Sprintf("Roll %s %s..%s (%d commits)", goModChildPath, lastRollRev.Id[:12], tipRev.Id[:12], len(childCommits)-1)), pushed.CommitMsg)
+	require.Contains(t, pushed.CommitMsg, fmt.Sprintf("go get -d %s@%s", goModChildPath, tipRev.Id[:12]))
+}
+
+func TestGoModRepoManagerCreateNewRollGitlab(t *testing.T) {
+	f := gitlab_testutils.NewFakeGitLab(t)
+	defer f.Close()
+	ctx, rm, _, childCommits, _, _, pushed, cleanup := setupGoMod(t, goModCfg(), nil, f.GitLab())
+	defer cleanup()
+
+	lastRollRev, tipRev, notRolledRevs, err := rm.Update(ctx)
+	require.NoError(t, err)
+
+	issue, err := rm.CreateNewRoll(ctx, lastRollRev, tipRev, notRolledRevs, emails, "", false)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), issue)
+
+	// The roll was committed and pushed to a branch of the parent repo.
+	require.Equal(t, []string{"push", "origin", ROLL_BRANCH, "-f"}, pushed.Args)
+	require.Equal(t, fmt.Sprintf(goModTmpl, tipRev.Id), pushed.GoMod)
+
+	// A merge request was created from that branch, using the commit
+	// message for its title and description.
+	mr := f.MergeRequest(issue)
+	require.NotNil(t, mr)
+	require.Equal(t, gitlab.MR_STATE_OPENED, mr.State)
+	require.Equal(t, ROLL_BRANCH, mr.SourceBranch)
+	require.Equal(t, "master", mr.TargetBranch)
+	require.Equal(t, fmt.Sprintf("Roll %s %s..%s (%d commits)", goModChildPath, lastRollRev.Id[:12], tipRev.Id[:12], len(childCommits)-1), mr.Title)
+	require.Equal(t, pushed.CommitMsg, mr.Title+"\n\n"+mr.Description)
+	require.Contains(t, mr.Description, fmt.Sprintf("go get -d %s@%s", goModChildPath, tipRev.Id[:12]))
+}
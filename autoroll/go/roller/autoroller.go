@@ -30,6 +30,7 @@ import (
 	"go.skia.org/infra/go/gcs"
 	"go.skia.org/infra/go/gerrit"
 	"go.skia.org/infra/go/github"
+	"go.skia.org/infra/go/gitlab"
 	"go.skia.org/infra/go/human"
 	"go.skia.org/infra/go/metrics2"
 	"go.skia.org/infra/go/notifier"
@@ -61,6 +62,7 @@ type AutoRoller struct {
 	failureThrottle *state_machine.Throttler
 	lastRollRev     *revision.Revision
 	liveness        metrics2.Liveness
+	lkgr            *strategy.LKGR
 	manualRollDB    manual.DB
 	modeHistory     *modes.ModeHistory
 	nextRollRev     *revision.Revision
@@ -89,13 +91,13 @@ type AutoRoller struct {
 }
 
 // NewAutoRoller returns an AutoRoller instance.
-func NewAutoRoller(ctx context.Context, c AutoRollerConfig, emailer *email.GMail, chatBotConfigReader chatbot.ConfigReader, g *gerrit.Gerrit, githubClient *github.GitHub, workdir, recipesCfgFile, serverURL string, gcsClient gcs.GCSClient, client *http.Client, rollerName string, local bool, manualRollDB manual.DB) (*AutoRoller, error) {
+func NewAutoRoller(ctx context.Context, c AutoRollerConfig, emailer *email.GMail, chatBotConfigReader chatbot.ConfigReader, g *gerrit.Gerrit, githubClient *github.GitHub, gitlabClient *gitlab.GitLab, workdir, recipesCfgFile, serverURL string, gcsClient gcs.GCSClient, client *http.Client, rollerName string, local bool, manualRollDB manual.DB) (*AutoRoller, error) {
 	// Validation and setup.
 	if err := c.Validate(); err != nil {
 		return nil, skerr.Wrapf(err, "Failed to validate config")
 	}
 
-	cr, err := c.CodeReview().Init(g, githubClient)
+	cr, err := c.CodeReview().Init(g, githubClient, gitlabClient)
 	if err != nil {
 		return nil, skerr.Wrapf(err, "Failed to initialize code review")
 	}
@@ -120,6 +122,12 @@ func NewAutoRoller(ctx context.Context, c AutoRollerConfig, emailer *email.GMail
 		rm, err = repo_manager.NewGithubCipdDEPSRepoManager(ctx, c.GithubCipdDEPSRepoManager, workdir, rollerName, githubClient, recipesCfgFile, serverURL, client, cr, local)
 	} else if c.GithubDEPSRepoManager != nil {
 		rm, err = repo_manager.NewGithubDEPSRepoManager(ctx, c.GithubDEPSRepoManager, workdir, rollerName, githubClient, recipesCfgFile, serverURL, client, cr, local)
+	} else if c.GoModRepoManager != nil {
+		var gi gerrit.GerritInterface
+		if g != nil {
+			gi = g
+		}
+		rm, err = repo_manager.NewGoModRepoManager(ctx, c.GoModRepoManager, workdir, gi, githubClient, gitlabClient, serverURL, client, cr, local)
 	} else if c.NoCheckoutDEPSRepoManager != nil {
 		rm, err = repo_manager.NewNoCheckoutDEPSRepoManager(ctx, c.NoCheckoutDEPSRepoManager, workdir, g, recipesCfgFile, serverURL, client, cr, local)
 	} else if c.SemVerGCSRepoManager != nil {
@@ -145,8 +153,19 @@ func NewAutoRoller(ctx context.Context, c AutoRollerConfig, emailer *email.GMail
 		}
 		currentStrategy = sh.CurrentStrategy()
 	}
+	var lkgr *strategy.LKGR
+	if c.LKGR != nil {
+		sklog.Info("Creating LKGR.")
+		lkgr, err = strategy.NewLKGR(c.LKGR, client)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "Failed to create LKGR")
+		}
+		if err := lkgr.Update(ctx); err != nil {
+			sklog.Errorf("Failed initial LKGR update: %s", err)
+		}
+	}
 	sklog.Info("Setting strategy.")
-	strat, err := strategy.GetNextRollStrategy(currentStrategy.Strategy)
+	strat, err := strategy.GetNextRollStrategy(currentStrategy.Strategy, lkgr)
 	if err != nil {
 		return nil, skerr.Wrapf(err, "Failed to get next roll strategy")
 	}
@@ -235,6 +254,7 @@ func NewAutoRoller(ctx context.Context, c AutoRollerConfig, emailer *email.GMail
 		failureThrottle: failureThrottle,
 		lastRollRev:     lastRollRev,
 		liveness:        metrics2.NewLiveness("last_autoroll_landed", map[string]string{"roller": c.RollerName}),
+		lkgr:            lkgr,
 		manualRollDB:    manualRollDB,
 		modeHistory:     mh,
 		nextRollRev:     nextRollRev,
@@ -496,6 +516,31 @@ func (r *AutoRoller) GetNextRollRev() *revision.Revision {
 	return r.nextRollRev
 }
 
+// See documentation for state_machine.AutoRollerImpl interface.
+func (r *AutoRoller) GetNotRolledRevs() []*revision.Revision {
+	r.statusMtx.RLock()
+	defer r.statusMtx.RUnlock()
+	return r.notRolledRevs
+}
+
+// See documentation for state_machine.AutoRollerImpl interface.
+func (r *AutoRoller) ShouldBisect() bool {
+	if r.cfg.BisectAfterFailures <= 0 {
+		return false
+	}
+	// Count the consecutive failed rolls, including the active roll. Dry
+	// runs, including those used for bisection, are not counted.
+	nFailed := 0
+	// recent is in reverse chronological order.
+	for _, roll := range r.recent.GetRecentRolls() {
+		if roll.Result != autoroll.ROLL_RESULT_FAILURE {
+			break
+		}
+		nFailed++
+	}
+	return nFailed >= r.cfg.BisectAfterFailures
+}
+
 // See documentation for state_machine.AutoRollerImpl interface.
 func (r *AutoRoller) InRollWindow(t time.Time) bool {
 	return r.timeWindow.Test(t)
@@ -547,9 +592,33 @@ func (r *AutoRoller) UpdateRepos(ctx context.Context) error {
 	if err != nil {
 		return skerr.Wrap(err)
 	}
+	if r.lkgr != nil {
+		// Use the previously-obtained LKGR if the status endpoint is
+		// unavailable; the roller shouldn't stop working as a result.
+		if err := r.lkgr.Update(ctx); err != nil {
+			sklog.Errorf("Failed to update LKGR: %s", err)
+		}
+	}
+	// If bisection found a culprit of failing rolls, roll up to the
+	// revision just before it. Once we've done so, resume normal rolling.
+	candidates := notRolledRevs
+	if culprit := r.sm.Culprit(); culprit != nil {
+		for idx, rev := range notRolledRevs {
+			if rev.Id == culprit.Id {
+				candidates = notRolledRevs[idx+1:]
+				break
+			}
+		}
+	}
 	r.strategyMtx.RLock()
 	defer r.strategyMtx.RUnlock()
-	nextRollRev := r.strategy.GetNextRollRev(notRolledRevs)
+	nextRollRev := r.strategy.GetNextRollRev(candidates)
+	if nextRollRev == nil && len(candidates) < len(notRolledRevs) {
+		nextRollRev = r.strategy.GetNextRollRev(notRolledRevs)
+	}
+	// Some strategies, eg. LKGR, may choose not to roll any of the
+	// candidates; that's not an error.
+	strategyChoseRev := nextRollRev != nil
 	if nextRollRev == nil {
 		nextRollRev = lastRollRev
 	}
@@ -603,6 +672,8 @@ func (r *AutoRoller) UpdateRepos(ctx context.Context) error {
 		if nextRollRev.Id == lastRollRev.Id {
 			if numValid == 0 {
 				sklog.Warningf("There are revisions to roll, but the next roll rev %q equals the last roll rev; all %d not-yet-rolled revisions are invalid.", nextRollRev.Id, len(notRolledRevs))
+			} else if !strategyChoseRev {
+				sklog.Warningf("There are revisions to roll, but the roll strategy chose none of the %d valid roll candidates.", numValid)
 			} else {
 				return skerr.Fmt("There are revisions to roll, but the next roll rev %q equals the last roll rev; at least one revision is a valid roll candidate.", nextRollRev.Id)
 			}
@@ -724,7 +795,7 @@ func (r *AutoRoller) Tick(ctx context.Context) error {
 	}
 	newStrategy := r.strategyHistory.CurrentStrategy().Strategy
 	if oldStrategy != newStrategy {
-		strat, err := strategy.GetNextRollStrategy(newStrategy)
+		strat, err := strategy.GetNextRollStrategy(newStrategy, r.lkgr)
 		if err != nil {
 			return skerr.Wrapf(err, "Failed to get next roll strategy")
 		}
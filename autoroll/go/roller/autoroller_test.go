@@ -2,10 +2,15 @@ package roller
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/autoroll/go/repo_manager"
 	"go.skia.org/infra/autoroll/go/revision"
+	"go.skia.org/infra/autoroll/go/state_machine"
+	"go.skia.org/infra/autoroll/go/strategy"
+	"go.skia.org/infra/go/mockhttpclient"
 	"go.skia.org/infra/go/testutils/unittest"
 )
 
@@ -42,3 +47,63 @@ func TestAutoRollerRolledPast(t *testing.T) {
 	check("5", false)             // tipRev
 	check("some other rev", true) // everything else
 }
+
+// fakeRepoManager is a RepoManager which returns fixed revisions from Update.
+type fakeRepoManager struct {
+	repo_manager.RepoManager
+	lastRollRev   *revision.Revision
+	tipRev        *revision.Revision
+	notRolledRevs []*revision.Revision
+}
+
+// See documentation for RepoManager interface.
+func (rm *fakeRepoManager) Update(context.Context) (*revision.Revision, *revision.Revision, []*revision.Revision, error) {
+	return rm.lastRollRev, rm.tipRev, rm.notRolledRevs, nil
+}
+
+func TestAutoRollerUpdateReposLKGRWaiting(t *testing.T) {
+	unittest.SmallTest(t)
+
+	ctx := context.Background()
+	rev := func(id string) *revision.Revision {
+		return &revision.Revision{Id: id}
+	}
+	const lkgrURL = "https://status.fake/lkgr"
+	urlmock := mockhttpclient.NewURLMock()
+	lkgr, err := strategy.NewLKGR(&strategy.LKGRConfig{
+		URL: lkgrURL,
+	}, urlmock.Client())
+	require.NoError(t, err)
+	rm := &fakeRepoManager{
+		lastRollRev: rev("0"),
+		tipRev:      rev("2"),
+		notRolledRevs: []*revision.Revision{
+			rev("2"),
+			rev("1"),
+		},
+	}
+	r := &AutoRoller{
+		lkgr:     lkgr,
+		rm:       rm,
+		sm:       &state_machine.AutoRollStateMachine{},
+		strategy: strategy.StrategyLKGR(lkgr),
+	}
+
+	// The LKGR is unknown, so the strategy waits. This is not an error; the
+	// next roll rev is the last roll rev.
+	urlmock.MockOnce(lkgrURL, mockhttpclient.MockGetError("Internal Server Error", http.StatusInternalServerError))
+	require.NoError(t, r.UpdateRepos(ctx))
+	require.Equal(t, "0", r.GetNextRollRev().Id)
+	require.Len(t, r.GetNotRolledRevs(), 2)
+
+	// The LKGR is older than any of the not-yet-rolled revisions, so we
+	// still wait.
+	urlmock.MockOnce(lkgrURL, mockhttpclient.MockGetDialogue([]byte("0")))
+	require.NoError(t, r.UpdateRepos(ctx))
+	require.Equal(t, "0", r.GetNextRollRev().Id)
+
+	// The LKGR moves forward; roll to it.
+	urlmock.MockOnce(lkgrURL, mockhttpclient.MockGetDialogue([]byte("1")))
+	require.NoError(t, r.UpdateRepos(ctx))
+	require.Equal(t, "1", r.GetNextRollRev().Id)
+}
@@ -166,6 +166,7 @@ type AutoRollerConfig struct {
 	// Code review settings.
 	Gerrit        *codereview.GerritConfig  `json:"gerrit,omitempty"`
 	Github        *codereview.GithubConfig  `json:"github,omitempty"`
+	Gitlab        *codereview.GitlabConfig  `json:"gitlab,omitempty"`
 	Google3Review *codereview.Google3Config `json:"google3Review,omitempty"`
 
 	// RepoManager configs. Exactly one must be provided.
@@ -178,6 +179,7 @@ type AutoRollerConfig struct {
 	GithubRepoManager            *repo_manager.GithubRepoManagerConfig            `json:"githubRepoManager,omitempty"`
 	GithubCipdDEPSRepoManager    *repo_manager.GithubCipdDEPSRepoManagerConfig    `json:"githubCipdDEPSRepoManager,omitempty"`
 	GithubDEPSRepoManager        *repo_manager.GithubDEPSRepoManagerConfig        `json:"githubDEPSRepoManager,omitempty"`
+	GoModRepoManager             *repo_manager.GoModRepoManagerConfig             `json:"goModRepoManager,omitempty"`
 	Google3RepoManager           *Google3FakeRepoManagerConfig                    `json:"google3,omitempty"`
 	NoCheckoutDEPSRepoManager    *repo_manager.NoCheckoutDEPSRepoManagerConfig    `json:"noCheckoutDEPSRepoManager,omitempty"`
 	SemVerGCSRepoManager         *repo_manager.SemVerGCSRepoManagerConfig         `json:"semVerGCSRepoManager,omitempty"`
@@ -187,9 +189,19 @@ type AutoRollerConfig struct {
 
 	// Optional Fields.
 
+	// If set, when this many consecutive rolls have failed, the roller
+	// bisects the revisions in the failing roll using dry runs to find the
+	// culprit, notifies with message type "culprit found", and then rolls
+	// up to the revision just before the culprit. Bugs may be filed by
+	// whitelisting that message type in a Monorail notifier config.
+	BisectAfterFailures int `json:"bisectAfterFailures,omitempty"`
 	// Comma-separated list of trybots to add to roll CLs, in addition to
 	// the default set of commit queue trybots.
 	CqExtraTrybots []string `json:"cqExtraTrybots,omitempty"`
+	// Configuration for the "lkgr" strategy, which rolls to the newest
+	// revision known to be good according to a status endpoint. If
+	// provided, "lkgr" becomes a valid strategy and is used by default.
+	LKGR *strategy.LKGRConfig `json:"lkgr,omitempty"`
 	// Limit to one successful roll within this time period.
 	MaxRollFrequency string `json:"maxRollFrequency,omitempty"`
 	// Any extra notification systems to be used for this roller.
@@ -238,11 +250,14 @@ func (c *AutoRollerConfig) Validate() error {
 	if c.Github != nil {
 		cr = append(cr, c.Github)
 	}
+	if c.Gitlab != nil {
+		cr = append(cr, c.Gitlab)
+	}
 	if c.Google3Review != nil {
 		cr = append(cr, c.Google3Review)
 	}
 	if len(cr) != 1 {
-		return errors.New("Exactly one of Gerrit, Github, or Google3Review is required.")
+		return errors.New("Exactly one of Gerrit, Github, Gitlab, or Google3Review is required.")
 	}
 	if err := cr[0].Validate(); err != nil {
 		return err
@@ -255,6 +270,10 @@ func (c *AutoRollerConfig) Validate() error {
 	if err := rm.Validate(); err != nil {
 		return err
 	}
+	// Only the GoModRepoManager knows how to upload rolls to GitLab.
+	if c.Gitlab != nil && c.GoModRepoManager == nil {
+		return errors.New("Gitlab is only supported by the GoModRepoManager.")
+	}
 
 	if c.Kubernetes == nil {
 		return errors.New("Kubernetes config is required.")
@@ -269,6 +288,15 @@ func (c *AutoRollerConfig) Validate() error {
 		return errors.New("kubernetes.disk is required for repo managers which use a checkout.")
 	}
 
+	if c.BisectAfterFailures < 0 {
+		return errors.New("BisectAfterFailures must not be negative.")
+	}
+	if c.LKGR != nil {
+		if err := c.LKGR.Validate(); err != nil {
+			return fmt.Errorf("LKGRConfig validation failed: %s", err)
+		}
+	}
+
 	// Verify that the notifier configs are valid.
 	if _, err := arb_notifier.New(context.Background(), "fake", "fake", "fake", nil, nil, nil, c.Notifiers); err != nil {
 		return err
@@ -284,6 +312,9 @@ func (c *AutoRollerConfig) CodeReview() codereview.CodeReviewConfig {
 	if c.Github != nil {
 		return c.Github
 	}
+	if c.Gitlab != nil {
+		return c.Gitlab
+	}
 	if c.Google3Review != nil {
 		return c.Google3Review
 	}
@@ -320,6 +351,9 @@ func (c *AutoRollerConfig) repoManagerConfig() (RepoManagerConfig, error) {
 	if c.GithubDEPSRepoManager != nil {
 		rm = append(rm, c.GithubDEPSRepoManager)
 	}
+	if c.GoModRepoManager != nil {
+		rm = append(rm, c.GoModRepoManager)
+	}
 	if c.Google3RepoManager != nil {
 		rm = append(rm, c.Google3RepoManager)
 	}
@@ -341,6 +375,9 @@ func (c *AutoRollerConfig) DefaultStrategy() string {
 	if err != nil {
 		sklog.Fatalf("Failed to obtain RepoManagerConfig; this should have been caught during validation! %s", err)
 	}
+	if c.LKGR != nil {
+		return strategy.ROLL_STRATEGY_LKGR
+	}
 	return rm.DefaultStrategy()
 }
 
@@ -350,6 +387,9 @@ func (c *AutoRollerConfig) ValidStrategies() []string {
 	if err != nil {
 		sklog.Fatalf("Failed to obtain RepoManagerConfig; this should have been caught during validation! %s", err)
 	}
+	if c.LKGR != nil {
+		return append(rm.ValidStrategies(), strategy.ROLL_STRATEGY_LKGR)
+	}
 	return rm.ValidStrategies()
 }
 
@@ -73,7 +73,7 @@ func TestConfigs(t *testing.T) {
 
 	testErr(func(c *AutoRollerConfig) {
 		c.Gerrit = nil
-	}, "Exactly one of Gerrit, Github, or Google3Review is required.")
+	}, "Exactly one of Gerrit, Github, Gitlab, or Google3Review is required.")
 
 	testErr(func(c *AutoRollerConfig) {
 		c.ParentName = ""
@@ -134,6 +134,14 @@ func TestConfigs(t *testing.T) {
 		}
 	}, "kubernetes.disk is not valid for no-checkout repo managers.")
 
+	testErr(func(c *AutoRollerConfig) {
+		c.Gerrit = nil
+		c.Gitlab = &codereview.GitlabConfig{
+			URL:     "https://gitlab.com",
+			Project: "my/project",
+		}
+	}, "Gitlab is only supported by the GoModRepoManager.")
+
 	// Helper function: create a valid base config, allow the caller to
 	// mutate it, then assert that validation succeeds.
 	testNoErr := func(fn func(c *AutoRollerConfig)) {
@@ -185,6 +193,24 @@ func TestConfigs(t *testing.T) {
 			ChecksWaitFor: []string{"a", "b", "c"},
 		}
 	})
+
+	testNoErr(func(c *AutoRollerConfig) {
+		c.Gerrit = nil
+		c.Gitlab = &codereview.GitlabConfig{
+			URL:     "https://gitlab.com",
+			Project: "my/project",
+		}
+		c.Google3RepoManager = nil
+		c.GoModRepoManager = &repo_manager.GoModRepoManagerConfig{
+			CommonRepoManagerConfig: repo_manager.CommonRepoManagerConfig{
+				ChildBranch:  "master",
+				ChildPath:    "example.com/child",
+				ParentBranch: "master",
+				ParentRepo:   "https://gitlab.com/my/project.git",
+			},
+			ChildRepo: "https://example.com/child.git",
+		}
+	})
 }
 
 func TestConfigSerialization(t *testing.T) {
@@ -0,0 +1,161 @@
+package state_machine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.skia.org/infra/autoroll/go/revision"
+	"go.skia.org/infra/go/gcs"
+	"go.skia.org/infra/go/skerr"
+)
+
+/*
+	Bisection of failing rolls, used to find the child revision which
+	caused the failure.
+*/
+
+// Bisection tracks the search for the child revision which caused a roll to
+// fail. Each step of the search uploads a dry run which rolls from the
+// currently-rolled revision to the midpoint of the remaining range.
+type Bisection struct {
+	// The currently-rolled revision when the bisection started. This is
+	// assumed to be good.
+	From *revision.Revision `json:"from"`
+	// Valid revisions included in the failing roll, in reverse
+	// chronological order. The first is the target of the failing roll.
+	Revisions []*revision.Revision `json:"revisions"`
+	// Index of the oldest revision in Revisions which is known to be bad.
+	Bad int `json:"bad"`
+	// Index of the newest revision in Revisions which is known to be good,
+	// or len(Revisions) if only From is known to be good.
+	Good int `json:"good"`
+	// The first bad revision, set when the bisection is finished.
+	Culprit *revision.Revision `json:"culprit,omitempty"`
+}
+
+// newBisection returns a Bisection which searches the given revisions, in
+// reverse chronological order, the first of which is known to be bad.
+// Invalid revisions cannot be rolled to and are therefore skipped.
+func newBisection(from *revision.Revision, revs []*revision.Revision) *Bisection {
+	valid := make([]*revision.Revision, 0, len(revs))
+	for _, rev := range revs {
+		if rev.InvalidReason == "" {
+			valid = append(valid, rev)
+		}
+	}
+	b := &Bisection{
+		From:      from,
+		Revisions: valid,
+		Bad:       0,
+		Good:      len(valid),
+	}
+	b.maybeFinish()
+	return b
+}
+
+// Done returns true iff the culprit has been found.
+func (b *Bisection) Done() bool {
+	return b.Culprit != nil
+}
+
+// Next returns the revision to try next. Only valid if the bisection is not
+// yet finished.
+func (b *Bisection) Next() *revision.Revision {
+	return b.Revisions[(b.Bad+b.Good)/2]
+}
+
+// LastGood returns the newest revision known to be good.
+func (b *Bisection) LastGood() *revision.Revision {
+	if b.Good < len(b.Revisions) {
+		return b.Revisions[b.Good]
+	}
+	return b.From
+}
+
+// Record the result of a dry run which rolled to the given revision.
+func (b *Bisection) Record(rev *revision.Revision, success bool) error {
+	if b.Done() {
+		return skerr.Fmt("Bisection is already finished")
+	}
+	for idx := b.Bad + 1; idx < b.Good; idx++ {
+		if b.Revisions[idx].Id == rev.Id {
+			if success {
+				b.Good = idx
+			} else {
+				b.Bad = idx
+			}
+			b.maybeFinish()
+			return nil
+		}
+	}
+	return skerr.Fmt("Revision %s is not within the bisection range", rev.Id)
+}
+
+// maybeFinish sets the culprit if there are no revisions left to try.
+func (b *Bisection) maybeFinish() {
+	if b.Good-b.Bad <= 1 && len(b.Revisions) > 0 {
+		b.Culprit = b.Revisions[b.Bad]
+	}
+}
+
+// culpritIn returns true iff the given revisions include the culprit.
+func (b *Bisection) culpritIn(revs []*revision.Revision) bool {
+	if b == nil || b.Culprit == nil {
+		return false
+	}
+	for _, rev := range revs {
+		if rev.Id == b.Culprit.Id {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a human-readable summary of the Bisection.
+func (b *Bisection) String() string {
+	if b.Done() {
+		return fmt.Sprintf("culprit %s; last good %s", b.Culprit, b.LastGood())
+	}
+	return fmt.Sprintf("%d revisions remaining between %s and %s", b.Good-b.Bad-1, b.LastGood(), b.Revisions[b.Bad])
+}
+
+// revisionsInRoll returns the revisions from notRolled which are included in a
+// roll to the given revision, in reverse chronological order.
+func revisionsInRoll(notRolled []*revision.Revision, rollingTo *revision.Revision) []*revision.Revision {
+	for idx, rev := range notRolled {
+		if rev.Id == rollingTo.Id {
+			return notRolled[idx:]
+		}
+	}
+	return nil
+}
+
+// readBisection reads the Bisection from the given file, returning nil if it
+// does not exist.
+func readBisection(ctx context.Context, gcsClient gcs.GCSClient, file string) (*Bisection, error) {
+	exists, err := gcsClient.DoesFileExist(ctx, file)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	} else if !exists {
+		return nil, nil
+	}
+	contents, err := gcsClient.GetFileContents(ctx, file)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	var rv Bisection
+	if err := json.Unmarshal(contents, &rv); err != nil {
+		return nil, skerr.Wrapf(err, "Failed to decode bisection from %s", file)
+	}
+	return &rv, nil
+}
+
+// writeBisection writes the Bisection to the given file.
+func writeBisection(ctx context.Context, gcsClient gcs.GCSClient, file string, b *Bisection) error {
+	contents, err := json.Marshal(b)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	return skerr.Wrap(gcsClient.SetFileContents(ctx, file, gcs.FileWriteOptions{ContentType: "application/json"}, contents))
+}
@@ -0,0 +1,57 @@
+package state_machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/autoroll/go/revision"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+func TestBisection(t *testing.T) {
+	unittest.SmallTest(t)
+
+	from := &revision.Revision{Id: "a"}
+	revs := []*revision.Revision{
+		{Id: "h"},
+		{Id: "g"},
+		{Id: "f", InvalidReason: "no builds"},
+		{Id: "e"},
+		{Id: "d"},
+		{Id: "c"},
+		{Id: "b"},
+	}
+	require.Equal(t, revs[3:], revisionsInRoll(revs, revs[3]))
+	require.Nil(t, revisionsInRoll(revs, from))
+
+	// Invalid revisions are skipped.
+	b := newBisection(from, revs)
+	require.Len(t, b.Revisions, 6)
+	require.False(t, b.Done())
+	require.Equal(t, from, b.LastGood())
+
+	// The culprit is "c".
+	require.Equal(t, "d", b.Next().Id)
+	require.NoError(t, b.Record(b.Next(), false))
+	require.Equal(t, "c", b.Next().Id)
+	require.NoError(t, b.Record(b.Next(), false))
+	require.Equal(t, "b", b.Next().Id)
+	require.NoError(t, b.Record(b.Next(), true))
+	require.True(t, b.Done())
+	require.Equal(t, "c", b.Culprit.Id)
+	require.Equal(t, "b", b.LastGood().Id)
+	require.True(t, b.culpritIn(revs))
+	require.False(t, b.culpritIn(revs[:4]))
+	require.Contains(t, b.Record(revs[0], true).Error(), "Bisection is already finished")
+
+	// Results outside of the remaining range are rejected.
+	b = newBisection(from, revs)
+	require.NoError(t, b.Record(b.Next(), true))
+	require.Contains(t, b.Record(revs[5], false).Error(), "Revision c is not within the bisection range")
+
+	// A single revision is its own culprit.
+	b = newBisection(from, revs[:1])
+	require.True(t, b.Done())
+	require.Equal(t, "h", b.Culprit.Id)
+	require.Equal(t, from, b.LastGood())
+}
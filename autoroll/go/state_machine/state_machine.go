@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"go.skia.org/infra/autoroll/go/modes"
@@ -40,6 +41,8 @@ const (
 	S_DRY_RUN_SAFETY_THROTTLED     = "dry run safety throttled"
 	S_STOPPED                      = "stopped"
 	S_CURRENT_ROLL_MISSING         = "current roll missing"
+	S_BISECT_IDLE                  = "bisect idle"
+	S_BISECT_ACTIVE                = "bisect active"
 
 	// Transition function names.
 	F_NOOP                       = "no-op"
@@ -59,6 +62,10 @@ const (
 	F_NOTIFY_FAILURE_THROTTLE    = "notify failure throttled"
 	F_NOTIFY_SAFETY_THROTTLE     = "notify safety throttled"
 	F_ERROR_CURRENT_ROLL_MISSING = "error: current roll missing"
+	F_START_BISECT               = "start bisection"
+	F_UPLOAD_BISECT_DRY_RUN      = "upload bisection dry run"
+	F_RECORD_BISECT_RESULT       = "record bisection result"
+	F_END_BISECT                 = "end bisection"
 
 	// Maximum number of no-op transitions to perform at once. This is an
 	// arbitrary limit just to keep us from performing an unbounded number
@@ -129,6 +136,10 @@ type AutoRollerImpl interface {
 	// Return the currently-rolled revision of the sub-project.
 	GetCurrentRev() *revision.Revision
 
+	// Return the not-yet-rolled revisions of the sub-project, in reverse
+	// chronological order.
+	GetNotRolledRevs() []*revision.Revision
+
 	// Return the next revision of the sub-project which we want to roll.
 	// This is the same as GetCurrentRev when the sub-project is up-to-date.
 	GetNextRollRev() *revision.Revision
@@ -147,6 +158,10 @@ type AutoRollerImpl interface {
 	// many CLs within a time period.
 	SafetyThrottle() *Throttler
 
+	// Return true iff we should bisect to find the culprit of the failed
+	// active roll, eg. because rolls have failed repeatedly.
+	ShouldBisect() bool
+
 	// Return a Throttler indicating whether we have successfully rolled too
 	// many times within a time period.
 	SuccessThrottle() *Throttler
@@ -162,6 +177,11 @@ type AutoRollerImpl interface {
 type AutoRollStateMachine struct {
 	a AutoRollerImpl
 	s *state_machine.StateMachine
+
+	bisection     *Bisection
+	bisectionFile string
+	bisectionMtx  sync.RWMutex
+	gcs           gcs.GCSClient
 }
 
 // Throttler determines whether we should be throttled.
@@ -230,9 +250,17 @@ func (t *Throttler) ThrottledUntil() time.Time {
 
 // New returns a StateMachine for the autoroller.
 func New(ctx context.Context, impl AutoRollerImpl, n *notifier.AutoRollNotifier, gcsClient gcs.GCSClient, gcsPrefix string) (*AutoRollStateMachine, error) {
+	bisectionFile := gcsPrefix + "/bisection"
+	bisection, err := readBisection(ctx, gcsClient, bisectionFile)
+	if err != nil {
+		return nil, err
+	}
 	s := &AutoRollStateMachine{
-		a: impl,
-		s: nil, // Filled in later.
+		a:             impl,
+		s:             nil, // Filled in later.
+		bisection:     bisection,
+		bisectionFile: bisectionFile,
+		gcs:           gcsClient,
 	}
 
 	b := state_machine.NewBuilder()
@@ -386,6 +414,64 @@ func New(ctx context.Context, impl AutoRollerImpl, n *notifier.AutoRollNotifier,
 		sklog.Error("State machine could not obtain current roll; transitioning back to idle state. Was the roller interrupted?")
 		return nil
 	})
+	f(F_START_BISECT, func(ctx context.Context, roll RollCLImpl) error {
+		bisection := newBisection(s.a.GetCurrentRev(), revisionsInRoll(s.a.GetNotRolledRevs(), roll.RollingTo()))
+		if err := s.setBisection(ctx, bisection); err != nil {
+			return err
+		}
+		if err := roll.Close(ctx, autoroll.ROLL_RESULT_FAILURE, "Commit queue failed repeatedly; bisecting to find the culprit."); err != nil {
+			return err
+		}
+		n.SendIssueUpdate(ctx, roll.IssueID(), roll.IssueURL(), fmt.Sprintf("This CL was abandoned because the commit queue failed repeatedly. The roller will bisect the %d revisions in this roll to find the culprit.", len(bisection.Revisions)))
+		return nil
+	})
+	b.F(F_UPLOAD_BISECT_DRY_RUN, func(ctx context.Context) error {
+		if err := s.a.SafetyThrottle().Inc(ctx); err != nil {
+			return err
+		}
+		roll, err := s.a.UploadNewRoll(ctx, s.a.GetCurrentRev(), s.Bisection().Next(), true)
+		if err != nil {
+			n.SendRollCreationFailed(ctx, err)
+			return err
+		}
+		n.SendIssueUpdate(ctx, roll.IssueID(), roll.IssueURL(), fmt.Sprintf("The roller has uploaded a dry run to bisect failing rolls: %s", roll.IssueURL()))
+		return nil
+	})
+	f(F_RECORD_BISECT_RESULT, func(ctx context.Context, roll RollCLImpl) error {
+		bisection := s.Bisection()
+		success := roll.IsDryRunSuccess()
+		if err := bisection.Record(roll.RollingTo(), success); err != nil {
+			return err
+		}
+		if err := s.setBisection(ctx, bisection); err != nil {
+			return err
+		}
+		result := autoroll.ROLL_RESULT_DRY_RUN_FAILURE
+		if success {
+			result = autoroll.ROLL_RESULT_DRY_RUN_SUCCESS
+		}
+		return roll.Close(ctx, result, fmt.Sprintf("Bisection dry run finished; %s.", bisection))
+	})
+	b.F(F_END_BISECT, func(ctx context.Context) error {
+		if roll := s.a.GetActiveRoll(); roll != nil && !roll.IsClosed() {
+			if err := roll.Close(ctx, autoroll.ROLL_RESULT_FAILURE, "Bisection was interrupted; closing the active roll."); err != nil {
+				return err
+			}
+		}
+		bisection := s.Bisection()
+		if bisection == nil || !bisection.Done() {
+			// Discard the incomplete bisection, so that we don't
+			// pick it up again.
+			sklog.Warningf("Bisection was interrupted before finding a culprit.")
+			if err := s.setBisection(ctx, nil); err != nil {
+				return err
+			}
+		} else {
+			n.SendCulpritFound(ctx, bisection.Culprit, bisection.LastGood())
+		}
+		// The culprit may change the next roll revision.
+		return s.a.UpdateRepos(ctx)
+	})
 
 	// States and transitions.
 
@@ -467,6 +553,18 @@ func New(ctx context.Context, impl AutoRollerImpl, n *notifier.AutoRollNotifier,
 	b.T(S_DRY_RUN_SAFETY_THROTTLED, S_DRY_RUN_IDLE, F_NOOP)
 	b.T(S_DRY_RUN_SAFETY_THROTTLED, S_DRY_RUN_SAFETY_THROTTLED, F_UPDATE_REPOS)
 
+	// Bisection states.
+	b.T(S_NORMAL_FAILURE, S_BISECT_IDLE, F_START_BISECT)
+	b.T(S_BISECT_IDLE, S_BISECT_IDLE, F_UPDATE_REPOS)
+	b.T(S_BISECT_IDLE, S_BISECT_ACTIVE, F_UPLOAD_BISECT_DRY_RUN)
+	b.T(S_BISECT_IDLE, S_NORMAL_IDLE, F_END_BISECT)
+	b.T(S_BISECT_IDLE, S_STOPPED, F_END_BISECT)
+	b.T(S_BISECT_ACTIVE, S_BISECT_ACTIVE, F_UPDATE_ROLL)
+	b.T(S_BISECT_ACTIVE, S_BISECT_IDLE, F_RECORD_BISECT_RESULT)
+	b.T(S_BISECT_ACTIVE, S_NORMAL_IDLE, F_END_BISECT)
+	b.T(S_BISECT_ACTIVE, S_STOPPED, F_END_BISECT)
+	b.T(S_BISECT_ACTIVE, S_CURRENT_ROLL_MISSING, F_NOOP)
+
 	// Error; current roll is missing.
 	b.T(S_CURRENT_ROLL_MISSING, S_NORMAL_IDLE, F_ERROR_CURRENT_ROLL_MISSING)
 
@@ -579,6 +677,9 @@ func (s *AutoRollStateMachine) GetNext(ctx context.Context) (string, error) {
 		if currentRoll.IsClosed() {
 			return S_NORMAL_IDLE, nil
 		}
+		if s.shouldBisect(currentRoll) {
+			return S_BISECT_IDLE, nil
+		}
 		throttle := s.a.FailureThrottle()
 		if err := throttle.Inc(ctx); err != nil {
 			return "", err
@@ -740,6 +841,37 @@ func (s *AutoRollStateMachine) GetNext(ctx context.Context) (string, error) {
 			return S_DRY_RUN_SAFETY_THROTTLED, nil
 		}
 		return S_DRY_RUN_IDLE, nil
+	case S_BISECT_IDLE:
+		if desiredMode == modes.MODE_STOPPED {
+			return S_STOPPED, nil
+		}
+		bisection := s.Bisection()
+		if bisection == nil || bisection.Done() {
+			return S_NORMAL_IDLE, nil
+		}
+		if s.a.GetCurrentRev().Id != bisection.From.Id {
+			// Someone rolled manually while we were bisecting; the
+			// results no longer apply.
+			return S_NORMAL_IDLE, nil
+		}
+		if s.a.SafetyThrottle().IsThrottled() {
+			return S_BISECT_IDLE, nil
+		}
+		return S_BISECT_ACTIVE, nil
+	case S_BISECT_ACTIVE:
+		if currentRoll == nil {
+			return S_CURRENT_ROLL_MISSING, nil
+		}
+		if desiredMode == modes.MODE_STOPPED {
+			return S_STOPPED, nil
+		}
+		if currentRoll.IsClosed() {
+			// Someone manually closed the roll.
+			return S_NORMAL_IDLE, nil
+		} else if currentRoll.IsDryRunFinished() {
+			return S_BISECT_IDLE, nil
+		}
+		return S_BISECT_ACTIVE, nil
 	case S_CURRENT_ROLL_MISSING:
 		return S_NORMAL_IDLE, nil
 	default:
@@ -806,3 +938,52 @@ func (s *AutoRollStateMachine) NextTransitionSequence(ctx context.Context) error
 func (s *AutoRollStateMachine) Current() string {
 	return s.s.Current()
 }
+
+// Bisection returns the current or most recent Bisection, or nil if none
+// exists.
+func (s *AutoRollStateMachine) Bisection() *Bisection {
+	s.bisectionMtx.RLock()
+	defer s.bisectionMtx.RUnlock()
+	return s.bisection
+}
+
+// Culprit returns the first bad revision found by the most recent
+// bisection, or nil if no culprit has been found.
+func (s *AutoRollStateMachine) Culprit() *revision.Revision {
+	if b := s.Bisection(); b != nil {
+		return b.Culprit
+	}
+	return nil
+}
+
+// setBisection updates and persists the current Bisection.
+func (s *AutoRollStateMachine) setBisection(ctx context.Context, b *Bisection) error {
+	s.bisectionMtx.Lock()
+	defer s.bisectionMtx.Unlock()
+	if b != nil {
+		if err := writeBisection(ctx, s.gcs, s.bisectionFile, b); err != nil {
+			return err
+		}
+	} else if s.bisection != nil {
+		if err := s.gcs.DeleteFile(ctx, s.bisectionFile); err != nil {
+			return err
+		}
+	}
+	s.bisection = b
+	return nil
+}
+
+// shouldBisect returns true iff we should bisect to find the culprit of the
+// given failed roll. We don't bisect rolls which contain only a single
+// candidate, nor rolls which contain a previously-found culprit, which is
+// presumably still broken.
+func (s *AutoRollStateMachine) shouldBisect(roll RollCLImpl) bool {
+	if !s.a.ShouldBisect() {
+		return false
+	}
+	revs := revisionsInRoll(s.a.GetNotRolledRevs(), roll.RollingTo())
+	if s.Bisection().culpritIn(revs) {
+		return false
+	}
+	return len(newBisection(s.a.GetCurrentRev(), revs).Revisions) > 1
+}
@@ -178,8 +178,10 @@ type TestAutoRollerImpl struct {
 	getNextRollRevError  error
 
 	getModeResult   string
+	notRolledRevs   []*revision.Revision
 	rolledPast      map[string]bool
 	safetyThrottle  *Throttler
+	shouldBisect    bool
 	successThrottle *Throttler
 	updateError     error
 
@@ -265,6 +267,19 @@ func (r *TestAutoRollerImpl) SetNextRollRev(rev string) {
 	r.getNextRollRevResult = &revision.Revision{Id: rev}
 }
 
+// See documentation for AutoRollerImpl.
+func (r *TestAutoRollerImpl) GetNotRolledRevs() []*revision.Revision {
+	return r.notRolledRevs
+}
+
+// Set the result of GetNotRolledRevs.
+func (r *TestAutoRollerImpl) SetNotRolledRevs(revs ...string) {
+	r.notRolledRevs = make([]*revision.Revision, 0, len(revs))
+	for _, rev := range revs {
+		r.notRolledRevs = append(r.notRolledRevs, &revision.Revision{Id: rev})
+	}
+}
+
 // See documentation for AutoRollerImpl.
 func (r *TestAutoRollerImpl) GetMode() string {
 	return r.getModeResult
@@ -312,6 +327,11 @@ func (r *TestAutoRollerImpl) SafetyThrottle() *Throttler {
 	return r.safetyThrottle
 }
 
+// See documentation for AutoRollerImpl.
+func (r *TestAutoRollerImpl) ShouldBisect() bool {
+	return r.shouldBisect
+}
+
 // Return a Throttler indicating whether we have successfully rolled too
 // many times within a time period.
 func (r *TestAutoRollerImpl) SuccessThrottle() *Throttler {
@@ -826,7 +846,7 @@ func TestNilCurrentRoll(t *testing.T) {
 	// Verify that every state in the state machine handles a nil current
 	// roll without crashing.
 	states := sm.s.ListStates()
-	require.Equal(t, 17, len(states))
+	require.Equal(t, 19, len(states))
 	stateFile := "test-roller/state_machine"
 	n, err := notifier.New(ctx, "fake", "fake", "fake", nil, nil, nil, nil)
 	require.NoError(t, err)
@@ -840,3 +860,104 @@ func TestNilCurrentRoll(t *testing.T) {
 		require.NoError(t, sm.NextTransition(ctx))
 	}
 }
+
+func TestBisect(t *testing.T) {
+	ctx, sm, r, gcsClient, cleanup := setup(t)
+	defer cleanup()
+
+	failureThrottle, err := NewThrottler(ctx, gcsClient, "fail_counter", time.Hour, 1)
+	require.NoError(t, err)
+	r.failureThrottle = failureThrottle
+	r.shouldBisect = true
+
+	// A roll containing a single revision fails; there's nothing to bisect.
+	r.SetNotRolledRevs("HEAD+1")
+	r.SetNextRollRev("HEAD+1")
+	checkNextState(t, sm, S_NORMAL_ACTIVE)
+	roll := r.GetActiveRoll().(*TestRollCLImpl)
+	roll.SetFailed()
+	checkNextState(t, sm, S_NORMAL_FAILURE)
+	checkNextState(t, sm, S_NORMAL_FAILURE_THROTTLED)
+
+	// More revisions land, and the roll fails again. HEAD+3 is the culprit.
+	r.SetNotRolledRevs("HEAD+5", "HEAD+4", "HEAD+3", "HEAD+2", "HEAD+1")
+	r.SetNextRollRev("HEAD+5")
+	checkNextState(t, sm, S_NORMAL_IDLE)
+	checkNextState(t, sm, S_NORMAL_ACTIVE)
+	roll = r.GetActiveRoll().(*TestRollCLImpl)
+	roll.SetFailed()
+	checkNextState(t, sm, S_NORMAL_FAILURE)
+	checkNextState(t, sm, S_BISECT_IDLE)
+	roll.AssertClosed(autoroll.ROLL_RESULT_FAILURE)
+	require.Len(t, sm.Bisection().Revisions, 5)
+	require.Nil(t, sm.Culprit())
+
+	// The first dry run rolls to the midpoint.
+	checkNextState(t, sm, S_BISECT_ACTIVE)
+	roll = r.GetActiveRoll().(*TestRollCLImpl)
+	roll.AssertDryRun()
+	require.Equal(t, "HEAD+3", roll.RollingTo().Id)
+	checkNextState(t, sm, S_BISECT_ACTIVE)
+	roll.SetDryRunFailed()
+	checkNextState(t, sm, S_BISECT_IDLE)
+	roll.AssertClosed(autoroll.ROLL_RESULT_DRY_RUN_FAILURE)
+
+	// The bisection is persisted.
+	n, err := notifier.New(ctx, "fake", "fake", "fake", nil, nil, nil, nil)
+	require.NoError(t, err)
+	sm2, err := New(ctx, r, n, gcsClient, gcsPrefix)
+	require.NoError(t, err)
+	require.Equal(t, sm.Bisection(), sm2.Bisection())
+
+	// The next dry run succeeds, which identifies the culprit.
+	checkNextState(t, sm, S_BISECT_ACTIVE)
+	roll = r.GetActiveRoll().(*TestRollCLImpl)
+	require.Equal(t, "HEAD+2", roll.RollingTo().Id)
+	roll.SetDryRunSucceeded()
+	checkNextState(t, sm, S_BISECT_IDLE)
+	roll.AssertClosed(autoroll.ROLL_RESULT_DRY_RUN_SUCCESS)
+	checkNextState(t, sm, S_NORMAL_IDLE)
+	require.Equal(t, "HEAD+3", sm.Culprit().Id)
+	require.Equal(t, "HEAD+2", sm.Bisection().LastGood().Id)
+
+	// Roll up to just before the culprit.
+	r.SetNextRollRev("HEAD+2")
+	checkNextState(t, sm, S_NORMAL_ACTIVE)
+	roll = r.GetActiveRoll().(*TestRollCLImpl)
+	roll.SetSucceeded()
+	r.SetCurrentRev("HEAD+2")
+	checkNextState(t, sm, S_NORMAL_SUCCESS)
+	r.SetRolledPast("HEAD+2", true)
+	checkNextState(t, sm, S_NORMAL_IDLE)
+
+	// Rolls which include the culprit fail, but we don't bisect again.
+	r.SetNotRolledRevs("HEAD+5", "HEAD+4", "HEAD+3")
+	r.SetNextRollRev("HEAD+5")
+	checkNextState(t, sm, S_NORMAL_ACTIVE)
+	roll = r.GetActiveRoll().(*TestRollCLImpl)
+	roll.SetFailed()
+	checkNextState(t, sm, S_NORMAL_FAILURE)
+	checkNextState(t, sm, S_NORMAL_FAILURE_THROTTLED)
+
+	// Once we've rolled past the culprit, a new failure is bisected. The
+	// roller may be stopped during bisection.
+	r.SetCurrentRev("HEAD+5")
+	r.SetNotRolledRevs("HEAD+7", "HEAD+6")
+	r.SetNextRollRev("HEAD+7")
+	checkNextState(t, sm, S_NORMAL_IDLE)
+	checkNextState(t, sm, S_NORMAL_ACTIVE)
+	roll = r.GetActiveRoll().(*TestRollCLImpl)
+	roll.SetFailed()
+	checkNextState(t, sm, S_NORMAL_FAILURE)
+	checkNextState(t, sm, S_BISECT_IDLE)
+	checkNextState(t, sm, S_BISECT_ACTIVE)
+	roll = r.GetActiveRoll().(*TestRollCLImpl)
+	require.Equal(t, "HEAD+6", roll.RollingTo().Id)
+	r.SetMode(ctx, modes.MODE_STOPPED)
+	checkNextState(t, sm, S_STOPPED)
+	roll.AssertClosed(autoroll.ROLL_RESULT_FAILURE)
+	require.Nil(t, sm.Bisection())
+	sm2, err = New(ctx, r, n, gcsClient, gcsPrefix)
+	require.NoError(t, err)
+	require.Nil(t, sm2.Bisection())
+}
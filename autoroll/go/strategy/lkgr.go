@@ -0,0 +1,196 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.skia.org/infra/autoroll/go/revision"
+	"go.skia.org/infra/go/human"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+)
+
+const (
+	// The default strategy used when no revision is known to be good for
+	// too long.
+	DEFAULT_LKGR_FALLBACK_STRATEGY = ROLL_STRATEGY_SINGLE
+)
+
+// LKGRConfig provides configuration for ROLL_STRATEGY_LKGR.
+type LKGRConfig struct {
+	// URL from which to obtain the last-known-good revision of the child,
+	// eg. "https://skia-status.skia.org/lkgr". The response body is
+	// expected to contain only the revision ID unless Regex is provided.
+	URL string `json:"url"`
+	// Optional regular expression used to extract the revision ID from the
+	// response body, eg. for endpoints which return JSON. Must contain
+	// exactly one capture group.
+	Regex string `json:"regex,omitempty"`
+	// If none of the not-yet-rolled revisions is known to be good and the
+	// oldest of them is older than this duration, eg. "24h", use
+	// FallbackStrategy to choose the next roll revision instead. If not
+	// provided, the roller waits indefinitely for a good revision.
+	FallbackAfter string `json:"fallbackAfter,omitempty"`
+	// Strategy used when the fallback applies. Defaults to
+	// DEFAULT_LKGR_FALLBACK_STRATEGY.
+	FallbackStrategy string `json:"fallbackStrategy,omitempty"`
+}
+
+// See documentation for util.Validator interface.
+func (c *LKGRConfig) Validate() error {
+	if c.URL == "" {
+		return errors.New("URL is required.")
+	}
+	if c.Regex != "" {
+		re, err := regexp.Compile(c.Regex)
+		if err != nil {
+			return fmt.Errorf("Invalid Regex: %s", err)
+		}
+		if re.NumSubexp() != 1 {
+			return errors.New("Regex must contain exactly one capture group.")
+		}
+	}
+	if c.FallbackAfter != "" {
+		if _, err := human.ParseDuration(c.FallbackAfter); err != nil {
+			return fmt.Errorf("Invalid FallbackAfter: %s", err)
+		}
+	}
+	if c.FallbackStrategy != "" {
+		if !util.In(c.FallbackStrategy, []string{ROLL_STRATEGY_BATCH, ROLL_STRATEGY_N_BATCH, ROLL_STRATEGY_SINGLE}) {
+			return fmt.Errorf("Invalid FallbackStrategy %q", c.FallbackStrategy)
+		}
+	}
+	return nil
+}
+
+// LKGR tracks the last-known-good revision of the child repo, as reported by
+// a status endpoint.
+type LKGR struct {
+	client        *http.Client
+	fallback      NextRollStrategy
+	fallbackAfter time.Duration
+	regex         *regexp.Regexp
+	url           string
+
+	mtx sync.RWMutex
+	rev string
+}
+
+// NewLKGR returns an LKGR instance. Update must be called to obtain the
+// current last-known-good revision.
+func NewLKGR(c *LKGRConfig, client *http.Client) (*LKGR, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	rv := &LKGR{
+		client: client,
+		url:    c.URL,
+	}
+	if c.Regex != "" {
+		rv.regex = regexp.MustCompile(c.Regex)
+	}
+	if c.FallbackAfter != "" {
+		// Already validated above.
+		rv.fallbackAfter, _ = human.ParseDuration(c.FallbackAfter)
+	}
+	fallbackStrategy := c.FallbackStrategy
+	if fallbackStrategy == "" {
+		fallbackStrategy = DEFAULT_LKGR_FALLBACK_STRATEGY
+	}
+	fallback, err := GetNextRollStrategy(fallbackStrategy, nil)
+	if err != nil {
+		return nil, err
+	}
+	rv.fallback = fallback
+	return rv, nil
+}
+
+// Get returns the last-known-good revision ID, or the empty string if it has
+// not yet been retrieved.
+func (l *LKGR) Get() string {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return l.rev
+}
+
+// Update retrieves the last-known-good revision ID from the status endpoint.
+// On failure, the previously-retrieved revision ID is retained.
+func (l *LKGR) Update(ctx context.Context) error {
+	req, err := http.NewRequest(http.MethodGet, l.url, nil)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	resp, err := l.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to retrieve LKGR from %s", l.url)
+	}
+	defer util.Close(resp.Body)
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to read response from %s", l.url)
+	}
+	body := strings.TrimSpace(string(b))
+	if resp.StatusCode != http.StatusOK {
+		return skerr.Fmt("Failed to retrieve LKGR from %s; status code %d: %s", l.url, resp.StatusCode, body)
+	}
+	rev := body
+	if l.regex != nil {
+		m := l.regex.FindStringSubmatch(body)
+		if len(m) != 2 {
+			return skerr.Fmt("Unable to find LKGR in response from %s", l.url)
+		}
+		rev = m[1]
+	}
+	if rev == "" {
+		return skerr.Fmt("Empty LKGR from %s", l.url)
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.rev = rev
+	return nil
+}
+
+// lkgrStrategy is a NextRollStrategy which rolls to the newest revision which
+// is known to be good.
+type lkgrStrategy struct {
+	lkgr *LKGR
+}
+
+// See documentation for NextRollStrategy interface.
+func (s *lkgrStrategy) GetNextRollRev(notRolled []*revision.Revision) *revision.Revision {
+	if len(notRolled) == 0 {
+		return nil
+	}
+	// Revisions are listed in reverse chronological order, so every
+	// revision from the LKGR onward is a candidate. Choose the newest valid
+	// one.
+	if lkgr := s.lkgr.Get(); lkgr != "" {
+		for idx, rev := range notRolled {
+			if rev.Id == lkgr {
+				return StrategyBatch().GetNextRollRev(notRolled[idx:])
+			}
+		}
+	}
+	// None of the revisions is known to be good. If we've been waiting for
+	// too long, fall back to rolling regardless.
+	oldest := notRolled[len(notRolled)-1]
+	if s.lkgr.fallbackAfter > 0 && !oldest.Timestamp.IsZero() && time.Now().Sub(oldest.Timestamp) > s.lkgr.fallbackAfter {
+		return s.lkgr.fallback.GetNextRollRev(notRolled)
+	}
+	return nil
+}
+
+// StrategyLKGR returns a NextRollStrategy which rolls to the newest revision
+// which is known to be good, according to the given LKGR.
+func StrategyLKGR(lkgr *LKGR) NextRollStrategy {
+	return &lkgrStrategy{
+		lkgr: lkgr,
+	}
+}
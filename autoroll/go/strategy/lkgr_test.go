@@ -0,0 +1,133 @@
+package strategy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/autoroll/go/revision"
+	"go.skia.org/infra/go/mockhttpclient"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+const fakeLKGRURL = "https://status.fake/lkgr"
+
+func TestLKGRConfigValidation(t *testing.T) {
+	unittest.SmallTest(t)
+
+	cfg := &LKGRConfig{
+		URL:              fakeLKGRURL,
+		Regex:            `"lkgr":\s*"([0-9a-f]+)"`,
+		FallbackAfter:    "24h",
+		FallbackStrategy: ROLL_STRATEGY_BATCH,
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.FallbackStrategy = ROLL_STRATEGY_LKGR
+	require.EqualError(t, cfg.Validate(), `Invalid FallbackStrategy "lkgr"`)
+	cfg.FallbackStrategy = ""
+	cfg.Regex = "[0-9a-f]+"
+	require.EqualError(t, cfg.Validate(), "Regex must contain exactly one capture group.")
+	cfg.Regex = ""
+	cfg.FallbackAfter = "bogus"
+	require.Error(t, cfg.Validate())
+	cfg.FallbackAfter = ""
+	cfg.URL = ""
+	require.EqualError(t, cfg.Validate(), "URL is required.")
+}
+
+func TestLKGRUpdate(t *testing.T) {
+	unittest.SmallTest(t)
+
+	ctx := context.Background()
+	urlmock := mockhttpclient.NewURLMock()
+	l, err := NewLKGR(&LKGRConfig{
+		URL:   fakeLKGRURL,
+		Regex: `"lkgr":\s*"([0-9a-f]+)"`,
+	}, urlmock.Client())
+	require.NoError(t, err)
+	require.Equal(t, "", l.Get())
+
+	urlmock.MockOnce(fakeLKGRURL, mockhttpclient.MockGetDialogue([]byte(`{"lkgr": "abc123"}`)))
+	require.NoError(t, l.Update(ctx))
+	require.Equal(t, "abc123", l.Get())
+
+	// Failures retain the previous value.
+	urlmock.MockOnce(fakeLKGRURL, mockhttpclient.MockGetDialogue([]byte(`{}`)))
+	require.Contains(t, l.Update(ctx).Error(), "Unable to find LKGR in response from "+fakeLKGRURL)
+	require.Equal(t, "abc123", l.Get())
+	urlmock.MockOnce(fakeLKGRURL, mockhttpclient.MockGetError("Internal Server Error", http.StatusInternalServerError))
+	require.Error(t, l.Update(ctx))
+	require.Equal(t, "abc123", l.Get())
+}
+
+func TestStrategyLKGR(t *testing.T) {
+	unittest.SmallTest(t)
+
+	ctx := context.Background()
+	urlmock := mockhttpclient.NewURLMock()
+	l, err := NewLKGR(&LKGRConfig{
+		URL:           fakeLKGRURL,
+		FallbackAfter: "24h",
+	}, urlmock.Client())
+	require.NoError(t, err)
+	s, err := GetNextRollStrategy(ROLL_STRATEGY_LKGR, l)
+	require.NoError(t, err)
+	_, err = GetNextRollStrategy(ROLL_STRATEGY_LKGR, nil)
+	require.EqualError(t, err, `Roll strategy "lkgr" requires an LKGR config`)
+
+	// No revisions to roll.
+	require.Nil(t, s.GetNextRollRev(nil))
+	require.Nil(t, s.GetNextRollRev([]*revision.Revision{}))
+
+	// Revisions are passed in reverse chronological order.
+	now := time.Now()
+	testRevs := []*revision.Revision{
+		{
+			Id:        "D",
+			Timestamp: now,
+		},
+		{
+			Id:        "C",
+			Timestamp: now.Add(-time.Hour),
+		},
+		{
+			Id:        "B",
+			Timestamp: now.Add(-2 * time.Hour),
+		},
+		{
+			Id:        "A",
+			Timestamp: now.Add(-3 * time.Hour),
+		},
+	}
+
+	// We don't know the LKGR yet, so we can't roll.
+	require.Nil(t, s.GetNextRollRev(testRevs))
+
+	// Roll to the LKGR.
+	urlmock.MockOnce(fakeLKGRURL, mockhttpclient.MockGetDialogue([]byte("C\n")))
+	require.NoError(t, l.Update(ctx))
+	require.Equal(t, testRevs[1], s.GetNextRollRev(testRevs))
+
+	// The LKGR is not valid; we should choose the newest valid revision
+	// which precedes it.
+	testRevs[1].InvalidReason = "flu"
+	require.Equal(t, testRevs[2], s.GetNextRollRev(testRevs))
+	testRevs[1].InvalidReason = ""
+
+	// The LKGR is not in the list of not-yet-rolled revisions, eg. because
+	// we've already rolled past it. Nothing is known to be good.
+	urlmock.MockOnce(fakeLKGRURL, mockhttpclient.MockGetDialogue([]byte("Z")))
+	require.NoError(t, l.Update(ctx))
+	require.Nil(t, s.GetNextRollRev(testRevs))
+
+	// Nothing has been good for too long; fall back to the single strategy.
+	testRevs[3].Timestamp = now.Add(-25 * time.Hour)
+	require.Equal(t, testRevs[3], s.GetNextRollRev(testRevs))
+
+	// Revisions without timestamps never trigger the fallback.
+	testRevs[3].Timestamp = time.Time{}
+	require.Nil(t, s.GetNextRollRev(testRevs))
+}
@@ -8,6 +8,7 @@ import (
 
 const (
 	ROLL_STRATEGY_BATCH = "batch"
+	ROLL_STRATEGY_LKGR  = "lkgr"
 	// TODO(rmistry): Rename to "batch of " + N_REVISIONS ?
 	ROLL_STRATEGY_N_BATCH = "n_batch"
 	ROLL_STRATEGY_SINGLE  = "single"
@@ -26,11 +27,17 @@ type NextRollStrategy interface {
 	GetNextRollRev([]*revision.Revision) *revision.Revision
 }
 
-// Return the NextRollStrategy indicated by the given string.
-func GetNextRollStrategy(strategy string) (NextRollStrategy, error) {
+// Return the NextRollStrategy indicated by the given string. The LKGR is
+// required for ROLL_STRATEGY_LKGR and may be nil otherwise.
+func GetNextRollStrategy(strategy string, lkgr *LKGR) (NextRollStrategy, error) {
 	switch strategy {
 	case ROLL_STRATEGY_BATCH:
 		return StrategyBatch(), nil
+	case ROLL_STRATEGY_LKGR:
+		if lkgr == nil {
+			return nil, fmt.Errorf("Roll strategy %q requires an LKGR config", strategy)
+		}
+		return StrategyLKGR(lkgr), nil
 	case ROLL_STRATEGY_N_BATCH:
 		return StrategyNBatch(), nil
 	case ROLL_STRATEGY_SINGLE:
@@ -610,7 +610,7 @@ func (m *overdueJobMetrics) updateOverdueJobSpecMetrics(ctx context.Context, now
 		names := []string{}
 		for name, jobSpec := range headTaskCfg.Jobs {
 			// We're only interested in periodic jobs for this metric.
-			if util.In(jobSpec.Trigger, specs.PERIODIC_TRIGGERS) {
+			if util.In(jobSpec.Trigger, specs.PERIODIC_TRIGGERS) || strings.HasPrefix(jobSpec.Trigger, specs.TRIGGER_CRON_PREFIX) {
 				names = append(names, name)
 			}
 		}
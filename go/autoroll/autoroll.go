@@ -16,6 +16,7 @@ import (
 	"go.skia.org/infra/go/buildbucket"
 	"go.skia.org/infra/go/comment"
 	"go.skia.org/infra/go/github"
+	"go.skia.org/infra/go/gitlab"
 	"go.skia.org/infra/go/skerr"
 	"go.skia.org/infra/go/sklog"
 	"go.skia.org/infra/go/util"
@@ -387,6 +388,44 @@ func TryResultsFromGithubChecks(checks []*github.Check, checksWaitFor []string)
 	return tryResults
 }
 
+// TryResultsFromGitlabJobs returns a slice of TryResults based on the jobs in
+// a GitLab pipeline. Manual jobs are not run automatically and are therefore
+// ignored, as are failures of jobs which are allowed to fail.
+func TryResultsFromGitlabJobs(jobs []*gitlab.Job) []*TryResult {
+	tryResults := []*TryResult{}
+	for _, job := range jobs {
+		status := TRYBOT_STATUS_SCHEDULED
+		result := ""
+		switch job.Status {
+		case gitlab.PIPELINE_STATUS_MANUAL:
+			continue
+		case gitlab.PIPELINE_STATUS_RUNNING:
+			status = TRYBOT_STATUS_STARTED
+		case gitlab.PIPELINE_STATUS_SUCCESS, gitlab.PIPELINE_STATUS_SKIPPED:
+			status = TRYBOT_STATUS_COMPLETED
+			result = TRYBOT_RESULT_SUCCESS
+		case gitlab.PIPELINE_STATUS_FAILED:
+			status = TRYBOT_STATUS_COMPLETED
+			result = TRYBOT_RESULT_FAILURE
+			if job.AllowFailure {
+				result = TRYBOT_RESULT_SUCCESS
+			}
+		case gitlab.PIPELINE_STATUS_CANCELED:
+			status = TRYBOT_STATUS_COMPLETED
+			result = TRYBOT_RESULT_CANCELED
+		}
+		tryResults = append(tryResults, &TryResult{
+			Builder:  fmt.Sprintf("%s #%d", job.Name, job.ID),
+			Category: TRYBOT_CATEGORY_CQ,
+			Created:  job.CreatedAt,
+			Result:   result,
+			Status:   status,
+			Url:      job.WebURL,
+		})
+	}
+	return tryResults
+}
+
 // Finished returns true iff the trybot is done running.
 func (t TryResult) Finished() bool {
 	return t.Status == TRYBOT_STATUS_COMPLETED
@@ -11,6 +11,7 @@ import (
 	"go.skia.org/infra/go/comment"
 	"go.skia.org/infra/go/deepequal/assertdeep"
 	"go.skia.org/infra/go/github"
+	"go.skia.org/infra/go/gitlab"
 	"go.skia.org/infra/go/testutils/unittest"
 )
 
@@ -230,3 +231,26 @@ func TestTryResultsFromGithubChecks(t *testing.T) {
 	require.Equal(t, "", tryResults[2].Result)
 	require.Equal(t, TRYBOT_STATUS_STARTED, tryResults[2].Status)
 }
+
+func TestTryResultsFromGitlabJobs(t *testing.T) {
+	unittest.SmallTest(t)
+
+	now := time.Now()
+	jobs := []*gitlab.Job{
+		{ID: 1, Name: "build", Status: gitlab.PIPELINE_STATUS_PENDING, CreatedAt: now},
+		{ID: 2, Name: "test", Status: gitlab.PIPELINE_STATUS_RUNNING, CreatedAt: now},
+		{ID: 3, Name: "lint", Status: gitlab.PIPELINE_STATUS_FAILED, CreatedAt: now, WebURL: "https://gitlab/jobs/3"},
+		{ID: 4, Name: "flaky", Status: gitlab.PIPELINE_STATUS_FAILED, AllowFailure: true, CreatedAt: now},
+		{ID: 5, Name: "deploy", Status: gitlab.PIPELINE_STATUS_MANUAL, CreatedAt: now},
+		{ID: 6, Name: "docs", Status: gitlab.PIPELINE_STATUS_SUCCESS, CreatedAt: now},
+		{ID: 7, Name: "perf", Status: gitlab.PIPELINE_STATUS_CANCELED, CreatedAt: now},
+	}
+	assertdeep.Equal(t, []*TryResult{
+		{Builder: "build #1", Category: TRYBOT_CATEGORY_CQ, Created: now, Status: TRYBOT_STATUS_SCHEDULED},
+		{Builder: "test #2", Category: TRYBOT_CATEGORY_CQ, Created: now, Status: TRYBOT_STATUS_STARTED},
+		{Builder: "lint #3", Category: TRYBOT_CATEGORY_CQ, Created: now, Result: TRYBOT_RESULT_FAILURE, Status: TRYBOT_STATUS_COMPLETED, Url: "https://gitlab/jobs/3"},
+		{Builder: "flaky #4", Category: TRYBOT_CATEGORY_CQ, Created: now, Result: TRYBOT_RESULT_SUCCESS, Status: TRYBOT_STATUS_COMPLETED},
+		{Builder: "docs #6", Category: TRYBOT_CATEGORY_CQ, Created: now, Result: TRYBOT_RESULT_SUCCESS, Status: TRYBOT_STATUS_COMPLETED},
+		{Builder: "perf #7", Category: TRYBOT_CATEGORY_CQ, Created: now, Result: TRYBOT_RESULT_CANCELED, Status: TRYBOT_STATUS_COMPLETED},
+	}, TryResultsFromGitlabJobs(jobs))
+}
@@ -3,6 +3,7 @@ package calc
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 
 	"go.skia.org/infra/go/vec32"
@@ -591,3 +592,388 @@ func (ScaleByAveFunc) Describe() string {
 }
 
 var scaleByAveFunc = ScaleByAveFunc{}
+
+// numArg returns the i'th argument of node, which must be a number, for the
+// function 'name'.
+func numArg(name string, node *Node, i int) (float64, error) {
+	if node.Args[i].Typ != NodeNum {
+		return 0, fmt.Errorf("%s() takes a number as argument %d.", name, i+1)
+	}
+	ret, err := strconv.ParseFloat(node.Args[i].Val, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%s() argument %d is not a valid number %s : %s", name, i+1, node.Args[i].Val, err)
+	}
+	return ret, nil
+}
+
+// percentile returns the p'th percentile, 0 <= p <= 100, of the sorted
+// values, interpolating linearly between the closest ranks.
+func percentile(sorted []float32, p float64) float32 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	frac := float32(rank - float64(lower))
+	return sorted[lower] + frac*(sorted[upper]-sorted[lower])
+}
+
+// nonMissing returns the values in row that aren't vec32.MISSING_DATA_SENTINEL.
+func nonMissing(row []float32) []float32 {
+	ret := make([]float32, 0, len(row))
+	for _, v := range row {
+		if v != vec32.MISSING_DATA_SENTINEL {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// evalSingleFuncArg checks that node has a single function argument and
+// returns the result of evaluating it.
+func evalSingleFuncArg(name string, ctx *Context, node *Node) (Rows, error) {
+	if len(node.Args) != 1 {
+		return nil, fmt.Errorf("%s() takes a single argument.", name)
+	}
+	if node.Args[0].Typ != NodeFunc {
+		return nil, fmt.Errorf("%s() takes a function argument.", name)
+	}
+	rows, err := node.Args[0].Eval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s() argument failed to evaluate: %s", name, err)
+	}
+	return rows, nil
+}
+
+// acrossRows folds the values of all rows at each index into a single row by
+// calling f on the sorted non-missing values at that index. If all the values
+// at an index are vec32.MISSING_DATA_SENTINEL then the result at that index is
+// vec32.MISSING_DATA_SENTINEL.
+func acrossRows(ctx *Context, rows Rows, f func(sorted []float32) float32) Rows {
+	if len(rows) == 0 {
+		return rows
+	}
+	ret := newRow(rows)
+	values := make([]float32, 0, len(rows))
+	for i := range ret {
+		values = values[:0]
+		for _, r := range rows {
+			if v := r[i]; v != vec32.MISSING_DATA_SENTINEL {
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			sort.Slice(values, func(a, b int) bool { return values[a] < values[b] })
+			ret[i] = f(values)
+		}
+	}
+	return Rows{ctx.formula: ret}
+}
+
+// PercentileFunc implements Func and folds the values of all argument rows
+// into a single trace of the given percentile.
+//
+// vec32.MISSING_DATA_SENTINEL values are not included. Note that if all the
+// values at an index are vec32.MISSING_DATA_SENTINEL then the result will be
+// vec32.MISSING_DATA_SENTINEL.
+type PercentileFunc struct {
+	percentile float64
+}
+
+func (p PercentileFunc) name() string {
+	return fmt.Sprintf("p%g", p.percentile)
+}
+
+func (p PercentileFunc) Eval(ctx *Context, node *Node) (Rows, error) {
+	rows, err := evalSingleFuncArg(p.name(), ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	return acrossRows(ctx, rows, func(sorted []float32) float32 {
+		return percentile(sorted, p.percentile)
+	}), nil
+}
+
+func (p PercentileFunc) Describe() string {
+	return fmt.Sprintf(`%s() folds the values of all argument rows into a single trace of the %gth percentile.
+
+  Missing values are ignored, and the percentile interpolates between the
+  closest values.`, p.name(), p.percentile)
+}
+
+var p50Func = PercentileFunc{percentile: 50}
+var p90Func = PercentileFunc{percentile: 90}
+
+type MinFunc struct{}
+
+// MinFunc implements Func and folds the values of all argument rows into a
+// single trace of the minimum values.
+//
+// vec32.MISSING_DATA_SENTINEL values are not included. Note that if all the
+// values at an index are vec32.MISSING_DATA_SENTINEL then the min will be
+// vec32.MISSING_DATA_SENTINEL.
+func (MinFunc) Eval(ctx *Context, node *Node) (Rows, error) {
+	rows, err := evalSingleFuncArg("min", ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	return acrossRows(ctx, rows, func(sorted []float32) float32 {
+		return sorted[0]
+	}), nil
+}
+
+func (MinFunc) Describe() string {
+	return `min() folds the values of all argument rows into a single trace of the minimum values.`
+}
+
+var minFunc = MinFunc{}
+
+type MaxFunc struct{}
+
+// MaxFunc implements Func and folds the values of all argument rows into a
+// single trace of the maximum values.
+//
+// vec32.MISSING_DATA_SENTINEL values are not included. Note that if all the
+// values at an index are vec32.MISSING_DATA_SENTINEL then the max will be
+// vec32.MISSING_DATA_SENTINEL.
+func (MaxFunc) Eval(ctx *Context, node *Node) (Rows, error) {
+	rows, err := evalSingleFuncArg("max", ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	return acrossRows(ctx, rows, func(sorted []float32) float32 {
+		return sorted[len(sorted)-1]
+	}), nil
+}
+
+func (MaxFunc) Describe() string {
+	return `max() folds the values of all argument rows into a single trace of the maximum values.`
+}
+
+var maxFunc = MaxFunc{}
+
+type MovingAveFunc struct{}
+
+// MovingAveFunc implements Func and replaces each value in a trace with the
+// average of the values in a trailing window of the given size.
+//
+// vec32.MISSING_DATA_SENTINEL values are not included in the average, and are
+// left untouched.
+func (MovingAveFunc) Eval(ctx *Context, node *Node) (Rows, error) {
+	if len(node.Args) != 2 {
+		return nil, fmt.Errorf("moving_ave() takes two arguments.")
+	}
+	if node.Args[0].Typ != NodeFunc {
+		return nil, fmt.Errorf("moving_ave() takes a function as its first argument.")
+	}
+	window, err := numArg("moving_ave", node, 1)
+	if err != nil {
+		return nil, err
+	}
+	if window < 1 || window != math.Trunc(window) {
+		return nil, fmt.Errorf("moving_ave() window must be an integer of at least 1, got %g.", window)
+	}
+	rows, err := node.Args[0].Eval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("moving_ave() failed evaluating argument: %s", err)
+	}
+
+	n := int(window)
+	ret := Rows{}
+	for key, r := range rows {
+		row := vec32.Dup(r)
+		for i, v := range r {
+			if v == vec32.MISSING_DATA_SENTINEL {
+				continue
+			}
+			begin := i - n + 1
+			if begin < 0 {
+				begin = 0
+			}
+			row[i] = vec32.Mean(r[begin : i+1])
+		}
+		ret["moving_ave("+key+")"] = row
+	}
+	return ret, nil
+}
+
+func (MovingAveFunc) Describe() string {
+	return `moving_ave(rows, n) replaces each value with the average of the last n values in the trace, including itself.
+
+  n must be a positive integer. moving_avg() is an alias.
+
+  Missing values are ignored.`
+}
+
+var movingAveFunc = MovingAveFunc{}
+
+type EWMAFunc struct{}
+
+// EWMAFunc implements Func and smooths each trace with an exponentially
+// weighted moving average with the given smoothing factor alpha, where
+// 0 < alpha <= 1.
+//
+// vec32.MISSING_DATA_SENTINEL values are skipped, and are left untouched.
+func (EWMAFunc) Eval(ctx *Context, node *Node) (Rows, error) {
+	if len(node.Args) != 2 {
+		return nil, fmt.Errorf("ewma() takes two arguments.")
+	}
+	if node.Args[0].Typ != NodeFunc {
+		return nil, fmt.Errorf("ewma() takes a function as its first argument.")
+	}
+	alpha, err := numArg("ewma", node, 1)
+	if err != nil {
+		return nil, err
+	}
+	if alpha <= 0 || alpha > 1 {
+		return nil, fmt.Errorf("ewma() alpha must be in (0, 1], got %g.", alpha)
+	}
+	rows, err := node.Args[0].Eval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ewma() failed evaluating argument: %s", err)
+	}
+
+	ret := Rows{}
+	for key, r := range rows {
+		row := vec32.Dup(r)
+		first := true
+		smoothed := float32(0)
+		for i, v := range r {
+			if v == vec32.MISSING_DATA_SENTINEL {
+				continue
+			}
+			if first {
+				smoothed = v
+				first = false
+			} else {
+				smoothed = float32(alpha)*v + float32(1-alpha)*smoothed
+			}
+			row[i] = smoothed
+		}
+		ret["ewma("+key+")"] = row
+	}
+	return ret, nil
+}
+
+func (EWMAFunc) Describe() string {
+	return `ewma(rows, alpha) smooths each trace with an exponentially weighted moving average.
+
+  The smoothing factor alpha must be in (0, 1], where smaller values give
+  smoother traces, and 1 leaves the trace unchanged. Missing values are ignored.`
+}
+
+var ewmaFunc = EWMAFunc{}
+
+type DiffFunc struct{}
+
+// DiffFunc implements Func and transforms each trace into the differences
+// between each value and the previous value.
+//
+// vec32.MISSING_DATA_SENTINEL values are skipped, i.e. the difference is taken
+// from the last non-missing value, and the first non-missing value becomes
+// vec32.MISSING_DATA_SENTINEL since it has no previous value.
+func (DiffFunc) Eval(ctx *Context, node *Node) (Rows, error) {
+	rows, err := evalSingleFuncArg("diff", ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := Rows{}
+	for key, r := range rows {
+		row := vec32.Dup(r)
+		prev := vec32.MISSING_DATA_SENTINEL
+		for i, v := range r {
+			if v == vec32.MISSING_DATA_SENTINEL {
+				continue
+			}
+			if prev == vec32.MISSING_DATA_SENTINEL {
+				row[i] = vec32.MISSING_DATA_SENTINEL
+			} else {
+				row[i] = v - prev
+			}
+			prev = v
+		}
+		ret["diff("+key+")"] = row
+	}
+	return ret, nil
+}
+
+func (DiffFunc) Describe() string {
+	return `diff() returns the commit over commit change in each trace, i.e. each value minus the previous value.`
+}
+
+var diffFunc = DiffFunc{}
+
+type AbsFunc struct{}
+
+// AbsFunc implements Func and transforms a row of x into a row of |x|.
+//
+// vec32.MISSING_DATA_SENTINEL values are left untouched.
+func (AbsFunc) Eval(ctx *Context, node *Node) (Rows, error) {
+	rows, err := evalSingleFuncArg("abs", ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := Rows{}
+	for key, r := range rows {
+		row := vec32.Dup(r)
+		for i, v := range row {
+			if v != vec32.MISSING_DATA_SENTINEL && v < 0 {
+				row[i] = -v
+			}
+		}
+		ret["abs("+key+")"] = row
+	}
+	return ret, nil
+}
+
+func (AbsFunc) Describe() string {
+	return `abs() returns the absolute value of each datapoint.`
+}
+
+var absFunc = AbsFunc{}
+
+type IQRRFunc struct{}
+
+// IQRRFunc implements Func and removes outliers from each trace, where an
+// outlier is a value more than 1.5 times the interquartile range below the
+// first quartile or above the third quartile of the trace. The outliers are
+// replaced with vec32.MISSING_DATA_SENTINEL.
+func (IQRRFunc) Eval(ctx *Context, node *Node) (Rows, error) {
+	rows, err := evalSingleFuncArg("iqrr", ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := Rows{}
+	for key, r := range rows {
+		row := vec32.Dup(r)
+		sorted := nonMissing(r)
+		if len(sorted) > 0 {
+			sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+			q1 := percentile(sorted, 25)
+			q3 := percentile(sorted, 75)
+			iqr := q3 - q1
+			low := q1 - 1.5*iqr
+			high := q3 + 1.5*iqr
+			for i, v := range row {
+				if v != vec32.MISSING_DATA_SENTINEL && (v < low || v > high) {
+					row[i] = vec32.MISSING_DATA_SENTINEL
+				}
+			}
+		}
+		ret["iqrr("+key+")"] = row
+	}
+	return ret, nil
+}
+
+func (IQRRFunc) Describe() string {
+	return `iqrr() removes outliers from each trace using the interquartile range.
+
+  Values more than 1.5 times the interquartile range below the first quartile,
+  or above the third quartile, of the trace are replaced with missing values.`
+}
+
+var iqrrFunc = IQRRFunc{}
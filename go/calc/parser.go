@@ -58,13 +58,24 @@ func (n *Node) Eval(ctx *Context) (Rows, error) {
 	if n.Typ != NodeFunc {
 		return nil, fmt.Errorf("Tried to call eval on a non-Func node: %s", n.Val)
 	}
-	if f, ok := ctx.Funcs[n.Val]; ok {
+	name := n.Val
+	if alias, ok := funcAliases[name]; ok {
+		name = alias
+	}
+	if f, ok := ctx.Funcs[name]; ok {
 		return f.Eval(ctx, n)
 	} else {
 		return nil, fmt.Errorf("Unknown function name: %s", n.Val)
 	}
 }
 
+// funcAliases maps alternate spellings of function names to the name the
+// function is registered under in Context.Funcs, so that each function is
+// only listed once.
+var funcAliases = map[string]string{
+	"moving_avg": "moving_ave",
+}
+
 // Func defines a type for functions that can be used in the parser.
 //
 // The traces returned will always have a Param of "id" that identifies
@@ -107,6 +118,15 @@ func NewContext(rowsFromQuery RowsFromQuery, rowsFromShortcut RowsFromShortcut)
 			"trace_cov":    traceCovFunc,
 			"step":         traceStepFunc,
 			"scale_by_ave": scaleByAveFunc,
+			"p50":          p50Func,
+			"p90":          p90Func,
+			"min":          minFunc,
+			"max":          maxFunc,
+			"moving_ave":   movingAveFunc,
+			"ewma":         ewmaFunc,
+			"diff":         diffFunc,
+			"abs":          absFunc,
+			"iqrr":         iqrrFunc,
 		},
 	}
 }
@@ -343,3 +343,146 @@ func TestLog(t *testing.T) {
 		}
 	}
 }
+
+func TestPercentiles(t *testing.T) {
+	unittest.SmallTest(t)
+	ctx := newTestContext(Rows{
+		",name=t1,": []float32{1.0, 1.0, e, e},
+		",name=t2,": []float32{2.0, e, 5.0, e},
+		",name=t3,": []float32{3.0, 3.0, e, e},
+		",name=t4,": []float32{4.0, e, e, e},
+		",name=t5,": []float32{5.0, e, e, e},
+	}, nil)
+
+	testCases := []struct {
+		formula string
+		want    []float32
+	}{
+		{`p50(filter(""))`, []float32{3.0, 2.0, 5.0, e}},
+		{`p90(filter(""))`, []float32{4.6, 2.8, 5.0, e}},
+		{`min(filter(""))`, []float32{1.0, 1.0, 5.0, e}},
+		{`max(filter(""))`, []float32{5.0, 3.0, 5.0, e}},
+	}
+	for _, tc := range testCases {
+		rows, err := ctx.Eval(tc.formula)
+		if err != nil {
+			t.Fatalf("Failed to eval %s: %s", tc.formula, err)
+		}
+		if got, want := len(rows), 1; got != want {
+			t.Errorf("%s returned wrong length: Got %v Want %v", tc.formula, got, want)
+		}
+		for i, want := range tc.want {
+			if got := rows[tc.formula][i]; !near(got, want) {
+				t.Errorf("%s mismatch at %d: Got %v Want %v", tc.formula, i, got, want)
+			}
+		}
+	}
+}
+
+func TestMovingAve(t *testing.T) {
+	unittest.SmallTest(t)
+	ctx := newTestContext(Rows{
+		",name=t1,": []float32{1, 3, e, 5, 7},
+	}, nil)
+	rows, err := ctx.Eval(`moving_ave(filter(""), 2)`)
+	if err != nil {
+		t.Fatalf("Failed to eval moving_ave() test: %s", err)
+	}
+	for i, want := range []float32{1, 2, e, 5, 6} {
+		if got := rows["moving_ave(,name=t1,)"][i]; !near(got, want) {
+			t.Errorf("Distance mismatch: Got %v Want %v", got, want)
+		}
+	}
+
+	_, err = ctx.Eval(`moving_ave(filter(""), 0)`)
+	assert.Error(t, err)
+	_, err = ctx.Eval(`moving_ave(filter(""), 2.5)`)
+	assert.Error(t, err)
+	_, err = ctx.Eval(`moving_ave(filter(""))`)
+	assert.Error(t, err)
+
+	// moving_avg is an alias, but isn't listed separately.
+	aliasRows, err := ctx.Eval(`moving_avg(filter(""), 2)`)
+	assert.NoError(t, err)
+	assert.Equal(t, rows, aliasRows)
+	_, ok := ctx.Funcs["moving_avg"]
+	assert.False(t, ok)
+}
+
+func TestEWMA(t *testing.T) {
+	unittest.SmallTest(t)
+	ctx := newTestContext(Rows{
+		",name=t1,": []float32{e, 2, 4, e, 8},
+	}, nil)
+	rows, err := ctx.Eval(`ewma(filter(""), 0.5)`)
+	if err != nil {
+		t.Fatalf("Failed to eval ewma() test: %s", err)
+	}
+	for i, want := range []float32{e, 2, 3, e, 5.5} {
+		if got := rows["ewma(,name=t1,)"][i]; !near(got, want) {
+			t.Errorf("Distance mismatch: Got %v Want %v", got, want)
+		}
+	}
+
+	_, err = ctx.Eval(`ewma(filter(""), 1.5)`)
+	assert.Error(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	unittest.SmallTest(t)
+	ctx := newTestContext(Rows{
+		",name=t1,": []float32{e, 1, 4, e, 2},
+	}, nil)
+	rows, err := ctx.Eval(`diff(filter(""))`)
+	if err != nil {
+		t.Fatalf("Failed to eval diff() test: %s", err)
+	}
+	for i, want := range []float32{e, e, 3, e, -2} {
+		if got := rows["diff(,name=t1,)"][i]; !near(got, want) {
+			t.Errorf("Distance mismatch: Got %v Want %v", got, want)
+		}
+	}
+}
+
+func TestAbs(t *testing.T) {
+	unittest.SmallTest(t)
+	ctx := newTestContext(Rows{
+		",name=t1,": []float32{-1, 0, 2, e},
+	}, nil)
+	rows, err := ctx.Eval(`abs(filter(""))`)
+	if err != nil {
+		t.Fatalf("Failed to eval abs() test: %s", err)
+	}
+	for i, want := range []float32{1, 0, 2, e} {
+		if got := rows["abs(,name=t1,)"][i]; !near(got, want) {
+			t.Errorf("Distance mismatch: Got %v Want %v", got, want)
+		}
+	}
+}
+
+func TestIQRR(t *testing.T) {
+	unittest.SmallTest(t)
+	ctx := newTestContext(Rows{
+		",name=t1,": []float32{10, 11, 12, e, 11, 100, 10, -50},
+	}, nil)
+	rows, err := ctx.Eval(`iqrr(filter(""))`)
+	if err != nil {
+		t.Fatalf("Failed to eval iqrr() test: %s", err)
+	}
+	for i, want := range []float32{10, 11, 12, e, 11, e, 10, e} {
+		if got := rows["iqrr(,name=t1,)"][i]; !near(got, want) {
+			t.Errorf("Distance mismatch: Got %v Want %v", got, want)
+		}
+	}
+}
+
+func TestNewFuncsDescribe(t *testing.T) {
+	unittest.SmallTest(t)
+	ctx := NewContext(nil, nil)
+	for _, name := range []string{"p50", "p90", "min", "max", "moving_ave", "ewma", "diff", "abs", "iqrr"} {
+		f, ok := ctx.Funcs[name]
+		if assert.True(t, ok, name) {
+			assert.Contains(t, f.Describe(), name+"(")
+		}
+	}
+}
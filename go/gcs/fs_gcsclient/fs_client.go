@@ -0,0 +1,194 @@
+// Package fs_gcsclient implements gcs.GCSClient on top of a directory of the local file system,
+// for deployments that don't have access to Google Cloud Storage.
+package fs_gcsclient
+
+import (
+	"context"
+	"crypto/md5"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"go.skia.org/infra/go/gcs"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+)
+
+// FSGCSClient is a gcs.GCSClient that stores files in a directory instead of a GCS bucket. The
+// path of a file in the "bucket" is its path relative to that directory. FileWriteOptions are not
+// stored, so files are never transcoded.
+type FSGCSClient struct {
+	dir string
+}
+
+// New returns an FSGCSClient that stores files in the given directory, which is created if it
+// doesn't exist.
+func New(dir string) (*FSGCSClient, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, skerr.Wrapf(err, "creating directory %s", dir)
+	}
+	return &FSGCSClient{dir: dir}, nil
+}
+
+// fullPath returns the location on disk of the file with the given path.
+func (c *FSGCSClient) fullPath(path string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(path))
+}
+
+// See documentation for GCSClient interface.
+func (c *FSGCSClient) FileReader(ctx context.Context, path string) (io.ReadCloser, error) {
+	f, err := os.Open(c.fullPath(path))
+	if os.IsNotExist(err) {
+		return nil, storage.ErrObjectNotExist
+	}
+	return f, err
+}
+
+// fileWriter writes to a temporary file that is moved into place on Close, so readers never see
+// a partially written file.
+type fileWriter struct {
+	tmp  *os.File
+	path string
+	err  error
+}
+
+// See documentation for io.Writer.
+func (w *fileWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.tmp.Write(p)
+}
+
+// See documentation for io.Closer.
+func (w *fileWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.tmp.Close(); err != nil {
+		util.Remove(w.tmp.Name())
+		return skerr.Wrap(err)
+	}
+	if err := os.Rename(w.tmp.Name(), w.path); err != nil {
+		util.Remove(w.tmp.Name())
+		return skerr.Wrapf(err, "moving file into place at %s", w.path)
+	}
+	return nil
+}
+
+// See documentation for GCSClient interface. Any error creating the file is returned by Write and
+// Close.
+func (c *FSGCSClient) FileWriter(ctx context.Context, path string, opts gcs.FileWriteOptions) io.WriteCloser {
+	full := c.fullPath(path)
+	w := &fileWriter{path: full}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		w.err = skerr.Wrapf(err, "creating directory for %s", full)
+		return w
+	}
+	w.tmp, w.err = ioutil.TempFile(filepath.Dir(full), ".tmp-")
+	return w
+}
+
+// See documentation for GCSClient interface.
+func (c *FSGCSClient) DoesFileExist(ctx context.Context, path string) (bool, error) {
+	_, err := os.Stat(c.fullPath(path))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, skerr.Wrap(err)
+	}
+	return true, nil
+}
+
+// See documentation for GCSClient interface.
+func (c *FSGCSClient) GetFileContents(ctx context.Context, path string) ([]byte, error) {
+	r, err := c.FileReader(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer util.Close(r)
+	return ioutil.ReadAll(r)
+}
+
+// See documentation for GCSClient interface.
+func (c *FSGCSClient) SetFileContents(ctx context.Context, path string, opts gcs.FileWriteOptions, contents []byte) error {
+	return gcs.WithWriteFile(c, ctx, path, opts, func(w io.Writer) error {
+		_, err := w.Write(contents)
+		return err
+	})
+}
+
+// See documentation for GCSClient interface. Only Bucket, Name, Size, Updated and MD5 are set.
+func (c *FSGCSClient) GetFileObjectAttrs(ctx context.Context, path string) (*storage.ObjectAttrs, error) {
+	full := c.fullPath(path)
+	fi, err := os.Stat(full)
+	if os.IsNotExist(err) {
+		return nil, storage.ErrObjectNotExist
+	}
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer util.Close(f)
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, skerr.Wrapf(err, "hashing %s", full)
+	}
+	return &storage.ObjectAttrs{
+		Bucket:  c.dir,
+		Name:    path,
+		Size:    fi.Size(),
+		Updated: fi.ModTime(),
+		MD5:     h.Sum(nil),
+	}, nil
+}
+
+// See documentation for GCSClient interface.
+func (c *FSGCSClient) AllFilesInDirectory(ctx context.Context, prefix string, callback func(item *storage.ObjectAttrs)) error {
+	return filepath.Walk(c.dir, func(full string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(c.dir, full)
+		if err != nil {
+			return skerr.Wrap(err)
+		}
+		path := filepath.ToSlash(rel)
+		if !strings.HasPrefix(path, prefix) {
+			return nil
+		}
+		callback(&storage.ObjectAttrs{
+			Bucket:  c.dir,
+			Name:    path,
+			Size:    fi.Size(),
+			Updated: fi.ModTime(),
+		})
+		return nil
+	})
+}
+
+// See documentation for GCSClient interface.
+func (c *FSGCSClient) DeleteFile(ctx context.Context, path string) error {
+	if err := os.Remove(c.fullPath(path)); err != nil && !os.IsNotExist(err) {
+		return skerr.Wrap(err)
+	}
+	return nil
+}
+
+// See documentation for GCSClient interface. It returns the directory the files are stored in.
+func (c *FSGCSClient) Bucket() string {
+	return c.dir
+}
+
+// make sure FSGCSClient implements the GCSClient interface
+var _ gcs.GCSClient = (*FSGCSClient)(nil)
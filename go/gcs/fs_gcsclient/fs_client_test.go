@@ -0,0 +1,77 @@
+package fs_gcsclient
+
+import (
+	"context"
+	"crypto/md5"
+	"sort"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/gcs"
+	"go.skia.org/infra/go/testutils"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+func TestSetGetDelete(t *testing.T) {
+	unittest.MediumTest(t)
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	c, err := New(dir)
+	require.NoError(t, err)
+
+	exists, err := c.DoesFileExist(ctx, "images/abc.png")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	_, err = c.GetFileContents(ctx, "images/abc.png")
+	assert.Equal(t, storage.ErrObjectNotExist, err)
+	_, err = c.GetFileObjectAttrs(ctx, "images/abc.png")
+	assert.Equal(t, storage.ErrObjectNotExist, err)
+
+	contents := []byte("not really a png")
+	require.NoError(t, c.SetFileContents(ctx, "images/abc.png", gcs.FileWriteOptions{}, contents))
+
+	exists, err = c.DoesFileExist(ctx, "images/abc.png")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	b, err := c.GetFileContents(ctx, "images/abc.png")
+	require.NoError(t, err)
+	assert.Equal(t, contents, b)
+
+	attrs, err := c.GetFileObjectAttrs(ctx, "images/abc.png")
+	require.NoError(t, err)
+	h := md5.Sum(contents)
+	assert.Equal(t, h[:], attrs.MD5)
+	assert.Equal(t, int64(len(contents)), attrs.Size)
+	assert.Equal(t, "images/abc.png", attrs.Name)
+
+	require.NoError(t, c.DeleteFile(ctx, "images/abc.png"))
+	exists, err = c.DoesFileExist(ctx, "images/abc.png")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	// Deleting a file that doesn't exist is not an error.
+	require.NoError(t, c.DeleteFile(ctx, "images/abc.png"))
+}
+
+func TestAllFilesInDirectory(t *testing.T) {
+	unittest.MediumTest(t)
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	c, err := New(dir)
+	require.NoError(t, err)
+	for _, p := range []string{"images/abc.png", "images/def.png", "other/ghi.txt"} {
+		require.NoError(t, c.SetFileContents(ctx, p, gcs.FileWriteOptions{}, []byte(p)))
+	}
+
+	var names []string
+	require.NoError(t, c.AllFilesInDirectory(ctx, "images/", func(item *storage.ObjectAttrs) {
+		names = append(names, item.Name)
+	}))
+	sort.Strings(names)
+	assert.Equal(t, []string{"images/abc.png", "images/def.png"}, names)
+}
@@ -0,0 +1,414 @@
+// Package gitlab provides a library for interacting with GitLab via its REST
+// API: https://docs.gitlab.com/ee/api/
+//
+// This library assumes that the http.Client provided in NewGitLab contains the
+// appropriate authentication, eg. a personal or project access token supplied
+// via oauth2.StaticTokenSource.
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+)
+
+const (
+	GITLAB_TOKEN_FILENAME    = "gitlab_token"
+	GITLAB_TOKEN_SERVER_PATH = "/var/secrets/gitlab-token"
+
+	// States of a merge request.
+	MR_STATE_OPENED = "opened"
+	MR_STATE_CLOSED = "closed"
+	MR_STATE_LOCKED = "locked"
+	MR_STATE_MERGED = "merged"
+
+	// Merge status of a merge request, indicating whether it has conflicts.
+	MERGE_STATUS_CAN_BE_MERGED    = "can_be_merged"
+	MERGE_STATUS_CANNOT_BE_MERGED = "cannot_be_merged"
+	MERGE_STATUS_UNCHECKED        = "unchecked"
+
+	// Statuses of pipelines and jobs.
+	PIPELINE_STATUS_CREATED              = "created"
+	PIPELINE_STATUS_WAITING_FOR_RESOURCE = "waiting_for_resource"
+	PIPELINE_STATUS_PREPARING            = "preparing"
+	PIPELINE_STATUS_PENDING              = "pending"
+	PIPELINE_STATUS_RUNNING              = "running"
+	PIPELINE_STATUS_SUCCESS              = "success"
+	PIPELINE_STATUS_FAILED               = "failed"
+	PIPELINE_STATUS_CANCELED             = "canceled"
+	PIPELINE_STATUS_SKIPPED              = "skipped"
+	PIPELINE_STATUS_MANUAL               = "manual"
+	PIPELINE_STATUS_SCHEDULED            = "scheduled"
+
+	// Maximum number of items per page allowed by the API.
+	maxPerPage = 100
+	// Maximum number of pages we'll request for a single listing, to avoid
+	// looping forever on an unexpected response.
+	maxPages = 20
+)
+
+// ErrNotFound is returned when the requested resource does not exist.
+var ErrNotFound = errors.New("Requested GitLab resource does not exist.")
+
+// PipelineFinished returns true iff the given pipeline or job status indicates
+// that it will make no further progress without intervention.
+func PipelineFinished(status string) bool {
+	return util.In(status, []string{PIPELINE_STATUS_SUCCESS, PIPELINE_STATUS_FAILED, PIPELINE_STATUS_CANCELED, PIPELINE_STATUS_SKIPPED})
+}
+
+// User represents a GitLab user.
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	// Only provided for the authenticated user.
+	Email string `json:"email,omitempty"`
+}
+
+// Pipeline represents a GitLab CI pipeline.
+type Pipeline struct {
+	ID     int64  `json:"id"`
+	SHA    string `json:"sha"`
+	Ref    string `json:"ref"`
+	Status string `json:"status"`
+	WebURL string `json:"web_url"`
+}
+
+// Job represents a single job within a Pipeline.
+type Job struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	Stage        string     `json:"stage"`
+	Status       string     `json:"status"`
+	AllowFailure bool       `json:"allow_failure"`
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at"`
+	WebURL       string     `json:"web_url"`
+}
+
+// MergeRequest represents a GitLab merge request.
+type MergeRequest struct {
+	ID                        int64     `json:"id"`
+	IID                       int64     `json:"iid"`
+	Title                     string    `json:"title"`
+	Description               string    `json:"description"`
+	State                     string    `json:"state"`
+	SourceBranch              string    `json:"source_branch"`
+	TargetBranch              string    `json:"target_branch"`
+	SHA                       string    `json:"sha"`
+	MergeCommitSHA            string    `json:"merge_commit_sha"`
+	MergeStatus               string    `json:"merge_status"`
+	MergeWhenPipelineSucceeds bool      `json:"merge_when_pipeline_succeeds"`
+	Labels                    []string  `json:"labels"`
+	Author                    *User     `json:"author"`
+	CreatedAt                 time.Time `json:"created_at"`
+	UpdatedAt                 time.Time `json:"updated_at"`
+	HeadPipeline              *Pipeline `json:"head_pipeline"`
+	WebURL                    string    `json:"web_url"`
+}
+
+// MergeRequestVersion represents one version of the diff of a merge request,
+// created each time new commits are pushed to its source branch.
+type MergeRequestVersion struct {
+	ID            int64     `json:"id"`
+	HeadCommitSHA string    `json:"head_commit_sha"`
+	CreatedAt     time.Time `json:"created_at"`
+	State         string    `json:"state"`
+}
+
+// CreateMergeRequestOptions provides the parameters for a new merge request.
+type CreateMergeRequestOptions struct {
+	SourceBranch       string   `json:"source_branch"`
+	TargetBranch       string   `json:"target_branch"`
+	Title              string   `json:"title"`
+	Description        string   `json:"description,omitempty"`
+	Labels             []string `json:"-"`
+	RemoveSourceBranch bool     `json:"remove_source_branch,omitempty"`
+	Squash             bool     `json:"squash,omitempty"`
+}
+
+// GitLab is used for interacting with the API of a GitLab instance, on behalf
+// of a single project.
+type GitLab struct {
+	// Base URL of the GitLab instance, eg. "https://gitlab.com".
+	URL string
+	// Full path of the project, eg. "group/project".
+	Project string
+
+	client *http.Client
+}
+
+// NewGitLab returns a GitLab instance for the given project.
+func NewGitLab(gitlabURL, project string, client *http.Client) (*GitLab, error) {
+	if gitlabURL == "" {
+		return nil, errors.New("GitLab URL is required")
+	}
+	if project == "" {
+		return nil, errors.New("GitLab project is required")
+	}
+	return &GitLab{
+		URL:     strings.TrimSuffix(gitlabURL, "/"),
+		Project: project,
+		client:  client,
+	}, nil
+}
+
+// apiURL returns the URL of the given API endpoint.
+func (g *GitLab) apiURL(path string) string {
+	return g.URL + "/api/v4" + path
+}
+
+// projectURL returns the URL of the given API endpoint within the project.
+func (g *GitLab) projectURL(format string, args ...interface{}) string {
+	return g.apiURL("/projects/" + url.PathEscape(g.Project) + fmt.Sprintf(format, args...))
+}
+
+// do performs the given request and decodes the JSON response into rv, if
+// non-nil. Returns the response headers.
+func (g *GitLab) do(ctx context.Context, method, u string, body interface{}, rv interface{}) (http.Header, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := g.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed to %s %s", method, u)
+	}
+	defer util.Close(resp.Body)
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed to read response from %s %s", method, u)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, skerr.Fmt("Unexpected status code %d from %s %s: %s", resp.StatusCode, method, u, strings.TrimSpace(string(b)))
+	}
+	if rv != nil {
+		if err := json.Unmarshal(b, rv); err != nil {
+			return nil, skerr.Wrapf(err, "Failed to decode response from %s %s", method, u)
+		}
+	}
+	return resp.Header, nil
+}
+
+// getPaged performs a GET request for each page of the given listing endpoint,
+// calling fn with the body of each page. fn should return the number of items
+// decoded from the page.
+func (g *GitLab) getPaged(ctx context.Context, u string, fn func([]byte) (int, error)) error {
+	sep := "?"
+	if strings.Contains(u, "?") {
+		sep = "&"
+	}
+	page := "1"
+	for i := 0; i < maxPages && page != ""; i++ {
+		var raw json.RawMessage
+		hdr, err := g.do(ctx, http.MethodGet, fmt.Sprintf("%s%sper_page=%d&page=%s", u, sep, maxPerPage, page), nil, &raw)
+		if err != nil {
+			return err
+		}
+		n, err := fn(raw)
+		if err != nil {
+			return skerr.Wrapf(err, "Failed to decode response from %s", u)
+		}
+		if n == 0 {
+			break
+		}
+		page = hdr.Get("X-Next-Page")
+	}
+	return nil
+}
+
+// See https://docs.gitlab.com/ee/api/users.html#for-normal-users-1
+// for the API documentation.
+func (g *GitLab) GetAuthenticatedUser(ctx context.Context) (*User, error) {
+	var rv User
+	if _, err := g.do(ctx, http.MethodGet, g.apiURL("/user"), nil, &rv); err != nil {
+		return nil, err
+	}
+	return &rv, nil
+}
+
+// See https://docs.gitlab.com/ee/api/merge_requests.html#create-mr
+// for the API documentation.
+func (g *GitLab) CreateMergeRequest(ctx context.Context, opts *CreateMergeRequestOptions) (*MergeRequest, error) {
+	// The API expects labels as a comma-separated string.
+	body := struct {
+		*CreateMergeRequestOptions
+		Labels string `json:"labels,omitempty"`
+	}{
+		CreateMergeRequestOptions: opts,
+		Labels:                    strings.Join(opts.Labels, ","),
+	}
+	var rv MergeRequest
+	if _, err := g.do(ctx, http.MethodPost, g.projectURL("/merge_requests"), body, &rv); err != nil {
+		return nil, err
+	}
+	return &rv, nil
+}
+
+// See https://docs.gitlab.com/ee/api/merge_requests.html#get-single-mr
+// for the API documentation.
+func (g *GitLab) GetMergeRequest(ctx context.Context, iid int64) (*MergeRequest, error) {
+	var rv MergeRequest
+	if _, err := g.do(ctx, http.MethodGet, g.projectURL("/merge_requests/%d", iid), nil, &rv); err != nil {
+		return nil, err
+	}
+	return &rv, nil
+}
+
+// See https://docs.gitlab.com/ee/api/merge_requests.html#get-mr-diff-versions
+// for the API documentation. Versions are returned in reverse chronological
+// order.
+func (g *GitLab) GetMergeRequestVersions(ctx context.Context, iid int64) ([]*MergeRequestVersion, error) {
+	rv := []*MergeRequestVersion{}
+	if err := g.getPaged(ctx, g.projectURL("/merge_requests/%d/versions", iid), func(b []byte) (int, error) {
+		var versions []*MergeRequestVersion
+		if err := json.Unmarshal(b, &versions); err != nil {
+			return 0, err
+		}
+		rv = append(rv, versions...)
+		return len(versions), nil
+	}); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// updateMergeRequest updates the given merge request with the given params.
+// See https://docs.gitlab.com/ee/api/merge_requests.html#update-mr
+// for the API documentation.
+func (g *GitLab) updateMergeRequest(ctx context.Context, iid int64, params map[string]string) (*MergeRequest, error) {
+	var rv MergeRequest
+	if _, err := g.do(ctx, http.MethodPut, g.projectURL("/merge_requests/%d", iid), params, &rv); err != nil {
+		return nil, err
+	}
+	return &rv, nil
+}
+
+// AddLabels adds the given labels to the merge request.
+func (g *GitLab) AddLabels(ctx context.Context, iid int64, labels ...string) error {
+	_, err := g.updateMergeRequest(ctx, iid, map[string]string{"add_labels": strings.Join(labels, ",")})
+	return err
+}
+
+// RemoveLabels removes the given labels from the merge request.
+func (g *GitLab) RemoveLabels(ctx context.Context, iid int64, labels ...string) error {
+	_, err := g.updateMergeRequest(ctx, iid, map[string]string{"remove_labels": strings.Join(labels, ",")})
+	return err
+}
+
+// CloseMergeRequest closes the merge request without merging it.
+func (g *GitLab) CloseMergeRequest(ctx context.Context, iid int64) (*MergeRequest, error) {
+	mr, err := g.updateMergeRequest(ctx, iid, map[string]string{"state_event": "close"})
+	if err != nil {
+		return nil, err
+	}
+	if mr.State != MR_STATE_CLOSED {
+		return nil, skerr.Fmt("Tried to close merge request %d but the state is %s", iid, mr.State)
+	}
+	return mr, nil
+}
+
+// See https://docs.gitlab.com/ee/api/notes.html#create-new-merge-request-note
+// for the API documentation.
+func (g *GitLab) AddComment(ctx context.Context, iid int64, msg string) error {
+	_, err := g.do(ctx, http.MethodPost, g.projectURL("/merge_requests/%d/notes", iid), map[string]string{"body": msg}, nil)
+	return err
+}
+
+// MergeWhenPipelineSucceeds instructs GitLab to merge the merge request once
+// its pipeline succeeds. If sha is provided, it must match the head of the
+// source branch. Note that GitLab merges immediately if there is no active
+// pipeline, so callers should ensure that one exists.
+// See https://docs.gitlab.com/ee/api/merge_requests.html#accept-mr
+// for the API documentation.
+func (g *GitLab) MergeWhenPipelineSucceeds(ctx context.Context, iid int64, sha string) error {
+	params := map[string]interface{}{
+		"merge_when_pipeline_succeeds": true,
+	}
+	if sha != "" {
+		params["sha"] = sha
+	}
+	_, err := g.do(ctx, http.MethodPut, g.projectURL("/merge_requests/%d/merge", iid), params, nil)
+	return err
+}
+
+// See https://docs.gitlab.com/ee/api/merge_requests.html#cancel-merge-when-pipeline-succeeds
+// for the API documentation.
+func (g *GitLab) CancelMergeWhenPipelineSucceeds(ctx context.Context, iid int64) error {
+	_, err := g.do(ctx, http.MethodPost, g.projectURL("/merge_requests/%d/cancel_merge_when_pipeline_succeeds", iid), nil, nil)
+	return err
+}
+
+// See https://docs.gitlab.com/ee/api/jobs.html#list-pipeline-jobs
+// for the API documentation.
+func (g *GitLab) GetPipelineJobs(ctx context.Context, pipelineID int64) ([]*Job, error) {
+	rv := []*Job{}
+	if err := g.getPaged(ctx, g.projectURL("/pipelines/%d/jobs", pipelineID), func(b []byte) (int, error) {
+		var jobs []*Job
+		if err := json.Unmarshal(b, &jobs); err != nil {
+			return 0, err
+		}
+		rv = append(rv, jobs...)
+		return len(jobs), nil
+	}); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// See https://docs.gitlab.com/ee/api/pipelines.html#retry-jobs-in-a-pipeline
+// for the API documentation.
+func (g *GitLab) RetryPipeline(ctx context.Context, pipelineID int64) (*Pipeline, error) {
+	var rv Pipeline
+	if _, err := g.do(ctx, http.MethodPost, g.projectURL("/pipelines/%d/retry", pipelineID), nil, &rv); err != nil {
+		return nil, err
+	}
+	return &rv, nil
+}
+
+// GetIssueUrlBase returns a base URL which can be used to construct URLs for
+// individual merge requests.
+func (g *GitLab) GetIssueUrlBase() string {
+	return fmt.Sprintf("%s/%s/-/merge_requests/", g.URL, g.Project)
+}
+
+// GetFullHistoryUrl returns a URL listing all merge requests created by the
+// given user.
+func (g *GitLab) GetFullHistoryUrl(username string) string {
+	return fmt.Sprintf("%s/%s/-/merge_requests?scope=all&state=all&author_username=%s", g.URL, g.Project, url.QueryEscape(username))
+}
+
+// ParseMergeRequestID parses the given merge request IID.
+func ParseMergeRequestID(id string) (int64, error) {
+	iid, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || iid <= 0 {
+		return 0, skerr.Fmt("Invalid merge request ID %q", id)
+	}
+	return iid, nil
+}
@@ -0,0 +1,161 @@
+package gitlab_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/gitlab"
+	"go.skia.org/infra/go/gitlab/testutils"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+func TestNewGitLab(t *testing.T) {
+	unittest.SmallTest(t)
+
+	_, err := gitlab.NewGitLab("", "group/project", nil)
+	require.EqualError(t, err, "GitLab URL is required")
+	_, err = gitlab.NewGitLab("https://gitlab.example.com", "", nil)
+	require.EqualError(t, err, "GitLab project is required")
+
+	g, err := gitlab.NewGitLab("https://gitlab.example.com/", "group/project", nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://gitlab.example.com/group/project/-/merge_requests/", g.GetIssueUrlBase())
+	require.Equal(t, "https://gitlab.example.com/group/project/-/merge_requests?scope=all&state=all&author_username=some-user", g.GetFullHistoryUrl("some-user"))
+}
+
+func TestParseMergeRequestID(t *testing.T) {
+	unittest.SmallTest(t)
+
+	iid, err := gitlab.ParseMergeRequestID("42")
+	require.NoError(t, err)
+	require.Equal(t, int64(42), iid)
+	for _, bad := range []string{"", "0", "-1", "abc"} {
+		_, err := gitlab.ParseMergeRequestID(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestMergeRequests(t *testing.T) {
+	unittest.MediumTest(t)
+
+	ctx := context.Background()
+	f := testutils.NewFakeGitLab(t)
+	defer f.Close()
+	g := f.GitLab()
+
+	user, err := g.GetAuthenticatedUser(ctx)
+	require.NoError(t, err)
+	require.Equal(t, testutils.FAKE_USERNAME, user.Username)
+	require.Equal(t, testutils.FAKE_EMAIL, user.Email)
+
+	// Create a merge request.
+	mr, err := g.CreateMergeRequest(ctx, &gitlab.CreateMergeRequestOptions{
+		SourceBranch: "roll",
+		TargetBranch: "master",
+		Title:        "Roll the deps",
+		Description:  "Rolling the deps.",
+		Labels:       []string{"autoroll"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), mr.IID)
+	require.Equal(t, gitlab.MR_STATE_OPENED, mr.State)
+	require.Equal(t, []string{"autoroll"}, mr.Labels)
+	require.Equal(t, testutils.FAKE_USERNAME, mr.Author.Username)
+	f.PushCommit(mr.IID, "abc123")
+	f.PushCommit(mr.IID, "def456")
+
+	mr, err = g.GetMergeRequest(ctx, mr.IID)
+	require.NoError(t, err)
+	require.Equal(t, "Roll the deps", mr.Title)
+	require.Equal(t, "def456", mr.SHA)
+	_, err = g.GetMergeRequest(ctx, 99)
+	require.Equal(t, gitlab.ErrNotFound, err)
+
+	versions, err := g.GetMergeRequestVersions(ctx, mr.IID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	require.Equal(t, "def456", versions[0].HeadCommitSHA)
+	require.Equal(t, "abc123", versions[1].HeadCommitSHA)
+
+	// Labels.
+	require.NoError(t, g.AddLabels(ctx, mr.IID, "a", "b"))
+	require.Equal(t, []string{"autoroll", "a", "b"}, f.MergeRequest(mr.IID).Labels)
+	require.NoError(t, g.RemoveLabels(ctx, mr.IID, "autoroll", "b"))
+	require.Equal(t, []string{"a"}, f.MergeRequest(mr.IID).Labels)
+
+	// Comments.
+	require.NoError(t, g.AddComment(ctx, mr.IID, "hello"))
+	require.Equal(t, []string{"hello"}, f.Notes(mr.IID))
+
+	// Close.
+	mr, err = g.CloseMergeRequest(ctx, mr.IID)
+	require.NoError(t, err)
+	require.Equal(t, gitlab.MR_STATE_CLOSED, mr.State)
+}
+
+func TestPipelines(t *testing.T) {
+	unittest.MediumTest(t)
+
+	ctx := context.Background()
+	f := testutils.NewFakeGitLab(t)
+	defer f.Close()
+	g := f.GitLab()
+
+	mr := f.AddMergeRequest(&gitlab.MergeRequest{
+		Title:        "Roll the deps",
+		SourceBranch: "roll",
+		TargetBranch: "master",
+		SHA:          "abc123",
+	})
+
+	// There's no pipeline yet.
+	mr, err := g.GetMergeRequest(ctx, mr.IID)
+	require.NoError(t, err)
+	require.Nil(t, mr.HeadPipeline)
+
+	p := f.SetPipeline(mr.IID, gitlab.PIPELINE_STATUS_RUNNING, &gitlab.Job{
+		Name:   "build",
+		Status: gitlab.PIPELINE_STATUS_SUCCESS,
+	}, &gitlab.Job{
+		Name:   "test",
+		Status: gitlab.PIPELINE_STATUS_RUNNING,
+	})
+	mr, err = g.GetMergeRequest(ctx, mr.IID)
+	require.NoError(t, err)
+	require.Equal(t, p.ID, mr.HeadPipeline.ID)
+	require.Equal(t, gitlab.PIPELINE_STATUS_RUNNING, mr.HeadPipeline.Status)
+	require.False(t, gitlab.PipelineFinished(mr.HeadPipeline.Status))
+	jobs, err := g.GetPipelineJobs(ctx, p.ID)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	require.Equal(t, "build", jobs[0].Name)
+	require.Equal(t, "test", jobs[1].Name)
+
+	// Merge when the pipeline succeeds.
+	err = g.MergeWhenPipelineSucceeds(ctx, mr.IID, "wrong-sha")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Unexpected status code 409")
+	require.NoError(t, g.MergeWhenPipelineSucceeds(ctx, mr.IID, "abc123"))
+	require.True(t, f.MergeRequest(mr.IID).MergeWhenPipelineSucceeds)
+	require.NoError(t, g.CancelMergeWhenPipelineSucceeds(ctx, mr.IID))
+	require.False(t, f.MergeRequest(mr.IID).MergeWhenPipelineSucceeds)
+	require.NoError(t, g.MergeWhenPipelineSucceeds(ctx, mr.IID, ""))
+
+	// The pipeline fails, which cancels the merge. Retry it.
+	f.SetPipelineStatus(mr.IID, gitlab.PIPELINE_STATUS_FAILED)
+	require.True(t, gitlab.PipelineFinished(f.MergeRequest(mr.IID).HeadPipeline.Status))
+	require.False(t, f.MergeRequest(mr.IID).MergeWhenPipelineSucceeds)
+	retried, err := g.RetryPipeline(ctx, p.ID)
+	require.NoError(t, err)
+	require.Equal(t, gitlab.PIPELINE_STATUS_PENDING, retried.Status)
+	require.NoError(t, g.MergeWhenPipelineSucceeds(ctx, mr.IID, "abc123"))
+
+	// The pipeline succeeds and the merge request is merged.
+	f.SetPipelineStatus(mr.IID, gitlab.PIPELINE_STATUS_SUCCESS)
+	mr, err = g.GetMergeRequest(ctx, mr.IID)
+	require.NoError(t, err)
+	require.Equal(t, gitlab.MR_STATE_MERGED, mr.State)
+	_, err = g.CloseMergeRequest(ctx, mr.IID)
+	require.Error(t, err)
+}
@@ -0,0 +1,472 @@
+// Package testutils provides a fake GitLab server for use in tests.
+package testutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/gitlab"
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/go/util"
+)
+
+const (
+	FAKE_PROJECT  = "fake-group/fake-project"
+	FAKE_USERNAME = "fake-roller"
+	FAKE_EMAIL    = "fake-roller@example.com"
+)
+
+var (
+	// Matches API paths within FAKE_PROJECT, eg.
+	// "/merge_requests/12/notes" or "/pipelines/3/retry".
+	projectPathRegex  = regexp.MustCompile(`^/api/v4/projects/` + regexp.QuoteMeta(url.PathEscape(FAKE_PROJECT)) + `(/.*)$`)
+	mrPathRegex       = regexp.MustCompile(`^/merge_requests/(\d+)(/[a-z_]+)?$`)
+	pipelinePathRegex = regexp.MustCompile(`^/pipelines/(\d+)/(jobs|retry)$`)
+)
+
+// FakeGitLab is an in-memory fake of the subset of the GitLab API used by the
+// gitlab package. It runs a local HTTP server which the gitlab.GitLab
+// returned by GitLab() talks to.
+type FakeGitLab struct {
+	Server *httptest.Server
+	User   *gitlab.User
+
+	t sktest.TestingT
+
+	mtx            sync.Mutex
+	mrs            map[int64]*gitlab.MergeRequest
+	versions       map[int64][]*gitlab.MergeRequestVersion
+	notes          map[int64][]string
+	pipelines      map[int64]*gitlab.Pipeline
+	jobs           map[int64][]*gitlab.Job
+	nextIID        int64
+	nextPipelineID int64
+	nextVersionID  int64
+}
+
+// NewFakeGitLab returns a FakeGitLab instance. Callers must call Close when
+// finished.
+func NewFakeGitLab(t sktest.TestingT) *FakeGitLab {
+	f := &FakeGitLab{
+		User: &gitlab.User{
+			ID:       1,
+			Username: FAKE_USERNAME,
+			Name:     "Fake Roller",
+			Email:    FAKE_EMAIL,
+		},
+		t:              t,
+		mrs:            map[int64]*gitlab.MergeRequest{},
+		versions:       map[int64][]*gitlab.MergeRequestVersion{},
+		notes:          map[int64][]string{},
+		pipelines:      map[int64]*gitlab.Pipeline{},
+		jobs:           map[int64][]*gitlab.Job{},
+		nextIID:        1,
+		nextPipelineID: 1,
+		nextVersionID:  1,
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+// Close shuts down the server.
+func (f *FakeGitLab) Close() {
+	f.Server.Close()
+}
+
+// GitLab returns a gitlab.GitLab instance which talks to the fake server.
+func (f *FakeGitLab) GitLab() *gitlab.GitLab {
+	g, err := gitlab.NewGitLab(f.Server.URL, FAKE_PROJECT, f.Server.Client())
+	require.NoError(f.t, err)
+	return g
+}
+
+// now returns the current time with the precision used by the fake.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// copyMR returns a deep-enough copy of the given MergeRequest that the caller
+// may not modify the fake's state through it.
+func copyMR(mr *gitlab.MergeRequest) *gitlab.MergeRequest {
+	cp := *mr
+	cp.Labels = util.CopyStringSlice(mr.Labels)
+	if mr.HeadPipeline != nil {
+		p := *mr.HeadPipeline
+		cp.HeadPipeline = &p
+	}
+	if mr.Author != nil {
+		a := *mr.Author
+		cp.Author = &a
+	}
+	return &cp
+}
+
+// AddMergeRequest adds the given merge request to the fake, assigning an IID
+// and filling in defaults as needed. If mr.SHA is set, an initial version is
+// created. Returns a copy of the stored merge request.
+func (f *FakeGitLab) AddMergeRequest(mr *gitlab.MergeRequest) *gitlab.MergeRequest {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return copyMR(f.addMergeRequest(copyMR(mr)))
+}
+
+// addMergeRequest assumes that the caller holds f.mtx.
+func (f *FakeGitLab) addMergeRequest(mr *gitlab.MergeRequest) *gitlab.MergeRequest {
+	if mr.IID == 0 {
+		mr.IID = f.nextIID
+	}
+	if mr.IID >= f.nextIID {
+		f.nextIID = mr.IID + 1
+	}
+	mr.ID = mr.IID + 1000
+	if mr.State == "" {
+		mr.State = gitlab.MR_STATE_OPENED
+	}
+	if mr.MergeStatus == "" {
+		mr.MergeStatus = gitlab.MERGE_STATUS_CAN_BE_MERGED
+	}
+	if mr.Labels == nil {
+		mr.Labels = []string{}
+	}
+	if mr.Author == nil {
+		a := *f.User
+		a.Email = ""
+		mr.Author = &a
+	}
+	if mr.CreatedAt.IsZero() {
+		mr.CreatedAt = now()
+	}
+	if mr.UpdatedAt.IsZero() {
+		mr.UpdatedAt = mr.CreatedAt
+	}
+	mr.WebURL = fmt.Sprintf("%s/%s/-/merge_requests/%d", f.Server.URL, FAKE_PROJECT, mr.IID)
+	f.mrs[mr.IID] = mr
+	if mr.SHA != "" {
+		f.pushCommit(mr, mr.SHA)
+	}
+	return mr
+}
+
+// MergeRequest returns a copy of the given merge request.
+func (f *FakeGitLab) MergeRequest(iid int64) *gitlab.MergeRequest {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	mr, ok := f.mrs[iid]
+	require.True(f.t, ok, "No such merge request %d", iid)
+	return copyMR(mr)
+}
+
+// UpdateMergeRequest runs the given function on the stored merge request.
+func (f *FakeGitLab) UpdateMergeRequest(iid int64, fn func(*gitlab.MergeRequest)) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	mr, ok := f.mrs[iid]
+	require.True(f.t, ok, "No such merge request %d", iid)
+	fn(mr)
+	mr.UpdatedAt = now()
+}
+
+// PushCommit simulates pushing the given commit to the source branch of the
+// merge request, creating a new version.
+func (f *FakeGitLab) PushCommit(iid int64, sha string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	mr, ok := f.mrs[iid]
+	require.True(f.t, ok, "No such merge request %d", iid)
+	f.pushCommit(mr, sha)
+}
+
+// pushCommit assumes that the caller holds f.mtx.
+func (f *FakeGitLab) pushCommit(mr *gitlab.MergeRequest, sha string) {
+	mr.SHA = sha
+	mr.UpdatedAt = now()
+	v := &gitlab.MergeRequestVersion{
+		ID:            f.nextVersionID,
+		HeadCommitSHA: sha,
+		CreatedAt:     mr.UpdatedAt,
+		State:         "collected",
+	}
+	f.nextVersionID++
+	// Versions are listed in reverse chronological order.
+	f.versions[mr.IID] = append([]*gitlab.MergeRequestVersion{v}, f.versions[mr.IID]...)
+}
+
+// SetPipeline creates a new head pipeline for the merge request with the given
+// status and jobs. Like GitLab, a successful pipeline merges the merge request
+// if it is set to merge when the pipeline succeeds, and a failed pipeline
+// cancels that setting.
+func (f *FakeGitLab) SetPipeline(iid int64, status string, jobs ...*gitlab.Job) *gitlab.Pipeline {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	mr, ok := f.mrs[iid]
+	require.True(f.t, ok, "No such merge request %d", iid)
+	p := &gitlab.Pipeline{
+		ID:     f.nextPipelineID,
+		SHA:    mr.SHA,
+		Ref:    mr.SourceBranch,
+		Status: status,
+	}
+	p.WebURL = fmt.Sprintf("%s/%s/-/pipelines/%d", f.Server.URL, FAKE_PROJECT, p.ID)
+	f.nextPipelineID++
+	for idx, j := range jobs {
+		if j.ID == 0 {
+			j.ID = p.ID*100 + int64(idx)
+		}
+		if j.CreatedAt.IsZero() {
+			j.CreatedAt = now()
+		}
+	}
+	f.pipelines[p.ID] = p
+	f.jobs[p.ID] = jobs
+	mr.HeadPipeline = p
+	f.pipelineUpdated(mr)
+	cp := *p
+	return &cp
+}
+
+// SetPipelineStatus updates the status of the merge request's head pipeline.
+func (f *FakeGitLab) SetPipelineStatus(iid int64, status string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	mr, ok := f.mrs[iid]
+	require.True(f.t, ok, "No such merge request %d", iid)
+	require.NotNil(f.t, mr.HeadPipeline, "Merge request %d has no pipeline", iid)
+	mr.HeadPipeline.Status = status
+	f.pipelines[mr.HeadPipeline.ID].Status = status
+	f.pipelineUpdated(mr)
+}
+
+// pipelineUpdated assumes that the caller holds f.mtx.
+func (f *FakeGitLab) pipelineUpdated(mr *gitlab.MergeRequest) {
+	mr.UpdatedAt = now()
+	if !mr.MergeWhenPipelineSucceeds || mr.HeadPipeline == nil {
+		return
+	}
+	switch mr.HeadPipeline.Status {
+	case gitlab.PIPELINE_STATUS_SUCCESS:
+		f.merge(mr)
+	case gitlab.PIPELINE_STATUS_FAILED, gitlab.PIPELINE_STATUS_CANCELED:
+		mr.MergeWhenPipelineSucceeds = false
+	}
+}
+
+// merge assumes that the caller holds f.mtx.
+func (f *FakeGitLab) merge(mr *gitlab.MergeRequest) {
+	mr.State = gitlab.MR_STATE_MERGED
+	mr.MergeWhenPipelineSucceeds = false
+	mr.MergeCommitSHA = "merge-" + mr.SHA
+	mr.UpdatedAt = now()
+}
+
+// Notes returns the comments on the given merge request.
+func (f *FakeGitLab) Notes(iid int64) []string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return util.CopyStringSlice(f.notes[iid])
+}
+
+// writeJSON writes the given value as the response.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response in the format used by GitLab.
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"message": msg})
+}
+
+// handle is the http.HandlerFunc for the fake server.
+func (f *FakeGitLab) handle(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	// Decode parameters from the JSON body, if any.
+	params := map[string]interface{}{}
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	param := func(key string) string {
+		if v, ok := params[key]; ok {
+			return fmt.Sprintf("%v", v)
+		}
+		return ""
+	}
+
+	path := r.URL.EscapedPath()
+	if path == "/api/v4/user" && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, f.User)
+		return
+	}
+	m := projectPathRegex.FindStringSubmatch(path)
+	if m == nil {
+		writeError(w, http.StatusNotFound, "404 Project Not Found")
+		return
+	}
+	path = m[1]
+
+	// Create a merge request.
+	if path == "/merge_requests" && r.Method == http.MethodPost {
+		if param("source_branch") == "" || param("target_branch") == "" || param("title") == "" {
+			writeError(w, http.StatusBadRequest, "source_branch, target_branch and title are required")
+			return
+		}
+		mr := &gitlab.MergeRequest{
+			Title:        param("title"),
+			Description:  param("description"),
+			SourceBranch: param("source_branch"),
+			TargetBranch: param("target_branch"),
+		}
+		if labels := param("labels"); labels != "" {
+			mr.Labels = strings.Split(labels, ",")
+		}
+		writeJSON(w, http.StatusCreated, f.addMergeRequest(mr))
+		return
+	}
+
+	// Pipelines.
+	if m := pipelinePathRegex.FindStringSubmatch(path); m != nil {
+		id, _ := strconv.ParseInt(m[1], 10, 64)
+		p, ok := f.pipelines[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, "404 Not found")
+			return
+		}
+		if m[2] == "jobs" && r.Method == http.MethodGet {
+			if r.URL.Query().Get("page") != "1" {
+				writeJSON(w, http.StatusOK, []*gitlab.Job{})
+				return
+			}
+			writeJSON(w, http.StatusOK, f.jobs[id])
+			return
+		} else if m[2] == "retry" && r.Method == http.MethodPost {
+			for _, j := range f.jobs[id] {
+				if j.Status == gitlab.PIPELINE_STATUS_FAILED || j.Status == gitlab.PIPELINE_STATUS_CANCELED {
+					j.Status = gitlab.PIPELINE_STATUS_PENDING
+				}
+			}
+			p.Status = gitlab.PIPELINE_STATUS_PENDING
+			writeJSON(w, http.StatusCreated, p)
+			return
+		}
+		writeError(w, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		return
+	}
+
+	// Merge requests.
+	m = mrPathRegex.FindStringSubmatch(path)
+	if m == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	iid, _ := strconv.ParseInt(m[1], 10, 64)
+	mr, ok := f.mrs[iid]
+	if !ok {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return
+	}
+	switch {
+	case m[2] == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, mr)
+	case m[2] == "" && r.Method == http.MethodPut:
+		switch param("state_event") {
+		case "close":
+			if mr.State == gitlab.MR_STATE_MERGED {
+				writeError(w, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+				return
+			}
+			mr.State = gitlab.MR_STATE_CLOSED
+			mr.MergeWhenPipelineSucceeds = false
+		case "reopen":
+			mr.State = gitlab.MR_STATE_OPENED
+		}
+		if title := param("title"); title != "" {
+			mr.Title = title
+		}
+		if desc := param("description"); desc != "" {
+			mr.Description = desc
+		}
+		if add := param("add_labels"); add != "" {
+			for _, l := range strings.Split(add, ",") {
+				if !util.In(l, mr.Labels) {
+					mr.Labels = append(mr.Labels, l)
+				}
+			}
+		}
+		if remove := param("remove_labels"); remove != "" {
+			toRemove := strings.Split(remove, ",")
+			labels := []string{}
+			for _, l := range mr.Labels {
+				if !util.In(l, toRemove) {
+					labels = append(labels, l)
+				}
+			}
+			mr.Labels = labels
+		}
+		mr.UpdatedAt = now()
+		writeJSON(w, http.StatusOK, mr)
+	case m[2] == "/versions" && r.Method == http.MethodGet:
+		if r.URL.Query().Get("page") != "1" {
+			writeJSON(w, http.StatusOK, []*gitlab.MergeRequestVersion{})
+			return
+		}
+		versions := f.versions[iid]
+		if versions == nil {
+			versions = []*gitlab.MergeRequestVersion{}
+		}
+		writeJSON(w, http.StatusOK, versions)
+	case m[2] == "/notes" && r.Method == http.MethodPost:
+		if param("body") == "" {
+			writeError(w, http.StatusBadRequest, "body is required")
+			return
+		}
+		f.notes[iid] = append(f.notes[iid], param("body"))
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"id": len(f.notes[iid]), "body": param("body")})
+	case m[2] == "/merge" && r.Method == http.MethodPut:
+		if mr.State != gitlab.MR_STATE_OPENED {
+			writeError(w, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+			return
+		}
+		if mr.MergeStatus == gitlab.MERGE_STATUS_CANNOT_BE_MERGED {
+			writeError(w, http.StatusNotAcceptable, "Branch cannot be merged")
+			return
+		}
+		if sha := param("sha"); sha != "" && sha != mr.SHA {
+			writeError(w, http.StatusConflict, "SHA does not match HEAD of source branch")
+			return
+		}
+		if param("merge_when_pipeline_succeeds") == "true" && mr.HeadPipeline != nil && !gitlab.PipelineFinished(mr.HeadPipeline.Status) {
+			mr.MergeWhenPipelineSucceeds = true
+			mr.UpdatedAt = now()
+		} else if mr.HeadPipeline != nil && mr.HeadPipeline.Status != gitlab.PIPELINE_STATUS_SUCCESS {
+			writeError(w, http.StatusMethodNotAllowed, "Pipeline has not succeeded")
+			return
+		} else {
+			f.merge(mr)
+		}
+		writeJSON(w, http.StatusOK, mr)
+	case m[2] == "/cancel_merge_when_pipeline_succeeds" && r.Method == http.MethodPost:
+		if !mr.MergeWhenPipelineSucceeds {
+			writeError(w, http.StatusNotAcceptable, "Merge request is not set to merge when pipeline succeeds")
+			return
+		}
+		mr.MergeWhenPipelineSucceeds = false
+		mr.UpdatedAt = now()
+		writeJSON(w, http.StatusCreated, mr)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+	}
+}
@@ -3,12 +3,15 @@ package ingestion
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"regexp"
 	"sync"
 	"time"
@@ -159,7 +162,7 @@ func getSource(id string, dataSource *sharedconfig.DataSource, client *http.Clie
 		return NewGoogleStorageSource(id, dataSource.Bucket, dataSource.Dir, client, eventBus)
 	}
 
-	return nil, skerr.Fmt("Unable to create source. At least a bucket and directory must be supplied")
+	return NewFileSystemSource(id, dataSource.Dir), nil
 }
 
 // validIngestionFile returns true if the given file name matches basic rules.
@@ -258,6 +261,120 @@ func (g *GoogleStorageSource) SetEventChannel(resultCh chan<- ResultFileLocation
 	return nil
 }
 
+// FileSystemSource implements the Source interface for a directory on the local file system that
+// uses the same hourly layout as the Google Storage buckets. It only supports polling.
+type FileSystemSource struct {
+	rootDir string
+	id      string
+}
+
+// NewFileSystemSource returns a new instance of FileSystemSource that reads the files below
+// rootDir. The id is used to identify the Source and is generally the same id as the ingester.
+func NewFileSystemSource(baseName, rootDir string) Source {
+	return &FileSystemSource{
+		rootDir: rootDir,
+		id:      fmt.Sprintf("%s:file://%s", baseName, rootDir),
+	}
+}
+
+// See Source interface.
+func (f *FileSystemSource) Poll(startTime, endTime int64) <-chan ResultFileLocation {
+	dirs := fileutil.GetHourlyDirs(f.rootDir, startTime, endTime)
+	ch := make(chan ResultFileLocation, maxConcurrentDirPollers)
+
+	go func() {
+		defer close(ch)
+		for _, dir := range dirs {
+			err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					if os.IsNotExist(err) {
+						return nil
+					}
+					return err
+				}
+				if !info.IsDir() && validIngestionFile(path) && info.ModTime().Unix() > startTime {
+					ch <- newFileResultFileLocation(path, info.ModTime().Unix())
+				}
+				return nil
+			})
+			if err != nil {
+				sklog.Errorf("Error occurred while retrieving files from %s: %s", dir, err)
+			}
+		}
+	}()
+
+	return ch
+}
+
+// See Source interface.
+func (f *FileSystemSource) ID() string {
+	return f.id
+}
+
+// SetEventChannel implements the Source interface. The local file system does not emit storage
+// events, so new files are only found by polling.
+func (f *FileSystemSource) SetEventChannel(resultCh chan<- ResultFileLocation) error {
+	return nil
+}
+
+// fileResultFileLocation implements the ResultFileLocation for the local file system.
+type fileResultFileLocation struct {
+	path        string
+	lastUpdated int64
+	md5         string
+	content     []byte
+}
+
+func newFileResultFileLocation(path string, lastUpdated int64) ResultFileLocation {
+	return &fileResultFileLocation{
+		path:        path,
+		lastUpdated: lastUpdated,
+	}
+}
+
+// See ResultFileLocation interface. The content and its MD5 hash are read on the first call.
+func (f *fileResultFileLocation) Open() (io.ReadCloser, error) {
+	if f.content == nil {
+		content, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "reading %s", f.path)
+		}
+		f.content = content
+		f.md5 = fmt.Sprintf("%x", md5.Sum(content))
+	}
+	return ioutil.NopCloser(bytes.NewBuffer(f.content)), nil
+}
+
+// See ResultFileLocation interface.
+func (f *fileResultFileLocation) Name() string {
+	return f.path
+}
+
+// StorageIDs implements the ResultFileLocation interface. The local file system has no bucket.
+func (f *fileResultFileLocation) StorageIDs() (string, string) {
+	return "", f.path
+}
+
+// See ResultFileLocation interface.
+func (f *fileResultFileLocation) MD5() string {
+	if f.md5 == "" {
+		if _, err := f.Open(); err != nil {
+			sklog.Errorf("Could not compute MD5 of %s: %s", f.path, err)
+		}
+	}
+	return f.md5
+}
+
+// See ResultFileLocation interface.
+func (f *fileResultFileLocation) TimeStamp() int64 {
+	return f.lastUpdated
+}
+
+// See ResultFileLocation interface.
+func (f *fileResultFileLocation) Content() []byte {
+	return f.content
+}
+
 // gsResultFileLocation implements the ResultFileLocation for Google storage.
 type gsResultFileLocation struct {
 	bucket        string
@@ -1,8 +1,12 @@
 package ingestion
 
 import (
+	"crypto/md5"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
@@ -10,6 +14,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"go.skia.org/infra/go/gcs/gcs_testutils"
+	"go.skia.org/infra/go/testutils"
 	"go.skia.org/infra/go/testutils/unittest"
 )
 
@@ -44,6 +49,31 @@ func TestGoogleStorageSource(t *testing.T) {
 	testSource(t, src)
 }
 
+func TestFileSystemSource(t *testing.T) {
+	unittest.MediumTest(t)
+	rootDir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	// Lay out the same files that are in the test bucket, each with distinct content.
+	for _, name := range readTestFileNames(t) {
+		p := filepath.Join(rootDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, ioutil.WriteFile(p, []byte(name), 0644))
+	}
+
+	src := NewFileSystemSource("fs-test-src", rootDir)
+	testSource(t, src)
+
+	results := drainPollChannel(src.Poll(START_TIME, END_TIME))
+	require.NotEmpty(t, results)
+	r, err := results[0].Open()
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("%x", md5.Sum(b)), results[0].MD5())
+	require.Equal(t, b, results[0].Content())
+}
+
 func testSource(t *testing.T, src Source) {
 	testFilePaths := readTestFileNames(t)
 
@@ -0,0 +1,69 @@
+// Package sql_ingestionstore supplies an SQL-backed (SQLite or Postgres) implementation of
+// ingestion.IngestionStore.
+package sql_ingestionstore
+
+import (
+	"database/sql"
+
+	"go.skia.org/infra/go/ingestion"
+	"go.skia.org/infra/go/metrics2"
+	"go.skia.org/infra/go/skerr"
+)
+
+const (
+	// schema creates the IngestedFiles table, which has one row per ingested file/md5 combination.
+	schema = `CREATE TABLE IF NOT EXISTS IngestedFiles (
+	file_hash  TEXT PRIMARY KEY
+)`
+
+	separator = "|"
+)
+
+// Store implements the IngestionStore interface backed by an SQL database.
+type Store struct {
+	db *sql.DB
+}
+
+// combine creates a key for a file/md5 combination.
+func combine(fileName, md5 string) string {
+	return fileName + separator + md5
+}
+
+// New creates a new ingestionstore backed by the given database, creating the IngestedFiles
+// table if it doesn't already exist.
+func New(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, skerr.Wrapf(err, "creating IngestedFiles table")
+	}
+	return &Store{
+		db: db,
+	}, nil
+}
+
+// SetResultFileHash fulfills the IngestionStore interface
+func (s *Store) SetResultFileHash(fileName, md5 string) error {
+	defer metrics2.FuncTimer().Stop()
+	_, err := s.db.Exec(`INSERT INTO IngestedFiles (file_hash) VALUES ($1) ON CONFLICT (file_hash) DO NOTHING`,
+		combine(fileName, md5))
+	if err != nil {
+		return skerr.Wrapf(err, "writing %s:%s to ingestionstore", fileName, md5)
+	}
+	return nil
+}
+
+// ContainsResultFileHash fulfills the IngestionStore interface
+func (s *Store) ContainsResultFileHash(fileName, md5 string) (bool, error) {
+	defer metrics2.FuncTimer().Stop()
+	var found int
+	err := s.db.QueryRow(`SELECT 1 FROM IngestedFiles WHERE file_hash = $1`, combine(fileName, md5)).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, skerr.Wrapf(err, "reading %s:%s from ingestionstore", fileName, md5)
+	}
+	return true, nil
+}
+
+// Make sure Store fulfills IngestionStore
+var _ ingestion.IngestionStore = (*Store)(nil)
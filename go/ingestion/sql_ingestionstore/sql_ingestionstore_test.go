@@ -0,0 +1,55 @@
+package sql_ingestionstore
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+// TestSetContains writes some file hashes and then checks which ones are reported as ingested.
+func TestSetContains(t *testing.T) {
+	unittest.MediumTest(t)
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "ingestion.db"))
+	require.NoError(t, err)
+	defer testutils.AssertCloses(t, db)
+
+	f, err := New(db)
+	require.NoError(t, err)
+
+	b, err := f.ContainsResultFileHash("nope", "not here")
+	require.NoError(t, err)
+	require.False(t, b)
+
+	err = f.SetResultFileHash("skia-gold-flutter/dm-json-v1/2019/foo.json", "version1")
+	require.NoError(t, err)
+	err = f.SetResultFileHash("skia-gold-flutter/dm-json-v1/2019/foo.json", "version2")
+	require.NoError(t, err)
+	err = f.SetResultFileHash("skia-gold-flutter/dm-json-v1/2020/bar.json", "versionA")
+	require.NoError(t, err)
+	// Setting the same hash twice is not an error.
+	err = f.SetResultFileHash("skia-gold-flutter/dm-json-v1/2020/bar.json", "versionA")
+	require.NoError(t, err)
+
+	b, err = f.ContainsResultFileHash("skia-gold-flutter/dm-json-v1/2019/foo.json", "version2")
+	require.NoError(t, err)
+	require.True(t, b)
+
+	b, err = f.ContainsResultFileHash("skia-gold-flutter/dm-json-v1/2019/foo.json", "version1")
+	require.NoError(t, err)
+	require.True(t, b)
+
+	b, err = f.ContainsResultFileHash("nope", "version1")
+	require.NoError(t, err)
+	require.False(t, b)
+
+	b, err = f.ContainsResultFileHash("skia-gold-flutter/dm-json-v1/2019/foo.json", "versionA")
+	require.NoError(t, err)
+	require.False(t, b)
+}
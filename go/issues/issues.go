@@ -45,8 +45,8 @@ type IssueTracker interface {
 	FromQuery(q string) ([]Issue, error)
 	// AddComment adds a comment to the issue with the given id
 	AddComment(id string, comment CommentRequest) error
-	// AddIssue creates an issue with the passed in params.
-	AddIssue(issue IssueRequest) error
+	// AddIssue creates an issue with the passed in params and returns its id.
+	AddIssue(issue IssueRequest) (int64, error)
 }
 
 // Issue is an individual issue returned from the project hosting response.
@@ -112,11 +112,11 @@ func (m *MonorailIssueTracker) FromQuery(q string) ([]Issue, error) {
 // AddComment adds a comment to the issue with the given id
 func (m *MonorailIssueTracker) AddComment(id string, comment CommentRequest) error {
 	u := fmt.Sprintf("%s/%s/comments", m.url, id)
-	return post(m.client, u, comment)
+	return post(m.client, u, comment, nil)
 }
 
-// AddIssue creates an issue with the passed in params.
-func (m *MonorailIssueTracker) AddIssue(issue IssueRequest) error {
+// AddIssue creates an issue with the passed in params and returns its id.
+func (m *MonorailIssueTracker) AddIssue(issue IssueRequest) (int64, error) {
 	req := struct {
 		IssueRequest
 		Project string `json:"projectId"`
@@ -124,7 +124,11 @@ func (m *MonorailIssueTracker) AddIssue(issue IssueRequest) error {
 		IssueRequest: issue,
 		Project:      m.project,
 	}
-	return post(m.client, m.url, req)
+	created := &Issue{}
+	if err := post(m.client, m.url, req, created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
 }
 
 func get(client *http.Client, u string) ([]Issue, error) {
@@ -144,7 +148,9 @@ func get(client *http.Client, u string) ([]Issue, error) {
 	return issueResponse.Items, nil
 }
 
-func post(client *http.Client, dst string, request interface{}) error {
+// post sends the request to dst and, if response is not nil, decodes the JSON
+// response into it.
+func post(client *http.Client, dst string, request interface{}, response interface{}) error {
 	b := new(bytes.Buffer)
 	e := json.NewEncoder(b)
 	if err := e.Encode(request); err != nil {
@@ -159,5 +165,10 @@ func post(client *http.Client, dst string, request interface{}) error {
 	defer util.Close(resp.Body)
 	msg, err := ioutil.ReadAll(resp.Body)
 	sklog.Infof("%s\n\nErr: %v", string(msg), err)
+	if response != nil {
+		if err := json.Unmarshal(msg, response); err != nil {
+			return fmt.Errorf("Failed to decode issue tracker response: %s", err)
+		}
+	}
 	return nil
 }
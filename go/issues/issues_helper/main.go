@@ -113,9 +113,12 @@ func handleCreate() {
 		Description: *description,
 		Components:  []string{*component},
 	}
-	if err := tracker.AddIssue(req); err != nil {
+	id, err := tracker.AddIssue(req)
+	if err != nil {
 		sklog.Errorf("Failed to add issue: %s", err)
+		return
 	}
+	sklog.Infof("Created issue %d", id)
 }
 
 func checkCreateFlags() bool {
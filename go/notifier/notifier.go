@@ -316,7 +316,8 @@ func (n *monorailNotifier) Send(ctx context.Context, subject string, msg *Messag
 		Status:      "New",
 		Summary:     subject,
 	}
-	return n.tk.AddIssue(req)
+	_, err := n.tk.AddIssue(req)
+	return err
 }
 
 // MonorailNotifier returns a Notifier which files bugs in Monorail.
@@ -0,0 +1,196 @@
+package periodic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// cronMaxSearch limits how far into the future Next looks for a
+	// matching time, so that schedules which can never match (eg. the 30th
+	// of February) don't loop forever.
+	cronMaxSearch = 5 * 365 * 24 * time.Hour
+)
+
+var (
+	cronMonthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	cronDayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// CronSchedule is a parsed cron expression with the five standard fields:
+// minute, hour, day of month, month and day of week. Each field may be "*",
+// a value, a range "a-b", a list "a,b-c" and may have a step, eg. "*/15" or
+// "1-5/2". Months and days of the week may be given by their three-letter
+// English names, and Sunday is either 0 or 7. As in Vixie cron, if both the
+// day of month and the day of week are restricted, a time matches if either
+// of them matches. All times are interpreted in UTC.
+type CronSchedule struct {
+	expr    string
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool
+	dowStar bool
+}
+
+// ParseCron parses the given cron expression.
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Expected 5 fields in cron expression %q but got %d", expr, len(fields))
+	}
+	rv := &CronSchedule{
+		expr:    expr,
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	if rv.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("Invalid minute in cron expression %q: %s", expr, err)
+	}
+	if rv.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("Invalid hour in cron expression %q: %s", expr, err)
+	}
+	if rv.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("Invalid day of month in cron expression %q: %s", expr, err)
+	}
+	if rv.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("Invalid month in cron expression %q: %s", expr, err)
+	}
+	if rv.dow, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return nil, fmt.Errorf("Invalid day of week in cron expression %q: %s", expr, err)
+	}
+	// Sunday may be given as 7.
+	if rv.dow&(1<<7) != 0 {
+		rv.dow = (rv.dow | 1) &^ (1 << 7)
+	}
+	return rv, nil
+}
+
+// parseCronField returns a bitmask of the values allowed by the given field.
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var rv uint64
+	for _, part := range strings.Split(field, ",") {
+		valueRange := part
+		step := 1
+		hasStep := false
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			s, err := strconv.Atoi(part[idx+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = s
+			hasStep = true
+			valueRange = part[:idx]
+		}
+		lo, hi := min, max
+		if valueRange != "*" {
+			bounds := strings.SplitN(valueRange, "-", 2)
+			var err error
+			lo, err = parseCronValue(bounds[0], names)
+			if err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				hi, err = parseCronValue(bounds[1], names)
+				if err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// "a/n" means every n starting at a.
+				hi = max
+			}
+			if lo < min || hi > max || lo > hi {
+				return 0, fmt.Errorf("%q is out of range [%d, %d]", part, min, max)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			rv |= 1 << uint(v)
+		}
+	}
+	return rv, nil
+}
+
+// parseCronValue parses a single number or name in a cron field.
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return v, nil
+}
+
+// String returns the cron expression.
+func (s *CronSchedule) String() string {
+	return s.expr
+}
+
+// matchesDay returns true if the schedule matches the day of the given time.
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	if s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Matches returns true if the schedule matches the minute of the given time.
+func (s *CronSchedule) Matches(t time.Time) bool {
+	t = t.UTC()
+	return s.matchesDay(t) && s.hour&(1<<uint(t.Hour())) != 0 && s.minute&(1<<uint(t.Minute())) != 0
+}
+
+// Next returns the first time after the given time which matches the
+// schedule, or the zero time if there is none in the next five years.
+func (s *CronSchedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronMaxSearch)
+	for t.Before(limit) {
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Prev returns the latest time at or before the given time which matches the
+// schedule, looking back no further than the given duration. Returns false if
+// there is no such time.
+func (s *CronSchedule) Prev(before time.Time, lookback time.Duration) (time.Time, bool) {
+	t := before.UTC().Truncate(time.Minute)
+	earliest := before.Add(-lookback)
+	for !t.Before(earliest) {
+		if s.Matches(t) {
+			return t, true
+		}
+		t = t.Add(-time.Minute)
+	}
+	return time.Time{}, false
+}
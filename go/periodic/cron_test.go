@@ -0,0 +1,100 @@
+package periodic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+func ts(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	unittest.SmallTest(t)
+
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"* * * foo *",
+	} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCronSchedule_Matches(t *testing.T) {
+	unittest.SmallTest(t)
+
+	test := func(expr, time string, expect bool) {
+		s, err := ParseCron(expr)
+		require.NoError(t, err)
+		assert.Equal(t, expect, s.Matches(ts(time)), "%s at %s", expr, time)
+	}
+	// 2020-01-06 is a Monday.
+	test("0 */4 * * 1-5", "2020-01-06T08:00:00Z", true)
+	test("0 */4 * * 1-5", "2020-01-06T08:01:00Z", false)
+	test("0 */4 * * 1-5", "2020-01-06T09:00:00Z", false)
+	test("0 */4 * * 1-5", "2020-01-05T08:00:00Z", false)
+	test("30 2 1,15 * *", "2020-01-15T02:30:59Z", true)
+	test("30 2 1,15 * *", "2020-01-16T02:30:00Z", false)
+	test("0 0 * jan,jul sun", "2020-01-05T00:00:00Z", true)
+	test("0 0 * jan,jul sun", "2020-02-02T00:00:00Z", false)
+	// Sunday is both 0 and 7.
+	test("0 0 * * 7", "2020-01-05T00:00:00Z", true)
+	// "a/n" starts at a.
+	test("5/20 * * * *", "2020-01-05T00:45:00Z", true)
+	test("5/20 * * * *", "2020-01-05T00:40:00Z", false)
+	// If both days are restricted, either one matches.
+	test("0 0 13 * fri", "2020-01-13T00:00:00Z", true)
+	test("0 0 13 * fri", "2020-01-10T00:00:00Z", true)
+	test("0 0 13 * fri", "2020-01-11T00:00:00Z", false)
+	// Times are in UTC.
+	test("0 12 * * *", "2020-01-05T07:00:00-05:00", true)
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	unittest.SmallTest(t)
+
+	s, err := ParseCron("0 */4 * * 1-5")
+	require.NoError(t, err)
+	// Friday evening until Monday morning.
+	assert.Equal(t, ts("2020-01-06T00:00:00Z"), s.Next(ts("2020-01-03T20:00:00Z")))
+	assert.Equal(t, ts("2020-01-06T04:00:00Z"), s.Next(ts("2020-01-06T00:00:00Z")))
+	assert.Equal(t, ts("2020-01-06T04:00:00Z"), s.Next(ts("2020-01-06T03:59:30Z")))
+
+	never, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, never.Next(ts("2020-01-01T00:00:00Z")).IsZero())
+}
+
+func TestCronSchedule_Prev(t *testing.T) {
+	unittest.SmallTest(t)
+
+	s, err := ParseCron("0 3 * * *")
+	require.NoError(t, err)
+	prev, ok := s.Prev(ts("2020-01-06T10:17:00Z"), 24*time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, ts("2020-01-06T03:00:00Z"), prev)
+	prev, ok = s.Prev(ts("2020-01-06T03:00:00Z"), 24*time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, ts("2020-01-06T03:00:00Z"), prev)
+	_, ok = s.Prev(ts("2020-01-06T10:17:00Z"), time.Hour)
+	assert.False(t, ok)
+}
@@ -37,13 +37,15 @@ const (
 	// Google Cloud project name used for pubsub.
 	PUBSUB_PROJECT = "skia-public"
 
-	// Names of periodic triggers.
+	// Names of periodic triggers. TRIGGER_CRON is sent every few minutes;
+	// subscribers decide which of their cron schedules are due.
+	TRIGGER_CRON    = "cron"
 	TRIGGER_NIGHTLY = "nightly"
 	TRIGGER_WEEKLY  = "weekly"
 )
 
 var (
-	VALID_TRIGGERS = []string{TRIGGER_NIGHTLY, TRIGGER_WEEKLY}
+	VALID_TRIGGERS = []string{TRIGGER_CRON, TRIGGER_NIGHTLY, TRIGGER_WEEKLY}
 )
 
 // TriggerCallbackFn is a function called when handling requests for periodic
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/gold-client/go/goldclient"
+	"go.skia.org/infra/golden/go/expstorage/expsync"
+)
+
+// expectationsEnv is the state for the expectations command and its sub-commands.
+type expectationsEnv struct {
+	instanceID  string
+	urlOverride string
+	workDir     string
+
+	// export flags
+	outputFile string
+
+	// import flags
+	inputFile    string
+	fromInstance string
+	fromURL      string
+	// a slice of strings like pattern=replacement that are split on the first '='.
+	remap []string
+}
+
+// getExpectationsCmd returns the definition of the expectations command.
+func getExpectationsCmd() *cobra.Command {
+	env := &expectationsEnv{}
+
+	expCmd := &cobra.Command{
+		Use:   "expectations",
+		Short: "Export and import the triaged digests of a Gold instance",
+		Long: `
+Move the expectations (triaged digests) of the master branch between Gold instances, or keep
+them when tests are renamed. Exported expectations include who triaged each digest and the
+import attributes the changes to the same users.`,
+	}
+
+	expExportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Writes all expectations of an instance as JSON",
+		Long: `
+Writes all expectations of the master branch, and who triaged them, to the output file or to
+stdout. The output can be passed to 'goldctl expectations import'.`,
+		Args: cobra.NoArgs,
+		Run:  env.runExportCmd,
+	}
+	env.addCommonFlags(expExportCmd)
+	expExportCmd.Flags().StringVar(&env.outputFile, "output", "", "File to write the expectations to. If empty, they are written to stdout.")
+
+	expImportCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Adds exported expectations to an instance",
+		Long: `
+Adds the expectations that were exported from another instance (or the same one) to the
+master branch of the instance. Test names can be changed with --remap, e.g.
+--remap 'gm_(.*)=$1' renames gm_circles to circles. The pattern must match the whole test name.
+
+With --dryrun, the changes that would be made are printed, but not applied.`,
+		Args: cobra.NoArgs,
+		Run:  env.runImportCmd,
+	}
+	env.addCommonFlags(expImportCmd)
+	expImportCmd.Flags().StringVar(&env.inputFile, "input", "", "File with the exported expectations. If empty and --from-instance isn't set, they are read from stdin.")
+	expImportCmd.Flags().StringVar(&env.fromInstance, "from-instance", "", "ID of a Gold instance to export the expectations from, instead of reading them from --input.")
+	expImportCmd.Flags().StringVar(&env.fromURL, "from-url", "", "URL of the Gold instance given by --from-instance. Used for testing, if empty the URL will be derived from the value of 'from-instance'")
+	expImportCmd.Flags().StringArrayVar(&env.remap, "remap", []string{}, "Any amount of pattern=replacement rules to rename tests. The first matching rule is applied.")
+
+	expCmd.AddCommand(expExportCmd, expImportCmd)
+	return expCmd
+}
+
+func (e *expectationsEnv) addCommonFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&e.workDir, fstrWorkDir, "", "Work directory for intermediate results")
+	cmd.Flags().StringVar(&e.instanceID, "instance", "", "ID of the Gold instance.")
+	cmd.Flags().StringVar(&e.urlOverride, "url", "", "URL of the Gold instance. Used for testing, if empty the URL will be derived from the value of 'instance'")
+	Must(cmd.MarkFlagRequired(fstrWorkDir))
+	Must(cmd.MarkFlagRequired("instance"))
+}
+
+// runExportCmd executes the export command.
+func (e *expectationsEnv) runExportCmd(cmd *cobra.Command, args []string) {
+	goldClient, err := e.makeClient(cmd, e.instanceID, e.urlOverride)
+	ifErrLogExit(cmd, err)
+
+	dump, err := goldClient.ExportExpectations()
+	ifErrLogExit(cmd, err)
+	logVerbose(cmd, fmt.Sprintf("Exported %d expectations\n", len(dump.Entries)))
+
+	out := os.Stdout
+	if e.outputFile != "" {
+		f, err := os.Create(e.outputFile)
+		ifErrLogExit(cmd, err)
+		defer util.Close(f)
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	ifErrLogExit(cmd, enc.Encode(dump))
+}
+
+// runImportCmd executes the import command.
+func (e *expectationsEnv) runImportCmd(cmd *cobra.Command, args []string) {
+	remap, err := parseRemapRules(e.remap)
+	ifErrLogExit(cmd, err)
+
+	var dump expsync.Dump
+	if e.fromInstance != "" {
+		if e.inputFile != "" {
+			logErrf(cmd, "Only one of --input and --from-instance can be set.\n")
+			exitProcess(cmd, 1)
+		}
+		fromClient, err := e.makeClient(cmd, e.fromInstance, e.fromURL)
+		ifErrLogExit(cmd, err)
+		dump, err = fromClient.ExportExpectations()
+		ifErrLogExit(cmd, err)
+	} else {
+		f, closeFn, err := getFileOrStdin(e.inputFile)
+		ifErrLogExit(cmd, err)
+		err = json.NewDecoder(f).Decode(&dump)
+		ifErrLogExit(cmd, closeFn())
+		ifErrLogExit(cmd, err)
+	}
+	logVerbose(cmd, fmt.Sprintf("Importing %d expectations\n", len(dump.Entries)))
+
+	goldClient, err := e.makeClient(cmd, e.instanceID, e.urlOverride)
+	ifErrLogExit(cmd, err)
+	resp, err := goldClient.ImportExpectations(expsync.ImportRequest{
+		Dump:   dump,
+		Remap:  remap,
+		DryRun: flagDryRun,
+	})
+	ifErrLogExit(cmd, err)
+
+	for _, d := range resp.Deltas {
+		if d.TriagedBy != "" {
+			fmt.Printf("%s %s %s (%s, triaged by %s)\n", d.Grouping, d.Digest, d.Label, d.User, d.TriagedBy)
+		} else {
+			fmt.Printf("%s %s %s (%s)\n", d.Grouping, d.Digest, d.Label, d.User)
+		}
+	}
+	if resp.Applied {
+		fmt.Printf("Applied %d changes.\n", len(resp.Deltas))
+	} else {
+		fmt.Printf("Dry run: %d changes would be applied.\n", len(resp.Deltas))
+	}
+}
+
+// makeClient returns a GoldClient for the given instance, using the auth from the work dir.
+func (e *expectationsEnv) makeClient(cmd *cobra.Command, instanceID, urlOverride string) (goldclient.GoldClient, error) {
+	auth, err := goldclient.LoadAuthOpt(e.workDir)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	if auth == nil {
+		logErrf(cmd, "Auth is empty - did you call goldctl auth first?")
+		exitProcess(cmd, 1)
+	}
+
+	config := goldclient.GoldClientConfig{
+		InstanceID:      instanceID,
+		WorkDir:         e.workDir,
+		OverrideGoldURL: urlOverride,
+	}
+	// Overwrite any existing config in the work directory.
+	return goldclient.NewCloudClient(auth, config)
+}
+
+// parseRemapRules parses rules like "pattern=replacement". The pattern cannot contain '=', but
+// the replacement can.
+func parseRemapRules(rules []string) ([]expsync.RemapRule, error) {
+	rv := make([]expsync.RemapRule, 0, len(rules))
+	for _, r := range rules {
+		split := strings.SplitN(r, "=", 2)
+		if len(split) != 2 || split[0] == "" {
+			return nil, skerr.Fmt("invalid remap rule %q; expected pattern=replacement", r)
+		}
+		rv = append(rv, expsync.RemapRule{Pattern: split[0], Replacement: split[1]})
+	}
+	return rv, nil
+}
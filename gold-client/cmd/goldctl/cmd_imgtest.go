@@ -42,6 +42,9 @@ type imgTest struct {
 	// a slice of strings like foo:bar that will be split on the first ':' into
 	// key value pairs that will go into a map[string]string
 	testKeysStrings []string
+	// a slice of strings like foo:bar that will be split on the first ':' into
+	// optional key value pairs, e.g. to configure the image matching algorithm.
+	testOptionalKeysStrings []string
 }
 
 // getImgTestCmd returns the definition of the imgtest command.
@@ -87,6 +90,7 @@ Add images generated by the tests to the test results. This requires two argumen
 	imgTestAddCmd.Flags().StringVar(&env.pngFile, "png-file", "", "Path to the PNG file that contains the test results.")
 	imgTestAddCmd.Flags().StringVar(&env.testKeysFile, "add-test-key-file", "", "A JSON file containing keys and values that should be applied to this test only.")
 	imgTestAddCmd.Flags().StringSliceVar(&env.testKeysStrings, "add-test-key", []string{}, "Any amount of key:value paris that will be added to this test only.")
+	imgTestAddCmd.Flags().StringSliceVar(&env.testOptionalKeysStrings, "add-test-optional-key", []string{}, "Any amount of key:value pairs that will be added to the options of this test only, e.g. to configure image matching with image_matching_algorithm:fuzzy.")
 
 	Must(imgTestAddCmd.MarkFlagRequired("test-name"))
 	Must(imgTestAddCmd.MarkFlagRequired("png-file"))
@@ -335,7 +339,17 @@ func (i *imgTest) runImgTestAddCmd(cmd *cobra.Command, args []string) {
 		extraKeys[types.CORPUS_FIELD] = i.corpus
 	}
 
-	pass, err := goldClient.Test(types.TestName(i.testName), i.pngFile, extraKeys)
+	optionalKeys := map[string]string{}
+	for _, pair := range i.testOptionalKeysStrings {
+		split := strings.SplitN(pair, ":", 2)
+		if len(split) != 2 {
+			logInfof(cmd, "Ignoring malformatted --add-test-optional-key=%s", pair)
+		} else {
+			optionalKeys[split[0]] = split[1]
+		}
+	}
+
+	pass, err := goldClient.Test(types.TestName(i.testName), i.pngFile, extraKeys, optionalKeys)
 	ifErrLogExit(cmd, err)
 
 	if !pass {
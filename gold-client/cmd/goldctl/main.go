@@ -38,6 +38,7 @@ It can be used directly or in a scripted environment. `,
 	rootCmd.AddCommand(getDumpCmd())
 	rootCmd.AddCommand(getDiffCmd())
 	rootCmd.AddCommand(getWhoamiCmd())
+	rootCmd.AddCommand(getExpectationsCmd())
 
 	// Execute the root command.
 	if cmd, err := rootCmd.ExecuteC(); err != nil {
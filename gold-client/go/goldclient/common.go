@@ -18,6 +18,7 @@ import (
 // http.Client by representing a smaller interface.
 type HTTPClient interface {
 	Get(url string) (resp *http.Response, err error)
+	Post(url, contentType string, body io.Reader) (resp *http.Response, err error)
 }
 
 const maxAttempts = 5
@@ -12,10 +12,12 @@ import (
 	"image/png"
 	"io/ioutil"
 	"math"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,8 +26,12 @@ import (
 	"go.skia.org/infra/go/fileutil"
 	"go.skia.org/infra/go/skerr"
 	"go.skia.org/infra/go/sklog"
+	"go.skia.org/infra/go/util"
 	"go.skia.org/infra/golden/go/diff"
+	"go.skia.org/infra/golden/go/expstorage/expsync"
+	"go.skia.org/infra/golden/go/imgmatching"
 	"go.skia.org/infra/golden/go/jsonio"
+	"go.skia.org/infra/golden/go/shared"
 	"go.skia.org/infra/golden/go/types"
 	"go.skia.org/infra/golden/go/types/expectations"
 	"go.skia.org/infra/golden/go/web/frontend"
@@ -56,9 +62,13 @@ type GoldClient interface {
 	// additionalKeys is an optional set of key:value pairs that apply to only this test.
 	// This is typically a small amount of data (and can be nil). If there are many keys,
 	// they are likely shared between tests and should be added in SetSharedConfig.
+	// optionalKeys is an optional set of key:value pairs that are uploaded as the options of
+	// this test (and can be nil). They configure the image matching algorithm (see the
+	// imgmatching package), which decides whether an image that is not a known positive is
+	// close enough to the closest positive image to pass.
 	//
 	// An error is only returned if there was a technical problem in processing the test.
-	Test(name types.TestName, imgFileName string, additionalKeys map[string]string) (bool, error)
+	Test(name types.TestName, imgFileName string, additionalKeys, optionalKeys map[string]string) (bool, error)
 
 	// Check operates similarly to Test, except it does not persist anything about the call.
 	// That is, the image will not be uploaded to Gold, only compared against the baseline.
@@ -78,6 +88,14 @@ type GoldClient interface {
 	// Whoami makes a request to Gold's /json/whoami endpoint and returns the email address in the
 	// response. For debugging purposes only.
 	Whoami() (string, error)
+
+	// ExportExpectations returns all expectations of the master branch of the Gold instance,
+	// together with who triaged each digest.
+	ExportExpectations() (expsync.Dump, error)
+
+	// ImportExpectations adds the given expectations to the master branch of the Gold instance.
+	// It returns the changes that were made, or would have been made if req.DryRun is true.
+	ImportExpectations(req expsync.ImportRequest) (expsync.ImportResponse, error)
 }
 
 // GoldClientDebug contains some "optional" methods that can assist
@@ -266,8 +284,8 @@ func (c *CloudClient) SetSharedConfig(sharedConfig jsonio.GoldResults, skipValid
 }
 
 // Test implements the GoldClient interface.
-func (c *CloudClient) Test(name types.TestName, imgFileName string, additionalKeys map[string]string) (bool, error) {
-	if res, err := c.addTest(name, imgFileName, additionalKeys); err != nil {
+func (c *CloudClient) Test(name types.TestName, imgFileName string, additionalKeys, optionalKeys map[string]string) (bool, error) {
+	if res, err := c.addTest(name, imgFileName, additionalKeys, optionalKeys); err != nil {
 		return false, err
 	} else {
 		return res, saveJSONFile(c.getResultStatePath(), c.resultState)
@@ -276,11 +294,16 @@ func (c *CloudClient) Test(name types.TestName, imgFileName string, additionalKe
 
 // addTest adds a test to results. If perTestPassFail is true it will also upload the result.
 // Returns true if the test was added (and maybe uploaded) successfully.
-func (c *CloudClient) addTest(name types.TestName, imgFileName string, additionalKeys map[string]string) (bool, error) {
+func (c *CloudClient) addTest(name types.TestName, imgFileName string, additionalKeys, optionalKeys map[string]string) (bool, error) {
 	if err := c.isReady(); err != nil {
 		return false, skerr.Wrapf(err, "gold client not ready")
 	}
 
+	algorithm, matcher, err := imgmatching.MakeMatcher(optionalKeys)
+	if err != nil {
+		return false, skerr.Wrapf(err, "invalid image matching optional keys for test %s", name)
+	}
+
 	// Get an uploader. This is either based on an authenticated client or on gsutils.
 	uploader, err := c.auth.GetGCSUploader()
 	if err != nil {
@@ -293,8 +316,31 @@ func (c *CloudClient) addTest(name types.TestName, imgFileName string, additiona
 		return false, skerr.Wrap(err)
 	}
 
+	// If the image isn't a known positive, see if it is close enough to the closest positive
+	// according to the image matching algorithm. This needs the baseline, so it can't be done
+	// in upload only mode.
+	isPositive := c.resultState.Expectations[name][imgHash] == expectations.Positive
+	matchedPositive := false
+	var matchOptions map[string]string
+	if !isPositive && algorithm != imgmatching.ExactMatching && !c.resultState.UploadOnly {
+		closest, matched, err := c.matchClosestPositive(context.TODO(), name, imgBytes, matcher)
+		if err != nil {
+			return false, skerr.Wrapf(err, "matching %s against the positive images of test %s", imgFileName, name)
+		}
+		matchOptions = map[string]string{
+			imgmatching.MatchedOptKey: strconv.FormatBool(matched),
+		}
+		if closest != "" {
+			matchOptions[imgmatching.ClosestPositiveOptKey] = string(closest)
+		}
+		if matched {
+			fmt.Printf("Image with hash %s matched positive image %s using %s matching\n", imgHash, closest, algorithm)
+		}
+		matchedPositive = matched
+	}
+
 	// Add the result of this test.
-	c.addResult(name, imgHash, additionalKeys)
+	c.addResult(name, imgHash, additionalKeys, optionalKeys, matchOptions)
 
 	// At this point the result should be correct for uploading.
 	if err := c.resultState.SharedConfig.Validate(false); err != nil {
@@ -325,7 +371,7 @@ func (c *CloudClient) addTest(name types.TestName, imgFileName string, additiona
 			return c.uploadResultJSON(uploader)
 		})
 
-		ret = c.resultState.Expectations[name][imgHash] == expectations.Positive
+		ret = isPositive || matchedPositive
 		if !ret {
 			link := fmt.Sprintf("%s/detail?test=%s&digest=%s", c.resultState.GoldURL, name, imgHash)
 			if c.resultState.SharedConfig.ChangeListID != "" {
@@ -457,8 +503,9 @@ func (c *CloudClient) getResultStatePath() string {
 	return filepath.Join(c.workDir, stateFile)
 }
 
-// addResult adds the given test to the overall results.
-func (c *CloudClient) addResult(name types.TestName, imgHash types.Digest, additionalKeys map[string]string) {
+// addResult adds the given test to the overall results. The optionalKeys and matchOptions are
+// both added to the options of the result.
+func (c *CloudClient) addResult(name types.TestName, imgHash types.Digest, additionalKeys, optionalKeys, matchOptions map[string]string) {
 	newResult := &jsonio.Result{
 		Digest: imgHash,
 		Key:    map[string]string{types.PRIMARY_KEY_FIELD: string(name)},
@@ -470,6 +517,12 @@ func (c *CloudClient) addResult(name types.TestName, imgHash types.Digest, addit
 	for k, v := range additionalKeys {
 		newResult.Key[k] = v
 	}
+	for k, v := range optionalKeys {
+		newResult.Options[k] = v
+	}
+	for k, v := range matchOptions {
+		newResult.Options[k] = v
+	}
 
 	// Set the CORPUS_FIELD (e.g. source_type) to the default value of the instanceID
 	// if it is not set either on Key (via init) or additionalKeys (via add)
@@ -593,6 +646,53 @@ func (c *CloudClient) Diff(ctx context.Context, name types.TestName, corpus, img
 	return skerr.Wrap(diffFile.Close())
 }
 
+// matchClosestPositive finds the positive image of the given test that is closest to the given
+// encoded image, downloading the positive images from GCS as needed, and returns its digest and
+// whether the matcher considers the image a match of it. If the test has no positive images then
+// the returned digest is empty and the image is not a match.
+func (c *CloudClient) matchClosestPositive(ctx context.Context, name types.TestName, imgBytes []byte, matcher imgmatching.Matcher) (types.Digest, bool, error) {
+	img, err := png.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return "", false, skerr.Wrapf(err, "decoding PNG")
+	}
+	var positives types.DigestSlice
+	for d, label := range c.resultState.Expectations[name] {
+		if label == expectations.Positive {
+			positives = append(positives, d)
+		}
+	}
+	if len(positives) == 0 {
+		return "", false, nil
+	}
+	// Sort them so the closest digest is deterministic if there are ties.
+	sort.Sort(positives)
+
+	digestsPath := filepath.Join(c.workDir, "digests")
+	if err := os.MkdirAll(digestsPath, os.ModePerm); err != nil {
+		return "", false, skerr.Wrapf(err, "creating digests directory %s", digestsPath)
+	}
+	smallestCombined := float32(math.MaxFloat32)
+	var closestDigest types.Digest
+	var closestImg image.Image
+	for _, d := range positives {
+		b, err := c.getEncodedDigestFromCacheOrGCS(ctx, d, digestsPath)
+		if err != nil {
+			return "", false, skerr.Wrap(err)
+		}
+		positiveImg, err := png.Decode(bytes.NewReader(b))
+		if err != nil {
+			return "", false, skerr.Wrapf(err, "Invalid PNG stored in digest %s (cached at %s)", d, digestsPath)
+		}
+		dm, _ := diff.PixelDiff(positiveImg, img)
+		if cdm := diff.CombinedDiffMetric(dm, nil, nil); cdm < smallestCombined {
+			smallestCombined = cdm
+			closestDigest = d
+			closestImg = positiveImg
+		}
+	}
+	return closestDigest, matcher.Match(closestImg, img), nil
+}
+
 // getEncodedDigestFromCacheOrGCS returns the encoded PNG bytes for a digest from GCS or
 // from the local cache (e.g. cachePath).
 func (c *CloudClient) getEncodedDigestFromCacheOrGCS(ctx context.Context, d types.Digest, cachePath string) ([]byte, error) {
@@ -635,6 +735,47 @@ func (c *CloudClient) Whoami() (string, error) {
 	return email, nil
 }
 
+// ExportExpectations fulfills the GoldClient interface.
+func (c *CloudClient) ExportExpectations() (expsync.Dump, error) {
+	u := c.resultState.GoldURL + shared.ExpectationsExportRoute
+	jsonBytes, err := getWithRetries(c.httpClient, u)
+	if err != nil {
+		return expsync.Dump{}, skerr.Wrapf(err, "making request to %s", u)
+	}
+	var dump expsync.Dump
+	if err := json.Unmarshal(jsonBytes, &dump); err != nil {
+		return expsync.Dump{}, skerr.Wrapf(err, "parsing JSON response from %s", u)
+	}
+	return dump, nil
+}
+
+// ImportExpectations fulfills the GoldClient interface.
+func (c *CloudClient) ImportExpectations(req expsync.ImportRequest) (expsync.ImportResponse, error) {
+	u := c.resultState.GoldURL + shared.ExpectationsImportRoute
+	body, err := json.Marshal(req)
+	if err != nil {
+		return expsync.ImportResponse{}, skerr.Wrapf(err, "encoding import request")
+	}
+	// This is not retried, because the request is not idempotent if it partially succeeded.
+	resp, err := c.httpClient.Post(u, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return expsync.ImportResponse{}, skerr.Wrapf(err, "making request to %s", u)
+	}
+	defer util.Close(resp.Body)
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return expsync.ImportResponse{}, skerr.Wrapf(err, "reading response from %s", u)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return expsync.ImportResponse{}, skerr.Fmt("%s returned status %d: %s", u, resp.StatusCode, respBytes)
+	}
+	var rv expsync.ImportResponse
+	if err := json.Unmarshal(respBytes, &rv); err != nil {
+		return expsync.ImportResponse{}, skerr.Wrapf(err, "parsing JSON response from %s", u)
+	}
+	return rv, nil
+}
+
 // DumpBaseline fulfills the GoldClientDebug interface
 func (c *CloudClient) DumpBaseline() (string, error) {
 	if c.resultState == nil || c.resultState.Expectations == nil {
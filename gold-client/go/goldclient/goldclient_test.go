@@ -26,7 +26,9 @@ import (
 	"go.skia.org/infra/go/util"
 	"go.skia.org/infra/gold-client/go/mocks"
 	"go.skia.org/infra/golden/go/diff"
+	"go.skia.org/infra/golden/go/expstorage/expsync"
 	"go.skia.org/infra/golden/go/image/text"
+	"go.skia.org/infra/golden/go/imgmatching"
 	"go.skia.org/infra/golden/go/jsonio"
 	one_by_five "go.skia.org/infra/golden/go/testutils/data_one_by_five"
 	"go.skia.org/infra/golden/go/types"
@@ -283,7 +285,7 @@ func TestNewReportNormal(t *testing.T) {
 		return imgData, imgHash, nil
 	})
 
-	pass, err := goldClient.Test("first-test", testImgPath, nil)
+	pass, err := goldClient.Test("first-test", testImgPath, nil, nil)
 	assert.NoError(t, err)
 	// true is always returned if we are not on passFail mode.
 	assert.True(t, pass)
@@ -321,7 +323,7 @@ func TestNewReportNormalBadKeys(t *testing.T) {
 		return imgData, imgHash, nil
 	})
 
-	_, err = goldClient.Test("first-test", testImgPath, map[string]string{"empty": ""})
+	_, err = goldClient.Test("first-test", testImgPath, map[string]string{"empty": ""}, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid test config")
 }
@@ -443,7 +445,7 @@ func TestInitAddFinalize(t *testing.T) {
 
 	pass, err := goldClient.Test("first-test", testImgPath, map[string]string{
 		"config": "canvas",
-	})
+	}, nil)
 	assert.NoError(t, err)
 	// true is always returned if we are not on passFail mode.
 	assert.True(t, pass)
@@ -474,7 +476,7 @@ func TestInitAddFinalize(t *testing.T) {
 	})
 	pass, err = goldClient.Test("second-test", testImgPath, map[string]string{
 		"config": "svg",
-	})
+	}, nil)
 	assert.NoError(t, err)
 	// true is always returned if we are not on passFail mode.
 	assert.True(t, pass)
@@ -568,7 +570,7 @@ func TestNewReportPassFail(t *testing.T) {
 		return imgData, imgHash, nil
 	})
 
-	pass, err := goldClient.Test(testName, testImgPath, nil)
+	pass, err := goldClient.Test(testName, testImgPath, nil, nil)
 	assert.NoError(t, err)
 	// Returns false because the test name has never been seen before
 	// (and the digest is brand new)
@@ -649,7 +651,7 @@ func TestReportPassFailPassWithCorpusInInit(t *testing.T) {
 		"another_notch": "emeril",
 	}
 
-	pass, err := goldClient.Test(testName, testImgPath, extraKeys)
+	pass, err := goldClient.Test(testName, testImgPath, extraKeys, nil)
 	assert.NoError(t, err)
 	// Returns true because the test has been seen before and marked positive.
 	assert.True(t, pass)
@@ -725,7 +727,7 @@ func TestReportPassFailPassWithCorpusInKeys(t *testing.T) {
 		"another_notch": "emeril",
 	}
 
-	pass, err := goldClient.Test(testName, testImgPath, extraKeys)
+	pass, err := goldClient.Test(testName, testImgPath, extraKeys, nil)
 	assert.NoError(t, err)
 	// Returns true because the test has been seen before and marked positive.
 	assert.True(t, pass)
@@ -768,13 +770,13 @@ func TestNegativePassFail(t *testing.T) {
 		return imgData, imgHash, nil
 	})
 
-	pass, err := goldClient.Test(testName, testImgPath, nil)
+	pass, err := goldClient.Test(testName, testImgPath, nil, nil)
 	assert.NoError(t, err)
 	// Returns false because the test is negative
 	assert.False(t, pass)
 
 	// Run it again to make sure the failure log isn't truncated
-	pass, err = goldClient.Test(testName, testImgPath, nil)
+	pass, err = goldClient.Test(testName, testImgPath, nil, nil)
 	assert.NoError(t, err)
 	// Returns false because the test is negative
 	assert.False(t, pass)
@@ -823,7 +825,7 @@ func TestPositivePassFail(t *testing.T) {
 		return imgData, imgHash, nil
 	})
 
-	pass, err := goldClient.Test(testName, testImgPath, nil)
+	pass, err := goldClient.Test(testName, testImgPath, nil, nil)
 	assert.NoError(t, err)
 	// Returns true because this test has been seen before and the digest was
 	// previously triaged positive.
@@ -835,6 +837,113 @@ func TestPositivePassFail(t *testing.T) {
 	assert.Equal(t, "", string(b))
 }
 
+// TestFuzzyMatchPassFail ensures that a digest that is not positive, but is close enough to a
+// positive digest according to the image matching optional keys, passes and that the match is
+// recorded in the uploaded results.
+func TestFuzzyMatchPassFail(t *testing.T) {
+	unittest.MediumTest(t)
+
+	wd, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	// image2 differs from image1 by one in one channel of each of its five pixels.
+	imgData := asEncodedBytes(t, image2)
+	imgHash := types.Digest("44444444444444444444444444444444")
+	// This is the positive digest defined in mockBaselineJSON.
+	const positiveHash = types.Digest("beef00d3a1527db19619ec12a4e0df68")
+	testName := types.TestName("ThisIsTheOnlyTest")
+
+	auth, httpClient, uploader, dlr := makeMocks()
+	defer httpClient.AssertExpectations(t)
+	defer uploader.AssertExpectations(t)
+	defer dlr.AssertExpectations(t)
+
+	hashesResp := httpResponse([]byte(imgHash), "200 OK", http.StatusOK)
+	httpClient.On("Get", "https://testing-gold.skia.org/json/hashes").Return(hashesResp, nil)
+
+	exp := httpResponse([]byte(mockBaselineJSON), "200 OK", http.StatusOK)
+	httpClient.On("Get", "https://testing-gold.skia.org/json/expectations?issue=867").Return(exp, nil)
+
+	// The positive image is only downloaded once, the second match uses the cached copy.
+	dlr.On("Download", testutils.AnyContext, "gs://skia-gold-testing/dm-images-v1/"+string(positiveHash)+".png", mock.Anything).Return(asEncodedBytes(t, image1), nil).Once()
+
+	expectedJSONPath := "skia-gold-testing/trybot/dm-json-v1/2019/04/02/19/abcd1234/117/1554234843/dm-1554234843000000000.json"
+	uploader.On("UploadJSON", testutils.AnyContext, mock.AnythingOfType("*jsonio.GoldResults"), filepath.Join(wd, jsonTempFile), expectedJSONPath).Return(nil)
+
+	goldClient, err := makeGoldClient(auth, true /*=passFail*/, false /*=uploadOnly*/, wd)
+	require.NoError(t, err)
+	err = goldClient.SetSharedConfig(makeTestSharedConfig(), false)
+	require.NoError(t, err)
+
+	overrideLoadAndHashImage(goldClient, func(path string) ([]byte, types.Digest, error) {
+		assert.Equal(t, testImgPath, path)
+		return imgData, imgHash, nil
+	})
+
+	pass, err := goldClient.Test(testName, testImgPath, nil, map[string]string{
+		imgmatching.AlgorithmNameOptKey:      string(imgmatching.FuzzyMatching),
+		imgmatching.MaxDifferentPixelsOptKey: "5",
+		imgmatching.MaxChannelDeltaOptKey:    "1",
+	})
+	require.NoError(t, err)
+	assert.True(t, pass)
+
+	// One too few pixels are allowed to differ.
+	pass, err = goldClient.Test(testName, testImgPath, nil, map[string]string{
+		imgmatching.AlgorithmNameOptKey:      string(imgmatching.FuzzyMatching),
+		imgmatching.MaxDifferentPixelsOptKey: "4",
+		imgmatching.MaxChannelDeltaOptKey:    "1",
+	})
+	require.NoError(t, err)
+	assert.False(t, pass)
+
+	results := goldClient.resultState.SharedConfig.Results
+	require.Len(t, results, 2)
+	assert.Equal(t, map[string]string{
+		"ext":                                "png",
+		imgmatching.AlgorithmNameOptKey:      "fuzzy",
+		imgmatching.MaxDifferentPixelsOptKey: "5",
+		imgmatching.MaxChannelDeltaOptKey:    "1",
+		imgmatching.ClosestPositiveOptKey:    string(positiveHash),
+		imgmatching.MatchedOptKey:            "true",
+	}, results[0].Options)
+	assert.Equal(t, "false", results[1].Options[imgmatching.MatchedOptKey])
+	assert.Equal(t, string(positiveHash), results[1].Options[imgmatching.ClosestPositiveOptKey])
+
+	// Only the failure is written to the failure file.
+	b, err := ioutil.ReadFile(filepath.Join(wd, failureLog))
+	require.NoError(t, err)
+	assert.Equal(t, "https://testing-gold.skia.org/detail?test=ThisIsTheOnlyTest&digest=44444444444444444444444444444444&issue=867\n", string(b))
+}
+
+// TestInvalidImageMatchingKeys ensures that invalid image matching optional keys are an error.
+func TestInvalidImageMatchingKeys(t *testing.T) {
+	unittest.MediumTest(t)
+
+	wd, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	auth, httpClient, _, _ := makeMocks()
+	defer httpClient.AssertExpectations(t)
+
+	hashesResp := httpResponse([]byte(mockHashesTxt), "200 OK", http.StatusOK)
+	httpClient.On("Get", "https://testing-gold.skia.org/json/hashes").Return(hashesResp, nil)
+
+	exp := httpResponse([]byte(mockBaselineJSON), "200 OK", http.StatusOK)
+	httpClient.On("Get", "https://testing-gold.skia.org/json/expectations?issue=867").Return(exp, nil)
+
+	goldClient, err := makeGoldClient(auth, true /*=passFail*/, false /*=uploadOnly*/, wd)
+	require.NoError(t, err)
+	err = goldClient.SetSharedConfig(makeTestSharedConfig(), false)
+	require.NoError(t, err)
+
+	_, err = goldClient.Test("ThisIsTheOnlyTest", testImgPath, nil, map[string]string{
+		imgmatching.AlgorithmNameOptKey: string(imgmatching.FuzzyMatching),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid image matching optional keys")
+}
+
 // Tests service account authentication is properly setup in the working directory.
 // This (and the rest of TestInit*) are effectively tests of "goldctl auth".
 func TestInitServiceAccountAuth(t *testing.T) {
@@ -1323,6 +1432,112 @@ func TestCloudClient_Whoami_InternalServerError_Failure(t *testing.T) {
 	assert.Contains(t, err.Error(), "500")
 }
 
+func TestCloudClient_ExportExpectations_Success(t *testing.T) {
+	// This test reads and writes a small amount of data from/to disk.
+	unittest.MediumTest(t)
+
+	wd, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	auth, httpClient, _, _ := makeMocks()
+	defer httpClient.AssertExpectations(t)
+
+	config := GoldClientConfig{
+		WorkDir:    wd,
+		InstanceID: "testing",
+	}
+	goldClient, err := NewCloudClient(auth, config)
+	assert.NoError(t, err)
+
+	url := "https://testing-gold.skia.org/json/expectations/export"
+	response := `{"entries":[{"grouping":"test_one","digest":"aaa0ddfc45a95372747804fc75061fc1","label":"positive","triaged_by":"user@example.com","triaged_at":"2020-01-02T03:04:05Z"}]}`
+	httpClient.On("Get", url).Return(httpResponse([]byte(response), "200 OK", http.StatusOK), nil)
+
+	dump, err := goldClient.ExportExpectations()
+	assert.NoError(t, err)
+	assert.Equal(t, expsync.Dump{
+		Entries: []expsync.Entry{
+			{
+				Grouping:  "test_one",
+				Digest:    "aaa0ddfc45a95372747804fc75061fc1",
+				Label:     "positive",
+				TriagedBy: "user@example.com",
+				TriagedAt: time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC),
+			},
+		},
+	}, dump)
+}
+
+func TestCloudClient_ImportExpectations_Success(t *testing.T) {
+	// This test reads and writes a small amount of data from/to disk.
+	unittest.MediumTest(t)
+
+	wd, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	auth, httpClient, _, _ := makeMocks()
+	defer httpClient.AssertExpectations(t)
+
+	config := GoldClientConfig{
+		WorkDir:    wd,
+		InstanceID: "testing",
+	}
+	goldClient, err := NewCloudClient(auth, config)
+	assert.NoError(t, err)
+
+	url := "https://testing-gold.skia.org/json/expectations/import"
+	expectedBody := `{"dump":{"entries":[{"grouping":"old_one","digest":"aaa0ddfc45a95372747804fc75061fc1","label":"positive","triaged_at":"0001-01-01T00:00:00Z"}]},"remap":[{"pattern":"old_(.*)","replacement":"new_$1"}],"dry_run":true}`
+	var body []byte
+	response := `{"deltas":[{"grouping":"new_one","digest":"aaa0ddfc45a95372747804fc75061fc1","label":"positive","user":"user@example.com"}],"applied":false}`
+	httpClient.On("Post", url, "application/json", mock.Anything).Run(func(args mock.Arguments) {
+		var err error
+		body, err = ioutil.ReadAll(args.Get(2).(io.Reader))
+		assert.NoError(t, err)
+	}).Return(httpResponse([]byte(response), "200 OK", http.StatusOK), nil)
+
+	resp, err := goldClient.ImportExpectations(expsync.ImportRequest{
+		Dump: expsync.Dump{
+			Entries: []expsync.Entry{
+				{Grouping: "old_one", Digest: "aaa0ddfc45a95372747804fc75061fc1", Label: "positive"},
+			},
+		},
+		Remap:  []expsync.RemapRule{{Pattern: "old_(.*)", Replacement: "new_$1"}},
+		DryRun: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, expsync.ImportResponse{
+		Deltas: []expsync.ImportDelta{
+			{Grouping: "new_one", Digest: "aaa0ddfc45a95372747804fc75061fc1", Label: "positive", User: "user@example.com"},
+		},
+	}, resp)
+	assert.JSONEq(t, expectedBody, string(body))
+}
+
+func TestCloudClient_ImportExpectations_Unauthorized_Failure(t *testing.T) {
+	// This test reads and writes a small amount of data from/to disk.
+	unittest.MediumTest(t)
+
+	wd, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	auth, httpClient, _, _ := makeMocks()
+	defer httpClient.AssertExpectations(t)
+
+	config := GoldClientConfig{
+		WorkDir:    wd,
+		InstanceID: "testing",
+	}
+	goldClient, err := NewCloudClient(auth, config)
+	assert.NoError(t, err)
+
+	url := "https://testing-gold.skia.org/json/expectations/import"
+	httpClient.On("Post", url, "application/json", mock.Anything).Return(httpResponse([]byte("You must be logged in"), "401 Unauthorized", http.StatusUnauthorized), nil)
+
+	_, err = goldClient.ImportExpectations(expsync.ImportRequest{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
 func makeMocks() (AuthOpt, *mocks.HTTPClient, *mocks.GCSUploader, *mocks.GCSDownloader) {
 	mh := mocks.HTTPClient{}
 	mg := mocks.GCSUploader{}
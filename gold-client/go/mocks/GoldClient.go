@@ -5,6 +5,8 @@ package mocks
 import (
 	context "context"
 
+	expsync "go.skia.org/infra/golden/go/expstorage/expsync"
+
 	jsonio "go.skia.org/infra/golden/go/jsonio"
 
 	mock "github.com/stretchr/testify/mock"
@@ -52,6 +54,27 @@ func (_m *GoldClient) Diff(ctx context.Context, name types.TestName, corpus stri
 	return r0
 }
 
+// ExportExpectations provides a mock function with given fields:
+func (_m *GoldClient) ExportExpectations() (expsync.Dump, error) {
+	ret := _m.Called()
+
+	var r0 expsync.Dump
+	if rf, ok := ret.Get(0).(func() expsync.Dump); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(expsync.Dump)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Finalize provides a mock function with given fields:
 func (_m *GoldClient) Finalize() error {
 	ret := _m.Called()
@@ -66,6 +89,27 @@ func (_m *GoldClient) Finalize() error {
 	return r0
 }
 
+// ImportExpectations provides a mock function with given fields: req
+func (_m *GoldClient) ImportExpectations(req expsync.ImportRequest) (expsync.ImportResponse, error) {
+	ret := _m.Called(req)
+
+	var r0 expsync.ImportResponse
+	if rf, ok := ret.Get(0).(func(expsync.ImportRequest) expsync.ImportResponse); ok {
+		r0 = rf(req)
+	} else {
+		r0 = ret.Get(0).(expsync.ImportResponse)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(expsync.ImportRequest) error); ok {
+		r1 = rf(req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SetSharedConfig provides a mock function with given fields: sharedConfig, skipValidation
 func (_m *GoldClient) SetSharedConfig(sharedConfig jsonio.GoldResults, skipValidation bool) error {
 	ret := _m.Called(sharedConfig, skipValidation)
@@ -80,20 +124,20 @@ func (_m *GoldClient) SetSharedConfig(sharedConfig jsonio.GoldResults, skipValid
 	return r0
 }
 
-// Test provides a mock function with given fields: name, imgFileName, additionalKeys
-func (_m *GoldClient) Test(name types.TestName, imgFileName string, additionalKeys map[string]string) (bool, error) {
-	ret := _m.Called(name, imgFileName, additionalKeys)
+// Test provides a mock function with given fields: name, imgFileName, additionalKeys, optionalKeys
+func (_m *GoldClient) Test(name types.TestName, imgFileName string, additionalKeys map[string]string, optionalKeys map[string]string) (bool, error) {
+	ret := _m.Called(name, imgFileName, additionalKeys, optionalKeys)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(types.TestName, string, map[string]string) bool); ok {
-		r0 = rf(name, imgFileName, additionalKeys)
+	if rf, ok := ret.Get(0).(func(types.TestName, string, map[string]string, map[string]string) bool); ok {
+		r0 = rf(name, imgFileName, additionalKeys, optionalKeys)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(types.TestName, string, map[string]string) error); ok {
-		r1 = rf(name, imgFileName, additionalKeys)
+	if rf, ok := ret.Get(1).(func(types.TestName, string, map[string]string, map[string]string) error); ok {
+		r1 = rf(name, imgFileName, additionalKeys, optionalKeys)
 	} else {
 		r1 = ret.Error(1)
 	}
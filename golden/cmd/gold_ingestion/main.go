@@ -26,9 +26,11 @@ import (
 	"go.skia.org/infra/go/httputils"
 	"go.skia.org/infra/go/ingestion"
 	"go.skia.org/infra/go/ingestion/fs_ingestionstore"
+	"go.skia.org/infra/go/ingestion/sql_ingestionstore"
 	"go.skia.org/infra/go/sharedconfig"
 	"go.skia.org/infra/go/sklog"
 	"go.skia.org/infra/go/swarming"
+	"go.skia.org/infra/golden/go/sql_utils"
 	"google.golang.org/api/option"
 
 	// The init() of this package register several ingestion.Processors to
@@ -58,6 +60,8 @@ func main() {
 		local           = flag.Bool("local", false, "Running locally if true. As opposed to in production.")
 		promPort        = flag.String("prom_port", ":20000", "Metrics service address (e.g., ':10110')")
 		pubsubProjectID = flag.String("pubsub_project_id", "", "Project ID that houses the pubsub topics (e.g. for ingestion).")
+		sqlDataSource   = flag.String("sql_data_source", "", "Data source of the SQL database, e.g. the path of the SQLite file. Only used if --sql_driver is set.")
+		sqlDriver       = flag.String("sql_driver", "", "If set, keep track of ingested files in this SQL database ('sqlite3' or 'postgres') instead of Firestore.")
 	)
 
 	// Parse the options. So we can configure logging.
@@ -81,22 +85,39 @@ func main() {
 
 	// Initialize oauth client and start the ingesters.
 	tokenSrc, err := auth.NewDefaultTokenSource(*local, auth.SCOPE_USERINFO_EMAIL, storage.ScopeFullControl, pubsub.ScopePubSub, pubsub.ScopeCloudPlatform, swarming.AUTH_SCOPE, auth.SCOPE_GERRIT)
-	if err != nil {
+	clientConfig := httputils.DefaultClientConfig().With2xxOnly().WithDialTimeout(time.Second * 10)
+	if err == nil {
+		clientConfig = clientConfig.WithTokenSource(tokenSrc)
+	} else if *sqlDriver != "" {
+		// A deployment backed by SQL does not need to talk to Google Cloud.
+		sklog.Warningf("Failed to auth, continuing with an unauthenticated client: %s", err)
+	} else {
 		sklog.Fatalf("Failed to auth: %s", err)
 	}
-	client := httputils.DefaultClientConfig().WithTokenSource(tokenSrc).With2xxOnly().WithDialTimeout(time.Second * 10).Client()
+	client := clientConfig.Client()
 
-	if *fsNamespace == "" || *fsProjectID == "" {
-		sklog.Fatalf("You must specify --fs_namespace and --fs_project_id")
-	}
-	// Auth note: the underlying firestore.NewClient looks at the
-	// GOOGLE_APPLICATION_CREDENTIALS env variable, so we don't need to supply
-	// a token source.
-	fsClient, err := firestore.NewClient(context.Background(), *fsProjectID, "gold", *fsNamespace, nil)
-	if err != nil {
-		sklog.Fatalf("Unable to configure Firestore: %s", err)
+	var ingestionStore ingestion.IngestionStore
+	if *sqlDriver != "" {
+		db, err := sql_utils.Open(*sqlDriver, *sqlDataSource)
+		if err != nil {
+			sklog.Fatalf("Unable to open SQL database: %s", err)
+		}
+		if ingestionStore, err = sql_ingestionstore.New(db); err != nil {
+			sklog.Fatalf("Unable to configure SQL ingestionstore: %s", err)
+		}
+	} else {
+		if *fsNamespace == "" || *fsProjectID == "" {
+			sklog.Fatalf("You must specify --fs_namespace and --fs_project_id")
+		}
+		// Auth note: the underlying firestore.NewClient looks at the
+		// GOOGLE_APPLICATION_CREDENTIALS env variable, so we don't need to supply
+		// a token source.
+		fsClient, err := firestore.NewClient(context.Background(), *fsProjectID, "gold", *fsNamespace, nil)
+		if err != nil {
+			sklog.Fatalf("Unable to configure Firestore: %s", err)
+		}
+		ingestionStore = fs_ingestionstore.New(fsClient)
 	}
-	ingestionStore := fs_ingestionstore.New(fsClient)
 
 	// Start the ingesters.
 	config, err := sharedconfig.ConfigFromJson5File(*configFilename)
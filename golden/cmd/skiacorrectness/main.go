@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"flag"
 	"fmt"
 	"html/template"
@@ -15,6 +16,7 @@ import (
 	"strings"
 	"time"
 
+	gcstorage "cloud.google.com/go/storage"
 	"github.com/flynn/json5"
 	"github.com/gorilla/mux"
 	"golang.org/x/oauth2"
@@ -24,12 +26,18 @@ import (
 
 	"go.skia.org/infra/go/auth"
 	"go.skia.org/infra/go/bt"
+	"go.skia.org/infra/go/chatbot"
 	"go.skia.org/infra/go/common"
+	"go.skia.org/infra/go/email"
 	"go.skia.org/infra/go/eventbus"
 	"go.skia.org/infra/go/firestore"
+	"go.skia.org/infra/go/gcs/fs_gcsclient"
+	"go.skia.org/infra/go/gcs/gcsclient"
 	"go.skia.org/infra/go/gerrit"
 	"go.skia.org/infra/go/gevent"
+	"go.skia.org/infra/go/git/gitinfo"
 	"go.skia.org/infra/go/gitiles"
+	"go.skia.org/infra/go/gitlab"
 	"go.skia.org/infra/go/gitstore/bt_gitstore"
 	"go.skia.org/infra/go/httputils"
 	"go.skia.org/infra/go/login"
@@ -42,25 +50,43 @@ import (
 	"go.skia.org/infra/go/vcsinfo"
 	"go.skia.org/infra/go/vcsinfo/bt_vcs"
 	"go.skia.org/infra/golden/go/baseline/simple_baseliner"
+	"go.skia.org/infra/golden/go/clstore"
 	"go.skia.org/infra/golden/go/clstore/fs_clstore"
+	"go.skia.org/infra/golden/go/clstore/sql_clstore"
 	"go.skia.org/infra/golden/go/code_review"
 	"go.skia.org/infra/golden/go/code_review/commenter"
 	"go.skia.org/infra/golden/go/code_review/gerrit_crs"
 	"go.skia.org/infra/golden/go/code_review/github_crs"
+	"go.skia.org/infra/golden/go/code_review/gitlab_crs"
 	"go.skia.org/infra/golden/go/code_review/updater"
 	"go.skia.org/infra/golden/go/diff"
 	"go.skia.org/infra/golden/go/diffstore"
+	"go.skia.org/infra/golden/go/diffstore/failurestore/sql_failurestore"
+	"go.skia.org/infra/golden/go/diffstore/metricsstore/sql_metricsstore"
+	"go.skia.org/infra/golden/go/expstorage"
 	"go.skia.org/infra/golden/go/expstorage/fs_expstore"
+	"go.skia.org/infra/golden/go/expstorage/sql_expstore"
+	"go.skia.org/infra/golden/go/flaky"
+	"go.skia.org/infra/golden/go/flaky/fs_flakystore"
+	"go.skia.org/infra/golden/go/flaky/sql_flakystore"
 	"go.skia.org/infra/golden/go/ignore"
+	"go.skia.org/infra/golden/go/ignore/expiry_notifier"
 	"go.skia.org/infra/golden/go/ignore/fs_ignorestore"
+	"go.skia.org/infra/golden/go/ignore/sql_ignorestore"
 	"go.skia.org/infra/golden/go/indexer"
+	"go.skia.org/infra/golden/go/retention"
 	"go.skia.org/infra/golden/go/search"
 	"go.skia.org/infra/golden/go/shared"
+	"go.skia.org/infra/golden/go/sql_utils"
 	"go.skia.org/infra/golden/go/status"
 	"go.skia.org/infra/golden/go/storage"
 	"go.skia.org/infra/golden/go/tilesource"
+	"go.skia.org/infra/golden/go/tjstore"
 	"go.skia.org/infra/golden/go/tjstore/fs_tjstore"
+	"go.skia.org/infra/golden/go/tjstore/sql_tjstore"
+	"go.skia.org/infra/golden/go/tracestore"
 	"go.skia.org/infra/golden/go/tracestore/bt_tracestore"
+	"go.skia.org/infra/golden/go/tracestore/sql_tracestore"
 	"go.skia.org/infra/golden/go/warmer"
 	"go.skia.org/infra/golden/go/web"
 )
@@ -98,25 +124,44 @@ func main() {
 		defaultMatchFields  = flag.String("match_fields", "name", "A comma separated list of fields that need to match when finding closest images.")
 		diffServerGRPCAddr  = flag.String("diff_server_grpc", "", "The grpc port of the diff server. 'diff_server_http also needs to be set.")
 		diffServerImageAddr = flag.String("diff_server_http", "", "The images serving address of the diff server. 'diff_server_grpc has to be set as well.")
+		emailSecretFile     = flag.String("email_client_secret_file", "", "OAuth client secret JSON file for sending emails about expiring ignore rules. If empty, no emails are sent.")
+		emailTokenCacheFile = flag.String("email_token_cache_file", "client_token.json", "OAuth token cache file for sending email.")
 		eventTopic          = flag.String("event_topic", "", "The pubsub topic to use for distributed events.")
 		forceLogin          = flag.Bool("force_login", true, "Force the user to be authenticated for all requests.")
 		fsNamespace         = flag.String("fs_namespace", "", "Typically the instance id. e.g. 'flutter', 'skia', etc")
 		fsProjectID         = flag.String("fs_project_id", "skia-firestore", "The project with the firestore instance. Datastore and Firestore can't be in the same project.")
+		gcBatchSize         = flag.Int("gc_batch_size", retention.DefaultBatchSize, "How many unused digests are purged at once.")
+		gcDryRun            = flag.Bool("gc_dry_run", true, "If true, the unused digests are only logged and not purged.")
+		gcImageBucket       = flag.String("gc_image_bucket", "", "GCS bucket with the images, i.e. the --gs_bucket of the diff server. Required for --gc_interval unless --image_dir is set.")
+		gcInterval          = flag.Duration("gc_interval", 0, "How often to purge the digests that are no longer needed. If 0, nothing is purged. Only the authoritative instance purges.")
+		gcMinAge            = flag.Duration("gc_min_age", 24*time.Hour, "Images uploaded more recently than this are not purged, since their results may not have been ingested yet.")
+		gcTiles             = flag.Int("gc_tiles", 10, "Digests drawn in the last gc_tiles * n_commits commits are not purged. Must be at least 1.")
 		gerritURL           = flag.String("gerrit_url", gerrit.GERRIT_SKIA_URL, "URL of the Gerrit instance where we retrieve CL metadata.")
 		gitBTTableID        = flag.String("git_bt_table", "", "ID of the BigTable table that contains Git metadata")
 		githubCredPath      = flag.String("github_cred_path", "", "Filepath to file containing GitHub token")
 		githubRepo          = flag.String("github_repo", "", "User and repo of GitHub project to connect to, e.g. google/skia")
+		gitlabCredPath      = flag.String("gitlab_cred_path", "", "Filepath to file containing GitLab token")
+		gitlabProject       = flag.String("gitlab_project", "", "Full path of GitLab project to connect to, e.g. group/project")
+		gitlabURL           = flag.String("gitlab_url", "", "URL of the GitLab instance where we retrieve MR metadata, e.g. https://gitlab.com")
+		gitRepoDir          = flag.String("git_repo_dir", "", "Directory for a local checkout of --git_repo_url. Used instead of BigTable if --git_bt_table is not set.")
 		gitRepoURL          = flag.String("git_repo_url", "https://skia.googlesource.com/skia", "The URL to pass to git clone for the source repository.")
 		hang                = flag.Bool("hang", false, "If true, just hang and do nothing.")
+		ignoreChatRoom      = flag.String("ignore_notify_chat_room", "", "Chat room to notify about expiring ignore rules. Requires --notify_chat_bot_name.")
+		ignoreGracePeriod   = flag.Duration("ignore_grace_period", 0, "How long an expired ignore rule is still applied. If 0, expired rules are applied until they are deleted.")
+		ignoreNotifyBefore  = flag.Duration("ignore_notify_before", 3*24*time.Hour, "How long before an ignore rule expires its owners are notified. Only the authoritative instance notifies.")
+		imageCacheSize      = flag.Int("image_cache_size", 1, "Approximate cachesize used to cache images and diff metrics in GiB. Only used with --image_dir.")
+		imageDir            = flag.String("image_dir", "", "Directory with the uploaded images, laid out like the GCS bucket. If set, diffs are computed in this process instead of by a diff server. Requires --sql_driver.")
 		indexInterval       = flag.Duration("idx_interval", 5*time.Minute, "Interval at which the indexer calculates the search index.")
 		internalPort        = flag.String("internal_port", "", "HTTP service address for internal pprof data. No authentication on this port.")
+		knownHashesFile     = flag.String("known_hashes_file", "", "Local path, where the known hashes file should be stored if --sql_driver is set. If empty no file will be written.")
 		knownHashesGCSPath  = flag.String("known_hashes_gcs_path", "", "GCS path, where the known hashes file should be stored. If empty no file will be written. Format: <bucket>/<path>.")
 		litHTMLDir          = flag.String("lit_html_dir", "", "File path to build lit-html files")
 		local               = flag.Bool("local", false, "Running locally if true. As opposed to in production.")
 		nCommits            = flag.Int("n_commits", 50, "Number of recent commits to include in the analysis.")
+		notifyChatBotName   = flag.String("notify_chat_bot_name", "", "The chatbot name used to send chat notifications.")
 		noCloudLog          = flag.Bool("no_cloud_log", false, "Disables cloud logging. Primarily for running locally and in K8s.")
 		port                = flag.String("port", ":9000", "HTTP service address (e.g., ':9000')")
-		primaryCRS          = flag.String("primary_crs", "gerrit", "Primary CodeReviewSystem (e.g. 'gerrit', 'github'")
+		primaryCRS          = flag.String("primary_crs", "gerrit", "Primary CodeReviewSystem (e.g. 'gerrit', 'github', 'gitlab'")
 		promPort            = flag.String("prom_port", ":20000", "Metrics service address (e.g., ':10110')")
 		pubWhiteList        = flag.String("public_whitelist", "", fmt.Sprintf("File name of a JSON5 file that contains a query with the traces to white list. If set to '%s' everything is included. This is required if force_login is false.", everythingPublic))
 		pubsubProjectID     = flag.String("pubsub_project_id", "", "Project ID that houses the pubsub topics (e.g. for ingestion).")
@@ -124,6 +169,8 @@ func main() {
 		resourcesDir        = flag.String("resources_dir", "", "The directory to find Polymer templates, JS, and CSS files.")
 		showBotProgress     = flag.Bool("show_bot_progress", true, "Query status.skia.org for the progress of bot results.")
 		siteURL             = flag.String("site_url", "https://gold.skia.org", "URL where this app is hosted.")
+		sqlDataSource       = flag.String("sql_data_source", "", "Data source of the SQL database, e.g. the path of the SQLite file. Only used if --sql_driver is set.")
+		sqlDriver           = flag.String("sql_driver", "", "If set, store traces, expectations, ignores, ChangeLists and TryJobs in this SQL database ('sqlite3' or 'postgres') instead of BigTable and Firestore.")
 		tileFreshness       = flag.Duration("tile_freshness", time.Minute, "How often to re-fetch the tile")
 		traceBTTableID      = flag.String("trace_bt_table", "", "BigTable table ID for the traces.")
 	)
@@ -201,10 +248,25 @@ func main() {
 	// Get the token source for the service account with access to the services
 	// we need to operate.
 	tokenSource, err := auth.NewDefaultTokenSource(*local, auth.SCOPE_USERINFO_EMAIL, gstorage.CloudPlatformScope, auth.SCOPE_GERRIT)
-	if err != nil {
+	clientConfig := httputils.DefaultClientConfig()
+	if err == nil {
+		clientConfig = clientConfig.WithTokenSource(tokenSource)
+	} else if *sqlDriver != "" {
+		// A deployment backed by SQL does not need to talk to Google Cloud.
+		sklog.Warningf("Failed to authenticate service account, continuing with an unauthenticated client: %s", err)
+	} else {
 		sklog.Fatalf("Failed to authenticate service account: %s", err)
 	}
-	client := httputils.DefaultClientConfig().WithTokenSource(tokenSource).Client()
+	client := clientConfig.Client()
+
+	// If an SQL database was given, it replaces BigTable and Firestore for all the stores.
+	var db *sql.DB
+	if *sqlDriver != "" {
+		db, err = sql_utils.Open(*sqlDriver, *sqlDataSource)
+		if err != nil {
+			sklog.Fatalf("Unable to open SQL database: %s", err)
+		}
+	}
 
 	// serviceName uniquely identifies this host and app and is used as ID for
 	// other services.
@@ -216,6 +278,8 @@ func main() {
 	// If the addresses for a remote DiffStore were given, then set it up
 	// otherwise create an embedded DiffStore instance.
 	var diffStore diff.DiffStore = nil
+	// imageSource lists the images of the DiffStore, so unused ones can be purged.
+	var imageSource retention.DigestSource = nil
 	if (*diffServerGRPCAddr != "") || (*diffServerImageAddr != "") {
 		// Create the client connection and connect to the server.
 		conn, err := grpc.Dial(*diffServerGRPCAddr,
@@ -232,8 +296,37 @@ func main() {
 			sklog.Fatalf("Unable to initialize NetDiffStore: %s", err)
 		}
 		sklog.Infof("DiffStore: NetDiffStore initiated.")
+		if *gcImageBucket != "" {
+			storageClient, err := gcstorage.NewClient(ctx, option.WithHTTPClient(client))
+			if err != nil {
+				sklog.Fatalf("Unable to create storage client: %s", err)
+			}
+			imageSource = retention.NewGCSImageSource(gcsclient.New(storageClient, *gcImageBucket), diffstore.DefaultGCSImgDir, *gcMinAge)
+		}
+	} else if *imageDir != "" {
+		if db == nil {
+			sklog.Fatalf("--image_dir requires --sql_driver")
+		}
+		gcsClient, err := fs_gcsclient.New(*imageDir)
+		if err != nil {
+			sklog.Fatalf("Unable to use image directory %s: %s", *imageDir, err)
+		}
+		mStore, err := sql_metricsstore.New(db)
+		if err != nil {
+			sklog.Fatalf("Unable to initialize sql_metricsstore: %s", err)
+		}
+		fStore, err := sql_failurestore.New(db)
+		if err != nil {
+			sklog.Fatalf("Unable to initialize sql_failurestore: %s", err)
+		}
+		diffStore, err = diffstore.NewMemDiffStore(gcsClient, diffstore.DefaultGCSImgDir, *imageCacheSize, mStore, fStore)
+		if err != nil {
+			sklog.Fatalf("Allocating DiffStore failed: %s", err)
+		}
+		sklog.Infof("DiffStore: MemDiffStore initiated for %s.", *imageDir)
+		imageSource = retention.NewGCSImageSource(gcsClient, diffstore.DefaultGCSImgDir, *gcMinAge)
 	} else {
-		sklog.Fatalf("Must specify --diff_server_http and --diff_server_grpc")
+		sklog.Fatalf("Must specify --diff_server_http and --diff_server_grpc, or --image_dir")
 	}
 
 	// Set up the event bus which can either be in-process or distributed
@@ -278,57 +371,84 @@ func main() {
 			sklog.Fatalf("Error creating BT-backed VCS instance: %s", err)
 		}
 		vcs = bvcs
+	} else if *gitRepoDir != "" {
+		gvcs, err := gitinfo.CloneOrUpdate(ctx, *gitRepoURL, *gitRepoDir, false)
+		if err != nil {
+			sklog.Fatalf("Error creating VCS instance for local checkout in %s: %s", *gitRepoDir, err)
+		}
+		vcs = gvcs
 	} else {
-		sklog.Fatal("You must specify --bt_instance and --git_bt_table")
+		sklog.Fatal("You must specify --bt_instance and --git_bt_table, or --git_repo_dir")
 	}
 
-	if *traceBTTableID == "" {
-		sklog.Fatal("You must specify --trace_bt_table")
-	}
+	var traceStore tracestore.TraceStore
+	var gsClient storage.GCSClient
+	var fsClient *firestore.Client
+	var expStore expstorage.ExpectationsStore
+	if db != nil {
+		traceStore, err = sql_tracestore.New(db, vcs)
+		if err != nil {
+			sklog.Fatalf("Could not instantiate SQL tracestore: %s", err)
+		}
 
-	btc := bt_tracestore.BTConfig{
-		ProjectID:  *btProjectID,
-		InstanceID: *btInstanceID,
-		TableID:    *traceBTTableID,
-		VCS:        vcs,
-	}
+		gsClient = storage.NewLocalClient(storage.GCSClientOptions{
+			KnownHashesGCSPath: *knownHashesFile,
+			Dryrun:             !*authoritative,
+		})
 
-	err = bt_tracestore.InitBT(ctx, btc)
-	if err != nil {
-		sklog.Fatalf("Could not initialize BigTable tracestore with config %#v: %s", btc, err)
-	}
+		expStore, err = sql_expstore.New(db, evt, sql_expstore.ReadWrite)
+		if err != nil {
+			sklog.Fatalf("Unable to initialize sql_expstore: %s", err)
+		}
+	} else {
+		if *traceBTTableID == "" {
+			sklog.Fatal("You must specify --trace_bt_table")
+		}
 
-	traceStore, err := bt_tracestore.New(ctx, btc, false)
-	if err != nil {
-		sklog.Fatalf("Could not instantiate BT tracestore: %s", err)
-	}
+		btc := bt_tracestore.BTConfig{
+			ProjectID:  *btProjectID,
+			InstanceID: *btInstanceID,
+			TableID:    *traceBTTableID,
+			VCS:        vcs,
+		}
 
-	gsClientOpt := storage.GCSClientOptions{
-		KnownHashesGCSPath: *knownHashesGCSPath,
-		Dryrun:             !*authoritative,
-	}
+		err = bt_tracestore.InitBT(ctx, btc)
+		if err != nil {
+			sklog.Fatalf("Could not initialize BigTable tracestore with config %#v: %s", btc, err)
+		}
 
-	gsClient, err := storage.NewGCSClient(client, gsClientOpt)
-	if err != nil {
-		sklog.Fatalf("Unable to create GCSClient: %s", err)
-	}
+		traceStore, err = bt_tracestore.New(ctx, btc, false)
+		if err != nil {
+			sklog.Fatalf("Could not instantiate BT tracestore: %s", err)
+		}
 
-	if *fsNamespace == "" {
-		sklog.Fatalf("--fs_namespace must be set")
-	}
+		gsClientOpt := storage.GCSClientOptions{
+			KnownHashesGCSPath: *knownHashesGCSPath,
+			Dryrun:             !*authoritative,
+		}
 
-	// Auth note: the underlying firestore.NewClient looks at the
-	// GOOGLE_APPLICATION_CREDENTIALS env variable, so we don't need to supply
-	// a token source.
-	fsClient, err := firestore.NewClient(ctx, *fsProjectID, "gold", *fsNamespace, nil)
-	if err != nil {
-		sklog.Fatalf("Unable to configure Firestore: %s", err)
-	}
+		gsClient, err = storage.NewGCSClient(client, gsClientOpt)
+		if err != nil {
+			sklog.Fatalf("Unable to create GCSClient: %s", err)
+		}
 
-	// Set up the cloud expectations store
-	expStore, err := fs_expstore.New(ctx, fsClient, evt, fs_expstore.ReadWrite)
-	if err != nil {
-		sklog.Fatalf("Unable to initialize fs_expstore: %s", err)
+		if *fsNamespace == "" {
+			sklog.Fatalf("--fs_namespace must be set")
+		}
+
+		// Auth note: the underlying firestore.NewClient looks at the
+		// GOOGLE_APPLICATION_CREDENTIALS env variable, so we don't need to supply
+		// a token source.
+		fsClient, err = firestore.NewClient(ctx, *fsProjectID, "gold", *fsNamespace, nil)
+		if err != nil {
+			sklog.Fatalf("Unable to configure Firestore: %s", err)
+		}
+
+		// Set up the cloud expectations store
+		expStore, err = fs_expstore.New(ctx, fsClient, evt, fs_expstore.ReadWrite)
+		if err != nil {
+			sklog.Fatalf("Unable to initialize fs_expstore: %s", err)
+		}
 	}
 
 	baseliner := simple_baseliner.New(expStore)
@@ -350,14 +470,65 @@ func main() {
 	// openSite indicates whether this can expose all end-points. The user still has to be authenticated.
 	openSite := (*pubWhiteList == everythingPublic) || *forceLogin
 
-	ignoreStore := fs_ignorestore.New(ctx, fsClient)
+	var ignoreStore ignore.Store
+	if db != nil {
+		if ignoreStore, err = sql_ignorestore.New(db); err != nil {
+			sklog.Fatalf("Unable to initialize sql_ignorestore: %s", err)
+		}
+	} else {
+		ignoreStore = fs_ignorestore.New(ctx, fsClient)
+	}
 
-	if err := ignore.StartMetrics(ctx, ignoreStore, *tileFreshness); err != nil {
+	var expiryNotifiers []ignore.ExpiryNotifier
+	// Only one instance should notify the owners of the ignore rules.
+	if *authoritative {
+		if *emailSecretFile != "" {
+			emailAuth, err := email.NewFromFiles(*emailTokenCacheFile, *emailSecretFile)
+			if err != nil {
+				sklog.Fatalf("Failed to create email auth: %s", err)
+			}
+			expiryNotifiers = append(expiryNotifiers, expiry_notifier.NewEmailNotifier(emailAuth, *siteURL))
+		}
+		if *ignoreChatRoom != "" {
+			if *notifyChatBotName == "" {
+				sklog.Fatalf("--ignore_notify_chat_room requires --notify_chat_bot_name")
+			}
+			chatbot.Init(*notifyChatBotName)
+			expiryNotifiers = append(expiryNotifiers, expiry_notifier.NewChatNotifier(*ignoreChatRoom, *siteURL))
+		}
+	}
+	expiryMonitor := ignore.NewExpiryMonitor(ignore.ExpiryMonitorConfig{
+		Store:        ignoreStore,
+		Notifiers:    expiryNotifiers,
+		NotifyBefore: *ignoreNotifyBefore,
+		GracePeriod:  *ignoreGracePeriod,
+	})
+	if err := expiryMonitor.Start(ctx, *tileFreshness); err != nil {
 		sklog.Fatalf("Failed to start monitoring for expired ignore rules: %s", err)
 	}
 
-	cls := fs_clstore.New(fsClient, *primaryCRS)
-	tjs := fs_tjstore.New(fsClient)
+	var flakyStore flaky.Store
+	if db != nil {
+		if flakyStore, err = sql_flakystore.New(db); err != nil {
+			sklog.Fatalf("Unable to initialize sql_flakystore: %s", err)
+		}
+	} else {
+		flakyStore = fs_flakystore.New(fsClient)
+	}
+
+	var cls clstore.Store
+	var tjs tjstore.Store
+	if db != nil {
+		if cls, err = sql_clstore.New(db, *primaryCRS); err != nil {
+			sklog.Fatalf("Unable to initialize sql_clstore: %s", err)
+		}
+		if tjs, err = sql_tjstore.New(db); err != nil {
+			sklog.Fatalf("Unable to initialize sql_tjstore: %s", err)
+		}
+	} else {
+		cls = fs_clstore.New(fsClient, *primaryCRS)
+		tjs = fs_tjstore.New(fsClient)
+	}
 
 	var crs code_review.Client
 	if *primaryCRS == "gerrit" {
@@ -378,6 +549,23 @@ func main() {
 		githubTS := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: gToken})
 		c := httputils.DefaultClientConfig().With2xxOnly().WithTokenSource(githubTS).Client()
 		crs = github_crs.New(c, *githubRepo)
+	} else if *primaryCRS == "gitlab" {
+		if *gitlabURL == "" || *gitlabProject == "" || *gitlabCredPath == "" {
+			sklog.Fatalf("You must specify --gitlab_url, --gitlab_project and --gitlab_cred_path")
+		}
+		gBody, err := ioutil.ReadFile(*gitlabCredPath)
+		if err != nil {
+			sklog.Fatalf("Couldn't find gitlabToken in %s: %s", *gitlabCredPath, err)
+		}
+		gToken := strings.TrimSpace(string(gBody))
+		gitlabTS := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: gToken})
+		// The gitlab package interprets non-2xx responses itself.
+		c := httputils.DefaultClientConfig().WithTokenSource(gitlabTS).Client()
+		gitlabClient, err := gitlab.NewGitLab(*gitlabURL, *gitlabProject, c)
+		if err != nil {
+			sklog.Fatalf("Could not create GitLab client: %s", err)
+		}
+		crs = gitlab_crs.New(gitlabClient)
 	} else {
 		sklog.Warningf("CRS %s not supported, tracking ChangeLists is disabled", *primaryCRS)
 	}
@@ -390,8 +578,30 @@ func main() {
 		startCommenter(ctx, clCommenter)
 	}
 
+	if *authoritative && *gcInterval > 0 {
+		if imageSource == nil {
+			sklog.Fatalf("--gc_interval requires --gc_image_bucket or --image_dir")
+		}
+		if *gcTiles < 1 {
+			sklog.Fatalf("--gc_tiles must be at least 1, got %d", *gcTiles)
+		}
+		retention.New(retention.Config{
+			ChangeListStore:   cls,
+			DiffStore:         diffStore,
+			DigestSource:      imageSource,
+			ExpectationsStore: expStore,
+			TraceStore:        traceStore,
+			TryJobStore:       tjs,
+			NCommits:          *gcTiles * *nCommits,
+			BatchSize:         *gcBatchSize,
+			PurgeGCS:          true,
+			DryRun:            *gcDryRun,
+		}).Start(ctx, *gcInterval)
+	}
+
 	ctc := tilesource.CachedTileSourceConfig{
 		CLUpdater:              clUpdater,
+		IgnoreGracePeriod:      *ignoreGracePeriod,
 		IgnoreStore:            ignoreStore,
 		NCommits:               *nCommits,
 		PubliclyViewableParams: publiclyViewableParams,
@@ -411,6 +621,7 @@ func main() {
 		DiffStore:         diffStore,
 		EventBus:          evt,
 		ExpectationsStore: expStore,
+		FlakyStore:        flakyStore,
 		GCSClient:         gsClient,
 		TileSource:        tileSource,
 		Warmer:            warmer.New(),
@@ -448,7 +659,9 @@ func main() {
 		CodeReviewURLTemplate:            *crsURLTemplate,
 		DiffStore:                        diffStore,
 		ExpectationsStore:                expStore,
+		FlakyStore:                       flakyStore,
 		GCSClient:                        gsClient,
+		IgnoreGracePeriod:                *ignoreGracePeriod,
 		IgnoreStore:                      ignoreStore,
 		Indexer:                          ixr,
 		SearchAPI:                        searchAPI,
@@ -526,6 +739,11 @@ func main() {
 		jsonRouter.HandleFunc(trim("/json/ignores/add/"), handlers.AddIgnoreRule).Methods("POST")
 		jsonRouter.HandleFunc(trim("/json/ignores/del/{id}"), handlers.DeleteIgnoreRule).Methods("POST")
 		jsonRouter.HandleFunc(trim("/json/ignores/save/{id}"), handlers.UpdateIgnoreRule).Methods("POST")
+		jsonRouter.HandleFunc(trim("/json/flaky"), handlers.ListFlakyTraces).Methods("GET")
+		jsonRouter.HandleFunc(trim("/json/flaky/add"), handlers.AddFlakyMark).Methods("POST")
+		jsonRouter.HandleFunc(trim("/json/flaky/del"), handlers.DeleteFlakyMark).Methods("POST")
+		jsonRouter.HandleFunc(trim(shared.ExpectationsExportRoute), handlers.ExportExpectationsHandler).Methods("GET")
+		jsonRouter.HandleFunc(trim(shared.ExpectationsImportRoute), handlers.ImportExpectationsHandler).Methods("POST")
 	}
 
 	// Make sure we return a 404 for anything that starts with /json and could not be found.
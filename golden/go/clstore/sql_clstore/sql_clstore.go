@@ -0,0 +1,227 @@
+// Package sql_clstore implements the clstore.Store interface with an SQL (SQLite or Postgres)
+// backend.
+package sql_clstore
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"go.skia.org/infra/go/metrics2"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/golden/go/clstore"
+	"go.skia.org/infra/golden/go/code_review"
+	"go.skia.org/infra/golden/go/sql_utils"
+)
+
+// schema creates the ChangeLists and PatchSets tables. Both are keyed by the name of the
+// CodeReviewSystem so that more than one system can share a database.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS ChangeLists (
+	crs      TEXT NOT NULL,
+	id       TEXT NOT NULL,
+	owner    TEXT NOT NULL,
+	status   INTEGER NOT NULL,
+	subject  TEXT NOT NULL,
+	updated  BIGINT NOT NULL,
+	PRIMARY KEY (crs, id)
+)`,
+	`CREATE INDEX IF NOT EXISTS ChangeListsByUpdated ON ChangeLists (crs, updated)`,
+	`CREATE TABLE IF NOT EXISTS PatchSets (
+	crs                    TEXT NOT NULL,
+	cl_id                  TEXT NOT NULL,
+	id                     TEXT NOT NULL,
+	ps_order               INTEGER NOT NULL,
+	git_hash               TEXT NOT NULL,
+	has_untriaged_digests  BOOLEAN NOT NULL,
+	commented_on_cl        BOOLEAN NOT NULL,
+	PRIMARY KEY (crs, cl_id, id)
+)`,
+}
+
+// StoreImpl is the SQL based implementation of clstore.
+type StoreImpl struct {
+	db      *sql.DB
+	crsName string
+}
+
+// New returns a new StoreImpl, creating the tables if they don't already exist.
+func New(db *sql.DB, crsName string) (*StoreImpl, error) {
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, skerr.Wrapf(err, "creating clstore tables")
+		}
+	}
+	return &StoreImpl{
+		db:      db,
+		crsName: crsName,
+	}, nil
+}
+
+// GetChangeList implements the clstore.Store interface.
+func (s *StoreImpl) GetChangeList(ctx context.Context, id string) (code_review.ChangeList, error) {
+	defer metrics2.FuncTimer().Stop()
+	row := s.db.QueryRowContext(ctx, `SELECT id, owner, status, subject, updated FROM ChangeLists WHERE crs=$1 AND id=$2`, s.crsName, id)
+	cl, err := scanChangeList(row)
+	if err == sql.ErrNoRows {
+		return code_review.ChangeList{}, clstore.ErrNotFound
+	} else if err != nil {
+		return code_review.ChangeList{}, skerr.Wrapf(err, "retrieving %s CL %s", s.crsName, id)
+	}
+	return cl, nil
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanChangeList reads a ChangeList from a row with the columns id, owner, status, subject
+// and updated.
+func scanChangeList(row scanner) (code_review.ChangeList, error) {
+	cl := code_review.ChangeList{}
+	var updated int64
+	if err := row.Scan(&cl.SystemID, &cl.Owner, &cl.Status, &cl.Subject, &updated); err != nil {
+		return code_review.ChangeList{}, err
+	}
+	cl.Updated = sql_utils.FromTimestamp(updated)
+	return cl, nil
+}
+
+// GetChangeLists implements the clstore.Store interface.
+func (s *StoreImpl) GetChangeLists(ctx context.Context, opts clstore.SearchOptions) ([]code_review.ChangeList, int, error) {
+	defer metrics2.FuncTimer().Stop()
+	if opts.Limit <= 0 {
+		return nil, 0, skerr.Fmt("must supply a limit")
+	}
+	q := `SELECT id, owner, status, subject, updated FROM ChangeLists WHERE crs=$1`
+	args := []interface{}{s.crsName}
+	if !opts.After.IsZero() {
+		args = append(args, sql_utils.ToTimestamp(opts.After))
+		q += ` AND updated >= $2`
+	}
+	if opts.OpenCLsOnly {
+		args = append(args, code_review.Open)
+		q += ` AND status = $` + strconv.Itoa(len(args))
+	}
+	args = append(args, opts.Limit, opts.StartIdx)
+	q += ` ORDER BY updated DESC, id LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, -1, skerr.Wrapf(err, "fetching cls in range [%d:%d]", opts.StartIdx, opts.StartIdx+opts.Limit)
+	}
+	defer util.Close(rows)
+	var xcl []code_review.ChangeList
+	for rows.Next() {
+		cl, err := scanChangeList(rows)
+		if err != nil {
+			return nil, -1, skerr.Wrapf(err, "reading cl")
+		}
+		xcl = append(xcl, cl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, -1, skerr.Wrapf(err, "fetching cls in range [%d:%d]", opts.StartIdx, opts.StartIdx+opts.Limit)
+	}
+	n := len(xcl)
+	if n == opts.Limit && n != 0 {
+		// Counting would need another query, so just give the "many" response like the other
+		// implementations.
+		n = clstore.CountMany
+	} else {
+		// We know exactly either 1) how many there are (if n > 0) or 2) an upper bound on how many
+		// there are (if n == 0)
+		n += opts.StartIdx
+	}
+	return xcl, n, nil
+}
+
+// GetPatchSet implements the clstore.Store interface.
+func (s *StoreImpl) GetPatchSet(ctx context.Context, clID, psID string) (code_review.PatchSet, error) {
+	defer metrics2.FuncTimer().Stop()
+	row := s.db.QueryRowContext(ctx, `SELECT id, cl_id, ps_order, git_hash, has_untriaged_digests, commented_on_cl
+		FROM PatchSets WHERE crs=$1 AND cl_id=$2 AND id=$3`, s.crsName, clID, psID)
+	ps, err := scanPatchSet(row)
+	if err == sql.ErrNoRows {
+		return code_review.PatchSet{}, clstore.ErrNotFound
+	} else if err != nil {
+		return code_review.PatchSet{}, skerr.Wrapf(err, "retrieving PS %s of %s CL %s", psID, s.crsName, clID)
+	}
+	return ps, nil
+}
+
+// scanPatchSet reads a PatchSet from a row with the columns id, cl_id, ps_order, git_hash,
+// has_untriaged_digests and commented_on_cl.
+func scanPatchSet(row scanner) (code_review.PatchSet, error) {
+	ps := code_review.PatchSet{}
+	err := row.Scan(&ps.SystemID, &ps.ChangeListID, &ps.Order, &ps.GitHash, &ps.HasUntriagedDigests, &ps.CommentedOnCL)
+	return ps, err
+}
+
+// GetPatchSetByOrder implements the clstore.Store interface.
+func (s *StoreImpl) GetPatchSetByOrder(ctx context.Context, clID string, psOrder int) (code_review.PatchSet, error) {
+	defer metrics2.FuncTimer().Stop()
+	row := s.db.QueryRowContext(ctx, `SELECT id, cl_id, ps_order, git_hash, has_untriaged_digests, commented_on_cl
+		FROM PatchSets WHERE crs=$1 AND cl_id=$2 AND ps_order=$3 LIMIT 1`, s.crsName, clID, psOrder)
+	ps, err := scanPatchSet(row)
+	if err == sql.ErrNoRows {
+		return code_review.PatchSet{}, clstore.ErrNotFound
+	} else if err != nil {
+		return code_review.PatchSet{}, skerr.Wrapf(err, "fetching patchsets for cl %s", clID)
+	}
+	return ps, nil
+}
+
+// GetPatchSets implements the clstore.Store interface.
+func (s *StoreImpl) GetPatchSets(ctx context.Context, clID string) ([]code_review.PatchSet, error) {
+	defer metrics2.FuncTimer().Stop()
+	rows, err := s.db.QueryContext(ctx, `SELECT id, cl_id, ps_order, git_hash, has_untriaged_digests, commented_on_cl
+		FROM PatchSets WHERE crs=$1 AND cl_id=$2 ORDER BY ps_order`, s.crsName, clID)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "fetching patchsets for cl %s", clID)
+	}
+	defer util.Close(rows)
+	var xps []code_review.PatchSet
+	for rows.Next() {
+		ps, err := scanPatchSet(rows)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "reading patchset for cl %s", clID)
+		}
+		xps = append(xps, ps)
+	}
+	return xps, skerr.Wrap(rows.Err())
+}
+
+// PutChangeList implements the clstore.Store interface.
+func (s *StoreImpl) PutChangeList(ctx context.Context, cl code_review.ChangeList) error {
+	defer metrics2.FuncTimer().Stop()
+	_, err := s.db.ExecContext(ctx, `INSERT INTO ChangeLists (crs, id, owner, status, subject, updated) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (crs, id) DO UPDATE SET owner=excluded.owner, status=excluded.status, subject=excluded.subject, updated=excluded.updated`,
+		s.crsName, cl.SystemID, cl.Owner, cl.Status, cl.Subject, sql_utils.ToTimestamp(cl.Updated))
+	if err != nil {
+		return skerr.Wrapf(err, "could not write CL %v to clstore", cl)
+	}
+	return nil
+}
+
+// PutPatchSet implements the clstore.Store interface.
+func (s *StoreImpl) PutPatchSet(ctx context.Context, ps code_review.PatchSet) error {
+	defer metrics2.FuncTimer().Stop()
+	_, err := s.db.ExecContext(ctx, `INSERT INTO PatchSets (crs, cl_id, id, ps_order, git_hash, has_untriaged_digests, commented_on_cl) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (crs, cl_id, id) DO UPDATE SET ps_order=excluded.ps_order, git_hash=excluded.git_hash,
+		has_untriaged_digests=excluded.has_untriaged_digests, commented_on_cl=excluded.commented_on_cl`,
+		s.crsName, ps.ChangeListID, ps.SystemID, ps.Order, ps.GitHash, ps.HasUntriagedDigests, ps.CommentedOnCL)
+	if err != nil {
+		return skerr.Wrapf(err, "could not write PS %v to clstore", ps)
+	}
+	return nil
+}
+
+// System implements the clstore.Store interface.
+func (s *StoreImpl) System() string {
+	return s.crsName
+}
+
+// Make sure StoreImpl fulfills the clstore.Store interface.
+var _ clstore.Store = (*StoreImpl)(nil)
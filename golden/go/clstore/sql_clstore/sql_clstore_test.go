@@ -0,0 +1,63 @@
+package sql_clstore
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/golden/go/clstore"
+	"go.skia.org/infra/golden/go/clstore/shared_tests"
+	"go.skia.org/infra/golden/go/code_review"
+	"go.skia.org/infra/golden/go/sql_utils"
+)
+
+func TestSharedTests_SQLite(t *testing.T) {
+	unittest.MediumTest(t)
+	runSharedTests(t, sql_utils.NewSQLiteForTesting)
+}
+
+func TestSharedTests_Postgres(t *testing.T) {
+	unittest.LargeTest(t) // should use a Postgres database
+	runSharedTests(t, sql_utils.NewPostgresForTesting)
+}
+
+// runSharedTests runs each of the shared tests with Stores in a new database.
+func runSharedTests(t *testing.T, newDB func(sktest.TestingT) (*sql.DB, func())) {
+	for name, test := range shared_tests.Tests {
+		t.Run(name, func(t *testing.T) {
+			db, cleanup := newDB(t)
+			defer cleanup()
+
+			test(t, func(crsName string) clstore.Store {
+				return newStore(t, db, crsName)
+			})
+		})
+	}
+}
+
+func TestNewTwice(t *testing.T) {
+	unittest.MediumTest(t)
+	db, cleanup := sql_utils.NewSQLiteForTesting(t)
+	defer cleanup()
+
+	f := newStore(t, db, "gerrit")
+	cl := code_review.ChangeList{SystemID: "1234", Status: code_review.Open}
+	require.NoError(t, f.PutChangeList(context.Background(), cl))
+
+	// Creating the store again on the same database keeps the existing data.
+	f = newStore(t, db, "gerrit")
+	actual, err := f.GetChangeList(context.Background(), "1234")
+	require.NoError(t, err)
+	assert.Equal(t, cl, actual)
+}
+
+func newStore(t *testing.T, db *sql.DB, crsName string) *StoreImpl {
+	s, err := New(db, crsName)
+	require.NoError(t, err)
+	return s
+}
@@ -0,0 +1,159 @@
+// Package gitlab_crs provides a client for Gold's interaction with
+// the GitLab code review system.
+package gitlab_crs
+
+import (
+	"context"
+	"regexp"
+
+	"go.skia.org/infra/go/gitlab"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/sklog"
+	"go.skia.org/infra/go/vcsinfo"
+	"go.skia.org/infra/golden/go/code_review"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// Authenticated clients of gitlab.com can do up to 2000 queries per
+	// minute. These limits are conservative based on that; self-hosted
+	// instances are typically more generous.
+	maxQPS   = rate.Limit(5)
+	maxBurst = 100
+)
+
+type CRSImpl struct {
+	client *gitlab.GitLab
+	rl     *rate.Limiter
+}
+
+// New returns a new instance of CRSImpl, ready to target the project of the
+// given GitLab client.
+func New(client *gitlab.GitLab) *CRSImpl {
+	return &CRSImpl{
+		client: client,
+		rl:     rate.NewLimiter(maxQPS, maxBurst),
+	}
+}
+
+// GetChangeList implements the code_review.Client interface.
+func (c *CRSImpl) GetChangeList(ctx context.Context, id string) (code_review.ChangeList, error) {
+	iid, err := gitlab.ParseMergeRequestID(id)
+	if err != nil {
+		return code_review.ChangeList{}, skerr.Fmt("invalid ChangeList ID")
+	}
+	// Respect the rate limit.
+	if err := c.rl.Wait(ctx); err != nil {
+		return code_review.ChangeList{}, skerr.Wrap(err)
+	}
+	mr, err := c.client.GetMergeRequest(ctx, iid)
+	if err == gitlab.ErrNotFound {
+		return code_review.ChangeList{}, code_review.ErrNotFound
+	} else if err != nil {
+		return code_review.ChangeList{}, skerr.Wrapf(err, "fetching merge request %d from GitLab", iid)
+	}
+
+	state := code_review.Open
+	if mr.State == gitlab.MR_STATE_MERGED {
+		state = code_review.Landed
+	} else if mr.State == gitlab.MR_STATE_CLOSED {
+		state = code_review.Abandoned
+	}
+	owner := ""
+	if mr.Author != nil {
+		owner = mr.Author.Username
+	}
+
+	return code_review.ChangeList{
+		SystemID: id,
+		Owner:    owner,
+		Subject:  mr.Title,
+		Status:   state,
+		Updated:  mr.UpdatedAt,
+	}, nil
+}
+
+// GetPatchSets implements the code_review.Client interface. Each version of
+// the merge request's diff, created when commits are pushed to its source
+// branch, is treated as a PatchSet. Like GitHub, PatchSets are identified by
+// their commit hash, since that's what is known to CI pipelines.
+func (c *CRSImpl) GetPatchSets(ctx context.Context, clID string) ([]code_review.PatchSet, error) {
+	iid, err := gitlab.ParseMergeRequestID(clID)
+	if err != nil {
+		return nil, skerr.Fmt("invalid ChangeList ID")
+	}
+	// Respect the rate limit.
+	if err := c.rl.Wait(ctx); err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	versions, err := c.client.GetMergeRequestVersions(ctx, iid)
+	if err == gitlab.ErrNotFound {
+		return nil, code_review.ErrNotFound
+	} else if err != nil {
+		return nil, skerr.Wrapf(err, "fetching versions of merge request %d from GitLab", iid)
+	}
+
+	// GitLab returns versions in reverse chronological order.
+	xps := make([]code_review.PatchSet, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		xps = append(xps, code_review.PatchSet{
+			SystemID:     v.HeadCommitSHA,
+			ChangeListID: clID,
+			Order:        len(xps) + 1,
+			GitHash:      v.HeadCommitSHA,
+		})
+	}
+	return xps, nil
+}
+
+// GetChangeListIDForCommit implements the code_review.Client interface.
+func (c *CRSImpl) GetChangeListIDForCommit(ctx context.Context, commit *vcsinfo.LongCommit) (string, error) {
+	if commit == nil {
+		return "", skerr.Fmt("commit cannot be nil")
+	}
+	id, err := extractMRFromBody(commit.Body)
+	if err != nil {
+		sklog.Debugf("Could not find GitLab merge request: %s", err)
+		return "", code_review.ErrNotFound
+	}
+	return id, nil
+}
+
+// GitLab's merge commits reference the merge request at the end of the body.
+// e.g. "See merge request group/project!123" refers to merge request 123.
+var mrReference = regexp.MustCompile(`(?m)^See merge request \S*!(?P<id>\d+)\s*$`)
+
+// extractMRFromBody returns the merge request id extracted from the body of
+// a merge commit, or an error if it cannot.
+func extractMRFromBody(b string) (string, error) {
+	matches := mrReference.FindAllStringSubmatch(b, -1)
+	if len(matches) == 0 {
+		return "", skerr.Fmt("Could not find merge request in body %q", b)
+	}
+	// Use the last reference, in case the body quotes another merge
+	// commit message.
+	return matches[len(matches)-1][1], nil
+}
+
+// CommentOn implements the code_review.Client interface.
+func (c *CRSImpl) CommentOn(ctx context.Context, clID, message string) error {
+	sklog.Infof("Commenting on GitLab CL (MR) %s with message %q", clID, message)
+	iid, err := gitlab.ParseMergeRequestID(clID)
+	if err != nil {
+		return skerr.Fmt("invalid ChangeList ID")
+	}
+	// Respect the rate limit.
+	if err := c.rl.Wait(ctx); err != nil {
+		return skerr.Wrap(err)
+	}
+	return skerr.Wrapf(c.client.AddComment(ctx, iid, message), "commenting on GitLab merge request %d", iid)
+}
+
+// System implements the code_review.Client interface.
+func (c *CRSImpl) System() string {
+	return "gitlab"
+}
+
+// Make sure CRSImpl fulfills the code_review.Client interface.
+var _ code_review.Client = (*CRSImpl)(nil)
@@ -0,0 +1,226 @@
+package gitlab_crs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/gitlab"
+	gitlab_testutils "go.skia.org/infra/go/gitlab/testutils"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/go/vcsinfo"
+	"go.skia.org/infra/golden/go/code_review"
+)
+
+func TestGetChangeListSunnyDay(t *testing.T) {
+	unittest.MediumTest(t)
+
+	f := gitlab_testutils.NewFakeGitLab(t)
+	defer f.Close()
+	c := New(f.GitLab())
+
+	ts := time.Date(2019, time.November, 7, 23, 39, 17, 0, time.UTC)
+	mr := f.AddMergeRequest(&gitlab.MergeRequest{
+		Title:     "Roll engine ddceed5f7af1..629930e8887c (1 commits)",
+		Author:    &gitlab.User{Username: "engine-autoroll"},
+		UpdatedAt: ts,
+	})
+
+	cl, err := c.GetChangeList(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, code_review.ChangeList{
+		SystemID: "1",
+		Owner:    "engine-autoroll",
+		Status:   code_review.Open,
+		Subject:  "Roll engine ddceed5f7af1..629930e8887c (1 commits)",
+		Updated:  ts,
+	}, cl)
+
+	f.UpdateMergeRequest(mr.IID, func(mr *gitlab.MergeRequest) {
+		mr.State = gitlab.MR_STATE_MERGED
+	})
+	cl, err = c.GetChangeList(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, code_review.Landed, cl.Status)
+
+	f.UpdateMergeRequest(mr.IID, func(mr *gitlab.MergeRequest) {
+		mr.State = gitlab.MR_STATE_CLOSED
+	})
+	cl, err = c.GetChangeList(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, code_review.Abandoned, cl.Status)
+}
+
+func TestGetChangeListDoesNotExist(t *testing.T) {
+	unittest.MediumTest(t)
+
+	f := gitlab_testutils.NewFakeGitLab(t)
+	defer f.Close()
+	c := New(f.GitLab())
+
+	_, err := c.GetChangeList(context.Background(), "1234")
+	require.Error(t, err)
+	assert.Equal(t, code_review.ErrNotFound, err)
+}
+
+func TestGetChangeListInvalidID(t *testing.T) {
+	unittest.SmallTest(t)
+
+	c := New(nil)
+
+	_, err := c.GetChangeList(context.Background(), "bogus")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid")
+}
+
+func TestGetPatchSetsSunnyDay(t *testing.T) {
+	unittest.MediumTest(t)
+
+	f := gitlab_testutils.NewFakeGitLab(t)
+	defer f.Close()
+	c := New(f.GitLab())
+
+	mr := f.AddMergeRequest(&gitlab.MergeRequest{
+		Title: "Make things better",
+		SHA:   "aaa",
+	})
+	f.PushCommit(mr.IID, "bbb")
+	f.PushCommit(mr.IID, "ccc")
+
+	xps, err := c.GetPatchSets(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, []code_review.PatchSet{
+		{
+			SystemID:     "aaa",
+			ChangeListID: "1",
+			Order:        1,
+			GitHash:      "aaa",
+		},
+		{
+			SystemID:     "bbb",
+			ChangeListID: "1",
+			Order:        2,
+			GitHash:      "bbb",
+		},
+		{
+			SystemID:     "ccc",
+			ChangeListID: "1",
+			Order:        3,
+			GitHash:      "ccc",
+		},
+	}, xps)
+}
+
+func TestGetPatchSetsNone(t *testing.T) {
+	unittest.MediumTest(t)
+
+	f := gitlab_testutils.NewFakeGitLab(t)
+	defer f.Close()
+	c := New(f.GitLab())
+
+	f.AddMergeRequest(&gitlab.MergeRequest{
+		Title: "Nothing pushed yet",
+	})
+
+	xps, err := c.GetPatchSets(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Empty(t, xps)
+}
+
+func TestGetPatchSetsDoesNotExist(t *testing.T) {
+	unittest.MediumTest(t)
+
+	f := gitlab_testutils.NewFakeGitLab(t)
+	defer f.Close()
+	c := New(f.GitLab())
+
+	_, err := c.GetPatchSets(context.Background(), "1234")
+	require.Error(t, err)
+	assert.Equal(t, code_review.ErrNotFound, err)
+}
+
+func TestGetPatchSetsInvalidID(t *testing.T) {
+	unittest.SmallTest(t)
+
+	c := New(nil)
+
+	_, err := c.GetPatchSets(context.Background(), "bogus")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid")
+}
+
+func TestGetChangeListForCommitSunnyDay(t *testing.T) {
+	unittest.SmallTest(t)
+
+	c := New(nil)
+
+	clID, err := c.GetChangeListIDForCommit(context.Background(), &vcsinfo.LongCommit{
+		ShortCommit: &vcsinfo.ShortCommit{
+			Subject: "Merge branch 'roll' into 'master'",
+		},
+		Body: "Roll engine ddceed5f7af1..629930e8887c (1 commits)\n\nSee merge request unit/test!44380",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "44380", clID)
+}
+
+func TestGetChangeListForCommitMalformed(t *testing.T) {
+	unittest.SmallTest(t)
+
+	c := New(nil)
+
+	_, err := c.GetChangeListIDForCommit(context.Background(), &vcsinfo.LongCommit{
+		ShortCommit: &vcsinfo.ShortCommit{
+			Subject: "Roll engine ddceed5f7af1..629930e8887c (1 commits)",
+		},
+		Body: "This mentions unit/test!44380 but isn't a merge commit.",
+	})
+	require.Error(t, err)
+	assert.Equal(t, code_review.ErrNotFound, err)
+}
+
+func TestExtractMRFromBody(t *testing.T) {
+	unittest.SmallTest(t)
+
+	id, err := extractMRFromBody("Title\n\nSee merge request group/sub/project!12\n")
+	require.NoError(t, err)
+	assert.Equal(t, "12", id)
+
+	// The last reference wins.
+	id, err = extractMRFromBody("Revert \"Title\"\n\nSee merge request group/project!12\n\nSee merge request group/project!34")
+	require.NoError(t, err)
+	assert.Equal(t, "34", id)
+
+	_, err = extractMRFromBody("")
+	require.Error(t, err)
+}
+
+func TestCommentOnSunnyDay(t *testing.T) {
+	unittest.MediumTest(t)
+
+	f := gitlab_testutils.NewFakeGitLab(t)
+	defer f.Close()
+	c := New(f.GitLab())
+
+	f.AddMergeRequest(&gitlab.MergeRequest{
+		Title: "Make things better",
+	})
+
+	require.NoError(t, c.CommentOn(context.Background(), "1", "untriaged digests!"))
+	assert.Equal(t, []string{"untriaged digests!"}, f.Notes(1))
+}
+
+func TestCommentOnError(t *testing.T) {
+	unittest.MediumTest(t)
+
+	f := gitlab_testutils.NewFakeGitLab(t)
+	defer f.Close()
+	c := New(f.GitLab())
+
+	err := c.CommentOn(context.Background(), "1234", "untriaged digests!")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "commenting on GitLab merge request 1234")
+}
@@ -0,0 +1,90 @@
+// Package sql_failurestore hosts an SQL-based (SQLite or Postgres) implementation of
+// failurestore.FailureStore.
+package sql_failurestore
+
+import (
+	"context"
+	"database/sql"
+
+	"go.skia.org/infra/go/metrics2"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/golden/go/diff"
+	"go.skia.org/infra/golden/go/diffstore/failurestore"
+	"go.skia.org/infra/golden/go/sql_utils"
+	"go.skia.org/infra/golden/go/types"
+)
+
+// schema creates the DigestFailures table. There is at most one failure per digest; ts is in
+// milliseconds since the epoch, like diff.DigestFailure.
+const schema = `CREATE TABLE IF NOT EXISTS DigestFailures (
+	digest  TEXT PRIMARY KEY,
+	reason  TEXT NOT NULL,
+	ts      BIGINT NOT NULL
+)`
+
+// StoreImpl is the SQL-backed implementation of FailureStore.
+type StoreImpl struct {
+	db *sql.DB
+}
+
+// New returns a new instance of the SQL-backed failurestore.FailureStore implementation,
+// creating the DigestFailures table if it doesn't already exist.
+func New(db *sql.DB) (*StoreImpl, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, skerr.Wrapf(err, "creating DigestFailures table")
+	}
+	return &StoreImpl{db: db}, nil
+}
+
+// UnavailableDigests implements the failurestore.FailureStore interface.
+func (s *StoreImpl) UnavailableDigests(ctx context.Context) (map[types.Digest]*diff.DigestFailure, error) {
+	defer metrics2.FuncTimer().Stop()
+	rows, err := s.db.QueryContext(ctx, `SELECT digest, reason, ts FROM DigestFailures`)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "querying digest failures")
+	}
+	defer util.Close(rows)
+	results := map[types.Digest]*diff.DigestFailure{}
+	for rows.Next() {
+		f := &diff.DigestFailure{}
+		if err := rows.Scan(&f.Digest, &f.Reason, &f.TS); err != nil {
+			return nil, skerr.Wrapf(err, "reading digest failure")
+		}
+		results[f.Digest] = f
+	}
+	return results, skerr.Wrap(rows.Err())
+}
+
+// AddDigestFailure implements the failurestore.FailureStore interface. If there already is a
+// failure for the digest, the more recent of the two is kept.
+func (s *StoreImpl) AddDigestFailure(ctx context.Context, failure *diff.DigestFailure) error {
+	defer metrics2.FuncTimer().Stop()
+	_, err := s.db.ExecContext(ctx, `INSERT INTO DigestFailures (digest, reason, ts) VALUES ($1, $2, $3)
+ON CONFLICT (digest) DO UPDATE SET reason=excluded.reason, ts=excluded.ts WHERE excluded.ts >= DigestFailures.ts`,
+		failure.Digest, failure.Reason, failure.TS)
+	if err != nil {
+		return skerr.Wrapf(err, "writing failure (Digest=%q): %v", failure.Digest, failure)
+	}
+	return nil
+}
+
+// PurgeDigestFailures implements the failurestore.FailureStore interface.
+func (s *StoreImpl) PurgeDigestFailures(ctx context.Context, digests types.DigestSlice) error {
+	defer metrics2.FuncTimer().Stop()
+	if len(digests) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 0, len(digests))
+	for _, d := range digests {
+		args = append(args, d)
+	}
+	q := `DELETE FROM DigestFailures WHERE digest IN (` + sql_utils.InPlaceholders(1, len(args)) + `)`
+	if _, err := s.db.ExecContext(ctx, q, args...); err != nil {
+		return skerr.Wrapf(err, "purging failures for %d digests", len(digests))
+	}
+	return nil
+}
+
+// Make sure StoreImpl fulfills the FailureStore interface
+var _ failurestore.FailureStore = (*StoreImpl)(nil)
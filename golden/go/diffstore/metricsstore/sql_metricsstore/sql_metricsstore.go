@@ -0,0 +1,154 @@
+// Package sql_metricsstore hosts an SQL-based (SQLite or Postgres) implementation of
+// metricsstore.MetricsStore.
+package sql_metricsstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.skia.org/infra/go/metrics2"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/golden/go/diff"
+	"go.skia.org/infra/golden/go/diffstore/common"
+	"go.skia.org/infra/golden/go/diffstore/metricsstore"
+	"go.skia.org/infra/golden/go/sql_utils"
+	"go.skia.org/infra/golden/go/types"
+)
+
+// batchSize is the maximum number of ids used in a single query. It keeps us well below the
+// limit on the number of placeholders in SQLite.
+const batchSize = 500
+
+// schema creates the DiffMetrics table. The left and right digests of the diff id are stored
+// separately to support purging by digest.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS DiffMetrics (
+	id                   TEXT PRIMARY KEY,
+	left_digest          TEXT NOT NULL,
+	right_digest         TEXT NOT NULL,
+	num_diff_pixels      INTEGER NOT NULL,
+	percent_diff_pixels  REAL NOT NULL,
+	max_r_diff           INTEGER NOT NULL,
+	max_g_diff           INTEGER NOT NULL,
+	max_b_diff           INTEGER NOT NULL,
+	max_a_diff           INTEGER NOT NULL,
+	dimensions_differ    BOOLEAN NOT NULL
+)`,
+	`CREATE INDEX IF NOT EXISTS DiffMetricsByLeft ON DiffMetrics (left_digest)`,
+	`CREATE INDEX IF NOT EXISTS DiffMetricsByRight ON DiffMetrics (right_digest)`,
+}
+
+// StoreImpl is the SQL-backed implementation of MetricsStore.
+type StoreImpl struct {
+	db *sql.DB
+}
+
+// New returns a new instance of the SQL-backed metricsstore.MetricsStore implementation,
+// creating the DiffMetrics table if it doesn't already exist.
+func New(db *sql.DB) (*StoreImpl, error) {
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, skerr.Wrapf(err, "creating DiffMetrics table")
+		}
+	}
+	return &StoreImpl{db: db}, nil
+}
+
+// PurgeDiffMetrics implements the metricsstore.MetricsStore interface.
+func (s *StoreImpl) PurgeDiffMetrics(ctx context.Context, digests types.DigestSlice) error {
+	defer metrics2.FuncTimer().Stop()
+	return util.ChunkIter(len(digests), batchSize, func(start, stop int) error {
+		args := make([]interface{}, 0, stop-start)
+		for _, d := range digests[start:stop] {
+			args = append(args, d)
+		}
+		in := sql_utils.InPlaceholders(1, len(args))
+		q := `DELETE FROM DiffMetrics WHERE left_digest IN (` + in + `) OR right_digest IN (` + in + `)`
+		if _, err := s.db.ExecContext(ctx, q, args...); err != nil {
+			return skerr.Wrapf(err, "purging diff metrics for %d digests", len(args))
+		}
+		return nil
+	})
+}
+
+// SaveDiffMetrics implements the metricsstore.MetricsStore interface. It assumes the given
+// diff.DiffMetrics instance was generated with ComputeDiffMetrics(), which computes the Diffs
+// field from the other fields in the struct, and therefore is not necessary to store.
+func (s *StoreImpl) SaveDiffMetrics(ctx context.Context, id string, dm *diff.DiffMetrics) error {
+	defer metrics2.FuncTimer().Stop()
+	left, right := splitDiffID(id)
+	_, err := s.db.ExecContext(ctx, `INSERT INTO DiffMetrics (id, left_digest, right_digest, num_diff_pixels, percent_diff_pixels,
+max_r_diff, max_g_diff, max_b_diff, max_a_diff, dimensions_differ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (id) DO UPDATE SET num_diff_pixels=excluded.num_diff_pixels, percent_diff_pixels=excluded.percent_diff_pixels,
+max_r_diff=excluded.max_r_diff, max_g_diff=excluded.max_g_diff, max_b_diff=excluded.max_b_diff,
+max_a_diff=excluded.max_a_diff, dimensions_differ=excluded.dimensions_differ`,
+		id, left, right, dm.NumDiffPixels, dm.PixelDiffPercent,
+		dm.MaxRGBADiffs[0], dm.MaxRGBADiffs[1], dm.MaxRGBADiffs[2], dm.MaxRGBADiffs[3], dm.DimDiffer)
+	if err != nil {
+		return skerr.Wrapf(err, "writing diff metrics (ID=%q): %v", id, dm)
+	}
+	return nil
+}
+
+// LoadDiffMetrics implements the metricsstore.MetricsStore interface.
+func (s *StoreImpl) LoadDiffMetrics(ctx context.Context, ids []string) ([]*diff.DiffMetrics, error) {
+	defer metrics2.FuncTimer().Stop()
+	found := make(map[string]*diff.DiffMetrics, len(ids))
+	err := util.ChunkIter(len(ids), batchSize, func(start, stop int) error {
+		args := make([]interface{}, 0, stop-start)
+		for _, id := range ids[start:stop] {
+			args = append(args, id)
+		}
+		rows, err := s.db.QueryContext(ctx, `SELECT id, num_diff_pixels, percent_diff_pixels, max_r_diff, max_g_diff,
+max_b_diff, max_a_diff, dimensions_differ FROM DiffMetrics WHERE id IN (`+sql_utils.InPlaceholders(1, len(args))+`)`, args...)
+		if err != nil {
+			return skerr.Wrapf(err, "querying %d diff metrics", len(args))
+		}
+		defer util.Close(rows)
+		for rows.Next() {
+			var id string
+			dm := &diff.DiffMetrics{}
+			if err := rows.Scan(&id, &dm.NumDiffPixels, &dm.PixelDiffPercent, &dm.MaxRGBADiffs[0], &dm.MaxRGBADiffs[1],
+				&dm.MaxRGBADiffs[2], &dm.MaxRGBADiffs[3], &dm.DimDiffer); err != nil {
+				return skerr.Wrapf(err, "reading diff metrics")
+			}
+			found[id] = withDiffs(dm)
+		}
+		return skerr.Wrap(rows.Err())
+	})
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+
+	rv := make([]*diff.DiffMetrics, len(ids))
+	for i, id := range ids {
+		rv[i] = found[id]
+	}
+	return rv, nil
+}
+
+// withDiffs sets the Diffs map of the given diff.DiffMetrics the same way as ComputeDiffMetrics
+// does and returns it.
+func withDiffs(dm *diff.DiffMetrics) *diff.DiffMetrics {
+	dm.Diffs = map[string]float32{
+		diff.PercentMetric: dm.PixelDiffPercent,
+		diff.PixelMetric:   float32(dm.NumDiffPixels),
+	}
+	dm.Diffs[diff.CombinedMetric] = diff.CombinedDiffMetric(dm, nil, nil)
+	return dm
+}
+
+// splitDiffID returns the left and right digests of the given diff id. Unlike
+// common.SplitDiffID, it tolerates malformed ids.
+func splitDiffID(id string) (types.Digest, types.Digest) {
+	parts := strings.SplitN(id, common.DiffImageSeparator, 2)
+	if len(parts) < 2 {
+		return types.Digest(id), ""
+	}
+	return types.Digest(parts[0]), types.Digest(parts[1])
+}
+
+// Make sure StoreImpl fulfills the MetricsStore interface
+var _ metricsstore.MetricsStore = (*StoreImpl)(nil)
@@ -0,0 +1,241 @@
+// Package expsync exports the expectations of the master branch, together with who triaged each
+// digest, and imports them into another ExpectationsStore. This allows triage decisions to be
+// moved to a new Gold instance or kept when tests are renamed.
+package expsync
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/golden/go/expstorage"
+	"go.skia.org/infra/golden/go/types"
+	"go.skia.org/infra/golden/go/types/expectations"
+)
+
+const (
+	// logPageSize is how many triage log entries are read at once when attributing the
+	// expectations.
+	logPageSize = 500
+)
+
+// Entry is the label of one test/digest pair and who gave it that label.
+type Entry struct {
+	Grouping types.TestName `json:"grouping"`
+	Digest   types.Digest   `json:"digest"`
+	Label    string         `json:"label"`
+	// TriagedBy is the user who set the label. It is empty if the triage log doesn't say.
+	TriagedBy string `json:"triaged_by,omitempty"`
+	// TriagedAt is when the label was set. It is the zero time if TriagedBy is empty.
+	TriagedAt time.Time `json:"triaged_at"`
+}
+
+// Dump contains all expectations of the master branch of an instance.
+type Dump struct {
+	Entries []Entry `json:"entries"`
+}
+
+// RemapRule renames tests when importing. Pattern is a regular expression that must match the
+// whole test name. Replacement can refer to groups in the pattern, e.g. "$1".
+type RemapRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// ImportRequest is the body of a request to import expectations.
+type ImportRequest struct {
+	Dump Dump `json:"dump"`
+	// Remap is applied in order. The first rule that matches a test name renames it.
+	Remap []RemapRule `json:"remap"`
+	// DryRun means the changes are computed but not applied.
+	DryRun bool `json:"dry_run"`
+}
+
+// ImportDelta is one change that an import makes (or would make) to the expectations.
+type ImportDelta struct {
+	Grouping types.TestName `json:"grouping"`
+	Digest   types.Digest   `json:"digest"`
+	Label    string         `json:"label"`
+	// User is who the change is attributed to in the triage log, i.e. the importing user.
+	User string `json:"user"`
+	// TriagedBy is who the export says triaged the digest, if known. It is not verified, so it is
+	// only informational and never used as the author of a change.
+	TriagedBy string `json:"triaged_by,omitempty"`
+}
+
+// ImportResponse is returned after importing expectations.
+type ImportResponse struct {
+	// Deltas contains the changes, i.e. the imported test/digest pairs which didn't already have
+	// the same label.
+	Deltas []ImportDelta `json:"deltas"`
+	// Applied is true if the Deltas were written to the ExpectationsStore.
+	Applied bool `json:"applied"`
+}
+
+// Export returns all expectations in the given store. Each entry is attributed to the most
+// recent triage log entry that set its label.
+func Export(ctx context.Context, store expstorage.ExpectationsStore) (Dump, error) {
+	exp, err := store.Get(ctx)
+	if err != nil {
+		return Dump{}, skerr.Wrapf(err, "getting expectations")
+	}
+	type key struct {
+		grouping types.TestName
+		digest   types.Digest
+	}
+	entries := make([]Entry, 0, exp.Len())
+	idx := make(map[key]int, exp.Len())
+	_ = exp.ForAll(func(tn types.TestName, d types.Digest, l expectations.Label) error {
+		idx[key{grouping: tn, digest: d}] = len(entries)
+		entries = append(entries, Entry{Grouping: tn, Digest: d, Label: l.String()})
+		return nil
+	})
+
+	// The log is ordered from newest to oldest, so the first entry that mentions a test/digest
+	// pair with its current label is the one that set it.
+	remaining := len(entries)
+	for offset := 0; remaining > 0; offset += logPageSize {
+		logEntries, _, err := store.QueryLog(ctx, offset, logPageSize, true)
+		if err != nil {
+			return Dump{}, skerr.Wrapf(err, "reading triage log at offset %d", offset)
+		}
+		for _, le := range logEntries {
+			for _, d := range le.Details {
+				i, ok := idx[key{grouping: d.Grouping, digest: d.Digest}]
+				if !ok || entries[i].TriagedBy != "" || entries[i].Label != d.Label.String() {
+					continue
+				}
+				entries[i].TriagedBy = le.User
+				entries[i].TriagedAt = le.TS
+				remaining--
+			}
+		}
+		if len(logEntries) < logPageSize {
+			break
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Grouping != entries[j].Grouping {
+			return entries[i].Grouping < entries[j].Grouping
+		}
+		return entries[i].Digest < entries[j].Digest
+	})
+	return Dump{Entries: entries}, nil
+}
+
+// Import computes the changes needed to add the expectations in the request to the given
+// store and, unless it is a dry run, applies them. All changes are attributed to the given user,
+// who is doing the import; the triagers named in the export can't be verified, so they are only
+// reported as TriagedBy.
+func Import(ctx context.Context, store expstorage.ExpectationsStore, req ImportRequest, user string) (ImportResponse, error) {
+	if user == "" {
+		return ImportResponse{}, skerr.Fmt("importing user must be set")
+	}
+	deltas, err := plan(ctx, store, req)
+	if err != nil {
+		return ImportResponse{}, skerr.Wrap(err)
+	}
+	changes := make([]expstorage.Delta, 0, len(deltas))
+	for i, d := range deltas {
+		deltas[i].User = user
+		changes = append(changes, expstorage.Delta{
+			Grouping: d.Grouping,
+			Digest:   d.Digest,
+			Label:    expectations.LabelFromString(d.Label),
+		})
+	}
+	rv := ImportResponse{Deltas: deltas}
+	if req.DryRun || len(deltas) == 0 {
+		return rv, nil
+	}
+
+	if err := store.AddChange(ctx, changes, user); err != nil {
+		return ImportResponse{}, skerr.Wrapf(err, "adding %d changes by %s", len(changes), user)
+	}
+	rv.Applied = true
+	return rv, nil
+}
+
+// plan returns the changes that importing would make, ordered by when they were triaged.
+func plan(ctx context.Context, store expstorage.ExpectationsStore, req ImportRequest) ([]ImportDelta, error) {
+	remap, err := compileRemap(req.Remap)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	exp, err := store.Get(ctx)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "getting expectations")
+	}
+
+	entries := make([]Entry, 0, len(req.Dump.Entries))
+	for _, e := range req.Dump.Entries {
+		if !expectations.ValidLabel(e.Label) {
+			return nil, skerr.Fmt("invalid label %q for %s/%s", e.Label, e.Grouping, e.Digest)
+		}
+		if !validDigest(e.Digest) {
+			return nil, skerr.Fmt("invalid digest %q for test %s", e.Digest, e.Grouping)
+		}
+		e.Grouping = remap(e.Grouping)
+		if e.Grouping == "" {
+			return nil, skerr.Fmt("empty test name for digest %s", e.Digest)
+		}
+		if exp.Classification(e.Grouping, e.Digest).String() == e.Label {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].TriagedAt.Before(entries[j].TriagedAt)
+	})
+
+	// If remapping merges two tests, the same test/digest pair may be in the list more than once.
+	// The most recent label wins.
+	type key struct {
+		grouping types.TestName
+		digest   types.Digest
+	}
+	idx := map[key]int{}
+	var rv []ImportDelta
+	for _, e := range entries {
+		d := ImportDelta{Grouping: e.Grouping, Digest: e.Digest, Label: e.Label, TriagedBy: e.TriagedBy}
+		k := key{grouping: e.Grouping, digest: e.Digest}
+		if i, ok := idx[k]; ok {
+			rv[i] = d
+			continue
+		}
+		idx[k] = len(rv)
+		rv = append(rv, d)
+	}
+	return rv, nil
+}
+
+// compileRemap returns a function that renames a test according to the given rules.
+func compileRemap(rules []RemapRule) (func(types.TestName) types.TestName, error) {
+	regs := make([]*regexp.Regexp, 0, len(rules))
+	for _, r := range rules {
+		re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", r.Pattern))
+		if err != nil {
+			return nil, skerr.Wrapf(err, "invalid remap pattern %q", r.Pattern)
+		}
+		regs = append(regs, re)
+	}
+	return func(tn types.TestName) types.TestName {
+		for i, re := range regs {
+			if re.MatchString(string(tn)) {
+				return types.TestName(re.ReplaceAllString(string(tn), rules[i].Replacement))
+			}
+		}
+		return tn
+	}, nil
+}
+
+// validDigest returns true if the given digest looks like an MD5 hash.
+func validDigest(d types.Digest) bool {
+	return validDigestRegex.MatchString(string(d))
+}
+
+var validDigestRegex = regexp.MustCompile("^[0-9a-f]{32}$")
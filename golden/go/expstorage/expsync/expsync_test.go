@@ -0,0 +1,189 @@
+package expsync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/golden/go/expstorage"
+	"go.skia.org/infra/golden/go/expstorage/sql_expstore"
+	"go.skia.org/infra/golden/go/sql_utils"
+	"go.skia.org/infra/golden/go/types"
+	"go.skia.org/infra/golden/go/types/expectations"
+)
+
+func TestExportImport(t *testing.T) {
+	unittest.MediumTest(t)
+	ctx := context.Background()
+
+	src, cleanupSrc := newStore(t)
+	defer cleanupSrc()
+	require.NoError(t, src.AddChange(ctx, []expstorage.Delta{
+		{Grouping: "old_one", Digest: alpha, Label: expectations.Positive},
+		{Grouping: "old_one", Digest: beta, Label: expectations.Negative},
+	}, "alice@example.com"))
+	// bob changes the label of beta, so he is the one it is attributed to.
+	require.NoError(t, src.AddChange(ctx, []expstorage.Delta{
+		{Grouping: "old_one", Digest: beta, Label: expectations.Positive},
+		{Grouping: "two", Digest: gamma, Label: expectations.Positive},
+	}, "bob@example.com"))
+
+	dump, err := Export(ctx, src)
+	require.NoError(t, err)
+	require.Len(t, dump.Entries, 3)
+	assert.Equal(t, Entry{Grouping: "old_one", Digest: alpha, Label: "positive", TriagedBy: "alice@example.com"},
+		withoutTime(dump.Entries[0]))
+	assert.Equal(t, Entry{Grouping: "old_one", Digest: beta, Label: "positive", TriagedBy: "bob@example.com"},
+		withoutTime(dump.Entries[1]))
+	assert.Equal(t, Entry{Grouping: "two", Digest: gamma, Label: "positive", TriagedBy: "bob@example.com"},
+		withoutTime(dump.Entries[2]))
+	assert.True(t, dump.Entries[0].TriagedAt.Before(dump.Entries[1].TriagedAt))
+
+	dst, cleanupDst := newStore(t)
+	defer cleanupDst()
+	// gamma is already positive, so it doesn't need to be imported.
+	require.NoError(t, dst.AddChange(ctx, []expstorage.Delta{
+		{Grouping: "two", Digest: gamma, Label: expectations.Positive},
+	}, "carol@example.com"))
+
+	req := ImportRequest{
+		Dump:   dump,
+		Remap:  []RemapRule{{Pattern: "old_(.*)", Replacement: "new_$1"}},
+		DryRun: true,
+	}
+	// The changes are attributed to the importer; the original triagers are only annotations.
+	expectedDeltas := []ImportDelta{
+		{Grouping: "new_one", Digest: alpha, Label: "positive", User: "importer@example.com", TriagedBy: "alice@example.com"},
+		{Grouping: "new_one", Digest: beta, Label: "positive", User: "importer@example.com", TriagedBy: "bob@example.com"},
+	}
+	resp, err := Import(ctx, dst, req, "importer@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, ImportResponse{Deltas: expectedDeltas}, resp)
+	// Nothing was written in dry-run mode.
+	exp, err := dst.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, exp.Len())
+
+	req.DryRun = false
+	resp, err = Import(ctx, dst, req, "importer@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, ImportResponse{Deltas: expectedDeltas, Applied: true}, resp)
+
+	exp, err = dst.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, exp.Len())
+	assert.Equal(t, expectations.Positive, exp.Classification("new_one", alpha))
+	assert.Equal(t, expectations.Positive, exp.Classification("new_one", beta))
+	assert.Equal(t, expectations.Untriaged, exp.Classification("old_one", alpha))
+
+	// The import is one entry in the log, by the importer.
+	log, _, err := dst.QueryLog(ctx, 0, 10, true)
+	require.NoError(t, err)
+	require.Len(t, log, 2)
+	assert.Equal(t, "importer@example.com", log[0].User)
+	assert.Equal(t, 2, log[0].ChangeCount)
+	assert.Equal(t, "carol@example.com", log[1].User)
+
+	// Importing again doesn't change anything.
+	resp, err = Import(ctx, dst, req, "importer@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, ImportResponse{}, resp)
+}
+
+func TestImportUnattributed(t *testing.T) {
+	unittest.MediumTest(t)
+	ctx := context.Background()
+
+	dst, cleanupDst := newStore(t)
+	defer cleanupDst()
+	resp, err := Import(ctx, dst, ImportRequest{
+		Dump: Dump{Entries: []Entry{
+			{Grouping: "one", Digest: alpha, Label: "negative"},
+		}},
+	}, "importer@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, ImportResponse{
+		Deltas: []ImportDelta{
+			{Grouping: "one", Digest: alpha, Label: "negative", User: "importer@example.com"},
+		},
+		Applied: true,
+	}, resp)
+	exp, err := dst.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, expectations.Negative, exp.Classification("one", alpha))
+}
+
+func TestImportInvalid(t *testing.T) {
+	unittest.MediumTest(t)
+	ctx := context.Background()
+
+	dst, cleanupDst := newStore(t)
+	defer cleanupDst()
+	test := func(name string, req ImportRequest) {
+		t.Run(name, func(t *testing.T) {
+			_, err := Import(ctx, dst, req, "importer@example.com")
+			require.Error(t, err)
+		})
+	}
+	test("bad label", ImportRequest{Dump: Dump{Entries: []Entry{
+		{Grouping: "one", Digest: alpha, Label: "maybe"},
+	}}})
+	test("bad digest", ImportRequest{Dump: Dump{Entries: []Entry{
+		{Grouping: "one", Digest: "not a digest", Label: "positive"},
+	}}})
+	test("bad pattern", ImportRequest{
+		Dump:  Dump{Entries: []Entry{{Grouping: "one", Digest: alpha, Label: "positive"}}},
+		Remap: []RemapRule{{Pattern: "(", Replacement: "x"}},
+	})
+	test("empty name after remap", ImportRequest{
+		Dump:  Dump{Entries: []Entry{{Grouping: "one", Digest: alpha, Label: "positive"}}},
+		Remap: []RemapRule{{Pattern: "one", Replacement: ""}},
+	})
+
+	_, err := Import(ctx, dst, ImportRequest{Dump: Dump{Entries: []Entry{
+		{Grouping: "one", Digest: alpha, Label: "positive", TriagedBy: "alice@example.com"},
+	}}}, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "importing user must be set")
+
+	exp, err := dst.Get(ctx)
+	require.NoError(t, err)
+	assert.True(t, exp.Empty())
+}
+
+func TestCompileRemap(t *testing.T) {
+	unittest.SmallTest(t)
+
+	remap, err := compileRemap([]RemapRule{
+		{Pattern: "exact", Replacement: "renamed"},
+		{Pattern: "gm_(.*)", Replacement: "$1"},
+		{Pattern: "gm_.*", Replacement: "never_used"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", string(remap("exact")))
+	// The pattern must match the whole name.
+	assert.Equal(t, "not_exact", string(remap("not_exact")))
+	assert.Equal(t, "circles", string(remap("gm_circles")))
+	assert.Equal(t, "other", string(remap("other")))
+}
+
+func newStore(t *testing.T) (expstorage.ExpectationsStore, func()) {
+	db, cleanup := sql_utils.NewSQLiteForTesting(t)
+	s, err := sql_expstore.New(db, nil, sql_expstore.ReadWrite)
+	require.NoError(t, err)
+	return s, cleanup
+}
+
+// withoutTime returns the entry without TriagedAt, which depends on when the test ran.
+func withoutTime(e Entry) Entry {
+	return Entry{Grouping: e.Grouping, Digest: e.Digest, Label: e.Label, TriagedBy: e.TriagedBy}
+}
+
+const (
+	alpha = types.Digest("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	beta  = types.Digest("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	gamma = types.Digest("cccccccccccccccccccccccccccccccc")
+)
@@ -0,0 +1,346 @@
+// Package sql_expstore hosts an SQL-based (SQLite or Postgres) implementation of
+// expstorage.ExpectationsStore.
+package sql_expstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go.skia.org/infra/go/eventbus"
+	"go.skia.org/infra/go/metrics2"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/golden/go/expstorage"
+	"go.skia.org/infra/golden/go/sql_utils"
+	"go.skia.org/infra/golden/go/types"
+	"go.skia.org/infra/golden/go/types/expectations"
+)
+
+// AccessMode indicates if this ExpectationsStore can update existing Expectations
+// in the backing store or if if can only read them.
+type AccessMode int
+
+const (
+	ReadOnly AccessMode = iota
+	ReadWrite
+)
+
+var (
+	ReadOnlyErr = errors.New("expectationStore is in read-only mode")
+)
+
+const masterBranch = ""
+
+// schema creates the tables used by the StoreImpl. crs_cl_id is crs+"_"+id for ChangeList
+// expectations and masterBranch for the master branch. Timestamps are sql_utils timestamps.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS Expectations (
+	crs_cl_id  TEXT NOT NULL,
+	test_name  TEXT NOT NULL,
+	digest     TEXT NOT NULL,
+	label      INTEGER NOT NULL,
+	updated    BIGINT NOT NULL,
+	PRIMARY KEY (crs_cl_id, test_name, digest)
+)`,
+	`CREATE TABLE IF NOT EXISTS TriageRecords (
+	id         TEXT PRIMARY KEY,
+	user_name  TEXT NOT NULL,
+	ts         BIGINT NOT NULL,
+	crs_cl_id  TEXT NOT NULL,
+	changes    INTEGER NOT NULL
+)`,
+	`CREATE INDEX IF NOT EXISTS TriageRecordsByBranch ON TriageRecords (crs_cl_id, ts)`,
+	`CREATE TABLE IF NOT EXISTS TriageChanges (
+	record_id     TEXT NOT NULL,
+	test_name     TEXT NOT NULL,
+	digest        TEXT NOT NULL,
+	label_before  INTEGER NOT NULL,
+	label_after   INTEGER NOT NULL,
+	PRIMARY KEY (record_id, test_name, digest)
+)`,
+}
+
+// StoreImpl implements expstorage.ExpectationsStore backed by an SQL database. Unlike the
+// Firestore implementation, there is no cache; every read goes to the database, so multiple
+// instances (e.g. a ReadWrite and several ReadOnly ones) always agree with each other.
+type StoreImpl struct {
+	db         *sql.DB
+	mode       AccessMode
+	crsAndCLID string // crs+"_"+id. Empty string means master branch.
+
+	// eventBus allows this StoreImpl to communicate with the outside world when
+	// expectations change. It is only set for the master branch.
+	eventBus eventbus.EventBus
+}
+
+// New returns a new StoreImpl using the given database, creating the tables if they don't already
+// exist. The StoreImpl will track masterBranch- see ForChangeList() for getting Stores that track
+// ChangeLists. If eventBus is not nil, an expstorage.ExpectationsChangedTopic event is published
+// (globally) for every expectation changed on the master branch.
+func New(db *sql.DB, eventBus eventbus.EventBus, mode AccessMode) (*StoreImpl, error) {
+	for _, s := range schema {
+		if _, err := db.Exec(s); err != nil {
+			return nil, skerr.Wrapf(err, "creating expectations tables")
+		}
+	}
+	return &StoreImpl{
+		db:         db,
+		mode:       mode,
+		crsAndCLID: masterBranch,
+		eventBus:   eventBus,
+	}, nil
+}
+
+// ForChangeList implements the ExpectationsStore interface.
+func (s *StoreImpl) ForChangeList(id, crs string) expstorage.ExpectationsStore {
+	if id == masterBranch {
+		// It is invalid to re-request the master branch
+		return nil
+	}
+	return &StoreImpl{
+		db:         s.db,
+		mode:       s.mode,
+		crsAndCLID: crs + "_" + id,
+	}
+}
+
+// Get implements the ExpectationsStore interface.
+func (s *StoreImpl) Get(ctx context.Context) (expectations.ReadOnly, error) {
+	return s.GetCopy(ctx)
+}
+
+// GetCopy implements the ExpectationsStore interface.
+func (s *StoreImpl) GetCopy(ctx context.Context) (*expectations.Expectations, error) {
+	isMaster := "false"
+	if s.crsAndCLID == masterBranch {
+		isMaster = "true"
+	}
+	defer metrics2.NewTimer("gold_get_expectations", map[string]string{"master_branch": isMaster}).Stop()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT test_name, digest, label FROM Expectations WHERE crs_cl_id=$1`, s.crsAndCLID)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "querying expectations for %q", s.crsAndCLID)
+	}
+	defer util.Close(rows)
+	e := &expectations.Expectations{}
+	for rows.Next() {
+		var grouping types.TestName
+		var digest types.Digest
+		var label expectations.Label
+		if err := rows.Scan(&grouping, &digest, &label); err != nil {
+			return nil, skerr.Wrapf(err, "reading expectation")
+		}
+		e.Set(grouping, digest, label)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, skerr.Wrapf(err, "reading expectations for %q", s.crsAndCLID)
+	}
+	return e, nil
+}
+
+// AddChange implements the ExpectationsStore interface. All the changes and the triage record are
+// written in a single transaction.
+func (s *StoreImpl) AddChange(ctx context.Context, delta []expstorage.Delta, userID string) error {
+	defer metrics2.FuncTimer().Stop()
+	if s.mode == ReadOnly {
+		return ReadOnlyErr
+	}
+	// Nothing to add
+	if len(delta) == 0 {
+		return nil
+	}
+	now := time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return skerr.Wrapf(err, "starting transaction")
+	}
+	if err := s.addChangeInTx(ctx, tx, now, delta, userID); err != nil {
+		_ = tx.Rollback()
+		return skerr.Wrap(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return skerr.Wrapf(err, "committing %d expectations", len(delta))
+	}
+
+	if s.eventBus != nil {
+		for _, d := range delta {
+			s.eventBus.Publish(expstorage.ExpectationsChangedTopic, &expstorage.EventExpectationChange{
+				ExpectationDelta: d,
+				CRSAndCLID:       s.crsAndCLID,
+			}, true)
+		}
+	}
+	return nil
+}
+
+// addChangeInTx writes the triage record, the triage changes (using the previous labels) and the
+// new expectations using the given transaction.
+func (s *StoreImpl) addChangeInTx(ctx context.Context, tx *sql.Tx, now time.Time, delta []expstorage.Delta, userID string) error {
+	recordID := uuid.New().String()
+	ts := sql_utils.ToTimestamp(now)
+	_, err := tx.ExecContext(ctx, `INSERT INTO TriageRecords (id, user_name, ts, crs_cl_id, changes) VALUES ($1, $2, $3, $4, $5)`,
+		recordID, userID, ts, s.crsAndCLID, len(delta))
+	if err != nil {
+		return skerr.Wrapf(err, "storing triage record")
+	}
+
+	getLabel, err := tx.PrepareContext(ctx, `SELECT label FROM Expectations WHERE crs_cl_id=$1 AND test_name=$2 AND digest=$3`)
+	if err != nil {
+		return skerr.Wrapf(err, "preparing label query")
+	}
+	defer util.Close(getLabel)
+	putChange, err := tx.PrepareContext(ctx, `INSERT INTO TriageChanges (record_id, test_name, digest, label_before, label_after) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (record_id, test_name, digest) DO UPDATE SET label_after=excluded.label_after`)
+	if err != nil {
+		return skerr.Wrapf(err, "preparing triage change insert")
+	}
+	defer util.Close(putChange)
+	putExp, err := tx.PrepareContext(ctx, `INSERT INTO Expectations (crs_cl_id, test_name, digest, label, updated) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (crs_cl_id, test_name, digest) DO UPDATE SET label=excluded.label, updated=excluded.updated`)
+	if err != nil {
+		return skerr.Wrapf(err, "preparing expectation insert")
+	}
+	defer util.Close(putExp)
+
+	for _, d := range delta {
+		before := expectations.Untriaged
+		err := getLabel.QueryRowContext(ctx, s.crsAndCLID, d.Grouping, d.Digest).Scan(&before)
+		if err != nil && err != sql.ErrNoRows {
+			return skerr.Wrapf(err, "getting previous label for %s %s", d.Grouping, d.Digest)
+		}
+		if _, err := putChange.ExecContext(ctx, recordID, d.Grouping, d.Digest, before, d.Label); err != nil {
+			return skerr.Wrapf(err, "storing triage change for %s %s", d.Grouping, d.Digest)
+		}
+		if _, err := putExp.ExecContext(ctx, s.crsAndCLID, d.Grouping, d.Digest, d.Label, ts); err != nil {
+			return skerr.Wrapf(err, "storing expectation for %s %s", d.Grouping, d.Digest)
+		}
+	}
+	return nil
+}
+
+// QueryLog implements the ExpectationsStore interface.
+func (s *StoreImpl) QueryLog(ctx context.Context, offset, size int, details bool) ([]expstorage.TriageLogEntry, int, error) {
+	if offset < 0 || size <= 0 {
+		return nil, -1, skerr.Fmt("offset: %d and size: %d must be positive", offset, size)
+	}
+	defer metrics2.FuncTimer().Stop()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, user_name, ts, changes FROM TriageRecords WHERE crs_cl_id=$1
+ORDER BY ts DESC LIMIT $2 OFFSET $3`, s.crsAndCLID, size, offset)
+	if err != nil {
+		return nil, -1, skerr.Wrapf(err, "could not request triage records [%d: %d]", offset, size)
+	}
+	var rv []expstorage.TriageLogEntry
+	err = func() error {
+		defer util.Close(rows)
+		for rows.Next() {
+			var e expstorage.TriageLogEntry
+			var ts int64
+			if err := rows.Scan(&e.ID, &e.User, &ts, &e.ChangeCount); err != nil {
+				return skerr.Wrapf(err, "reading triage record")
+			}
+			e.TS = sql_utils.FromTimestamp(ts)
+			rv = append(rv, e)
+		}
+		return skerr.Wrap(rows.Err())
+	}()
+	if err != nil {
+		return nil, -1, skerr.Wrapf(err, "could not request triage records [%d: %d]", offset, size)
+	}
+
+	n := len(rv)
+	if n == size && n != 0 {
+		// We don't know how many there are and it might be too slow to count, so just give
+		// the "many" response.
+		n = expstorage.CountMany
+	} else {
+		// We know exactly either 1) how many there are (if n > 0) or 2) an upper bound on how many
+		// there are (if n == 0)
+		n += offset
+	}
+
+	if !details {
+		return rv, n, nil
+	}
+	for i := range rv {
+		changes, err := s.getChanges(ctx, rv[i].ID)
+		if err != nil {
+			return nil, -1, skerr.Wrapf(err, "could not query details")
+		}
+		for _, c := range changes {
+			rv[i].Details = append(rv[i].Details, expstorage.Delta{
+				Grouping: c.Grouping,
+				Digest:   c.Digest,
+				Label:    c.labelAfter,
+			})
+		}
+	}
+	return rv, n, nil
+}
+
+// triageChange is one row of the TriageChanges table.
+type triageChange struct {
+	expstorage.Delta
+	labelBefore expectations.Label
+	labelAfter  expectations.Label
+}
+
+// getChanges returns the changes belonging to the given triage record, sorted by grouping, then
+// digest for determinism.
+func (s *StoreImpl) getChanges(ctx context.Context, recordID string) ([]triageChange, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT test_name, digest, label_before, label_after FROM TriageChanges
+WHERE record_id=$1 ORDER BY test_name, digest`, recordID)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "querying changes for record %s", recordID)
+	}
+	defer util.Close(rows)
+	var rv []triageChange
+	for rows.Next() {
+		var c triageChange
+		if err := rows.Scan(&c.Grouping, &c.Digest, &c.labelBefore, &c.labelAfter); err != nil {
+			return nil, skerr.Wrapf(err, "reading change for record %s", recordID)
+		}
+		rv = append(rv, c)
+	}
+	return rv, skerr.Wrap(rows.Err())
+}
+
+// UndoChange implements the ExpectationsStore interface.
+func (s *StoreImpl) UndoChange(ctx context.Context, changeID, userID string) error {
+	defer metrics2.FuncTimer().Stop()
+	if s.mode == ReadOnly {
+		return ReadOnlyErr
+	}
+	// Verify the original change id exists.
+	var id string
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM TriageRecords WHERE id=$1`, changeID).Scan(&id)
+	if err != nil {
+		return skerr.Wrapf(err, "could not find change to undo with id %s", changeID)
+	}
+
+	changes, err := s.getChanges(ctx, changeID)
+	if err != nil {
+		return skerr.Wrapf(err, "could not get delta to undo %s", changeID)
+	}
+	delta := make([]expstorage.Delta, 0, len(changes))
+	for _, c := range changes {
+		delta = append(delta, expstorage.Delta{
+			Grouping: c.Grouping,
+			Digest:   c.Digest,
+			Label:    c.labelBefore,
+		})
+	}
+
+	if err = s.AddChange(ctx, delta, userID); err != nil {
+		return skerr.Wrapf(err, "could not apply delta to undo %s", changeID)
+	}
+	return nil
+}
+
+// Make sure StoreImpl fulfills the ExpectationsStore interface.
+var _ expstorage.ExpectationsStore = (*StoreImpl)(nil)
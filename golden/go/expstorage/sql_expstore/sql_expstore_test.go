@@ -0,0 +1,41 @@
+package sql_expstore
+
+import (
+	"database/sql"
+	"testing"
+
+	"go.skia.org/infra/go/eventbus"
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/golden/go/expstorage"
+	"go.skia.org/infra/golden/go/expstorage/shared_tests"
+	"go.skia.org/infra/golden/go/sql_utils"
+)
+
+func TestSharedTests_SQLite(t *testing.T) {
+	unittest.MediumTest(t)
+	runSharedTests(t, sql_utils.NewSQLiteForTesting)
+}
+
+func TestSharedTests_Postgres(t *testing.T) {
+	unittest.LargeTest(t) // should use a Postgres database
+	runSharedTests(t, sql_utils.NewPostgresForTesting)
+}
+
+// runSharedTests runs each of the shared tests with ExpectationsStores in a new database.
+func runSharedTests(t *testing.T, newDB func(sktest.TestingT) (*sql.DB, func())) {
+	for name, test := range shared_tests.Tests {
+		t.Run(name, func(t *testing.T) {
+			db, cleanup := newDB(t)
+			defer cleanup()
+
+			test(t, func(eventBus eventbus.EventBus, readOnly bool) (expstorage.ExpectationsStore, error) {
+				mode := ReadWrite
+				if readOnly {
+					mode = ReadOnly
+				}
+				return New(db, eventBus, mode)
+			})
+		})
+	}
+}
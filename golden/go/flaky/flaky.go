@@ -0,0 +1,197 @@
+// Package flaky finds traces that keep switching between a few digests without any related code
+// change, and keeps track of the traces users have marked as flaky. The digests that are only
+// drawn by flaky traces are grouped together instead of being triaged one by one.
+package flaky
+
+import (
+	"context"
+	"time"
+
+	"go.skia.org/infra/go/tiling"
+	"go.skia.org/infra/golden/go/types"
+)
+
+const (
+	// GroupID is the blame group id that collects the untriaged digests that were only drawn by
+	// flaky traces. It can be used in place of a regular blame group id when searching.
+	GroupID = "flaky"
+
+	// MinScore is the minimum Score of a trace for it to be detected as flaky.
+	MinScore = 2
+)
+
+// Store is an interface for a database that saves which traces were marked as flaky by users.
+type Store interface {
+	// Add marks a trace as flaky. If the trace was already marked, the mark is replaced.
+	Add(ctx context.Context, m Mark) error
+
+	// List returns all marks in the store.
+	List(ctx context.Context) ([]Mark, error)
+
+	// Delete removes the mark from a trace. If the trace wasn't marked, there will be no error.
+	Delete(ctx context.Context, id tiling.TraceID) error
+}
+
+// Mark records that a user has marked a trace as flaky.
+type Mark struct {
+	// TraceID is the id of the marked trace.
+	TraceID tiling.TraceID
+	// MarkedBy is the email of the user who marked the trace.
+	MarkedBy string
+	// TS is when the trace was marked.
+	TS time.Time
+	// Note is a comment by the user, typically a bug.
+	Note string
+}
+
+// TraceStats summarizes how much the digests of a trace change over a tile.
+type TraceStats struct {
+	// DistinctDigests is the number of different digests in the trace, ignoring missing data.
+	DistinctDigests int `json:"distinct_digests"`
+	// Transitions is the number of times the digest changes between two consecutive commits
+	// with data.
+	Transitions int `json:"transitions"`
+}
+
+// Score returns the flakiness score of the trace. A trace that changes its digest only because
+// of code changes, and never goes back to a previous digest, needs one transition for each new
+// digest and has a score of 0. Every further transition is one more than the code changes
+// explain, so it counts towards the score.
+func (t TraceStats) Score() int {
+	if t.DistinctDigests == 0 {
+		return 0
+	}
+	return t.Transitions - (t.DistinctDigests - 1)
+}
+
+// IsFlaky returns true if the trace switches back and forth often enough to be considered
+// flaky. Going back to an old digest once, e.g. because a commit was reverted, is not enough.
+func (t TraceStats) IsFlaky() bool {
+	return t.Score() >= MinScore
+}
+
+// ComputeStats returns the TraceStats of the given trace.
+func ComputeStats(tr *types.GoldenTrace) TraceStats {
+	seen := types.DigestSet{}
+	rv := TraceStats{}
+	last := types.MISSING_DIGEST
+	for _, d := range tr.Digests {
+		if d == types.MISSING_DIGEST {
+			continue
+		}
+		if last != types.MISSING_DIGEST && d != last {
+			rv.Transitions++
+		}
+		seen[d] = true
+		last = d
+	}
+	rv.DistinctDigests = len(seen)
+	return rv
+}
+
+// TraceStatus describes a trace that is considered flaky.
+type TraceStatus struct {
+	TraceStats
+	// Detected is true if the trace was found to be flaky by looking at its digests.
+	Detected bool
+	// Mark is set if a user marked the trace as flaky.
+	Mark *Mark
+}
+
+// Index contains the flaky traces of a tile and the digests that are only drawn by them. It
+// should be considered immutable. A nil *Index contains no flaky traces.
+type Index struct {
+	traces map[tiling.TraceID]TraceStatus
+	// onlyFlaky contains, by test, the digests that no trace except the flaky ones has drawn.
+	onlyFlaky map[types.TestName]types.DigestSet
+}
+
+// NewIndex finds the flaky traces in the given tile, both those whose digests show flakiness and
+// those that were marked by a user. Marks for traces that are not in the tile are ignored.
+func NewIndex(cpxTile types.ComplexTile, marks []Mark) *Index {
+	idx := &Index{
+		traces:    map[tiling.TraceID]TraceStatus{},
+		onlyFlaky: map[types.TestName]types.DigestSet{},
+	}
+	all := cpxTile.GetTile(types.IncludeIgnoredTraces)
+	for id, tr := range all.Traces {
+		gt, ok := tr.(*types.GoldenTrace)
+		if !ok {
+			continue
+		}
+		stats := ComputeStats(gt)
+		if stats.IsFlaky() {
+			idx.traces[id] = TraceStatus{TraceStats: stats, Detected: true}
+		}
+	}
+	for i := range marks {
+		m := marks[i]
+		tr, ok := all.Traces[m.TraceID].(*types.GoldenTrace)
+		if !ok {
+			continue
+		}
+		ts, ok := idx.traces[m.TraceID]
+		if !ok {
+			ts = TraceStatus{TraceStats: ComputeStats(tr)}
+		}
+		ts.Mark = &m
+		idx.traces[m.TraceID] = ts
+	}
+	if len(idx.traces) == 0 {
+		return idx
+	}
+
+	// Only the traces that are not ignored can draw untriaged digests that need attention.
+	drawnByStable := map[types.TestName]types.DigestSet{}
+	for id, tr := range cpxTile.GetTile(types.ExcludeIgnoredTraces).Traces {
+		gt, ok := tr.(*types.GoldenTrace)
+		if !ok {
+			continue
+		}
+		dest := drawnByStable
+		if _, ok := idx.traces[id]; ok {
+			dest = idx.onlyFlaky
+		}
+		test := gt.TestName()
+		if _, ok := dest[test]; !ok {
+			dest[test] = types.DigestSet{}
+		}
+		for _, d := range gt.Digests {
+			if d != types.MISSING_DIGEST {
+				dest[test][d] = true
+			}
+		}
+	}
+	for test, digests := range idx.onlyFlaky {
+		for d := range drawnByStable[test] {
+			delete(digests, d)
+		}
+	}
+	return idx
+}
+
+// Traces returns all flaky traces, keyed by trace id.
+func (idx *Index) Traces() map[tiling.TraceID]TraceStatus {
+	if idx == nil {
+		return nil
+	}
+	return idx.traces
+}
+
+// IsFlaky returns true if the trace with the given id is flaky.
+func (idx *Index) IsFlaky(id tiling.TraceID) bool {
+	if idx == nil {
+		return false
+	}
+	_, ok := idx.traces[id]
+	return ok
+}
+
+// OnlyInFlakyTraces returns true if the given digest of the given test was drawn by flaky traces
+// only. Ignored traces are not taken into account.
+func (idx *Index) OnlyInFlakyTraces(test types.TestName, digest types.Digest) bool {
+	if idx == nil {
+		return false
+	}
+	return idx.onlyFlaky[test][digest]
+}
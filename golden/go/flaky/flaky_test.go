@@ -0,0 +1,136 @@
+package flaky
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/go/tiling"
+	"go.skia.org/infra/golden/go/types"
+)
+
+func TestComputeStats(t *testing.T) {
+	unittest.SmallTest(t)
+
+	test := func(name string, digests []types.Digest, expected TraceStats, score int, flaky bool) {
+		t.Run(name, func(t *testing.T) {
+			stats := ComputeStats(types.NewGoldenTrace(digests, nil))
+			assert.Equal(t, expected, stats)
+			assert.Equal(t, score, stats.Score())
+			assert.Equal(t, flaky, stats.IsFlaky())
+		})
+	}
+
+	test("empty", []types.Digest{"", "", ""}, TraceStats{}, 0, false)
+	test("stable", []types.Digest{alpha, alpha, "", alpha}, TraceStats{DistinctDigests: 1}, 0, false)
+	test("code changes", []types.Digest{alpha, beta, beta, "", gamma},
+		TraceStats{DistinctDigests: 3, Transitions: 2}, 0, false)
+	test("reverted once", []types.Digest{alpha, beta, alpha, alpha},
+		TraceStats{DistinctDigests: 2, Transitions: 2}, 1, false)
+	test("missing data is skipped", []types.Digest{alpha, "", alpha, "", beta, "", alpha, "", beta},
+		TraceStats{DistinctDigests: 2, Transitions: 3}, 2, true)
+	test("three digests", []types.Digest{alpha, beta, gamma, alpha, gamma, beta},
+		TraceStats{DistinctDigests: 3, Transitions: 5}, 3, true)
+}
+
+func TestNewIndex(t *testing.T) {
+	unittest.SmallTest(t)
+
+	cpxTile := makeComplexTile()
+	marks := []Mark{
+		{
+			TraceID:  stableTraceID,
+			MarkedBy: "user@example.com",
+			TS:       time.Date(2019, time.November, 1, 2, 3, 4, 0, time.UTC),
+			Note:     "skbug.com/1234",
+		},
+		{
+			// Marks for traces that are not in the tile don't matter.
+			TraceID:  ",name=not_in_tile,",
+			MarkedBy: "user@example.com",
+		},
+	}
+	idx := NewIndex(cpxTile, marks)
+
+	traces := idx.Traces()
+	require.Len(t, traces, 3)
+	assert.Equal(t, TraceStatus{
+		TraceStats: TraceStats{DistinctDigests: 2, Transitions: 3},
+		Detected:   true,
+	}, traces[flakyTraceID])
+	assert.Equal(t, TraceStatus{
+		TraceStats: TraceStats{DistinctDigests: 2, Transitions: 4},
+		Detected:   true,
+	}, traces[ignoredFlakyTraceID])
+	assert.Equal(t, TraceStatus{
+		TraceStats: TraceStats{DistinctDigests: 1},
+		Mark:       &marks[0],
+	}, traces[stableTraceID])
+
+	assert.True(t, idx.IsFlaky(flakyTraceID))
+	assert.True(t, idx.IsFlaky(stableTraceID))
+	assert.False(t, idx.IsFlaky(otherTraceID))
+
+	// beta is only drawn by flaky traces, but alpha is also drawn by otherTraceID.
+	assert.False(t, idx.OnlyInFlakyTraces(testOne, alpha))
+	assert.True(t, idx.OnlyInFlakyTraces(testOne, beta))
+	// gamma is drawn by stableTraceID, which was marked as flaky.
+	assert.True(t, idx.OnlyInFlakyTraces(testTwo, gamma))
+	// delta is only drawn by an ignored trace.
+	assert.False(t, idx.OnlyInFlakyTraces(testTwo, delta))
+}
+
+func TestNilIndex(t *testing.T) {
+	unittest.SmallTest(t)
+
+	var idx *Index
+	assert.Empty(t, idx.Traces())
+	assert.False(t, idx.IsFlaky(flakyTraceID))
+	assert.False(t, idx.OnlyInFlakyTraces(testOne, beta))
+}
+
+// makeComplexTile returns a tile with one flaky trace, one stable trace (which is marked as
+// flaky in TestNewIndex) and one trace that only changes once for testOne and testTwo. It also
+// contains a flaky trace that is ignored.
+func makeComplexTile() types.ComplexTile {
+	all := &tiling.Tile{
+		Traces: map[tiling.TraceID]tiling.Trace{
+			flakyTraceID: types.NewGoldenTrace([]types.Digest{alpha, beta, alpha, beta},
+				map[string]string{types.PRIMARY_KEY_FIELD: string(testOne), "os": "Android"}),
+			otherTraceID: types.NewGoldenTrace([]types.Digest{alpha, alpha, alpha, gamma},
+				map[string]string{types.PRIMARY_KEY_FIELD: string(testOne), "os": "Linux"}),
+			stableTraceID: types.NewGoldenTrace([]types.Digest{gamma, gamma, "", gamma},
+				map[string]string{types.PRIMARY_KEY_FIELD: string(testTwo), "os": "Android"}),
+			ignoredFlakyTraceID: types.NewGoldenTrace([]types.Digest{delta, alpha, delta, alpha, delta},
+				map[string]string{types.PRIMARY_KEY_FIELD: string(testTwo), "os": "Windows"}),
+		},
+	}
+	reduced := &tiling.Tile{
+		Traces: map[tiling.TraceID]tiling.Trace{
+			flakyTraceID:  all.Traces[flakyTraceID],
+			otherTraceID:  all.Traces[otherTraceID],
+			stableTraceID: all.Traces[stableTraceID],
+		},
+	}
+	cpxTile := types.NewComplexTile(all)
+	cpxTile.SetIgnoreRules(reduced, nil)
+	return cpxTile
+}
+
+const (
+	alpha = types.Digest("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	beta  = types.Digest("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	gamma = types.Digest("cccccccccccccccccccccccccccccccc")
+	delta = types.Digest("dddddddddddddddddddddddddddddddd")
+
+	testOne = types.TestName("test_one")
+	testTwo = types.TestName("test_two")
+
+	flakyTraceID        = tiling.TraceID(",name=test_one,os=Android,")
+	otherTraceID        = tiling.TraceID(",name=test_one,os=Linux,")
+	stableTraceID       = tiling.TraceID(",name=test_two,os=Android,")
+	ignoredFlakyTraceID = tiling.TraceID(",name=test_two,os=Windows,")
+)
@@ -0,0 +1,115 @@
+// Package fs_flakystore hosts a Firestore-based implementation of flaky.Store.
+package fs_flakystore
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+
+	ifirestore "go.skia.org/infra/go/firestore"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/tiling"
+	"go.skia.org/infra/golden/go/flaky"
+)
+
+const (
+	// These are the collections in Firestore.
+	marksCollection = "flakystore_marks"
+
+	maxReadAttempts  = 5
+	maxWriteAttempts = 5
+	maxOperationTime = time.Minute
+)
+
+// StoreImpl is the Firestore-based implementation of flaky.Store. There are few marks (tens or
+// hundreds), so List simply reads all of them.
+type StoreImpl struct {
+	client *ifirestore.Client
+}
+
+// markEntry represents how a flaky.Mark is stored in Firestore.
+type markEntry struct {
+	TraceID  tiling.TraceID `firestore:"traceid"`
+	MarkedBy string         `firestore:"markedby"`
+	TS       time.Time      `firestore:"ts"`
+	Note     string         `firestore:"note"`
+}
+
+// New returns a new StoreImpl.
+func New(client *ifirestore.Client) *StoreImpl {
+	return &StoreImpl{
+		client: client,
+	}
+}
+
+// docID returns the id of the document for the given trace. Trace ids can contain characters
+// that are not allowed in document ids (e.g. '/'), so the hash of the trace id is used.
+func docID(id tiling.TraceID) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(id)))
+}
+
+// Add implements the flaky.Store interface.
+func (s *StoreImpl) Add(ctx context.Context, m flaky.Mark) error {
+	if m.TraceID == "" {
+		return skerr.Fmt("trace id of flaky mark cannot be empty")
+	}
+	doc := s.client.Collection(marksCollection).Doc(docID(m.TraceID))
+	entry := markEntry{
+		TraceID:  m.TraceID,
+		MarkedBy: m.MarkedBy,
+		TS:       m.TS,
+		Note:     m.Note,
+	}
+	if _, err := s.client.Set(ctx, doc, entry, maxWriteAttempts, maxOperationTime); err != nil {
+		return skerr.Wrapf(err, "storing flaky mark to Firestore (%#v)", m)
+	}
+	return nil
+}
+
+// List implements the flaky.Store interface. The marks are sorted by trace id.
+func (s *StoreImpl) List(ctx context.Context) ([]flaky.Mark, error) {
+	q := s.client.Collection(marksCollection).Query
+	rv := []flaky.Mark{}
+	err := s.client.IterDocs(ctx, "list_flaky_marks", "", q, maxReadAttempts, maxOperationTime, func(doc *firestore.DocumentSnapshot) error {
+		if doc == nil {
+			return nil
+		}
+		entry := markEntry{}
+		if err := doc.DataTo(&entry); err != nil {
+			return skerr.Wrapf(err, "corrupt data in firestore, could not unmarshal markEntry with id %s", doc.Ref.ID)
+		}
+		rv = append(rv, flaky.Mark{
+			TraceID:  entry.TraceID,
+			MarkedBy: entry.MarkedBy,
+			TS:       entry.TS.UTC(),
+			Note:     entry.Note,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, skerr.Wrapf(err, "fetching flaky marks")
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].TraceID < rv[j].TraceID
+	})
+	return rv, nil
+}
+
+// Delete implements the flaky.Store interface.
+func (s *StoreImpl) Delete(ctx context.Context, id tiling.TraceID) error {
+	if id == "" {
+		return skerr.Fmt("trace id of flaky mark cannot be empty")
+	}
+	doc := s.client.Collection(marksCollection).Doc(docID(id))
+	if _, err := s.client.Delete(ctx, doc, maxWriteAttempts, maxOperationTime); err != nil {
+		return skerr.Wrapf(err, "deleting flaky mark for trace %s", id)
+	}
+	return nil
+}
+
+// Make sure StoreImpl fulfills the flaky.Store interface.
+var _ flaky.Store = (*StoreImpl)(nil)
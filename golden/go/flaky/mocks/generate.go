@@ -0,0 +1,3 @@
+package mocks
+
+//go:generate mockery -name Store -dir ../ -output .
@@ -0,0 +1,82 @@
+// Package sql_flakystore hosts an SQL-based (SQLite or Postgres) implementation of flaky.Store.
+package sql_flakystore
+
+import (
+	"context"
+	"database/sql"
+
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/tiling"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/golden/go/flaky"
+	"go.skia.org/infra/golden/go/sql_utils"
+)
+
+// schema creates the FlakyTraces table. ts is stored as a sql_utils timestamp.
+const schema = `CREATE TABLE IF NOT EXISTS FlakyTraces (
+	trace_id   TEXT PRIMARY KEY,
+	marked_by  TEXT NOT NULL,
+	ts         BIGINT NOT NULL,
+	note       TEXT NOT NULL
+)`
+
+// StoreImpl is the SQL-based implementation of flaky.Store.
+type StoreImpl struct {
+	db *sql.DB
+}
+
+// New returns a new StoreImpl, creating the FlakyTraces table if it doesn't already exist.
+func New(db *sql.DB) (*StoreImpl, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, skerr.Wrapf(err, "creating FlakyTraces table")
+	}
+	return &StoreImpl{db: db}, nil
+}
+
+// Add implements the flaky.Store interface.
+func (s *StoreImpl) Add(ctx context.Context, m flaky.Mark) error {
+	if m.TraceID == "" {
+		return skerr.Fmt("trace id of flaky mark cannot be empty")
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO FlakyTraces (trace_id, marked_by, ts, note) VALUES ($1, $2, $3, $4)
+ON CONFLICT (trace_id) DO UPDATE SET marked_by=excluded.marked_by, ts=excluded.ts, note=excluded.note`,
+		string(m.TraceID), m.MarkedBy, sql_utils.ToTimestamp(m.TS), m.Note)
+	if err != nil {
+		return skerr.Wrapf(err, "storing flaky mark (%#v)", m)
+	}
+	return nil
+}
+
+// List implements the flaky.Store interface. The marks are sorted by trace id.
+func (s *StoreImpl) List(ctx context.Context) ([]flaky.Mark, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT trace_id, marked_by, ts, note FROM FlakyTraces ORDER BY trace_id`)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "querying flaky marks")
+	}
+	defer util.Close(rows)
+	rv := []flaky.Mark{}
+	for rows.Next() {
+		var m flaky.Mark
+		var ts int64
+		if err := rows.Scan(&m.TraceID, &m.MarkedBy, &ts, &m.Note); err != nil {
+			return nil, skerr.Wrapf(err, "reading flaky mark")
+		}
+		m.TS = sql_utils.FromTimestamp(ts)
+		rv = append(rv, m)
+	}
+	return rv, skerr.Wrap(rows.Err())
+}
+
+// Delete implements the flaky.Store interface.
+func (s *StoreImpl) Delete(ctx context.Context, id tiling.TraceID) error {
+	if id == "" {
+		return skerr.Fmt("trace id of flaky mark cannot be empty")
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM FlakyTraces WHERE trace_id=$1`, string(id)); err != nil {
+		return skerr.Wrapf(err, "deleting flaky mark for trace %s", id)
+	}
+	return nil
+}
+
+// Make sure StoreImpl fulfills the flaky.Store interface.
+var _ flaky.Store = (*StoreImpl)(nil)
@@ -0,0 +1,109 @@
+package sql_flakystore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/golden/go/flaky"
+	"go.skia.org/infra/golden/go/sql_utils"
+)
+
+func TestAddListDelete(t *testing.T) {
+	unittest.MediumTest(t)
+	ctx := context.Background()
+	s, cleanup := newEmptyStore(t)
+	defer cleanup()
+
+	xm := makeMarks()
+	// Add them in a not-sorted order to make sure List sorts them.
+	require.NoError(t, s.Add(ctx, xm[1]))
+	require.NoError(t, s.Add(ctx, xm[0]))
+	require.NoError(t, s.Add(ctx, xm[2]))
+
+	actual, err := s.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, makeMarks(), actual)
+
+	require.NoError(t, s.Delete(ctx, xm[1].TraceID))
+	actual, err = s.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []flaky.Mark{xm[0], xm[2]}, actual)
+}
+
+func TestAddReplaces(t *testing.T) {
+	unittest.MediumTest(t)
+	ctx := context.Background()
+	s, cleanup := newEmptyStore(t)
+	defer cleanup()
+
+	xm := makeMarks()
+	require.NoError(t, s.Add(ctx, xm[0]))
+	updated := xm[0]
+	updated.MarkedBy = "other@example.com"
+	updated.TS = updated.TS.Add(time.Hour)
+	updated.Note = "still flaky"
+	require.NoError(t, s.Add(ctx, updated))
+
+	actual, err := s.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []flaky.Mark{updated}, actual)
+}
+
+func TestDeleteNonExistentMark(t *testing.T) {
+	unittest.MediumTest(t)
+	s, cleanup := newEmptyStore(t)
+	defer cleanup()
+
+	require.NoError(t, s.Delete(context.Background(), ",name=not_in_there,"))
+}
+
+func TestEmptyTraceID(t *testing.T) {
+	unittest.MediumTest(t)
+	s, cleanup := newEmptyStore(t)
+	defer cleanup()
+
+	err := s.Delete(context.Background(), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "empty")
+
+	err = s.Add(context.Background(), flaky.Mark{MarkedBy: "user@example.com"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "empty")
+}
+
+func newEmptyStore(t *testing.T) (*StoreImpl, func()) {
+	db, cleanup := sql_utils.NewSQLiteForTesting(t)
+	s, err := New(db)
+	require.NoError(t, err)
+	return s, cleanup
+}
+
+// makeMarks returns some marks, sorted by trace id.
+func makeMarks() []flaky.Mark {
+	now := time.Date(2019, time.November, 1, 2, 3, 4, 0, time.UTC)
+	return []flaky.Mark{
+		{
+			TraceID:  ",config=8888,name=blend_modes,os=Android,",
+			MarkedBy: "alpha@example.com",
+			TS:       now,
+			Note:     "skbug.com/1234",
+		},
+		{
+			TraceID:  ",config=gles,name=text_blob,os=Linux,",
+			MarkedBy: "beta@example.com",
+			TS:       now.Add(-time.Hour),
+			Note:     "",
+		},
+		{
+			TraceID:  ",config=vk,name=blend_modes,os/version=Win10,",
+			MarkedBy: "alpha@example.com",
+			TS:       now.Add(time.Minute),
+			Note:     "GPU driver is flaky",
+		},
+	}
+}
@@ -0,0 +1,126 @@
+package ignore
+
+import (
+	"context"
+	"time"
+
+	"go.skia.org/infra/go/metrics2"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/sklog"
+	"go.skia.org/infra/go/util"
+)
+
+const (
+	numExpiredMetric = "gold_num_expired_ignore_rules"
+	livenessMetric   = "gold_expired_ignore_rules_monitoring"
+)
+
+// ExpiryNotifier tells the owners of an ignore rule that the rule is about to expire.
+type ExpiryNotifier interface {
+	// NotifyExpiring notifies the CreatedBy and UpdatedBy users of the given rule.
+	NotifyExpiring(ctx context.Context, rule Rule) error
+}
+
+// ExpiryMonitorConfig configures an ExpiryMonitor.
+type ExpiryMonitorConfig struct {
+	Store Store
+	// Notifiers are used to tell the owners about rules that will expire soon. It may be empty.
+	Notifiers []ExpiryNotifier
+	// NotifyBefore is how long before a rule expires its owners are notified. If it is <= 0,
+	// nobody is notified.
+	NotifyBefore time.Duration
+	// GracePeriod is how long after a rule expires it is no longer applied (see Rule.IsApplied).
+	// It is only used to log the rules that stop being applied.
+	GracePeriod time.Duration
+}
+
+// ExpiryMonitor periodically looks at the ignore rules. It counts the expired rules in a metric
+// and notifies the owners of the rules that are about to expire.
+type ExpiryMonitor struct {
+	ExpiryMonitorConfig
+
+	numExpired metrics2.Int64Metric
+
+	// notified keeps track of the expiration time for which the owners of a rule were notified by
+	// each of the Notifiers. Owners are notified again if the rule gets a new expiration time. This
+	// is only kept in memory, so owners may be notified again after a restart.
+	notified map[notification]time.Time
+	// stopped keeps track of the rules (by id) that were logged as no longer applied.
+	stopped map[string]bool
+
+	// now can be set for unit tests.
+	now func() time.Time
+}
+
+// notification identifies the notification of the owners of a rule (by id) by one of the
+// Notifiers (by index).
+type notification struct {
+	notifier int
+	ruleID   string
+}
+
+// NewExpiryMonitor returns a new ExpiryMonitor. Call Start to begin monitoring.
+func NewExpiryMonitor(c ExpiryMonitorConfig) *ExpiryMonitor {
+	return &ExpiryMonitor{
+		ExpiryMonitorConfig: c,
+		numExpired:          metrics2.GetInt64Metric(numExpiredMetric, nil),
+		notified:            map[notification]time.Time{},
+		stopped:             map[string]bool{},
+		now:                 time.Now,
+	}
+}
+
+// Start checks the ignore rules once and then starts a goroutine that checks them again at the
+// given interval. Errors after the first check are only logged.
+func (m *ExpiryMonitor) Start(ctx context.Context, interval time.Duration) error {
+	liveness := metrics2.NewLiveness(livenessMetric)
+	if err := m.oneStep(ctx); err != nil {
+		return skerr.Wrapf(err, "starting to monitor ignore rules")
+	}
+	go util.RepeatCtx(interval, ctx, func(ctx context.Context) {
+		if err := m.oneStep(ctx); err != nil {
+			sklog.Errorf("Failed one step of monitoring ignore rules: %s", err)
+			return
+		}
+		liveness.Reset()
+	})
+	return nil
+}
+
+// oneStep counts the number of ignore rules in the store that are expired and notifies the
+// owners of the rules that are about to expire. A failing notification is logged, but does not
+// fail the whole step. Only the failed notification will be retried on the next step.
+func (m *ExpiryMonitor) oneStep(ctx context.Context) error {
+	list, err := m.Store.List(ctx)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	now := m.now()
+	n := 0
+	for _, rule := range list {
+		if rule.IsExpired(now) {
+			n++
+			if !rule.IsApplied(now, m.GracePeriod) && !m.stopped[rule.ID] {
+				sklog.Infof("Ignore rule %s (%q) expired on %s and is no longer applied", rule.ID, rule.Query, rule.Expires)
+				m.stopped[rule.ID] = true
+			}
+			continue
+		}
+		if m.NotifyBefore <= 0 || now.Add(m.NotifyBefore).Before(rule.Expires) {
+			continue
+		}
+		for i, notifier := range m.Notifiers {
+			key := notification{notifier: i, ruleID: rule.ID}
+			if last, ok := m.notified[key]; ok && last.Equal(rule.Expires) {
+				continue
+			}
+			if err := notifier.NotifyExpiring(ctx, rule); err != nil {
+				sklog.Warningf("Could not notify owners of ignore rule %s: %s", rule.ID, err)
+				continue
+			}
+			m.notified[key] = rule.Expires
+		}
+	}
+	m.numExpired.Update(int64(n))
+	return nil
+}
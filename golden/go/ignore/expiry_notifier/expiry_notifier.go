@@ -0,0 +1,130 @@
+// Package expiry_notifier contains implementations of ignore.ExpiryNotifier that reach the owners
+// of an ignore rule via email or chat.
+package expiry_notifier
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"go.skia.org/infra/go/chatbot"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/golden/go/ignore"
+)
+
+const (
+	// fromDisplayName is the display name of the sender of emails.
+	fromDisplayName = "Gold"
+
+	// timeFormat is how the expiration time is shown to users.
+	timeFormat = "Mon, 02 Jan 2006 15:04 MST"
+)
+
+// Email is the subset of email.GMail that is used to send emails.
+type Email interface {
+	Send(senderDisplayName string, to []string, subject string, body string) error
+}
+
+// EmailNotifier implements ignore.ExpiryNotifier by emailing the owners of the rule.
+type EmailNotifier struct {
+	email   Email
+	siteURL string
+}
+
+// NewEmailNotifier returns an EmailNotifier that uses the given Email to send messages with links
+// to the Gold instance at siteURL.
+func NewEmailNotifier(email Email, siteURL string) *EmailNotifier {
+	return &EmailNotifier{
+		email:   email,
+		siteURL: strings.TrimSuffix(siteURL, "/"),
+	}
+}
+
+// NotifyExpiring implements the ignore.ExpiryNotifier interface.
+func (e *EmailNotifier) NotifyExpiring(_ context.Context, rule ignore.Rule) error {
+	to := owners(rule)
+	if len(to) == 0 {
+		return nil
+	}
+	subject := fmt.Sprintf("Gold ignore rule expires soon: %s", rule.Query)
+	body := fmt.Sprintf(`<p>The following ignore rule expires on %s:</p>
+<ul>
+  <li>Query: <code>%s</code></li>
+  <li>Note: %s</li>
+</ul>
+<p>Please <a href="%s">update the rule</a> if it is still needed, or delete it if it is not.</p>`,
+		html.EscapeString(rule.Expires.Format(timeFormat)), html.EscapeString(prettyQuery(rule.Query)),
+		html.EscapeString(rule.Note), ignoresURL(e.siteURL))
+	if err := e.email.Send(fromDisplayName, to, subject, body); err != nil {
+		return skerr.Wrapf(err, "emailing %v about ignore rule %s", to, rule.ID)
+	}
+	return nil
+}
+
+// ChatNotifier implements ignore.ExpiryNotifier by sending a message to a chat room via
+// go/chatbot. Note that chatbot.Init must be called before sending.
+type ChatNotifier struct {
+	room    string
+	siteURL string
+	send    func(body, room, thread string) error
+}
+
+// NewChatNotifier returns a ChatNotifier that sends messages to the given room, with links to the
+// Gold instance at siteURL.
+func NewChatNotifier(room, siteURL string) *ChatNotifier {
+	return &ChatNotifier{
+		room:    room,
+		siteURL: strings.TrimSuffix(siteURL, "/"),
+		send:    chatbot.Send,
+	}
+}
+
+// NotifyExpiring implements the ignore.ExpiryNotifier interface. All messages about one rule go
+// to the same thread.
+func (c *ChatNotifier) NotifyExpiring(_ context.Context, rule ignore.Rule) error {
+	body := fmt.Sprintf("*Gold ignore rule expires on %s*\n\nQuery: %s\nNote: %s\nOwners: %s\n\n%s",
+		rule.Expires.Format(timeFormat), prettyQuery(rule.Query), rule.Note,
+		strings.Join(owners(rule), ", "), ignoresURL(c.siteURL))
+	if err := c.send(body, c.room, "gold-ignore-rule-"+rule.ID); err != nil {
+		return skerr.Wrapf(err, "sending chat message about ignore rule %s", rule.ID)
+	}
+	return nil
+}
+
+// owners returns the distinct, non-empty emails of the users who created and last updated the
+// given rule.
+func owners(rule ignore.Rule) []string {
+	var ret []string
+	for _, o := range []string{rule.CreatedBy, rule.UpdatedBy} {
+		if o == "" {
+			continue
+		}
+		if len(ret) > 0 && ret[0] == o {
+			continue
+		}
+		ret = append(ret, o)
+	}
+	return ret
+}
+
+// prettyQuery returns the url-encoded query in a more readable form, or as is if it cannot be
+// decoded.
+func prettyQuery(q string) string {
+	if u, err := url.QueryUnescape(q); err == nil {
+		return strings.Replace(u, "&", " ", -1)
+	}
+	return q
+}
+
+// ignoresURL returns the URL of the page that lists the ignore rules.
+func ignoresURL(siteURL string) string {
+	return siteURL + "/ignores"
+}
+
+// Make sure the notifiers fulfill the ignore.ExpiryNotifier interface.
+var (
+	_ ignore.ExpiryNotifier = (*EmailNotifier)(nil)
+	_ ignore.ExpiryNotifier = (*ChatNotifier)(nil)
+)
@@ -0,0 +1,93 @@
+package expiry_notifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/golden/go/ignore"
+)
+
+func TestEmailNotifier(t *testing.T) {
+	unittest.SmallTest(t)
+
+	fe := &fakeEmail{}
+	n := NewEmailNotifier(fe, "https://gold.example.com/")
+	require.NoError(t, n.NotifyExpiring(context.Background(), makeRule()))
+
+	assert.Equal(t, "Gold", fe.from)
+	assert.Equal(t, []string{"alpha@example.com", "beta@example.com"}, fe.to)
+	assert.Equal(t, "Gold ignore rule expires soon: device=angler&os=Android%2010", fe.subject)
+	assert.Contains(t, fe.body, "Fri, 10 Jan 2020 03:04 UTC")
+	assert.Contains(t, fe.body, "<code>device=angler os=Android 10</code>")
+	assert.Contains(t, fe.body, "skbug.com/1234 &lt;flaky&gt;")
+	assert.Contains(t, fe.body, `href="https://gold.example.com/ignores"`)
+}
+
+func TestEmailNotifierSameOwner(t *testing.T) {
+	unittest.SmallTest(t)
+
+	fe := &fakeEmail{}
+	n := NewEmailNotifier(fe, "https://gold.example.com")
+	r := makeRule()
+	r.UpdatedBy = r.CreatedBy
+	require.NoError(t, n.NotifyExpiring(context.Background(), r))
+	assert.Equal(t, []string{"alpha@example.com"}, fe.to)
+}
+
+func TestEmailNotifierError(t *testing.T) {
+	unittest.SmallTest(t)
+
+	fe := &fakeEmail{err: errors.New("gmail broke")}
+	n := NewEmailNotifier(fe, "https://gold.example.com")
+	err := n.NotifyExpiring(context.Background(), makeRule())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gmail broke")
+}
+
+func TestChatNotifier(t *testing.T) {
+	unittest.SmallTest(t)
+
+	var body, room, thread string
+	n := NewChatNotifier("gold-alerts", "https://gold.example.com")
+	n.send = func(b, r, th string) error {
+		body, room, thread = b, r, th
+		return nil
+	}
+	require.NoError(t, n.NotifyExpiring(context.Background(), makeRule()))
+
+	assert.Equal(t, "gold-alerts", room)
+	assert.Equal(t, "gold-ignore-rule-1234", thread)
+	assert.Contains(t, body, "Query: device=angler os=Android 10")
+	assert.Contains(t, body, "Owners: alpha@example.com, beta@example.com")
+	assert.Contains(t, body, "https://gold.example.com/ignores")
+}
+
+func makeRule() ignore.Rule {
+	return ignore.Rule{
+		ID:        "1234",
+		CreatedBy: "alpha@example.com",
+		UpdatedBy: "beta@example.com",
+		Expires:   time.Date(2020, time.January, 10, 3, 4, 5, 0, time.UTC),
+		Query:     "device=angler&os=Android%2010",
+		Note:      "skbug.com/1234 <flaky>",
+	}
+}
+
+type fakeEmail struct {
+	from    string
+	to      []string
+	subject string
+	body    string
+	err     error
+}
+
+func (f *fakeEmail) Send(from string, to []string, subject string, body string) error {
+	f.from, f.to, f.subject, f.body = from, to, subject, body
+	return f.err
+}
@@ -0,0 +1,129 @@
+package ignore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+func TestExpiryMonitorOneStep(t *testing.T) {
+	unittest.SmallTest(t)
+
+	now := time.Date(2020, time.January, 10, 0, 0, 0, 0, time.UTC)
+	expiringSoon := Rule{ID: "soon", CreatedBy: "alpha@example.com", Expires: now.Add(time.Hour), Query: "device=angler"}
+	expiringLater := Rule{ID: "later", CreatedBy: "beta@example.com", Expires: now.Add(30 * 24 * time.Hour), Query: "device=bullhead"}
+	expired := Rule{ID: "expired", CreatedBy: "gamma@example.com", Expires: now.Add(-time.Hour), Query: "device=crosshatch"}
+	fs := &fakeStore{rules: []Rule{expiringSoon, expiringLater, expired}}
+	fn := &fakeNotifier{}
+
+	m := NewExpiryMonitor(ExpiryMonitorConfig{
+		Store:        fs,
+		Notifiers:    []ExpiryNotifier{fn},
+		NotifyBefore: 3 * 24 * time.Hour,
+	})
+	m.now = func() time.Time { return now }
+
+	require.NoError(t, m.oneStep(context.Background()))
+	assert.Equal(t, int64(1), m.numExpired.Get())
+	assert.Equal(t, []string{"soon"}, fn.notified)
+
+	// The owners are not notified again on the next step...
+	require.NoError(t, m.oneStep(context.Background()))
+	assert.Equal(t, []string{"soon"}, fn.notified)
+
+	// ... unless the rule gets a new expiration time.
+	fs.rules[0].Expires = now.Add(2 * time.Hour)
+	require.NoError(t, m.oneStep(context.Background()))
+	assert.Equal(t, []string{"soon", "soon"}, fn.notified)
+}
+
+func TestExpiryMonitorNotificationFailureIsRetried(t *testing.T) {
+	unittest.SmallTest(t)
+
+	now := time.Date(2020, time.January, 10, 0, 0, 0, 0, time.UTC)
+	fs := &fakeStore{rules: []Rule{{ID: "soon", CreatedBy: "alpha@example.com", Expires: now.Add(time.Hour)}}}
+	fn := &fakeNotifier{err: errors.New("email broke")}
+
+	m := NewExpiryMonitor(ExpiryMonitorConfig{
+		Store:        fs,
+		Notifiers:    []ExpiryNotifier{fn},
+		NotifyBefore: 3 * 24 * time.Hour,
+	})
+	m.now = func() time.Time { return now }
+
+	// A failed notification does not fail the step.
+	require.NoError(t, m.oneStep(context.Background()))
+	fn.err = nil
+	require.NoError(t, m.oneStep(context.Background()))
+	assert.Equal(t, []string{"soon", "soon"}, fn.notified)
+}
+
+func TestExpiryMonitorNotificationFailure_OnlyFailedNotifierRetried(t *testing.T) {
+	unittest.SmallTest(t)
+
+	now := time.Date(2020, time.January, 10, 0, 0, 0, 0, time.UTC)
+	fs := &fakeStore{rules: []Rule{{ID: "soon", CreatedBy: "alpha@example.com", Expires: now.Add(time.Hour)}}}
+	email := &fakeNotifier{}
+	chat := &fakeNotifier{err: errors.New("chat broke")}
+
+	m := NewExpiryMonitor(ExpiryMonitorConfig{
+		Store:        fs,
+		Notifiers:    []ExpiryNotifier{email, chat},
+		NotifyBefore: 3 * 24 * time.Hour,
+	})
+	m.now = func() time.Time { return now }
+
+	// The failing notifier is retried on every step, but the owners are only sent one email.
+	require.NoError(t, m.oneStep(context.Background()))
+	require.NoError(t, m.oneStep(context.Background()))
+	assert.Equal(t, []string{"soon"}, email.notified)
+	assert.Equal(t, []string{"soon", "soon"}, chat.notified)
+
+	// Once the failing notifier succeeds, it isn't retried anymore either.
+	chat.err = nil
+	require.NoError(t, m.oneStep(context.Background()))
+	require.NoError(t, m.oneStep(context.Background()))
+	assert.Equal(t, []string{"soon"}, email.notified)
+	assert.Equal(t, []string{"soon", "soon", "soon"}, chat.notified)
+}
+
+func TestExpiryMonitorStoreFailure(t *testing.T) {
+	unittest.SmallTest(t)
+
+	m := NewExpiryMonitor(ExpiryMonitorConfig{
+		Store: &fakeStore{err: errors.New("firestore broke")},
+	})
+	require.Error(t, m.oneStep(context.Background()))
+}
+
+// fakeStore is an in-memory Store. It cannot use the mocks package, because that would be an
+// import cycle.
+type fakeStore struct {
+	rules []Rule
+	err   error
+}
+
+func (f *fakeStore) Create(context.Context, Rule) error { return errors.New("not implemented") }
+
+func (f *fakeStore) List(context.Context) ([]Rule, error) { return f.rules, f.err }
+
+func (f *fakeStore) Update(context.Context, Rule) error { return errors.New("not implemented") }
+
+func (f *fakeStore) Delete(context.Context, string) error { return errors.New("not implemented") }
+
+// fakeNotifier records the ids of the rules it was called with.
+type fakeNotifier struct {
+	notified []string
+	err      error
+}
+
+func (f *fakeNotifier) NotifyExpiring(_ context.Context, rule Rule) error {
+	f.notified = append(f.notified, rule.ID)
+	return f.err
+}
@@ -5,12 +5,9 @@ import (
 	"net/url"
 	"time"
 
-	"go.skia.org/infra/go/metrics2"
 	"go.skia.org/infra/go/paramtools"
 	"go.skia.org/infra/go/skerr"
-	"go.skia.org/infra/go/sklog"
 	"go.skia.org/infra/go/tiling"
-	"go.skia.org/infra/go/util"
 )
 
 // Store is an interface for a database that saves ignore rules.
@@ -59,6 +56,33 @@ func NewRule(createdByUser string, expires time.Time, queryStr string, note stri
 	}
 }
 
+// IsExpired returns true if the rule has expired at the given time.
+func (r Rule) IsExpired(now time.Time) bool {
+	return now.After(r.Expires)
+}
+
+// IsApplied returns true if the rule should still be applied to the tile at the given time. Rules
+// that have been expired for longer than the grace period are no longer applied. A grace period
+// <= 0 means that rules are applied forever, even after they expire.
+func (r Rule) IsApplied(now time.Time, gracePeriod time.Duration) bool {
+	if gracePeriod <= 0 {
+		return true
+	}
+	return !now.After(r.Expires.Add(gracePeriod))
+}
+
+// AppliedRules returns the rules that should still be applied to the tile at the given time.
+// See Rule.IsApplied.
+func AppliedRules(rules []Rule, now time.Time, gracePeriod time.Duration) []Rule {
+	ret := make([]Rule, 0, len(rules))
+	for _, r := range rules {
+		if r.IsApplied(now, gracePeriod) {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
 // AsMatcher makes a paramtools.ParamMatcher from the given slice of Rules. If any rules are
 // invalid, an error will be returned.
 func AsMatcher(ignores []Rule) (paramtools.ParamMatcher, error) {
@@ -104,39 +128,3 @@ nextTrace:
 
 	return ret, ignoreQueries, nil
 }
-
-// oneStep counts the number of ignore rules in the given store that are expired.
-func oneStep(ctx context.Context, store Store, metric metrics2.Int64Metric) error {
-	list, err := store.List(ctx)
-	if err != nil {
-		return skerr.Wrap(err)
-	}
-	n := 0
-	for _, rule := range list {
-		if time.Now().After(rule.Expires) {
-			n += 1
-		}
-	}
-	metric.Update(int64(n))
-	return nil
-}
-
-// StartMetrics starts a new monitoring routine for the given
-// ignore.Store that counts expired ignore rules and pushes
-// that info into a metric.
-func StartMetrics(ctx context.Context, store Store, interval time.Duration) error {
-	numExpired := metrics2.GetInt64Metric("gold_num_expired_ignore_rules", nil)
-	liveness := metrics2.NewLiveness("gold_expired_ignore_rules_monitoring")
-
-	if err := oneStep(ctx, store, numExpired); err != nil {
-		return skerr.Wrapf(err, "starting to monitor ignore rules")
-	}
-	go util.RepeatCtx(interval, ctx, func(ctx context.Context) {
-		if err := oneStep(ctx, store, numExpired); err != nil {
-			sklog.Errorf("Failed one step of monitoring ignore rules: %s", err)
-			return
-		}
-		liveness.Reset()
-	})
-	return nil
-}
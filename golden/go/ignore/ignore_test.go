@@ -100,3 +100,41 @@ func TestFilterIgnored(t *testing.T) {
 	require.NotContains(t, ft.Traces, data.CrosshatchAlphaTraceID)
 	require.NotContains(t, ft.Traces, data.CrosshatchBetaTraceID)
 }
+
+func TestIsExpiredIsApplied(t *testing.T) {
+	unittest.SmallTest(t)
+
+	expires := time.Date(2020, time.January, 10, 0, 0, 0, 0, time.UTC)
+	r := NewRule("user@example.com", expires, "device=crosshatch", "note")
+	const week = 7 * 24 * time.Hour
+
+	before := expires.Add(-time.Hour)
+	assert.False(t, r.IsExpired(before))
+	assert.True(t, r.IsApplied(before, 0))
+	assert.True(t, r.IsApplied(before, week))
+
+	withinGrace := expires.Add(week - time.Hour)
+	assert.True(t, r.IsExpired(withinGrace))
+	assert.True(t, r.IsApplied(withinGrace, 0))
+	assert.True(t, r.IsApplied(withinGrace, week))
+
+	afterGrace := expires.Add(week + time.Hour)
+	assert.True(t, r.IsExpired(afterGrace))
+	// A grace period of 0 means rules are always applied.
+	assert.True(t, r.IsApplied(afterGrace, 0))
+	assert.False(t, r.IsApplied(afterGrace, week))
+}
+
+func TestAppliedRules(t *testing.T) {
+	unittest.SmallTest(t)
+
+	now := time.Date(2020, time.January, 10, 0, 0, 0, 0, time.UTC)
+	active := NewRule("user@example.com", now.Add(time.Hour), "device=angler", "note")
+	recentlyExpired := NewRule("user@example.com", now.Add(-time.Hour), "device=bullhead", "note")
+	longExpired := NewRule("user@example.com", now.Add(-48*time.Hour), "device=crosshatch", "note")
+	rules := []Rule{active, recentlyExpired, longExpired}
+
+	assert.Equal(t, rules, AppliedRules(rules, now, 0))
+	assert.Equal(t, []Rule{active, recentlyExpired}, AppliedRules(rules, now, 24*time.Hour))
+	assert.Empty(t, AppliedRules(nil, now, 24*time.Hour))
+}
@@ -0,0 +1,178 @@
+// Package shared_tests contains tests that every implementation of ignore.Store should pass.
+package shared_tests
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/deepequal"
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/golden/go/ignore"
+)
+
+// Tests contains the tests that every implementation of ignore.Store should pass, by name. Each
+// test must be given a new, empty Store.
+var Tests = map[string]func(t sktest.TestingT, s ignore.Store){
+	"CreateListIgnoreRule":  testCreateListIgnoreRule,
+	"CreateDelete":          testCreateDelete,
+	"DeleteNonExistentRule": testDeleteNonExistentRule,
+	"DeleteEmptyRule":       testDeleteEmptyRule,
+	"CreateUpdate":          testCreateUpdate,
+	"UpdateNonExistentRule": testUpdateNonExistentRule,
+	"UpdateEmptyRule":       testUpdateEmptyRule,
+}
+
+// testCreateListIgnoreRule tests that the created rules are listed, sorted by expiration.
+func testCreateListIgnoreRule(t sktest.TestingT, s ignore.Store) {
+	ctx := context.Background()
+	requireEmpty(t, s)
+
+	xir := makeIgnoreRules()
+	// Add them in a not-sorted order to make sure List sorts them.
+	require.NoError(t, s.Create(ctx, xir[2]))
+	require.NoError(t, s.Create(ctx, xir[0]))
+	require.NoError(t, s.Create(ctx, xir[3]))
+	require.NoError(t, s.Create(ctx, xir[1]))
+
+	requireCurrentListMatches(t, s, makeIgnoreRules())
+}
+
+// testCreateDelete tests that deleted rules are no longer listed.
+func testCreateDelete(t sktest.TestingT, s ignore.Store) {
+	ctx := context.Background()
+	requireEmpty(t, s)
+
+	xir := makeIgnoreRules()
+	// Add 0, 1, 2, 2, 2, 2, 3 (there are 3 extra of index 2)
+	require.NoError(t, s.Create(ctx, xir[0]))
+	require.NoError(t, s.Create(ctx, xir[1]))
+	require.NoError(t, s.Create(ctx, xir[2]))
+	require.NoError(t, s.Create(ctx, xir[2]))
+	require.NoError(t, s.Create(ctx, xir[2]))
+	require.NoError(t, s.Create(ctx, xir[2]))
+	require.NoError(t, s.Create(ctx, xir[3]))
+	// Wait until those 7 rules are in the list
+	require.Eventually(t, func() bool {
+		actualRules, _ := s.List(ctx)
+		return len(actualRules) == 7
+	}, 5*time.Second, 200*time.Millisecond)
+	// Re-query the rules to make sure none got dropped or added unexpectedly.
+	actualRules, err := s.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, actualRules, 7) // should still have 7 elements in the list
+
+	require.NoError(t, s.Delete(ctx, actualRules[3].ID))
+	require.NoError(t, s.Delete(ctx, actualRules[4].ID))
+	require.NoError(t, s.Delete(ctx, actualRules[5].ID))
+
+	requireCurrentListMatches(t, s, makeIgnoreRules())
+}
+
+// testDeleteNonExistentRule tests that deleting an unknown rule is not an error.
+func testDeleteNonExistentRule(t sktest.TestingT, s ignore.Store) {
+	require.NoError(t, s.Delete(context.Background(), "Not in there"))
+}
+
+// testDeleteEmptyRule tests that a rule ID is required for deleting.
+func testDeleteEmptyRule(t sktest.TestingT, s ignore.Store) {
+	err := s.Delete(context.Background(), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "empty")
+}
+
+// testCreateUpdate tests that updating a rule changes everything but who created it.
+func testCreateUpdate(t sktest.TestingT, s ignore.Store) {
+	ctx := context.Background()
+	requireEmpty(t, s)
+
+	xir := makeIgnoreRules()
+	require.NoError(t, s.Create(ctx, xir[1]))
+	// Wait until that rule is in the list
+	require.Eventually(t, func() bool {
+		actualRules, _ := s.List(ctx)
+		return len(actualRules) == 1
+	}, 5*time.Second, 200*time.Millisecond)
+
+	actualRules, err := s.List(ctx)
+	require.NoError(t, err)
+	newContent := xir[0]
+	newContent.ID = actualRules[0].ID
+	require.NoError(t, s.Update(ctx, newContent))
+
+	requireCurrentListMatches(t, s, []ignore.Rule{
+		{
+			// Notice the CreatedBy is the same as the original rule, but everything else is changed.
+			CreatedBy: "beta@example.com",
+			UpdatedBy: "alpha@example.com",
+			Expires:   now.Add(-time.Hour),
+			Query:     "config=gpu",
+			Note:      "expired",
+		},
+	})
+}
+
+// testUpdateNonExistentRule tests that unknown rules can't be updated.
+func testUpdateNonExistentRule(t sktest.TestingT, s ignore.Store) {
+	ir := makeIgnoreRules()[0]
+	ir.ID = "whoops"
+	err := s.Update(context.Background(), ir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "before updating")
+}
+
+// testUpdateEmptyRule tests that a rule ID is required for updating.
+func testUpdateEmptyRule(t sktest.TestingT, s ignore.Store) {
+	err := s.Update(context.Background(), ignore.Rule{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "empty")
+}
+
+var now = time.Date(2020, time.January, 13, 14, 15, 16, 0, time.UTC)
+
+func makeIgnoreRules() []ignore.Rule {
+	return []ignore.Rule{
+		ignore.NewRule("alpha@example.com", now.Add(-time.Hour), "config=gpu", "expired"),
+		ignore.NewRule("beta@example.com", now.Add(time.Minute*10), "config=8888", "No good reason."),
+		ignore.NewRule("beta@example.com", now.Add(time.Minute*50), "extra=123&extra=abc", "Ignore multiple."),
+		ignore.NewRule("alpha@example.com", now.Add(time.Minute*100), "extra=123&extra=abc&config=8888", "Ignore multiple 2."),
+	}
+}
+
+// requireEmpty fails the test if the store has any rules.
+func requireEmpty(t sktest.TestingT, s ignore.Store) {
+	empty, err := s.List(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, empty)
+}
+
+// compareIgnoreRulesIgnoringIDs returns true if the two lists of rules match (disregarding the ID).
+// ID is ignored because it is nondeterministic.
+func compareIgnoreRulesIgnoringIDs(first []ignore.Rule, second []ignore.Rule) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		r1, r2 := first[i], second[i]
+		r1.ID = ""
+		r2.ID = ""
+		if !deepequal.DeepEqual(r1, r2) {
+			return false
+		}
+	}
+	return true
+}
+
+// requireCurrentListMatches either returns because the content in the given store matches the
+// expected rules or it fails the test because it does not match.
+func requireCurrentListMatches(t sktest.TestingT, s ignore.Store, expected []ignore.Rule) {
+	// List may use a query snapshot, which is not synchronous, so we might have to query a few
+	// times before everything syncs up.
+	require.Eventually(t, func() bool {
+		actualRules, err := s.List(context.Background())
+		assert.NoError(t, err)
+		return compareIgnoreRulesIgnoringIDs(actualRules, expected)
+	}, 5*time.Second, 200*time.Millisecond)
+}
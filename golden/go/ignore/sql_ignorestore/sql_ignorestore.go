@@ -0,0 +1,101 @@
+// Package sql_ignorestore hosts an SQL-based (SQLite or Postgres) implementation of ignore.Store.
+package sql_ignorestore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/golden/go/ignore"
+	"go.skia.org/infra/golden/go/sql_utils"
+)
+
+// schema creates the IgnoreRules table. Expires is stored as a sql_utils timestamp.
+const schema = `CREATE TABLE IF NOT EXISTS IgnoreRules (
+	id          TEXT PRIMARY KEY,
+	created_by  TEXT NOT NULL,
+	updated_by  TEXT NOT NULL,
+	expires     BIGINT NOT NULL,
+	query       TEXT NOT NULL,
+	note        TEXT NOT NULL
+)`
+
+// StoreImpl is the SQL-based implementation of ignore.Store.
+type StoreImpl struct {
+	db *sql.DB
+}
+
+// New returns a new StoreImpl, creating the IgnoreRules table if it doesn't already exist.
+func New(db *sql.DB) (*StoreImpl, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, skerr.Wrapf(err, "creating IgnoreRules table")
+	}
+	return &StoreImpl{db: db}, nil
+}
+
+// Create implements the ignore.Store interface.
+func (s *StoreImpl) Create(ctx context.Context, r ignore.Rule) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO IgnoreRules (id, created_by, updated_by, expires, query, note) VALUES ($1, $2, $3, $4, $5, $6)`,
+		uuid.New().String(), r.CreatedBy, r.UpdatedBy, sql_utils.ToTimestamp(r.Expires), r.Query, r.Note)
+	if err != nil {
+		return skerr.Wrapf(err, "storing new ignore rule (%#v)", r)
+	}
+	return nil
+}
+
+// List implements the ignore.Store interface. The rules are sorted by Expires, soonest first.
+func (s *StoreImpl) List(ctx context.Context) ([]ignore.Rule, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, created_by, updated_by, expires, query, note FROM IgnoreRules ORDER BY expires, id`)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "querying ignore rules")
+	}
+	defer util.Close(rows)
+	rv := []ignore.Rule{}
+	for rows.Next() {
+		var r ignore.Rule
+		var expires int64
+		if err := rows.Scan(&r.ID, &r.CreatedBy, &r.UpdatedBy, &expires, &r.Query, &r.Note); err != nil {
+			return nil, skerr.Wrapf(err, "reading ignore rule")
+		}
+		r.Expires = sql_utils.FromTimestamp(expires)
+		rv = append(rv, r)
+	}
+	return rv, skerr.Wrap(rows.Err())
+}
+
+// Update implements the ignore.Store interface.
+func (s *StoreImpl) Update(ctx context.Context, rule ignore.Rule) error {
+	if rule.ID == "" {
+		return skerr.Fmt("ID for ignore rule cannot be empty")
+	}
+	res, err := s.db.ExecContext(ctx, `UPDATE IgnoreRules SET updated_by=$1, expires=$2, query=$3, note=$4 WHERE id=$5`,
+		rule.UpdatedBy, sql_utils.ToTimestamp(rule.Expires), rule.Query, rule.Note, rule.ID)
+	if err != nil {
+		return skerr.Wrapf(err, "updating ignore rule %s", rule.ID)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return skerr.Wrapf(err, "updating ignore rule %s", rule.ID)
+	}
+	if n == 0 {
+		return skerr.Fmt("getting ignore rule %s before updating: not found", rule.ID)
+	}
+	return nil
+}
+
+// Delete implements the ignore.Store interface.
+func (s *StoreImpl) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return skerr.Fmt("ID for ignore rule cannot be empty")
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM IgnoreRules WHERE id=$1`, id); err != nil {
+		return skerr.Wrapf(err, "deleting ignore rule with id %s", id)
+	}
+	return nil
+}
+
+// Make sure StoreImpl fulfills the ignore.Store interface.
+var _ ignore.Store = (*StoreImpl)(nil)
@@ -0,0 +1,36 @@
+package sql_ignorestore
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/golden/go/ignore/shared_tests"
+	"go.skia.org/infra/golden/go/sql_utils"
+)
+
+func TestSharedTests_SQLite(t *testing.T) {
+	unittest.MediumTest(t)
+	runSharedTests(t, sql_utils.NewSQLiteForTesting)
+}
+
+func TestSharedTests_Postgres(t *testing.T) {
+	unittest.LargeTest(t) // should use a Postgres database
+	runSharedTests(t, sql_utils.NewPostgresForTesting)
+}
+
+// runSharedTests runs each of the shared tests with a Store in a new database.
+func runSharedTests(t *testing.T, newDB func(sktest.TestingT) (*sql.DB, func())) {
+	for name, test := range shared_tests.Tests {
+		t.Run(name, func(t *testing.T) {
+			db, cleanup := newDB(t)
+			defer cleanup()
+			s, err := New(db)
+			require.NoError(t, err)
+			test(t, s)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Package imgmatching contains image matching algorithms that can be used to decide whether an
+// image produced by a test is close enough to a known positive image to be considered a pass.
+//
+// The algorithm for a test is configured via optional keys (see jsonio.Result.Options), which
+// means that different tests in the same run can use different algorithms.
+package imgmatching
+
+import (
+	"image"
+	"strconv"
+
+	"go.skia.org/infra/go/skerr"
+)
+
+// AlgorithmName is the name of an image matching algorithm.
+type AlgorithmName string
+
+const (
+	// ExactMatching means that the image must have exactly the same pixels as a positive image.
+	// It is the default if no algorithm is specified.
+	ExactMatching = AlgorithmName("exact")
+
+	// FuzzyMatching allows a limited number of pixels to differ by a limited amount from a
+	// positive image.
+	FuzzyMatching = AlgorithmName("fuzzy")
+
+	// SobelFuzzyMatching works like FuzzyMatching, except that pixels on the edges found by a
+	// Sobel operator on the positive image are ignored. This makes it tolerant of antialiasing
+	// differences.
+	SobelFuzzyMatching = AlgorithmName("sobel")
+)
+
+const (
+	// AlgorithmNameOptKey is the optional key that selects the image matching algorithm.
+	AlgorithmNameOptKey = "image_matching_algorithm"
+
+	// MaxDifferentPixelsOptKey is the optional key for the maximum number of pixels that may
+	// differ. Used by FuzzyMatching and SobelFuzzyMatching.
+	MaxDifferentPixelsOptKey = "fuzzy_max_different_pixels"
+
+	// MaxChannelDeltaOptKey is the optional key for the maximum amount that any channel (R, G, B
+	// or A) of a pixel may differ by. Used by FuzzyMatching and SobelFuzzyMatching.
+	MaxChannelDeltaOptKey = "fuzzy_max_channel_delta"
+
+	// EdgeThresholdOptKey is the optional key for the Sobel edge magnitude, in [0, 255], above
+	// which a pixel is considered to be on an edge and is ignored. Used by SobelFuzzyMatching.
+	EdgeThresholdOptKey = "sobel_edge_threshold"
+
+	// ClosestPositiveOptKey is the option recorded in the results with the positive digest the
+	// image was matched against.
+	ClosestPositiveOptKey = "image_matching_closest_positive"
+
+	// MatchedOptKey is the option recorded in the results with whether the image matched the
+	// closest positive digest, either "true" or "false".
+	MatchedOptKey = "image_matching_matched"
+)
+
+// Matcher decides whether an image is close enough to an expected image.
+type Matcher interface {
+	// Match returns true if 'actual' is considered a match of 'expected'.
+	Match(expected, actual image.Image) bool
+}
+
+// MakeMatcher returns the name of the algorithm and a Matcher as configured by the given
+// optional keys. If no algorithm is given then ExactMatching is used. An error is returned if the
+// optional keys are invalid or incomplete for the chosen algorithm.
+func MakeMatcher(optionalKeys map[string]string) (AlgorithmName, Matcher, error) {
+	algorithm := AlgorithmName(optionalKeys[AlgorithmNameOptKey])
+	switch algorithm {
+	case "", ExactMatching:
+		return ExactMatching, &ExactMatcher{}, nil
+	case FuzzyMatching:
+		m, err := makeFuzzyMatcher(optionalKeys)
+		if err != nil {
+			return "", nil, skerr.Wrap(err)
+		}
+		return FuzzyMatching, m, nil
+	case SobelFuzzyMatching:
+		fm, err := makeFuzzyMatcher(optionalKeys)
+		if err != nil {
+			return "", nil, skerr.Wrap(err)
+		}
+		edgeThreshold, err := getIntOptKey(optionalKeys, EdgeThresholdOptKey, 0, 255)
+		if err != nil {
+			return "", nil, skerr.Wrap(err)
+		}
+		return SobelFuzzyMatching, &SobelFuzzyMatcher{
+			EdgeThreshold:      edgeThreshold,
+			MaxDifferentPixels: fm.MaxDifferentPixels,
+			MaxChannelDelta:    fm.MaxChannelDelta,
+		}, nil
+	default:
+		return "", nil, skerr.Fmt("unknown image matching algorithm %q", algorithm)
+	}
+}
+
+// makeFuzzyMatcher returns a FuzzyMatcher configured by the given optional keys.
+func makeFuzzyMatcher(optionalKeys map[string]string) (*FuzzyMatcher, error) {
+	maxDifferentPixels, err := getIntOptKey(optionalKeys, MaxDifferentPixelsOptKey, 0, -1)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	maxChannelDelta, err := getIntOptKey(optionalKeys, MaxChannelDeltaOptKey, 0, 255)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	return &FuzzyMatcher{
+		MaxDifferentPixels: maxDifferentPixels,
+		MaxChannelDelta:    maxChannelDelta,
+	}, nil
+}
+
+// getIntOptKey returns the value of the given optional key as an int. The key is required and
+// the value must be in [min, max]. If max is negative then there is no upper bound.
+func getIntOptKey(optionalKeys map[string]string, key string, min, max int) (int, error) {
+	s, ok := optionalKeys[key]
+	if !ok {
+		return 0, skerr.Fmt("required image matching parameter %q not found", key)
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, skerr.Wrapf(err, "parsing image matching parameter %q", key)
+	}
+	if v < min || (max >= 0 && v > max) {
+		return 0, skerr.Fmt("image matching parameter %q out of range: %d", key, v)
+	}
+	return v, nil
+}
@@ -0,0 +1,83 @@
+package imgmatching
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+func TestMakeMatcher_Exact(t *testing.T) {
+	unittest.SmallTest(t)
+	name, m, err := MakeMatcher(nil)
+	require.NoError(t, err)
+	assert.Equal(t, ExactMatching, name)
+	assert.Equal(t, &ExactMatcher{}, m)
+
+	name, m, err = MakeMatcher(map[string]string{AlgorithmNameOptKey: "exact"})
+	require.NoError(t, err)
+	assert.Equal(t, ExactMatching, name)
+	assert.Equal(t, &ExactMatcher{}, m)
+}
+
+func TestMakeMatcher_Fuzzy(t *testing.T) {
+	unittest.SmallTest(t)
+	name, m, err := MakeMatcher(map[string]string{
+		AlgorithmNameOptKey:      "fuzzy",
+		MaxDifferentPixelsOptKey: "10",
+		MaxChannelDeltaOptKey:    "4",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, FuzzyMatching, name)
+	assert.Equal(t, &FuzzyMatcher{MaxDifferentPixels: 10, MaxChannelDelta: 4}, m)
+}
+
+func TestMakeMatcher_Sobel(t *testing.T) {
+	unittest.SmallTest(t)
+	name, m, err := MakeMatcher(map[string]string{
+		AlgorithmNameOptKey:      "sobel",
+		MaxDifferentPixelsOptKey: "10",
+		MaxChannelDeltaOptKey:    "4",
+		EdgeThresholdOptKey:      "64",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, SobelFuzzyMatching, name)
+	assert.Equal(t, &SobelFuzzyMatcher{EdgeThreshold: 64, MaxDifferentPixels: 10, MaxChannelDelta: 4}, m)
+}
+
+func TestMakeMatcher_InvalidKeys_ReturnsError(t *testing.T) {
+	unittest.SmallTest(t)
+	test := func(name string, optionalKeys map[string]string) {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := MakeMatcher(optionalKeys)
+			assert.Error(t, err)
+		})
+	}
+	test("unknown algorithm", map[string]string{AlgorithmNameOptKey: "nope"})
+	test("missing fuzzy keys", map[string]string{AlgorithmNameOptKey: "fuzzy"})
+	test("missing channel delta", map[string]string{
+		AlgorithmNameOptKey:      "fuzzy",
+		MaxDifferentPixelsOptKey: "10",
+	})
+	test("not a number", map[string]string{
+		AlgorithmNameOptKey:      "fuzzy",
+		MaxDifferentPixelsOptKey: "ten",
+		MaxChannelDeltaOptKey:    "4",
+	})
+	test("negative pixels", map[string]string{
+		AlgorithmNameOptKey:      "fuzzy",
+		MaxDifferentPixelsOptKey: "-1",
+		MaxChannelDeltaOptKey:    "4",
+	})
+	test("channel delta too big", map[string]string{
+		AlgorithmNameOptKey:      "fuzzy",
+		MaxDifferentPixelsOptKey: "10",
+		MaxChannelDeltaOptKey:    "256",
+	})
+	test("missing edge threshold", map[string]string{
+		AlgorithmNameOptKey:      "sobel",
+		MaxDifferentPixelsOptKey: "10",
+		MaxChannelDeltaOptKey:    "4",
+	})
+}
@@ -0,0 +1,131 @@
+package imgmatching
+
+import (
+	"image"
+	"math"
+
+	"go.skia.org/infra/golden/go/diff"
+)
+
+// ExactMatcher matches images that have exactly the same pixels.
+type ExactMatcher struct{}
+
+// Match implements the Matcher interface.
+func (m *ExactMatcher) Match(expected, actual image.Image) bool {
+	return fuzzyMatch(diff.GetNRGBA(expected), diff.GetNRGBA(actual), 0, 0, nil)
+}
+
+// FuzzyMatcher matches images of the same size where at most MaxDifferentPixels pixels differ,
+// and no channel of any pixel differs by more than MaxChannelDelta.
+type FuzzyMatcher struct {
+	MaxDifferentPixels int
+	MaxChannelDelta    int
+}
+
+// Match implements the Matcher interface.
+func (m *FuzzyMatcher) Match(expected, actual image.Image) bool {
+	return fuzzyMatch(diff.GetNRGBA(expected), diff.GetNRGBA(actual), m.MaxDifferentPixels, m.MaxChannelDelta, nil)
+}
+
+// SobelFuzzyMatcher works like FuzzyMatcher, except that it ignores pixels where the magnitude
+// of the Sobel operator applied to the expected image is greater than EdgeThreshold.
+type SobelFuzzyMatcher struct {
+	EdgeThreshold      int
+	MaxDifferentPixels int
+	MaxChannelDelta    int
+}
+
+// Match implements the Matcher interface.
+func (m *SobelFuzzyMatcher) Match(expected, actual image.Image) bool {
+	exp := diff.GetNRGBA(expected)
+	edges := sobel(exp)
+	ignore := func(i int) bool {
+		return edges[i] > m.EdgeThreshold
+	}
+	return fuzzyMatch(exp, diff.GetNRGBA(actual), m.MaxDifferentPixels, m.MaxChannelDelta, ignore)
+}
+
+// fuzzyMatch returns true if the images are the same size, at most maxDifferentPixels pixels
+// differ and no channel of any pixel differs by more than maxChannelDelta. Pixels are numbered in
+// row-major order and any pixel for which ignore returns true is skipped. The ignore func may be
+// nil.
+func fuzzyMatch(expected, actual *image.NRGBA, maxDifferentPixels, maxChannelDelta int, ignore func(i int) bool) bool {
+	if expected.Bounds().Size() != actual.Bounds().Size() {
+		return false
+	}
+	size := expected.Bounds().Size()
+	numDifferent := 0
+	for y := 0; y < size.Y; y++ {
+		expRow := expected.Pix[y*expected.Stride : y*expected.Stride+4*size.X]
+		actRow := actual.Pix[y*actual.Stride : y*actual.Stride+4*size.X]
+		for x := 0; x < size.X; x++ {
+			if ignore != nil && ignore(y*size.X+x) {
+				continue
+			}
+			different := false
+			for c := 4 * x; c < 4*x+4; c++ {
+				delta := int(expRow[c]) - int(actRow[c])
+				if delta == 0 {
+					continue
+				}
+				if delta < 0 {
+					delta = -delta
+				}
+				if delta > maxChannelDelta {
+					return false
+				}
+				different = true
+			}
+			if different {
+				numDifferent++
+				if numDifferent > maxDifferentPixels {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// sobel returns the magnitude of the Sobel operator applied to the luma of each pixel in the
+// image, in row-major order, clamped to [0, 255]. Pixels on the border use the nearest pixel
+// inside the image for their missing neighbors.
+func sobel(img *image.NRGBA) []int {
+	size := img.Bounds().Size()
+	luma := make([]float64, size.X*size.Y)
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			p := img.Pix[y*img.Stride+4*x:]
+			luma[y*size.X+x] = 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+		}
+	}
+	at := func(x, y int) float64 {
+		if x < 0 {
+			x = 0
+		} else if x >= size.X {
+			x = size.X - 1
+		}
+		if y < 0 {
+			y = 0
+		} else if y >= size.Y {
+			y = size.Y - 1
+		}
+		return luma[y*size.X+x]
+	}
+	ret := make([]int, size.X*size.Y)
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+			gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+			// The largest possible magnitude is 4*255*sqrt(2), so scale it into [0, 255].
+			m := math.Sqrt(gx*gx+gy*gy) / (4 * math.Sqrt2)
+			ret[y*size.X+x] = int(math.Min(255, math.Round(m)))
+		}
+	}
+	return ret
+}
+
+// Confirm we implement the interface.
+var _ Matcher = (*ExactMatcher)(nil)
+var _ Matcher = (*FuzzyMatcher)(nil)
+var _ Matcher = (*SobelFuzzyMatcher)(nil)
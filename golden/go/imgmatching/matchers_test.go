@@ -0,0 +1,109 @@
+package imgmatching
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+// makeImage returns a w x h image filled with the given gray level.
+func makeImage(w, h int, gray uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: gray, G: gray, B: gray, A: 0xff})
+		}
+	}
+	return img
+}
+
+func TestExactMatcher(t *testing.T) {
+	unittest.SmallTest(t)
+	m := &ExactMatcher{}
+	assert.True(t, m.Match(makeImage(4, 4, 100), makeImage(4, 4, 100)))
+
+	actual := makeImage(4, 4, 100)
+	actual.Set(1, 1, color.NRGBA{R: 101, G: 100, B: 100, A: 0xff})
+	assert.False(t, m.Match(makeImage(4, 4, 100), actual))
+
+	assert.False(t, m.Match(makeImage(4, 4, 100), makeImage(4, 5, 100)))
+}
+
+func TestFuzzyMatcher(t *testing.T) {
+	unittest.SmallTest(t)
+	m := &FuzzyMatcher{MaxDifferentPixels: 2, MaxChannelDelta: 5}
+	expected := makeImage(4, 4, 100)
+
+	actual := makeImage(4, 4, 100)
+	assert.True(t, m.Match(expected, actual))
+
+	// Two pixels differ within the channel delta.
+	actual.Set(0, 0, color.NRGBA{R: 105, G: 100, B: 100, A: 0xff})
+	actual.Set(3, 3, color.NRGBA{R: 100, G: 95, B: 97, A: 0xff})
+	assert.True(t, m.Match(expected, actual))
+
+	// A third pixel is too many.
+	actual.Set(2, 2, color.NRGBA{R: 100, G: 100, B: 101, A: 0xff})
+	assert.False(t, m.Match(expected, actual))
+
+	// A single pixel that differs by too much.
+	actual = makeImage(4, 4, 100)
+	actual.Set(1, 2, color.NRGBA{R: 100, G: 100, B: 100, A: 0xf0})
+	assert.False(t, m.Match(expected, actual))
+
+	assert.False(t, m.Match(expected, makeImage(5, 4, 100)))
+}
+
+func TestSobelFuzzyMatcher(t *testing.T) {
+	unittest.SmallTest(t)
+	// The expected image has a vertical edge between x=3 and x=4.
+	expected := makeImage(8, 8, 0)
+	for y := 0; y < 8; y++ {
+		for x := 4; x < 8; x++ {
+			expected.Set(x, y, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+		}
+	}
+	// The actual image is antialiased along the edge, which changes all 16 pixels on it by a
+	// lot.
+	actual := makeImage(8, 8, 0)
+	actual.Pix = append([]uint8{}, expected.Pix...)
+	for y := 0; y < 8; y++ {
+		actual.Set(3, y, color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff})
+		actual.Set(4, y, color.NRGBA{R: 0xc0, G: 0xc0, B: 0xc0, A: 0xff})
+	}
+
+	fuzzy := &FuzzyMatcher{MaxDifferentPixels: 2, MaxChannelDelta: 5}
+	assert.False(t, fuzzy.Match(expected, actual))
+
+	m := &SobelFuzzyMatcher{EdgeThreshold: 64, MaxDifferentPixels: 2, MaxChannelDelta: 5}
+	assert.True(t, m.Match(expected, actual))
+
+	// Differences away from the edge are still caught.
+	actual.Set(0, 0, color.NRGBA{R: 0x40, G: 0, B: 0, A: 0xff})
+	assert.False(t, m.Match(expected, actual))
+
+	// With a high enough threshold nothing is an edge.
+	m = &SobelFuzzyMatcher{EdgeThreshold: 255, MaxDifferentPixels: 2, MaxChannelDelta: 5}
+	actual.Set(0, 0, color.NRGBA{R: 0, G: 0, B: 0, A: 0xff})
+	assert.False(t, m.Match(expected, actual))
+}
+
+func TestSobel(t *testing.T) {
+	unittest.SmallTest(t)
+	// A flat image has no edges.
+	for _, v := range sobel(makeImage(3, 3, 200)) {
+		assert.Equal(t, 0, v)
+	}
+
+	img := makeImage(4, 1, 0)
+	img.Set(2, 0, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	img.Set(3, 0, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	edges := sobel(img)
+	assert.Equal(t, 0, edges[0])
+	assert.Equal(t, edges[1], edges[2])
+	assert.Greater(t, edges[1], 128)
+	assert.Equal(t, 0, edges[3])
+}
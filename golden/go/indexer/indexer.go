@@ -19,6 +19,7 @@ import (
 	"go.skia.org/infra/golden/go/digest_counter"
 	"go.skia.org/infra/golden/go/digesttools"
 	"go.skia.org/infra/golden/go/expstorage"
+	"go.skia.org/infra/golden/go/flaky"
 	"go.skia.org/infra/golden/go/paramsets"
 	"go.skia.org/infra/golden/go/pdag"
 	"go.skia.org/infra/golden/go/shared"
@@ -48,6 +49,7 @@ type SearchIndex struct {
 
 	cpxTile types.ComplexTile
 	blamer  blame.Blamer
+	flaky   *flaky.Index
 
 	// This is set by the indexing pipeline when we just want to update
 	// individual tests that have changed.
@@ -66,6 +68,7 @@ type countsAndBlames []*summary.TriageStatus
 type searchIndexConfig struct {
 	diffStore         diff.DiffStore
 	expectationsStore expstorage.ExpectationsStore
+	flakyStore        flaky.Store
 	gcsClient         storage.GCSClient
 	warmer            warmer.DiffWarmer
 }
@@ -98,10 +101,17 @@ func SearchIndexForTesting(cpxTile types.ComplexTile, dc [2]digest_counter.Diges
 		preSliced:         map[preSliceGroup][]*types.TracePair{},
 		blamer:            b,
 		cpxTile:           cpxTile,
+		flaky:             flaky.NewIndex(cpxTile, nil),
 	}
 	return s, preSliceData(context.Background(), s)
 }
 
+// SetFlakyMarksForTesting recomputes the flaky traces of an index returned by
+// SearchIndexForTesting, as if the given traces had been marked as flaky by users.
+func (idx *SearchIndex) SetFlakyMarksForTesting(marks []flaky.Mark) {
+	idx.flaky = flaky.NewIndex(idx.cpxTile, marks)
+}
+
 // Tile implements the IndexSearcher interface.
 func (idx *SearchIndex) Tile() types.ComplexTile {
 	return idx.cpxTile
@@ -208,6 +218,11 @@ func (idx *SearchIndex) GetBlame(test types.TestName, digest types.Digest, commi
 	return idx.blamer.GetBlame(test, digest, commits)
 }
 
+// FlakyTraces implements the IndexSearcher interface.
+func (idx *SearchIndex) FlakyTraces() *flaky.Index {
+	return idx.flaky
+}
+
 // SlicedTraces returns a slice of TracePairs that match the query and the ignore state.
 // This is meant to be a superset of traces, as only the corpus and testname from the query are
 // used for this pre-filter step.
@@ -242,9 +257,12 @@ type IndexerConfig struct {
 	DiffStore         diff.DiffStore
 	EventBus          eventbus.EventBus
 	ExpectationsStore expstorage.ExpectationsStore
-	GCSClient         storage.GCSClient
-	TileSource        tilesource.TileSource
-	Warmer            warmer.DiffWarmer
+	// FlakyStore is optional. If set, the traces marked as flaky by users are indexed along
+	// with those detected as flaky.
+	FlakyStore flaky.Store
+	GCSClient  storage.GCSClient
+	TileSource tilesource.TileSource
+	Warmer     warmer.DiffWarmer
 }
 
 // Indexer is the type that continuously processes data as the underlying
@@ -279,6 +297,8 @@ func New(ctx context.Context, ic IndexerConfig, interval time.Duration) (*Indexe
 
 	preSliceNode := root.Child(preSliceData)
 
+	flakyNode := root.Child(calcFlaky)
+
 	// Node that triggers blame and writing baselines.
 	// This is used to trigger when expectations change.
 	// We don't need to re-calculate DigestCounts if the
@@ -307,7 +327,7 @@ func New(ctx context.Context, ic IndexerConfig, interval time.Duration) (*Indexe
 
 	// Set the result on the Indexer instance, once summaries, parameters and writing
 	// the hash files is done.
-	pdag.NewNodeWithParents(ret.setIndex, summariesNode, paramsNodeInclude, paramsNodeExclude, writeHashes)
+	pdag.NewNodeWithParents(ret.setIndex, summariesNode, paramsNodeInclude, paramsNodeExclude, writeHashes, flakyNode)
 
 	ret.pipeline = root
 	ret.indexTestsNode = indexTestsNode
@@ -412,6 +432,7 @@ func (ix *Indexer) executePipeline(ctx context.Context, cpxTile types.ComplexTil
 	sic := searchIndexConfig{
 		diffStore:         ix.DiffStore,
 		expectationsStore: ix.ExpectationsStore,
+		flakyStore:        ix.FlakyStore,
 		gcsClient:         ix.GCSClient,
 		warmer:            ix.Warmer,
 	}
@@ -446,6 +467,7 @@ func (ix *Indexer) cloneLastIndex() *SearchIndex {
 	sic := searchIndexConfig{
 		diffStore:         ix.DiffStore,
 		expectationsStore: ix.ExpectationsStore,
+		flakyStore:        ix.FlakyStore,
 		gcsClient:         ix.GCSClient,
 		warmer:            ix.Warmer,
 	}
@@ -456,6 +478,7 @@ func (ix *Indexer) cloneLastIndex() *SearchIndex {
 		dCounters:         lastIdx.dCounters,         // stay the same even if expectations change.
 		paramsetSummaries: lastIdx.paramsetSummaries, // stay the same even if expectations change.
 		preSliced:         lastIdx.preSliced,         // stay the same even if expectations change.
+		flaky:             lastIdx.flaky,             // stay the same even if expectations change.
 
 		summaries: [2]countsAndBlames{
 			// the objects inside the summaries are immutable, but may be replaced if expectations
@@ -603,6 +626,22 @@ func calcBlame(ctx context.Context, state interface{}) error {
 	return nil
 }
 
+// calcFlaky is the pipeline function to find the flaky traces. The marks added by users are
+// read for every new tile, so it can take until the next tile for a new mark to be indexed.
+func calcFlaky(ctx context.Context, state interface{}) error {
+	idx := state.(*SearchIndex)
+	var marks []flaky.Mark
+	if idx.flakyStore != nil {
+		var err error
+		marks, err = idx.flakyStore.List(ctx)
+		if err != nil {
+			return skerr.Wrapf(err, "fetching traces marked as flaky")
+		}
+	}
+	idx.flaky = flaky.NewIndex(idx.cpxTile, marks)
+	return nil
+}
+
 func writeKnownHashesList(ctx context.Context, state interface{}) error {
 	idx := state.(*SearchIndex)
 
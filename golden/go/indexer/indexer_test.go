@@ -21,6 +21,8 @@ import (
 	"go.skia.org/infra/golden/go/digest_counter"
 	"go.skia.org/infra/golden/go/expstorage"
 	mock_expstorage "go.skia.org/infra/golden/go/expstorage/mocks"
+	"go.skia.org/infra/golden/go/flaky"
+	mock_flaky "go.skia.org/infra/golden/go/flaky/mocks"
 	"go.skia.org/infra/golden/go/mocks"
 	"go.skia.org/infra/golden/go/paramsets"
 	"go.skia.org/infra/golden/go/summary"
@@ -365,6 +367,53 @@ func TestSummarizeByGrouping(t *testing.T) {
 	})
 }
 
+// TestCalcFlaky makes sure the traces marked as flaky by users end up in the index.
+func TestCalcFlaky(t *testing.T) {
+	unittest.SmallTest(t)
+	ct, _, _ := makeComplexTileWithCrosshatchIgnores()
+	mfs := &mock_flaky.Store{}
+	defer mfs.AssertExpectations(t)
+
+	mark := flaky.Mark{
+		TraceID:  data.BullheadAlphaTraceID,
+		MarkedBy: "user@example.com",
+		TS:       time.Date(2019, time.November, 1, 2, 3, 4, 0, time.UTC),
+	}
+	mfs.On("List", testutils.AnyContext).Return([]flaky.Mark{mark}, nil)
+
+	si := &SearchIndex{
+		searchIndexConfig: searchIndexConfig{
+			flakyStore: mfs,
+		},
+		cpxTile: ct,
+	}
+	require.NoError(t, calcFlaky(context.Background(), si))
+
+	assert.Equal(t, map[tiling.TraceID]flaky.TraceStatus{
+		data.BullheadAlphaTraceID: {
+			TraceStats: flaky.TraceStats{DistinctDigests: 2, Transitions: 1},
+			Mark:       &mark,
+		},
+	}, si.FlakyTraces().Traces())
+	// The untriaged digest was only drawn by the trace that is marked as flaky, but the bad digest
+	// was also drawn by angler.
+	assert.True(t, si.FlakyTraces().OnlyInFlakyTraces(data.AlphaTest, data.AlphaUntriaged1Digest))
+	assert.False(t, si.FlakyTraces().OnlyInFlakyTraces(data.AlphaTest, data.AlphaBad1Digest))
+}
+
+// TestCalcFlakyNoStore makes sure flaky traces are detected without a flaky.Store.
+func TestCalcFlakyNoStore(t *testing.T) {
+	unittest.SmallTest(t)
+	ct, _, _ := makeComplexTileWithCrosshatchIgnores()
+
+	si := &SearchIndex{
+		cpxTile: ct,
+	}
+	require.NoError(t, calcFlaky(context.Background(), si))
+	// None of the traces in the test data changes often enough to be flaky.
+	assert.Empty(t, si.FlakyTraces().Traces())
+}
+
 const (
 	// valid, but arbitrary md5 hash
 	unavailableDigest = types.Digest("fed541470e246b63b313930523220de8")
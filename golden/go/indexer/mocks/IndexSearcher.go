@@ -9,6 +9,8 @@ import (
 
 	digest_counter "go.skia.org/infra/golden/go/digest_counter"
 
+	flaky "go.skia.org/infra/golden/go/flaky"
+
 	mock "github.com/stretchr/testify/mock"
 
 	paramtools "go.skia.org/infra/go/paramtools"
@@ -75,6 +77,22 @@ func (_m *IndexSearcher) DigestCountsByTrace(is types.IgnoreState) map[tiling.Tr
 	return r0
 }
 
+// FlakyTraces provides a mock function with given fields:
+func (_m *IndexSearcher) FlakyTraces() *flaky.Index {
+	ret := _m.Called()
+
+	var r0 *flaky.Index
+	if rf, ok := ret.Get(0).(func() *flaky.Index); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*flaky.Index)
+		}
+	}
+
+	return r0
+}
+
 // GetBlame provides a mock function with given fields: test, digest, commits
 func (_m *IndexSearcher) GetBlame(test types.TestName, digest types.Digest, commits []*tiling.Commit) blame.BlameDistribution {
 	ret := _m.Called(test, digest, commits)
@@ -8,6 +8,7 @@ import (
 	"go.skia.org/infra/go/tiling"
 	"go.skia.org/infra/golden/go/blame"
 	"go.skia.org/infra/golden/go/digest_counter"
+	"go.skia.org/infra/golden/go/flaky"
 	"go.skia.org/infra/golden/go/summary"
 	"go.skia.org/infra/golden/go/types"
 )
@@ -56,6 +57,10 @@ type IndexSearcher interface {
 	// GetBlame returns the blame computed for the given test/digest.
 	GetBlame(test types.TestName, digest types.Digest, commits []*tiling.Commit) blame.BlameDistribution
 
+	// FlakyTraces returns the traces that were detected or marked as flaky. The returned value
+	// may be nil, which means that there are no flaky traces.
+	FlakyTraces() *flaky.Index
+
 	// SlicedTraces returns a slice of TracePairs that match the query and the ignore state.
 	// This is meant to be a partial slice, as only the corpus and testname from the query are
 	// used to create the subslice.
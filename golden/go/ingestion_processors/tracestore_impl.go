@@ -2,9 +2,11 @@ package ingestion_processors
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"go.skia.org/infra/go/ingestion"
@@ -15,8 +17,10 @@ import (
 	"go.skia.org/infra/go/vcsinfo"
 	"go.skia.org/infra/golden/go/jsonio"
 	"go.skia.org/infra/golden/go/shared"
+	"go.skia.org/infra/golden/go/sql_utils"
 	"go.skia.org/infra/golden/go/tracestore"
 	"go.skia.org/infra/golden/go/tracestore/bt_tracestore"
+	"go.skia.org/infra/golden/go/tracestore/sql_tracestore"
 	"go.skia.org/infra/golden/go/types"
 )
 
@@ -27,11 +31,18 @@ const (
 	btProjectConfig  = "BTProjectID"
 	btInstanceConfig = "BTInstance"
 	btTableConfig    = "BTTable"
+
+	// Configuration option that identifies a tracestore backed by an SQL database.
+	sqlGoldIngester = "gold-sql"
+
+	sqlDriverParam     = "SQLDriver"
+	sqlDataSourceParam = "SQLDataSource"
 )
 
 // Register the processor with the ingestion framework.
 func init() {
 	ingestion.Register(btGoldIngester, newBTTraceStoreProcessor)
+	ingestion.Register(sqlGoldIngester, newSQLTraceStoreProcessor)
 }
 
 // newTraceStoreProcessor implements the ingestion.Constructor signature and creates
@@ -54,8 +65,42 @@ func newBTTraceStoreProcessor(ctx context.Context, vcs vcsinfo.VCS, config *shar
 	}, nil
 }
 
+// newSQLTraceStoreProcessor implements the ingestion.Constructor signature and creates
+// a Processor that uses an SQL-backed tracestore.
+func newSQLTraceStoreProcessor(_ context.Context, vcs vcsinfo.VCS, config *sharedconfig.IngesterConfig, _ *http.Client) (ingestion.Processor, error) {
+	db, err := openSQL(config)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	sts, err := sql_tracestore.New(db, vcs)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "could not instantiate SQL tracestore")
+	}
+	return &btProcessor{
+		ts:  sts,
+		vcs: vcs,
+	}, nil
+}
+
+// openSQL opens the database configured by the SQLDriver and SQLDataSource params.
+func openSQL(config *sharedconfig.IngesterConfig) (*sql.DB, error) {
+	driver := config.ExtraParams[sqlDriverParam]
+	if strings.TrimSpace(driver) == "" {
+		return nil, skerr.Fmt("missing SQL driver (e.g. 'sqlite3')")
+	}
+	dataSource := config.ExtraParams[sqlDataSourceParam]
+	if strings.TrimSpace(dataSource) == "" {
+		return nil, skerr.Fmt("missing SQL data source")
+	}
+	db, err := sql_utils.Open(driver, dataSource)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "could not open %s database", driver)
+	}
+	return db, nil
+}
+
 // btProcessor implements the ingestion.Processor interface for gold using
-// the BigTable TraceStore
+// the BigTable TraceStore. It is also used with the SQL TraceStore.
 type btProcessor struct {
 	ts  tracestore.TraceStore
 	vcs vcsinfo.VCS
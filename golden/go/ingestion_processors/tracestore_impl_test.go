@@ -7,12 +7,15 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	ingestion_mocks "go.skia.org/infra/go/ingestion/mocks"
+	"go.skia.org/infra/go/sharedconfig"
 	"go.skia.org/infra/go/testutils"
 	"go.skia.org/infra/go/testutils/unittest"
 	"go.skia.org/infra/go/vcsinfo"
 	mock_vcs "go.skia.org/infra/go/vcsinfo/mocks"
 	"go.skia.org/infra/golden/go/mocks"
+	"go.skia.org/infra/golden/go/sql_utils"
 	"go.skia.org/infra/golden/go/tracestore"
 	"go.skia.org/infra/golden/go/types"
 )
@@ -93,6 +96,24 @@ func TestTraceStoreProcessorSunnyDay(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// TestSQLTraceStoreProcessorMissingParams tests that the SQL processor requires a driver and a
+// data source.
+func TestSQLTraceStoreProcessorMissingParams(t *testing.T) {
+	unittest.SmallTest(t)
+
+	config := &sharedconfig.IngesterConfig{
+		ExtraParams: map[string]string{},
+	}
+	_, err := newSQLTraceStoreProcessor(context.Background(), nil, config, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing SQL driver")
+
+	config.ExtraParams[sqlDriverParam] = sql_utils.SQLiteDriver
+	_, err = newSQLTraceStoreProcessor(context.Background(), nil, config, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing SQL data source")
+}
+
 const (
 	testCommitHash = "02cb37309c01506e2552e931efa9c04a569ed266"
 )
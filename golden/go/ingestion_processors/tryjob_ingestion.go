@@ -13,6 +13,7 @@ import (
 	"go.skia.org/infra/go/buildbucket"
 	"go.skia.org/infra/go/firestore"
 	"go.skia.org/infra/go/gerrit"
+	"go.skia.org/infra/go/gitlab"
 	"go.skia.org/infra/go/httputils"
 	"go.skia.org/infra/go/ingestion"
 	"go.skia.org/infra/go/metrics2"
@@ -23,41 +24,57 @@ import (
 	"go.skia.org/infra/go/vcsinfo"
 	"go.skia.org/infra/golden/go/clstore"
 	"go.skia.org/infra/golden/go/clstore/fs_clstore"
+	"go.skia.org/infra/golden/go/clstore/sql_clstore"
 	"go.skia.org/infra/golden/go/code_review"
 	"go.skia.org/infra/golden/go/code_review/gerrit_crs"
 	"go.skia.org/infra/golden/go/code_review/github_crs"
+	"go.skia.org/infra/golden/go/code_review/gitlab_crs"
 	"go.skia.org/infra/golden/go/continuous_integration"
 	"go.skia.org/infra/golden/go/continuous_integration/buildbucket_cis"
 	"go.skia.org/infra/golden/go/continuous_integration/dummy_cis"
 	"go.skia.org/infra/golden/go/expstorage"
 	"go.skia.org/infra/golden/go/expstorage/fs_expstore"
+	"go.skia.org/infra/golden/go/expstorage/sql_expstore"
 	"go.skia.org/infra/golden/go/ignore"
 	"go.skia.org/infra/golden/go/ignore/fs_ignorestore"
+	"go.skia.org/infra/golden/go/ignore/sql_ignorestore"
 	"go.skia.org/infra/golden/go/jsonio"
 	"go.skia.org/infra/golden/go/shared"
 	"go.skia.org/infra/golden/go/storage"
 	"go.skia.org/infra/golden/go/tjstore"
 	"go.skia.org/infra/golden/go/tjstore/fs_tjstore"
+	"go.skia.org/infra/golden/go/tjstore/sql_tjstore"
 	"go.skia.org/infra/golden/go/types"
 	"go.skia.org/infra/golden/go/types/expectations"
 )
 
 const (
 	firestoreTryJobIngester = "gold-tryjob-fs"
+	sqlTryJobIngester       = "gold-tryjob-sql"
 	firestoreProjectIDParam = "FirestoreProjectID"
 	firestoreNamespaceParam = "FirestoreNamespace"
 
-	gcsKnownHashesParam = "GCSKnownHashes"
+	gcsKnownHashesParam  = "GCSKnownHashes"
+	knownHashesFileParam = "KnownHashesFile"
 
 	codeReviewSystemParam      = "CodeReviewSystem"
 	gerritURLParam             = "GerritURL"
 	githubRepoParam            = "GitHubRepo"
 	githubCredentialsPathParam = "GitHubCredentialsPath"
+	gitlabURLParam             = "GitLabURL"
+	gitlabProjectParam         = "GitLabProject"
+	gitlabCredentialsPathParam = "GitLabCredentialsPath"
 
 	continuousIntegrationSystemsParam = "ContinuousIntegrationSystems"
 
+	// ignoreGracePeriodParam is optional. It is how long an expired ignore rule is still applied,
+	// as a duration string (e.g. "72h"). It should match the --ignore_grace_period of
+	// skiacorrectness. If unset, expired rules are applied until they are deleted.
+	ignoreGracePeriodParam = "IgnoreGracePeriod"
+
 	gerritCRS      = "gerrit"
 	githubCRS      = "github"
+	gitlabCRS      = "gitlab"
 	buildbucketCIS = "buildbucket"
 	cirrusCIS      = "cirrus"
 )
@@ -65,6 +82,7 @@ const (
 // Register the ingestion Processor with the ingestion framework.
 func init() {
 	ingestion.Register(firestoreTryJobIngester, newModularTryjobProcessor)
+	ingestion.Register(sqlTryJobIngester, newSQLTryjobProcessor)
 }
 
 // goldTryjobProcessor implements the ingestion.Processor interface to ingest tryjob results.
@@ -79,34 +97,17 @@ type goldTryjobProcessor struct {
 	ignoreStore     ignore.Store
 	tryJobStore     tjstore.Store
 
-	crsName string
+	crsName           string
+	ignoreGracePeriod time.Duration
 }
 
 // newModularTryjobProcessor returns an ingestion.Processor which is modular and can support
 // different CodeReviewSystems (e.g. "Gerrit", "GitHub") and different ContinuousIntegrationSystems
 // (e.g. "BuildBucket", "CirrusCI"). This particular implementation stores the data in Firestore.
 func newModularTryjobProcessor(ctx context.Context, _ vcsinfo.VCS, config *sharedconfig.IngesterConfig, client *http.Client) (ingestion.Processor, error) {
-	crsName := config.ExtraParams[codeReviewSystemParam]
-	if strings.TrimSpace(crsName) == "" {
-		return nil, skerr.Fmt("missing code review system (e.g. 'gerrit')")
-	}
-
-	crs, err := codeReviewSystemFactory(crsName, config, client)
+	g, err := newTryjobProcessor(config, client)
 	if err != nil {
-		return nil, skerr.Wrapf(err, "could not create client for CRS %q", crsName)
-	}
-
-	cisNames := strings.Split(config.ExtraParams[continuousIntegrationSystemsParam], ",")
-	if len(cisNames) == 0 {
-		return nil, skerr.Fmt("missing CI system (e.g. 'buildbucket')")
-	}
-	cisClients := make(map[string]continuous_integration.Client, len(cisNames))
-	for _, cisName := range cisNames {
-		cis, err := continuousIntegrationSystemFactory(cisName, config, client)
-		if err != nil {
-			return nil, skerr.Wrapf(err, "could not create client for CIS %q", cisName)
-		}
-		cisClients[cisName] = cis
+		return nil, skerr.Wrap(err)
 	}
 
 	fsProjectID := config.ExtraParams[firestoreProjectIDParam]
@@ -142,15 +143,100 @@ func newModularTryjobProcessor(ctx context.Context, _ vcsinfo.VCS, config *share
 		return nil, skerr.Wrapf(err, "creating GCSClient with opts %v", gsClientOpt)
 	}
 
+	g.gcsClient = gsClient
+	g.ignoreStore = fs_ignorestore.New(ctx, fsClient)
+	g.changeListStore = fs_clstore.New(fsClient, g.crsName)
+	g.tryJobStore = fs_tjstore.New(fsClient)
+	g.expStore = expStore
+	return g, nil
+}
+
+// newSQLTryjobProcessor returns an ingestion.Processor like newModularTryjobProcessor, except
+// that it stores the data in an SQL database and reads the known hashes from a local file.
+func newSQLTryjobProcessor(_ context.Context, _ vcsinfo.VCS, config *sharedconfig.IngesterConfig, client *http.Client) (ingestion.Processor, error) {
+	g, err := newTryjobProcessor(config, client)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+
+	db, err := openSQL(config)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+
+	expStore, err := sql_expstore.New(db, nil, sql_expstore.ReadOnly)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "initializing expectation store")
+	}
+	ignoreStore, err := sql_ignorestore.New(db)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "initializing ignore store")
+	}
+	changeListStore, err := sql_clstore.New(db, g.crsName)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "initializing changelist store")
+	}
+	tryJobStore, err := sql_tjstore.New(db)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "initializing tryjob store")
+	}
+
+	hashesPath := config.ExtraParams[knownHashesFileParam]
+	if strings.TrimSpace(hashesPath) == "" {
+		return nil, skerr.Fmt("missing known hashes file (e.g. '/data/gold-[instance]-hashes.txt')")
+	}
+
+	g.gcsClient = storage.NewLocalClient(storage.GCSClientOptions{
+		KnownHashesGCSPath: hashesPath,
+		Dryrun:             true,
+	})
+	g.ignoreStore = ignoreStore
+	g.changeListStore = changeListStore
+	g.tryJobStore = tryJobStore
+	g.expStore = expStore
+	return g, nil
+}
+
+// newTryjobProcessor returns a goldTryjobProcessor with the clients for the CodeReviewSystem and
+// the ContinuousIntegrationSystems set up. The caller is responsible for setting the stores and
+// the GCSClient.
+func newTryjobProcessor(config *sharedconfig.IngesterConfig, client *http.Client) (*goldTryjobProcessor, error) {
+	crsName := config.ExtraParams[codeReviewSystemParam]
+	if strings.TrimSpace(crsName) == "" {
+		return nil, skerr.Fmt("missing code review system (e.g. 'gerrit')")
+	}
+
+	crs, err := codeReviewSystemFactory(crsName, config, client)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "could not create client for CRS %q", crsName)
+	}
+
+	cisNames := strings.Split(config.ExtraParams[continuousIntegrationSystemsParam], ",")
+	if len(cisNames) == 0 {
+		return nil, skerr.Fmt("missing CI system (e.g. 'buildbucket')")
+	}
+	cisClients := make(map[string]continuous_integration.Client, len(cisNames))
+	for _, cisName := range cisNames {
+		cis, err := continuousIntegrationSystemFactory(cisName, config, client)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "could not create client for CIS %q", cisName)
+		}
+		cisClients[cisName] = cis
+	}
+
+	var gracePeriod time.Duration
+	if gp := strings.TrimSpace(config.ExtraParams[ignoreGracePeriodParam]); gp != "" {
+		gracePeriod, err = time.ParseDuration(gp)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "invalid ignore grace period %q", gp)
+		}
+	}
+
 	return &goldTryjobProcessor{
-		reviewClient:    crs,
-		cisClients:      cisClients,
-		gcsClient:       gsClient,
-		ignoreStore:     fs_ignorestore.New(ctx, fsClient),
-		changeListStore: fs_clstore.New(fsClient, crsName),
-		tryJobStore:     fs_tjstore.New(fsClient),
-		expStore:        expStore,
-		crsName:         crsName,
+		reviewClient:      crs,
+		cisClients:        cisClients,
+		crsName:           crsName,
+		ignoreGracePeriod: gracePeriod,
 	}, nil
 }
 
@@ -184,6 +270,33 @@ func codeReviewSystemFactory(crsName string, config *sharedconfig.IngesterConfig
 		c := httputils.DefaultClientConfig().With2xxOnly().WithTokenSource(githubTS).Client()
 		return github_crs.New(c, githubRepo), nil
 	}
+	if crsName == gitlabCRS {
+		gitlabURL := config.ExtraParams[gitlabURLParam]
+		if strings.TrimSpace(gitlabURL) == "" {
+			return nil, skerr.Fmt("missing URL for the GitLab code review system")
+		}
+		gitlabProject := config.ExtraParams[gitlabProjectParam]
+		if strings.TrimSpace(gitlabProject) == "" {
+			return nil, skerr.Fmt("missing project for the GitLab code review system")
+		}
+		gitlabCredPath := config.ExtraParams[gitlabCredentialsPathParam]
+		if strings.TrimSpace(gitlabCredPath) == "" {
+			return nil, skerr.Fmt("missing credentials path for the GitLab code review system")
+		}
+		gBody, err := ioutil.ReadFile(gitlabCredPath)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "reading gitlabToken in %s", gitlabCredPath)
+		}
+		gToken := strings.TrimSpace(string(gBody))
+		gitlabTS := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: gToken})
+		// The gitlab package interprets non-2xx responses itself.
+		c := httputils.DefaultClientConfig().WithTokenSource(gitlabTS).Client()
+		gitlabClient, err := gitlab.NewGitLab(gitlabURL, gitlabProject, c)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "creating gitlab client for %s", gitlabURL)
+		}
+		return gitlab_crs.New(gitlabClient), nil
+	}
 	return nil, skerr.Fmt("CodeReviewSystem %q not recognized", crsName)
 }
 
@@ -318,7 +431,9 @@ func (g *goldTryjobProcessor) Process(ctx context.Context, rf ingestion.ResultFi
 		if err != nil {
 			return skerr.Wrap(err)
 		}
-		rules, err := ignore.AsMatcher(r)
+		// Only apply the rules which are applied to the master branch, so that digests which
+		// are untriaged there because their rule expired are untriaged here, too.
+		rules, err := ignore.AsMatcher(ignore.AppliedRules(r, time.Now(), g.ignoreGracePeriod))
 		if err != nil {
 			// This should never happen - it means an invalid rule has gotten into the ignore
 			// store.
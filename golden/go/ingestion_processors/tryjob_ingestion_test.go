@@ -101,6 +101,57 @@ func TestGitHubCirrusBuildbucketFactory(t *testing.T) {
 	assert.Contains(t, gtp.cisClients, buildbucketCIS)
 }
 
+func TestGitLabCodeReviewSystemFactory(t *testing.T) {
+	unittest.SmallTest(t)
+
+	config := &sharedconfig.IngesterConfig{
+		ExtraParams: map[string]string{
+			codeReviewSystemParam:      "gitlab",
+			gitlabURLParam:             "https://gitlab.example.com",
+			gitlabProjectParam:         "group/project",
+			gitlabCredentialsPathParam: "testdata/fake_token", // this is actually a file on disk.
+		},
+	}
+
+	crs, err := codeReviewSystemFactory(gitlabCRS, config, httputils.NewTimeoutClient())
+	require.NoError(t, err)
+	assert.Equal(t, "gitlab", crs.System())
+
+	config.ExtraParams[gitlabProjectParam] = ""
+	_, err = codeReviewSystemFactory(gitlabCRS, config, httputils.NewTimeoutClient())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing project")
+}
+
+func TestNewTryjobProcessor_IgnoreGracePeriod(t *testing.T) {
+	unittest.SmallTest(t)
+
+	config := &sharedconfig.IngesterConfig{
+		ExtraParams: map[string]string{
+			codeReviewSystemParam:      "gitlab",
+			gitlabURLParam:             "https://gitlab.example.com",
+			gitlabProjectParam:         "group/project",
+			gitlabCredentialsPathParam: "testdata/fake_token", // this is actually a file on disk.
+
+			continuousIntegrationSystemsParam: "cirrus",
+		},
+	}
+
+	gtp, err := newTryjobProcessor(config, httputils.NewTimeoutClient())
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), gtp.ignoreGracePeriod)
+
+	config.ExtraParams[ignoreGracePeriodParam] = "72h"
+	gtp, err = newTryjobProcessor(config, httputils.NewTimeoutClient())
+	require.NoError(t, err)
+	assert.Equal(t, 72*time.Hour, gtp.ignoreGracePeriod)
+
+	config.ExtraParams[ignoreGracePeriodParam] = "three days"
+	_, err = newTryjobProcessor(config, httputils.NewTimeoutClient())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid ignore grace period")
+}
+
 // TestTryJobProcessFreshStartSunnyDay tests the scenario in which we see data uploaded to Gerrit
 // for a brand new CL, PS, and TryJob. There are no ignore rules and the known digests don't contain
 // gerritDigest.
@@ -521,6 +572,45 @@ func TestTryJobProcess_IngestedResultIgnored(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// TestTryJobProcess_IngestedResultIgnoreRuleExpired tests the case that an ingested result
+// matches an ignore rule which expired longer ago than the grace period. That rule is no longer
+// applied on the master branch, so the result should count as Untriaged, making the PatchSet get
+// marked as having Untriaged digests.
+func TestTryJobProcess_IngestedResultIgnoreRuleExpired(t *testing.T) {
+	unittest.SmallTest(t)
+	mcls := &mock_clstore.Store{}
+	mtjs := &mock_tjstore.Store{}
+	// We want to assert that the Process calls PutPatchSet with HasUntriagedDigests = true,
+	// and PutResults with the appropriate TryJobResults
+	defer mcls.AssertExpectations(t)
+	defer mtjs.AssertExpectations(t)
+
+	mcls.On("GetChangeList", testutils.AnyContext, gerritCLID).Return(makeChangeList(), nil)
+	mcls.On("GetPatchSetByOrder", testutils.AnyContext, gerritCLID, gerritPSOrder).Return(makeGerritPatchSet(false), nil)
+	mcls.On("PutPatchSet", testutils.AnyContext, makeGerritPatchSet(true /* = hasUntriagedDigests*/)).Return(nil)
+
+	mtjs.On("GetTryJob", testutils.AnyContext, gerritTJID, buildbucketCIS).Return(makeGerritBuildbucketTryJob(), nil)
+	mtjs.On("PutResults", testutils.AnyContext, gerritCombinedID, gerritTJID, buildbucketCIS, makeTryJobResults()).Return(nil)
+
+	gtp := goldTryjobProcessor{
+		changeListStore: mcls,
+		tryJobStore:     mtjs,
+		gcsClient:       makeGCSClientWithoutMatchingDigests(t),
+		// The only rule expired in January 2020.
+		ignoreStore:       makeIgnoreStoreWhichIgnoresGerritTrace(),
+		expStore:          makeEmptyExpectations(),
+		cisClients:        makeBuildbucketCIS(),
+		crsName:           gerritCRS,
+		ignoreGracePeriod: 72 * time.Hour,
+	}
+
+	fsResult, err := ingestion_mocks.MockResultFileLocationFromFile(legacyGoldCtlFile)
+	require.NoError(t, err)
+
+	err = gtp.Process(context.Background(), fsResult)
+	require.NoError(t, err)
+}
+
 // TestTryJobProcess_CLIntroducedNewUntriagedDigest tests the cases that an ingested result is
 // part of a TryJob that is in the tjstore and a ChangeList that is in clstore. This result is
 // Untriaged and 1) was not already on master and 2) does not match any ignore rules, so we
@@ -863,7 +953,8 @@ func makeIgnoreStoreWhichIgnoresGerritTrace() *mock_ignorestore.Store {
 			ID:        "abc123123",
 			CreatedBy: "user@example.com",
 			UpdatedBy: "admin@example.com",
-			// This time doesn't matter, we should apply the ignore even if it's expired.
+			// This time doesn't matter unless there is a grace period, we should apply the
+			// ignore even if it's expired.
 			Expires: time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC),
 			Query:   "device_id=0x1cb3",
 			Note:    "This query will match the legacy-tryjob-goldctl.json file",
@@ -0,0 +1,285 @@
+// Package retention removes the images and diff data of digests that are no longer needed.
+// A digest is kept if it is drawn by any trace in the most recent commits, if it is positive in
+// the expectations or if it belongs to an open ChangeList. Everything else is purged from the
+// DiffStore, which removes it from the metrics store, the failure store and the image storage.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+
+	"go.skia.org/infra/go/gcs"
+	"go.skia.org/infra/go/metrics2"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/sklog"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/golden/go/clstore"
+	"go.skia.org/infra/golden/go/diff"
+	"go.skia.org/infra/golden/go/diffstore/common"
+	"go.skia.org/infra/golden/go/expstorage"
+	"go.skia.org/infra/golden/go/tjstore"
+	"go.skia.org/infra/golden/go/tracestore"
+	"go.skia.org/infra/golden/go/types"
+	"go.skia.org/infra/golden/go/types/expectations"
+)
+
+const (
+	// DefaultBatchSize is the number of digests purged at once if Config.BatchSize is not set.
+	DefaultBatchSize = 100
+
+	// clPageSize is how many open ChangeLists are requested at once.
+	clPageSize = 100
+
+	purgedMetric    = "gold_retention_purged_digests"
+	candidateMetric = "gold_retention_purge_candidates"
+	livenessMetric  = "gold_retention"
+)
+
+// DigestSource lists the digests that could be purged.
+type DigestSource interface {
+	// Digests returns all digests that have an image in storage.
+	Digests(ctx context.Context) (types.DigestSet, error)
+}
+
+// Config configures a Collector.
+type Config struct {
+	ChangeListStore   clstore.Store
+	DiffStore         diff.DiffStore
+	DigestSource      DigestSource
+	ExpectationsStore expstorage.ExpectationsStore
+	TraceStore        tracestore.TraceStore
+	TryJobStore       tjstore.Store
+
+	// NCommits is the number of most recent commits whose digests are kept. This is typically a
+	// number of tiles times the tile size.
+	NCommits int
+	// BatchSize is the maximum number of digests purged with one call to the DiffStore.
+	BatchSize int
+	// PurgeGCS means the images are also removed from the image storage, not just from the cache.
+	PurgeGCS bool
+	// DryRun means the digests that would be purged are reported, but nothing is purged.
+	DryRun bool
+}
+
+// Report summarizes one run of the Collector.
+type Report struct {
+	// Candidates is the number of digests returned by the DigestSource.
+	Candidates int
+	// InTile is the number of candidates that were drawn in the last NCommits commits.
+	InTile int
+	// Positive is the number of remaining candidates that are positive in the expectations.
+	Positive int
+	// InOpenCLs is the number of remaining candidates that belong to an open ChangeList.
+	InOpenCLs int
+	// Purged contains the digests that were purged, or would have been purged if DryRun is true.
+	Purged types.DigestSlice
+	// DryRun is true if nothing was purged.
+	DryRun bool
+}
+
+// String returns a human readable summary of the report.
+func (r Report) String() string {
+	verb := "purged"
+	if r.DryRun {
+		verb = "would purge"
+	}
+	return fmt.Sprintf("%d candidates: %d in tile, %d positive, %d in open CLs; %s %d digests",
+		r.Candidates, r.InTile, r.Positive, r.InOpenCLs, verb, len(r.Purged))
+}
+
+// Collector finds and purges the digests that are no longer needed.
+type Collector struct {
+	Config
+}
+
+// New returns a new Collector.
+func New(c Config) *Collector {
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	return &Collector{Config: c}
+}
+
+// Start runs Collect once per interval in a goroutine. The reports are logged.
+func (c *Collector) Start(ctx context.Context, interval time.Duration) {
+	liveness := metrics2.NewLiveness(livenessMetric)
+	go util.RepeatCtx(interval, ctx, func(ctx context.Context) {
+		report, err := c.Collect(ctx)
+		if err != nil {
+			sklog.Errorf("Failed to purge unused digests: %s", err)
+			return
+		}
+		sklog.Infof("Retention: %s", report)
+		if report.DryRun && len(report.Purged) > 0 {
+			sklog.Debugf("Retention dry run would purge: %v", report.Purged)
+		}
+		liveness.Reset()
+	})
+}
+
+// Collect finds the digests that are no longer needed and, unless DryRun is set, purges them in
+// batches. If purging fails, the returned report contains the digests purged before the error.
+func (c *Collector) Collect(ctx context.Context) (Report, error) {
+	defer metrics2.FuncTimer().Stop()
+	candidates, err := c.DigestSource.Digests(ctx)
+	if err != nil {
+		return Report{}, skerr.Wrapf(err, "listing digests")
+	}
+	rv := Report{Candidates: len(candidates), DryRun: c.DryRun}
+
+	tile, _, err := c.TraceStore.GetTile(ctx, c.NCommits)
+	if err != nil {
+		return Report{}, skerr.Wrapf(err, "getting tile with %d commits", c.NCommits)
+	}
+	for _, tr := range tile.Traces {
+		gt, ok := tr.(*types.GoldenTrace)
+		if !ok {
+			continue
+		}
+		for _, d := range gt.Digests {
+			if candidates[d] {
+				delete(candidates, d)
+				rv.InTile++
+			}
+		}
+	}
+
+	exp, err := c.ExpectationsStore.Get(ctx)
+	if err != nil {
+		return Report{}, skerr.Wrapf(err, "getting expectations")
+	}
+	rv.Positive = removePositive(candidates, exp)
+
+	if c.ChangeListStore != nil {
+		n, err := c.removeOpenCLDigests(ctx, candidates)
+		if err != nil {
+			return Report{}, skerr.Wrap(err)
+		}
+		rv.InOpenCLs = n
+	}
+
+	purge := candidates.Keys()
+	sort.Sort(purge)
+	metrics2.GetInt64Metric(candidateMetric, nil).Update(int64(len(purge)))
+	if c.DryRun {
+		rv.Purged = purge
+		return rv, nil
+	}
+
+	purged := metrics2.GetCounter(purgedMetric, nil)
+	for start := 0; start < len(purge); start += c.BatchSize {
+		end := util.MinInt(start+c.BatchSize, len(purge))
+		if err := c.DiffStore.PurgeDigests(ctx, purge[start:end], c.PurgeGCS); err != nil {
+			return rv, skerr.Wrapf(err, "purging %d digests after %d were purged", end-start, start)
+		}
+		rv.Purged = purge[:end]
+		purged.Inc(int64(end - start))
+	}
+	return rv, nil
+}
+
+// removeOpenCLDigests removes the digests that were produced by the TryJobs of an open CL, or
+// that are positive in the expectations of one. It returns how many digests were removed.
+func (c *Collector) removeOpenCLDigests(ctx context.Context, candidates types.DigestSet) (int, error) {
+	crs := c.ChangeListStore.System()
+	n := 0
+	for start := 0; ; start += clPageSize {
+		cls, _, err := c.ChangeListStore.GetChangeLists(ctx, clstore.SearchOptions{
+			StartIdx:    start,
+			Limit:       clPageSize,
+			OpenCLsOnly: true,
+		})
+		if err != nil {
+			return 0, skerr.Wrapf(err, "getting open CLs starting at %d", start)
+		}
+		for _, cl := range cls {
+			exp, err := c.ExpectationsStore.ForChangeList(cl.SystemID, crs).Get(ctx)
+			if err != nil {
+				return 0, skerr.Wrapf(err, "getting expectations for CL %s", cl.SystemID)
+			}
+			n += removePositive(candidates, exp)
+
+			if c.TryJobStore == nil {
+				continue
+			}
+			patchsets, err := c.ChangeListStore.GetPatchSets(ctx, cl.SystemID)
+			if err != nil {
+				return 0, skerr.Wrapf(err, "getting patchsets for CL %s", cl.SystemID)
+			}
+			for _, ps := range patchsets {
+				id := tjstore.CombinedPSID{CL: cl.SystemID, CRS: crs, PS: ps.SystemID}
+				results, err := c.TryJobStore.GetResults(ctx, id)
+				if err != nil {
+					return 0, skerr.Wrapf(err, "getting results for %s", id.Key())
+				}
+				for _, r := range results {
+					if candidates[r.Digest] {
+						delete(candidates, r.Digest)
+						n++
+					}
+				}
+			}
+		}
+		if len(cls) < clPageSize {
+			return n, nil
+		}
+	}
+}
+
+// removePositive removes the digests that are positive in the given expectations for any test.
+// It returns how many digests were removed.
+func removePositive(candidates types.DigestSet, exp expectations.ReadOnly) int {
+	n := 0
+	_ = exp.ForAll(func(_ types.TestName, d types.Digest, l expectations.Label) error {
+		if l == expectations.Positive && candidates[d] {
+			delete(candidates, d)
+			n++
+		}
+		return nil
+	})
+	return n
+}
+
+// GCSImageSource implements DigestSource by listing the images in a directory of a bucket.
+type GCSImageSource struct {
+	client  gcs.GCSClient
+	baseDir string
+	minAge  time.Duration
+}
+
+// NewGCSImageSource returns a DigestSource for the images in the given directory, which is
+// typically diffstore.DefaultGCSImgDir. Images updated less than minAge ago are not listed, so
+// that images which were uploaded, but whose results have not been ingested yet, are not purged.
+func NewGCSImageSource(client gcs.GCSClient, baseDir string, minAge time.Duration) *GCSImageSource {
+	return &GCSImageSource{client: client, baseDir: baseDir, minAge: minAge}
+}
+
+// Digests implements the DigestSource interface.
+func (g *GCSImageSource) Digests(ctx context.Context) (types.DigestSet, error) {
+	rv := types.DigestSet{}
+	ext := "." + common.IMG_EXTENSION
+	now := time.Now()
+	err := g.client.AllFilesInDirectory(ctx, g.baseDir+"/", func(item *storage.ObjectAttrs) {
+		name := path.Base(item.Name)
+		if !strings.HasSuffix(name, ext) {
+			return
+		}
+		if now.Sub(item.Updated) < g.minAge {
+			return
+		}
+		rv[types.Digest(strings.TrimSuffix(name, ext))] = true
+	})
+	if err != nil {
+		return nil, skerr.Wrapf(err, "listing images in %s", g.baseDir)
+	}
+	return rv, nil
+}
+
+// Make sure GCSImageSource fulfills the DigestSource interface.
+var _ DigestSource = (*GCSImageSource)(nil)
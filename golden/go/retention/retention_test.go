@@ -0,0 +1,250 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/gcs"
+	"go.skia.org/infra/go/gcs/fs_gcsclient"
+	"go.skia.org/infra/go/testutils"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/go/tiling"
+	mock_clstore "go.skia.org/infra/golden/go/clstore/mocks"
+	"go.skia.org/infra/golden/go/code_review"
+	mock_diffstore "go.skia.org/infra/golden/go/diffstore/mocks"
+	mock_expstorage "go.skia.org/infra/golden/go/expstorage/mocks"
+	"go.skia.org/infra/golden/go/tjstore"
+	mock_tjstore "go.skia.org/infra/golden/go/tjstore/mocks"
+	"go.skia.org/infra/golden/go/tracestore"
+	"go.skia.org/infra/golden/go/types"
+	"go.skia.org/infra/golden/go/types/expectations"
+)
+
+func TestCollect_DryRun(t *testing.T) {
+	unittest.SmallTest(t)
+
+	c, mds := makeCollector()
+	defer mds.AssertExpectations(t)
+	c.DryRun = true
+
+	report, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Report{
+		Candidates: 8,
+		InTile:     2,
+		Positive:   2,
+		InOpenCLs:  2,
+		Purged:     types.DigestSlice{oldDigest, negativeDigest},
+		DryRun:     true,
+	}, report)
+	assert.Equal(t, "8 candidates: 2 in tile, 2 positive, 2 in open CLs; would purge 2 digests", report.String())
+	// Nothing is purged in a dry run.
+	mds.AssertNotCalled(t, "PurgeDigests", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCollect_PurgesInBatches(t *testing.T) {
+	unittest.SmallTest(t)
+
+	c, mds := makeCollector()
+	defer mds.AssertExpectations(t)
+	c.BatchSize = 1
+	c.PurgeGCS = true
+
+	mds.On("PurgeDigests", testutils.AnyContext, types.DigestSlice{oldDigest}, true).Return(nil).Once()
+	mds.On("PurgeDigests", testutils.AnyContext, types.DigestSlice{negativeDigest}, true).Return(nil).Once()
+
+	report, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, types.DigestSlice{oldDigest, negativeDigest}, report.Purged)
+	assert.False(t, report.DryRun)
+}
+
+func TestCollect_PurgeFailure_ReportsPartialProgress(t *testing.T) {
+	unittest.SmallTest(t)
+
+	c, mds := makeCollector()
+	defer mds.AssertExpectations(t)
+	c.BatchSize = 1
+
+	mds.On("PurgeDigests", testutils.AnyContext, types.DigestSlice{oldDigest}, false).Return(nil).Once()
+	mds.On("PurgeDigests", testutils.AnyContext, types.DigestSlice{negativeDigest}, false).Return(errors.New("disk full")).Once()
+
+	report, err := c.Collect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk full")
+	assert.Equal(t, types.DigestSlice{oldDigest}, report.Purged)
+}
+
+func TestCollect_NoChangeListStore(t *testing.T) {
+	unittest.SmallTest(t)
+
+	c, _ := makeCollector()
+	c.ChangeListStore = nil
+	c.TryJobStore = nil
+	c.DryRun = true
+
+	report, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.InOpenCLs)
+	assert.Equal(t, types.DigestSlice{oldDigest, negativeDigest, clDigest, clPositiveDigest}, report.Purged)
+}
+
+func TestGCSImageSource(t *testing.T) {
+	unittest.MediumTest(t)
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	client, err := fs_gcsclient.New(dir)
+	require.NoError(t, err)
+	for _, p := range []string{
+		"dm-images-v1/" + string(oldDigest) + ".png",
+		"dm-images-v1/" + string(tileDigest) + ".png",
+		"dm-images-v1/not_an_image.txt",
+		"other/" + string(clDigest) + ".png",
+	} {
+		require.NoError(t, client.SetFileContents(ctx, p, gcs.FileWriteOptions{}, []byte("png")))
+	}
+
+	digests, err := NewGCSImageSource(client, "dm-images-v1", 0).Digests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, types.DigestSet{oldDigest: true, tileDigest: true}, digests)
+}
+
+// TestCollect_FreshImage_NotPurged tests that an image which was just uploaded is not purged,
+// even though it is not referenced by anything yet, because its results may not be ingested yet.
+func TestCollect_FreshImage_NotPurged(t *testing.T) {
+	unittest.MediumTest(t)
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	client, err := fs_gcsclient.New(dir)
+	require.NoError(t, err)
+	for _, d := range []types.Digest{oldDigest, negativeDigest} {
+		require.NoError(t, client.SetFileContents(ctx, "dm-images-v1/"+string(d)+".png", gcs.FileWriteOptions{}, []byte("png")))
+	}
+	// Only the image of oldDigest was uploaded long enough ago.
+	twoDaysAgo := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "dm-images-v1", string(oldDigest)+".png"), twoDaysAgo, twoDaysAgo))
+
+	mds := &mock_diffstore.DiffStore{}
+	defer mds.AssertExpectations(t)
+	mds.On("PurgeDigests", testutils.AnyContext, types.DigestSlice{oldDigest}, true).Return(nil)
+
+	mes := &mock_expstorage.ExpectationsStore{}
+	mes.On("Get", testutils.AnyContext).Return(&expectations.Expectations{}, nil)
+
+	c := New(Config{
+		DiffStore:         mds,
+		DigestSource:      NewGCSImageSource(client, "dm-images-v1", 24*time.Hour),
+		ExpectationsStore: mes,
+		TraceStore:        fakeTraceStore{tile: &tiling.Tile{}},
+		NCommits:          4,
+		PurgeGCS:          true,
+	})
+	report, err := c.Collect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Candidates)
+	assert.Equal(t, types.DigestSlice{oldDigest}, report.Purged)
+}
+
+// makeCollector returns a Collector where each of the candidates is kept for a different reason,
+// except for negativeDigest and oldDigest, which should be purged.
+func makeCollector() (*Collector, *mock_diffstore.DiffStore) {
+	mds := &mock_diffstore.DiffStore{}
+
+	var exp expectations.Expectations
+	exp.Set(testName, positiveDigest, expectations.Positive)
+	exp.Set(testName, otherPositiveDigest, expectations.Positive)
+	exp.Set(testName, negativeDigest, expectations.Negative)
+	// tileDigest is also positive, but it is counted as in the tile.
+	exp.Set(testName, tileDigest, expectations.Positive)
+	var clExp expectations.Expectations
+	clExp.Set(testName, clPositiveDigest, expectations.Positive)
+
+	mes := &mock_expstorage.ExpectationsStore{}
+	clMes := &mock_expstorage.ExpectationsStore{}
+	mes.On("Get", testutils.AnyContext).Return(&exp, nil)
+	mes.On("ForChangeList", "1234", "gerrit").Return(clMes)
+	clMes.On("Get", testutils.AnyContext).Return(&clExp, nil)
+
+	mcs := &mock_clstore.Store{}
+	mcs.On("System").Return("gerrit")
+	mcs.On("GetChangeLists", testutils.AnyContext, mock.Anything).Return([]code_review.ChangeList{
+		{SystemID: "1234", Status: code_review.Open},
+	}, 1, nil)
+	mcs.On("GetPatchSets", testutils.AnyContext, "1234").Return([]code_review.PatchSet{
+		{SystemID: "ps1", ChangeListID: "1234", Order: 1},
+	}, nil)
+
+	mts := &mock_tjstore.Store{}
+	mts.On("GetResults", testutils.AnyContext, tjstore.CombinedPSID{CL: "1234", CRS: "gerrit", PS: "ps1"}).Return([]tjstore.TryJobResult{
+		{Digest: clDigest},
+	}, nil)
+
+	return New(Config{
+		ChangeListStore:   mcs,
+		DiffStore:         mds,
+		DigestSource:      fakeSource{oldDigest, tileDigest, otherTileDigest, positiveDigest, otherPositiveDigest, negativeDigest, clDigest, clPositiveDigest},
+		ExpectationsStore: mes,
+		TraceStore: fakeTraceStore{tile: &tiling.Tile{
+			Traces: map[tiling.TraceID]tiling.Trace{
+				",name=test,": types.NewGoldenTrace([]types.Digest{tileDigest, types.MISSING_DIGEST, otherTileDigest, notInSourceDigest}, nil),
+			},
+		}},
+		TryJobStore: mts,
+		NCommits:    4,
+	}), mds
+}
+
+// fakeSource is a DigestSource with a fixed list of digests.
+type fakeSource []types.Digest
+
+// Digests implements the DigestSource interface.
+func (f fakeSource) Digests(_ context.Context) (types.DigestSet, error) {
+	rv := types.DigestSet{}
+	for _, d := range f {
+		rv[d] = true
+	}
+	return rv, nil
+}
+
+// fakeTraceStore is a TraceStore that always returns the same tile.
+type fakeTraceStore struct {
+	tile *tiling.Tile
+}
+
+func (f fakeTraceStore) Put(_ context.Context, _ string, _ []*tracestore.Entry, _ time.Time) error {
+	return errors.New("not implemented")
+}
+
+func (f fakeTraceStore) GetTile(_ context.Context, _ int) (*tiling.Tile, []*tiling.Commit, error) {
+	return f.tile, nil, nil
+}
+
+func (f fakeTraceStore) GetDenseTile(_ context.Context, _ int) (*tiling.Tile, []*tiling.Commit, error) {
+	return f.tile, nil, nil
+}
+
+const (
+	testName = types.TestName("test")
+
+	oldDigest           = types.Digest("00000000000000000000000000000000")
+	tileDigest          = types.Digest("11111111111111111111111111111111")
+	otherTileDigest     = types.Digest("22222222222222222222222222222222")
+	positiveDigest      = types.Digest("33333333333333333333333333333333")
+	otherPositiveDigest = types.Digest("44444444444444444444444444444444")
+	negativeDigest      = types.Digest("55555555555555555555555555555555")
+	clDigest            = types.Digest("66666666666666666666666666666666")
+	clPositiveDigest    = types.Digest("77777777777777777777777777777777")
+	notInSourceDigest   = types.Digest("88888888888888888888888888888888")
+)
@@ -73,6 +73,7 @@ func ParseSearch(r *http.Request, q *Search) error {
 	q.Unt = r.FormValue("unt") == "true"
 	q.Head = r.FormValue("head") == "true"
 	q.IncludeIgnores = r.FormValue("include") == "true"
+	q.ExcludeFlaky = r.FormValue("exclude_flaky") == "true"
 	q.IncludeMaster = r.FormValue("master") == "true"
 
 	// Extract the filter values.
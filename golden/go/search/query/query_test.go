@@ -56,6 +56,20 @@ func TestParseQuery(t *testing.T) {
 	}, q)
 }
 
+// TestParseQueryFlaky makes sure the flaky blame group and the flag to exclude flaky traces
+// are parsed.
+func TestParseQueryFlaky(t *testing.T) {
+	unittest.SmallTest(t)
+
+	q := &Search{}
+	require.NoError(t, clearParseQuery(q, "blame=flaky&unt=true&exclude_flaky=true"))
+	require.Equal(t, "flaky", q.BlameGroupID)
+	require.True(t, q.ExcludeFlaky)
+
+	require.NoError(t, clearParseQuery(q, "unt=true"))
+	require.False(t, q.ExcludeFlaky)
+}
+
 // TestParseSearchValidList checks a list of queries from live data
 // processes as valid.
 func TestParseSearchValidList(t *testing.T) {
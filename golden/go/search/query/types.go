@@ -25,6 +25,9 @@ type Search struct {
 	Unt            bool `json:"unt"`
 	IncludeIgnores bool `json:"include"`
 
+	// ExcludeFlaky excludes the traces that were detected or marked as flaky.
+	ExcludeFlaky bool `json:"exclude_flaky"`
+
 	// URL encoded query string
 	QueryStr    string     `json:"query"`
 	TraceValues url.Values `json:"-"`
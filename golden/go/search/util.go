@@ -11,6 +11,7 @@ import (
 	"go.skia.org/infra/go/util"
 	"go.skia.org/infra/golden/go/blame"
 	"go.skia.org/infra/golden/go/digest_counter"
+	"go.skia.org/infra/golden/go/flaky"
 	"go.skia.org/infra/golden/go/indexer"
 	"go.skia.org/infra/golden/go/search/frontend"
 	"go.skia.org/infra/golden/go/search/query"
@@ -50,6 +51,11 @@ func iterTile(ctx context.Context, q *query.Search, addFn addFn, acceptFn accept
 	// traces is pre-sliced by corpus and test name, if provided.
 	traces := idx.SlicedTraces(q.IgnoreState(), q.TraceValues)
 	digestCountsByTrace := idx.DigestCountsByTrace(q.IgnoreState())
+	// The flaky traces are only needed if the query filters by them.
+	var flakyIdx *flaky.Index
+	if q.ExcludeFlaky || q.BlameGroupID != "" {
+		flakyIdx = idx.FlakyTraces()
+	}
 
 	const numChunks = 4 // arbitrarily picked, could likely be tuned based on contention of the
 	// mutexes in addFn/acceptFn
@@ -62,6 +68,9 @@ func iterTile(ctx context.Context, q *query.Search, addFn addFn, acceptFn accept
 				return skerr.Wrap(err)
 			}
 			id, trace := tp.ID, tp.Trace
+			if q.ExcludeFlaky && flakyIdx.IsFlaky(id) {
+				continue
+			}
 			// Check if the query matches.
 			if tiling.Matches(trace, q.TraceValues) {
 				params := trace.Params()
@@ -86,6 +95,19 @@ func iterTile(ctx context.Context, q *query.Search, addFn addFn, acceptFn accept
 					// Fix blamer to make this easier.
 					if q.BlameGroupID != "" {
 						if cl == expectations.Untriaged {
+							// Untriaged digests that were only drawn by flaky traces are
+							// grouped together instead of being blamed on a commit.
+							onlyFlaky := flakyIdx.OnlyInFlakyTraces(test, digest)
+							if q.BlameGroupID == flaky.GroupID {
+								if !onlyFlaky {
+									continue
+								}
+								addFn(test, digest, id, trace, acceptRet)
+								continue
+							}
+							if onlyFlaky {
+								continue
+							}
 							b := idx.GetBlame(test, digest, cpxTile.DataCommits())
 							if b.IsEmpty() || q.BlameGroupID != blameGroupID(b, cpxTile.DataCommits()) {
 								continue
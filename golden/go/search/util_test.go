@@ -1,13 +1,21 @@
 package search
 
 import (
+	"context"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.skia.org/infra/go/paramtools"
 	"go.skia.org/infra/go/testutils/unittest"
 	"go.skia.org/infra/go/tiling"
+	"go.skia.org/infra/golden/go/blame"
+	"go.skia.org/infra/golden/go/digest_counter"
+	"go.skia.org/infra/golden/go/flaky"
+	mock_index "go.skia.org/infra/golden/go/indexer/mocks"
+	"go.skia.org/infra/golden/go/search/query"
 	data "go.skia.org/infra/golden/go/testutils/data_three_devices"
 	"go.skia.org/infra/golden/go/types"
 )
@@ -176,3 +184,51 @@ func TestIntermediate(t *testing.T) {
 		},
 	}, srMap)
 }
+
+// TestIterTileFlaky makes sure that flaky traces can be excluded and that the untriaged digests
+// that were only drawn by flaky traces are in the flaky blame group instead of a regular one.
+func TestIterTileFlaky(t *testing.T) {
+	unittest.SmallTest(t)
+
+	mis := &mock_index.IndexSearcher{}
+	defer mis.AssertExpectations(t)
+
+	tile := data.MakeTestTile()
+	cpxTile := types.NewComplexTile(tile)
+	var traces []*types.TracePair
+	for id, tr := range tile.Traces {
+		traces = append(traces, &types.TracePair{ID: id, Trace: tr.(*types.GoldenTrace)})
+	}
+	// AlphaUntriaged1Digest is only drawn by bullhead.
+	flakyIdx := flaky.NewIndex(cpxTile, []flaky.Mark{{TraceID: data.BullheadAlphaTraceID}})
+
+	mis.On("Tile").Return(cpxTile)
+	mis.On("SlicedTraces", types.ExcludeIgnoredTraces, mock.Anything).Return(traces)
+	mis.On("DigestCountsByTrace", types.ExcludeIgnoredTraces).Return(digest_counter.New(tile).ByTrace())
+	mis.On("FlakyTraces").Return(flakyIdx)
+	// Only the untriaged digest that was not drawn by the flaky trace is blamed. An empty blame
+	// never matches a blame group.
+	mis.On("GetBlame", data.BetaTest, data.BetaUntriaged1Digest, mock.Anything).Return(blame.BlameDistribution{})
+
+	test := func(name string, q *query.Search, expected map[tiling.TraceID]types.Digest) {
+		t.Run(name, func(t *testing.T) {
+			mutex := sync.Mutex{}
+			actual := map[tiling.TraceID]types.Digest{}
+			addFn := func(test types.TestName, digest types.Digest, traceID tiling.TraceID, trace *types.GoldenTrace, _ interface{}) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				actual[traceID] = digest
+			}
+			require.NoError(t, iterTile(context.Background(), q, addFn, nil, data.MakeTestExpectations(), mis))
+			assert.Equal(t, expected, actual)
+		})
+	}
+
+	test("exclude flaky", &query.Search{Unt: true, ExcludeFlaky: true}, map[tiling.TraceID]types.Digest{
+		data.CrosshatchBetaTraceID: data.BetaUntriaged1Digest,
+	})
+	test("flaky blame group", &query.Search{Unt: true, BlameGroupID: flaky.GroupID}, map[tiling.TraceID]types.Digest{
+		data.BullheadAlphaTraceID: data.AlphaUntriaged1Digest,
+	})
+	test("regular blame group", &query.Search{Unt: true, BlameGroupID: "1:0"}, map[tiling.TraceID]types.Digest{})
+}
@@ -24,6 +24,13 @@ const (
 	// TODO(lovisolo): Remove this when goldctl is fully migrated.
 	ExpectationsLegacyRoute = "/json/expectations/commit/{commit_hash}"
 
+	// ExpectationsExportRoute serves all expectations of the master branch with their triage
+	// attribution, in a form that can be imported by another instance.
+	ExpectationsExportRoute = "/json/expectations/export"
+
+	// ExpectationsImportRoute adds previously exported expectations to the master branch.
+	ExpectationsImportRoute = "/json/expectations/import"
+
 	// KnownHashesRoute serves the list of known hashes.
 	KnownHashesRoute = "/json/hashes"
 )
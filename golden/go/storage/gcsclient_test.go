@@ -5,12 +5,14 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils"
 	"go.skia.org/infra/go/testutils/unittest"
 	"go.skia.org/infra/golden/go/types"
 )
@@ -93,3 +95,23 @@ func loadKnownHashes(t *testing.T, gsClient GCSClient) types.DigestSlice {
 	}
 	return ret
 }
+
+func TestLocalWritingReadingHashes(t *testing.T) {
+	unittest.MediumTest(t)
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	c := NewLocalClient(GCSClientOptions{
+		KnownHashesGCSPath: filepath.Join(dir, "known-hashes.txt"),
+	})
+	// A missing file is treated as no known digests.
+	assert.Empty(t, loadKnownHashes(t, c))
+
+	knownDigests := types.DigestSlice{
+		"c003788f8d306ff1226e2a460835dae4",
+		"885b31941c25efc313b0fd66d55b86d9",
+		"264d0d87b12ba337f796fc592cd5357d",
+	}
+	require.NoError(t, c.WriteKnownDigests(context.Background(), knownDigests))
+	assert.Equal(t, knownDigests, loadKnownHashes(t, c))
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/sklog"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/golden/go/types"
+)
+
+// LocalClientImpl implements the GCSClient interface by storing the known digests in a file on
+// the local file system, for deployments that don't have access to Google Cloud Storage. The
+// KnownHashesGCSPath option is interpreted as the path of that file.
+type LocalClientImpl struct {
+	options GCSClientOptions
+}
+
+// NewLocalClient creates a new instance of LocalClientImpl.
+func NewLocalClient(options GCSClientOptions) *LocalClientImpl {
+	return &LocalClientImpl{
+		options: options,
+	}
+}
+
+// Options implements the GCSClient interface.
+func (l *LocalClientImpl) Options() GCSClientOptions {
+	return l.options
+}
+
+// WriteKnownDigests fulfills the GCSClient interface.
+func (l *LocalClientImpl) WriteKnownDigests(ctx context.Context, digests types.DigestSlice) error {
+	if l.options.Dryrun {
+		sklog.Infof("dryrun: Writing %d digests", len(digests))
+		return nil
+	}
+	// Only write the known digests if a target path was given.
+	if l.options.KnownHashesGCSPath == "" {
+		return nil
+	}
+	err := util.WithWriteFile(l.options.KnownHashesGCSPath, func(w io.Writer) error {
+		for _, digest := range digests {
+			if _, err := w.Write([]byte(digest + "\n")); err != nil {
+				return skerr.Wrapf(err, "writing digests")
+			}
+		}
+		return nil
+	})
+	return skerr.Wrapf(err, "writing digests to %s", l.options.KnownHashesGCSPath)
+}
+
+// LoadKnownDigests fulfills the GCSClient interface.
+func (l *LocalClientImpl) LoadKnownDigests(ctx context.Context, w io.Writer) error {
+	b, err := ioutil.ReadFile(l.options.KnownHashesGCSPath)
+	if err != nil {
+		// We simply assume an empty hashes file if the file was not found.
+		if os.IsNotExist(err) {
+			sklog.Warningf("No known digests found - maybe %s is a wrong path?", l.options.KnownHashesGCSPath)
+			return nil
+		}
+		return skerr.Wrapf(err, "reading digests from %s", l.options.KnownHashesGCSPath)
+	}
+	n, err := w.Write(b)
+	return skerr.Wrapf(err, "writing %d bytes to writer", n)
+}
+
+// Ensure LocalClientImpl fulfills the GCSClient interface.
+var _ GCSClient = (*LocalClientImpl)(nil)
@@ -43,6 +43,10 @@ type CachedTileSourceConfig struct {
 	// NCommits is the number of commits we should consider. If NCommits is
 	// 0 or smaller all commits in the last tile will be considered.
 	NCommits int
+
+	// IgnoreGracePeriod is how long an ignore rule is still applied after it expires. If it is
+	// 0 or smaller, expired rules are applied forever (see ignore.Rule.IsApplied).
+	IgnoreGracePeriod time.Duration
 }
 
 type CachedTileSourceImpl struct {
@@ -118,6 +122,7 @@ func (s *CachedTileSourceImpl) updateTile(ctx context.Context) error {
 	if err != nil {
 		return skerr.Wrapf(err, "fetching ignore rules")
 	}
+	ignores = ignore.AppliedRules(ignores, time.Now(), s.IgnoreGracePeriod)
 	retIgnoredTile, ignoreRules, err := ignore.FilterIgnored(denseTile, ignores)
 	if err != nil {
 		return skerr.Wrapf(err, "applying ignore rules to tile")
@@ -391,6 +391,59 @@ func TestUpdateTileWithRules(t *testing.T) {
 	assert.Equal(t, "1", metrics_utils.GetRecordedMetric(t, emptyCommitsAtHeadMetric, nil))
 }
 
+// TestUpdateTileWithExpiredRules tests that rules which expired longer than the grace period ago
+// are no longer applied.
+func TestUpdateTileWithExpiredRules(t *testing.T) {
+	unittest.SmallTest(t)
+
+	mis := &mock_ignorestore.Store{}
+	mts := &mocks.TraceStore{}
+	mvcs := &mock_vcs.VCS{}
+	mct := &mocks.ComplexTile{}
+	defer mis.AssertExpectations(t)
+	defer mts.AssertExpectations(t)
+	defer mvcs.AssertExpectations(t)
+	defer mct.AssertExpectations(t)
+
+	mvcs.On("Update", testutils.AnyContext, true, false).Return(nil)
+
+	mts.On("GetDenseTile", testutils.AnyContext, nCommits).Return(data.MakeTestTile(), makeSparseTilingCommits(), nil)
+
+	mis.On("List", testutils.AnyContext).Return([]ignore.Rule{
+		{
+			Query:   "device=crosshatch&name=test_beta", // hides one trace
+			Expires: time.Now().Add(-time.Hour),         // still within the grace period
+		},
+		{
+			Query:   "device=angler",                      // would hide two traces
+			Expires: time.Now().Add(-30 * 24 * time.Hour), // no longer applied
+		},
+	}, nil)
+
+	mct.On("AllCommits").Return(makeSparseTilingCommits())
+
+	ts := New(CachedTileSourceConfig{
+		NCommits:          nCommits,
+		IgnoreStore:       mis,
+		TraceStore:        mts,
+		VCS:               mvcs,
+		IgnoreGracePeriod: 7 * 24 * time.Hour,
+	})
+	// Pretend there was a tile previously.
+	ts.lastCpxTile = mct
+
+	err := ts.updateTile(context.Background())
+	require.NoError(t, err)
+
+	cpxTile := ts.GetTile()
+	require.NotNil(t, cpxTile)
+
+	trimmedTile := data.MakeTestTile()
+	delete(trimmedTile.Traces, data.CrosshatchBetaTraceID)
+	assert.Equal(t, trimmedTile, cpxTile.GetTile(types.ExcludeIgnoredTraces))
+	assert.Equal(t, data.MakeTestTile(), cpxTile.GetTile(types.IncludeIgnoredTraces))
+}
+
 const (
 	// zerothCommitHash and fourthCommitHash are commits with no data, bolted on to the data in
 	// three_devices_data to emulate "sparse" commits.
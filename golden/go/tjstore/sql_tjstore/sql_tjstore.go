@@ -0,0 +1,273 @@
+// Package sql_tjstore implements the tjstore.Store interface with an SQL (SQLite or Postgres)
+// backend.
+package sql_tjstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+
+	"go.skia.org/infra/go/metrics2"
+	"go.skia.org/infra/go/paramtools"
+	"go.skia.org/infra/go/query"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+	ci "go.skia.org/infra/golden/go/continuous_integration"
+	"go.skia.org/infra/golden/go/sql_utils"
+	"go.skia.org/infra/golden/go/tjstore"
+)
+
+const emptyParamsHash = ""
+
+// schema creates the tables for TryJobs and TryJobResults. The GroupParams and Options of the
+// results are shared by many results, so they are stored once in TryJobParams, keyed by a hash
+// of their content. All params are stored as JSON.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS TryJobs (
+	cis           TEXT NOT NULL,
+	id            TEXT NOT NULL,
+	crs           TEXT NOT NULL,
+	cl_id         TEXT NOT NULL,
+	ps_id         TEXT NOT NULL,
+	display_name  TEXT NOT NULL,
+	updated       BIGINT NOT NULL,
+	PRIMARY KEY (cis, id)
+)`,
+	`CREATE INDEX IF NOT EXISTS TryJobsByPatchSet ON TryJobs (crs, cl_id, ps_id)`,
+	`CREATE TABLE IF NOT EXISTS TryJobResults (
+	crs            TEXT NOT NULL,
+	cl_id          TEXT NOT NULL,
+	ps_id          TEXT NOT NULL,
+	cis            TEXT NOT NULL,
+	tj_id          TEXT NOT NULL,
+	digest         TEXT NOT NULL,
+	result_params  TEXT NOT NULL,
+	group_hash     TEXT NOT NULL,
+	options_hash   TEXT NOT NULL
+)`,
+	`CREATE INDEX IF NOT EXISTS TryJobResultsByPatchSet ON TryJobResults (crs, cl_id, ps_id)`,
+	`CREATE TABLE IF NOT EXISTS TryJobParams (
+	hash    TEXT PRIMARY KEY,
+	params  TEXT NOT NULL
+)`,
+}
+
+// StoreImpl is the SQL based implementation of tjstore.
+type StoreImpl struct {
+	db *sql.DB
+}
+
+// New returns a new StoreImpl, creating the tables if they don't already exist.
+func New(db *sql.DB) (*StoreImpl, error) {
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, skerr.Wrapf(err, "creating tjstore tables")
+		}
+	}
+	return &StoreImpl{db: db}, nil
+}
+
+// GetTryJob implements the tjstore.Store interface.
+func (s *StoreImpl) GetTryJob(ctx context.Context, id, cisName string) (ci.TryJob, error) {
+	defer metrics2.FuncTimer().Stop()
+	tj := ci.TryJob{
+		SystemID: id,
+		System:   cisName,
+	}
+	var updated int64
+	err := s.db.QueryRowContext(ctx, `SELECT display_name, updated FROM TryJobs WHERE cis=$1 AND id=$2`, cisName, id).Scan(&tj.DisplayName, &updated)
+	if err == sql.ErrNoRows {
+		return ci.TryJob{}, tjstore.ErrNotFound
+	} else if err != nil {
+		return ci.TryJob{}, skerr.Wrapf(err, "retrieving TryJob %s from %s", id, cisName)
+	}
+	tj.Updated = sql_utils.FromTimestamp(updated)
+	return tj, nil
+}
+
+// GetTryJobs implements the tjstore.Store interface.
+func (s *StoreImpl) GetTryJobs(ctx context.Context, psID tjstore.CombinedPSID) ([]ci.TryJob, error) {
+	defer metrics2.FuncTimer().Stop()
+	rows, err := s.db.QueryContext(ctx, `SELECT id, cis, display_name, updated FROM TryJobs WHERE crs=$1 AND cl_id=$2 AND ps_id=$3`,
+		psID.CRS, psID.CL, psID.PS)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "fetching tryjobs for cl/ps %s", psID.Key())
+	}
+	defer util.Close(rows)
+	var xtj []ci.TryJob
+	for rows.Next() {
+		tj := ci.TryJob{}
+		var updated int64
+		if err := rows.Scan(&tj.SystemID, &tj.System, &tj.DisplayName, &updated); err != nil {
+			return nil, skerr.Wrapf(err, "reading tryjob for cl/ps %s", psID.Key())
+		}
+		tj.Updated = sql_utils.FromTimestamp(updated)
+		xtj = append(xtj, tj)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, skerr.Wrapf(err, "fetching tryjobs for cl/ps %s", psID.Key())
+	}
+	ci.SortTryJobsByName(xtj)
+	return xtj, nil
+}
+
+// GetResults implements the tjstore.Store interface.
+func (s *StoreImpl) GetResults(ctx context.Context, psID tjstore.CombinedPSID) ([]tjstore.TryJobResult, error) {
+	defer metrics2.FuncTimer().Stop()
+	// The empty params have no row in TryJobParams, hence the outer joins.
+	rows, err := s.db.QueryContext(ctx, `SELECT r.digest, r.result_params, g.params, o.params FROM TryJobResults r
+		LEFT JOIN TryJobParams g ON r.group_hash = g.hash
+		LEFT JOIN TryJobParams o ON r.options_hash = o.hash
+		WHERE r.crs=$1 AND r.cl_id=$2 AND r.ps_id=$3`, psID.CRS, psID.CL, psID.PS)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "fetching tryjob results for %v", psID)
+	}
+	defer util.Close(rows)
+
+	// Share the decoded GroupParams and Options between results, as the interface allows.
+	paramsByJSON := map[string]paramtools.Params{}
+	decode := func(s sql.NullString) (paramtools.Params, error) {
+		if !s.Valid {
+			return paramtools.Params{}, nil
+		}
+		if p, ok := paramsByJSON[s.String]; ok {
+			return p, nil
+		}
+		p := paramtools.Params{}
+		if err := json.Unmarshal([]byte(s.String), &p); err != nil {
+			return nil, skerr.Wrapf(err, "corrupt params %q", s.String)
+		}
+		paramsByJSON[s.String] = p
+		return p, nil
+	}
+
+	var ret []tjstore.TryJobResult
+	for rows.Next() {
+		tr := tjstore.TryJobResult{}
+		var resultParams string
+		var groupParams, options sql.NullString
+		if err := rows.Scan(&tr.Digest, &resultParams, &groupParams, &options); err != nil {
+			return nil, skerr.Wrapf(err, "reading tryjob result for %v", psID)
+		}
+		if err := json.Unmarshal([]byte(resultParams), &tr.ResultParams); err != nil {
+			return nil, skerr.Wrapf(err, "corrupt result params %q", resultParams)
+		}
+		if tr.GroupParams, err = decode(groupParams); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		if tr.Options, err = decode(options); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		ret = append(ret, tr)
+	}
+	return ret, skerr.Wrap(rows.Err())
+}
+
+// PutTryJob implements the tjstore.Store interface.
+func (s *StoreImpl) PutTryJob(ctx context.Context, psID tjstore.CombinedPSID, tj ci.TryJob) error {
+	defer metrics2.FuncTimer().Stop()
+	_, err := s.db.ExecContext(ctx, `INSERT INTO TryJobs (cis, id, crs, cl_id, ps_id, display_name, updated) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (cis, id) DO UPDATE SET crs=excluded.crs, cl_id=excluded.cl_id, ps_id=excluded.ps_id,
+		display_name=excluded.display_name, updated=excluded.updated`,
+		tj.System, tj.SystemID, psID.CRS, psID.CL, psID.PS, tj.DisplayName, sql_utils.ToTimestamp(tj.Updated))
+	if err != nil {
+		return skerr.Wrapf(err, "could not write TryJob %v to tjstore", tj)
+	}
+	return nil
+}
+
+// PutResults implements the tjstore.Store interface. All the results are written in a single
+// transaction, so unlike the Firestore implementation there is no partial success.
+func (s *StoreImpl) PutResults(ctx context.Context, psID tjstore.CombinedPSID, tjID, cisName string, r []tjstore.TryJobResult) error {
+	if len(r) == 0 {
+		return nil
+	}
+	defer metrics2.FuncTimer().Stop()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return skerr.Wrapf(err, "starting transaction")
+	}
+	if err := putResults(ctx, tx, psID, tjID, cisName, r); err != nil {
+		_ = tx.Rollback()
+		return skerr.Wrap(err)
+	}
+	return skerr.Wrapf(tx.Commit(), "committing %d results", len(r))
+}
+
+// putResults writes the results and their params using the given transaction.
+func putResults(ctx context.Context, tx *sql.Tx, psID tjstore.CombinedPSID, tjID, cisName string, r []tjstore.TryJobResult) error {
+	paramsStmt, err := tx.PrepareContext(ctx, `INSERT INTO TryJobParams (hash, params) VALUES ($1, $2) ON CONFLICT (hash) DO NOTHING`)
+	if err != nil {
+		return skerr.Wrapf(err, "preparing params insert")
+	}
+	defer util.Close(paramsStmt)
+	resultStmt, err := tx.PrepareContext(ctx, `INSERT INTO TryJobResults (crs, cl_id, ps_id, cis, tj_id, digest, result_params, group_hash, options_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
+	if err != nil {
+		return skerr.Wrapf(err, "preparing results insert")
+	}
+	defer util.Close(resultStmt)
+
+	stored := map[string]bool{emptyParamsHash: true}
+	storeParams := func(p paramtools.Params) (string, error) {
+		h, err := hashParams(p)
+		if err != nil {
+			return "", skerr.Wrap(err)
+		}
+		if stored[h] {
+			return h, nil
+		}
+		b, err := json.Marshal(p)
+		if err != nil {
+			return "", skerr.Wrap(err)
+		}
+		if _, err := paramsStmt.ExecContext(ctx, h, string(b)); err != nil {
+			return "", skerr.Wrapf(err, "writing params %v", p)
+		}
+		stored[h] = true
+		return h, nil
+	}
+
+	for _, tr := range r {
+		gh, err := storeParams(tr.GroupParams)
+		if err != nil {
+			return skerr.Wrapf(err, "storing group params of %v", tr)
+		}
+		oh, err := storeParams(tr.Options)
+		if err != nil {
+			return skerr.Wrapf(err, "storing options params of %v", tr)
+		}
+		rp := tr.ResultParams
+		if rp == nil {
+			rp = paramtools.Params{}
+		}
+		b, err := json.Marshal(rp)
+		if err != nil {
+			return skerr.Wrapf(err, "encoding result params of %v", tr)
+		}
+		if _, err := resultStmt.ExecContext(ctx, psID.CRS, psID.CL, psID.PS, cisName, tjID, tr.Digest, string(b), gh, oh); err != nil {
+			return skerr.Wrapf(err, "writing result %v", tr)
+		}
+	}
+	return nil
+}
+
+// hashParams returns a hex-encoded sha256 hash of the contents of the map in a
+// deterministic fashion. It uses the fact that the query package can deterministically
+// turn a map into a trace key, and hashes that output.
+func hashParams(m paramtools.Params) (string, error) {
+	if len(m) == 0 {
+		return emptyParamsHash, nil
+	}
+	s, err := query.MakeKeyFast(m)
+	if err != nil {
+		return "", skerr.Wrapf(err, "flattening map")
+	}
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:]), nil
+}
+
+// Make sure StoreImpl fulfills the tjstore.Store interface.
+var _ tjstore.Store = (*StoreImpl)(nil)
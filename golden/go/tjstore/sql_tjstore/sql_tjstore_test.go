@@ -0,0 +1,79 @@
+package sql_tjstore
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/paramtools"
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/golden/go/sql_utils"
+	"go.skia.org/infra/golden/go/tjstore/shared_tests"
+)
+
+func TestSharedTests_SQLite(t *testing.T) {
+	unittest.MediumTest(t)
+	runSharedTests(t, sql_utils.NewSQLiteForTesting)
+}
+
+func TestSharedTests_Postgres(t *testing.T) {
+	unittest.LargeTest(t) // should use a Postgres database
+	runSharedTests(t, sql_utils.NewPostgresForTesting)
+}
+
+// runSharedTests runs each of the shared tests with a Store in a new database.
+func runSharedTests(t *testing.T, newDB func(sktest.TestingT) (*sql.DB, func())) {
+	for name, test := range shared_tests.Tests {
+		t.Run(name, func(t *testing.T) {
+			db, cleanup := newDB(t)
+			defer cleanup()
+
+			test(t, newStore(t, db))
+		})
+	}
+}
+
+// TestConsistentParamsHashing makes sure we consistently hash a Params map to the same
+// value - this is vital for making sure we can re-assemble the TestResults
+func TestConsistentParamsHashing(t *testing.T) {
+	unittest.SmallTest(t)
+	m := paramtools.Params{
+		"a": "b",
+		"e": "f",
+		"0": "98",
+		"c": "d",
+	}
+	expectedHash := "62ee4de905f9ebda22ac5ffc81cddfb14939844dd33cc9c70de498054740d8f8"
+	h, err := hashParams(m)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedHash, h)
+
+	// Check in a loop to make sure it isn't flaky
+	for i := 0; i < 1000; i++ {
+		h, err := hashParams(m)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedHash, h)
+	}
+
+	m["a"] = "c"
+	h, err = hashParams(m)
+	assert.NoError(t, err)
+	assert.NotEqual(t, expectedHash, h)
+
+	h, err = hashParams(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, emptyParamsHash, h)
+
+	h, err = hashParams(paramtools.Params{})
+	assert.NoError(t, err)
+	assert.Equal(t, emptyParamsHash, h)
+}
+
+func newStore(t *testing.T, db *sql.DB) *StoreImpl {
+	s, err := New(db)
+	require.NoError(t, err)
+	return s
+}
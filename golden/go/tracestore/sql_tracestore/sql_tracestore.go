@@ -0,0 +1,358 @@
+// Package sql_tracestore implements the tracestore.TraceStore interface with an SQL (SQLite or
+// Postgres) backend.
+package sql_tracestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"go.skia.org/infra/go/metrics2"
+	"go.skia.org/infra/go/paramtools"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/sklog"
+	"go.skia.org/infra/go/tiling"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/go/vcsinfo"
+	"go.skia.org/infra/golden/go/sql_utils"
+	"go.skia.org/infra/golden/go/tracestore"
+	"go.skia.org/infra/golden/go/types"
+)
+
+// schema creates the tables for the traces. Traces holds the params of each trace once, keyed by
+// the tiling.TraceID. TraceValues has one row per trace and commit (identified by its index in the
+// repo) with data; ts is the (sql_utils) timestamp of the digest, so that the most recent Put wins.
+// TraceOptions has the options of the most recent commit (by commit time) for each trace.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS Traces (
+	trace_id  TEXT PRIMARY KEY,
+	params    TEXT NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS TraceValues (
+	trace_id      TEXT NOT NULL,
+	commit_index  INTEGER NOT NULL,
+	digest        TEXT NOT NULL,
+	ts            BIGINT NOT NULL,
+	PRIMARY KEY (trace_id, commit_index)
+)`,
+	`CREATE INDEX IF NOT EXISTS TraceValuesByCommit ON TraceValues (commit_index)`,
+	`CREATE TABLE IF NOT EXISTS TraceOptions (
+	trace_id   TEXT PRIMARY KEY,
+	options    TEXT NOT NULL,
+	commit_ts  BIGINT NOT NULL
+)`,
+}
+
+// StoreImpl implements the TraceStore interface.
+type StoreImpl struct {
+	db  *sql.DB
+	vcs vcsinfo.VCS
+}
+
+// New returns a new StoreImpl, creating the tables if they don't already exist. The given VCS is
+// used to map commit hashes to their index in the repo and vice versa.
+func New(db *sql.DB, vcs vcsinfo.VCS) (*StoreImpl, error) {
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, skerr.Wrapf(err, "creating tracestore tables")
+		}
+	}
+	return &StoreImpl{db: db, vcs: vcs}, nil
+}
+
+// Put implements the TraceStore interface.
+func (s *StoreImpl) Put(ctx context.Context, commitHash string, entries []*tracestore.Entry, ts time.Time) error {
+	defer metrics2.FuncTimer().Stop()
+	// if there are no entries this becomes a no-op.
+	if len(entries) == 0 {
+		return nil
+	}
+
+	repoIndex, err := s.vcs.IndexOf(ctx, commitHash)
+	if err != nil {
+		err = s.vcs.Update(ctx, true, false)
+		if err != nil {
+			return skerr.Wrapf(err, "could not update VCS to look up %s", commitHash)
+		}
+		repoIndex, err = s.vcs.IndexOf(ctx, commitHash)
+	}
+	if err != nil {
+		return skerr.Wrapf(err, "could not look up commit index of %s", commitHash)
+	}
+
+	commit, err := s.vcs.Details(ctx, commitHash, false)
+	if err != nil {
+		return skerr.Wrapf(err, "could not look up commit details for %s", commitHash)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return skerr.Wrapf(err, "starting transaction")
+	}
+	if err := putEntries(ctx, tx, repoIndex, entries, ts, commit.Timestamp); err != nil {
+		_ = tx.Rollback()
+		return skerr.Wrapf(err, "writing %d entries for commit %s", len(entries), commitHash)
+	}
+	return skerr.Wrap(tx.Commit())
+}
+
+// putEntries writes the given entries for the commit with the given index using the given
+// transaction. Digests are only replaced by ones with a later digestTS and Options by ones with a
+// later (or the same) optionsTS.
+func putEntries(ctx context.Context, tx *sql.Tx, repoIndex int, entries []*tracestore.Entry, digestTS, optionsTS time.Time) error {
+	putTrace, err := tx.PrepareContext(ctx, `INSERT INTO Traces (trace_id, params) VALUES ($1, $2)
+ON CONFLICT (trace_id) DO NOTHING`)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	defer util.Close(putTrace)
+	putValue, err := tx.PrepareContext(ctx, `INSERT INTO TraceValues (trace_id, commit_index, digest, ts) VALUES ($1, $2, $3, $4)
+ON CONFLICT (trace_id, commit_index) DO UPDATE SET digest=excluded.digest, ts=excluded.ts
+WHERE excluded.ts >= TraceValues.ts`)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	defer util.Close(putValue)
+	putOptions, err := tx.PrepareContext(ctx, `INSERT INTO TraceOptions (trace_id, options, commit_ts) VALUES ($1, $2, $3)
+ON CONFLICT (trace_id) DO UPDATE SET options=excluded.options, commit_ts=excluded.commit_ts
+WHERE excluded.commit_ts >= TraceOptions.commit_ts`)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	defer util.Close(putOptions)
+
+	dts := sql_utils.ToTimestamp(digestTS)
+	ots := sql_utils.ToTimestamp(optionsTS)
+	for _, entry := range entries {
+		traceID := tracestore.TraceIDFromParams(entry.Params)
+		p, err := json.Marshal(entry.Params)
+		if err != nil {
+			return skerr.Wrapf(err, "invalid params")
+		}
+		if _, err := putTrace.ExecContext(ctx, traceID, string(p)); err != nil {
+			return skerr.Wrapf(err, "storing trace %s", traceID)
+		}
+		if _, err := putValue.ExecContext(ctx, traceID, repoIndex, entry.Digest, dts); err != nil {
+			return skerr.Wrapf(err, "storing digest for trace %s", traceID)
+		}
+		if len(entry.Options) == 0 {
+			continue
+		}
+		o, err := json.Marshal(entry.Options)
+		if err != nil {
+			return skerr.Wrapf(err, "invalid options")
+		}
+		if _, err := putOptions.ExecContext(ctx, traceID, string(o), ots); err != nil {
+			return skerr.Wrapf(err, "storing options for trace %s", traceID)
+		}
+	}
+	return nil
+}
+
+// GetTile implements the TraceStore interface.
+func (s *StoreImpl) GetTile(ctx context.Context, nCommits int) (*tiling.Tile, []*tiling.Commit, error) {
+	defer metrics2.FuncTimer().Stop()
+	idxCommits := s.vcs.LastNIndex(nCommits)
+	if len(idxCommits) == 0 {
+		return nil, nil, skerr.Fmt("No commits found.")
+	}
+
+	hashes := make([]string, 0, len(idxCommits))
+	// columns maps the repo index of a commit to its index in the traces.
+	columns := make(map[int]int, len(idxCommits))
+	for i, ic := range idxCommits {
+		hashes = append(hashes, ic.Hash)
+		columns[ic.Index] = i
+	}
+	commits, err := s.makeTileCommits(ctx, hashes)
+	if err != nil {
+		return nil, nil, skerr.Wrapf(err, "could not load tile commits")
+	}
+
+	first, last := idxCommits[0].Index, idxCommits[len(idxCommits)-1].Index
+	traces, params, err := s.getTraces(ctx, first, last, columns)
+	if err != nil {
+		return nil, nil, skerr.Wrapf(err, "could not load last %d commits into tile", nCommits)
+	}
+
+	ret := &tiling.Tile{
+		Traces:   traces,
+		ParamSet: params,
+		Commits:  commits,
+		Scale:    0,
+	}
+	return ret, commits, nil
+}
+
+// GetDenseTile implements the TraceStore interface.
+func (s *StoreImpl) GetDenseTile(ctx context.Context, nCommits int) (*tiling.Tile, []*tiling.Commit, error) {
+	defer metrics2.FuncTimer().Stop()
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT commit_index FROM TraceValues WHERE digest <> $1
+ORDER BY commit_index DESC LIMIT $2`, types.MISSING_DIGEST, nCommits)
+	if err != nil {
+		return nil, nil, skerr.Wrapf(err, "querying commits with data")
+	}
+	var commitsWithData []int
+	err = func() error {
+		defer util.Close(rows)
+		for rows.Next() {
+			var idx int
+			if err := rows.Scan(&idx); err != nil {
+				return skerr.Wrap(err)
+			}
+			commitsWithData = append(commitsWithData, idx)
+		}
+		return skerr.Wrap(rows.Err())
+	}()
+	if err != nil {
+		return nil, nil, skerr.Wrapf(err, "reading commits with data")
+	}
+
+	if len(commitsWithData) == 0 {
+		return &tiling.Tile{}, nil, nil
+	}
+	// put them in oldest to newest order
+	sort.Ints(commitsWithData)
+	columns := make(map[int]int, len(commitsWithData))
+	for i, idx := range commitsWithData {
+		columns[idx] = i
+	}
+
+	allCommits, denseCommits, err := s.commitsFromVCS(ctx, commitsWithData)
+	if err != nil {
+		return nil, nil, skerr.Wrap(err)
+	}
+	traces, params, err := s.getTraces(ctx, commitsWithData[0], commitsWithData[len(commitsWithData)-1], columns)
+	if err != nil {
+		return nil, nil, skerr.Wrapf(err, "could not load dense tile of %d commits", nCommits)
+	}
+
+	ret := &tiling.Tile{
+		Traces:   traces,
+		ParamSet: params,
+		Commits:  denseCommits,
+		Scale:    0,
+	}
+	return ret, allCommits, nil
+}
+
+// getTraces returns the traces with data for commits in the range [first, last] of repo indices,
+// along with the ParamSet of those traces. columns maps the repo index of the commits to include
+// to their index in the returned traces; data for any other commits in the range is ignored.
+// The Options of each trace are added to its params.
+func (s *StoreImpl) getTraces(ctx context.Context, first, last int, columns map[int]int) (map[tiling.TraceID]tiling.Trace, paramtools.ParamSet, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT v.trace_id, v.commit_index, v.digest, t.params, o.options FROM TraceValues v
+		JOIN Traces t ON v.trace_id = t.trace_id
+		LEFT JOIN TraceOptions o ON v.trace_id = o.trace_id
+		WHERE v.commit_index >= $1 AND v.commit_index <= $2`, first, last)
+	if err != nil {
+		return nil, nil, skerr.Wrapf(err, "querying traces in [%d, %d]", first, last)
+	}
+	defer util.Close(rows)
+
+	traces := map[tiling.TraceID]tiling.Trace{}
+	paramSet := paramtools.ParamSet{}
+	for rows.Next() {
+		var traceID tiling.TraceID
+		var commitIndex int
+		var digest types.Digest
+		var params string
+		var options sql.NullString
+		if err := rows.Scan(&traceID, &commitIndex, &digest, &params, &options); err != nil {
+			return nil, nil, skerr.Wrapf(err, "reading trace value")
+		}
+		col, ok := columns[commitIndex]
+		if !ok {
+			continue
+		}
+		if _, ok := traces[traceID]; !ok {
+			p := paramtools.Params{}
+			if err := json.Unmarshal([]byte(params), &p); err != nil {
+				return nil, nil, skerr.Wrapf(err, "corrupt params for trace %s", traceID)
+			}
+			if options.Valid {
+				o := paramtools.Params{}
+				if err := json.Unmarshal([]byte(options.String), &o); err != nil {
+					return nil, nil, skerr.Wrapf(err, "corrupt options for trace %s", traceID)
+				}
+				p.Add(o)
+			}
+			traces[traceID] = types.NewEmptyGoldenTrace(len(columns), p)
+			// Build up the total set of params
+			paramSet.AddParams(p)
+		}
+		traces[traceID].(*types.GoldenTrace).Digests[col] = digest
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, skerr.Wrapf(err, "reading traces in [%d, %d]", first, last)
+	}
+
+	// Sort the params for determinism.
+	paramSet.Normalize()
+	return traces, paramSet, nil
+}
+
+// commitsFromVCS takes the indices of the commits that have data (sorted from oldest to newest)
+// and turns those into actual commit objects with data from the VCS. It returns all commits from
+// the first commit with data to the most recent commit as well as just the commits specified.
+// These are "allCommits" and "denseCommits", respectively.
+func (s *StoreImpl) commitsFromVCS(ctx context.Context, commitsWithData []int) ([]*tiling.Commit, []*tiling.Commit, error) {
+	oldestIdx := commitsWithData[0]
+	oldestCommit, err := s.vcs.ByIndex(ctx, oldestIdx)
+	if err != nil {
+		return nil, nil, skerr.Wrapf(err, "invalid oldest index %d", oldestIdx)
+	}
+	// Timestamps in Git are to the second granularity, so we should step back 1 second to make sure
+	// we include oldestCommit in our range.
+	hashes := s.vcs.From(oldestCommit.Timestamp.Add(-1 * time.Second))
+
+	// There's no guarantee that hashes[0] == oldestCommit[0] (e.g. two commits at same timestamp)
+	// So we trim hashes down if necessary
+	for i := 0; i < len(hashes); i++ {
+		if hashes[i] == oldestCommit.Hash {
+			hashes = hashes[i:]
+			break
+		}
+	}
+
+	allCommits, err := s.makeTileCommits(ctx, hashes)
+	if err != nil {
+		return nil, nil, skerr.Wrapf(err, "could not make tile commits")
+	}
+
+	denseCommits := make([]*tiling.Commit, len(commitsWithData))
+	for i, idx := range commitsWithData {
+		if idx-oldestIdx >= len(allCommits) {
+			sklog.Debugf("Commits with data: %d, hashes: %q", commitsWithData, hashes)
+			return nil, nil, skerr.Fmt("could not identify dense commits (%d - %d >= %d)", idx, oldestIdx, len(allCommits))
+		}
+		denseCommits[i] = allCommits[idx-oldestIdx]
+	}
+	return allCommits, denseCommits, nil
+}
+
+// makeTileCommits creates a slice of tiling.Commit from the given git hashes.
+func (s *StoreImpl) makeTileCommits(ctx context.Context, hashes []string) ([]*tiling.Commit, error) {
+	longCommits, err := s.vcs.DetailsMulti(ctx, hashes, false)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "could not fetch commit data for commits with hashes %q", hashes)
+	}
+
+	commits := make([]*tiling.Commit, len(hashes))
+	for i, lc := range longCommits {
+		if lc == nil {
+			return nil, skerr.Fmt("commit %s not found from VCS", hashes[i])
+		}
+		commits[i] = &tiling.Commit{
+			Hash:       lc.Hash,
+			Author:     lc.Author,
+			CommitTime: lc.Timestamp.Unix(),
+		}
+	}
+	return commits, nil
+}
+
+// Make sure StoreImpl fulfills the TraceStore Interface
+var _ tracestore.TraceStore = (*StoreImpl)(nil)
@@ -0,0 +1,39 @@
+package sql_tracestore
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/go/vcsinfo"
+	"go.skia.org/infra/golden/go/sql_utils"
+	"go.skia.org/infra/golden/go/tracestore"
+	"go.skia.org/infra/golden/go/tracestore/shared_tests"
+)
+
+func TestSharedTests_SQLite(t *testing.T) {
+	unittest.MediumTest(t)
+	runSharedTests(t, sql_utils.NewSQLiteForTesting)
+}
+
+func TestSharedTests_Postgres(t *testing.T) {
+	unittest.LargeTest(t) // should use a Postgres database
+	runSharedTests(t, sql_utils.NewPostgresForTesting)
+}
+
+// runSharedTests runs each of the shared tests, giving every TraceStore a new database.
+func runSharedTests(t *testing.T, newDB func(sktest.TestingT) (*sql.DB, func())) {
+	for name, test := range shared_tests.Tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, func(t sktest.TestingT, vcs vcsinfo.VCS) (tracestore.TraceStore, func()) {
+				db, cleanup := newDB(t)
+				s, err := New(db, vcs)
+				require.NoError(t, err)
+				return s, cleanup
+			})
+		})
+	}
+}
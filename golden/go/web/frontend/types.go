@@ -9,9 +9,11 @@ import (
 	"time"
 
 	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/tiling"
 	"go.skia.org/infra/golden/go/code_review"
 	ci "go.skia.org/infra/golden/go/continuous_integration"
 	"go.skia.org/infra/golden/go/expstorage"
+	"go.skia.org/infra/golden/go/flaky"
 	"go.skia.org/infra/golden/go/ignore"
 	"go.skia.org/infra/golden/go/types"
 )
@@ -146,10 +148,16 @@ type IgnoreRule struct {
 	Query       string              `json:"query"`
 	ParsedQuery map[string][]string `json:"-"`
 	Note        string              `json:"note"`
+	// Expired is true if the rule has expired.
+	Expired bool `json:"expired"`
+	// Applied is false if the rule has been expired for longer than the grace period, in which
+	// case it no longer hides any traces.
+	Applied bool `json:"applied"`
 	// Count represents how many traces are affected by this ignore rule.
 	Count int `json:"countAll"`
 	// ExclusiveCount represents how many traces are affected *exclusively* by this ignore rule,
-	// that is, they are only matched by this rule.
+	// that is, they are only matched by this rule. This is the number of traces that would be
+	// un-hidden if the rule were deleted or stopped being applied.
 	ExclusiveCount int `json:"exclusiveCountAll"`
 	// UntriagedCount represents how many traces with an untriaged digest at HEAD are affected
 	// by this ignore rule.
@@ -188,3 +196,39 @@ type IgnoreRuleBody struct {
 	// Note is a short comment by a developer, typically a bug. Note is limited to 1 KB.
 	Note string `json:"note"`
 }
+
+// FlakyTrace represents a trace that was detected as flaky, marked as flaky by a user, or both.
+type FlakyTrace struct {
+	TraceID tiling.TraceID    `json:"trace_id"`
+	Params  map[string]string `json:"params"`
+	// DistinctDigests, Transitions and Score are computed over the current tile (see
+	// flaky.TraceStats).
+	DistinctDigests int `json:"distinct_digests"`
+	Transitions     int `json:"transitions"`
+	Score           int `json:"score"`
+	// Detected is true if the digests of the trace show that it is flaky.
+	Detected bool `json:"detected"`
+	// MarkedBy, Marked and Note are only set if a user marked the trace as flaky.
+	MarkedBy string    `json:"marked_by"`
+	Marked   time.Time `json:"marked"`
+	Note     string    `json:"note"`
+}
+
+// ConvertFlakyTrace returns a FlakyTrace with the given id and stats. The other fields are
+// left for the caller to fill in.
+func ConvertFlakyTrace(id tiling.TraceID, stats flaky.TraceStats) FlakyTrace {
+	return FlakyTrace{
+		TraceID:         id,
+		DistinctDigests: stats.DistinctDigests,
+		Transitions:     stats.Transitions,
+		Score:           stats.Score(),
+	}
+}
+
+// FlakyMarkBody is the body of a request to mark a trace as flaky, or to remove such a mark.
+type FlakyMarkBody struct {
+	TraceID tiling.TraceID `json:"trace_id"`
+	// Note is a short comment by a developer, typically a bug. Note is limited to 1 KB. It is
+	// ignored when removing a mark.
+	Note string `json:"note"`
+}
@@ -27,6 +27,8 @@ import (
 	"go.skia.org/infra/golden/go/clstore"
 	"go.skia.org/infra/golden/go/diff"
 	"go.skia.org/infra/golden/go/expstorage"
+	"go.skia.org/infra/golden/go/expstorage/expsync"
+	"go.skia.org/infra/golden/go/flaky"
 	"go.skia.org/infra/golden/go/ignore"
 	"go.skia.org/infra/golden/go/indexer"
 	"go.skia.org/infra/golden/go/search"
@@ -77,7 +79,9 @@ type HandlersConfig struct {
 	ContinuousIntegrationURLTemplate string
 	DiffStore                        diff.DiffStore
 	ExpectationsStore                expstorage.ExpectationsStore
+	FlakyStore                       flaky.Store
 	GCSClient                        storage.GCSClient
+	IgnoreGracePeriod                time.Duration
 	IgnoreStore                      ignore.Store
 	Indexer                          indexer.IndexSource
 	SearchAPI                        search.SearchAPI
@@ -120,6 +124,9 @@ func NewHandlers(conf HandlersConfig, val validateFields) (*Handlers, error) {
 		if conf.ExpectationsStore == nil {
 			return nil, skerr.Fmt("ExpectationsStore cannot be nil")
 		}
+		if conf.FlakyStore == nil {
+			return nil, skerr.Fmt("FlakyStore cannot be nil")
+		}
 		if conf.IgnoreStore == nil {
 			return nil, skerr.Fmt("IgnoreStore cannot be nil")
 		}
@@ -208,10 +215,13 @@ func (wh *Handlers) computeByBlame(ctx context.Context, corpus string) ([]ByBlam
 		return nil, skerr.Wrapf(err, "could not get summaries for corpus %q", corpus)
 	}
 	commits := idx.Tile().DataCommits()
+	flakyIdx := idx.FlakyTraces()
 
 	// This is a very simple grouping of digests, for every digest we look up the
 	// blame list for that digest and then use the concatenated git hashes as a
-	// group id. All of the digests are then grouped by their group id.
+	// group id. All of the digests are then grouped by their group id. The digests
+	// that were only drawn by flaky traces are not blamed on any commit, they all
+	// go into the flaky.GroupID group instead.
 
 	// Collects a ByBlame for each untriaged digest, keyed by group id.
 	grouped := map[string][]ByBlame{}
@@ -224,23 +234,32 @@ func (wh *Handlers) computeByBlame(ctx context.Context, corpus string) ([]ByBlam
 	for _, s := range untriagedSummaries {
 		test := s.Name
 		for _, d := range s.UntHashes {
-			dist := idx.GetBlame(test, d, commits)
-			if dist.IsEmpty() {
-				// Should only happen if the index isn't quite ready being prepared.
-				// Since we wait until the index is created before exposing the web
-				// server, this should never happen.
-				sklog.Warningf("empty blame for %s %s", test, d)
-				continue
-			}
-			groupid := strings.Join(lookUpCommits(dist.Freq, commits), ":")
-			// Only fill in commitinfo for each groupid only once.
-			if _, ok := commitinfo[groupid]; !ok {
-				ci := []*tiling.Commit{}
-				for _, index := range dist.Freq {
-					ci = append(ci, commits[index])
+			var dist blame.BlameDistribution
+			var groupid string
+			if flakyIdx.OnlyInFlakyTraces(test, d) {
+				groupid = flaky.GroupID
+				if _, ok := commitinfo[groupid]; !ok {
+					commitinfo[groupid] = []*tiling.Commit{}
+				}
+			} else {
+				dist = idx.GetBlame(test, d, commits)
+				if dist.IsEmpty() {
+					// Should only happen if the index isn't quite ready being prepared.
+					// Since we wait until the index is created before exposing the web
+					// server, this should never happen.
+					sklog.Warningf("empty blame for %s %s", test, d)
+					continue
+				}
+				groupid = strings.Join(lookUpCommits(dist.Freq, commits), ":")
+				// Only fill in commitinfo for each groupid only once.
+				if _, ok := commitinfo[groupid]; !ok {
+					ci := []*tiling.Commit{}
+					for _, index := range dist.Freq {
+						ci = append(ci, commits[index])
+					}
+					sort.Sort(CommitSlice(ci))
+					commitinfo[groupid] = ci
 				}
-				sort.Sort(CommitSlice(ci))
-				commitinfo[groupid] = ci
 			}
 			// Construct a ByBlame and add it to grouped.
 			value := ByBlame{
@@ -702,11 +721,14 @@ func (wh *Handlers) getIgnores(ctx context.Context, withCounts bool) ([]*fronten
 
 	// We want to make a slice of pointers because addIgnoreCounts will add the counts in-place.
 	ret := make([]*frontend.IgnoreRule, 0, len(rules))
+	now := wh.now()
 	for _, r := range rules {
 		fr, err := frontend.ConvertIgnoreRule(r)
 		if err != nil {
 			return nil, skerr.Wrap(err)
 		}
+		fr.Expired = r.IsExpired(now)
+		fr.Applied = r.IsApplied(now, wh.IgnoreGracePeriod)
 		ret = append(ret, &fr)
 	}
 
@@ -722,7 +744,8 @@ func (wh *Handlers) getIgnores(ctx context.Context, withCounts bool) ([]*fronten
 
 // addIgnoreCounts goes through the whole tile and counts how many traces each of the rules
 // applies to. This uses the most recent index, so there may be some discrepancies in the counts
-// if a new rule has been added since the last index was computed.
+// if a new rule has been added since the last index was computed. Rules that are no longer
+// applied don't hide any traces, so they are not counted.
 func (wh *Handlers) addIgnoreCounts(ctx context.Context, rules []*frontend.IgnoreRule) error {
 	defer metrics2.FuncTimer().Stop()
 	sklog.Debugf("adding counts to %d rules", len(rules))
@@ -768,7 +791,7 @@ func (wh *Handlers) addIgnoreCounts(ctx context.Context, rules []*frontend.Ignor
 			numMatched := 0
 			untMatched := 0
 			for i, r := range rules {
-				if ruleMatches(r.ParsedQuery, gt) {
+				if r.Applied && ruleMatches(r.ParsedQuery, gt) {
 					numMatched++
 					ruleCounts[i].Count++
 					idxMatched = i
@@ -912,6 +935,145 @@ func (wh *Handlers) AddIgnoreRule(w http.ResponseWriter, r *http.Request) {
 	sendJSONResponse(w, map[string]string{"added": "true"})
 }
 
+// ListFlakyTraces returns the traces that were detected or marked as flaky in JSON format. The
+// most flaky traces come first.
+func (wh *Handlers) ListFlakyTraces(w http.ResponseWriter, r *http.Request) {
+	defer metrics2.FuncTimer().Stop()
+	if err := wh.cheapLimitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+
+	traces, err := wh.getFlakyTraces(r.Context())
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to retrieve flaky traces", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSONResponse(w, traces)
+}
+
+// getFlakyTraces combines the traces that the current index detected as flaky with the marks in
+// the FlakyStore. The marks are read from the store instead of the index, so that marks that
+// were added or deleted since the index was computed are reflected.
+func (wh *Handlers) getFlakyTraces(ctx context.Context) ([]frontend.FlakyTrace, error) {
+	marks, err := wh.FlakyStore.List(ctx)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "fetching flaky marks from store")
+	}
+	idx := wh.Indexer.GetIndex()
+	traces := idx.Tile().GetTile(types.IncludeIgnoredTraces).Traces
+
+	byID := map[tiling.TraceID]*frontend.FlakyTrace{}
+	for id, ts := range idx.FlakyTraces().Traces() {
+		if ts.Detected {
+			ft := frontend.ConvertFlakyTrace(id, ts.TraceStats)
+			ft.Detected = true
+			byID[id] = &ft
+		}
+	}
+	for _, m := range marks {
+		ft, ok := byID[m.TraceID]
+		if !ok {
+			// The trace might have been marked after the index was computed.
+			stats := flaky.TraceStats{}
+			if gt, ok := traces[m.TraceID].(*types.GoldenTrace); ok {
+				stats = flaky.ComputeStats(gt)
+			}
+			nft := frontend.ConvertFlakyTrace(m.TraceID, stats)
+			ft = &nft
+			byID[m.TraceID] = ft
+		}
+		ft.MarkedBy = m.MarkedBy
+		ft.Marked = m.TS
+		ft.Note = m.Note
+	}
+
+	ret := make([]frontend.FlakyTrace, 0, len(byID))
+	for id, ft := range byID {
+		if tr, ok := traces[id]; ok {
+			ft.Params = tr.Params()
+		}
+		ret = append(ret, *ft)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Score > ret[j].Score ||
+			// For test determinism, use TraceID as a tie-breaker
+			(ret[i].Score == ret[j].Score && ret[i].TraceID < ret[j].TraceID)
+	})
+	return ret, nil
+}
+
+// AddFlakyMark marks a trace as flaky. The digests only drawn by flaky traces are grouped together
+// once the next index has been computed.
+func (wh *Handlers) AddFlakyMark(w http.ResponseWriter, r *http.Request) {
+	defer metrics2.FuncTimer().Stop()
+	user := wh.loggedInAs(r)
+	if user == "" {
+		http.Error(w, "You must be logged in to mark a trace as flaky", http.StatusUnauthorized)
+		return
+	}
+
+	fmb, err := getValidatedFlakyMark(r)
+	if err != nil {
+		httputils.ReportError(w, err, "invalid flaky mark input", http.StatusBadRequest)
+		return
+	}
+
+	m := flaky.Mark{
+		TraceID:  fmb.TraceID,
+		MarkedBy: user,
+		TS:       wh.now(),
+		Note:     fmb.Note,
+	}
+	if err := wh.FlakyStore.Add(r.Context(), m); err != nil {
+		httputils.ReportError(w, err, "Failed to mark trace as flaky", http.StatusInternalServerError)
+		return
+	}
+
+	sklog.Infof("Successfully marked trace %s as flaky for %s", fmb.TraceID, user)
+	sendJSONResponse(w, map[string]string{"added": "true"})
+}
+
+// DeleteFlakyMark removes the mark from a trace that was marked as flaky. The trace id is in the
+// body instead of the URL, because trace ids contain characters like ',' and '='.
+func (wh *Handlers) DeleteFlakyMark(w http.ResponseWriter, r *http.Request) {
+	defer metrics2.FuncTimer().Stop()
+	user := wh.loggedInAs(r)
+	if user == "" {
+		http.Error(w, "You must be logged in to unmark a flaky trace", http.StatusUnauthorized)
+		return
+	}
+
+	fmb, err := getValidatedFlakyMark(r)
+	if err != nil {
+		httputils.ReportError(w, err, "invalid flaky mark input", http.StatusBadRequest)
+		return
+	}
+
+	if err := wh.FlakyStore.Delete(r.Context(), fmb.TraceID); err != nil {
+		httputils.ReportError(w, err, "Unable to unmark flaky trace", http.StatusInternalServerError)
+		return
+	}
+	sklog.Infof("Successfully unmarked flaky trace %s", fmb.TraceID)
+	sendJSONResponse(w, map[string]string{"deleted": "true"})
+}
+
+// getValidatedFlakyMark parses the JSON from the given request into a FlakyMarkBody.
+func getValidatedFlakyMark(r *http.Request) (frontend.FlakyMarkBody, error) {
+	fmb := frontend.FlakyMarkBody{}
+	if err := parseJSON(r, &fmb); err != nil {
+		return fmb, skerr.Wrapf(err, "reading request JSON")
+	}
+	if fmb.TraceID == "" {
+		return fmb, skerr.Fmt("must supply a trace id")
+	}
+	if len(fmb.Note) >= 1024 {
+		return fmb, skerr.Fmt("Note must be < 1 KB")
+	}
+	return fmb, nil
+}
+
 // TriageHandler handles a request to change the triage status of one or more
 // digests of one test.
 //
@@ -972,6 +1134,49 @@ func (wh *Handlers) triage(ctx context.Context, user string, req frontend.Triage
 	return nil
 }
 
+// ExportExpectationsHandler returns all expectations of the master branch, together with who
+// triaged each digest. The result can be passed to ImportExpectationsHandler of another instance.
+func (wh *Handlers) ExportExpectationsHandler(w http.ResponseWriter, r *http.Request) {
+	defer metrics2.FuncTimer().Stop()
+	if err := wh.limitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+
+	dump, err := expsync.Export(r.Context(), wh.ExpectationsStore)
+	if err != nil {
+		httputils.ReportError(w, err, "Could not export expectations", http.StatusInternalServerError)
+		return
+	}
+	sendJSONResponse(w, dump)
+}
+
+// ImportExpectationsHandler adds the expectations of an export to the master branch. Tests can
+// be renamed on the way. It returns the changes that were made or, in dry-run mode, the changes
+// that would have been made.
+func (wh *Handlers) ImportExpectationsHandler(w http.ResponseWriter, r *http.Request) {
+	defer metrics2.FuncTimer().Stop()
+	user := wh.loggedInAs(r)
+	if user == "" {
+		http.Error(w, "You must be logged in to import expectations.", http.StatusUnauthorized)
+		return
+	}
+
+	req := expsync.ImportRequest{}
+	if err := parseJSON(r, &req); err != nil {
+		httputils.ReportError(w, err, "Failed to parse JSON request.", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := expsync.Import(r.Context(), wh.ExpectationsStore, req, user)
+	if err != nil {
+		httputils.ReportError(w, err, "Could not import expectations", http.StatusInternalServerError)
+		return
+	}
+	sklog.Infof("%s imported %d expectations (dry run: %t)", user, len(resp.Deltas), req.DryRun)
+	sendJSONResponse(w, resp)
+}
+
 // StatusHandler returns the current status of with respect to HEAD.
 func (wh *Handlers) StatusHandler(w http.ResponseWriter, r *http.Request) {
 	defer metrics2.FuncTimer().Stop()
@@ -32,6 +32,8 @@ import (
 	"go.skia.org/infra/golden/go/digest_counter"
 	"go.skia.org/infra/golden/go/expstorage"
 	mock_expstorage "go.skia.org/infra/golden/go/expstorage/mocks"
+	"go.skia.org/infra/golden/go/flaky"
+	mock_flaky "go.skia.org/infra/golden/go/flaky/mocks"
 	"go.skia.org/infra/golden/go/ignore"
 	mock_ignore "go.skia.org/infra/golden/go/ignore/mocks"
 	"go.skia.org/infra/golden/go/indexer"
@@ -225,6 +227,49 @@ func TestComputeByBlame_MultipleUntriagedDigests_Success(t *testing.T) {
 	}, output)
 }
 
+// TestComputeByBlame_FlakyTraces_Success makes sure that untriaged digests that were only drawn by
+// flaky traces are put in their own group instead of being blamed on a commit.
+func TestComputeByBlame_FlakyTraces_Success(t *testing.T) {
+	unittest.SmallTest(t)
+
+	mi := &mock_indexer.IndexSource{}
+	defer mi.AssertExpectations(t)
+
+	commits := bug_revert.MakeTestCommits()
+	fis := makeBugRevertIndex(len(commits))
+	// These are the only traces that drew UntriagedDigestFoxtrot.
+	fis.SetFlakyMarksForTesting([]flaky.Mark{
+		{TraceID: ",device=delta,name=test_two,source_type=gm,"},
+		{TraceID: ",device=gamma,name=test_two,source_type=gm,"},
+	})
+	mi.On("GetIndex").Return(fis)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			Indexer: mi,
+		},
+	}
+
+	output, err := wh.computeByBlame(context.Background(), "gm")
+	require.NoError(t, err)
+
+	assert.Equal(t, []ByBlameEntry{
+		{
+			GroupID:  flaky.GroupID,
+			NDigests: 1,
+			NTests:   1,
+			Commits:  []*tiling.Commit{},
+			AffectedTests: []TestRollup{
+				{
+					Test:         bug_revert.TestTwo,
+					Num:          1,
+					SampleDigest: bug_revert.UntriagedDigestFoxtrot,
+				},
+			},
+		},
+	}, output)
+}
+
 // makeBugRevertIndex returns a search index corresponding to a subset of the bug_revert_data
 // (which currently has nothing ignored). We choose to use this instead of mocking
 // out the SearchIndex, as per the advice in http://go/mocks#prefer-real-objects
@@ -868,6 +913,7 @@ func TestGetIgnores_NoCounts_SunnyDay_Success(t *testing.T) {
 		HandlersConfig: HandlersConfig{
 			IgnoreStore: mis,
 		},
+		testingNow: ignoreRulesNow,
 	}
 
 	xir, err := wh.getIgnores(context.Background(), false)
@@ -881,6 +927,8 @@ func TestGetIgnores_NoCounts_SunnyDay_Success(t *testing.T) {
 			Expires:   firstRuleExpire,
 			Query:     "device=delta",
 			Note:      "Flaky driver",
+			Expired:   true,
+			Applied:   true,
 		},
 		{
 			ID:        "5678",
@@ -889,6 +937,7 @@ func TestGetIgnores_NoCounts_SunnyDay_Success(t *testing.T) {
 			Expires:   secondRuleExpire,
 			Query:     "name=test_two&source_type=gm",
 			Note:      "Not ready yet",
+			Applied:   true,
 		},
 		{
 			ID:        "-1",
@@ -897,6 +946,7 @@ func TestGetIgnores_NoCounts_SunnyDay_Success(t *testing.T) {
 			Expires:   thirdRuleExpire,
 			Query:     "matches=nothing",
 			Note:      "Oops, this matches nothing",
+			Applied:   true,
 		},
 	}, xir)
 }
@@ -931,6 +981,7 @@ func TestGetIgnores_WithCounts_SunnyDay_Success(t *testing.T) {
 			IgnoreStore:       mis,
 			Indexer:           mi,
 		},
+		testingNow: ignoreRulesNow,
 	}
 
 	xir, err := wh.getIgnores(context.Background(), true /* = withCounts*/)
@@ -944,6 +995,8 @@ func TestGetIgnores_WithCounts_SunnyDay_Success(t *testing.T) {
 			Expires:                 firstRuleExpire,
 			Query:                   "device=delta",
 			Note:                    "Flaky driver",
+			Expired:                 true,
+			Applied:                 true,
 			Count:                   2,
 			ExclusiveCount:          1,
 			UntriagedCount:          1,
@@ -956,6 +1009,7 @@ func TestGetIgnores_WithCounts_SunnyDay_Success(t *testing.T) {
 			Expires:                 secondRuleExpire,
 			Query:                   "name=test_two&source_type=gm",
 			Note:                    "Not ready yet",
+			Applied:                 true,
 			Count:                   4,
 			ExclusiveCount:          3,
 			UntriagedCount:          2,
@@ -968,6 +1022,7 @@ func TestGetIgnores_WithCounts_SunnyDay_Success(t *testing.T) {
 			Expires:                 thirdRuleExpire,
 			Query:                   "matches=nothing",
 			Note:                    "Oops, this matches nothing",
+			Applied:                 true,
 			Count:                   0,
 			ExclusiveCount:          0,
 			UntriagedCount:          0,
@@ -976,6 +1031,61 @@ func TestGetIgnores_WithCounts_SunnyDay_Success(t *testing.T) {
 	}, xir)
 }
 
+// TestGetIgnores_WithCounts_ExpiredRuleNotApplied_Success tests that a rule which has been expired
+// for longer than the grace period is reported as not applied and does not count any traces.
+// The traces it used to hide exclusively are now only hidden by the other rules.
+func TestGetIgnores_WithCounts_ExpiredRuleNotApplied_Success(t *testing.T) {
+	unittest.SmallTest(t)
+
+	mes := &mock_expstorage.ExpectationsStore{}
+	mi := &mock_indexer.IndexSource{}
+	mis := &mock_ignore.Store{}
+	defer mes.AssertExpectations(t)
+	defer mi.AssertExpectations(t)
+	defer mis.AssertExpectations(t)
+
+	exp := bug_revert.MakeTestExpectations()
+	exp.Set(bug_revert.TestTwo, bug_revert.GoodDigestEcho, expectations.Untriaged)
+	mes.On("Get", testutils.AnyContext).Return(exp, nil)
+
+	gracePeriod := 7 * 24 * time.Hour
+	// The tile source would only apply the rules that are still applied.
+	applied := ignore.AppliedRules(makeIgnoreRules(), ignoreRulesNow, gracePeriod)
+	require.Len(t, applied, 2)
+	fis := makeBugRevertIndexWithIgnores(applied, 1)
+	mi.On("GetIndex").Return(fis)
+
+	mis.On("List", testutils.AnyContext).Return(makeIgnoreRules(), nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			ExpectationsStore: mes,
+			IgnoreGracePeriod: gracePeriod,
+			IgnoreStore:       mis,
+			Indexer:           mi,
+		},
+		testingNow: ignoreRulesNow,
+	}
+
+	xir, err := wh.getIgnores(context.Background(), true /* = withCounts*/)
+	require.NoError(t, err)
+	require.Len(t, xir, 3)
+
+	assert.Equal(t, "1234", xir[0].ID)
+	assert.True(t, xir[0].Expired)
+	assert.False(t, xir[0].Applied)
+	assert.Equal(t, 0, xir[0].Count)
+	assert.Equal(t, 0, xir[0].ExclusiveCount)
+
+	assert.Equal(t, "5678", xir[1].ID)
+	assert.False(t, xir[1].Expired)
+	assert.True(t, xir[1].Applied)
+	assert.Equal(t, 4, xir[1].Count)
+	assert.Equal(t, 4, xir[1].ExclusiveCount)
+	assert.Equal(t, 2, xir[1].UntriagedCount)
+	assert.Equal(t, 2, xir[1].ExclusiveUntriagedCount)
+}
+
 // TestGetIgnores_WithCountsOnBigTile_SunnyDay_NoRaceConditions uses an artificially bigger tile to
 // process to make sure the counting code has no races in it when sharded.
 func TestGetIgnores_WithCountsOnBigTile_SunnyDay_NoRaceConditions(t *testing.T) {
@@ -1032,6 +1142,8 @@ func TestHandlersThatRequireLogin_NotLoggedIn_UnauthorizedError(t *testing.T) {
 	test("add", wh.AddIgnoreRule)
 	test("update", wh.UpdateIgnoreRule)
 	test("delete", wh.DeleteIgnoreRule)
+	test("add flaky", wh.AddFlakyMark)
+	test("delete flaky", wh.DeleteFlakyMark)
 	// TODO(kjlubick): check all handlers that need login, not just Ignores*
 }
 
@@ -1056,6 +1168,8 @@ func TestHandlersWhichTakeJSON_BadInput_BadRequestError(t *testing.T) {
 	}
 	test("add", wh.AddIgnoreRule)
 	test("update", wh.UpdateIgnoreRule)
+	test("add flaky", wh.AddFlakyMark)
+	test("delete flaky", wh.DeleteFlakyMark)
 	// TODO(kjlubick): check all handlers that process JSON
 }
 
@@ -1319,6 +1433,162 @@ func TestDeleteIgnoreRule_StoreFailure_InternalServerError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 }
 
+// TestGetFlakyTraces_SunnyDay_Success makes sure that the marks from the FlakyStore are combined
+// with the data from the index, even if the marked trace is not in the tile.
+func TestGetFlakyTraces_SunnyDay_Success(t *testing.T) {
+	unittest.SmallTest(t)
+
+	mi := &mock_indexer.IndexSource{}
+	mfs := &mock_flaky.Store{}
+	defer mi.AssertExpectations(t)
+	defer mfs.AssertExpectations(t)
+
+	fis := makeBugRevertIndex(len(bug_revert.MakeTestCommits()))
+	mi.On("GetIndex").Return(fis)
+
+	ts := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	mfs.On("List", testutils.AnyContext).Return([]flaky.Mark{
+		{
+			TraceID:  ",device=gamma,name=test_two,source_type=gm,",
+			MarkedBy: "user@example.com",
+			TS:       ts,
+			Note:     "skbug.com/1234",
+		},
+		{
+			TraceID:  ",name=not_in_tile,",
+			MarkedBy: "user@example.com",
+			TS:       ts,
+		},
+	}, nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			FlakyStore: mfs,
+			Indexer:    mi,
+		},
+	}
+
+	xft, err := wh.getFlakyTraces(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []frontend.FlakyTrace{
+		{
+			TraceID: ",device=gamma,name=test_two,source_type=gm,",
+			Params: map[string]string{
+				"device":                bug_revert.GammaDevice,
+				types.PRIMARY_KEY_FIELD: string(bug_revert.TestTwo),
+				types.CORPUS_FIELD:      "gm",
+			},
+			DistinctDigests: 4,
+			Transitions:     3,
+			Score:           0,
+			MarkedBy:        "user@example.com",
+			Marked:          ts,
+			Note:            "skbug.com/1234",
+		},
+		{
+			TraceID:  ",name=not_in_tile,",
+			MarkedBy: "user@example.com",
+			Marked:   ts,
+		},
+	}, xft)
+}
+
+// TestAddFlakyMark_SunnyDay_Success tests a typical case of marking a trace as flaky.
+func TestAddFlakyMark_SunnyDay_Success(t *testing.T) {
+	unittest.SmallTest(t)
+
+	const user = "test@example.com"
+	var fakeNow = time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	mfs := &mock_flaky.Store{}
+	defer mfs.AssertExpectations(t)
+
+	mfs.On("Add", testutils.AnyContext, flaky.Mark{
+		TraceID:  ",device=gamma,name=test_two,source_type=gm,",
+		MarkedBy: user,
+		TS:       fakeNow,
+		Note:     "skbug:9744",
+	}).Return(nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			FlakyStore: mfs,
+		},
+		testingAuthAs: user,
+		testingNow:    fakeNow,
+	}
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"trace_id": ",device=gamma,name=test_two,source_type=gm,", "note": "skbug:9744"}`)
+	r := httptest.NewRequest(http.MethodPost, requestURL, body)
+	wh.AddFlakyMark(w, r)
+
+	assertJSONResponseWas(t, http.StatusOK, `{"added":"true"}`, w)
+}
+
+// TestAddFlakyMark_NoTraceID_BadRequestError tests that a trace id must be given.
+func TestAddFlakyMark_NoTraceID_BadRequestError(t *testing.T) {
+	unittest.SmallTest(t)
+
+	wh := Handlers{
+		testingAuthAs: "test@example.com",
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, requestURL, strings.NewReader(`{"note": "skbug:9744"}`))
+	wh.AddFlakyMark(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// TestDeleteFlakyMark_SunnyDay_Success tests a typical case of removing the flaky mark from a
+// trace.
+func TestDeleteFlakyMark_SunnyDay_Success(t *testing.T) {
+	unittest.SmallTest(t)
+
+	mfs := &mock_flaky.Store{}
+	defer mfs.AssertExpectations(t)
+
+	mfs.On("Delete", testutils.AnyContext, tiling.TraceID(",device=gamma,name=test_two,source_type=gm,")).Return(nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			FlakyStore: mfs,
+		},
+		testingAuthAs: "test@example.com",
+	}
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"trace_id": ",device=gamma,name=test_two,source_type=gm,"}`)
+	r := httptest.NewRequest(http.MethodPost, requestURL, body)
+	wh.DeleteFlakyMark(w, r)
+
+	assertJSONResponseWas(t, http.StatusOK, `{"deleted":"true"}`, w)
+}
+
+// TestDeleteFlakyMark_StoreFailure_InternalServerError tests the exceptional case where the mark
+// could not be removed from the FlakyStore.
+func TestDeleteFlakyMark_StoreFailure_InternalServerError(t *testing.T) {
+	unittest.SmallTest(t)
+
+	mfs := &mock_flaky.Store{}
+	defer mfs.AssertExpectations(t)
+
+	mfs.On("Delete", testutils.AnyContext, mock.Anything).Return(errors.New("firestore broke"))
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			FlakyStore: mfs,
+		},
+		testingAuthAs: "test@example.com",
+	}
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"trace_id": ",device=gamma,name=test_two,source_type=gm,"}`)
+	r := httptest.NewRequest(http.MethodPost, requestURL, body)
+	wh.DeleteFlakyMark(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
 // TestBaselineHandler_Success tests that the handler correctly calls the BaselineFetcher when no
 // GET parameters are set.
 func TestBaselineHandler_Success(t *testing.T) {
@@ -1636,13 +1906,110 @@ func TestWhoami_LoggedIn_Success(t *testing.T) {
 	assertJSONResponseWas(t, http.StatusOK, `{"whoami":"test@example.com"}`, w)
 }
 
+// TestExportExpectationsHandler_SunnyDay_Success tests that the expectations are exported with
+// the user who triaged them.
+func TestExportExpectationsHandler_SunnyDay_Success(t *testing.T) {
+	unittest.SmallTest(t)
+
+	mes := &mock_expstorage.ExpectationsStore{}
+	defer mes.AssertExpectations(t)
+
+	var exp expectations.Expectations
+	exp.Set(bug_revert.TestOne, bug_revert.GoodDigestAlfa, expectations.Positive)
+	mes.On("Get", testutils.AnyContext).Return(&exp, nil)
+	triageTime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	mes.On("QueryLog", testutils.AnyContext, 0, 500, true).Return([]expstorage.TriageLogEntry{
+		{
+			ID:          "abc",
+			User:        "user@example.com",
+			TS:          triageTime,
+			ChangeCount: 1,
+			Details: []expstorage.Delta{
+				{Grouping: bug_revert.TestOne, Digest: bug_revert.GoodDigestAlfa, Label: expectations.Positive},
+			},
+		},
+	}, 1, nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			ExpectationsStore: mes,
+		},
+		anonymousExpensiveQuota: rate.NewLimiter(rate.Inf, 1),
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, requestURL, nil)
+	wh.ExportExpectationsHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{"entries":[{"grouping":"test_one","digest":"aaa0ddfc45a95372747804fc75061fc1","label":"positive","triaged_by":"user@example.com","triaged_at":"2020-01-02T03:04:05Z"}]}`, w)
+}
+
+// TestImportExpectationsHandler_DryRun_Success tests that a dry run returns the changes, but
+// doesn't apply them.
+func TestImportExpectationsHandler_DryRun_Success(t *testing.T) {
+	unittest.SmallTest(t)
+
+	mes := &mock_expstorage.ExpectationsStore{}
+	defer mes.AssertExpectations(t)
+
+	mes.On("Get", testutils.AnyContext).Return(&expectations.Expectations{}, nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			ExpectationsStore: mes,
+		},
+		testingAuthAs: "importer@example.com",
+	}
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"dump": {"entries": [{"grouping": "old_test", "digest": "aaa0ddfc45a95372747804fc75061fc1", "label": "positive"}]}, "remap": [{"pattern": "old_(.*)", "replacement": "new_$1"}], "dry_run": true}`)
+	r := httptest.NewRequest(http.MethodPost, requestURL, body)
+	wh.ImportExpectationsHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{"deltas":[{"grouping":"new_test","digest":"aaa0ddfc45a95372747804fc75061fc1","label":"positive","user":"importer@example.com"}],"applied":false}`, w)
+}
+
+// TestImportExpectationsHandler_ForgedTriager_AttributedToImporter tests that the triagers named
+// in an import are not trusted; every change is attributed to the logged-in user.
+func TestImportExpectationsHandler_ForgedTriager_AttributedToImporter(t *testing.T) {
+	unittest.SmallTest(t)
+
+	mes := &mock_expstorage.ExpectationsStore{}
+	defer mes.AssertExpectations(t)
+
+	mes.On("Get", testutils.AnyContext).Return(&expectations.Expectations{}, nil)
+	mes.On("AddChange", testutils.AnyContext, []expstorage.Delta{
+		{Grouping: bug_revert.TestOne, Digest: bug_revert.GoodDigestAlfa, Label: expectations.Positive},
+	}, "importer@example.com").Return(nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			ExpectationsStore: mes,
+		},
+		testingAuthAs: "importer@example.com",
+	}
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"dump": {"entries": [{"grouping": "test_one", "digest": "aaa0ddfc45a95372747804fc75061fc1", "label": "positive", "triaged_by": "admin@example.com", "triaged_at": "2020-01-02T03:04:05Z"}]}}`)
+	r := httptest.NewRequest(http.MethodPost, requestURL, body)
+	wh.ImportExpectationsHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{"deltas":[{"grouping":"test_one","digest":"aaa0ddfc45a95372747804fc75061fc1","label":"positive","user":"importer@example.com","triaged_by":"admin@example.com"}],"applied":true}`, w)
+}
+
+// TestImportExpectationsHandler_NotLoggedIn_Unauthorized tests that only logged-in users can
+// import expectations.
+func TestImportExpectationsHandler_NotLoggedIn_Unauthorized(t *testing.T) {
+	unittest.SmallTest(t)
+
+	wh := Handlers{}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, requestURL, strings.NewReader(`{}`))
+	wh.ImportExpectationsHandler(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+}
+
 // Because we are calling our handlers directly, the target URL doesn't matter. The target URL
 // would only matter if we were calling into the router, so it knew which handler to call.
 const requestURL = "/does/not/matter"
 
 var (
-	// These dates are arbitrary and don't matter. The logic for determining if an alert has
-	// "expired" is handled on the frontend.
+	// ignoreRulesNow is after the first rule expired and before the other two expire.
+	ignoreRulesNow   = time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
 	firstRuleExpire  = time.Date(2019, time.November, 30, 3, 4, 5, 0, time.UTC)
 	secondRuleExpire = time.Date(2020, time.November, 30, 3, 4, 5, 0, time.UTC)
 	thirdRuleExpire  = time.Date(2020, time.November, 27, 3, 4, 5, 0, time.UTC)
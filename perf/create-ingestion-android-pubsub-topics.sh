@@ -8,5 +8,5 @@ source ../kube/config.sh
 
 TOPIC=perf-ingestion-android-production
 
-perf-tool config create-pubsub-topics --big_table_config=android-prod
+perf-tool config create-pubsub-topics --config_filename=./configs/android-prod.json
 gsutil notification create -f json -e OBJECT_FINALIZE -t projects/${PROJECT_ID}/topics/${TOPIC} -p android-master-ingest gs://skia-perf
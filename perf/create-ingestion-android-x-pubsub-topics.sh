@@ -8,5 +8,5 @@ source ../kube/config.sh
 
 TOPIC=perf-ingestion-android-x-production
 
-perf-tool config create-pubsub-topics --big_table_config=android-x
+perf-tool config create-pubsub-topics --config_filename=./configs/android-x.json
 gsutil notification create -f json -e OBJECT_FINALIZE -t projects/${PROJECT_ID}/topics/${TOPIC} -p android-master-ingest gs://skia-perf
@@ -8,5 +8,5 @@ source ../kube/config.sh
 
 TOPIC=perf-ingestion-ct-production
 
-perf-tool config create-pubsub-topics --big_table_config=ct-prod
+perf-tool config create-pubsub-topics --config_filename=./configs/ct-prod.json
 gsutil notification create -f json -e OBJECT_FINALIZE -t projects/${PROJECT_ID}/topics/${TOPIC} -p ingest gs://cluster-telemetry-perf
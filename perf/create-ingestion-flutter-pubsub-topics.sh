@@ -7,5 +7,5 @@ set -e -x
 PROJECT_ID=skia-public
 TOPIC=perf-ingestion-flutter
 
-perf-tool config create-pubsub-topics --big_table_config=flutter
+perf-tool config create-pubsub-topics --config_filename=./configs/flutter.json
 gsutil notification create -f json -e OBJECT_FINALIZE -t projects/${PROJECT_ID}/topics/${TOPIC} -p flutter-engine gs://flutter-skia-perf
@@ -0,0 +1,57 @@
+// Package dsactivitystore implements activitylog.Store using Cloud Datastore.
+package dsactivitystore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.skia.org/infra/go/ds"
+	"go.skia.org/infra/perf/go/activitylog"
+	"google.golang.org/api/iterator"
+)
+
+// DSActivityStore implements activitylog.Store.
+type DSActivityStore struct{}
+
+// New returns a new DSActivityStore. Note that ds.Init must be called before
+// using the returned store.
+func New() *DSActivityStore {
+	return &DSActivityStore{}
+}
+
+// Write implements activitylog.Store.
+func (s *DSActivityStore) Write(ctx context.Context, r *activitylog.Activity) error {
+	if r.TS == 0 {
+		r.TS = time.Now().Unix()
+	}
+	key := ds.NewKey(ds.ACTIVITY)
+	key, err := ds.DS.Put(ctx, key, r)
+	if err != nil {
+		return fmt.Errorf("Failed to store activity: %s", err)
+	}
+	r.ID = key.ID
+	return nil
+}
+
+// GetRecent implements activitylog.Store.
+func (s *DSActivityStore) GetRecent(ctx context.Context, n int) ([]*activitylog.Activity, error) {
+	ret := []*activitylog.Activity{}
+	q := ds.NewQuery(ds.ACTIVITY).EventualConsistency().Limit(n).Order("-TS")
+	it := ds.DS.Run(ctx, q)
+	for {
+		a := &activitylog.Activity{}
+		k, err := it.Next(a)
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("Failed retrieving activity list: %s", err)
+		}
+		a.ID = k.ID
+		ret = append(ret, a)
+	}
+	return ret, nil
+}
+
+// Confirm we implement the interface.
+var _ activitylog.Store = (*DSActivityStore)(nil)
@@ -0,0 +1,18 @@
+package dsactivitystore
+
+import (
+	"testing"
+
+	"go.skia.org/infra/go/ds"
+	"go.skia.org/infra/go/ds/testutil"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/activitylog/shared_tests"
+)
+
+func TestDSActivityStore(t *testing.T) {
+	unittest.ManualTest(t)
+	cleanup := testutil.InitDatastore(t, ds.ACTIVITY)
+	defer cleanup()
+
+	shared_tests.TestActivityStore(t, New())
+}
@@ -0,0 +1,64 @@
+// Package memactivitystore implements activitylog.Store in memory, which is
+// useful for testing and for running Perf locally.
+package memactivitystore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.skia.org/infra/perf/go/activitylog"
+)
+
+// MemActivityStore implements activitylog.Store.
+type MemActivityStore struct {
+	// mutex protects activities and lastID.
+	mutex      sync.Mutex
+	activities []activitylog.Activity
+	lastID     int64
+}
+
+// New returns a new MemActivityStore.
+func New() *MemActivityStore {
+	return &MemActivityStore{
+		activities: []activitylog.Activity{},
+	}
+}
+
+// Write implements activitylog.Store.
+func (s *MemActivityStore) Write(ctx context.Context, r *activitylog.Activity) error {
+	if r.TS == 0 {
+		r.TS = time.Now().Unix()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastID++
+	r.ID = s.lastID
+	s.activities = append(s.activities, *r)
+	return nil
+}
+
+// GetRecent implements activitylog.Store.
+func (s *MemActivityStore) GetRecent(ctx context.Context, n int) ([]*activitylog.Activity, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ret := make([]*activitylog.Activity, 0, len(s.activities))
+	for i := range s.activities {
+		a := s.activities[i]
+		ret = append(ret, &a)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].TS == ret[j].TS {
+			return ret[i].ID > ret[j].ID
+		}
+		return ret[i].TS > ret[j].TS
+	})
+	if len(ret) > n {
+		ret = ret[:n]
+	}
+	return ret, nil
+}
+
+// Confirm we implement the interface.
+var _ activitylog.Store = (*MemActivityStore)(nil)
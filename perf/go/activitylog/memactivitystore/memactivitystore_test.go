@@ -0,0 +1,14 @@
+package memactivitystore
+
+import (
+	"testing"
+
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/activitylog/shared_tests"
+)
+
+func TestMemActivityStore(t *testing.T) {
+	unittest.SmallTest(t)
+
+	shared_tests.TestActivityStore(t, New())
+}
@@ -0,0 +1,78 @@
+// Package shared_tests contains tests that every implementation of
+// activitylog.Store should pass.
+package shared_tests
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/go/testutils"
+	"go.skia.org/infra/perf/go/activitylog"
+)
+
+// TestActivityStore exercises all the methods of activitylog.Store. The store
+// must be empty.
+func TestActivityStore(t sktest.TestingT, store activitylog.Store) {
+	ctx := context.Background()
+
+	// Start empty.
+	list, err := store.GetRecent(ctx, 2)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	// Add one activity.
+	a := &activitylog.Activity{
+		UserID: "user@example.com",
+		Action: "Triage",
+		URL:    "https://perf.skia.org/t/",
+		TS:     time.Now().Unix() - 10,
+	}
+	err = store.Write(ctx, a)
+	require.NoError(t, err)
+
+	// Confirm it's there.
+	err = testutils.EventuallyConsistent(time.Second, func() error {
+		list, err = store.GetRecent(ctx, 2)
+		require.NoError(t, err)
+		if len(list) != 1 {
+			return testutils.TryAgainErr
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", list[0].UserID)
+	assert.Equal(t, "Triage", list[0].Action)
+	assert.Equal(t, "https://perf.skia.org/t/", list[0].URL)
+
+	// Add another item, leaving TS unset so it is filled in.
+	b := &activitylog.Activity{
+		UserID: "somebody@example.org",
+		Action: "Alert Create",
+	}
+	err = store.Write(ctx, b)
+	require.NoError(t, err)
+	assert.NotZero(t, b.TS)
+
+	// Confirm they're both there, newest first, with different ids.
+	err = testutils.EventuallyConsistent(time.Second, func() error {
+		list, err = store.GetRecent(ctx, 2)
+		require.NoError(t, err)
+		if len(list) != 2 {
+			return testutils.TryAgainErr
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "somebody@example.org", list[0].UserID)
+	assert.Equal(t, "user@example.com", list[1].UserID)
+	assert.NotEqual(t, list[0].ID, list[1].ID)
+
+	// Confirm GetRecent honors its argument.
+	list, err = store.GetRecent(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "somebody@example.org", list[0].UserID)
+}
@@ -0,0 +1,77 @@
+// Package sqlactivitystore implements activitylog.Store on top of an SQL
+// database. The same SQL is used for both SQLite and Postgres.
+package sqlactivitystore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/perf/go/activitylog"
+)
+
+// schema creates the Activities table.
+//
+// SQLite and Postgres disagree on how to declare auto-incrementing columns, so
+// the id is assigned by SQLActivityStore.
+const schema = `CREATE TABLE IF NOT EXISTS Activities (
+	id       BIGINT PRIMARY KEY,
+	ts       BIGINT NOT NULL,
+	user_id  TEXT NOT NULL,
+	action   TEXT NOT NULL,
+	url      TEXT NOT NULL
+)`
+
+// SQLActivityStore implements activitylog.Store.
+type SQLActivityStore struct {
+	db *sql.DB
+}
+
+// New returns a new SQLActivityStore, creating the Activities table if it
+// doesn't already exist.
+func New(db *sql.DB) (*SQLActivityStore, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, skerr.Wrapf(err, "Failed to create Activities table.")
+	}
+	return &SQLActivityStore{
+		db: db,
+	}, nil
+}
+
+// Write implements activitylog.Store.
+func (s *SQLActivityStore) Write(ctx context.Context, r *activitylog.Activity) error {
+	if r.TS == 0 {
+		r.TS = time.Now().Unix()
+	}
+	r.ID = time.Now().UnixNano()
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO Activities (id, ts, user_id, action, url) VALUES ($1, $2, $3, $4, $5)`, r.ID, r.TS, r.UserID, r.Action, r.URL); err != nil {
+		return skerr.Wrapf(err, "Failed to store activity.")
+	}
+	return nil
+}
+
+// GetRecent implements activitylog.Store.
+func (s *SQLActivityStore) GetRecent(ctx context.Context, n int) ([]*activitylog.Activity, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, ts, user_id, action, url FROM Activities ORDER BY ts DESC, id DESC LIMIT $1`, n)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed retrieving activity list.")
+	}
+	defer util.Close(rows)
+	ret := []*activitylog.Activity{}
+	for rows.Next() {
+		a := &activitylog.Activity{}
+		if err := rows.Scan(&a.ID, &a.TS, &a.UserID, &a.Action, &a.URL); err != nil {
+			return nil, skerr.Wrapf(err, "Failed reading activity.")
+		}
+		ret = append(ret, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, skerr.Wrapf(err, "Failed retrieving activity list.")
+	}
+	return ret, nil
+}
+
+// Confirm we implement the interface.
+var _ activitylog.Store = (*SQLActivityStore)(nil)
@@ -0,0 +1,37 @@
+package sqlactivitystore
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/activitylog/shared_tests"
+)
+
+func TestSQLActivityStore(t *testing.T) {
+	unittest.MediumTest(t)
+	tmpDir, err := ioutil.TempDir("", "sqlactivitystore")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tmpDir))
+	}()
+	db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "activities.db"))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, db.Close())
+	}()
+	store, err := New(db)
+	require.NoError(t, err)
+
+	shared_tests.TestActivityStore(t, store)
+
+	// Creating the store again on the same database is fine.
+	_, err = New(db)
+	assert.NoError(t, err)
+}
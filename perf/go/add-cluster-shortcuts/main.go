@@ -12,7 +12,9 @@ import (
 	"go.skia.org/infra/go/ds"
 	"go.skia.org/infra/go/sklog"
 	"go.skia.org/infra/perf/go/regression"
+	"go.skia.org/infra/perf/go/regression/dsregressionstore"
 	"go.skia.org/infra/perf/go/shortcut2"
+	"go.skia.org/infra/perf/go/shortcut2/dsshortcutstore"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
@@ -34,9 +36,10 @@ func main() {
 	}
 	q := ds.NewQuery(ds.REGRESSION)
 	ctx := context.Background()
+	shortcutStore := dsshortcutstore.New()
 	it := ds.DS.Run(ctx, q)
 	for {
-		var dsRegression regression.DSRegression
+		var dsRegression dsregressionstore.DSRegression
 		key, err := it.Next(&dsRegression)
 		if err == iterator.Done {
 			break
@@ -52,14 +55,14 @@ func main() {
 			var err error
 			if r.High != nil {
 				sklog.Infof("High shortcut before: %q", r.High.Shortcut)
-				if r.High.Shortcut, err = shortcut2.InsertShortcut(&shortcut2.Shortcut{Keys: r.High.Keys}); err != nil {
+				if r.High.Shortcut, err = shortcutStore.InsertShortcut(ctx, &shortcut2.Shortcut{Keys: r.High.Keys}); err != nil {
 					sklog.Fatal(err)
 				}
 				sklog.Infof("shortcut: %q", r.High.Shortcut)
 			}
 			if r.Low != nil {
 				sklog.Infof("Low shortcut before: %q", r.Low.Shortcut)
-				if r.Low.Shortcut, err = shortcut2.InsertShortcut(&shortcut2.Shortcut{Keys: r.Low.Keys}); err != nil {
+				if r.Low.Shortcut, err = shortcutStore.InsertShortcut(ctx, &shortcut2.Shortcut{Keys: r.Low.Keys}); err != nil {
 					sklog.Fatal(err)
 				}
 				sklog.Infof("shortcut: %q", r.Low.Shortcut)
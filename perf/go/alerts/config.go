@@ -25,7 +25,7 @@ type Alert struct {
 	DisplayName    string                            `json:"display_name"     datastore:",noindex"`
 	Query          string                            `json:"query"            datastore:",noindex"` // The query to perform on the trace store to select the traces to alert on.
 	Alert          string                            `json:"alert"            datastore:",noindex"` // Email address or id of a chat room to send alerts to.
-	Interesting    float32                           `json:"interesting"      datastore:",noindex"` // The regression interestingness threshold.
+	Interesting    float32                           `json:"interesting"      datastore:",noindex"` // The regression interestingness threshold. For MANNWHITNEYU_STEP it is the p-value in (0, 1) below which a step is a regression.
 	BugURITemplate string                            `json:"bug_uri_template" datastore:",noindex"` // URI Template used for reporting bugs. Format TBD.
 	Algo           types.RegressionDetectionGrouping `json:"algo"             datastore:",noindex"` // Which clustering algorithm to use.
 	Step           types.StepDetection               `json:"step"             datastore:",noindex"` // Which algorithm to use to detect steps.
@@ -39,6 +39,7 @@ type Alert struct {
 	Sparse         bool                              `json:"sparse"           datastore:",noindex"` // Data is sparse, so only include commits that have data.
 	MinimumNum     int                               `json:"minimum_num"      datastore:",noindex"` // How many traces need to be found interesting before an alert is fired.
 	Category       string                            `json:"category"         datastore:",noindex"` // Which category this alert falls into.
+	Notifications  []Notification                    `json:"notifications"    datastore:",noindex"` // Additional channels that notifications are sent to, see NotificationChannels().
 }
 
 func (c *Alert) IdAsString() string {
@@ -205,6 +206,14 @@ func (c *Alert) Validate() error {
 			}
 		}
 	}
+	if c.Step == types.MANNWHITNEYU_STEP && (c.Interesting <= 0 || c.Interesting >= 1) {
+		return fmt.Errorf("Invalid Config: The threshold for Mann-Whitney U step detection is a p-value and must be in (0, 1), got %g.", c.Interesting)
+	}
+	for _, n := range c.Notifications {
+		if err := n.Validate(); err != nil {
+			return fmt.Errorf("Invalid Config: %s", err)
+		}
+	}
 	if c.StepUpOnly {
 		c.StepUpOnly = false
 		c.Direction = UP
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.skia.org/infra/go/paramtools"
 	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/types"
 )
 
 func TestConfig(t *testing.T) {
@@ -47,6 +48,24 @@ func TestValidate(t *testing.T) {
 	assert.Error(t, a.Validate())
 }
 
+func TestValidate_MannWhitneyUThreshold(t *testing.T) {
+	unittest.SmallTest(t)
+	a := NewConfig()
+	a.Step = types.MANNWHITNEYU_STEP
+
+	a.Interesting = 0.05
+	assert.NoError(t, a.Validate())
+
+	// A threshold meant for the other step detection algorithms would flag
+	// every trace.
+	a.Interesting = 50
+	assert.Error(t, a.Validate())
+	a.Interesting = 1
+	assert.Error(t, a.Validate())
+	a.Interesting = 0
+	assert.Error(t, a.Validate())
+}
+
 func TestGroupedBy(t *testing.T) {
 	unittest.SmallTest(t)
 	testCases := []struct {
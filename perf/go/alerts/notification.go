@@ -0,0 +1,106 @@
+package alerts
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// NotificationType is the kind of channel a notification is sent over.
+type NotificationType string
+
+// NotificationType constants.
+const (
+	EmailNotification   NotificationType = "email"   // Target is a comma separated list of email addresses.
+	WebhookNotification NotificationType = "webhook" // Target is an https URL that a JSON payload is POST'd to, see ValidateWebhookURL.
+	ChatNotification    NotificationType = "chat"    // Target is the name of a chat room known to go/chatbot.
+	IssueNotification   NotificationType = "issue"   // Target is the issue tracker component to file bugs in.
+)
+
+// AllNotificationTypes is a list of all the valid NotificationTypes.
+var AllNotificationTypes = []NotificationType{
+	EmailNotification,
+	WebhookNotification,
+	ChatNotification,
+	IssueNotification,
+}
+
+// Notification is a single channel that notifications for an Alert are sent
+// to.
+type Notification struct {
+	Type   NotificationType `json:"type"`
+	Target string           `json:"target"`
+}
+
+// Validate returns an error if the Notification is not valid.
+func (n Notification) Validate() error {
+	valid := false
+	for _, t := range AllNotificationTypes {
+		if n.Type == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("Unknown notification type: %q", n.Type)
+	}
+	if strings.TrimSpace(n.Target) == "" {
+		return fmt.Errorf("Notification of type %q has no target.", n.Type)
+	}
+	if n.Type == WebhookNotification {
+		if err := ValidateWebhookURL(n.Target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateWebhookURL returns an error if 'target' isn't a valid webhook URL.
+//
+// Webhooks must be https URLs without credentials. Since the payload is
+// POST'd from the Perf server, URLs that point at the server itself or at
+// link-local addresses, such as the GCE metadata server, are also rejected.
+func ValidateWebhookURL(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("Webhook target is not a valid URL: %q: %s", target, err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("Webhook target must be an https URL: %q", target)
+	}
+	if u.User != nil {
+		return fmt.Errorf("Webhook target must not contain credentials: %q", target)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("Webhook target has no host: %q", target)
+	}
+	if strings.EqualFold(host, "localhost") {
+		return fmt.Errorf("Webhook target must not be localhost: %q", target)
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()) {
+		return fmt.Errorf("Webhook target must not be a loopback or link-local address: %q", target)
+	}
+	return nil
+}
+
+// NotificationChannels returns all the channels that notifications for this
+// alert should be sent to. The legacy Alert field, if set, is treated as an
+// EmailNotification.
+func (c *Alert) NotificationChannels() []Notification {
+	ret := []Notification{}
+	if strings.TrimSpace(c.Alert) != "" {
+		ret = append(ret, Notification{
+			Type:   EmailNotification,
+			Target: c.Alert,
+		})
+	}
+	for _, n := range c.Notifications {
+		if n.Type == EmailNotification && n.Target == c.Alert {
+			continue
+		}
+		ret = append(ret, n)
+	}
+	return ret
+}
@@ -21,6 +21,7 @@ import (
 	"go.skia.org/infra/perf/go/btts_testutils"
 	"go.skia.org/infra/perf/go/config"
 	"go.skia.org/infra/perf/go/types"
+	"go.skia.org/infra/perf/go/types/shared_tests"
 )
 
 var (
@@ -381,6 +382,19 @@ func TestTraces(t *testing.T) {
 	assert.Equal(t, "", s)
 }
 
+func TestBigTableTraceStore_SharedTests(t *testing.T) {
+	unittest.LargeTest(t)
+	unittest.RequiresBigTableEmulator(t)
+
+	ctx := context.Background()
+	btts_testutils.CreateTestTable(t)
+	defer btts_testutils.CleanUpTestTable(t)
+
+	b, err := NewBigTableTraceStoreFromConfig(ctx, cfg, &btts_testutils.MockTS{}, true)
+	assert.NoError(t, err)
+	shared_tests.TestTraceStore(t, b)
+}
+
 func TestTileKey(t *testing.T) {
 	unittest.SmallTest(t)
 
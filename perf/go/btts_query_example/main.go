@@ -17,7 +17,8 @@ import (
 
 // flags
 var (
-	local = flag.Bool("local", false, "True if running locally.")
+	configFilename = flag.String("config_filename", "./configs/nano.json", "The name of the JSON or JSON5 config file that describes the Perf instance.")
+	local          = flag.Bool("local", false, "True if running locally.")
 )
 
 func main() {
@@ -29,7 +30,10 @@ func main() {
 	}
 
 	// Create the store client.
-	cfg := config.PERF_BIGTABLE_CONFIGS[config.NANO]
+	cfg, err := config.InstanceConfigFromFile(*configFilename)
+	if err != nil {
+		sklog.Fatal(err)
+	}
 	store, err := btts.NewBigTableTraceStoreFromConfig(ctx, cfg, ts, false)
 	if err != nil {
 		sklog.Fatalf("Failed to create client: %s", err)
@@ -0,0 +1,171 @@
+// Package builders builds objects from config.InstanceConfig objects.
+//
+// These are functions separate from config.InstanceConfig so that we don't end
+// up with cyclical import issues.
+package builders
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	// Register the SQL drivers used by sqltracestore.
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/perf/go/activitylog"
+	"go.skia.org/infra/perf/go/activitylog/dsactivitystore"
+	"go.skia.org/infra/perf/go/activitylog/memactivitystore"
+	"go.skia.org/infra/perf/go/activitylog/sqlactivitystore"
+	"go.skia.org/infra/perf/go/btts"
+	"go.skia.org/infra/perf/go/config"
+	"go.skia.org/infra/perf/go/regression"
+	"go.skia.org/infra/perf/go/regression/dsregressionstore"
+	"go.skia.org/infra/perf/go/regression/memregressionstore"
+	"go.skia.org/infra/perf/go/regression/sqlregressionstore"
+	"go.skia.org/infra/perf/go/shortcut2"
+	"go.skia.org/infra/perf/go/shortcut2/dsshortcutstore"
+	"go.skia.org/infra/perf/go/shortcut2/memshortcutstore"
+	"go.skia.org/infra/perf/go/shortcut2/sqlshortcutstore"
+	"go.skia.org/infra/perf/go/sqltracestore"
+	"go.skia.org/infra/perf/go/trybot"
+	"go.skia.org/infra/perf/go/trybot/memtrybotstore"
+	"go.skia.org/infra/perf/go/trybot/sqltrybotstore"
+	"go.skia.org/infra/perf/go/types"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// dbMutex protects dbs.
+	dbMutex sync.Mutex
+
+	// dbs caches the open databases, keyed by connection string, so that
+	// all the stores for an instance share a single *sql.DB.
+	dbs = map[string]*sql.DB{}
+)
+
+// newDBFromConfig returns the *sql.DB for the SQL datastore in the
+// InstanceConfig.
+func newDBFromConfig(cfg *config.InstanceConfig) (*sql.DB, error) {
+	if !cfg.DataStoreType.IsSQL() {
+		return nil, skerr.Fmt("Not an SQL DataStoreType: %q", cfg.DataStoreType)
+	}
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+	if db, ok := dbs[cfg.ConnectionString]; ok {
+		return db, nil
+	}
+	db, err := sql.Open(string(cfg.DataStoreType), cfg.ConnectionString)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed to open database.")
+	}
+	if cfg.DataStoreType == config.SQLite3DataStoreType {
+		// SQLite only allows a single writer at a time.
+		db.SetMaxOpenConns(1)
+	}
+	dbs[cfg.ConnectionString] = db
+	return db, nil
+}
+
+// NewTraceStoreFromConfig creates a new types.TraceStore from the
+// InstanceConfig.
+//
+// The 'ts' TokenSource is only used for BigTable. If cacheOps is true then
+// the BigTable store caches OrderedParamSets, which should only be done by
+// ingesters.
+func NewTraceStoreFromConfig(ctx context.Context, cfg *config.InstanceConfig, ts oauth2.TokenSource, cacheOps bool) (types.TraceStore, error) {
+	switch cfg.DataStoreType {
+	case "", config.BigTableDataStoreType:
+		return btts.NewBigTableTraceStoreFromConfig(ctx, cfg, ts, cacheOps)
+	case config.SQLite3DataStoreType, config.PostgresDataStoreType:
+		db, err := newDBFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sqltracestore.New(db, cfg.TileSize)
+	}
+	return nil, skerr.Fmt("Unknown DataStoreType: %q", cfg.DataStoreType)
+}
+
+// NewRegressionStoreFromConfig creates a new regression.Store from the
+// InstanceConfig.
+//
+// The datastore store requires that ds.Init has already been called.
+func NewRegressionStoreFromConfig(cfg *config.InstanceConfig) (regression.Store, error) {
+	switch cfg.GetStoreType() {
+	case config.DatastoreStoreType:
+		return dsregressionstore.New(), nil
+	case config.MemoryStoreType:
+		return memregressionstore.New(), nil
+	case config.SQLStoreType:
+		db, err := newDBFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sqlregressionstore.New(db)
+	}
+	return nil, skerr.Fmt("Unknown StoreType: %q", cfg.StoreType)
+}
+
+// NewShortcutStoreFromConfig creates a new shortcut2.Store from the
+// InstanceConfig.
+//
+// The datastore store requires that ds.Init has already been called.
+func NewShortcutStoreFromConfig(cfg *config.InstanceConfig) (shortcut2.Store, error) {
+	switch cfg.GetStoreType() {
+	case config.DatastoreStoreType:
+		return dsshortcutstore.New(), nil
+	case config.MemoryStoreType:
+		return memshortcutstore.New(), nil
+	case config.SQLStoreType:
+		db, err := newDBFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sqlshortcutstore.New(db)
+	}
+	return nil, skerr.Fmt("Unknown StoreType: %q", cfg.StoreType)
+}
+
+// NewActivityStoreFromConfig creates a new activitylog.Store from the
+// InstanceConfig.
+//
+// The datastore store requires that ds.Init has already been called.
+func NewActivityStoreFromConfig(cfg *config.InstanceConfig) (activitylog.Store, error) {
+	switch cfg.GetStoreType() {
+	case config.DatastoreStoreType:
+		return dsactivitystore.New(), nil
+	case config.MemoryStoreType:
+		return memactivitystore.New(), nil
+	case config.SQLStoreType:
+		db, err := newDBFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sqlactivitystore.New(db)
+	}
+	return nil, skerr.Fmt("Unknown StoreType: %q", cfg.StoreType)
+}
+
+// NewTryBotStoreFromConfig creates a new trybot.Store from the
+// InstanceConfig.
+//
+// Trybot results are only supported for the SQL and memory StoreTypes. The
+// datastore StoreType, which is the default for BigTable instances, gets no
+// trybot store at all. A memory store is only visible to the process that
+// created it, so perf-ingest only writes trybot results to a SQL store.
+func NewTryBotStoreFromConfig(cfg *config.InstanceConfig) (trybot.Store, error) {
+	switch cfg.GetStoreType() {
+	case config.DatastoreStoreType:
+		return nil, skerr.Fmt("Trybot results are not supported for StoreType %q.", config.DatastoreStoreType)
+	case config.MemoryStoreType:
+		return memtrybotstore.New(), nil
+	case config.SQLStoreType:
+		db, err := newDBFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sqltrybotstore.New(db)
+	}
+	return nil, skerr.Fmt("Unknown StoreType: %q", cfg.StoreType)
+}
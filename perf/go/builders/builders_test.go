@@ -0,0 +1,128 @@
+package builders
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/activitylog/memactivitystore"
+	"go.skia.org/infra/perf/go/activitylog/sqlactivitystore"
+	"go.skia.org/infra/perf/go/config"
+	"go.skia.org/infra/perf/go/regression/memregressionstore"
+	"go.skia.org/infra/perf/go/regression/sqlregressionstore"
+	"go.skia.org/infra/perf/go/shortcut2/memshortcutstore"
+	"go.skia.org/infra/perf/go/shortcut2/sqlshortcutstore"
+	"go.skia.org/infra/perf/go/sqltracestore"
+	"go.skia.org/infra/perf/go/trybot/memtrybotstore"
+	"go.skia.org/infra/perf/go/trybot/sqltrybotstore"
+)
+
+func TestNewTraceStoreFromConfig_SQLite(t *testing.T) {
+	unittest.MediumTest(t)
+	tmpDir, err := ioutil.TempDir("", "builders")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	cfg := &config.InstanceConfig{
+		DataStoreType:    config.SQLite3DataStoreType,
+		ConnectionString: filepath.Join(tmpDir, "traces.db"),
+		TileSize:         256,
+	}
+	store, err := NewTraceStoreFromConfig(context.Background(), cfg, nil, false)
+	require.NoError(t, err)
+	assert.IsType(t, &sqltracestore.SQLTraceStore{}, store)
+	assert.Equal(t, int32(256), store.TileSize())
+}
+
+func TestNewTraceStoreFromConfig_UnknownDataStoreType(t *testing.T) {
+	unittest.SmallTest(t)
+	cfg := &config.InstanceConfig{
+		DataStoreType: "unknown",
+		TileSize:      256,
+	}
+	_, err := NewTraceStoreFromConfig(context.Background(), cfg, nil, false)
+	assert.Error(t, err)
+}
+
+func TestNewStoresFromConfig_SQLite(t *testing.T) {
+	unittest.MediumTest(t)
+	tmpDir, err := ioutil.TempDir("", "builders")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tmpDir))
+	}()
+
+	cfg := &config.InstanceConfig{
+		DataStoreType:    config.SQLite3DataStoreType,
+		ConnectionString: filepath.Join(tmpDir, "perf.db"),
+		TileSize:         256,
+	}
+	regStore, err := NewRegressionStoreFromConfig(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &sqlregressionstore.SQLRegressionStore{}, regStore)
+
+	shortcutStore, err := NewShortcutStoreFromConfig(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &sqlshortcutstore.SQLShortcutStore{}, shortcutStore)
+
+	activityStore, err := NewActivityStoreFromConfig(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &sqlactivitystore.SQLActivityStore{}, activityStore)
+
+	tryBotStore, err := NewTryBotStoreFromConfig(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &sqltrybotstore.SQLTryBotStore{}, tryBotStore)
+}
+
+func TestNewStoresFromConfig_Memory(t *testing.T) {
+	unittest.SmallTest(t)
+	cfg := &config.InstanceConfig{
+		StoreType: config.MemoryStoreType,
+	}
+	regStore, err := NewRegressionStoreFromConfig(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &memregressionstore.MemRegressionStore{}, regStore)
+
+	shortcutStore, err := NewShortcutStoreFromConfig(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &memshortcutstore.MemShortcutStore{}, shortcutStore)
+
+	activityStore, err := NewActivityStoreFromConfig(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &memactivitystore.MemActivityStore{}, activityStore)
+
+	tryBotStore, err := NewTryBotStoreFromConfig(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &memtrybotstore.MemTryBotStore{}, tryBotStore)
+}
+
+func TestNewStoresFromConfig_UnknownStoreType(t *testing.T) {
+	unittest.SmallTest(t)
+	cfg := &config.InstanceConfig{
+		StoreType: "unknown",
+	}
+	_, err := NewRegressionStoreFromConfig(cfg)
+	assert.Error(t, err)
+	_, err = NewShortcutStoreFromConfig(cfg)
+	assert.Error(t, err)
+	_, err = NewActivityStoreFromConfig(cfg)
+	assert.Error(t, err)
+	_, err = NewTryBotStoreFromConfig(cfg)
+	assert.Error(t, err)
+}
+
+func TestNewTryBotStoreFromConfig_DatastoreNotSupported(t *testing.T) {
+	unittest.SmallTest(t)
+	cfg := &config.InstanceConfig{
+		StoreType: config.DatastoreStoreType,
+	}
+	_, err := NewTryBotStoreFromConfig(cfg)
+	assert.Error(t, err)
+}
@@ -1,6 +1,11 @@
 package config
 
-import "fmt"
+import (
+	"strings"
+
+	skconfig "go.skia.org/infra/go/config"
+	"go.skia.org/infra/go/skerr"
+)
 
 const (
 	// MAX_SAMPLE_TRACES_PER_CLUSTER  is the maximum number of traces stored in a
@@ -19,25 +24,89 @@ const (
 	CONSTRUCTOR_NANO_TRYBOT = "nano-trybot"
 )
 
-// InstanceConfig contains all the info needed by btts.BigTableTraceStore.
+// DataStoreType determines what type of datastore is used to store traces.
+type DataStoreType string
+
+const (
+	// BigTableDataStoreType stores traces in BigTable via
+	// btts.BigTableTraceStore. This is the default if no DataStoreType is
+	// given.
+	BigTableDataStoreType DataStoreType = "bigtable"
+
+	// SQLite3DataStoreType stores traces in an SQLite database via
+	// sqltracestore.SQLTraceStore. The ConnectionString is the name of the
+	// database file.
+	SQLite3DataStoreType DataStoreType = "sqlite3"
+
+	// PostgresDataStoreType stores traces in a Postgres or CockroachDB
+	// database via sqltracestore.SQLTraceStore. The ConnectionString is a
+	// Postgres connection string, e.g.
+	// "postgresql://root@localhost:26257/perf?sslmode=disable".
+	PostgresDataStoreType DataStoreType = "postgres"
+)
+
+// IsSQL returns true if the DataStoreType is one of the SQL datastores.
+func (d DataStoreType) IsSQL() bool {
+	return d == SQLite3DataStoreType || d == PostgresDataStoreType
+}
+
+// StoreType determines where regressions, shortcuts and the activity log are
+// stored.
+type StoreType string
+
+const (
+	// DatastoreStoreType stores them in Cloud Datastore. This is the default
+	// if no StoreType is given and the DataStoreType is BigTable. There is no
+	// trybot store, so trybot results are ingested into the TraceStore like
+	// any other results.
+	DatastoreStoreType StoreType = "datastore"
+
+	// SQLStoreType stores them in the same SQL database as the traces. This is
+	// the default if no StoreType is given and the DataStoreType is one of the
+	// SQL datastores. Trybot results are kept separately in the same database.
+	SQLStoreType StoreType = "sql"
+
+	// MemoryStoreType keeps them in memory, so they are lost on restart. Only
+	// useful for local testing. Since the memory isn't shared between
+	// perf-ingest and skiaperf, trybot results are ingested into the
+	// TraceStore like any other results.
+	MemoryStoreType StoreType = "memory"
+)
+
+// InstanceConfig contains all the info needed by a types.TraceStore, and the
+// ingesters and frontends that use it.
 //
-// May eventually move to a separate config file.
+// An InstanceConfig can be loaded from a JSON or JSON5 file via
+// InstanceConfigFromFile.
 type InstanceConfig struct {
-	TileSize int32
-	Project  string
-	Instance string
-	Table    string
-	Topic    string
-	GitUrl   string
-	Shards   int32
-	Sources  []string // List of gs: locations.
-	Branches []string // If populated then restrict to ingesting just these branches.
+	// DataStoreType is the type of datastore that traces are stored in.
+	// Defaults to BigTableDataStoreType if empty.
+	DataStoreType DataStoreType `json:"data_store_type,omitempty"`
+
+	// ConnectionString is used to connect to the database when DataStoreType
+	// is one of the SQL datastores. Ignored for BigTable.
+	ConnectionString string `json:"connection_string,omitempty"`
+
+	// StoreType is where regressions, shortcuts and the activity log are
+	// stored. If empty then it defaults to SQLStoreType for the SQL
+	// datastores, and DatastoreStoreType otherwise. See GetStoreType.
+	StoreType StoreType `json:"store_type,omitempty"`
+
+	TileSize int32    `json:"tile_size"`
+	Project  string   `json:"project,omitempty"`
+	Instance string   `json:"instance,omitempty"`
+	Table    string   `json:"table,omitempty"`
+	Topic    string   `json:"topic"`
+	GitUrl   string   `json:"git_url"`
+	Shards   int32    `json:"shards,omitempty"`
+	Sources  []string `json:"sources"`  // List of gs: locations.
+	Branches []string `json:"branches"` // If populated then restrict to ingesting just these branches.
 
 	// Some repos are synthetic and just contain a single file that changes,
 	// with a commit message that is a URL that points to the true source of
 	// information. If this value is true then links to commits need to be
 	// debounced and use the commit message instead.
-	DebouceCommitURL bool
+	DebouceCommitURL bool `json:"debounce_commit_url,omitempty"`
 
 	// FileIngestionTopicName is the PubSub topic name we should use if doing
 	// event driven regression detection. The ingesters use this to know where
@@ -46,92 +115,88 @@ type InstanceConfig struct {
 	//
 	// Should only be turned on for instances that have a huge amount of data,
 	// i.e. >500k traces, and that have sparse data.
-	FileIngestionTopicName string
+	FileIngestionTopicName string `json:"file_ingestion_topic_name,omitempty"`
 }
 
-const (
-	NANO         = "nano"
-	ANDROID_PROD = "android-prod"
-	CT_PROD      = "ct-prod"
-	ANDROID_X    = "android-x"
-	FLUTTER      = "flutter"
-)
+// Validate returns an error if the InstanceConfig is not valid.
+func (c *InstanceConfig) Validate() error {
+	if c.TileSize <= 0 {
+		return skerr.Fmt("tile_size must be > 0, got %d.", c.TileSize)
+	}
+	switch c.DataStoreType {
+	case "", BigTableDataStoreType:
+		if c.Project == "" || c.Instance == "" || c.Table == "" {
+			return skerr.Fmt("project, instance and table are required for a BigTable data store.")
+		}
+		if c.Shards <= 0 {
+			return skerr.Fmt("shards must be > 0 for a BigTable data store, got %d.", c.Shards)
+		}
+	case SQLite3DataStoreType, PostgresDataStoreType:
+		if c.ConnectionString == "" {
+			return skerr.Fmt("connection_string is required for data_store_type %q.", c.DataStoreType)
+		}
+	default:
+		return skerr.Fmt("Unknown data_store_type: %q", c.DataStoreType)
+	}
+	switch c.StoreType {
+	case "", DatastoreStoreType, MemoryStoreType:
+	case SQLStoreType:
+		if !c.DataStoreType.IsSQL() {
+			return skerr.Fmt("store_type %q requires an SQL data_store_type, got %q.", c.StoreType, c.DataStoreType)
+		}
+	default:
+		return skerr.Fmt("Unknown store_type: %q", c.StoreType)
+	}
+	if c.GitUrl == "" {
+		return skerr.Fmt("git_url is required.")
+	}
+	if c.Topic == "" {
+		return skerr.Fmt("topic is required.")
+	}
+	for _, source := range c.Sources {
+		if !strings.HasPrefix(source, "gs://") {
+			return skerr.Fmt("sources must all be gs:// locations, got %q.", source)
+		}
+	}
+	return nil
+}
 
-var (
-	PERF_BIGTABLE_CONFIGS = map[string]*InstanceConfig{
-		NANO: {
-			TileSize:               256,
-			Project:                "skia-public",
-			Instance:               "production",
-			Table:                  "perf-skia",
-			Topic:                  "perf-ingestion-skia-production",
-			GitUrl:                 "https://skia.googlesource.com/skia",
-			Shards:                 8,
-			Sources:                []string{"gs://skia-perf/nano-json-v1", "gs://skia-perf/task-duration", "gs://skia-perf/buildstats-json-v1"},
-			Branches:               []string{},
-			FileIngestionTopicName: "",
-		},
-		ANDROID_PROD: {
-			TileSize:               8192,
-			Project:                "skia-public",
-			Instance:               "production",
-			Table:                  "perf-android",
-			Topic:                  "perf-ingestion-android-production",
-			GitUrl:                 "https://skia.googlesource.com/perf-buildid/android-master",
-			Shards:                 8,
-			Sources:                []string{"gs://skia-perf/android-master-ingest"},
-			Branches:               []string{},
-			DebouceCommitURL:       true,
-			FileIngestionTopicName: "perf-ingestion-complete-android-production",
-		},
-		CT_PROD: {
-			TileSize:               256,
-			Project:                "skia-public",
-			Instance:               "production",
-			Table:                  "perf-ct",
-			Topic:                  "perf-ingestion-ct-production",
-			GitUrl:                 "https://skia.googlesource.com/perf-ct",
-			Shards:                 8,
-			Sources:                []string{"gs://cluster-telemetry-perf/ingest"},
-			Branches:               []string{},
-			FileIngestionTopicName: "",
-		},
-		ANDROID_X: { // https://bug.skia.org/9315
-			TileSize:               512,
-			Project:                "skia-public",
-			Instance:               "production",
-			Table:                  "perf-android-x",
-			Topic:                  "perf-ingestion-android-x-production",
-			GitUrl:                 "https://skia.googlesource.com/perf-buildid/android-master",
-			Shards:                 8,
-			Sources:                []string{"gs://skia-perf/android-master-ingest"},
-			Branches:               []string{"aosp-androidx-master-dev"},
-			DebouceCommitURL:       true,
-			FileIngestionTopicName: "",
-		},
-		FLUTTER: {
-			TileSize:               256,
-			Project:                "skia-public",
-			Instance:               "production",
-			Table:                  "perf-flutter",
-			Topic:                  "perf-ingestion-flutter",
-			GitUrl:                 "https://github.com/flutter/engine",
-			Shards:                 8,
-			Sources:                []string{"gs://flutter-skia-perf/flutter-engine"},
-			Branches:               []string{},
-			FileIngestionTopicName: "",
-		},
+// GetStoreType returns the StoreType to use, applying the defaults if
+// StoreType is empty.
+func (c *InstanceConfig) GetStoreType() StoreType {
+	if c.StoreType != "" {
+		return c.StoreType
 	}
-)
+	if c.DataStoreType.IsSQL() {
+		return SQLStoreType
+	}
+	return DatastoreStoreType
+}
+
+// InstanceConfigFromFile loads an InstanceConfig from the given JSON or JSON5
+// file and validates it.
+func InstanceConfigFromFile(filename string) (*InstanceConfig, error) {
+	if filename == "" {
+		return nil, skerr.Fmt("--config_filename is required.")
+	}
+	ret := &InstanceConfig{}
+	if err := skconfig.ParseConfigFile(filename, "--config_filename", ret); err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	if err := ret.Validate(); err != nil {
+		return nil, skerr.Wrapf(err, "Invalid config file %q", filename)
+	}
+	return ret, nil
+}
 
 // Config is the currently running config.
 var Config *InstanceConfig
 
-// Init loads the selected config by name.
-func Init(configName string) error {
-	cfg, ok := PERF_BIGTABLE_CONFIGS[configName]
-	if !ok {
-		return fmt.Errorf("Invalid config name: %q", configName)
+// Init loads the config from 'filename'. See InstanceConfigFromFile.
+func Init(filename string) error {
+	cfg, err := InstanceConfigFromFile(filename)
+	if err != nil {
+		return err
 	}
 	Config = cfg
 	return nil
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+// configsDir is the location of the config files, relative to this directory.
+var configsDir = filepath.Join("..", "..", "configs")
+
+func TestInstanceConfigFromFile_Success(t *testing.T) {
+	unittest.SmallTest(t)
+	cfg, err := InstanceConfigFromFile(filepath.Join(configsDir, "android-prod.json"))
+	require.NoError(t, err)
+	assert.Equal(t, &InstanceConfig{
+		TileSize:               8192,
+		Project:                "skia-public",
+		Instance:               "production",
+		Table:                  "perf-android",
+		Topic:                  "perf-ingestion-android-production",
+		GitUrl:                 "https://skia.googlesource.com/perf-buildid/android-master",
+		Shards:                 8,
+		Sources:                []string{"gs://skia-perf/android-master-ingest"},
+		Branches:               []string{},
+		DebouceCommitURL:       true,
+		FileIngestionTopicName: "perf-ingestion-complete-android-production",
+	}, cfg)
+}
+
+func TestInstanceConfigFromFile_AllConfigsAreValid(t *testing.T) {
+	unittest.SmallTest(t)
+	files, err := ioutil.ReadDir(configsDir)
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	for _, f := range files {
+		_, err := InstanceConfigFromFile(filepath.Join(configsDir, f.Name()))
+		assert.NoError(t, err, f.Name())
+	}
+}
+
+func TestInstanceConfigFromFile_InvalidConfigIsError(t *testing.T) {
+	unittest.SmallTest(t)
+	tmpDir, err := ioutil.TempDir("", "perf-config")
+	require.NoError(t, err)
+	defer testutils.RemoveAll(t, tmpDir)
+
+	filename := filepath.Join(tmpDir, "bad.json")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{"tile_size": 0}`), 0644))
+	_, err = InstanceConfigFromFile(filename)
+	assert.Error(t, err)
+
+	_, err = InstanceConfigFromFile(filepath.Join(tmpDir, "missing.json"))
+	assert.Error(t, err)
+
+	_, err = InstanceConfigFromFile("")
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	unittest.SmallTest(t)
+	valid := func() *InstanceConfig {
+		return &InstanceConfig{
+			TileSize: 256,
+			Project:  "skia-public",
+			Instance: "production",
+			Table:    "perf-skia",
+			Topic:    "perf-ingestion-skia-production",
+			GitUrl:   "https://skia.googlesource.com/skia",
+			Shards:   8,
+			Sources:  []string{"gs://skia-perf/nano-json-v1"},
+		}
+	}
+	assert.NoError(t, valid().Validate())
+
+	cfg := valid()
+	cfg.TileSize = 0
+	assert.Error(t, cfg.Validate())
+
+	cfg = valid()
+	cfg.Shards = 0
+	assert.Error(t, cfg.Validate())
+
+	cfg = valid()
+	cfg.GitUrl = ""
+	assert.Error(t, cfg.Validate())
+
+	cfg = valid()
+	cfg.Topic = ""
+	assert.Error(t, cfg.Validate())
+
+	cfg = valid()
+	cfg.Sources = []string{"/tmp/local/dir"}
+	assert.Error(t, cfg.Validate())
+
+	cfg = valid()
+	cfg.DataStoreType = "unknown"
+	assert.Error(t, cfg.Validate())
+
+	cfg = valid()
+	cfg.DataStoreType = SQLite3DataStoreType
+	assert.Error(t, cfg.Validate())
+	cfg.ConnectionString = "/tmp/perf.db"
+	assert.NoError(t, cfg.Validate())
+
+	cfg = valid()
+	cfg.StoreType = "unknown"
+	assert.Error(t, cfg.Validate())
+
+	cfg = valid()
+	cfg.StoreType = SQLStoreType
+	assert.Error(t, cfg.Validate())
+	cfg.DataStoreType = PostgresDataStoreType
+	cfg.ConnectionString = "postgresql://root@localhost:26257/perf?sslmode=disable"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestGetStoreType(t *testing.T) {
+	unittest.SmallTest(t)
+	cfg := &InstanceConfig{}
+	assert.Equal(t, DatastoreStoreType, cfg.GetStoreType())
+
+	cfg.DataStoreType = SQLite3DataStoreType
+	assert.Equal(t, SQLStoreType, cfg.GetStoreType())
+
+	cfg.StoreType = MemoryStoreType
+	assert.Equal(t, MemoryStoreType, cfg.GetStoreType())
+}
+
+func TestInit(t *testing.T) {
+	unittest.SmallTest(t)
+	require.NoError(t, Init(filepath.Join(configsDir, "nano.json")))
+	assert.Equal(t, "perf-skia", Config.Table)
+
+	assert.Error(t, Init(""))
+}
@@ -50,7 +50,10 @@ const (
 )
 
 var (
-	errorNotFound = errors.New("Process not found.")
+	// ErrNotFound is returned if there is no FrameRequestProcess with the
+	// given id, e.g. because it finished more than MAX_FINISHED_PROCESS_AGE
+	// ago.
+	ErrNotFound = errors.New("Process not found.")
 )
 
 // FrameRequest is used to deserialize JSON frame requests.
@@ -90,6 +93,9 @@ type FrameRequestProcess struct {
 	// dfBuilder builds DataFrame's.
 	dfBuilder DataFrameBuilder
 
+	// shortcutStore is used to look up the keys of shortcuts.
+	shortcutStore shortcut2.Store
+
 	mutex         sync.RWMutex // Protects access to the remaining struct members.
 	response      *FrameResponse
 	lastUpdate    time.Time    // The last time this process was updated.
@@ -112,6 +118,7 @@ func (fr *RunningFrameRequests) newProcess(ctx context.Context, req *FrameReques
 		state:         PROCESS_RUNNING,
 		totalSearches: len(req.Formulas) + len(req.Queries) + numKeys,
 		dfBuilder:     fr.dfBuilder,
+		shortcutStore: fr.shortcutStore,
 	}
 	go ret.Run(ctx)
 	return ret
@@ -128,15 +135,18 @@ type RunningFrameRequests struct {
 
 	dfBuilder DataFrameBuilder
 
+	shortcutStore shortcut2.Store
+
 	// inProcess maps a FrameRequest.Id() of the request to the FrameRequestProcess
 	// handling that request.
 	inProcess map[string]*FrameRequestProcess
 }
 
-func NewRunningFrameRequests(vcs vcsinfo.VCS, dfBuilder DataFrameBuilder) *RunningFrameRequests {
+func NewRunningFrameRequests(vcs vcsinfo.VCS, dfBuilder DataFrameBuilder, shortcutStore shortcut2.Store) *RunningFrameRequests {
 	fr := &RunningFrameRequests{
-		vcs:       vcs,
-		dfBuilder: dfBuilder,
+		vcs:           vcs,
+		dfBuilder:     dfBuilder,
+		shortcutStore: shortcutStore,
 
 		inProcess: map[string]*FrameRequestProcess{},
 	}
@@ -185,7 +195,7 @@ func (fr *RunningFrameRequests) Status(id string) (ProcessState, string, float32
 	fr.mutex.Lock()
 	defer fr.mutex.Unlock()
 	if p, ok := fr.inProcess[id]; !ok {
-		return PROCESS_ERROR, "", 0.0, errorNotFound
+		return PROCESS_ERROR, "", 0.0, ErrNotFound
 	} else {
 		return p.Status()
 	}
@@ -196,7 +206,7 @@ func (fr *RunningFrameRequests) Response(id string) (*FrameResponse, error) {
 	fr.mutex.Lock()
 	defer fr.mutex.Unlock()
 	if p, ok := fr.inProcess[id]; !ok {
-		return nil, errorNotFound
+		return nil, ErrNotFound
 	} else {
 		return p.Response(), nil
 	}
@@ -449,7 +459,7 @@ func (p *FrameRequestProcess) doSearch(ctx context.Context, queryStr string, beg
 // doKeys returns a DataFrame that matches the given set of keys given
 // the time range [begin, end).
 func (p *FrameRequestProcess) doKeys(keyID string, begin, end time.Time) (*DataFrame, error) {
-	keys, err := shortcut2.Get(keyID)
+	keys, err := p.shortcutStore.Get(context.Background(), keyID)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to find that set of keys %q: %s", keyID, err)
 	}
@@ -495,7 +505,7 @@ func (p *FrameRequestProcess) doCalc(formula string, begin, end time.Time) (*Dat
 	}
 
 	rowsFromShortcut := func(s string) (calc.Rows, error) {
-		keys, err := shortcut2.Get(s)
+		keys, err := p.shortcutStore.Get(context.Background(), s)
 		if err != nil {
 			return nil, err
 		}
@@ -140,3 +140,14 @@ func TestDFAppend(t *testing.T) {
 	assert.Equal(t, types.Trace{e, e, 4.3, 4.4}, r.TraceSet[",config=565,arch=arm,"])
 	assert.Equal(t, types.Trace{e, e, 3.3, 3.4}, r.TraceSet[",config=565,arch=x86,"])
 }
+
+func TestRunningFrameRequests_UnknownID_ReturnsErrNotFound(t *testing.T) {
+	unittest.SmallTest(t)
+	fr := &RunningFrameRequests{
+		inProcess: map[string]*FrameRequestProcess{},
+	}
+	_, err := fr.Response("unknown")
+	assert.Equal(t, ErrNotFound, err)
+	_, _, _, err = fr.Status("unknown")
+	assert.Equal(t, ErrNotFound, err)
+}
@@ -0,0 +1,152 @@
+package dataframe
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+
+	"go.skia.org/infra/go/query"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/vcsinfo"
+	"go.skia.org/infra/go/vec32"
+)
+
+// ExportHeader describes a single column, i.e. a commit, of an exported
+// DataFrame.
+type ExportHeader struct {
+	Offset    int64  `json:"offset"`
+	Timestamp int64  `json:"timestamp"` // In seconds from the Unix epoch.
+	Hash      string `json:"hash"`
+	Author    string `json:"author"`
+	Subject   string `json:"subject"`
+}
+
+// ExportTrace is a single trace of an exported DataFrame.
+type ExportTrace struct {
+	ID     string            `json:"id"`
+	Params map[string]string `json:"params"`
+
+	// Values has the same length and order as Export.Header. Missing data
+	// points are null.
+	Values []*float32 `json:"values"`
+}
+
+// Export is a DataFrame in a form that is easy to consume outside of Perf,
+// e.g. in a notebook.
+type Export struct {
+	Header []*ExportHeader `json:"header"`
+	Traces []*ExportTrace  `json:"traces"`
+}
+
+// NewExport returns the DataFrame as an Export. The 'vcs' is used to add the
+// commit details to the headers, and can be nil, in which case only the
+// offsets and timestamps are filled in. The traces are sorted by ID.
+func NewExport(ctx context.Context, df *DataFrame, vcs vcsinfo.VCS) (*Export, error) {
+	ret := &Export{
+		Header: make([]*ExportHeader, 0, len(df.Header)),
+		Traces: make([]*ExportTrace, 0, len(df.TraceSet)),
+	}
+	for _, h := range df.Header {
+		eh := &ExportHeader{
+			Offset:    h.Offset,
+			Timestamp: h.Timestamp,
+		}
+		if vcs != nil {
+			commit, err := vcs.ByIndex(ctx, int(h.Offset))
+			if err != nil {
+				return nil, skerr.Wrapf(err, "Failed to find commit at offset %d.", h.Offset)
+			}
+			eh.Hash = commit.Hash
+			eh.Author = commit.Author
+			eh.Subject = commit.Subject
+		}
+		ret.Header = append(ret.Header, eh)
+	}
+
+	ids := make([]string, 0, len(df.TraceSet))
+	for id := range df.TraceSet {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		params, err := query.ParseKey(id)
+		if err != nil {
+			// Calculated traces, e.g. from formulas, aren't structured keys.
+			params = map[string]string{}
+		}
+		trace := df.TraceSet[id]
+		values := make([]*float32, len(trace))
+		for i := range trace {
+			if trace[i] != vec32.MISSING_DATA_SENTINEL {
+				values[i] = &trace[i]
+			}
+		}
+		ret.Traces = append(ret.Traces, &ExportTrace{
+			ID:     id,
+			Params: params,
+			Values: values,
+		})
+	}
+	return ret, nil
+}
+
+// paramKeys returns the sorted union of all the param keys of the traces.
+func (e *Export) paramKeys() []string {
+	keys := map[string]bool{}
+	for _, tr := range e.Traces {
+		for k := range tr.Params {
+			keys[k] = true
+		}
+	}
+	ret := make([]string, 0, len(keys))
+	for k := range keys {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// WriteCSV writes the Export as CSV to 'w'.
+//
+// There is one row per trace. The columns are the trace id, then one column
+// per param key, then one column per commit. The commit columns are titled
+// with the commit hash if known, otherwise the commit offset. Missing values
+// are left empty.
+func (e *Export) WriteCSV(w io.Writer) error {
+	keys := e.paramKeys()
+	cw := csv.NewWriter(w)
+	row := make([]string, 0, 1+len(keys)+len(e.Header))
+	row = append(row, "id")
+	row = append(row, keys...)
+	for _, h := range e.Header {
+		if h.Hash != "" {
+			row = append(row, h.Hash)
+		} else {
+			row = append(row, strconv.FormatInt(h.Offset, 10))
+		}
+	}
+	if err := cw.Write(row); err != nil {
+		return skerr.Wrapf(err, "Failed to write CSV header.")
+	}
+	for _, tr := range e.Traces {
+		row = row[:0]
+		row = append(row, tr.ID)
+		for _, k := range keys {
+			row = append(row, tr.Params[k])
+		}
+		for _, v := range tr.Values {
+			if v == nil {
+				row = append(row, "")
+			} else {
+				row = append(row, strconv.FormatFloat(float64(*v), 'g', -1, 32))
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return skerr.Wrapf(err, "Failed to write CSV row.")
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,85 @@
+package dataframe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/go/vcsinfo"
+	"go.skia.org/infra/go/vcsinfo/mocks"
+	"go.skia.org/infra/go/vec32"
+	"go.skia.org/infra/perf/go/types"
+)
+
+func exportTestDataFrame() *DataFrame {
+	return &DataFrame{
+		TraceSet: types.TraceSet{
+			",config=8888,name=foo,": types.Trace{1, vec32.MISSING_DATA_SENTINEL},
+			",arch=x86,config=565,":  types.Trace{2.5, 3},
+		},
+		Header: []*ColumnHeader{
+			{Offset: 10, Timestamp: 1000},
+			{Offset: 11, Timestamp: 2000},
+		},
+	}
+}
+
+func TestNewExport_NoVCS(t *testing.T) {
+	unittest.SmallTest(t)
+	e, err := NewExport(context.Background(), exportTestDataFrame(), nil)
+	require.NoError(t, err)
+
+	require.Len(t, e.Header, 2)
+	assert.Equal(t, &ExportHeader{Offset: 10, Timestamp: 1000}, e.Header[0])
+	assert.Equal(t, &ExportHeader{Offset: 11, Timestamp: 2000}, e.Header[1])
+
+	require.Len(t, e.Traces, 2)
+	assert.Equal(t, ",arch=x86,config=565,", e.Traces[0].ID)
+	assert.Equal(t, map[string]string{"arch": "x86", "config": "565"}, e.Traces[0].Params)
+	assert.Equal(t, float32(2.5), *e.Traces[0].Values[0])
+	assert.Equal(t, float32(3), *e.Traces[0].Values[1])
+	assert.Equal(t, ",config=8888,name=foo,", e.Traces[1].ID)
+	assert.Equal(t, float32(1), *e.Traces[1].Values[0])
+	assert.Nil(t, e.Traces[1].Values[1])
+
+	// Missing values are serialized as null.
+	b, err := json.Marshal(e.Traces[1].Values)
+	require.NoError(t, err)
+	assert.Equal(t, "[1,null]", string(b))
+}
+
+func TestNewExport_WithVCS(t *testing.T) {
+	unittest.SmallTest(t)
+	vcs := &mocks.VCS{}
+	vcs.On("ByIndex", context.Background(), 10).Return(&vcsinfo.LongCommit{
+		ShortCommit: &vcsinfo.ShortCommit{Hash: "aaa", Author: "alice@example.com", Subject: "First"},
+	}, nil)
+	vcs.On("ByIndex", context.Background(), 11).Return(&vcsinfo.LongCommit{
+		ShortCommit: &vcsinfo.ShortCommit{Hash: "bbb", Author: "bob@example.com", Subject: "Second"},
+	}, nil)
+
+	e, err := NewExport(context.Background(), exportTestDataFrame(), vcs)
+	require.NoError(t, err)
+	assert.Equal(t, &ExportHeader{Offset: 10, Timestamp: 1000, Hash: "aaa", Author: "alice@example.com", Subject: "First"}, e.Header[0])
+	assert.Equal(t, &ExportHeader{Offset: 11, Timestamp: 2000, Hash: "bbb", Author: "bob@example.com", Subject: "Second"}, e.Header[1])
+	vcs.AssertExpectations(t)
+}
+
+func TestExportWriteCSV(t *testing.T) {
+	unittest.SmallTest(t)
+	e, err := NewExport(context.Background(), exportTestDataFrame(), nil)
+	require.NoError(t, err)
+	e.Header[1].Hash = "bbb"
+
+	var b bytes.Buffer
+	require.NoError(t, e.WriteCSV(&b))
+	expected := `id,arch,config,name,10,bbb
+",arch=x86,config=565,",x86,565,,2.5,3
+",config=8888,name=foo,",,8888,foo,1,
+`
+	assert.Equal(t, expected, b.String())
+}
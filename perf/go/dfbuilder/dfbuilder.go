@@ -15,7 +15,6 @@ import (
 	"go.skia.org/infra/go/timer"
 	"go.skia.org/infra/go/vcsinfo"
 	"go.skia.org/infra/go/vec32"
-	"go.skia.org/infra/perf/go/btts"
 	"go.skia.org/infra/perf/go/cid"
 	"go.skia.org/infra/perf/go/dataframe"
 	"go.skia.org/infra/perf/go/tracesetbuilder"
@@ -33,14 +32,16 @@ const (
 	NEW_N_MAX_SEARCH = 4
 )
 
-// builder implements DataFrameBuilder using btts.
+// builder implements DataFrameBuilder using a types.TraceStore.
 type builder struct {
 	vcs      vcsinfo.VCS
-	store    *btts.BigTableTraceStore
+	store    types.TraceStore
 	tileSize int32
 }
 
-func NewDataFrameBuilderFromBTTS(vcs vcsinfo.VCS, store *btts.BigTableTraceStore) dataframe.DataFrameBuilder {
+// NewDataFrameBuilderFromTraceStore returns a DataFrameBuilder that builds
+// DataFrames from the traces in 'store'.
+func NewDataFrameBuilderFromTraceStore(vcs vcsinfo.VCS, store types.TraceStore) dataframe.DataFrameBuilder {
 	return &builder{
 		vcs:      vcs,
 		store:    store,
@@ -132,10 +133,10 @@ func fromTimeRange(vcs vcsinfo.VCS, begin, end time.Time, downsample bool) ([]*d
 // should appear in the resulting Trace.
 type tileMapOffsetToIndex map[types.TileNumber]map[int32]int32
 
-// buildTileMapOffsetToIndex returns a tileMapOffsetToIndex for the given indices and the given BigTableTraceStore.
+// buildTileMapOffsetToIndex returns a tileMapOffsetToIndex for the given indices and the given TraceStore.
 //
 // The returned map is used when loading traces out of tiles.
-func buildTileMapOffsetToIndex(indices []types.CommitNumber, store *btts.BigTableTraceStore) tileMapOffsetToIndex {
+func buildTileMapOffsetToIndex(indices []types.CommitNumber, store types.TraceStore) tileMapOffsetToIndex {
 	ret := tileMapOffsetToIndex{}
 	for targetIndex, commitNumber := range indices {
 		tileNumber := store.TileNumber(commitNumber)
@@ -691,5 +692,5 @@ func (b *builder) PreflightQuery(ctx context.Context, end time.Time, q *query.Qu
 	return count, ps, nil
 }
 
-// Validate that the concrete bttsDataFrameBuilder faithfully implements the DataFrameBuidler interface.
+// Validate that the concrete builder faithfully implements the DataFrameBuidler interface.
 var _ dataframe.DataFrameBuilder = (*builder)(nil)
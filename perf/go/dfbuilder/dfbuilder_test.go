@@ -140,7 +140,7 @@ func TestBuildNew(t *testing.T) {
 			{Index: 7, Hash: "823", Timestamp: now},
 		},
 	}
-	builder := NewDataFrameBuilderFromBTTS(v, store)
+	builder := NewDataFrameBuilderFromTraceStore(v, store)
 	df, err := builder.New(nil)
 	assert.NoError(t, err)
 	assert.Len(t, df.TraceSet, 0)
@@ -20,6 +20,7 @@ import (
 	"go.skia.org/infra/perf/go/cid"
 	"go.skia.org/infra/perf/go/dataframe"
 	"go.skia.org/infra/perf/go/regression"
+	"go.skia.org/infra/perf/go/shortcut2"
 	"go.skia.org/infra/perf/go/types"
 )
 
@@ -66,16 +67,18 @@ type Running struct {
 type Requests struct {
 	cidl           *cid.CommitIDLookup
 	dfBuilder      dataframe.DataFrameBuilder
+	shortcutStore  shortcut2.Store
 	vcs            vcsinfo.VCS
 	paramsProvider regression.ParamsetProvider // TODO build the paramset from dfBuilder.
 	mutex          sync.Mutex
 	inFlight       map[string]*Running
 }
 
-func New(cidl *cid.CommitIDLookup, dfBuilder dataframe.DataFrameBuilder, paramsProvider regression.ParamsetProvider, vcs vcsinfo.VCS) *Requests {
+func New(cidl *cid.CommitIDLookup, dfBuilder dataframe.DataFrameBuilder, shortcutStore shortcut2.Store, paramsProvider regression.ParamsetProvider, vcs vcsinfo.VCS) *Requests {
 	ret := &Requests{
 		cidl:           cidl,
 		dfBuilder:      dfBuilder,
+		shortcutStore:  shortcutStore,
 		paramsProvider: paramsProvider,
 		vcs:            vcs,
 		inFlight:       map[string]*Running{},
@@ -169,7 +172,7 @@ func (d *Requests) StartHandler(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 			domain := domainFromUIDomain(req.Domain)
-			regression.RegressionsForAlert(ctx, &req.Config, domain, d.paramsProvider(), cb, d.vcs, d.cidl, d.dfBuilder, nil)
+			regression.RegressionsForAlert(ctx, &req.Config, domain, d.paramsProvider(), cb, d.vcs, d.cidl, d.dfBuilder, d.shortcutStore, nil)
 			running.mutex.Lock()
 			defer running.mutex.Unlock()
 			running.Finished = true
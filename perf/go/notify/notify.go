@@ -5,7 +5,10 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"io"
 	"regexp"
+	"strings"
+	texttemplate "text/template"
 
 	"go.skia.org/infra/go/sklog"
 	"go.skia.org/infra/perf/go/alerts"
@@ -31,14 +34,74 @@ const (
 <p>
 	With {{.Cluster.Num}} matching traces.
 </p>`
+
+	TEXT = `A Perf Regression has been found at:
+
+  https://{{.SubDomain}}.skia.org/g/t/{{.Commit.Hash}}
+
+For:
+
+  {{.Commit.URL}}
+
+With {{.Cluster.Num}} matching traces.`
+
+	TRIAGED_EMAIL = `<b>Alert Triaged</b><br><br>
+<p>
+	The Perf Regression found at:
+</p>
+<p style="padding: 1em;">
+	<a href="https://{{.SubDomain}}.skia.org/g/t/{{.Commit.Hash}}">https://{{.SubDomain}}.skia.org/g/t/{{.Commit.Hash}}</a>
+</p>
+<p>
+	Has been triaged as <b>{{.Triage.Status}}</b>.
+</p>
+{{if .Triage.Message}}<p style="padding: 1em;">
+	{{.Triage.Message}}
+</p>{{end}}`
+
+	TRIAGED_TEXT = `The Perf Regression found at:
+
+  https://{{.SubDomain}}.skia.org/g/t/{{.Commit.Hash}}
+
+Has been triaged as {{.Triage.Status}}.{{if .Triage.Message}}
+
+  {{.Triage.Message}}{{end}}`
+
+	RECOVERED_EMAIL = `<b>Alert Recovered</b><br><br>
+<p>
+	The Perf Regression found at:
+</p>
+<p style="padding: 1em;">
+	<a href="https://{{.SubDomain}}.skia.org/g/t/{{.Commit.Hash}}">https://{{.SubDomain}}.skia.org/g/t/{{.Commit.Hash}}</a>
+</p>
+<p>
+	Has recovered.
+</p>`
+
+	RECOVERED_TEXT = `The Perf Regression found at:
+
+  https://{{.SubDomain}}.skia.org/g/t/{{.Commit.Hash}}
+
+Has recovered.`
 )
 
 var (
-	emailTemplate = template.Must(template.New("email").Parse(EMAIL))
+	emailTemplate          = template.Must(template.New("email").Parse(EMAIL))
+	triagedEmailTemplate   = template.Must(template.New("triaged_email").Parse(TRIAGED_EMAIL))
+	recoveredEmailTemplate = template.Must(template.New("recovered_email").Parse(RECOVERED_EMAIL))
+
+	textTemplate          = texttemplate.Must(texttemplate.New("text").Parse(TEXT))
+	triagedTextTemplate   = texttemplate.Must(texttemplate.New("triaged_text").Parse(TRIAGED_TEXT))
+	recoveredTextTemplate = texttemplate.Must(texttemplate.New("recovered_text").Parse(RECOVERED_TEXT))
 
 	emailAddressSplitter = regexp.MustCompile("[, ]+")
 )
 
+// executor is implemented by both html/template and text/template Templates.
+type executor interface {
+	Execute(wr io.Writer, data interface{}) error
+}
+
 // Email sending interface. Note that email.GMail implements this interface.
 type Email interface {
 	Send(from string, to []string, subject string, body string) error
@@ -54,35 +117,59 @@ func (n NoEmail) Send(from string, to []string, subject string, body string) err
 
 // Notifier sends notifications.
 type Notifier struct {
-	email     Email
-	subdomain string
+	transports map[alerts.NotificationType]Transport
+	subdomain  string
 }
 
-// New returns a new Notifier.
+// New returns a new Notifier that sends email notifications via 'email'.
+//
+// Transports for the other alerts.NotificationTypes can be added via
+// AddTransport.
 func New(email Email, subdomain string) *Notifier {
 	return &Notifier{
-		email:     email,
+		transports: map[alerts.NotificationType]Transport{
+			alerts.EmailNotification: NewEmailTransport(email),
+		},
 		subdomain: subdomain,
 	}
 }
 
+// AddTransport sets the Transport used to send notifications of the given
+// type, replacing any existing Transport for that type.
+func (n *Notifier) AddTransport(notificationType alerts.NotificationType, t Transport) {
+	n.transports[notificationType] = t
+}
+
 type context struct {
 	SubDomain string
 	Commit    *cid.CommitDetail
 	Alert     *alerts.Alert
 	Cluster   *clustering2.ClusterSummary
+	Triage    *Triage
+}
+
+// Triage is the triage status of a regression, used in follow-up
+// notifications. Status is one of the regression.Status values.
+type Triage struct {
+	Status  string
+	Message string
 }
 
 func (n *Notifier) formatEmail(c *cid.CommitDetail, alert *alerts.Alert, cl *clustering2.ClusterSummary) (string, error) {
+	return n.format(emailTemplate, c, alert, cl, nil)
+}
+
+func (n *Notifier) format(tmpl executor, c *cid.CommitDetail, alert *alerts.Alert, cl *clustering2.ClusterSummary, triage *Triage) (string, error) {
 	templateContext := &context{
 		SubDomain: n.subdomain,
 		Commit:    c,
 		Alert:     alert,
 		Cluster:   cl,
+		Triage:    triage,
 	}
 
 	var b bytes.Buffer
-	if err := emailTemplate.Execute(&b, templateContext); err != nil {
+	if err := tmpl.Execute(&b, templateContext); err != nil {
 		return "", fmt.Errorf("Failed to format email body: %s", err)
 	}
 	return b.String(), nil
@@ -98,21 +185,114 @@ func splitEmails(s string) []string {
 	return ret
 }
 
+// subject returns the subject of the first notification sent for a
+// regression. Follow-ups use the same subject so they thread together.
+func subject(c *cid.CommitDetail, alert *alerts.Alert) string {
+	return fmt.Sprintf("%s - Regression found for %q", alert.DisplayName, c.Message)
+}
+
+// newMessage fills in the parts of a Message that are common to all Kinds.
+func (n *Notifier) newMessage(kind Kind, c *cid.CommitDetail, alert *alerts.Alert) *Message {
+	return &Message{
+		Kind:          kind,
+		Subject:       subject(c, alert),
+		URL:           fmt.Sprintf("https://%s.skia.org/g/t/%s", n.subdomain, c.Hash),
+		AlertID:       alert.ID,
+		AlertName:     alert.DisplayName,
+		Owner:         alert.Owner,
+		CommitHash:    c.Hash,
+		CommitURL:     c.URL,
+		CommitMessage: c.Message,
+		commit:        c,
+	}
+}
+
+// send sends the Message to every notification channel of the alert. All the
+// channels are tried even if one fails.
+func (n *Notifier) send(alert *alerts.Alert, msg *Message) error {
+	channels := alert.NotificationChannels()
+	if len(channels) == 0 {
+		return fmt.Errorf("No notification sent. No notification channels set for alert #%d", alert.ID)
+	}
+	errs := []string{}
+	for _, channel := range channels {
+		t, ok := n.transports[channel.Type]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("no transport configured for %q notifications", channel.Type))
+			continue
+		}
+		if err := t.Send(channel.Target, msg); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to send %q notification: %s", channel.Type, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Failed to send notifications for alert #%d: %s", alert.ID, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
 // Send a notification for the given cluster found at the given commit. Where to send it is defined in the alerts.Config.
 func (n *Notifier) Send(c *cid.CommitDetail, alert *alerts.Alert, cl *clustering2.ClusterSummary) error {
-	if alert.Alert == "" {
-		return fmt.Errorf("No notification sent. No email address set for alert #%d", alert.ID)
-	}
-	body, err := n.formatEmail(c, alert, cl)
+	msg, err := n.regressionMessage(c, alert, cl)
 	if err != nil {
 		return err
 	}
-	subject := fmt.Sprintf("%s - Regression found for %q", alert.DisplayName, c.Message)
-	if err := n.email.Send(FROM_ADDRESS, splitEmails(alert.Alert), subject, body); err != nil {
-		return fmt.Errorf("Failed to send email: %s", err)
+	return n.send(alert, msg)
+}
+
+// regressionMessage returns the Message for the first notification about the
+// given cluster found at the given commit.
+func (n *Notifier) regressionMessage(c *cid.CommitDetail, alert *alerts.Alert, cl *clustering2.ClusterSummary) (*Message, error) {
+	msg := n.newMessage(NewRegression, c, alert)
+	if cl != nil {
+		msg.NumTraces = cl.Num
+		if cl.StepFit != nil {
+			msg.StepStatus = string(cl.StepFit.Status)
+			msg.Regression = cl.StepFit.Regression
+		}
+	}
+	var err error
+	if msg.HTML, err = n.formatEmail(c, alert, cl); err != nil {
+		return nil, err
+	}
+	if msg.Text, err = n.format(textTemplate, c, alert, cl, nil); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// SendTriaged sends a follow-up notification that the regression found for
+// the given alert at the given commit has been triaged.
+func (n *Notifier) SendTriaged(c *cid.CommitDetail, alert *alerts.Alert, triage *Triage) error {
+	msg := n.newMessage(Triaged, c, alert)
+	msg.TriageStatus = triage.Status
+	msg.TriageMessage = triage.Message
+	var err error
+	if msg.HTML, err = n.format(triagedEmailTemplate, c, alert, nil, triage); err != nil {
+		return err
+	}
+	if msg.Text, err = n.format(triagedTextTemplate, c, alert, nil, triage); err != nil {
+		return err
 	}
+	return n.send(alert, msg)
+}
 
-	return nil
+// SendRecovered sends a follow-up notification that the regression found for
+// the given alert at the given commit has recovered, i.e. the traces have
+// returned to their values from before the regression.
+func (n *Notifier) SendRecovered(c *cid.CommitDetail, alert *alerts.Alert, cl *clustering2.ClusterSummary) error {
+	msg := n.newMessage(Recovered, c, alert)
+	if cl != nil {
+		msg.NumTraces = cl.Num
+	}
+	var err error
+	if msg.HTML, err = n.format(recoveredEmailTemplate, c, alert, cl, nil); err != nil {
+		return err
+	}
+	if msg.Text, err = n.format(recoveredTextTemplate, c, alert, cl, nil); err != nil {
+		return err
+	}
+	return n.send(alert, msg)
 }
 
 // ExampleSend sends an example for dummy data for the given alerts.Config.
@@ -125,5 +305,11 @@ func (n *Notifier) ExampleSend(alert *alerts.Alert) error {
 	cl := &clustering2.ClusterSummary{
 		Num: 10,
 	}
-	return n.Send(c, alert, cl)
+	msg, err := n.regressionMessage(c, alert, cl)
+	if err != nil {
+		return err
+	}
+	// There is no regression to record bugs against.
+	msg.commit = nil
+	return n.send(alert, msg)
 }
@@ -1,11 +1,21 @@
 package notify
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/issues"
 	"go.skia.org/infra/go/testutils/unittest"
 	"go.skia.org/infra/perf/go/alerts"
+	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/clustering2"
+	"go.skia.org/infra/perf/go/stepfit"
 )
 
 type emailMock struct {
@@ -39,3 +49,264 @@ func TestExampleSend(t *testing.T) {
 	assert.Equal(t, "MyAlert - Regression found for \"Re-enable opList dependency tracking\"", e.subject)
 	assert.Equal(t, "<b>Alert</b><br><br>\n<p>\n\tA Perf Regression has been found at:\n</p>\n<p style=\"padding: 1em;\">\n\t<a href=\"https://perf.skia.org/g/t/d261e1075a93677442fdf7fe72aba7e583863664\">https://perf.skia.org/g/t/d261e1075a93677442fdf7fe72aba7e583863664</a>\n</p>\n<p>\n  For:\n</p>\n<p style=\"padding: 1em;\">\n  <a href=\"https://skia.googlesource.com/skia/&#43;/d261e1075a93677442fdf7fe72aba7e583863664\">https://skia.googlesource.com/skia/&#43;/d261e1075a93677442fdf7fe72aba7e583863664</a>\n</p>\n<p>\n\tWith 10 matching traces.\n</p>", e.body)
 }
+
+func testCommit() *cid.CommitDetail {
+	return &cid.CommitDetail{
+		Message: "Re-enable opList dependency tracking",
+		URL:     "https://skia.googlesource.com/skia/+/d261e1075a93677442fdf7fe72aba7e583863664",
+		Hash:    "d261e1075a93677442fdf7fe72aba7e583863664",
+	}
+}
+
+func TestSend_NoChannels_ReturnsError(t *testing.T) {
+	unittest.SmallTest(t)
+
+	n := New(&emailMock{}, "perf")
+	err := n.ExampleSend(&alerts.Alert{ID: 2})
+	assert.Error(t, err)
+}
+
+func TestSend_NoTransportForChannel_ReturnsErrorButStillSendsEmail(t *testing.T) {
+	unittest.SmallTest(t)
+
+	e := &emailMock{}
+	n := New(e, "perf")
+	alert := &alerts.Alert{
+		Alert: "someone@example.org",
+		Notifications: []alerts.Notification{
+			{Type: alerts.ChatNotification, Target: "perf-room"},
+		},
+	}
+	err := n.ExampleSend(alert)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"someone@example.org"}, e.to)
+}
+
+// newWebhookTransportForTest returns a WebhookTransport that sends to the
+// test server 'ts', which listens on a loopback address that
+// alerts.ValidateWebhookURL rejects.
+func newWebhookTransportForTest(ts *httptest.Server) *WebhookTransport {
+	w := NewWebhookTransport(ts.Client())
+	w.validateURL = func(target string) error { return nil }
+	return w
+}
+
+func TestSend_Webhook(t *testing.T) {
+	unittest.SmallTest(t)
+
+	var payload Message
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer ts.Close()
+
+	n := New(&emailMock{}, "perf")
+	n.AddTransport(alerts.WebhookNotification, newWebhookTransportForTest(ts))
+	alert := &alerts.Alert{
+		ID:          12,
+		DisplayName: "MyAlert",
+		Notifications: []alerts.Notification{
+			{Type: alerts.WebhookNotification, Target: ts.URL},
+		},
+	}
+	cl := &clustering2.ClusterSummary{
+		Num: 10,
+		StepFit: &stepfit.StepFit{
+			Status:     stepfit.HIGH,
+			Regression: 5,
+		},
+	}
+	err := n.Send(testCommit(), alert, cl)
+	require.NoError(t, err)
+	assert.Equal(t, NewRegression, payload.Kind)
+	assert.Equal(t, int64(12), payload.AlertID)
+	assert.Equal(t, "MyAlert", payload.AlertName)
+	assert.Equal(t, "d261e1075a93677442fdf7fe72aba7e583863664", payload.CommitHash)
+	assert.Equal(t, "https://perf.skia.org/g/t/d261e1075a93677442fdf7fe72aba7e583863664", payload.URL)
+	assert.Equal(t, 10, payload.NumTraces)
+	assert.Equal(t, "High", payload.StepStatus)
+	assert.Equal(t, float32(5), payload.Regression)
+	assert.Contains(t, payload.Text, "With 10 matching traces.")
+	assert.Empty(t, payload.HTML)
+}
+
+func TestSend_WebhookFails_ReturnsError(t *testing.T) {
+	unittest.SmallTest(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	n := New(&emailMock{}, "perf")
+	n.AddTransport(alerts.WebhookNotification, newWebhookTransportForTest(ts))
+	alert := &alerts.Alert{
+		Notifications: []alerts.Notification{
+			{Type: alerts.WebhookNotification, Target: ts.URL},
+		},
+	}
+	assert.Error(t, n.ExampleSend(alert))
+}
+
+func TestSend_WebhookInvalidURL_NotSent(t *testing.T) {
+	unittest.SmallTest(t)
+
+	called := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer ts.Close()
+
+	n := New(&emailMock{}, "perf")
+	n.AddTransport(alerts.WebhookNotification, NewWebhookTransport(ts.Client()))
+	alert := &alerts.Alert{
+		Notifications: []alerts.Notification{
+			{Type: alerts.WebhookNotification, Target: ts.URL},
+		},
+	}
+	assert.Error(t, n.ExampleSend(alert))
+	assert.False(t, called)
+}
+
+func TestSendTriaged_ChatUsesSameThreadAsNewRegression(t *testing.T) {
+	unittest.SmallTest(t)
+
+	bodies := []string{}
+	threads := []string{}
+	chat := &ChatTransport{
+		send: func(body, room, thread string) error {
+			assert.Equal(t, "perf-room", room)
+			bodies = append(bodies, body)
+			threads = append(threads, thread)
+			return nil
+		},
+	}
+	n := New(&emailMock{}, "perf")
+	n.AddTransport(alerts.ChatNotification, chat)
+	alert := &alerts.Alert{
+		ID:          12,
+		DisplayName: "MyAlert",
+		Notifications: []alerts.Notification{
+			{Type: alerts.ChatNotification, Target: "perf-room"},
+		},
+	}
+	require.NoError(t, n.Send(testCommit(), alert, &clustering2.ClusterSummary{Num: 3}))
+	require.NoError(t, n.SendTriaged(testCommit(), alert, &Triage{Status: "negative", Message: "Filed a bug."}))
+	require.Len(t, threads, 2)
+	assert.Equal(t, threads[0], threads[1])
+	assert.Contains(t, bodies[0], "With 3 matching traces.")
+	assert.Contains(t, bodies[1], "Has been triaged as negative.")
+	assert.Contains(t, bodies[1], "Filed a bug.")
+}
+
+func TestSendRecovered_EmailIsAReply(t *testing.T) {
+	unittest.SmallTest(t)
+
+	e := &emailMock{}
+	n := New(e, "perf")
+	alert := &alerts.Alert{
+		Alert:       "someone@example.org",
+		DisplayName: "MyAlert",
+	}
+	err := n.SendRecovered(testCommit(), alert, &clustering2.ClusterSummary{Num: 3})
+	require.NoError(t, err)
+	assert.Equal(t, "Re: MyAlert - Regression found for \"Re-enable opList dependency tracking\"", e.subject)
+	assert.Contains(t, e.body, "Has recovered.")
+}
+
+type issueTrackerMock struct {
+	issues   []issues.IssueRequest
+	comments map[string][]issues.CommentRequest
+}
+
+func (i *issueTrackerMock) FromQuery(q string) ([]issues.Issue, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (i *issueTrackerMock) AddComment(id string, comment issues.CommentRequest) error {
+	i.comments[id] = append(i.comments[id], comment)
+	return nil
+}
+
+// AddIssue returns ids starting at 1234.
+func (i *issueTrackerMock) AddIssue(issue issues.IssueRequest) (int64, error) {
+	i.issues = append(i.issues, issue)
+	return int64(1233 + len(i.issues)), nil
+}
+
+// issueIDStoreMock is an in-memory IssueIDStore.
+type issueIDStoreMock map[string]int64
+
+func issueIDStoreKey(c *cid.CommitDetail, alertID int64) string {
+	return fmt.Sprintf("%s-%d", c.ID(), alertID)
+}
+
+func (s issueIDStoreMock) GetIssueID(c *cid.CommitDetail, alertID int64) (int64, error) {
+	return s[issueIDStoreKey(c, alertID)], nil
+}
+
+func (s issueIDStoreMock) SetIssueID(c *cid.CommitDetail, alertID int64, issueID int64) error {
+	s[issueIDStoreKey(c, alertID)] = issueID
+	return nil
+}
+
+func TestSend_IssueTracker_FilesBugThenComments(t *testing.T) {
+	unittest.SmallTest(t)
+
+	tracker := &issueTrackerMock{
+		comments: map[string][]issues.CommentRequest{},
+	}
+	store := issueIDStoreMock{}
+	n := New(&emailMock{}, "perf")
+	n.AddTransport(alerts.IssueNotification, NewIssueTransport(tracker, store))
+	alert := &alerts.Alert{
+		ID:          12,
+		DisplayName: "MyAlert",
+		Owner:       "owner@example.org",
+		Notifications: []alerts.Notification{
+			{Type: alerts.IssueNotification, Target: "Perf>Regressions"},
+		},
+	}
+
+	// A follow-up for a regression with no bug is an error.
+	assert.Error(t, n.SendRecovered(testCommit(), alert, nil))
+
+	require.NoError(t, n.Send(testCommit(), alert, &clustering2.ClusterSummary{Num: 3}))
+	require.Len(t, tracker.issues, 1)
+	assert.Equal(t, []string{"Perf>Regressions"}, tracker.issues[0].Components)
+	assert.Equal(t, "owner@example.org", tracker.issues[0].CC[0].Name)
+	assert.Equal(t, int64(1234), store[issueIDStoreKey(testCommit(), 12)])
+
+	// A bug for the same alert at another commit gets its own id.
+	other := testCommit()
+	other.Offset++
+	require.NoError(t, n.Send(other, alert, &clustering2.ClusterSummary{Num: 3}))
+	assert.Equal(t, int64(1235), store[issueIDStoreKey(other, 12)])
+
+	// Follow-ups are added to the bug filed for the regression.
+	require.NoError(t, n.SendTriaged(testCommit(), alert, &Triage{Status: "positive"}))
+	require.Len(t, tracker.comments["1234"], 1)
+	assert.Contains(t, tracker.comments["1234"][0].Content, "Has been triaged as positive.")
+	assert.Empty(t, tracker.comments["1235"])
+}
+
+func TestExampleSend_IssueTracker_DoesNotRecordBug(t *testing.T) {
+	unittest.SmallTest(t)
+
+	tracker := &issueTrackerMock{
+		comments: map[string][]issues.CommentRequest{},
+	}
+	store := issueIDStoreMock{}
+	n := New(&emailMock{}, "perf")
+	n.AddTransport(alerts.IssueNotification, NewIssueTransport(tracker, store))
+	alert := &alerts.Alert{
+		ID: 12,
+		Notifications: []alerts.Notification{
+			{Type: alerts.IssueNotification, Target: "Perf>Regressions"},
+		},
+	}
+	require.NoError(t, n.ExampleSend(alert))
+	assert.Len(t, tracker.issues, 1)
+	assert.Empty(t, store)
+}
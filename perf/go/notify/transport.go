@@ -0,0 +1,233 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.skia.org/infra/go/chatbot"
+	"go.skia.org/infra/go/issues"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/perf/go/alerts"
+	"go.skia.org/infra/perf/go/cid"
+)
+
+// Kind is the kind of notification being sent.
+type Kind string
+
+// Kind constants.
+const (
+	NewRegression Kind = "new"       // A new regression has been found.
+	Triaged       Kind = "triaged"   // A previously found regression has been triaged.
+	Recovered     Kind = "recovered" // A previously found regression has recovered.
+)
+
+// Message is a single notification about a regression. Each Transport picks
+// the parts of the Message that make sense for its channel.
+type Message struct {
+	Kind          Kind    `json:"kind"`
+	Subject       string  `json:"subject"`
+	HTML          string  `json:"-"`    // The body formatted as HTML, used for email.
+	Text          string  `json:"text"` // The body formatted as plain text.
+	URL           string  `json:"url"`  // The URL of the regression in Perf.
+	AlertID       int64   `json:"alert_id"`
+	AlertName     string  `json:"alert_name"`
+	Owner         string  `json:"owner"`
+	CommitHash    string  `json:"commit_hash"`
+	CommitURL     string  `json:"commit_url"`
+	CommitMessage string  `json:"commit_message"`
+	NumTraces     int     `json:"num_traces"`
+	StepStatus    string  `json:"step_status,omitempty"`
+	Regression    float32 `json:"regression,omitempty"`
+	TriageStatus  string  `json:"triage_status,omitempty"`
+	TriageMessage string  `json:"triage_message,omitempty"`
+
+	// commit is the commit the regression was found at. It is nil for
+	// example notifications, which aren't about a real regression.
+	commit *cid.CommitDetail
+}
+
+// threadKey returns a key that is the same for all the notifications about a
+// single regression.
+func (m *Message) threadKey() string {
+	return fmt.Sprintf("perf-regression-%d-%s", m.AlertID, m.CommitHash)
+}
+
+// Transport sends a Message over a single kind of notification channel.
+type Transport interface {
+	// Send the Message to the given target, where the meaning of target
+	// depends on the alerts.NotificationType the Transport handles.
+	Send(target string, msg *Message) error
+}
+
+// EmailTransport implements Transport by sending HTML email.
+type EmailTransport struct {
+	email Email
+}
+
+// NewEmailTransport returns a new EmailTransport.
+func NewEmailTransport(email Email) *EmailTransport {
+	return &EmailTransport{
+		email: email,
+	}
+}
+
+// Send implements Transport. The target is a comma or space separated list of
+// email addresses.
+func (e *EmailTransport) Send(target string, msg *Message) error {
+	subject := msg.Subject
+	if msg.Kind != NewRegression {
+		subject = "Re: " + subject
+	}
+	if err := e.email.Send(FROM_ADDRESS, splitEmails(target), subject, msg.HTML); err != nil {
+		return fmt.Errorf("Failed to send email: %s", err)
+	}
+	return nil
+}
+
+// WebhookTransport implements Transport by POSTing the Message serialized as
+// JSON to a URL.
+type WebhookTransport struct {
+	client      *http.Client
+	validateURL func(target string) error
+}
+
+// NewWebhookTransport returns a new WebhookTransport that uses the given
+// client, which should have a timeout set, e.g. httputils.NewTimeoutClient().
+func NewWebhookTransport(client *http.Client) *WebhookTransport {
+	return &WebhookTransport{
+		client:      client,
+		validateURL: alerts.ValidateWebhookURL,
+	}
+}
+
+// Send implements Transport. The target is the URL of the webhook, which must
+// pass alerts.ValidateWebhookURL.
+func (w *WebhookTransport) Send(target string, msg *Message) error {
+	if err := w.validateURL(target); err != nil {
+		return err
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("Failed to encode webhook payload: %s", err)
+	}
+	resp, err := w.client.Post(target, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("Failed to send webhook: %s", err)
+	}
+	defer util.Close(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ChatTransport implements Transport by sending chat messages via
+// go/chatbot. Note that chatbot.Init must be called before sending.
+type ChatTransport struct {
+	send func(body, room, thread string) error
+}
+
+// NewChatTransport returns a new ChatTransport.
+func NewChatTransport() *ChatTransport {
+	return &ChatTransport{
+		send: chatbot.Send,
+	}
+}
+
+// Send implements Transport. The target is the name of the chat room. All the
+// messages about a single regression are sent to the same thread.
+func (c *ChatTransport) Send(target string, msg *Message) error {
+	body := fmt.Sprintf("*%s*\n\n%s", msg.Subject, msg.Text)
+	if err := c.send(body, target, msg.threadKey()); err != nil {
+		return fmt.Errorf("Failed to send chat message: %s", err)
+	}
+	return nil
+}
+
+// IssueIDStore keeps the ids of the bugs filed by an IssueTransport, so that
+// follow-ups are added to the right bug. See regression.NewIssueIDStore.
+type IssueIDStore interface {
+	// GetIssueID returns the id of the bug filed for the regression found
+	// for the given alert at the given commit, or 0 if none was filed.
+	GetIssueID(c *cid.CommitDetail, alertID int64) (int64, error)
+
+	// SetIssueID records the id of the bug filed for the regression found
+	// for the given alert at the given commit.
+	SetIssueID(c *cid.CommitDetail, alertID int64, issueID int64) error
+}
+
+// IssueTransport implements Transport by filing a bug in an issue tracker for
+// each new regression, and commenting on that bug for any follow-ups.
+type IssueTransport struct {
+	tracker issues.IssueTracker
+	store   IssueIDStore
+}
+
+// NewIssueTransport returns a new IssueTransport that records the ids of the
+// bugs it files in 'store'.
+func NewIssueTransport(tracker issues.IssueTracker, store IssueIDStore) *IssueTransport {
+	return &IssueTransport{
+		tracker: tracker,
+		store:   store,
+	}
+}
+
+// Send implements Transport. The target is the component that bugs are filed
+// in.
+func (i *IssueTransport) Send(target string, msg *Message) error {
+	description := fmt.Sprintf("%s\n\n%s", msg.Text, msg.URL)
+	if msg.Kind == NewRegression {
+		req := issues.IssueRequest{
+			Status:      "Untriaged",
+			Labels:      []string{"Type-Bug", "Perf-Regression"},
+			Summary:     msg.Subject,
+			Description: description,
+			Components:  []string{strings.TrimSpace(target)},
+		}
+		if msg.Owner != "" {
+			req.CC = []issues.MonorailPerson{
+				{
+					Name: msg.Owner,
+					Kind: "monorail#issuePerson",
+				},
+			}
+		}
+		issueID, err := i.tracker.AddIssue(req)
+		if err != nil {
+			return fmt.Errorf("Failed to file bug: %s", err)
+		}
+		if msg.commit == nil {
+			return nil
+		}
+		if err := i.store.SetIssueID(msg.commit, msg.AlertID, issueID); err != nil {
+			return fmt.Errorf("Failed to record bug %d for %q: %s", issueID, msg.threadKey(), err)
+		}
+		return nil
+	}
+
+	if msg.commit == nil {
+		return fmt.Errorf("No bug filed for %q", msg.threadKey())
+	}
+	issueID, err := i.store.GetIssueID(msg.commit, msg.AlertID)
+	if err != nil {
+		return fmt.Errorf("Failed to find bug for %q: %s", msg.threadKey(), err)
+	}
+	if issueID == 0 {
+		return fmt.Errorf("No bug filed for %q", msg.threadKey())
+	}
+	if err := i.tracker.AddComment(fmt.Sprintf("%d", issueID), issues.CommentRequest{Content: description}); err != nil {
+		return fmt.Errorf("Failed to comment on bug %d: %s", issueID, err)
+	}
+	return nil
+}
+
+// Confirm we implement the interface.
+var (
+	_ Transport = (*EmailTransport)(nil)
+	_ Transport = (*WebhookTransport)(nil)
+	_ Transport = (*ChatTransport)(nil)
+	_ Transport = (*IssueTransport)(nil)
+)
@@ -12,7 +12,7 @@ import (
 	"go.skia.org/infra/go/sklog"
 	"go.skia.org/infra/perf/go/activitylog"
 	"go.skia.org/infra/perf/go/alerts"
-	"go.skia.org/infra/perf/go/regression"
+	"go.skia.org/infra/perf/go/regression/dsregressionstore"
 	"go.skia.org/infra/perf/go/shortcut2"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
@@ -64,7 +64,7 @@ func main() {
 	// Copy Regressions.
 	q = ds.NewQuery(ds.REGRESSION)
 	for t := srcClient.Run(ctx, q); ; {
-		var x regression.DSRegression
+		var x dsregressionstore.DSRegression
 		key, err := t.Next(&x)
 		if err == iterator.Done {
 			break
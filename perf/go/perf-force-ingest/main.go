@@ -25,11 +25,11 @@ import (
 
 // flags
 var (
-	configName = flag.String("config_name", "nano", "Name of the perf ingester config to use.")
-	local      = flag.Bool("local", false, "Running locally if true. As opposed to in production.")
-	start      = flag.String("start", "", "Start the ingestion at this time, of the form: 2006-01-02. Default to one week ago.")
-	end        = flag.String("end", "", "Ingest up to this time, of the form: 2006-01-02. Defaults to now.")
-	dryrun     = flag.Bool("dry_run", false, "Just display the list of files to send.")
+	configFilename = flag.String("config_filename", "", "The name of the JSON or JSON5 config file that describes the Perf instance, e.g. ./configs/nano.json. Required.")
+	local          = flag.Bool("local", false, "Running locally if true. As opposed to in production.")
+	start          = flag.String("start", "", "Start the ingestion at this time, of the form: 2006-01-02. Default to one week ago.")
+	end            = flag.String("end", "", "Ingest up to this time, of the form: 2006-01-02. Defaults to now.")
+	dryrun         = flag.Bool("dry_run", false, "Just display the list of files to send.")
 )
 
 func main() {
@@ -38,9 +38,9 @@ func main() {
 	)
 
 	ctx := context.Background()
-	cfg, ok := config.PERF_BIGTABLE_CONFIGS[*configName]
-	if !ok {
-		sklog.Fatalf("Invalid --config value: %q", *configName)
+	cfg, err := config.InstanceConfigFromFile(*configFilename)
+	if err != nil {
+		sklog.Fatalf("Failed to load config: %s", err)
 	}
 	ts, err := auth.NewDefaultTokenSource(*local, storage.ScopeReadOnly)
 	if err != nil {
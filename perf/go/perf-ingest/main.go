@@ -13,6 +13,7 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -32,20 +33,21 @@ import (
 	"go.skia.org/infra/go/sklog"
 	"go.skia.org/infra/go/util"
 	"go.skia.org/infra/go/vcsinfo"
-	"go.skia.org/infra/perf/go/btts"
+	"go.skia.org/infra/perf/go/builders"
 	"go.skia.org/infra/perf/go/config"
 	"go.skia.org/infra/perf/go/ingestcommon"
 	"go.skia.org/infra/perf/go/ingestevents"
+	"go.skia.org/infra/perf/go/trybot"
 	"go.skia.org/infra/perf/go/types"
 	"google.golang.org/api/option"
 )
 
 // flags
 var (
-	configName = flag.String("config_name", "nano", "Name of the perf ingester config to use.")
-	local      = flag.Bool("local", false, "Running locally if true. As opposed to in production.")
-	port       = flag.String("port", ":8000", "HTTP service address (e.g., ':8000')")
-	promPort   = flag.String("prom_port", ":20000", "Metrics service address (e.g., ':10110')")
+	configFilename = flag.String("config_filename", "", "The name of the JSON or JSON5 config file that describes the Perf instance, e.g. ./configs/nano.json. Required.")
+	local          = flag.Bool("local", false, "Running locally if true. As opposed to in production.")
+	port           = flag.String("port", ":8000", "HTTP service address (e.g., ':8000')")
+	promPort       = flag.String("prom_port", ":20000", "Metrics service address (e.g., ':10110')")
 )
 
 const (
@@ -143,10 +145,34 @@ func indexToCache(hash string, index types.CommitNumber) {
 	hashCache[hash] = int(index)
 }
 
+// processTryBotFile writes the values from a single trybot results file into
+// the trybot.Store, keyed by the CL and patchset the results are for.
+func processTryBotFile(ctx context.Context, tryStore trybot.Store, benchData *ingestcommon.BenchData, params []paramtools.Params, values []float32, filename string, timestamp time.Time) error {
+	patchset, err := strconv.Atoi(benchData.PatchSet)
+	if err != nil {
+		sklog.Errorf("Invalid patchset %q for issue %q in %q: %s", benchData.PatchSet, benchData.Issue, filename, err)
+		return NonRecoverableError
+	}
+	traceIDs := make([]string, 0, len(params))
+	traceValues := make([]float32, 0, len(values))
+	for i, p := range params {
+		key, err := query.MakeKeyFast(p)
+		if err != nil {
+			continue
+		}
+		traceIDs = append(traceIDs, key)
+		traceValues = append(traceValues, values[i])
+	}
+	return tryStore.Write(ctx, benchData.Issue, patchset, traceIDs, traceValues, filename, timestamp)
+}
+
 // processSingleFile parses the contents of a single JSON file and writes the values into BigTable.
 //
 // If 'branches' is not empty then restrict to ingesting just the branches in the slice.
-func processSingleFile(ctx context.Context, store *btts.BigTableTraceStore, vcs vcsinfo.VCS, filename string, r io.Reader, timestamp time.Time, branches []string) error {
+//
+// If 'tryStore' is not nil then the results of trybot runs, i.e. files that
+// have an issue, are written to 'tryStore' instead of 'store'.
+func processSingleFile(ctx context.Context, store types.TraceStore, tryStore trybot.Store, vcs vcsinfo.VCS, filename string, r io.Reader, timestamp time.Time, branches []string) error {
 	benchData, err := ingestcommon.ParseBenchDataFromReader(r)
 	if err != nil {
 		sklog.Errorf("Failed to read or parse data: %s", err)
@@ -171,6 +197,10 @@ func processSingleFile(ctx context.Context, store *btts.BigTableTraceStore, vcs
 		sklog.Infof("No data in: %q", filename)
 		return nil
 	}
+	if benchData.Issue != "" && tryStore != nil {
+		sklog.Infof("Processing trybot results %q", filename)
+		return processTryBotFile(ctx, tryStore, benchData, params, values, filename, timestamp)
+	}
 	sklog.Infof("Processing %q", filename)
 	index, ok := indexFromCache(benchData.Hash)
 	if !ok {
@@ -251,10 +281,10 @@ func main() {
 	ackCounter := metrics2.GetCounter("ack", nil)
 
 	ctx := context.Background()
-	var ok bool
-	cfg, ok = config.PERF_BIGTABLE_CONFIGS[*configName]
-	if !ok {
-		sklog.Fatalf("Invalid --config value: %q", *configName)
+	var err error
+	cfg, err = config.InstanceConfigFromFile(*configFilename)
+	if err != nil {
+		sklog.Fatalf("Failed to load config: %s", err)
 	}
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -289,7 +319,7 @@ func main() {
 		subName = fmt.Sprintf("%s-%s", cfg.Topic, hostname)
 	}
 	sub := pubSubClient.Subscription(subName)
-	ok, err = sub.Exists(ctx)
+	ok, err := sub.Exists(ctx)
 	if err != nil {
 		sklog.Fatalf("Failed checking subscription existence: %s", err)
 	}
@@ -311,11 +341,25 @@ func main() {
 		sklog.Fatal(err)
 	}
 
-	store, err := btts.NewBigTableTraceStoreFromConfig(ctx, cfg, ts, true)
+	store, err := builders.NewTraceStoreFromConfig(ctx, cfg, ts, true)
 	if err != nil {
 		sklog.Fatal(err)
 	}
 
+	// Trybot results are only kept separately if they go to a store that
+	// skiaperf can also read, i.e. a SQL store. A memory store would only live
+	// in this process, and the datastore StoreType has no trybot store at all,
+	// so in those cases trybot results are written to the TraceStore as before.
+	var tryStore trybot.Store
+	if cfg.GetStoreType() == config.SQLStoreType {
+		tryStore, err = builders.NewTryBotStoreFromConfig(cfg)
+		if err != nil {
+			sklog.Fatal(err)
+		}
+	} else {
+		sklog.Infof("Not storing trybot results separately for StoreType %q.", cfg.GetStoreType())
+	}
+
 	// Process all incoming PubSub requests.
 	go func() {
 		for {
@@ -363,7 +407,7 @@ func main() {
 				sklog.Infof("Filename: %q", attrs.Name)
 				// Pull data out of file and write it into BigTable.
 				fullName := fmt.Sprintf("gs://%s/%s", event.Bucket, event.Name)
-				err = processSingleFile(ctx, store, vcs, fullName, reader, attrs.Created, cfg.Branches)
+				err = processSingleFile(ctx, store, tryStore, vcs, fullName, reader, attrs.Created, cfg.Branches)
 				if err := reader.Close(); err != nil {
 					sklog.Errorf("Failed to close: %s", err)
 				}
@@ -1,20 +1,27 @@
 package main
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.skia.org/infra/go/paramtools"
+	"go.skia.org/infra/go/testutils"
 	"go.skia.org/infra/go/testutils/unittest"
 	"go.skia.org/infra/perf/go/ingestcommon"
+	"go.skia.org/infra/perf/go/trybot/memtrybotstore"
 )
 
 const (
 	TEST_DATA_DIR = "./testdata"
 
 	TEST_INGESTION_FILE = "nano.json"
+
+	TEST_TRYBOT_FILE = "trybot.json"
 )
 
 func TestParamsAndValues(t *testing.T) {
@@ -45,3 +52,27 @@ func TestParamsAndValues(t *testing.T) {
 	expected.Normalize()
 	assert.Equal(t, expected, paramSet)
 }
+
+func TestProcessSingleFile_TryBot(t *testing.T) {
+	unittest.SmallTest(t)
+	r, err := os.Open(filepath.Join(TEST_DATA_DIR, TEST_TRYBOT_FILE))
+	require.NoError(t, err)
+	defer testutils.AssertCloses(t, r)
+
+	ctx := context.Background()
+	tryStore := memtrybotstore.New()
+	// The trace store and vcs aren't needed for trybot results.
+	err = processSingleFile(ctx, nil, tryStore, nil, "gs://bucket/trybot.json", r, time.Now(), nil)
+	require.NoError(t, err)
+
+	patchsets, err := tryStore.Patchsets(ctx, "12345")
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, patchsets)
+
+	results, err := tryStore.Get(ctx, "12345", 3)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]float32{
+		",arch=x86,config=nonrendering,gpu=GTX660,model=ShuttleA,os=Ubuntu12,source_type=bench,sub_result=min_ms,test=ChunkAlloc_PushPop_640_480,":  {3.5},
+		",arch=x86,config=nonrendering,gpu=GTX660,model=ShuttleA,os=Ubuntu12,source_type=bench,sub_result=min_ms,test=Deque_PushAllPopAll_640_480,": {6.25},
+	}, results)
+}
@@ -1,9 +1,11 @@
-// Command-line application for interacting with BigTable backed Perf storage.
+// Command-line application for interacting with Perf trace storage.
 package main
 
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"os"
 
@@ -11,9 +13,11 @@ import (
 	"cloud.google.com/go/pubsub"
 	"github.com/spf13/cobra"
 	"go.skia.org/infra/go/auth"
+	"go.skia.org/infra/go/httputils"
 	"go.skia.org/infra/go/query"
 	"go.skia.org/infra/go/sklog"
-	"go.skia.org/infra/perf/go/btts"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/perf/go/builders"
 	"go.skia.org/infra/perf/go/config"
 	"go.skia.org/infra/perf/go/types"
 	"golang.org/x/oauth2"
@@ -21,16 +25,21 @@ import (
 
 var (
 	ts    oauth2.TokenSource
-	store *btts.BigTableTraceStore
+	store types.TraceStore
 )
 
 // flags
 var (
-	all            bool
 	logToStdErr    bool
-	bigTableConfig string
+	configFilename string
 	tile           types.TileNumber
 	queryFlag      string
+	instanceURL    string
+	outFilename    string
+	frameID        string
+	exportFormat   string
+	beginOffset    int
+	endOffset      int
 )
 
 func main() {
@@ -39,11 +48,7 @@ func main() {
 	cmd := cobra.Command{
 		Use: "perf-tool [sub]",
 		PersistentPreRunE: func(c *cobra.Command, args []string) error {
-			logMode := sklog.SLogNone
-			if logToStdErr {
-				logMode = sklog.SLogStderr
-			}
-			sklog.SetLogger(sklog.NewStdErrCloudLogger(logMode))
+			setLogger()
 
 			var err error
 			ts, err = auth.NewDefaultTokenSource(true, bigtable.Scope)
@@ -52,33 +57,39 @@ func main() {
 			}
 
 			// Create the store client.
-			cfg := config.PERF_BIGTABLE_CONFIGS[bigTableConfig]
-			store, err = btts.NewBigTableTraceStoreFromConfig(ctx, cfg, ts, false)
+			cfg, err := config.InstanceConfigFromFile(configFilename)
+			if err != nil {
+				return err
+			}
+			store, err = builders.NewTraceStoreFromConfig(ctx, cfg, ts, false)
 			if err != nil {
 				return fmt.Errorf("Failed to create client: %s", err)
 			}
 			return nil
 		},
 	}
-	cmd.PersistentFlags().StringVar(&bigTableConfig, "big_table_config", "nano", "The name of the config to use when using a BigTable trace store.")
+	cmd.PersistentFlags().StringVar(&configFilename, "config_filename", "", "The name of the JSON or JSON5 config file that describes the Perf instance, e.g. ./configs/nano.json. Required.")
 	cmd.PersistentFlags().BoolVar(&logToStdErr, "logtostderr", false, "Otherwise logs are not produced.")
 
 	configCmd := &cobra.Command{
 		Use: "config [sub]",
+		// The config commands don't need a trace store.
+		PersistentPreRun: func(c *cobra.Command, args []string) {
+			setLogger()
+		},
 	}
-	configListCmd := &cobra.Command{
-		Use:   "list",
-		Short: "List all the available configs.",
-		Run:   configListAction,
+	configValidateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the config file given by --config_filename.",
+		RunE:  configValidateAction,
 	}
-	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configValidateCmd)
 
 	configPubSubCmd := &cobra.Command{
 		Use:   "create-pubsub-topics",
-		Short: "Create PubSub topics for the given big_table_config.",
+		Short: "Create PubSub topics for the config given by --config_filename.",
 		RunE:  configCreatePubSubTopicsAction,
 	}
-	configPubSubCmd.Flags().BoolVar(&all, "all", false, "If true then create topics for all configs.")
 	configCmd.AddCommand(configPubSubCmd)
 
 	indicesCmd := &cobra.Command{
@@ -147,8 +158,42 @@ func main() {
 		tracesListByIndexCmd,
 	)
 
+	exportCmd := &cobra.Command{
+		Use: "export [sub]",
+		// The export commands talk to a running Perf instance and don't need
+		// a trace store.
+		PersistentPreRun: func(c *cobra.Command, args []string) {
+			setLogger()
+		},
+	}
+	exportCmd.PersistentFlags().StringVar(&instanceURL, "instance", "https://perf.skia.org", "The URL of the Perf instance to export from.")
+	exportCmd.PersistentFlags().StringVar(&outFilename, "out", "", "The file to write the export to. Defaults to stdout.")
+
+	exportFrameCmd := &cobra.Command{
+		Use:   "frame",
+		Short: "Exports the traces of a completed frame request.",
+		Long:  "Exports the trace values, param keys, and commits of the completed frame request given by --id, as returned from /_/frame/start.",
+		RunE:  exportFrameAction,
+	}
+	exportFrameCmd.Flags().StringVar(&frameID, "id", "", "The id of the completed frame request.")
+	exportFrameCmd.Flags().StringVar(&exportFormat, "format", "csv", "The format of the export, either 'csv' or 'json'.")
+
+	exportRegressionsCmd := &cobra.Command{
+		Use:   "regressions",
+		Short: "Exports the regressions found in a range of commits, as JSON.",
+		RunE:  exportRegressionsAction,
+	}
+	exportRegressionsCmd.Flags().IntVar(&beginOffset, "begin", -1, "The offset of the first commit in the range.")
+	exportRegressionsCmd.Flags().IntVar(&endOffset, "end", -1, "The offset of the last commit in the range, inclusive. Defaults to --begin.")
+
+	exportCmd.AddCommand(
+		exportFrameCmd,
+		exportRegressionsCmd,
+	)
+
 	cmd.AddCommand(
 		configCmd,
+		exportCmd,
 		indicesCmd,
 		tilesCmd,
 		tracesCmd,
@@ -161,6 +206,15 @@ func main() {
 
 }
 
+// setLogger configures logging based on the --logtostderr flag.
+func setLogger() {
+	logMode := sklog.SLogNone
+	if logToStdErr {
+		logMode = sklog.SLogStderr
+	}
+	sklog.SetLogger(sklog.NewStdErrCloudLogger(logMode))
+}
+
 func tilesLastAction(c *cobra.Command, args []string) error {
 	tileNumber, err := store.GetLatestTile()
 	if err != nil {
@@ -285,10 +339,12 @@ func indicesCountAction(c *cobra.Command, args []string) error {
 	return err
 }
 
-func configListAction(c *cobra.Command, args []string) {
-	for k := range config.PERF_BIGTABLE_CONFIGS {
-		fmt.Println(k)
+func configValidateAction(c *cobra.Command, args []string) error {
+	if _, err := config.InstanceConfigFromFile(configFilename); err != nil {
+		return err
 	}
+	fmt.Printf("%q is a valid config.\n", configFilename)
+	return nil
 }
 
 func createPubSubTopic(ctx context.Context, client *pubsub.Client, topicName, configName string) error {
@@ -325,22 +381,62 @@ func createPubSubTopicsForConfig(name string, cfg *config.InstanceConfig) error
 }
 
 func configCreatePubSubTopicsAction(c *cobra.Command, args []string) error {
-	if all {
-		for name, cfg := range config.PERF_BIGTABLE_CONFIGS {
-			if err := createPubSubTopicsForConfig(name, cfg); err != nil {
-				return err
-			}
-			fmt.Printf("Config %q finished.\n", name)
-		}
-	} else {
-		if cfg, ok := config.PERF_BIGTABLE_CONFIGS[bigTableConfig]; ok {
-			if err := createPubSubTopicsForConfig(bigTableConfig, cfg); err != nil {
-				return err
-			}
-			fmt.Printf("Config %q finished.", bigTableConfig)
-		} else {
-			return fmt.Errorf("%q is not a valid config name. Run 'perf-tool config list' to see all config names.", bigTableConfig)
+	cfg, err := config.InstanceConfigFromFile(configFilename)
+	if err != nil {
+		return err
+	}
+	if err := createPubSubTopicsForConfig(configFilename, cfg); err != nil {
+		return err
+	}
+	fmt.Printf("Config %q finished.\n", configFilename)
+	return nil
+}
+
+// exportFromInstance GETs the given path from the Perf instance given by
+// --instance and writes the response to the file given by --out, or stdout.
+func exportFromInstance(path string, values url.Values) error {
+	u := fmt.Sprintf("%s%s?%s", instanceURL, path, values.Encode())
+	resp, err := httputils.NewTimeoutClient().Get(u)
+	if err != nil {
+		return fmt.Errorf("Failed to request %q: %s", u, err)
+	}
+	defer util.Close(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Request to %q failed with status code %d.", u, resp.StatusCode)
+	}
+	var out io.Writer = os.Stdout
+	if outFilename != "" {
+		f, err := os.Create(outFilename)
+		if err != nil {
+			return fmt.Errorf("Failed to create %q: %s", outFilename, err)
 		}
+		defer util.Close(f)
+		out = f
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return fmt.Errorf("Failed to write export: %s", err)
 	}
 	return nil
 }
+
+func exportFrameAction(c *cobra.Command, args []string) error {
+	if frameID == "" {
+		return fmt.Errorf("--id is required.")
+	}
+	if exportFormat != "csv" && exportFormat != "json" {
+		return fmt.Errorf("--format must be 'csv' or 'json', got %q.", exportFormat)
+	}
+	return exportFromInstance(fmt.Sprintf("/_/frame/%s/%s", exportFormat, url.PathEscape(frameID)), url.Values{})
+}
+
+func exportRegressionsAction(c *cobra.Command, args []string) error {
+	if beginOffset < 0 {
+		return fmt.Errorf("--begin is required.")
+	}
+	values := url.Values{}
+	values.Set("begin", fmt.Sprintf("%d", beginOffset))
+	if endOffset >= 0 {
+		values.Set("end", fmt.Sprintf("%d", endOffset))
+	}
+	return exportFromInstance("/_/reg/export", values)
+}
@@ -79,6 +79,7 @@ type RegressionDetectionProcess struct {
 	request           *RegressionDetectionRequest
 	vcs               vcsinfo.VCS
 	iter              DataFrameIterator
+	shortcutStore     shortcut2.Store
 	responseProcessor RegresssionDetectionResponseProcessor
 
 	// mutex protects access to the remaining struct members.
@@ -89,10 +90,11 @@ type RegressionDetectionProcess struct {
 	message    string                         // Describes the current state of the process.
 }
 
-func newProcess(ctx context.Context, req *RegressionDetectionRequest, vcs vcsinfo.VCS, cidl *cid.CommitIDLookup, dfBuilder dataframe.DataFrameBuilder, responseProcessor RegresssionDetectionResponseProcessor) (*RegressionDetectionProcess, error) {
+func newProcess(ctx context.Context, req *RegressionDetectionRequest, vcs vcsinfo.VCS, cidl *cid.CommitIDLookup, dfBuilder dataframe.DataFrameBuilder, shortcutStore shortcut2.Store, responseProcessor RegresssionDetectionResponseProcessor) (*RegressionDetectionProcess, error) {
 	ret := &RegressionDetectionProcess{
 		request:           req,
 		vcs:               vcs,
+		shortcutStore:     shortcutStore,
 		responseProcessor: responseProcessor,
 		response:          []*RegressionDetectionResponse{},
 		lastUpdate:        time.Now(),
@@ -109,8 +111,8 @@ func newProcess(ctx context.Context, req *RegressionDetectionRequest, vcs vcsinf
 	return ret, nil
 }
 
-func newRunningProcess(ctx context.Context, req *RegressionDetectionRequest, vcs vcsinfo.VCS, cidl *cid.CommitIDLookup, dfBuilder dataframe.DataFrameBuilder, responseProcessor RegresssionDetectionResponseProcessor) (*RegressionDetectionProcess, error) {
-	ret, err := newProcess(ctx, req, vcs, cidl, dfBuilder, responseProcessor)
+func newRunningProcess(ctx context.Context, req *RegressionDetectionRequest, vcs vcsinfo.VCS, cidl *cid.CommitIDLookup, dfBuilder dataframe.DataFrameBuilder, shortcutStore shortcut2.Store, responseProcessor RegresssionDetectionResponseProcessor) (*RegressionDetectionProcess, error) {
+	ret, err := newProcess(ctx, req, vcs, cidl, dfBuilder, shortcutStore, responseProcessor)
 	if err != nil {
 		return nil, err
 	}
@@ -127,6 +129,7 @@ type RunningRegressionDetectionRequests struct {
 	cidl               *cid.CommitIDLookup
 	defaultInteresting float32 // The threshold to control if a regression is considered interesting.
 	dfBuilder          dataframe.DataFrameBuilder
+	shortcutStore      shortcut2.Store
 
 	mutex sync.Mutex
 	// inProcess maps a RegressionDetectionRequest.Id() of the request to the RegressionDetectionProcess
@@ -135,13 +138,14 @@ type RunningRegressionDetectionRequests struct {
 }
 
 // NewRunningRegressionDetectionRequests return a new RegressionDetectionRequests.
-func NewRunningRegressionDetectionRequests(vcs vcsinfo.VCS, cidl *cid.CommitIDLookup, interesting float32, dfBuilder dataframe.DataFrameBuilder) *RunningRegressionDetectionRequests {
+func NewRunningRegressionDetectionRequests(vcs vcsinfo.VCS, cidl *cid.CommitIDLookup, interesting float32, dfBuilder dataframe.DataFrameBuilder, shortcutStore shortcut2.Store) *RunningRegressionDetectionRequests {
 	fr := &RunningRegressionDetectionRequests{
 		vcs:                vcs,
 		cidl:               cidl,
 		inProcess:          map[string]*RegressionDetectionProcess{},
 		defaultInteresting: interesting,
 		dfBuilder:          dfBuilder,
+		shortcutStore:      shortcutStore,
 	}
 	go fr.background()
 	return fr
@@ -187,7 +191,7 @@ func (fr *RunningRegressionDetectionRequests) Add(ctx context.Context, req *Regr
 	}
 	responseProcessor := func(_ *RegressionDetectionRequest, _ []*RegressionDetectionResponse) {}
 	if _, ok := fr.inProcess[id]; !ok {
-		proc, err := newRunningProcess(ctx, req, fr.vcs, fr.cidl, fr.dfBuilder, responseProcessor)
+		proc, err := newRunningProcess(ctx, req, fr.vcs, fr.cidl, fr.dfBuilder, fr.shortcutStore, responseProcessor)
 		if err != nil {
 			return "", err
 		}
@@ -306,10 +310,10 @@ func tooMuchMissingData(tr types.Trace) bool {
 }
 
 // ShortcutFromKeys stores a new shortcut for each regression based on its Keys.
-func ShortcutFromKeys(summary *clustering2.ClusterSummaries) error {
+func ShortcutFromKeys(ctx context.Context, store shortcut2.Store, summary *clustering2.ClusterSummaries) error {
 	var err error
 	for _, cs := range summary.Clusters {
-		if cs.Shortcut, err = shortcut2.InsertShortcut(&shortcut2.Shortcut{Keys: cs.Keys}); err != nil {
+		if cs.Shortcut, err = store.InsertShortcut(ctx, &shortcut2.Shortcut{Keys: cs.Keys}); err != nil {
 			return err
 		}
 	}
@@ -364,7 +368,7 @@ func (p *RegressionDetectionProcess) Run(ctx context.Context) {
 			p.reportError(err, "Invalid regression detection.")
 			return
 		}
-		if err := ShortcutFromKeys(summary); err != nil {
+		if err := ShortcutFromKeys(ctx, p.shortcutStore, summary); err != nil {
 			p.reportError(err, "Failed to write shortcut for keys.")
 			return
 		}
@@ -18,9 +18,11 @@ import (
 	"go.skia.org/infra/go/vcsinfo"
 	"go.skia.org/infra/perf/go/alerts"
 	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/clustering2"
 	"go.skia.org/infra/perf/go/dataframe"
 	"go.skia.org/infra/perf/go/ingestevents"
 	"go.skia.org/infra/perf/go/notify"
+	"go.skia.org/infra/perf/go/shortcut2"
 	"go.skia.org/infra/perf/go/stepfit"
 	"go.skia.org/infra/perf/go/types"
 )
@@ -33,6 +35,10 @@ const (
 	// POLLING_CLUSTERING_DELAY is the time to wait between clustering runs, but
 	// only when not doing event driven regression detection.
 	POLLING_CLUSTERING_DELAY = 5 * time.Minute
+
+	// RECOVERY_WINDOW is how far back we look for regressions that a newly
+	// found step might be a recovery of.
+	RECOVERY_WINDOW = 7 * 24 * time.Hour
 )
 
 // ConfigProvider is a function that's called to return a slice of alerts.Config. It is passed to NewContinuous.
@@ -57,7 +63,7 @@ type Current struct {
 type Continuous struct {
 	vcs             vcsinfo.VCS
 	cidl            *cid.CommitIDLookup
-	store           *Store
+	store           Store
 	numCommits      int // Number of recent commits to do clustering over.
 	radius          int
 	eventDriven     bool   // True if doing event driven regression detection.
@@ -70,6 +76,14 @@ type Continuous struct {
 	dfBuilder       dataframe.DataFrameBuilder
 	pollingDelay    time.Duration
 
+	// shortcutStore is used to store the keys of found clusters, and to load
+	// them back when looking for recoveries.
+	shortcutStore shortcut2.Store
+
+	// recoveries counts the regressions that were automatically triaged as
+	// RECOVERED.
+	recoveries metrics2.Counter
+
 	mutex   sync.Mutex // Protects current.
 	current *Current
 }
@@ -83,12 +97,13 @@ func NewContinuous(
 	vcs vcsinfo.VCS,
 	cidl *cid.CommitIDLookup,
 	provider ConfigProvider,
-	store *Store,
+	store Store,
 	numCommits int,
 	radius int,
 	notifier *notify.Notifier,
 	paramsProvider ParamsetProvider,
 	dfBuilder dataframe.DataFrameBuilder,
+	shortcutStore shortcut2.Store,
 	local bool,
 	projectID string,
 	fileIngestionTopicName string,
@@ -109,7 +124,18 @@ func NewContinuous(
 		paramsProvider:  paramsProvider,
 		dfBuilder:       dfBuilder,
 		pollingDelay:    POLLING_CLUSTERING_DELAY,
+		shortcutStore:   shortcutStore,
+		recoveries:      metrics2.GetCounter("perf_clustering_recovered", nil),
+	}
+}
+
+// keysLookup implements KeysLookup using the shortcut2.Store.
+func (c *Continuous) keysLookup(shortcut string) ([]string, error) {
+	sc, err := c.shortcutStore.Get(context.Background(), shortcut)
+	if err != nil {
+		return nil, err
 	}
+	return sc.Keys, nil
 }
 
 // CurrentStatus returns the current status of regression detection.
@@ -121,13 +147,13 @@ func (c *Continuous) CurrentStatus() Current {
 
 // Untriaged returns the number of untriaged regressions.
 func (c *Continuous) Untriaged() (int, error) {
-	return c.store.Untriaged()
+	return c.store.Untriaged(context.Background())
 }
 
 func (c *Continuous) reportUntriaged(newClustersGauge metrics2.Int64Metric) {
 	go func() {
 		for range time.Tick(time.Minute) {
-			if count, err := c.store.Untriaged(); err == nil {
+			if count, err := c.store.Untriaged(context.Background()); err == nil {
 				newClustersGauge.Update(int64(count))
 			} else {
 				sklog.Errorf("Failed to get untriaged count: %s", err)
@@ -158,28 +184,40 @@ func (c *Continuous) reportRegressions(ctx context.Context, req *RegressionDetec
 			resp.Frame.DataFrame.ParamSet = paramtools.ParamSet{}
 			// Update database if regression at the midpoint is found.
 			if cl.StepPoint.Offset == midOffset {
+				var recovered *Recovered
+				if (cl.StepFit.Status == stepfit.LOW || cl.StepFit.Status == stepfit.HIGH) && len(cl.Keys) >= cfg.MinimumNum {
+					recovered = c.recover(ctx, details[0], cfg, cl)
+				}
 				if cl.StepFit.Status == stepfit.LOW && len(cl.Keys) >= cfg.MinimumNum && (cfg.Direction == alerts.DOWN || cfg.Direction == alerts.BOTH) {
 					sklog.Infof("Found Low regression at %s: StepFit: %v Shortcut: %s AlertID: %d %d req: %#v", details[0].Message, *cl.StepFit, cl.Shortcut, cfg.ID, c.current.Alert.ID, *req)
-					isNew, err := c.store.SetLow(details[0], key, resp.Frame, cl)
+					isNew, err := c.store.SetLow(ctx, details[0], key, resp.Frame, cl)
 					if err != nil {
 						sklog.Errorf("Failed to save newly found cluster: %s", err)
 						continue
 					}
 					if isNew {
-						if err := c.notifier.Send(details[0], cfg, cl); err != nil {
+						if recovered != nil {
+							if err := c.store.TriageLow(ctx, details[0], key, recoveryStatus(recovered.CommitID)); err != nil {
+								sklog.Errorf("Failed to triage recovery: %s", err)
+							}
+						} else if err := c.notifier.Send(details[0], cfg, cl); err != nil {
 							sklog.Errorf("Failed to send notification: %s", err)
 						}
 					}
 				}
 				if cl.StepFit.Status == stepfit.HIGH && len(cl.Keys) >= cfg.MinimumNum && (cfg.Direction == alerts.UP || cfg.Direction == alerts.BOTH) {
 					sklog.Infof("Found High regression at %s: StepFit: %v Shortcut: %s AlertID: %d %d req: %#v", details[0].Message, *cl.StepFit, cl.Shortcut, cfg.ID, c.current.Alert.ID, *req)
-					isNew, err := c.store.SetHigh(details[0], key, resp.Frame, cl)
+					isNew, err := c.store.SetHigh(ctx, details[0], key, resp.Frame, cl)
 					if err != nil {
 						sklog.Errorf("Failed to save newly found cluster for alert %q length=%d: %s", key, len(cl.Keys), err)
 						continue
 					}
 					if isNew {
-						if err := c.notifier.Send(details[0], cfg, cl); err != nil {
+						if recovered != nil {
+							if err := c.store.TriageHigh(ctx, details[0], key, recoveryStatus(recovered.CommitID)); err != nil {
+								sklog.Errorf("Failed to triage recovery: %s", err)
+							}
+						} else if err := c.notifier.Send(details[0], cfg, cl); err != nil {
 							sklog.Errorf("Failed to send notification: %s", err)
 						}
 					}
@@ -189,6 +227,59 @@ func (c *Continuous) reportRegressions(ctx context.Context, req *RegressionDetec
 	}
 }
 
+// recoveryStatus returns the TriageStatus for a RECOVERED regression, or
+// recovery, where 'link' is the other commit involved.
+func recoveryStatus(link *cid.CommitID) TriageStatus {
+	return TriageStatus{
+		Status:  RECOVERED,
+		Message: fmt.Sprintf("Automatically triaged: recovered, see %s.", link.ID()),
+		Link:    link.ID(),
+	}
+}
+
+// recover looks for an untriaged regression for the alert 'cfg' that is
+// reverted by the cluster 'cl' found at commit 'at'. If one is found then it
+// is triaged as RECOVERED and a follow-up notification is sent.
+//
+// Returns the regression that recovered, or nil if none was found.
+func (c *Continuous) recover(ctx context.Context, at *cid.CommitDetail, cfg *alerts.Alert, cl *clustering2.ClusterSummary) *Recovered {
+	key := cfg.IdAsString()
+	regressions, err := c.store.Range(ctx, at.Timestamp-int64(RECOVERY_WINDOW.Seconds()), at.Timestamp)
+	if err != nil {
+		sklog.Errorf("Failed to load regressions looking for recoveries: %s", err)
+		return nil
+	}
+	recovered, err := FindRecovered(regressions, key, &at.CommitID, cl, c.keysLookup)
+	if err != nil {
+		sklog.Errorf("Failed looking for recoveries: %s", err)
+		return nil
+	}
+	if recovered == nil {
+		return nil
+	}
+	details, err := c.cidl.Lookup(ctx, []*cid.CommitID{recovered.CommitID})
+	if err != nil {
+		sklog.Errorf("Failed to look up commit %v: %s", *recovered.CommitID, err)
+		return nil
+	}
+	sklog.Infof("Regression at %s for AlertID: %d recovered at %s", details[0].Message, cfg.ID, at.Message)
+	status := recoveryStatus(&at.CommitID)
+	if recovered.Low {
+		err = c.store.TriageLow(ctx, details[0], key, status)
+	} else {
+		err = c.store.TriageHigh(ctx, details[0], key, status)
+	}
+	if err != nil {
+		sklog.Errorf("Failed to triage recovered regression: %s", err)
+		return nil
+	}
+	c.recoveries.Inc(1)
+	if err := c.notifier.SendRecovered(details[0], cfg, recovered.Cluster); err != nil {
+		sklog.Errorf("Failed to send recovery notification: %s", err)
+	}
+	return recovered
+}
+
 func (c *Continuous) setCurrentConfig(cfg *alerts.Alert) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -443,7 +534,7 @@ func (c *Continuous) Run(ctx context.Context) {
 				N:   int32(c.numCommits),
 				End: time.Time{},
 			}
-			RegressionsForAlert(ctx, cfg, domain, cnp.paramset, clusterResponseProcessor, c.vcs, c.cidl, c.dfBuilder, c.setCurrentStep)
+			RegressionsForAlert(ctx, cfg, domain, cnp.paramset, clusterResponseProcessor, c.vcs, c.cidl, c.dfBuilder, c.shortcutStore, c.setCurrentStep)
 			configsCounter.Inc(1)
 		}
 		clusteringLatency.Stop()
@@ -1,36 +1,27 @@
-package regression
+// Package dsregressionstore implements regression.Store using Cloud Datastore.
+package dsregressionstore
 
 import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 
 	"cloud.google.com/go/datastore"
 	"go.skia.org/infra/go/ds"
 	"go.skia.org/infra/perf/go/cid"
 	"go.skia.org/infra/perf/go/clustering2"
 	"go.skia.org/infra/perf/go/dataframe"
+	"go.skia.org/infra/perf/go/regression"
 	"google.golang.org/api/iterator"
 )
 
-// Subset is the subset of regressions we are querying for.
-type Subset string
+// DSRegressionStore persists Regressions to/from datastore.
+type DSRegressionStore struct{}
 
-const (
-	ALL_SUBSET         Subset = "all"         // Include all regressions in a range.
-	REGRESSIONS_SUBSET Subset = "regressions" // Only include regressions in a range that are alerting.
-	UNTRIAGED_SUBSET   Subset = "untriaged"   // All untriaged alerting regressions regardless of range.
-)
-
-// Store persists Regressions to/from datastore..
-type Store struct {
-	mutex sync.Mutex
-}
-
-// NewStore returns a new Store.
-func NewStore() *Store {
-	return &Store{}
+// New returns a new DSRegressionStore. Note that ds.Init must be called
+// before using the returned store.
+func New() *DSRegressionStore {
+	return &DSRegressionStore{}
 }
 
 // DSRegression is used for storing Regressions in Cloud Datastore.
@@ -41,14 +32,14 @@ type DSRegression struct {
 }
 
 // load_ds loads Regressions stored for the given commit from Cloud Datastore.
-func (s *Store) load_ds(tx *datastore.Transaction, cid *cid.CommitDetail) (*Regressions, error) {
+func (s *DSRegressionStore) load_ds(tx *datastore.Transaction, cid *cid.CommitDetail) (*regression.Regressions, error) {
 	key := ds.NewKey(ds.REGRESSION)
 	key.Name = cid.ID()
 	dsRegression := &DSRegression{}
 	if err := tx.Get(key, dsRegression); err != nil {
 		return nil, err
 	}
-	ret := New()
+	ret := regression.New()
 	if err := json.Unmarshal([]byte(dsRegression.Body), ret); err != nil {
 		return nil, fmt.Errorf("Failed to decode JSON body: %s", err)
 	}
@@ -56,7 +47,7 @@ func (s *Store) load_ds(tx *datastore.Transaction, cid *cid.CommitDetail) (*Regr
 }
 
 // store_ds stores Regressions for the given commit in Cloud Datastore.
-func (s *Store) store_ds(tx *datastore.Transaction, cid *cid.CommitDetail, r *Regressions) error {
+func (s *DSRegressionStore) store_ds(tx *datastore.Transaction, cid *cid.CommitDetail, r *regression.Regressions) error {
 	body, err := r.JSON()
 	if err != nil {
 		return fmt.Errorf("Failed to encode Regressions to JSON: %s", err)
@@ -78,10 +69,10 @@ func (s *Store) store_ds(tx *datastore.Transaction, cid *cid.CommitDetail, r *Re
 	return nil
 }
 
-// Untriaged returns the number of untriaged regressions.
-func (s *Store) Untriaged() (int, error) {
+// Untriaged implements regression.Store.
+func (s *DSRegressionStore) Untriaged(ctx context.Context) (int, error) {
 	q := ds.NewQuery(ds.REGRESSION).Filter("Triaged =", false).KeysOnly()
-	it := ds.DS.Run(context.TODO(), q)
+	it := ds.DS.Run(ctx, q)
 	count := 0
 	for {
 		_, err := it.Next(nil)
@@ -95,9 +86,8 @@ func (s *Store) Untriaged() (int, error) {
 	return count, nil
 }
 
-type DetailLookup func(c *cid.CommitID) (*cid.CommitDetail, error)
-
-func (s *Store) Write(regressions map[string]*Regressions, lookup DetailLookup) error {
+// Write implements regression.Store.
+func (s *DSRegressionStore) Write(ctx context.Context, regressions map[string]*regression.Regressions, lookup regression.DetailLookup) error {
 	i := 0
 	for cidString, reg := range regressions {
 		i += 1
@@ -115,7 +105,7 @@ func (s *Store) Write(regressions map[string]*Regressions, lookup DetailLookup)
 		if err != nil {
 			return fmt.Errorf("Could not find details for cid %q: %s", cidString, err)
 		}
-		_, err = ds.DS.RunInTransaction(context.TODO(), func(tx *datastore.Transaction) error {
+		_, err = ds.DS.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
 			return s.store_ds(tx, commitDetail, reg)
 		})
 		if err != nil {
@@ -125,11 +115,11 @@ func (s *Store) Write(regressions map[string]*Regressions, lookup DetailLookup)
 	return nil
 }
 
-// Range returns a map from cid.ID()'s to *Regressions that exist in the given time range
-func (s *Store) Range(begin, end int64) (map[string]*Regressions, error) {
-	ret := map[string]*Regressions{}
+// Range implements regression.Store.
+func (s *DSRegressionStore) Range(ctx context.Context, begin, end int64) (map[string]*regression.Regressions, error) {
+	ret := map[string]*regression.Regressions{}
 	q := ds.NewQuery(ds.REGRESSION).Filter("TS >=", begin).Filter("TS <", end)
-	it := ds.DS.Run(context.TODO(), q)
+	it := ds.DS.Run(ctx, q)
 	for {
 		dsRegression := &DSRegression{}
 		key, err := it.Next(dsRegression)
@@ -138,7 +128,7 @@ func (s *Store) Range(begin, end int64) (map[string]*Regressions, error) {
 		} else if err != nil {
 			return nil, fmt.Errorf("Failed to read from database: %s", err)
 		}
-		reg := New()
+		reg := regression.New()
 		if err := json.Unmarshal([]byte(dsRegression.Body), reg); err != nil {
 			return nil, fmt.Errorf("Failed to decode JSON body: %s", err)
 		}
@@ -147,13 +137,13 @@ func (s *Store) Range(begin, end int64) (map[string]*Regressions, error) {
 	return ret, nil
 }
 
-// SetHigh sets the cluster for a high regression at the given commit and alertID.
-func (s *Store) SetHigh(cid *cid.CommitDetail, alertID string, df *dataframe.FrameResponse, high *clustering2.ClusterSummary) (bool, error) {
+// SetHigh implements regression.Store.
+func (s *DSRegressionStore) SetHigh(ctx context.Context, cid *cid.CommitDetail, alertID string, df *dataframe.FrameResponse, high *clustering2.ClusterSummary) (bool, error) {
 	isNew := false
-	_, err := ds.DS.RunInTransaction(context.TODO(), func(tx *datastore.Transaction) error {
+	_, err := ds.DS.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
 		r, err := s.load_ds(tx, cid)
 		if err == datastore.ErrNoSuchEntity {
-			r = New()
+			r = regression.New()
 		} else if err != nil {
 			return err
 		}
@@ -163,13 +153,13 @@ func (s *Store) SetHigh(cid *cid.CommitDetail, alertID string, df *dataframe.Fra
 	return isNew, err
 }
 
-// SetLow sets the cluster for a low regression at the given commit and alertID.
-func (s *Store) SetLow(cid *cid.CommitDetail, alertID string, df *dataframe.FrameResponse, low *clustering2.ClusterSummary) (bool, error) {
+// SetLow implements regression.Store.
+func (s *DSRegressionStore) SetLow(ctx context.Context, cid *cid.CommitDetail, alertID string, df *dataframe.FrameResponse, low *clustering2.ClusterSummary) (bool, error) {
 	isNew := false
-	_, err := ds.DS.RunInTransaction(context.TODO(), func(tx *datastore.Transaction) error {
+	_, err := ds.DS.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
 		r, err := s.load_ds(tx, cid)
 		if err == datastore.ErrNoSuchEntity {
-			r = New()
+			r = regression.New()
 		} else if err != nil {
 			return err
 		}
@@ -179,9 +169,9 @@ func (s *Store) SetLow(cid *cid.CommitDetail, alertID string, df *dataframe.Fram
 	return isNew, err
 }
 
-// TriageLow sets the triage status for the low cluster at the given commit and alertID.
-func (s *Store) TriageLow(cid *cid.CommitDetail, alertID string, tr TriageStatus) error {
-	_, err := ds.DS.RunInTransaction(context.TODO(), func(tx *datastore.Transaction) error {
+// TriageLow implements regression.Store.
+func (s *DSRegressionStore) TriageLow(ctx context.Context, cid *cid.CommitDetail, alertID string, tr regression.TriageStatus) error {
+	_, err := ds.DS.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
 		r, err := s.load_ds(tx, cid)
 		if err != nil {
 			return fmt.Errorf("Failed to load Regressions: %s", err)
@@ -194,9 +184,9 @@ func (s *Store) TriageLow(cid *cid.CommitDetail, alertID string, tr TriageStatus
 	return err
 }
 
-// TriageHigh sets the triage status for the high cluster at the given commit and alertID.
-func (s *Store) TriageHigh(cid *cid.CommitDetail, alertID string, tr TriageStatus) error {
-	_, err := ds.DS.RunInTransaction(context.TODO(), func(tx *datastore.Transaction) error {
+// TriageHigh implements regression.Store.
+func (s *DSRegressionStore) TriageHigh(ctx context.Context, cid *cid.CommitDetail, alertID string, tr regression.TriageStatus) error {
+	_, err := ds.DS.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
 		r, err := s.load_ds(tx, cid)
 		if err != nil {
 			return fmt.Errorf("Failed to load Regressions: %s", err)
@@ -208,3 +198,21 @@ func (s *Store) TriageHigh(cid *cid.CommitDetail, alertID string, tr TriageStatu
 	})
 	return err
 }
+
+// SetIssueID implements regression.Store.
+func (s *DSRegressionStore) SetIssueID(ctx context.Context, cid *cid.CommitDetail, alertID string, issueID int64) error {
+	_, err := ds.DS.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		r, err := s.load_ds(tx, cid)
+		if err != nil {
+			return fmt.Errorf("Failed to load Regressions: %s", err)
+		}
+		if err = r.SetIssueID(alertID, issueID); err != nil {
+			return fmt.Errorf("Failed to update Regressions: %s", err)
+		}
+		return s.store_ds(tx, cid, r)
+	})
+	return err
+}
+
+// Confirm we implement the interface.
+var _ regression.Store = (*DSRegressionStore)(nil)
@@ -0,0 +1,107 @@
+package regression
+
+import (
+	"fmt"
+	"sort"
+
+	"go.skia.org/infra/perf/go/alerts"
+	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/clustering2"
+)
+
+// Direction constants for ExportedRegression.Direction.
+const (
+	EXPORT_LOW  = "low"
+	EXPORT_HIGH = "high"
+)
+
+// ExportedRegression is a single regression cluster, flattened so that it is
+// easy to consume outside of Perf, e.g. in a notebook.
+type ExportedRegression struct {
+	CommitID      string  `json:"commit_id"` // The cid.ID() of the commit.
+	Offset        int     `json:"offset"`
+	Hash          string  `json:"hash"`
+	Author        string  `json:"author"`
+	Message       string  `json:"message"`
+	URL           string  `json:"url"`
+	Timestamp     int64   `json:"timestamp"` // In seconds from the Unix epoch.
+	AlertID       string  `json:"alert_id"`
+	AlertName     string  `json:"alert_name"`
+	AlertQuery    string  `json:"alert_query"`
+	Direction     string  `json:"direction"` // One of EXPORT_LOW or EXPORT_HIGH.
+	StepStatus    string  `json:"step_status"`
+	StepSize      float32 `json:"step_size"`
+	Regression    float32 `json:"regression"`
+	NumTraces     int     `json:"num_traces"`
+	Shortcut      string  `json:"shortcut"`
+	TriageStatus  Status  `json:"triage_status"`
+	TriageMessage string  `json:"triage_message"`
+}
+
+// Export flattens 'regressions', a map from cid.ID()'s to *Regressions as
+// returned from Store.Range, into a slice of ExportedRegression, one for each
+// Low and High cluster found. The 'lookup' is used to find the details of each
+// commit, and 'configs' are used to find the names and queries of the alerts.
+//
+// The results are sorted by commit offset, then alert id, then direction.
+func Export(regressions map[string]*Regressions, lookup DetailLookup, configs []*alerts.Alert) ([]*ExportedRegression, error) {
+	configsByID := map[string]*alerts.Alert{}
+	for _, cfg := range configs {
+		configsByID[cfg.IdAsString()] = cfg
+	}
+	ret := []*ExportedRegression{}
+	for id, regs := range regressions {
+		commitID, err := cid.FromID(id)
+		if err != nil {
+			return nil, fmt.Errorf("Found invalid commit id %q: %s", id, err)
+		}
+		detail, err := lookup(commitID)
+		if err != nil {
+			return nil, fmt.Errorf("Could not find details for commit id %q: %s", id, err)
+		}
+		for alertID, reg := range regs.ByAlertID {
+			add := func(direction string, cl *clustering2.ClusterSummary, status TriageStatus) {
+				if cl == nil {
+					return
+				}
+				e := &ExportedRegression{
+					CommitID:      id,
+					Offset:        commitID.Offset,
+					Hash:          detail.Hash,
+					Author:        detail.Author,
+					Message:       detail.Message,
+					URL:           detail.URL,
+					Timestamp:     detail.Timestamp,
+					AlertID:       alertID,
+					Direction:     direction,
+					NumTraces:     cl.Num,
+					Shortcut:      cl.Shortcut,
+					TriageStatus:  status.Status,
+					TriageMessage: status.Message,
+				}
+				if cfg, ok := configsByID[alertID]; ok {
+					e.AlertName = cfg.DisplayName
+					e.AlertQuery = cfg.Query
+				}
+				if cl.StepFit != nil {
+					e.StepStatus = cl.StepFit.Status
+					e.StepSize = cl.StepFit.StepSize
+					e.Regression = cl.StepFit.Regression
+				}
+				ret = append(ret, e)
+			}
+			add(EXPORT_LOW, reg.Low, reg.LowStatus)
+			add(EXPORT_HIGH, reg.High, reg.HighStatus)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Offset != ret[j].Offset {
+			return ret[i].Offset < ret[j].Offset
+		}
+		if ret[i].AlertID != ret[j].AlertID {
+			return ret[i].AlertID < ret[j].AlertID
+		}
+		return ret[i].Direction < ret[j].Direction
+	})
+	return ret, nil
+}
@@ -0,0 +1,76 @@
+package regression
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/alerts"
+	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/stepfit"
+)
+
+func testDetailLookup(c *cid.CommitID) (*cid.CommitDetail, error) {
+	return &cid.CommitDetail{
+		CommitID:  *c,
+		Hash:      fmt.Sprintf("hash%d", c.Offset),
+		Timestamp: int64(c.Offset) * 100,
+	}, nil
+}
+
+func TestExport(t *testing.T) {
+	unittest.SmallTest(t)
+
+	at12 := New()
+	at12.ByAlertID["1"] = &Regression{
+		High:       newCluster(stepfit.HIGH, -2.0, ",a=1,"),
+		HighStatus: TriageStatus{Status: NEGATIVE, Message: "Bad"},
+		Low:        newCluster(stepfit.LOW, 1.0, ",a=2,"),
+		LowStatus:  TriageStatus{Status: UNTRIAGED},
+	}
+	at10 := New()
+	at10.ByAlertID["2"] = &Regression{
+		Low:       newCluster(stepfit.LOW, 3.0, ",a=3,"),
+		LowStatus: TriageStatus{Status: POSITIVE},
+	}
+	regressions := map[string]*Regressions{
+		(&cid.CommitID{Offset: 12}).ID(): at12,
+		(&cid.CommitID{Offset: 10}).ID(): at10,
+	}
+	configs := []*alerts.Alert{
+		{ID: 1, DisplayName: "First", Query: "a=1"},
+	}
+
+	exported, err := Export(regressions, testDetailLookup, configs)
+	require.NoError(t, err)
+	require.Len(t, exported, 3)
+
+	assert.Equal(t, 10, exported[0].Offset)
+	assert.Equal(t, "hash10", exported[0].Hash)
+	assert.Equal(t, int64(1000), exported[0].Timestamp)
+	assert.Equal(t, "2", exported[0].AlertID)
+	assert.Equal(t, "", exported[0].AlertName)
+	assert.Equal(t, EXPORT_LOW, exported[0].Direction)
+	assert.Equal(t, POSITIVE, exported[0].TriageStatus)
+	assert.Equal(t, float32(3.0), exported[0].StepSize)
+
+	assert.Equal(t, 12, exported[1].Offset)
+	assert.Equal(t, "First", exported[1].AlertName)
+	assert.Equal(t, "a=1", exported[1].AlertQuery)
+	assert.Equal(t, EXPORT_HIGH, exported[1].Direction)
+	assert.Equal(t, stepfit.HIGH, exported[1].StepStatus)
+	assert.Equal(t, NEGATIVE, exported[1].TriageStatus)
+	assert.Equal(t, "Bad", exported[1].TriageMessage)
+
+	assert.Equal(t, 12, exported[2].Offset)
+	assert.Equal(t, EXPORT_LOW, exported[2].Direction)
+	assert.Equal(t, UNTRIAGED, exported[2].TriageStatus)
+}
+
+func TestExport_InvalidCommitID(t *testing.T) {
+	unittest.SmallTest(t)
+	_, err := Export(map[string]*Regressions{"not-a-valid-id": New()}, testDetailLookup, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package regression
+
+import (
+	"context"
+	"fmt"
+
+	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/notify"
+)
+
+// issueIDStore implements notify.IssueIDStore on top of a Store, which keeps
+// the id of the bug filed for each Regression.
+type issueIDStore struct {
+	store Store
+}
+
+// NewIssueIDStore returns a notify.IssueIDStore that keeps the ids of the bugs
+// filed for regressions in 'store'.
+func NewIssueIDStore(store Store) notify.IssueIDStore {
+	return &issueIDStore{
+		store: store,
+	}
+}
+
+// GetIssueID implements notify.IssueIDStore.
+func (i *issueIDStore) GetIssueID(c *cid.CommitDetail, alertID int64) (int64, error) {
+	regressions, err := i.store.Range(context.Background(), c.Timestamp, c.Timestamp+1)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to load regressions: %s", err)
+	}
+	regs, ok := regressions[c.ID()]
+	if !ok {
+		return 0, nil
+	}
+	reg, ok := regs.ByAlertID[fmt.Sprintf("%d", alertID)]
+	if !ok {
+		return 0, nil
+	}
+	return reg.IssueID, nil
+}
+
+// SetIssueID implements notify.IssueIDStore.
+func (i *issueIDStore) SetIssueID(c *cid.CommitDetail, alertID int64, issueID int64) error {
+	return i.store.SetIssueID(context.Background(), c, fmt.Sprintf("%d", alertID), issueID)
+}
@@ -0,0 +1,195 @@
+// Package memregressionstore implements regression.Store in memory, which is
+// useful for testing and for running Perf locally.
+package memregressionstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/clustering2"
+	"go.skia.org/infra/perf/go/dataframe"
+	"go.skia.org/infra/perf/go/regression"
+)
+
+// entry is the stored form of the Regressions for a single commit. The
+// Regressions are stored serialized as JSON, just as the other stores do, so
+// that callers can't modify stored values.
+type entry struct {
+	ts      int64
+	triaged bool
+	body    []byte
+}
+
+// MemRegressionStore implements regression.Store.
+type MemRegressionStore struct {
+	// mutex protects regressions.
+	mutex sync.Mutex
+
+	// regressions maps cid.ID()'s to entries.
+	regressions map[string]*entry
+}
+
+// New returns a new MemRegressionStore.
+func New() *MemRegressionStore {
+	return &MemRegressionStore{
+		regressions: map[string]*entry{},
+	}
+}
+
+// load returns the Regressions stored for the given commit id, or nil if there
+// are none. The caller must hold the mutex.
+func (s *MemRegressionStore) load(id string) (*regression.Regressions, error) {
+	e, ok := s.regressions[id]
+	if !ok {
+		return nil, nil
+	}
+	ret := regression.New()
+	if err := json.Unmarshal(e.body, ret); err != nil {
+		return nil, fmt.Errorf("Failed to decode JSON body: %s", err)
+	}
+	return ret, nil
+}
+
+// store the Regressions for the given commit. The caller must hold the mutex.
+func (s *MemRegressionStore) store(c *cid.CommitDetail, r *regression.Regressions) error {
+	body, err := r.JSON()
+	if err != nil {
+		return fmt.Errorf("Failed to encode Regressions to JSON: %s", err)
+	}
+	s.regressions[c.ID()] = &entry{
+		ts:      c.Timestamp,
+		triaged: r.Triaged(),
+		body:    body,
+	}
+	return nil
+}
+
+// update loads the Regressions for the given commit, applies 'f', and stores
+// the result. If there are no Regressions for the commit then a new one is
+// created if 'create' is true, otherwise an error is returned.
+func (s *MemRegressionStore) update(c *cid.CommitDetail, create bool, f func(r *regression.Regressions) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	r, err := s.load(c.ID())
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		if !create {
+			return fmt.Errorf("Failed to load Regressions: no regressions at %q", c.ID())
+		}
+		r = regression.New()
+	}
+	if err := f(r); err != nil {
+		return err
+	}
+	return s.store(c, r)
+}
+
+// Untriaged implements regression.Store.
+func (s *MemRegressionStore) Untriaged(ctx context.Context) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	count := 0
+	for _, e := range s.regressions {
+		if !e.triaged {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Range implements regression.Store.
+func (s *MemRegressionStore) Range(ctx context.Context, begin, end int64) (map[string]*regression.Regressions, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ret := map[string]*regression.Regressions{}
+	for id, e := range s.regressions {
+		if e.ts < begin || e.ts >= end {
+			continue
+		}
+		r, err := s.load(id)
+		if err != nil {
+			return nil, err
+		}
+		ret[id] = r
+	}
+	return ret, nil
+}
+
+// SetHigh implements regression.Store.
+func (s *MemRegressionStore) SetHigh(ctx context.Context, c *cid.CommitDetail, alertID string, df *dataframe.FrameResponse, high *clustering2.ClusterSummary) (bool, error) {
+	isNew := false
+	err := s.update(c, true, func(r *regression.Regressions) error {
+		isNew = r.SetHigh(alertID, df, high)
+		return nil
+	})
+	return isNew, err
+}
+
+// SetLow implements regression.Store.
+func (s *MemRegressionStore) SetLow(ctx context.Context, c *cid.CommitDetail, alertID string, df *dataframe.FrameResponse, low *clustering2.ClusterSummary) (bool, error) {
+	isNew := false
+	err := s.update(c, true, func(r *regression.Regressions) error {
+		isNew = r.SetLow(alertID, df, low)
+		return nil
+	})
+	return isNew, err
+}
+
+// TriageLow implements regression.Store.
+func (s *MemRegressionStore) TriageLow(ctx context.Context, c *cid.CommitDetail, alertID string, tr regression.TriageStatus) error {
+	return s.update(c, false, func(r *regression.Regressions) error {
+		if err := r.TriageLow(alertID, tr); err != nil {
+			return fmt.Errorf("Failed to update Regressions: %s", err)
+		}
+		return nil
+	})
+}
+
+// TriageHigh implements regression.Store.
+func (s *MemRegressionStore) TriageHigh(ctx context.Context, c *cid.CommitDetail, alertID string, tr regression.TriageStatus) error {
+	return s.update(c, false, func(r *regression.Regressions) error {
+		if err := r.TriageHigh(alertID, tr); err != nil {
+			return fmt.Errorf("Failed to update Regressions: %s", err)
+		}
+		return nil
+	})
+}
+
+// SetIssueID implements regression.Store.
+func (s *MemRegressionStore) SetIssueID(ctx context.Context, c *cid.CommitDetail, alertID string, issueID int64) error {
+	return s.update(c, false, func(r *regression.Regressions) error {
+		if err := r.SetIssueID(alertID, issueID); err != nil {
+			return fmt.Errorf("Failed to update Regressions: %s", err)
+		}
+		return nil
+	})
+}
+
+// Write implements regression.Store.
+func (s *MemRegressionStore) Write(ctx context.Context, regressions map[string]*regression.Regressions, lookup regression.DetailLookup) error {
+	for cidString, reg := range regressions {
+		c, err := cid.FromID(cidString)
+		if err != nil {
+			return fmt.Errorf("Got an invalid cid %q: %s", cidString, err)
+		}
+		commitDetail, err := lookup(c)
+		if err != nil {
+			return fmt.Errorf("Could not find details for cid %q: %s", cidString, err)
+		}
+		s.mutex.Lock()
+		err = s.store(commitDetail, reg)
+		s.mutex.Unlock()
+		if err != nil {
+			return fmt.Errorf("Could not store regressions for cid %q: %s", cidString, err)
+		}
+	}
+	return nil
+}
+
+// Confirm we implement the interface.
+var _ regression.Store = (*MemRegressionStore)(nil)
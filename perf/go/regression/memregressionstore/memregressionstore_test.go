@@ -0,0 +1,56 @@
+package memregressionstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/clustering2"
+	"go.skia.org/infra/perf/go/dataframe"
+	"go.skia.org/infra/perf/go/regression"
+	"go.skia.org/infra/perf/go/regression/shared_tests"
+)
+
+func TestMemRegressionStore(t *testing.T) {
+	unittest.SmallTest(t)
+
+	shared_tests.TestRegressionStore(t, New())
+}
+
+func TestNewIssueIDStore(t *testing.T) {
+	unittest.SmallTest(t)
+
+	st := New()
+	c := &cid.CommitDetail{
+		CommitID: cid.CommitID{
+			Offset: 1,
+		},
+		Timestamp: 1479235651,
+	}
+	issueIDs := regression.NewIssueIDStore(st)
+
+	// No regression, so no bug.
+	id, err := issueIDs.GetIssueID(c, 12)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), id)
+	assert.Error(t, issueIDs.SetIssueID(c, 12, 1234))
+
+	_, err = st.SetHigh(context.Background(), c, "12", &dataframe.FrameResponse{}, &clustering2.ClusterSummary{})
+	require.NoError(t, err)
+	id, err = issueIDs.GetIssueID(c, 12)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), id)
+
+	require.NoError(t, issueIDs.SetIssueID(c, 12, 1234))
+	id, err = issueIDs.GetIssueID(c, 12)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), id)
+
+	// Other alerts at the same commit have no bug.
+	id, err = issueIDs.GetIssueID(c, 13)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), id)
+}
@@ -0,0 +1,133 @@
+package regression
+
+import (
+	"fmt"
+	"math"
+
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/clustering2"
+	"go.skia.org/infra/perf/go/stepfit"
+)
+
+const (
+	// RECOVERY_MIN_KEY_OVERLAP is the fraction of the traces in a regression
+	// cluster that must also appear in a later cluster for the later cluster
+	// to count as a recovery of the regression.
+	RECOVERY_MIN_KEY_OVERLAP = 0.5
+
+	// RECOVERY_STEP_SIZE_TOLERANCE is how much the magnitudes of the step
+	// sizes of a regression and its recovery can differ, as a fraction of the
+	// larger of the two.
+	RECOVERY_STEP_SIZE_TOLERANCE = 0.5
+)
+
+// KeysLookup returns the trace ids for the given shortcut id. Note that
+// clustering2.ClusterSummary.Keys isn't serialized, so the keys of a stored
+// cluster are only available via its Shortcut.
+type KeysLookup func(shortcut string) ([]string, error)
+
+// opposite returns the StepFit status of a step in the opposite direction, or
+// the empty string if status isn't HIGH or LOW.
+func opposite(status string) string {
+	switch status {
+	case stepfit.HIGH:
+		return stepfit.LOW
+	case stepfit.LOW:
+		return stepfit.HIGH
+	}
+	return ""
+}
+
+// keyOverlap returns the fraction of the keys in 'earlier' that also appear
+// in 'later'.
+func keyOverlap(earlier, later []string) float64 {
+	if len(earlier) == 0 {
+		return 0
+	}
+	laterSet := util.NewStringSet(later)
+	count := 0
+	for _, key := range earlier {
+		if laterSet[key] {
+			count++
+		}
+	}
+	return float64(count) / float64(len(earlier))
+}
+
+// isRecovery returns true if the cluster 'later' reverts the regression
+// cluster 'earlier', i.e. it is a step in the opposite direction, of similar
+// magnitude, in mostly the same traces.
+func isRecovery(earlier *clustering2.ClusterSummary, earlierKeys []string, later *clustering2.ClusterSummary) bool {
+	if earlier == nil || later == nil || earlier.StepFit == nil || later.StepFit == nil {
+		return false
+	}
+	if opposite(earlier.StepFit.Status) == "" || later.StepFit.Status != opposite(earlier.StepFit.Status) {
+		return false
+	}
+	a := math.Abs(float64(earlier.StepFit.StepSize))
+	b := math.Abs(float64(later.StepFit.StepSize))
+	if math.Abs(a-b) > RECOVERY_STEP_SIZE_TOLERANCE*math.Max(a, b) {
+		return false
+	}
+	return keyOverlap(earlierKeys, later.Keys) >= RECOVERY_MIN_KEY_OVERLAP
+}
+
+// Recovered describes an untriaged regression that has been reverted by a
+// later step.
+type Recovered struct {
+	// CommitID is the commit the regression was found at.
+	CommitID *cid.CommitID
+
+	// Cluster is the regression cluster.
+	Cluster *clustering2.ClusterSummary
+
+	// Low is true if the regression is the Low cluster of the Regression.
+	Low bool
+}
+
+// FindRecovered searches 'regressions', a map from cid.ID()'s to
+// *Regressions as returned from Store.Range, for an untriaged regression for
+// the given alert that was found at a commit before 'at' and is reverted by
+// the cluster 'cl' found at 'at'. If more than one regression matches then the
+// most recent is returned. Returns nil if no regression is found.
+func FindRecovered(regressions map[string]*Regressions, alertID string, at *cid.CommitID, cl *clustering2.ClusterSummary, lookup KeysLookup) (*Recovered, error) {
+	var ret *Recovered
+	for id, regs := range regressions {
+		commitID, err := cid.FromID(id)
+		if err != nil {
+			return nil, fmt.Errorf("Found invalid commit id %q: %s", id, err)
+		}
+		if commitID.Offset >= at.Offset || (ret != nil && commitID.Offset <= ret.CommitID.Offset) {
+			continue
+		}
+		reg, ok := regs.ByAlertID[alertID]
+		if !ok {
+			continue
+		}
+		candidates := []*Recovered{}
+		if reg.Low != nil && reg.LowStatus.Status == UNTRIAGED {
+			candidates = append(candidates, &Recovered{CommitID: commitID, Cluster: reg.Low, Low: true})
+		}
+		if reg.High != nil && reg.HighStatus.Status == UNTRIAGED {
+			candidates = append(candidates, &Recovered{CommitID: commitID, Cluster: reg.High, Low: false})
+		}
+		for _, candidate := range candidates {
+			if candidate.Cluster.StepFit == nil || cl.StepFit == nil || candidate.Cluster.StepFit.Status != opposite(cl.StepFit.Status) {
+				continue
+			}
+			keys := candidate.Cluster.Keys
+			if len(keys) == 0 && candidate.Cluster.Shortcut != "" {
+				keys, err = lookup(candidate.Cluster.Shortcut)
+				if err != nil {
+					return nil, fmt.Errorf("Failed to load keys for shortcut %q: %s", candidate.Cluster.Shortcut, err)
+				}
+			}
+			if isRecovery(candidate.Cluster, keys, cl) {
+				ret = candidate
+				break
+			}
+		}
+	}
+	return ret, nil
+}
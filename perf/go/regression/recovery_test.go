@@ -0,0 +1,127 @@
+package regression
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/clustering2"
+	"go.skia.org/infra/perf/go/stepfit"
+)
+
+func newCluster(status string, stepSize float32, keys ...string) *clustering2.ClusterSummary {
+	return &clustering2.ClusterSummary{
+		Keys: keys,
+		StepFit: &stepfit.StepFit{
+			Status:   status,
+			StepSize: stepSize,
+		},
+	}
+}
+
+func TestIsRecovery(t *testing.T) {
+	unittest.SmallTest(t)
+
+	earlier := newCluster(stepfit.HIGH, -2.0, ",a=1,", ",a=2,")
+	earlierKeys := earlier.Keys
+
+	// Opposite step of the same size in the same traces.
+	assert.True(t, isRecovery(earlier, earlierKeys, newCluster(stepfit.LOW, 2.0, ",a=1,", ",a=2,")))
+
+	// Similar enough magnitude, and enough overlap of traces.
+	assert.True(t, isRecovery(earlier, earlierKeys, newCluster(stepfit.LOW, 1.5, ",a=1,", ",a=3,")))
+
+	// Same direction.
+	assert.False(t, isRecovery(earlier, earlierKeys, newCluster(stepfit.HIGH, -2.0, ",a=1,", ",a=2,")))
+
+	// Much smaller step.
+	assert.False(t, isRecovery(earlier, earlierKeys, newCluster(stepfit.LOW, 0.5, ",a=1,", ",a=2,")))
+
+	// Different traces.
+	assert.False(t, isRecovery(earlier, earlierKeys, newCluster(stepfit.LOW, 2.0, ",a=3,", ",a=4,")))
+
+	// Not a regression.
+	assert.False(t, isRecovery(newCluster(stepfit.UNINTERESTING, 0), nil, newCluster(stepfit.LOW, 2.0)))
+}
+
+func TestKeyOverlap(t *testing.T) {
+	unittest.SmallTest(t)
+
+	assert.Equal(t, 0.0, keyOverlap(nil, []string{"a"}))
+	assert.Equal(t, 0.5, keyOverlap([]string{"a", "b"}, []string{"a", "c"}))
+	assert.Equal(t, 1.0, keyOverlap([]string{"a"}, []string{"a", "c"}))
+}
+
+func TestFindRecovered(t *testing.T) {
+	unittest.SmallTest(t)
+
+	// Stored clusters don't have Keys, so they are looked up by shortcut.
+	stored := func(status string, stepSize float32, shortcut string) *clustering2.ClusterSummary {
+		cl := newCluster(status, stepSize)
+		cl.Shortcut = shortcut
+		return cl
+	}
+	lookup := func(shortcut string) ([]string, error) {
+		switch shortcut {
+		case "X1":
+			return []string{",a=1,", ",a=2,"}, nil
+		case "X2":
+			return []string{",a=3,"}, nil
+		}
+		return nil, fmt.Errorf("Unknown shortcut: %q", shortcut)
+	}
+
+	regressions := map[string]*Regressions{}
+	add := func(offset int, alertID string, high *clustering2.ClusterSummary, status Status) {
+		r := New()
+		r.SetHigh(alertID, nil, high)
+		r.ByAlertID[alertID].HighStatus.Status = status
+		regressions[cid.CommitID{Offset: offset}.ID()] = r
+	}
+	add(10, "1", stored(stepfit.HIGH, -2.0, "X1"), UNTRIAGED)
+	add(12, "1", stored(stepfit.HIGH, -2.0, "X1"), UNTRIAGED)
+	add(13, "1", stored(stepfit.HIGH, -2.0, "X1"), NEGATIVE)
+	add(14, "1", stored(stepfit.HIGH, -2.0, "X2"), UNTRIAGED)
+	add(15, "2", stored(stepfit.HIGH, -2.0, "X1"), UNTRIAGED)
+	add(30, "1", stored(stepfit.HIGH, -2.0, "X1"), UNTRIAGED)
+
+	at := &cid.CommitID{Offset: 20}
+	cl := newCluster(stepfit.LOW, 2.0, ",a=1,", ",a=2,")
+	recovered, err := FindRecovered(regressions, "1", at, cl, lookup)
+	require.NoError(t, err)
+	require.NotNil(t, recovered)
+	// The most recent untriaged regression for the alert in the same traces
+	// and before 'at' is returned.
+	assert.Equal(t, 12, recovered.CommitID.Offset)
+	assert.False(t, recovered.Low)
+
+	// Nothing matches a step in the same direction.
+	recovered, err = FindRecovered(regressions, "1", at, newCluster(stepfit.HIGH, -2.0, ",a=1,"), lookup)
+	require.NoError(t, err)
+	assert.Nil(t, recovered)
+
+	// Nothing matches for an unknown alert.
+	recovered, err = FindRecovered(regressions, "3", at, cl, lookup)
+	require.NoError(t, err)
+	assert.Nil(t, recovered)
+}
+
+func TestFindRecovered_LookupFails(t *testing.T) {
+	unittest.SmallTest(t)
+
+	r := New()
+	high := newCluster(stepfit.HIGH, -2.0)
+	high.Shortcut = "X1"
+	r.SetHigh("1", nil, high)
+	regressions := map[string]*Regressions{
+		cid.CommitID{Offset: 10}.ID(): r,
+	}
+	lookup := func(shortcut string) ([]string, error) {
+		return nil, fmt.Errorf("Failed")
+	}
+	_, err := FindRecovered(regressions, "1", &cid.CommitID{Offset: 20}, newCluster(stepfit.LOW, 2.0, ",a=1,"), lookup)
+	assert.Error(t, err)
+}
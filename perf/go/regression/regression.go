@@ -21,6 +21,7 @@ const (
 	POSITIVE  Status = "positive"  // This change in performance is OK/expected.
 	NEGATIVE  Status = "negative"  // This regression is a bug.
 	UNTRIAGED Status = "untriaged" // The regression has not been triaged.
+	RECOVERED Status = "recovered" // The regression was reverted by a later change, see TriageStatus.Link.
 )
 
 // Regressions is a map[alertid]Regression and one Regressions is stored for each
@@ -33,6 +34,12 @@ type Regressions struct {
 type TriageStatus struct {
 	Status  Status `json:"status"`
 	Message string `json:"message"`
+
+	// Link is only set when Status is RECOVERED, and is the cid.ID() of the
+	// other commit involved, i.e. for a regression it is the commit where the
+	// regression recovered, and for a recovery it is the commit of the
+	// regression it reverted.
+	Link string `json:"link,omitempty"`
 }
 
 // Regression tracks the status of the Low and High regression clusters, if they
@@ -49,6 +56,7 @@ type Regression struct {
 	Frame      *dataframe.FrameResponse    `json:"frame"` // Describes the Low and High ClusterSummary's.
 	LowStatus  TriageStatus                `json:"low_status"`
 	HighStatus TriageStatus                `json:"high_status"`
+	IssueID    int64                       `json:"issue_id,omitempty"` // The bug filed for this regression, 0 if none.
 }
 
 func newRegression() *Regression {
@@ -92,6 +100,9 @@ func (r *Regression) Merge(rhs *Regression) *Regression {
 			r.Frame = rhs.Frame
 		}
 	}
+	if rhs.IssueID != 0 {
+		r.IssueID = rhs.IssueID
+	}
 	return r
 }
 
@@ -183,6 +194,19 @@ func (r *Regressions) TriageHigh(alertid string, tr TriageStatus) error {
 	return nil
 }
 
+// SetIssueID records the id of the bug filed for the regression found for
+// the given alert.
+func (r *Regressions) SetIssueID(alertid string, issueID int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	reg, ok := r.ByAlertID[alertid]
+	if !ok {
+		return ErrNoClusterFound
+	}
+	reg.IssueID = issueID
+	return nil
+}
+
 // Triaged returns true if all clusters are triaged.
 func (r *Regressions) Triaged() bool {
 	ret := true
@@ -0,0 +1,159 @@
+// Package shared_tests contains tests that every implementation of
+// regression.Store should pass.
+package shared_tests
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/go/testutils"
+	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/clustering2"
+	"go.skia.org/infra/perf/go/dataframe"
+	"go.skia.org/infra/perf/go/regression"
+)
+
+// TestRegressionStore exercises all the methods of regression.Store. The
+// store must be empty.
+func TestRegressionStore(t sktest.TestingT, st regression.Store) {
+	ctx := context.Background()
+	c := &cid.CommitDetail{
+		CommitID: cid.CommitID{
+			Offset: 1,
+		},
+		Timestamp: 1479235651,
+	}
+
+	df := &dataframe.FrameResponse{}
+	cl := &clustering2.ClusterSummary{}
+
+	now := time.Unix(c.Timestamp, 0)
+	begin := now.Add(-time.Hour).Unix()
+	end := now.Add(time.Hour).Unix()
+
+	// Start empty.
+	ranges, err := st.Range(ctx, begin, end)
+	require.NoError(t, err)
+	assert.Empty(t, ranges)
+	count, err := st.Untriaged(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// Create a new regression.
+	isNew, err := st.SetLow(ctx, c, "foo", df, cl)
+	assert.True(t, isNew)
+	require.NoError(t, err)
+
+	// Overwrite a regression.
+	isNew, err = st.SetLow(ctx, c, "foo", df, cl)
+	assert.False(t, isNew)
+	require.NoError(t, err)
+
+	// Confirm new regression is present.
+	ranges, err = st.Range(ctx, begin, end)
+	require.NoError(t, err)
+	assert.Len(t, ranges, 1)
+	assert.Equal(t, regression.UNTRIAGED, ranges["master-000001"].ByAlertID["foo"].LowStatus.Status)
+
+	// The end of the range is exclusive.
+	ranges, err = st.Range(ctx, begin, c.Timestamp)
+	require.NoError(t, err)
+	assert.Empty(t, ranges)
+
+	err = testutils.EventuallyConsistent(time.Second, func() error {
+		count, err = st.Untriaged(ctx)
+		require.NoError(t, err)
+		if count != 1 {
+			return testutils.TryAgainErr
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Triage existing regression.
+	tr := regression.TriageStatus{
+		Status:  regression.POSITIVE,
+		Message: "bad",
+	}
+	err = st.TriageLow(ctx, c, "foo", tr)
+	require.NoError(t, err)
+
+	// Confirm regression is triaged.
+	err = testutils.EventuallyConsistent(time.Second, func() error {
+		ranges, err = st.Range(ctx, begin, end)
+		require.NoError(t, err)
+		if ranges["master-000001"].ByAlertID["foo"].LowStatus == tr {
+			return nil
+		}
+		return testutils.TryAgainErr
+	})
+	require.NoError(t, err)
+
+	err = testutils.EventuallyConsistent(time.Second, func() error {
+		count, err = st.Untriaged(ctx)
+		require.NoError(t, err)
+		if count != 0 {
+			return testutils.TryAgainErr
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Try triaging a regression that doesn't exist.
+	err = st.TriageHigh(ctx, c, "bar", tr)
+	assert.Error(t, err)
+
+	// Try triaging at a commit that has no regressions.
+	err = st.TriageHigh(ctx, &cid.CommitDetail{CommitID: cid.CommitID{Offset: 5}, Timestamp: c.Timestamp}, "foo", tr)
+	assert.Error(t, err)
+
+	// Add a high regression at the same commit.
+	_, err = st.SetHigh(ctx, c, "foo", df, cl)
+	require.NoError(t, err)
+	err = st.TriageHigh(ctx, c, "foo", regression.TriageStatus{Status: regression.NEGATIVE})
+	require.NoError(t, err)
+
+	ranges, err = st.Range(ctx, begin, end)
+	require.NoError(t, err)
+	require.Len(t, ranges, 1)
+	assert.Equal(t, regression.NEGATIVE, ranges["master-000001"].ByAlertID["foo"].HighStatus.Status)
+	assert.Equal(t, regression.POSITIVE, ranges["master-000001"].ByAlertID["foo"].LowStatus.Status)
+
+	// Record the bug filed for the regression.
+	require.NoError(t, st.SetIssueID(ctx, c, "foo", 1234))
+	err = testutils.EventuallyConsistent(time.Second, func() error {
+		ranges, err = st.Range(ctx, begin, end)
+		require.NoError(t, err)
+		if ranges["master-000001"].ByAlertID["foo"].IssueID == 1234 {
+			return nil
+		}
+		return testutils.TryAgainErr
+	})
+	require.NoError(t, err)
+	assert.Error(t, st.SetIssueID(ctx, c, "bar", 1234))
+
+	lookup := func(c *cid.CommitID) (*cid.CommitDetail, error) {
+		return &cid.CommitDetail{
+			CommitID: cid.CommitID{
+				Offset: 2,
+			},
+			Timestamp: 1479235651 + 10,
+		}, nil
+	}
+	err = st.Write(ctx, map[string]*regression.Regressions{"master-000002": ranges["master-000001"]}, lookup)
+	require.NoError(t, err)
+	err = testutils.EventuallyConsistent(time.Second, func() error {
+		ranges, err = st.Range(ctx, begin, end)
+		require.NoError(t, err)
+		if len(ranges) != 2 {
+			return testutils.TryAgainErr
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	_, ok := ranges["master-000002"]
+	assert.True(t, ok)
+}
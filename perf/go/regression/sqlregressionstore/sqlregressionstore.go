@@ -0,0 +1,238 @@
+// Package sqlregressionstore implements regression.Store on top of an SQL
+// database. The same SQL is used for both SQLite and Postgres.
+//
+// Just like dsregressionstore, all the Regressions for a single commit are
+// stored as a single JSON encoded row in the Regressions table.
+package sqlregressionstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/clustering2"
+	"go.skia.org/infra/perf/go/dataframe"
+	"go.skia.org/infra/perf/go/regression"
+)
+
+// schema is the set of statements that create the tables and indices used by
+// SQLRegressionStore. Every statement is idempotent.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS Regressions (
+		commit_id  TEXT PRIMARY KEY,
+		ts         BIGINT NOT NULL,
+		triaged    BOOLEAN NOT NULL,
+		body       TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS Regressions_ts ON Regressions (ts)`,
+	`CREATE INDEX IF NOT EXISTS Regressions_triaged ON Regressions (triaged)`,
+}
+
+// SQLRegressionStore implements regression.Store.
+type SQLRegressionStore struct {
+	db *sql.DB
+
+	// mutex serializes the read-modify-write updates of a row. Note that this
+	// only protects against concurrent updates from within a single process.
+	mutex sync.Mutex
+}
+
+// New returns a new SQLRegressionStore, creating the tables if they don't
+// already exist.
+func New(db *sql.DB) (*SQLRegressionStore, error) {
+	for _, statement := range schema {
+		if _, err := db.Exec(statement); err != nil {
+			return nil, skerr.Wrapf(err, "Failed to create Regressions table.")
+		}
+	}
+	return &SQLRegressionStore{
+		db: db,
+	}, nil
+}
+
+// write stores the Regressions for the given commit using 'tx'.
+func write(ctx context.Context, tx *sql.Tx, c *cid.CommitDetail, r *regression.Regressions) error {
+	body, err := r.JSON()
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to encode Regressions to JSON.")
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO Regressions (commit_id, ts, triaged, body) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (commit_id) DO UPDATE SET ts=excluded.ts, triaged=excluded.triaged, body=excluded.body`,
+		c.ID(), c.Timestamp, r.Triaged(), string(body))
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to write to database.")
+	}
+	return nil
+}
+
+// update loads the Regressions for the given commit, applies 'f', and stores
+// the result, all in a single transaction. If there are no Regressions for the
+// commit then a new one is created if 'create' is true, otherwise an error is
+// returned.
+func (s *SQLRegressionStore) update(ctx context.Context, c *cid.CommitDetail, create bool, f func(r *regression.Regressions) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to start transaction.")
+	}
+	if err := updateInTx(ctx, tx, c, create, f); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return skerr.Wrapf(err, "Failed to commit Regressions.")
+	}
+	return nil
+}
+
+// updateInTx does the work of update() using 'tx'.
+func updateInTx(ctx context.Context, tx *sql.Tx, c *cid.CommitDetail, create bool, f func(r *regression.Regressions) error) error {
+	r := regression.New()
+	var body string
+	err := tx.QueryRowContext(ctx, `SELECT body FROM Regressions WHERE commit_id=$1`, c.ID()).Scan(&body)
+	if err == sql.ErrNoRows {
+		if !create {
+			return skerr.Fmt("Failed to load Regressions: no regressions at %q", c.ID())
+		}
+	} else if err != nil {
+		return skerr.Wrapf(err, "Failed to load Regressions.")
+	} else if err := json.Unmarshal([]byte(body), r); err != nil {
+		return skerr.Wrapf(err, "Failed to decode JSON body.")
+	}
+	if err := f(r); err != nil {
+		return err
+	}
+	return write(ctx, tx, c, r)
+}
+
+// Untriaged implements regression.Store.
+func (s *SQLRegressionStore) Untriaged(ctx context.Context) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM Regressions WHERE triaged=$1`, false).Scan(&count); err != nil {
+		return -1, skerr.Wrapf(err, "Failed to read from database.")
+	}
+	return count, nil
+}
+
+// Range implements regression.Store.
+func (s *SQLRegressionStore) Range(ctx context.Context, begin, end int64) (map[string]*regression.Regressions, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT commit_id, body FROM Regressions WHERE ts >= $1 AND ts < $2`, begin, end)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed to read from database.")
+	}
+	defer util.Close(rows)
+	ret := map[string]*regression.Regressions{}
+	for rows.Next() {
+		var id, body string
+		if err := rows.Scan(&id, &body); err != nil {
+			return nil, skerr.Wrapf(err, "Failed to read from database.")
+		}
+		reg := regression.New()
+		if err := json.Unmarshal([]byte(body), reg); err != nil {
+			return nil, skerr.Wrapf(err, "Failed to decode JSON body.")
+		}
+		ret[id] = reg
+	}
+	if err := rows.Err(); err != nil {
+		return nil, skerr.Wrapf(err, "Failed to read from database.")
+	}
+	return ret, nil
+}
+
+// SetHigh implements regression.Store.
+func (s *SQLRegressionStore) SetHigh(ctx context.Context, c *cid.CommitDetail, alertID string, df *dataframe.FrameResponse, high *clustering2.ClusterSummary) (bool, error) {
+	isNew := false
+	err := s.update(ctx, c, true, func(r *regression.Regressions) error {
+		isNew = r.SetHigh(alertID, df, high)
+		return nil
+	})
+	return isNew, err
+}
+
+// SetLow implements regression.Store.
+func (s *SQLRegressionStore) SetLow(ctx context.Context, c *cid.CommitDetail, alertID string, df *dataframe.FrameResponse, low *clustering2.ClusterSummary) (bool, error) {
+	isNew := false
+	err := s.update(ctx, c, true, func(r *regression.Regressions) error {
+		isNew = r.SetLow(alertID, df, low)
+		return nil
+	})
+	return isNew, err
+}
+
+// TriageLow implements regression.Store.
+func (s *SQLRegressionStore) TriageLow(ctx context.Context, c *cid.CommitDetail, alertID string, tr regression.TriageStatus) error {
+	return s.update(ctx, c, false, func(r *regression.Regressions) error {
+		if err := r.TriageLow(alertID, tr); err != nil {
+			return skerr.Wrapf(err, "Failed to update Regressions.")
+		}
+		return nil
+	})
+}
+
+// TriageHigh implements regression.Store.
+func (s *SQLRegressionStore) TriageHigh(ctx context.Context, c *cid.CommitDetail, alertID string, tr regression.TriageStatus) error {
+	return s.update(ctx, c, false, func(r *regression.Regressions) error {
+		if err := r.TriageHigh(alertID, tr); err != nil {
+			return skerr.Wrapf(err, "Failed to update Regressions.")
+		}
+		return nil
+	})
+}
+
+// SetIssueID implements regression.Store.
+func (s *SQLRegressionStore) SetIssueID(ctx context.Context, c *cid.CommitDetail, alertID string, issueID int64) error {
+	return s.update(ctx, c, false, func(r *regression.Regressions) error {
+		if err := r.SetIssueID(alertID, issueID); err != nil {
+			return skerr.Wrapf(err, "Failed to update Regressions.")
+		}
+		return nil
+	})
+}
+
+// writeOne overwrites the Regressions stored for the given commit. The caller
+// must hold the mutex.
+func (s *SQLRegressionStore) writeOne(ctx context.Context, c *cid.CommitDetail, r *regression.Regressions) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to start transaction.")
+	}
+	if err := write(ctx, tx, c, r); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return skerr.Wrapf(err, "Failed to commit Regressions.")
+	}
+	return nil
+}
+
+// Write implements regression.Store.
+func (s *SQLRegressionStore) Write(ctx context.Context, regressions map[string]*regression.Regressions, lookup regression.DetailLookup) error {
+	for cidString, reg := range regressions {
+		c, err := cid.FromID(cidString)
+		if err != nil {
+			return skerr.Wrapf(err, "Got an invalid cid %q.", cidString)
+		}
+		commitDetail, err := lookup(c)
+		if err != nil {
+			return skerr.Wrapf(err, "Could not find details for cid %q.", cidString)
+		}
+		s.mutex.Lock()
+		err = s.writeOne(ctx, commitDetail, reg)
+		s.mutex.Unlock()
+		if err != nil {
+			return skerr.Wrapf(err, "Could not store regressions for cid %q.", cidString)
+		}
+	}
+	return nil
+}
+
+// Confirm we implement the interface.
+var _ regression.Store = (*SQLRegressionStore)(nil)
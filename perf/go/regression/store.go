@@ -0,0 +1,62 @@
+package regression
+
+import (
+	"context"
+
+	"go.skia.org/infra/perf/go/cid"
+	"go.skia.org/infra/perf/go/clustering2"
+	"go.skia.org/infra/perf/go/dataframe"
+)
+
+// Subset is the subset of regressions we are querying for.
+type Subset string
+
+const (
+	ALL_SUBSET         Subset = "all"         // Include all regressions in a range.
+	REGRESSIONS_SUBSET Subset = "regressions" // Only include regressions in a range that are alerting.
+	UNTRIAGED_SUBSET   Subset = "untriaged"   // All untriaged alerting regressions regardless of range.
+)
+
+// DetailLookup is used by Store.Write to find the CommitDetail for a
+// CommitID.
+type DetailLookup func(c *cid.CommitID) (*cid.CommitDetail, error)
+
+// Store persists Regressions.
+//
+// Regressions are stored per commit, keyed by cid.ID(), along with the
+// timestamp of the commit. Implementations live in the dsregressionstore,
+// sqlregressionstore, and memregressionstore sub-packages.
+type Store interface {
+	// Untriaged returns the number of commits that have untriaged
+	// regressions.
+	Untriaged(ctx context.Context) (int, error)
+
+	// Range returns a map from cid.ID()'s to *Regressions that exist in the
+	// given time range, where begin and end are Unix timestamps and end is
+	// exclusive.
+	Range(ctx context.Context, begin, end int64) (map[string]*Regressions, error)
+
+	// SetHigh sets the cluster for a high regression at the given commit and
+	// alertID. Returns true if this is a new regression.
+	SetHigh(ctx context.Context, cid *cid.CommitDetail, alertID string, df *dataframe.FrameResponse, high *clustering2.ClusterSummary) (bool, error)
+
+	// SetLow sets the cluster for a low regression at the given commit and
+	// alertID. Returns true if this is a new regression.
+	SetLow(ctx context.Context, cid *cid.CommitDetail, alertID string, df *dataframe.FrameResponse, low *clustering2.ClusterSummary) (bool, error)
+
+	// TriageLow sets the triage status for the low cluster at the given
+	// commit and alertID.
+	TriageLow(ctx context.Context, cid *cid.CommitDetail, alertID string, tr TriageStatus) error
+
+	// TriageHigh sets the triage status for the high cluster at the given
+	// commit and alertID.
+	TriageHigh(ctx context.Context, cid *cid.CommitDetail, alertID string, tr TriageStatus) error
+
+	// SetIssueID records the id of the bug filed for the regression at the
+	// given commit and alertID.
+	SetIssueID(ctx context.Context, cid *cid.CommitDetail, alertID string, issueID int64) error
+
+	// Write the Regressions to the store. The keys of 'regressions' are
+	// cid.ID()'s and 'lookup' is used to find the details of each commit.
+	Write(ctx context.Context, regressions map[string]*Regressions, lookup DetailLookup) error
+}
@@ -0,0 +1,56 @@
+// Package dsshortcutstore implements shortcut2.Store using Cloud Datastore.
+package dsshortcutstore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.skia.org/infra/go/ds"
+	"go.skia.org/infra/perf/go/shortcut2"
+)
+
+// DSShortcutStore implements shortcut2.Store.
+type DSShortcutStore struct{}
+
+// New returns a new DSShortcutStore. Note that ds.Init must be called before
+// using the returned store.
+func New() *DSShortcutStore {
+	return &DSShortcutStore{}
+}
+
+// InsertShortcut implements shortcut2.Store.
+func (s *DSShortcutStore) InsertShortcut(ctx context.Context, shortcut *shortcut2.Shortcut) (string, error) {
+	key := ds.NewKey(ds.SHORTCUT)
+	key.Name = shortcut2.IDFromKeys(shortcut)
+	var err error
+	key, err = ds.DS.Put(ctx, key, shortcut)
+	if err != nil {
+		return "", fmt.Errorf("Failed to store shortcut: %s", err)
+	}
+	return key.Name, nil
+}
+
+// Get implements shortcut2.Store.
+func (s *DSShortcutStore) Get(ctx context.Context, id string) (*shortcut2.Shortcut, error) {
+	ret := &shortcut2.Shortcut{}
+
+	key := ds.NewKey(ds.SHORTCUT)
+	if strings.HasPrefix(id, "X") {
+		key.Name = id
+	} else {
+		i, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Error invalid id: %s", id)
+		}
+		key.ID = i
+	}
+	if err := ds.DS.Get(ctx, key, ret); err != nil {
+		return nil, fmt.Errorf("Error retrieving shortcut from db: %s", err)
+	}
+	return ret, nil
+}
+
+// Confirm we implement the interface.
+var _ shortcut2.Store = (*DSShortcutStore)(nil)
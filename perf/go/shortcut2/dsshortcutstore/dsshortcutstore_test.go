@@ -0,0 +1,18 @@
+package dsshortcutstore
+
+import (
+	"testing"
+
+	"go.skia.org/infra/go/ds"
+	"go.skia.org/infra/go/ds/testutil"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/shortcut2/shared_tests"
+)
+
+func TestDSShortcutStore(t *testing.T) {
+	unittest.LargeTest(t)
+	cleanup := testutil.InitDatastore(t, ds.SHORTCUT)
+	defer cleanup()
+
+	shared_tests.TestShortcutStore(t, New())
+}
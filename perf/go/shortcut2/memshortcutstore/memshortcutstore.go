@@ -0,0 +1,57 @@
+// Package memshortcutstore implements shortcut2.Store in memory, which is
+// useful for testing and for running Perf locally.
+package memshortcutstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.skia.org/infra/perf/go/shortcut2"
+)
+
+// MemShortcutStore implements shortcut2.Store.
+type MemShortcutStore struct {
+	// mutex protects shortcuts.
+	mutex sync.Mutex
+
+	// shortcuts maps shortcut ids to keys.
+	shortcuts map[string][]string
+}
+
+// New returns a new MemShortcutStore.
+func New() *MemShortcutStore {
+	return &MemShortcutStore{
+		shortcuts: map[string][]string{},
+	}
+}
+
+// InsertShortcut implements shortcut2.Store.
+func (s *MemShortcutStore) InsertShortcut(ctx context.Context, shortcut *shortcut2.Shortcut) (string, error) {
+	id := shortcut2.IDFromKeys(shortcut)
+	keys := make([]string, len(shortcut.Keys))
+	copy(keys, shortcut.Keys)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.shortcuts[id] = keys
+	return id, nil
+}
+
+// Get implements shortcut2.Store.
+func (s *MemShortcutStore) Get(ctx context.Context, id string) (*shortcut2.Shortcut, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	keys, ok := s.shortcuts[id]
+	if !ok {
+		return nil, fmt.Errorf("Unknown shortcut id: %q", id)
+	}
+	ret := &shortcut2.Shortcut{
+		Keys: make([]string, len(keys)),
+	}
+	copy(ret.Keys, keys)
+	return ret, nil
+}
+
+// Confirm we implement the interface.
+var _ shortcut2.Store = (*MemShortcutStore)(nil)
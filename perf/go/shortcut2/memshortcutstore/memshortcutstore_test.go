@@ -0,0 +1,14 @@
+package memshortcutstore
+
+import (
+	"testing"
+
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/shortcut2/shared_tests"
+)
+
+func TestMemShortcutStore(t *testing.T) {
+	unittest.SmallTest(t)
+
+	shared_tests.TestShortcutStore(t, New())
+}
@@ -0,0 +1,58 @@
+// Package shared_tests contains tests that every implementation of
+// shortcut2.Store should pass.
+package shared_tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/perf/go/shortcut2"
+)
+
+// TestShortcutStore exercises all the methods of shortcut2.Store. The store
+// must be empty.
+func TestShortcutStore(t sktest.TestingT, store shortcut2.Store) {
+	ctx := context.Background()
+
+	// Write a shortcut.
+	sh := &shortcut2.Shortcut{
+		Keys: []string{
+			"https://foo",
+			"https://bar",
+			"https://baz",
+		},
+	}
+	b, err := json.Marshal(sh)
+	require.NoError(t, err)
+	id, err := shortcut2.Insert(ctx, store, bytes.NewBuffer(b))
+	require.NoError(t, err)
+	assert.NotEqual(t, "", id)
+
+	// Read it back, confirm it is unchanged, except for being sorted.
+	sh2, err := store.Get(ctx, id)
+	require.NoError(t, err)
+	assert.NotEqual(t, sh, sh2)
+	sort.Strings(sh.Keys)
+	assert.Equal(t, sh, sh2)
+
+	// Inserting the same keys again returns the same id.
+	id2, err := store.InsertShortcut(ctx, &shortcut2.Shortcut{Keys: []string{"https://baz", "https://foo", "https://bar"}})
+	require.NoError(t, err)
+	assert.Equal(t, id, id2)
+
+	// An empty shortcut can be stored.
+	emptyID, err := store.InsertShortcut(ctx, &shortcut2.Shortcut{Keys: []string{}})
+	require.NoError(t, err)
+	empty, err := store.Get(ctx, emptyID)
+	require.NoError(t, err)
+	assert.Empty(t, empty.Keys)
+
+	// Unknown ids are an error.
+	_, err = store.Get(ctx, "Xunknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+// Package sqlshortcutstore implements shortcut2.Store on top of an SQL
+// database. The same SQL is used for both SQLite and Postgres.
+package sqlshortcutstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/perf/go/shortcut2"
+)
+
+// schema creates the Shortcuts table, where trace_ids is the JSON encoded
+// slice of keys of the shortcut.
+const schema = `CREATE TABLE IF NOT EXISTS Shortcuts (
+	id         TEXT PRIMARY KEY,
+	trace_ids  TEXT NOT NULL
+)`
+
+// SQLShortcutStore implements shortcut2.Store.
+type SQLShortcutStore struct {
+	db *sql.DB
+}
+
+// New returns a new SQLShortcutStore, creating the Shortcuts table if it
+// doesn't already exist.
+func New(db *sql.DB) (*SQLShortcutStore, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, skerr.Wrapf(err, "Failed to create Shortcuts table.")
+	}
+	return &SQLShortcutStore{
+		db: db,
+	}, nil
+}
+
+// InsertShortcut implements shortcut2.Store.
+func (s *SQLShortcutStore) InsertShortcut(ctx context.Context, shortcut *shortcut2.Shortcut) (string, error) {
+	id := shortcut2.IDFromKeys(shortcut)
+	keys := shortcut.Keys
+	if keys == nil {
+		keys = []string{}
+	}
+	b, err := json.Marshal(keys)
+	if err != nil {
+		return "", skerr.Wrapf(err, "Failed to encode shortcut keys.")
+	}
+	// The id is derived from the keys, so an existing row already has the
+	// same content.
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO Shortcuts (id, trace_ids) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING`, id, string(b)); err != nil {
+		return "", skerr.Wrapf(err, "Failed to store shortcut.")
+	}
+	return id, nil
+}
+
+// Get implements shortcut2.Store.
+func (s *SQLShortcutStore) Get(ctx context.Context, id string) (*shortcut2.Shortcut, error) {
+	var encoded string
+	if err := s.db.QueryRowContext(ctx, `SELECT trace_ids FROM Shortcuts WHERE id=$1`, id).Scan(&encoded); err != nil {
+		return nil, skerr.Wrapf(err, "Failed to load shortcut %q.", id)
+	}
+	ret := &shortcut2.Shortcut{}
+	if err := json.Unmarshal([]byte(encoded), &ret.Keys); err != nil {
+		return nil, skerr.Wrapf(err, "Failed to decode shortcut %q.", id)
+	}
+	return ret, nil
+}
+
+// Confirm we implement the interface.
+var _ shortcut2.Store = (*SQLShortcutStore)(nil)
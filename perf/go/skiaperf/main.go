@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
@@ -26,12 +27,14 @@ import (
 	"go.opencensus.io/trace"
 	"go.skia.org/infra/go/auth"
 	"go.skia.org/infra/go/calc"
+	"go.skia.org/infra/go/chatbot"
 	"go.skia.org/infra/go/common"
 	"go.skia.org/infra/go/ds"
 	"go.skia.org/infra/go/email"
 	"go.skia.org/infra/go/git/gitinfo"
 	"go.skia.org/infra/go/gitauth"
 	"go.skia.org/infra/go/httputils"
+	"go.skia.org/infra/go/issues"
 	"go.skia.org/infra/go/login"
 	"go.skia.org/infra/go/paramtools"
 	"go.skia.org/infra/go/query"
@@ -41,8 +44,8 @@ import (
 	"go.skia.org/infra/perf/go/activitylog"
 	"go.skia.org/infra/perf/go/alertfilter"
 	"go.skia.org/infra/perf/go/alerts"
-	"go.skia.org/infra/perf/go/btts"
 	"go.skia.org/infra/perf/go/bug"
+	"go.skia.org/infra/perf/go/builders"
 	"go.skia.org/infra/perf/go/cid"
 	"go.skia.org/infra/perf/go/config"
 	"go.skia.org/infra/perf/go/dataframe"
@@ -52,6 +55,7 @@ import (
 	"go.skia.org/infra/perf/go/psrefresh"
 	"go.skia.org/infra/perf/go/regression"
 	"go.skia.org/infra/perf/go/shortcut2"
+	"go.skia.org/infra/perf/go/trybot"
 	"go.skia.org/infra/perf/go/types"
 	"google.golang.org/api/option"
 )
@@ -92,13 +96,15 @@ var (
 
 // flags
 var (
-	bigTableConfig                 = flag.String("big_table_config", "nano", "The name of the config to use when using a BigTable trace store.")
+	configFilename                 = flag.String("config_filename", "", "The name of the JSON or JSON5 config file that describes the Perf instance, e.g. ./configs/nano.json. Required.")
 	clusterOnly                    = flag.Bool("cluster_only", true, "If true then run continuous clustering and not the UI.")
 	commitRangeURL                 = flag.String("commit_range_url", "", "A URI Template to be used for expanding details on a range of commits, from {begin} to {end} git hash. See cluster-summary2-sk.")
 	dataFrameSize                  = flag.Int("dataframe_size", dataframe.DEFAULT_NUM_COMMITS, "The number of commits to include in the default dataframe.")
 	defaultSparse                  = flag.Bool("default_sparse", false, "The default value for 'Sparse' in Alerts.")
 	doClustering                   = flag.Bool("do_clustering", true, "If true then run continuous clustering over all the alerts.")
 	noemail                        = flag.Bool("noemail", false, "Do not send emails.")
+	notifyChatBotName              = flag.String("notify_chat_bot_name", "", "The name the chat bot uses when sending alert notifications to chat rooms. If empty then alerts can't be sent to chat rooms.")
+	notifyIssueTrackerProject      = flag.String("notify_issue_tracker_project", "", "The Monorail project to file bugs in for alert notifications, e.g. 'skia'. If empty then alerts can't file bugs.")
 	emailClientIdFlag              = flag.String("email_clientid", "", "OAuth Client ID for sending email.")
 	emailClientSecretFile          = flag.String("email_client_secret_file", "client_secret.json", "OAuth client secret JSON file for sending email.")
 	emailClientSecretFlag          = flag.String("email_clientsecret", "", "OAuth Client Secret for sending email.")
@@ -132,7 +138,14 @@ var (
 
 	clusterRequests *regression.RunningRegressionDetectionRequests
 
-	regStore *regression.Store
+	regStore regression.Store
+
+	shortcutStore shortcut2.Store
+
+	activityStore activitylog.Store
+
+	// tryBotStore is nil if trybot results aren't supported by this instance.
+	tryBotStore trybot.Store
 
 	continuous []*regression.Continuous
 
@@ -144,7 +157,7 @@ var (
 
 	notifier *notify.Notifier
 
-	traceStore *btts.BigTableTraceStore
+	traceStore types.TraceStore
 
 	emailAuth *email.GMail
 
@@ -264,7 +277,7 @@ func Init() {
 		sklog.Fatal("When running in prod the datastore namespace must be a known value.")
 	}
 
-	scopes := []string{storage.ScopeReadOnly, datastore.ScopeDatastore, bigtable.Scope, auth.SCOPE_GERRIT}
+	scopes := []string{storage.ScopeReadOnly, datastore.ScopeDatastore, bigtable.Scope, auth.SCOPE_GERRIT, auth.SCOPE_USERINFO_EMAIL}
 
 	sklog.Info("About to create token source.")
 	ts, err := auth.NewDefaultTokenSource(*local, scopes...)
@@ -292,7 +305,7 @@ func Init() {
 	sklog.Info("About to parse templates.")
 	loadTemplates()
 
-	if err := config.Init(*bigTableConfig); err != nil {
+	if err := config.Init(*configFilename); err != nil {
 		sklog.Fatal(err)
 	}
 
@@ -332,7 +345,7 @@ func Init() {
 
 	sklog.Info("About to build dataframebuilder.")
 
-	traceStore, err = btts.NewBigTableTraceStoreFromConfig(ctx, config.Config, ts, false)
+	traceStore, err = builders.NewTraceStoreFromConfig(ctx, config.Config, ts, false)
 	if err != nil {
 		sklog.Fatalf("Failed to open trace store: %s", err)
 	}
@@ -342,7 +355,7 @@ func Init() {
 		sklog.Fatalf("Failed to build paramsetRefresher: %s", err)
 	}
 
-	dfBuilder = dfbuilder.NewDataFrameBuilderFromBTTS(vcs, traceStore)
+	dfBuilder = dfbuilder.NewDataFrameBuilderFromTraceStore(vcs, traceStore)
 
 	sklog.Info("About to build cidl.")
 	cidl = cid.New(ctx, vcs, config.Config.GitUrl)
@@ -361,14 +374,41 @@ func Init() {
 	} else {
 		notifier = notify.New(notify.NoEmail{}, *subdomain)
 	}
+	notifier.AddTransport(alerts.WebhookNotification, notify.NewWebhookTransport(httputils.NewTimeoutClient()))
+	if *notifyChatBotName != "" {
+		chatbot.Init(*notifyChatBotName)
+		notifier.AddTransport(alerts.ChatNotification, notify.NewChatTransport())
+	}
 
-	frameRequests = dataframe.NewRunningFrameRequests(vcs, dfBuilder)
-	clusterRequests = regression.NewRunningRegressionDetectionRequests(vcs, cidl, float32(*interesting), dfBuilder)
-	regStore = regression.NewStore()
+	sklog.Info("About to build stores.")
+	regStore, err = builders.NewRegressionStoreFromConfig(config.Config)
+	if err != nil {
+		sklog.Fatalf("Failed to build regression store: %s", err)
+	}
+	if *notifyIssueTrackerProject != "" {
+		client := httputils.DefaultClientConfig().WithTokenSource(ts).With2xxOnly().Client()
+		notifier.AddTransport(alerts.IssueNotification, notify.NewIssueTransport(issues.NewMonorailIssueTracker(client, *notifyIssueTrackerProject), regression.NewIssueIDStore(regStore)))
+	}
+	shortcutStore, err = builders.NewShortcutStoreFromConfig(config.Config)
+	if err != nil {
+		sklog.Fatalf("Failed to build shortcut store: %s", err)
+	}
+	activityStore, err = builders.NewActivityStoreFromConfig(config.Config)
+	if err != nil {
+		sklog.Fatalf("Failed to build activity store: %s", err)
+	}
+	tryBotStore, err = builders.NewTryBotStoreFromConfig(config.Config)
+	if err != nil {
+		sklog.Warningf("Trybot comparison disabled: %s", err)
+		tryBotStore = nil
+	}
+
+	frameRequests = dataframe.NewRunningFrameRequests(vcs, dfBuilder, shortcutStore)
+	clusterRequests = regression.NewRunningRegressionDetectionRequests(vcs, cidl, float32(*interesting), dfBuilder, shortcutStore)
 	configProvider = newAlertsConfigProvider()
 	paramsProvider := newParamsetProvider(paramsetRefresher)
 
-	dryrunRequests = dryrun.New(cidl, dfBuilder, paramsProvider, vcs)
+	dryrunRequests = dryrun.New(cidl, dfBuilder, shortcutStore, paramsProvider, vcs)
 
 	if *doClustering {
 		go func() {
@@ -376,7 +416,7 @@ func Init() {
 				// Start running continuous clustering looking for regressions.
 				time.Sleep(START_CLUSTER_DELAY)
 				c := regression.NewContinuous(vcs, cidl, configProvider, regStore, *numContinuous, *radius, notifier, paramsProvider, dfBuilder,
-					*local, config.Config.Project, config.Config.FileIngestionTopicName, *eventDrivenRegressionDetection)
+					shortcutStore, *local, config.Config.Project, config.Config.FileIngestionTopicName, *eventDrivenRegressionDetection)
 				continuous = append(continuous, c)
 				go c.Run(context.Background())
 			}
@@ -409,7 +449,7 @@ func activityHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		n = int(num)
 	}
-	a, err := activitylog.GetRecent(n)
+	a, err := activityStore.GetRecent(r.Context(), n)
 	if err != nil {
 		httputils.ReportError(w, err, "Failed to retrieve activity.", http.StatusInternalServerError)
 		return
@@ -639,6 +679,71 @@ func frameResultsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// errFrameNotCompleted is returned by frameExport if the FrameRequest is still
+// running, or failed.
+var errFrameNotCompleted = errors.New("FrameRequest has not completed.")
+
+// frameExport returns the dataframe.Export of a completed FrameRequest.
+//
+// Returns dataframe.ErrNotFound if there is no FrameRequest with the given id,
+// and errFrameNotCompleted if it hasn't completed.
+func frameExport(ctx context.Context, id string) (*dataframe.Export, error) {
+	resp, err := frameRequests.Response(id)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.DataFrame == nil {
+		return nil, errFrameNotCompleted
+	}
+	return dataframe.NewExport(ctx, resp.DataFrame, vcs)
+}
+
+// reportFrameExportError reports an error returned from frameExport, with a
+// 404 if the FrameRequest isn't found or hasn't completed.
+func reportFrameExportError(w http.ResponseWriter, err error) {
+	if err == dataframe.ErrNotFound || err == errFrameNotCompleted {
+		httputils.ReportError(w, err, "No completed frame found with that id.", http.StatusNotFound)
+		return
+	}
+	httputils.ReportError(w, err, "Failed to export frame.", http.StatusInternalServerError)
+}
+
+// frameCSVHandler returns the trace values, param keys, and commits of a
+// completed FrameRequest as a CSV file.
+//
+// See frameStatusHandler for more details, and dataframe.Export.WriteCSV for
+// the format.
+func frameCSVHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	export, err := frameExport(r.Context(), id)
+	if err != nil {
+		reportFrameExportError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "frame-"+id+".csv"))
+	if err := export.WriteCSV(w); err != nil {
+		sklog.Errorf("Failed to write CSV: %s", err)
+	}
+}
+
+// frameJSONHandler returns the trace values, param keys, and commits of a
+// completed FrameRequest as a JSON serialized dataframe.Export.
+//
+// See frameStatusHandler for more details.
+func frameJSONHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	export, err := frameExport(r.Context(), id)
+	if err != nil {
+		reportFrameExportError(w, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(export); err != nil {
+		sklog.Errorf("Failed to encode response: %s", err)
+	}
+}
+
 type countRequest struct {
 	Q     string `json:"q"`
 	Begin int    `json:"begin"`
@@ -725,7 +830,7 @@ func cidHandler(w http.ResponseWriter, r *http.Request) {
 //     "id": 123456,
 //   }
 func keysHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := shortcut2.Insert(r.Body)
+	id, err := shortcut2.Insert(r.Context(), shortcutStore, r.Body)
 	if err != nil {
 		httputils.ReportError(w, err, "Error inserting shortcut.", http.StatusInternalServerError)
 		return
@@ -823,6 +928,29 @@ type triageResponse struct {
 	Bug string `json:"bug"` // URL to bug reporting page.
 }
 
+// triageStatus returns the current triage status of the cluster of the given
+// type, "low" or "high", found for the alert with the given key at the given
+// commit. Returns regression.NONE if the status can't be found.
+func triageStatus(ctx context.Context, detail *cid.CommitDetail, key, clusterType string) regression.Status {
+	regMap, err := regStore.Range(ctx, detail.Timestamp, detail.Timestamp+1)
+	if err != nil {
+		sklog.Errorf("Failed to load the regressions at %q: %s", detail.ID(), err)
+		return regression.NONE
+	}
+	regs, ok := regMap[detail.ID()]
+	if !ok {
+		return regression.NONE
+	}
+	reg, ok := regs.ByAlertID[key]
+	if !ok {
+		return regression.NONE
+	}
+	if clusterType == "low" {
+		return reg.LowStatus.Status
+	}
+	return reg.HighStatus.Status
+}
+
 // triageHandler takes a POST'd TriageRequest serialized as JSON
 // and performs the triage.
 //
@@ -845,10 +973,11 @@ func triageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	key := tr.Alert.IdAsString()
+	prevStatus := triageStatus(r.Context(), detail[0], key, tr.ClusterType)
 	if tr.ClusterType == "low" {
-		err = regStore.TriageLow(detail[0], key, tr.Triage)
+		err = regStore.TriageLow(r.Context(), detail[0], key, tr.Triage)
 	} else {
-		err = regStore.TriageHigh(detail[0], key, tr.Triage)
+		err = regStore.TriageHigh(r.Context(), detail[0], key, tr.Triage)
 	}
 
 	if err != nil {
@@ -862,23 +991,40 @@ func triageHandler(w http.ResponseWriter, r *http.Request) {
 		Action: fmt.Sprintf("Perf Triage: %q %q %q %q", tr.Alert.Query, detail[0].URL, tr.Triage.Status, tr.Triage.Message),
 		URL:    link,
 	}
-	if err := activitylog.Write(a); err != nil {
+	if err := activityStore.Write(r.Context(), a); err != nil {
 		sklog.Errorf("Failed to log activity: %s", err)
 	}
 
 	resp := &triageResponse{}
 
-	if tr.Triage.Status == regression.NEGATIVE {
-		cfgs, err := configProvider()
-		if err != nil {
-			sklog.Errorf("Failed to load configs looking for BugURITemplate: %s", err)
+	cfgs, err := configProvider()
+	if err != nil {
+		sklog.Errorf("Failed to load configs looking for the triaged alert: %s", err)
+	}
+	var cfg *alerts.Alert
+	for _, c := range cfgs {
+		if c.ID == tr.Alert.ID {
+			cfg = c
+			break
+		}
+	}
+
+	// Only send a follow-up if the status changed, e.g. not when just the
+	// message was edited.
+	if cfg != nil && tr.Triage.Status != regression.UNTRIAGED && tr.Triage.Status != prevStatus {
+		triage := &notify.Triage{
+			Status:  string(tr.Triage.Status),
+			Message: tr.Triage.Message,
 		}
+		if err := notifier.SendTriaged(detail[0], cfg, triage); err != nil {
+			sklog.Errorf("Failed to send triage notification: %s", err)
+		}
+	}
+
+	if tr.Triage.Status == regression.NEGATIVE {
 		uritemplate := DEFAULT_BUG_URI_TEMPLATE
-		for _, c := range cfgs {
-			if c.ID == tr.Alert.ID {
-				uritemplate = c.BugURITemplate
-				break
-			}
+		if cfg != nil {
+			uritemplate = cfg.BugURITemplate
 		}
 		resp.Bug = bug.Expand(uritemplate, link, detail[0], tr.Triage.Message)
 	}
@@ -898,7 +1044,7 @@ func regressionCount(category string) (int, error) {
 	// Query for Regressions in the range.
 	end := time.Now()
 	begin := end.Add(REGRESSION_COUNT_DURATION)
-	regMap, err := regStore.Range(begin.Unix(), end.Unix())
+	regMap, err := regStore.Range(context.Background(), begin.Unix(), end.Unix())
 	if err != nil {
 		return 0, err
 	}
@@ -934,6 +1080,150 @@ func regressionCountHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// regressionExportHandler returns a JSON serialized list of
+// regression.ExportedRegression's, one for each regression cluster found at
+// the commits in the range [begin, end], along with its triage status. The
+// range is given by the 'begin' and 'end' query parameters, which are commit
+// offsets. If 'end' is omitted then it defaults to 'begin'.
+func regressionExportHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx := r.Context()
+	begin, err := strconv.Atoi(r.FormValue("begin"))
+	if err != nil {
+		httputils.ReportError(w, err, "Invalid or missing 'begin' commit offset.", http.StatusBadRequest)
+		return
+	}
+	end := begin
+	if r.FormValue("end") != "" {
+		end, err = strconv.Atoi(r.FormValue("end"))
+		if err != nil || end < begin {
+			httputils.ReportError(w, err, "Invalid 'end' commit offset.", http.StatusBadRequest)
+			return
+		}
+	}
+	details, err := cidl.Lookup(ctx, []*cid.CommitID{{Offset: begin}, {Offset: end}})
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to find commits.", http.StatusInternalServerError)
+		return
+	}
+	regMap, err := regStore.Range(ctx, details[0].Timestamp, details[1].Timestamp+1)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to retrieve regressions.", http.StatusInternalServerError)
+		return
+	}
+	configs, err := configProvider()
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to retrieve alert configs.", http.StatusInternalServerError)
+		return
+	}
+	lookup := func(c *cid.CommitID) (*cid.CommitDetail, error) {
+		details, err := cidl.Lookup(ctx, []*cid.CommitID{c})
+		if err != nil {
+			return nil, err
+		}
+		return details[0], nil
+	}
+	exported, err := regression.Export(regMap, lookup, configs)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to export regressions.", http.StatusInternalServerError)
+		return
+	}
+	// Timestamps aren't unique, so trim to the exact commit range.
+	ret := []*regression.ExportedRegression{}
+	for _, e := range exported {
+		if e.Offset >= begin && e.Offset <= end {
+			ret = append(ret, e)
+		}
+	}
+	if err := json.NewEncoder(w).Encode(ret); err != nil {
+		sklog.Errorf("Failed to write or encode output: %s", err)
+	}
+}
+
+// TryBotCompareResponse is the JSON response from tryBotCompareHandler.
+type TryBotCompareResponse struct {
+	CL         string               `json:"cl"`
+	Patchset   int                  `json:"patchset"`
+	NumCommits int                  `json:"num_commits"`
+	Results    []*trybot.TraceDelta `json:"results"`
+}
+
+// tryBotCompareHandler compares the trybot results for a CL against the same
+// traces over the most recent commits at tip-of-tree. The 'cl' query
+// parameter is required. The 'patchset' defaults to the latest patchset with
+// results, and 'n', the number of commits to use as the baseline, defaults to
+// trybot.DEFAULT_NUM_BASELINE_COMMITS.
+func tryBotCompareHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx := r.Context()
+	if tryBotStore == nil {
+		httputils.ReportError(w, fmt.Errorf("No trybot store configured."), "Trybot results are not supported by this instance.", http.StatusNotFound)
+		return
+	}
+	cl := r.FormValue("cl")
+	if cl == "" {
+		httputils.ReportError(w, fmt.Errorf("Missing cl."), "The 'cl' query parameter is required.", http.StatusBadRequest)
+		return
+	}
+	patchset := 0
+	if r.FormValue("patchset") != "" {
+		var err error
+		patchset, err = strconv.Atoi(r.FormValue("patchset"))
+		if err != nil {
+			httputils.ReportError(w, err, "Invalid 'patchset'.", http.StatusBadRequest)
+			return
+		}
+	} else {
+		patchsets, err := tryBotStore.Patchsets(ctx, cl)
+		if err != nil {
+			httputils.ReportError(w, err, "Failed to find patchsets.", http.StatusInternalServerError)
+			return
+		}
+		if len(patchsets) == 0 {
+			httputils.ReportError(w, fmt.Errorf("No results for CL %q.", cl), "No trybot results found for the CL.", http.StatusNotFound)
+			return
+		}
+		patchset = patchsets[len(patchsets)-1]
+	}
+	n := trybot.DEFAULT_NUM_BASELINE_COMMITS
+	if r.FormValue("n") != "" {
+		var err error
+		n, err = strconv.Atoi(r.FormValue("n"))
+		if err != nil || n <= 0 {
+			httputils.ReportError(w, err, "Invalid 'n'.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	try, err := tryBotStore.Get(ctx, cl, patchset)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to retrieve trybot results.", http.StatusInternalServerError)
+		return
+	}
+	keys := make([]string, 0, len(try))
+	for key := range try {
+		keys = append(keys, key)
+	}
+	baseline := types.TraceSet{}
+	if len(keys) > 0 {
+		df, err := dfBuilder.NewNFromKeys(ctx, time.Now(), keys, int32(n), nil)
+		if err != nil {
+			httputils.ReportError(w, err, "Failed to load baseline traces.", http.StatusInternalServerError)
+			return
+		}
+		baseline = df.TraceSet
+	}
+	resp := TryBotCompareResponse{
+		CL:         cl,
+		Patchset:   patchset,
+		NumCommits: n,
+		Results:    trybot.Compare(try, baseline, trybot.DEFAULT_ALPHA),
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		sklog.Errorf("Failed to write or encode output: %s", err)
+	}
+}
+
 // regressionRangeRequest is used in regressionRangeHandler and is used to query for a range of
 // of Regressions.
 //
@@ -984,7 +1274,7 @@ func regressionRangeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Query for Regressions in the range.
-	regMap, err := regStore.Range(rr.Begin, rr.End)
+	regMap, err := regStore.Range(ctx, rr.Begin, rr.End)
 	if err != nil {
 		httputils.ReportError(w, err, "Failed to retrieve clusters.", http.StatusInternalServerError)
 		return
@@ -1303,7 +1593,7 @@ func alertUpdateHandler(w http.ResponseWriter, r *http.Request) {
 		Action: fmt.Sprintf("Create/Update Alert: %#v, %d", *cfg, cfg.ID),
 		URL:    link,
 	}
-	if err := activitylog.Write(a); err != nil {
+	if err := activityStore.Write(r.Context(), a); err != nil {
 		sklog.Errorf("Failed to log activity: %s", err)
 	}
 }
@@ -1329,7 +1619,7 @@ func alertDeleteHandler(w http.ResponseWriter, r *http.Request) {
 		Action: fmt.Sprintf("Delete Alert: %d", id),
 		URL:    fmt.Sprintf("/a/?%d", id),
 	}
-	if err := activitylog.Write(a); err != nil {
+	if err := activityStore.Write(r.Context(), a); err != nil {
 		sklog.Errorf("Failed to log activity: %s", err)
 	}
 }
@@ -1374,9 +1664,15 @@ func alertNotifyTryHandler(w http.ResponseWriter, r *http.Request) {
 		httputils.ReportError(w, err, "Failed to decode JSON.", http.StatusInternalServerError)
 		return
 	}
+	for _, n := range req.Notifications {
+		if err := n.Validate(); err != nil {
+			httputils.ReportError(w, err, fmt.Sprintf("Invalid notification: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
 
 	if err := notifier.ExampleSend(req); err != nil {
-		httputils.ReportError(w, err, fmt.Sprintf("Failed to send email: %s", err), http.StatusInternalServerError)
+		httputils.ReportError(w, err, fmt.Sprintf("Failed to send notification: %s", err), http.StatusInternalServerError)
 	}
 }
 
@@ -1481,12 +1777,16 @@ func main() {
 	router.HandleFunc("/_/frame/start", frameStartHandler).Methods("POST")
 	router.HandleFunc("/_/frame/status/{id:[a-zA-Z0-9]+}", frameStatusHandler).Methods("GET")
 	router.HandleFunc("/_/frame/results/{id:[a-zA-Z0-9]+}", frameResultsHandler).Methods("GET")
+	router.HandleFunc("/_/frame/csv/{id:[a-zA-Z0-9]+}", frameCSVHandler).Methods("GET")
+	router.HandleFunc("/_/frame/json/{id:[a-zA-Z0-9]+}", frameJSONHandler).Methods("GET")
 
 	router.HandleFunc("/_/dryrun/start", dryrunRequests.StartHandler).Methods("POST")
 	router.HandleFunc("/_/dryrun/status/{id:[a-zA-Z0-9]+}", dryrunRequests.StatusHandler).Methods("GET")
 
 	router.HandleFunc("/_/reg/", regressionRangeHandler).Methods("POST")
 	router.HandleFunc("/_/reg/count", regressionCountHandler).Methods("GET")
+	router.HandleFunc("/_/reg/export", regressionExportHandler).Methods("GET")
+	router.HandleFunc("/_/trybot/compare", tryBotCompareHandler).Methods("GET")
 	router.HandleFunc("/_/reg/current", regressionCurrentHandler).Methods("GET")
 	router.HandleFunc("/_/triage/", triageHandler).Methods("POST")
 	router.HandleFunc("/_/alerts/", alertsHandler)
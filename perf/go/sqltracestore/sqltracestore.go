@@ -0,0 +1,591 @@
+/*
+Package sqltracestore implements types.TraceStore on top of an SQL database.
+
+The same SQL is used for both SQLite, which is handy for local development and
+testing, and for Postgres/CockroachDB in production. Traces are stored with
+their full structured key, i.e. ",arch=x86,config=8888,", so unlike btts there
+is no need to encode trace ids via an OrderedParamSet.
+
+The database has the following tables:
+
+	TraceValues - One row per trace per commit, along with the id of the source
+	              file that the value came from.
+
+	SourceFiles - Maps source file ids, which are the md5 hash of the source
+	              file name, to the full source file name.
+
+	ParamSets   - One row for each key=value pair seen in each tile, which is
+	              used to construct the OrderedParamSet for a tile.
+
+	Postings    - The inverted index, one row for each key=value pair for each
+	              trace in each tile. This is what QueryTracesByIndex uses to
+	              find matching traces.
+*/
+package sqltracestore
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opencensus.io/trace"
+	"go.skia.org/infra/go/metrics2"
+	"go.skia.org/infra/go/paramtools"
+	"go.skia.org/infra/go/query"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/sklog"
+	"go.skia.org/infra/go/timer"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/perf/go/types"
+)
+
+const (
+	// MAX_IN_CLAUSE_SIZE is the max number of values we put in a single
+	// SQL "IN (...)" clause. SQLite limits the number of host parameters in a
+	// single statement to 999.
+	MAX_IN_CLAUSE_SIZE = 500
+
+	// QUERY_ENGINE_CHANNEL_SIZE is the size of the channel returned from
+	// QueryTracesIDOnlyByIndex.
+	QUERY_ENGINE_CHANNEL_SIZE = 10000
+)
+
+// schema is the set of statements that create the tables and indices used by
+// SQLTraceStore. Every statement is idempotent so it can be run every time a
+// SQLTraceStore is created.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS TraceValues (
+		trace_name      TEXT NOT NULL,
+		commit_number   INTEGER NOT NULL,
+		val             REAL,
+		source_file_id  TEXT,
+		PRIMARY KEY (trace_name, commit_number)
+	)`,
+	`CREATE INDEX IF NOT EXISTS TraceValues_commit_number ON TraceValues (commit_number)`,
+	`CREATE TABLE IF NOT EXISTS SourceFiles (
+		source_file_id  TEXT PRIMARY KEY,
+		source_file     TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS ParamSets (
+		tile_number  INTEGER NOT NULL,
+		param_key    TEXT NOT NULL,
+		param_value  TEXT NOT NULL,
+		PRIMARY KEY (tile_number, param_key, param_value)
+	)`,
+	`CREATE TABLE IF NOT EXISTS Postings (
+		tile_number  INTEGER NOT NULL,
+		key_value    TEXT NOT NULL,
+		trace_name   TEXT NOT NULL,
+		PRIMARY KEY (tile_number, key_value, trace_name)
+	)`,
+}
+
+// SQLTraceStore implements types.TraceStore backed by an SQL database.
+type SQLTraceStore struct {
+	db *sql.DB
+
+	// tileSize is the number of commits we store per tile.
+	tileSize int32
+
+	writesCounter      metrics2.Counter
+	indexWritesCounter metrics2.Counter
+}
+
+// New returns a new SQLTraceStore that stores its data in 'db'.
+//
+// The tables needed by SQLTraceStore are created if they don't already exist.
+func New(db *sql.DB, tileSize int32) (*SQLTraceStore, error) {
+	if tileSize <= 0 {
+		return nil, skerr.Fmt("tileSize must be >0. %d", tileSize)
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, skerr.Wrapf(err, "Failed to create schema")
+		}
+	}
+	return &SQLTraceStore{
+		db:                 db,
+		tileSize:           tileSize,
+		writesCounter:      metrics2.GetCounter("sql_perf_writes", nil),
+		indexWritesCounter: metrics2.GetCounter("sql_perf_index_writes", nil),
+	}, nil
+}
+
+// keyValue returns the value stored in the key_value column of the Postings
+// table for the given key and value.
+func keyValue(key, value string) string {
+	return key + "=" + value
+}
+
+// sourceFileID returns the id used in the SourceFiles table for the given
+// source file name.
+func sourceFileID(source string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(source)))
+}
+
+// placeholders returns a comma separated list of n numbered placeholders
+// starting at 'start', e.g. placeholders(3, 2) returns "$3,$4".
+func placeholders(start, n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("$%d", start+i)
+	}
+	return strings.Join(parts, ",")
+}
+
+// commitRange returns the half open range [begin, end) of CommitNumbers that
+// are stored in the given tile.
+func (s *SQLTraceStore) commitRange(tileNumber types.TileNumber) (types.CommitNumber, types.CommitNumber) {
+	begin := types.CommitNumber(int32(tileNumber) * s.tileSize)
+	return begin, begin + types.CommitNumber(s.tileSize)
+}
+
+// CommitNumberOfTileStart implements the types.TraceStore interface.
+func (s *SQLTraceStore) CommitNumberOfTileStart(commitNumber types.CommitNumber) types.CommitNumber {
+	begin, _ := s.commitRange(s.TileNumber(commitNumber))
+	return begin
+}
+
+// CountIndices implements the types.TraceStore interface.
+func (s *SQLTraceStore) CountIndices(ctx context.Context, tileNumber types.TileNumber) (int64, error) {
+	var ret int64
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM Postings WHERE tile_number = $1`, tileNumber).Scan(&ret); err != nil {
+		return 0, skerr.Wrapf(err, "Failed to count indices.")
+	}
+	return ret, nil
+}
+
+// GetLatestTile implements the types.TraceStore interface.
+func (s *SQLTraceStore) GetLatestTile() (types.TileNumber, error) {
+	var tileNumber sql.NullInt64
+	if err := s.db.QueryRow(`SELECT MAX(tile_number) FROM ParamSets`).Scan(&tileNumber); err != nil {
+		return types.BadTileNumber, skerr.Wrapf(err, "Failed to find latest tile.")
+	}
+	if !tileNumber.Valid {
+		return types.BadTileNumber, skerr.Fmt("No tiles have been written.")
+	}
+	return types.TileNumber(tileNumber.Int64), nil
+}
+
+// paramSet returns the ParamSet for the given tile.
+func (s *SQLTraceStore) paramSet(ctx context.Context, tileNumber types.TileNumber) (paramtools.ParamSet, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT param_key, param_value FROM ParamSets WHERE tile_number = $1`, tileNumber)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed to read ParamSet.")
+	}
+	defer util.Close(rows)
+	ret := paramtools.ParamSet{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, skerr.Wrapf(err, "Failed to read ParamSet row.")
+		}
+		ret[key] = append(ret[key], value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	ret.Normalize()
+	return ret, nil
+}
+
+// GetOrderedParamSet implements the types.TraceStore interface.
+func (s *SQLTraceStore) GetOrderedParamSet(ctx context.Context, tileNumber types.TileNumber) (*paramtools.OrderedParamSet, error) {
+	ctx, span := trace.StartSpan(ctx, "SQLTraceStore.GetOrderedParamSet")
+	defer span.End()
+
+	ps, err := s.paramSet(ctx, tileNumber)
+	if err != nil {
+		return nil, err
+	}
+	ops := paramtools.NewOrderedParamSet()
+	ops.Update(ps)
+	return ops, nil
+}
+
+// GetSource implements the types.TraceStore interface.
+func (s *SQLTraceStore) GetSource(ctx context.Context, commitNumber types.CommitNumber, traceId string) (string, error) {
+	var ret string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT SourceFiles.source_file
+		FROM TraceValues
+		INNER JOIN SourceFiles ON TraceValues.source_file_id = SourceFiles.source_file_id
+		WHERE TraceValues.trace_name = $1 AND TraceValues.commit_number = $2`, traceId, commitNumber).Scan(&ret)
+	if err == sql.ErrNoRows {
+		return "", skerr.Fmt("No source found.")
+	}
+	if err != nil {
+		return "", skerr.Wrapf(err, "Failed to read source.")
+	}
+	return ret, nil
+}
+
+// OffsetFromIndex implements the types.TraceStore interface.
+func (s *SQLTraceStore) OffsetFromIndex(commitNumber types.CommitNumber) int32 {
+	return int32(commitNumber) % s.tileSize
+}
+
+// matchingTraceNames returns the sorted names of all the traces in the given
+// tile that match the query. The query must not be empty.
+func (s *SQLTraceStore) matchingTraceNames(ctx context.Context, tileNumber types.TileNumber, q *query.Query) ([]string, error) {
+	ops, err := s.GetOrderedParamSet(ctx, tileNumber)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed to get OPS.")
+	}
+	plan, err := q.QueryPlan(ops)
+	if err != nil || len(plan) == 0 {
+		// Not an error, we just won't match anything in this tile.
+		//
+		// The plan may be invalid because it is querying with keys or values
+		// that don't appear in a tile, which means they query won't work on
+		// this tile, but it may still work on other tiles, so we just don't
+		// return any results for this tile.
+		return nil, nil
+	}
+
+	// Find the traces that match each key, and then intersect the results,
+	// starting with the key that has the fewest values.
+	keys := make([]string, 0, len(plan))
+	for key := range plan {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return len(plan[keys[i]]) < len(plan[keys[j]])
+	})
+
+	var matches util.StringSet
+	for _, key := range keys {
+		keyValues := make([]string, 0, len(plan[key]))
+		for _, value := range plan[key] {
+			keyValues = append(keyValues, keyValue(key, value))
+		}
+		found := util.StringSet{}
+		for _, chunk := range chunk(keyValues, MAX_IN_CLAUSE_SIZE) {
+			args := []interface{}{tileNumber}
+			for _, kv := range chunk {
+				args = append(args, kv)
+			}
+			rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT trace_name FROM Postings WHERE tile_number = $1 AND key_value IN (%s)`, placeholders(2, len(chunk))), args...)
+			if err != nil {
+				return nil, skerr.Wrapf(err, "Failed to query Postings.")
+			}
+			for rows.Next() {
+				var traceName string
+				if err := rows.Scan(&traceName); err != nil {
+					util.Close(rows)
+					return nil, skerr.Wrapf(err, "Failed to read Postings row.")
+				}
+				found[traceName] = true
+			}
+			util.Close(rows)
+			if err := rows.Err(); err != nil {
+				return nil, skerr.Wrap(err)
+			}
+		}
+		if matches == nil {
+			matches = found
+		} else {
+			matches = matches.Intersect(found)
+		}
+		if len(matches) == 0 {
+			return nil, nil
+		}
+	}
+	ret := matches.Keys()
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// chunk splits 'values' into slices of no more than 'size' elements.
+func chunk(values []string, size int) [][]string {
+	ret := [][]string{}
+	for len(values) > size {
+		ret = append(ret, values[:size])
+		values = values[size:]
+	}
+	if len(values) > 0 {
+		ret = append(ret, values)
+	}
+	return ret
+}
+
+// readTraces returns the values for all the named traces in the given tile.
+// If 'traceNames' is nil then all traces in the tile are returned.
+func (s *SQLTraceStore) readTraces(ctx context.Context, tileNumber types.TileNumber, traceNames []string) (types.TraceSet, error) {
+	begin, end := s.commitRange(tileNumber)
+	ret := types.TraceSet{}
+
+	readRows := func(rows *sql.Rows) error {
+		defer util.Close(rows)
+		for rows.Next() {
+			var traceName string
+			var commitNumber types.CommitNumber
+			var val float64
+			if err := rows.Scan(&traceName, &commitNumber, &val); err != nil {
+				return skerr.Wrapf(err, "Failed to read TraceValues row.")
+			}
+			tr, ok := ret[traceName]
+			if !ok {
+				tr = types.NewTrace(int(s.tileSize))
+				ret[traceName] = tr
+			}
+			tr[s.OffsetFromIndex(commitNumber)] = float32(val)
+		}
+		return rows.Err()
+	}
+
+	if traceNames == nil {
+		rows, err := s.db.QueryContext(ctx, `SELECT trace_name, commit_number, val FROM TraceValues WHERE commit_number >= $1 AND commit_number < $2`, begin, end)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "Failed to query TraceValues.")
+		}
+		if err := readRows(rows); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		return ret, nil
+	}
+
+	for _, chunk := range chunk(traceNames, MAX_IN_CLAUSE_SIZE) {
+		args := []interface{}{begin, end}
+		for _, traceName := range chunk {
+			args = append(args, traceName)
+		}
+		rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT trace_name, commit_number, val FROM TraceValues WHERE commit_number >= $1 AND commit_number < $2 AND trace_name IN (%s)`, placeholders(3, len(chunk))), args...)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "Failed to query TraceValues.")
+		}
+		if err := readRows(rows); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+	}
+	return ret, nil
+}
+
+// QueryCount implements the types.TraceStore interface.
+func (s *SQLTraceStore) QueryCount(ctx context.Context, tileNumber types.TileNumber, q *query.Query) (int64, error) {
+	if q.Empty() {
+		return s.TraceCount(ctx, tileNumber)
+	}
+	traceNames, err := s.matchingTraceNames(ctx, tileNumber, q)
+	if err != nil {
+		return -1, err
+	}
+	return int64(len(traceNames)), nil
+}
+
+// QueryTracesByIndex implements the types.TraceStore interface.
+func (s *SQLTraceStore) QueryTracesByIndex(ctx context.Context, tileNumber types.TileNumber, q *query.Query) (types.TraceSet, error) {
+	ctx, span := trace.StartSpan(ctx, "SQLTraceStore.QueryTracesByIndex")
+	defer span.End()
+	defer timer.New("sqlts_query_traces_by_index").Stop()
+
+	// An empty query means we want all traces.
+	if q.Empty() {
+		return s.readTraces(ctx, tileNumber, nil)
+	}
+	traceNames, err := s.matchingTraceNames(ctx, tileNumber, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(traceNames) == 0 {
+		return nil, nil
+	}
+	return s.readTraces(ctx, tileNumber, traceNames)
+}
+
+// QueryTracesIDOnlyByIndex implements the types.TraceStore interface.
+func (s *SQLTraceStore) QueryTracesIDOnlyByIndex(ctx context.Context, tileNumber types.TileNumber, q *query.Query) (<-chan paramtools.Params, error) {
+	ctx, span := trace.StartSpan(ctx, "SQLTraceStore.QueryTracesIDOnlyByIndex")
+	defer span.End()
+
+	if q.Empty() {
+		return nil, skerr.Fmt("Can't run QueryTracesIDOnlyByIndex for the empty query.")
+	}
+	traceNames, err := s.matchingTraceNames(ctx, tileNumber, q)
+	if err != nil {
+		return nil, err
+	}
+	outParams := make(chan paramtools.Params, QUERY_ENGINE_CHANNEL_SIZE)
+	go func() {
+		defer close(outParams)
+		for _, traceName := range traceNames {
+			p, err := query.ParseKeyFast(traceName)
+			if err != nil {
+				sklog.Errorf("Failed to parse key %q: %s", traceName, err)
+				continue
+			}
+			outParams <- p
+		}
+	}()
+	return outParams, nil
+}
+
+// ReadTraces implements the types.TraceStore interface.
+func (s *SQLTraceStore) ReadTraces(tileNumber types.TileNumber, keys []string) (map[string][]float32, error) {
+	traceSet, err := s.readTraces(context.TODO(), tileNumber, keys)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string][]float32, len(traceSet))
+	for key, tr := range traceSet {
+		ret[key] = tr
+	}
+	return ret, nil
+}
+
+// TileNumber implements the types.TraceStore interface.
+func (s *SQLTraceStore) TileNumber(commitNumber types.CommitNumber) types.TileNumber {
+	return types.TileNumberFromCommitNumber(commitNumber, s.tileSize)
+}
+
+// TileSize implements the types.TraceStore interface.
+func (s *SQLTraceStore) TileSize() int32 {
+	return s.tileSize
+}
+
+// TraceCount implements the types.TraceStore interface.
+func (s *SQLTraceStore) TraceCount(ctx context.Context, tileNumber types.TileNumber) (int64, error) {
+	begin, end := s.commitRange(tileNumber)
+	var ret int64
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(DISTINCT trace_name) FROM TraceValues WHERE commit_number >= $1 AND commit_number < $2`, begin, end).Scan(&ret); err != nil {
+		return -1, skerr.Wrapf(err, "Failed to count traces.")
+	}
+	return ret, nil
+}
+
+// writePostings writes the Postings rows for the given traces using the
+// given transaction.
+func (s *SQLTraceStore) writePostings(ctx context.Context, tx *sql.Tx, tileNumber types.TileNumber, traceNames []string) error {
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO Postings (tile_number, key_value, trace_name) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to prepare Postings insert.")
+	}
+	defer util.Close(stmt)
+	count := int64(0)
+	for _, traceName := range traceNames {
+		p, err := query.ParseKeyFast(traceName)
+		if err != nil {
+			sklog.Warningf("Failed to parse key %q: %s", traceName, err)
+			continue
+		}
+		for key, value := range p {
+			if _, err := stmt.ExecContext(ctx, tileNumber, keyValue(key, value), traceName); err != nil {
+				return skerr.Wrapf(err, "Failed to write Postings.")
+			}
+			count++
+		}
+	}
+	s.indexWritesCounter.Inc(count)
+	return nil
+}
+
+// WriteIndices implements the types.TraceStore interface.
+func (s *SQLTraceStore) WriteIndices(ctx context.Context, tileNumber types.TileNumber) error {
+	begin, end := s.commitRange(tileNumber)
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT trace_name FROM TraceValues WHERE commit_number >= $1 AND commit_number < $2`, begin, end)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to read trace names.")
+	}
+	traceNames := []string{}
+	for rows.Next() {
+		var traceName string
+		if err := rows.Scan(&traceName); err != nil {
+			util.Close(rows)
+			return skerr.Wrapf(err, "Failed to read trace name.")
+		}
+		traceNames = append(traceNames, traceName)
+	}
+	util.Close(rows)
+	if err := rows.Err(); err != nil {
+		return skerr.Wrap(err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to start transaction.")
+	}
+	if err := s.writePostings(ctx, tx, tileNumber, traceNames); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return skerr.Wrapf(err, "Failed to commit indices.")
+	}
+	sklog.Infof("Total traces processed: %d", len(traceNames))
+	return nil
+}
+
+// WriteTraces implements the types.TraceStore interface.
+func (s *SQLTraceStore) WriteTraces(commitNumber types.CommitNumber, params []paramtools.Params, values []float32, paramset paramtools.ParamSet, source string, timestamp time.Time) error {
+	// TODO(jcgregorio) Pass in a context to WriteTraces.
+	ctx := context.TODO()
+	defer timer.New("sqlts_write_traces").Stop()
+	tileNumber := s.TileNumber(commitNumber)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to start transaction.")
+	}
+	if err := s.writeTracesInTx(ctx, tx, tileNumber, commitNumber, params, values, paramset, source); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return skerr.Wrapf(err, "Failed to commit traces.")
+	}
+	s.writesCounter.Inc(int64(len(values)))
+	return nil
+}
+
+// writeTracesInTx does the work of WriteTraces using the given transaction.
+func (s *SQLTraceStore) writeTracesInTx(ctx context.Context, tx *sql.Tx, tileNumber types.TileNumber, commitNumber types.CommitNumber, params []paramtools.Params, values []float32, paramset paramtools.ParamSet, source string) error {
+	// Update the ParamSet for the tile.
+	psStmt, err := tx.PrepareContext(ctx, `INSERT INTO ParamSets (tile_number, param_key, param_value) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to prepare ParamSets insert.")
+	}
+	defer util.Close(psStmt)
+	for key, values := range paramset {
+		for _, value := range values {
+			if _, err := psStmt.ExecContext(ctx, tileNumber, key, value); err != nil {
+				return skerr.Wrapf(err, "Failed to write ParamSets.")
+			}
+		}
+	}
+
+	// Write the source file.
+	sourceID := sourceFileID(source)
+	if _, err := tx.ExecContext(ctx, `INSERT INTO SourceFiles (source_file_id, source_file) VALUES ($1, $2) ON CONFLICT DO NOTHING`, sourceID, source); err != nil {
+		return skerr.Wrapf(err, "Failed to write source file.")
+	}
+
+	// Write the values.
+	valStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO TraceValues (trace_name, commit_number, val, source_file_id) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (trace_name, commit_number) DO UPDATE SET val = excluded.val, source_file_id = excluded.source_file_id`)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to prepare TraceValues insert.")
+	}
+	defer util.Close(valStmt)
+	traceNames := make([]string, 0, len(values))
+	for i, v := range values {
+		traceName, err := query.MakeKeyFast(params[i])
+		if err != nil {
+			sklog.Warningf("Failed to encode key %q: %s", params[i], err)
+			continue
+		}
+		if _, err := valStmt.ExecContext(ctx, traceName, commitNumber, v, sourceID); err != nil {
+			return skerr.Wrapf(err, "Failed to write TraceValues.")
+		}
+		traceNames = append(traceNames, traceName)
+	}
+
+	return s.writePostings(ctx, tx, tileNumber, traceNames)
+}
+
+// Confirm that SQLTraceStore fulfills the types.TraceStore interface.
+var _ types.TraceStore = (*SQLTraceStore)(nil)
@@ -0,0 +1,141 @@
+package sqltracestore
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/types/shared_tests"
+)
+
+// postgresTestURLEnvVar is the environment variable with the URL of the
+// Postgres or CockroachDB database used by the large tests, e.g.
+// "postgresql://root@localhost:26257/defaultdb?sslmode=disable".
+const postgresTestURLEnvVar = "POSTGRES_TEST_URL"
+
+// newDB is a func that returns a new, empty database and a func to call to
+// clean up once the test is done.
+type newDB func(t *testing.T) (*sql.DB, func())
+
+// newSQLiteForTest returns a fresh SQLite database.
+func newSQLiteForTest(t *testing.T) (*sql.DB, func()) {
+	tmpDir, err := ioutil.TempDir("", "sqltracestore")
+	require.NoError(t, err)
+	db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "traces.db"))
+	require.NoError(t, err)
+	return db, func() {
+		assert.NoError(t, db.Close())
+		assert.NoError(t, os.RemoveAll(tmpDir))
+	}
+}
+
+// newPostgresForTest returns a new, empty schema in the database given by
+// POSTGRES_TEST_URL. Like the tests which need an emulator, the test fails if
+// the variable is not set.
+func newPostgresForTest(t *testing.T) (*sql.DB, func()) {
+	dbURL := os.Getenv(postgresTestURLEnvVar)
+	if dbURL == "" {
+		t.Fatal(`This test requires a CockroachDB or Postgres database, which you can start with e.g.
+docker run --rm -p 26257:26257 cockroachdb/cockroach:v20.2.0 start-single-node --insecure
+and then point the tests to it with
+export POSTGRES_TEST_URL=postgresql://root@localhost:26257/defaultdb?sslmode=disable
+`)
+	}
+	u, err := url.Parse(dbURL)
+	require.NoError(t, err)
+
+	// Each test gets its own schema, so that concurrent tests don't interfere
+	// with each other.
+	schema := "test_" + strings.Replace(uuid.New().String(), "-", "", -1)
+	admin, err := sql.Open("postgres", dbURL)
+	require.NoError(t, err)
+	_, err = admin.Exec(`CREATE SCHEMA ` + schema)
+	require.NoError(t, err)
+
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	db, err := sql.Open("postgres", u.String())
+	require.NoError(t, err)
+	return db, func() {
+		assert.NoError(t, db.Close())
+		_, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		assert.NoError(t, err)
+		assert.NoError(t, admin.Close())
+	}
+}
+
+// newForTest returns a new SQLTraceStore backed by a database from 'newDB'
+// and a func to call to clean up once the test is done.
+func newForTest(t *testing.T, newDB newDB) (*SQLTraceStore, func()) {
+	db, cleanup := newDB(t)
+	store, err := New(db, shared_tests.TILE_SIZE)
+	require.NoError(t, err)
+	return store, cleanup
+}
+
+func TestSQLTraceStore_SQLite(t *testing.T) {
+	unittest.MediumTest(t)
+	runTests(t, newSQLiteForTest)
+}
+
+func TestSQLTraceStore_Postgres(t *testing.T) {
+	unittest.LargeTest(t) // should use a CockroachDB or Postgres database
+	runTests(t, newPostgresForTest)
+}
+
+// runTests runs all the tests that need a database, each one with a
+// SQLTraceStore in a new database from 'newDB'.
+func runTests(t *testing.T, newDB newDB) {
+	t.Run("SharedTests", func(t *testing.T) {
+		store, cleanup := newForTest(t, newDB)
+		defer cleanup()
+
+		shared_tests.TestTraceStore(t, store)
+	})
+
+	t.Run("New_SchemaIsIdempotent", func(t *testing.T) {
+		store, cleanup := newForTest(t, newDB)
+		defer cleanup()
+
+		_, err := New(store.db, shared_tests.TILE_SIZE)
+		assert.NoError(t, err)
+	})
+
+	t.Run("GetLatestTile_EmptyStoreIsError", func(t *testing.T) {
+		store, cleanup := newForTest(t, newDB)
+		defer cleanup()
+
+		_, err := store.GetLatestTile()
+		assert.Error(t, err)
+	})
+}
+
+func TestNew_BadTileSize(t *testing.T) {
+	unittest.SmallTest(t)
+	_, err := New(nil, 0)
+	assert.Error(t, err)
+}
+
+func TestPlaceholders(t *testing.T) {
+	unittest.SmallTest(t)
+	assert.Equal(t, "$3,$4", placeholders(3, 2))
+	assert.Equal(t, "$1", placeholders(1, 1))
+}
+
+func TestChunk(t *testing.T) {
+	unittest.SmallTest(t)
+	assert.Equal(t, [][]string{}, chunk([]string{}, 2))
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, chunk([]string{"a", "b", "c"}, 2))
+	assert.Equal(t, [][]string{{"a", "b"}}, chunk([]string{"a", "b"}, 2))
+}
@@ -0,0 +1,173 @@
+package stepfit
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// MIN_SEGMENT_LENGTH is the smallest number of points allowed between
+	// change points found by PELT.
+	MIN_SEGMENT_LENGTH = 2
+)
+
+// mannWhitneyU returns the Mann-Whitney U statistic for the samples 'x' and
+// 'y', along with the two-sided p-value of the test that they come from the
+// same distribution.
+//
+// The p-value uses the normal approximation to the distribution of U, with a
+// correction for ties, which is reasonable for the window sizes used by Perf.
+// If either sample is empty, or all the values are identical, then a p-value
+// of 1 is returned.
+func mannWhitneyU(x, y []float32) (float64, float64) {
+	n1 := len(x)
+	n2 := len(y)
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+
+	// Rank all the values together, giving tied values the average of the
+	// ranks they span.
+	type sample struct {
+		value float32
+		fromX bool
+	}
+	all := make([]sample, 0, n1+n2)
+	for _, v := range x {
+		all = append(all, sample{value: v, fromX: true})
+	}
+	for _, v := range y {
+		all = append(all, sample{value: v, fromX: false})
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].value < all[j].value
+	})
+
+	n := float64(n1 + n2)
+	rankSumX := 0.0
+	tieCorrection := 0.0
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		// Ranks are 1-based, so the values in [i, j) have ranks i+1...j.
+		rank := float64(i+1+j) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromX {
+				rankSumX += rank
+			}
+		}
+		t := float64(j - i)
+		tieCorrection += t*t*t - t
+		i = j
+	}
+
+	u1 := rankSumX - float64(n1*(n1+1))/2
+	u2 := float64(n1*n2) - u1
+	u := math.Min(u1, u2)
+
+	mean := float64(n1*n2) / 2
+	variance := float64(n1*n2) / 12 * ((n + 1) - tieCorrection/(n*(n-1)))
+	if variance <= 0 {
+		return u, 1
+	}
+	// Use a continuity correction of 0.5.
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		z = 0
+	}
+	p := math.Erfc(z / math.Sqrt2)
+	return u, math.Min(p, 1)
+}
+
+// robustStdDev estimates the standard deviation of the noise in 'trace' from
+// the median absolute difference between consecutive points, which unlike the
+// sample standard deviation isn't inflated by any steps in the trace.
+func robustStdDev(trace []float32) float64 {
+	if len(trace) < 2 {
+		return 0
+	}
+	diffs := make([]float64, 0, len(trace)-1)
+	for i := 1; i < len(trace); i++ {
+		diffs = append(diffs, math.Abs(float64(trace[i]-trace[i-1])))
+	}
+	sort.Float64s(diffs)
+	median := diffs[len(diffs)/2]
+	if len(diffs)%2 == 0 {
+		median = (diffs[len(diffs)/2-1] + diffs[len(diffs)/2]) / 2
+	}
+	// For normally distributed noise the median absolute deviation is
+	// 0.6745*sigma, and the difference of two points has a standard deviation
+	// of sqrt(2)*sigma.
+	return median / (0.6745 * math.Sqrt2)
+}
+
+// pelt finds the change points in 'trace' using the Pruned Exact Linear Time
+// method of Killick, Fearnhead and Eckley, https://arxiv.org/abs/1101.1438,
+// with a cost function that looks for changes in the mean of normally
+// distributed data with standard deviation 'sigma'.
+//
+// The returned change points are the indices of the first point of each new
+// segment, in increasing order.
+func pelt(trace []float32, sigma float64) []int {
+	n := len(trace)
+	if n < 2*MIN_SEGMENT_LENGTH || sigma <= 0 {
+		return []int{}
+	}
+	variance := sigma * sigma
+
+	// Prefix sums make the cost of any segment O(1) to calculate.
+	sum := make([]float64, n+1)
+	sumSq := make([]float64, n+1)
+	for i, v := range trace {
+		sum[i+1] = sum[i] + float64(v)
+		sumSq[i+1] = sumSq[i] + float64(v)*float64(v)
+	}
+	// cost is the normalized sum of squared errors of the segment [s, t)
+	// around its mean.
+	cost := func(s, t int) float64 {
+		length := float64(t - s)
+		segmentSum := sum[t] - sum[s]
+		return (sumSq[t] - sumSq[s] - segmentSum*segmentSum/length) / variance
+	}
+
+	// The penalty for adding a change point, the BIC.
+	beta := 2 * math.Log(float64(n))
+
+	f := make([]float64, n+1)
+	last := make([]int, n+1)
+	f[0] = -beta
+	candidates := []int{0}
+	for t := 1; t <= n; t++ {
+		f[t] = math.Inf(1)
+		last[t] = -1
+		for _, s := range candidates {
+			if t-s < MIN_SEGMENT_LENGTH {
+				continue
+			}
+			if c := f[s] + cost(s, t) + beta; c < f[t] {
+				f[t] = c
+				last[t] = s
+			}
+		}
+
+		// Prune the candidates that can never be optimal again.
+		pruned := candidates[:0]
+		for _, s := range candidates {
+			if t-s < MIN_SEGMENT_LENGTH || f[s]+cost(s, t) <= f[t] {
+				pruned = append(pruned, s)
+			}
+		}
+		candidates = pruned
+		if !math.IsInf(f[t], 1) {
+			candidates = append(candidates, t)
+		}
+	}
+
+	ret := []int{}
+	for t := last[n]; t > 0; t = last[t] {
+		ret = append([]int{t}, ret...)
+	}
+	return ret
+}
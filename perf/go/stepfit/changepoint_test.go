@@ -0,0 +1,113 @@
+package stepfit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/types"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	unittest.SmallTest(t)
+
+	// Completely separated samples.
+	u, p := mannWhitneyU([]float32{1, 2, 3, 4, 5, 6}, []float32{7, 8, 9, 10, 11, 12})
+	assert.Equal(t, 0.0, u)
+	assert.InDelta(t, 0.0051, p, 0.0001)
+
+	// Identical samples.
+	u, p = mannWhitneyU([]float32{1, 1, 1}, []float32{1, 1, 1})
+	assert.Equal(t, 4.5, u)
+	assert.Equal(t, 1.0, p)
+
+	// Interleaved samples aren't significant.
+	_, p = mannWhitneyU([]float32{1, 3, 5, 7}, []float32{2, 4, 6, 8})
+	assert.True(t, p > 0.5)
+
+	// Empty samples.
+	u, p = mannWhitneyU([]float32{}, []float32{1, 2})
+	assert.Equal(t, 0.0, u)
+	assert.Equal(t, 1.0, p)
+}
+
+func TestRobustStdDev(t *testing.T) {
+	unittest.SmallTest(t)
+	assert.Equal(t, 0.0, robustStdDev([]float32{}))
+	assert.Equal(t, 0.0, robustStdDev([]float32{1, 1, 1, 1}))
+	// A single large step doesn't change the estimate.
+	assert.Equal(t, robustStdDev([]float32{1, 2, 1, 2, 1, 2}), robustStdDev([]float32{1, 2, 1, 12, 11, 12}))
+}
+
+func TestPELT(t *testing.T) {
+	unittest.SmallTest(t)
+	assert.Equal(t, []int{}, pelt([]float32{}, 1))
+	assert.Equal(t, []int{}, pelt([]float32{1, 1, 1, 1, 1, 1}, 0.1))
+	assert.Equal(t, []int{4}, pelt([]float32{1, 1.1, 0.9, 1, 5, 5.1, 4.9, 5}, 0.1))
+	assert.Equal(t, []int{3, 6}, pelt([]float32{1, 1, 1, 5, 5, 5, 1, 1, 1}, 0.1))
+	// Noise alone doesn't produce change points.
+	assert.Equal(t, []int{}, pelt([]float32{1, 1.1, 0.9, 1, 1.1, 0.9, 1, 1.1}, 0.1))
+}
+
+func TestGetStepFitAtMid_MannWhitneyU(t *testing.T) {
+	unittest.SmallTest(t)
+
+	// Step up.
+	sf := GetStepFitAtMid([]float32{1, 1.1, 0.9, 1, 1.05, 0.95, 2, 2.1, 1.9, 2, 2.05, 1.95}, 0.1, 0.05, types.MANNWHITNEYU_STEP)
+	assert.Equal(t, HIGH, sf.Status)
+	assert.Equal(t, 6, sf.TurningPoint)
+	assert.Equal(t, float32(0), sf.Statistic)
+	assert.InDelta(t, 0.0049, sf.PValue, 0.0001)
+	assert.InDelta(t, -2.31, sf.Regression, 0.01)
+
+	// Step down.
+	sf = GetStepFitAtMid([]float32{2, 2.1, 1.9, 2, 2.05, 1.95, 1, 1.1, 0.9, 1, 1.05, 0.95}, 0.1, 0.05, types.MANNWHITNEYU_STEP)
+	assert.Equal(t, LOW, sf.Status)
+	assert.InDelta(t, 2.31, sf.Regression, 0.01)
+
+	// Noise.
+	sf = GetStepFitAtMid([]float32{1, 2, 1, 2, 1, 2, 1, 2, 1, 2}, 0.1, 0.05, types.MANNWHITNEYU_STEP)
+	assert.Equal(t, UNINTERESTING, sf.Status)
+	assert.True(t, sf.PValue > 0.05)
+
+	// A single large outlier doesn't cause a regression.
+	sf = GetStepFitAtMid([]float32{1, 1.1, 0.9, 1, 1.1, 0.9, 100, 1.1, 0.9, 1, 1.1, 0.9}, 0.1, 0.05, types.MANNWHITNEYU_STEP)
+	assert.Equal(t, UNINTERESTING, sf.Status)
+
+	// Empty.
+	sf = GetStepFitAtMid([]float32{}, 0.1, 0.05, types.MANNWHITNEYU_STEP)
+	assert.Equal(t, UNINTERESTING, sf.Status)
+	assert.Equal(t, float32(1), sf.PValue)
+}
+
+func TestGetStepFitAtMid_PELT(t *testing.T) {
+	unittest.SmallTest(t)
+
+	// Step up at the mid-point.
+	sf := GetStepFitAtMid([]float32{1, 1.1, 0.9, 1, 2, 2.1, 1.9, 2}, 0.01, 2, types.PELT_STEP)
+	assert.Equal(t, HIGH, sf.Status)
+	assert.Equal(t, 4, sf.TurningPoint)
+	assert.True(t, sf.Regression < -2)
+	assert.True(t, sf.PValue < 0.05)
+
+	// Step down at the mid-point.
+	sf = GetStepFitAtMid([]float32{2, 2.1, 1.9, 2, 1, 1.1, 0.9, 1}, 0.01, 2, types.PELT_STEP)
+	assert.Equal(t, LOW, sf.Status)
+	assert.True(t, sf.Regression > 2)
+
+	// A step that isn't at the mid-point isn't reported.
+	sf = GetStepFitAtMid([]float32{1, 1.1, 0.9, 1, 1.1, 0.9, 2, 2.1, 1.9, 2}, 0.01, 2, types.PELT_STEP)
+	assert.Equal(t, UNINTERESTING, sf.Status)
+	assert.Equal(t, float32(0), sf.Regression)
+	assert.Equal(t, float32(1), sf.PValue)
+
+	// Only the segments adjacent to the mid-point are compared, so an earlier
+	// step doesn't affect the size of the step found at the mid-point.
+	sf = GetStepFitAtMid([]float32{5, 5, 5, 1, 1, 1, 2, 2, 2, 2}, 0.1, 2, types.PELT_STEP)
+	assert.Equal(t, 5, sf.TurningPoint)
+	assert.Equal(t, UNINTERESTING, sf.Status)
+
+	// Noise only.
+	sf = GetStepFitAtMid([]float32{1, 1.1, 0.9, 1, 1.1, 0.9, 1, 1.1}, 0.01, 2, types.PELT_STEP)
+	assert.Equal(t, UNINTERESTING, sf.Status)
+}
@@ -42,8 +42,22 @@ type StepFit struct {
 	//
 	// Values can be "High", "Low", and "Uninteresting"
 	Status string `json:"status"`
+
+	// Statistic is the Mann-Whitney U statistic of the values before and after
+	// the step, for MANNWHITNEYU_STEP, and for PELT_STEP if a change point was
+	// found at the middle of the trace. Zero otherwise.
+	Statistic float32 `json:"statistic"`
+
+	// PValue is the p-value of the Mann-Whitney U test of the values before
+	// and after the step. For PELT_STEP it is 1 if no change point was found
+	// at the middle of the trace. Zero for the other algorithms.
+	PValue float32 `json:"p_value"`
 }
 
+// MIN_P_VALUE is the smallest p-value we report, which keeps Regression
+// finite for MANNWHITNEYU_STEP.
+const MIN_P_VALUE = 1e-30
+
 // GetStepFitAtMid takes one []float32 trace and calculates and returns a
 // *StepFit.
 //
@@ -55,10 +69,18 @@ type StepFit struct {
 //
 // stepDetection is the algorithm to use to test for a regression.
 //
+// For MANNWHITNEYU_STEP 'interesting' is the p-value below which a step is
+// flagged, and Regression is the negative log10 of the p-value, signed by the
+// direction of the step. For PELT_STEP a step is only flagged if a change
+// point is found exactly at the middle of the trace, and the Regression is
+// the size of the step in units of the estimated noise in the trace.
+//
 // See StepFit for a description of the values being calculated.
 func GetStepFitAtMid(trace []float32, stddevThreshold float32, interesting float32, stepDetection types.StepDetection) *StepFit {
 	var lse float32
 	var regression float32
+	var statistic float32
+	var pValue float32
 	stepSize := float32(-1.0)
 	i := len(trace) / 2
 
@@ -103,6 +125,46 @@ func GetStepFitAtMid(trace []float32, stddevThreshold float32, interesting float
 			stepSize = 0
 			regression = stepSize
 		}
+	} else if stepDetection == types.MANNWHITNEYU_STEP {
+		// https://en.wikipedia.org/wiki/Mann%E2%80%93Whitney_U_test
+		u, p := mannWhitneyU(trace[:i], trace[i:])
+		p = math.Max(p, MIN_P_VALUE)
+		stepSize = y0 - y1
+		regression = float32(-math.Log10(p))
+		if stepSize < 0 {
+			regression = -regression
+		}
+		statistic = float32(u)
+		pValue = float32(p)
+	} else if stepDetection == types.PELT_STEP {
+		// Find all the change points in the trace, and only report a step if
+		// one of them is at the middle of the trace. Only the segments
+		// directly on either side of the middle are compared, so other
+		// change points in the window don't mask or inflate the step.
+		sigma := math.Max(robustStdDev(trace), float64(stddevThreshold))
+		begin, end := 0, len(trace)
+		found := false
+		for _, cp := range pelt(trace, sigma) {
+			if cp < i {
+				begin = cp
+			} else if cp == i {
+				found = true
+			} else {
+				end = cp
+				break
+			}
+		}
+		stepSize = 0
+		pValue = 1
+		if found {
+			before := trace[begin:i]
+			after := trace[i:end]
+			stepSize = (vec32.Mean(before) - vec32.Mean(after)) / float32(sigma)
+			u, p := mannWhitneyU(before, after)
+			statistic = float32(u)
+			pValue = float32(p)
+		}
+		regression = stepSize
 	} else /* Cohen's d */ {
 		// https://en.wikipedia.org/wiki/Effect_size#Cohen's_d
 		if len(trace) < 4 {
@@ -122,7 +184,15 @@ func GetStepFitAtMid(trace []float32, stddevThreshold float32, interesting float
 		}
 	}
 	status := UNINTERESTING
-	if regression >= interesting {
+	if stepDetection == types.MANNWHITNEYU_STEP {
+		if pValue < interesting {
+			if stepSize > 0 {
+				status = LOW
+			} else if stepSize < 0 {
+				status = HIGH
+			}
+		}
+	} else if regression >= interesting {
 		status = LOW
 	} else if regression <= -interesting {
 		status = HIGH
@@ -133,5 +203,7 @@ func GetStepFitAtMid(trace []float32, stddevThreshold float32, interesting float
 		TurningPoint: i,
 		Regression:   regression,
 		Status:       status,
+		Statistic:    statistic,
+		PValue:       pValue,
 	}
 }
@@ -0,0 +1,86 @@
+// Package memtrybotstore implements trybot.Store in memory, which is useful
+// for testing and for running Perf locally.
+package memtrybotstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.skia.org/infra/perf/go/trybot"
+)
+
+// patchsetKey identifies a single patchset of a CL.
+type patchsetKey struct {
+	cl       string
+	patchset int
+}
+
+// MemTryBotStore implements trybot.Store.
+type MemTryBotStore struct {
+	// mutex protects results.
+	mutex sync.Mutex
+
+	// results maps a patchset to a map of source file names to the values of
+	// each trace found in that file.
+	results map[patchsetKey]map[string]map[string]float32
+}
+
+// New returns a new MemTryBotStore.
+func New() *MemTryBotStore {
+	return &MemTryBotStore{
+		results: map[patchsetKey]map[string]map[string]float32{},
+	}
+}
+
+// Write implements trybot.Store.
+func (s *MemTryBotStore) Write(ctx context.Context, cl string, patchset int, traceIDs []string, values []float32, source string, ts time.Time) error {
+	if len(traceIDs) != len(values) {
+		return fmt.Errorf("Got %d trace ids but %d values.", len(traceIDs), len(values))
+	}
+	fromSource := make(map[string]float32, len(traceIDs))
+	for i, traceID := range traceIDs {
+		fromSource[traceID] = values[i]
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := patchsetKey{cl: cl, patchset: patchset}
+	if _, ok := s.results[key]; !ok {
+		s.results[key] = map[string]map[string]float32{}
+	}
+	s.results[key][source] = fromSource
+	return nil
+}
+
+// Get implements trybot.Store.
+func (s *MemTryBotStore) Get(ctx context.Context, cl string, patchset int) (map[string][]float32, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ret := map[string][]float32{}
+	for _, fromSource := range s.results[patchsetKey{cl: cl, patchset: patchset}] {
+		for traceID, value := range fromSource {
+			ret[traceID] = append(ret[traceID], value)
+		}
+	}
+	return ret, nil
+}
+
+// Patchsets implements trybot.Store.
+func (s *MemTryBotStore) Patchsets(ctx context.Context, cl string) ([]int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ret := []int{}
+	for key := range s.results {
+		if key.cl == cl {
+			ret = append(ret, key.patchset)
+		}
+	}
+	sort.Ints(ret)
+	return ret, nil
+}
+
+// Confirm we implement the interface.
+var _ trybot.Store = (*MemTryBotStore)(nil)
@@ -0,0 +1,14 @@
+package memtrybotstore
+
+import (
+	"testing"
+
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/perf/go/trybot/shared_tests"
+)
+
+func TestMemTryBotStore(t *testing.T) {
+	unittest.SmallTest(t)
+
+	shared_tests.TestTryBotStore(t, New())
+}
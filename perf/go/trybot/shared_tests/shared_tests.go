@@ -0,0 +1,64 @@
+// Package shared_tests contains tests that every implementation of
+// trybot.Store should pass.
+package shared_tests
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/perf/go/trybot"
+)
+
+// TestTryBotStore exercises all the methods of trybot.Store. The store must
+// be empty.
+func TestTryBotStore(t sktest.TestingT, store trybot.Store) {
+	ctx := context.Background()
+	ts := time.Unix(1580000000, 0)
+
+	// Nothing stored yet.
+	patchsets, err := store.Patchsets(ctx, "123")
+	require.NoError(t, err)
+	assert.Empty(t, patchsets)
+	results, err := store.Get(ctx, "123", 1)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// Write results from two files for the same patchset.
+	err = store.Write(ctx, "123", 2, []string{",a=1,", ",a=2,"}, []float32{1.5, 2.5}, "gs://bucket/file1.json", ts)
+	require.NoError(t, err)
+	err = store.Write(ctx, "123", 2, []string{",a=1,"}, []float32{1.7}, "gs://bucket/file2.json", ts)
+	require.NoError(t, err)
+
+	// And for other patchsets and CLs.
+	err = store.Write(ctx, "123", 1, []string{",a=1,"}, []float32{3}, "gs://bucket/file3.json", ts)
+	require.NoError(t, err)
+	err = store.Write(ctx, "456", 10, []string{",a=1,"}, []float32{4}, "gs://bucket/file4.json", ts)
+	require.NoError(t, err)
+
+	results, err = store.Get(ctx, "123", 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.ElementsMatch(t, []float32{1.5, 1.7}, results[",a=1,"])
+	assert.Equal(t, []float32{2.5}, results[",a=2,"])
+
+	// Writing the same file again replaces its values.
+	err = store.Write(ctx, "123", 2, []string{",a=1,"}, []float32{1.6}, "gs://bucket/file2.json", ts)
+	require.NoError(t, err)
+	results, err = store.Get(ctx, "123", 2)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []float32{1.5, 1.6}, results[",a=1,"])
+
+	patchsets, err = store.Patchsets(ctx, "123")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, patchsets)
+	patchsets, err = store.Patchsets(ctx, "456")
+	require.NoError(t, err)
+	assert.Equal(t, []int{10}, patchsets)
+
+	// Mismatched slices are an error.
+	err = store.Write(ctx, "123", 2, []string{",a=1,"}, []float32{}, "gs://bucket/file5.json", ts)
+	assert.Error(t, err)
+}
@@ -0,0 +1,114 @@
+// Package sqltrybotstore implements trybot.Store on top of an SQL database.
+// The same SQL is used for both SQLite and Postgres.
+package sqltrybotstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/perf/go/trybot"
+)
+
+// schema creates the TryResults table, which has one row per trace per
+// source file, where the source is the file the value was ingested from.
+const schema = `CREATE TABLE IF NOT EXISTS TryResults (
+	cl         TEXT NOT NULL,
+	patchset   INTEGER NOT NULL,
+	trace_id   TEXT NOT NULL,
+	source     TEXT NOT NULL,
+	value      REAL NOT NULL,
+	ts         BIGINT NOT NULL,
+	PRIMARY KEY (cl, patchset, trace_id, source)
+)`
+
+// SQLTryBotStore implements trybot.Store.
+type SQLTryBotStore struct {
+	db *sql.DB
+}
+
+// New returns a new SQLTryBotStore, creating the TryResults table if it
+// doesn't already exist.
+func New(db *sql.DB) (*SQLTryBotStore, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, skerr.Wrapf(err, "Failed to create TryResults table.")
+	}
+	return &SQLTryBotStore{
+		db: db,
+	}, nil
+}
+
+// Write implements trybot.Store.
+func (s *SQLTryBotStore) Write(ctx context.Context, cl string, patchset int, traceIDs []string, values []float32, source string, ts time.Time) error {
+	if len(traceIDs) != len(values) {
+		return skerr.Fmt("Got %d trace ids but %d values.", len(traceIDs), len(values))
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to start transaction.")
+	}
+	// Remove any values from a previous ingestion of the same source.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM TryResults WHERE cl=$1 AND patchset=$2 AND source=$3`, cl, patchset, source); err != nil {
+		_ = tx.Rollback()
+		return skerr.Wrapf(err, "Failed to remove old results for %q.", source)
+	}
+	for i, traceID := range traceIDs {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO TryResults (cl, patchset, trace_id, source, value, ts) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (cl, patchset, trace_id, source) DO UPDATE SET value=excluded.value, ts=excluded.ts`, cl, patchset, traceID, source, values[i], ts.Unix()); err != nil {
+			_ = tx.Rollback()
+			return skerr.Wrapf(err, "Failed to write result for %q.", traceID)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return skerr.Wrapf(err, "Failed to commit results.")
+	}
+	return nil
+}
+
+// Get implements trybot.Store.
+func (s *SQLTryBotStore) Get(ctx context.Context, cl string, patchset int) (map[string][]float32, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT trace_id, value FROM TryResults WHERE cl=$1 AND patchset=$2`, cl, patchset)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed to query results.")
+	}
+	defer util.Close(rows)
+	ret := map[string][]float32{}
+	for rows.Next() {
+		var traceID string
+		var value float32
+		if err := rows.Scan(&traceID, &value); err != nil {
+			return nil, skerr.Wrapf(err, "Failed to read result.")
+		}
+		ret[traceID] = append(ret[traceID], value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, skerr.Wrapf(err, "Failed to read results.")
+	}
+	return ret, nil
+}
+
+// Patchsets implements trybot.Store.
+func (s *SQLTryBotStore) Patchsets(ctx context.Context, cl string) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT patchset FROM TryResults WHERE cl=$1 ORDER BY patchset`, cl)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed to query patchsets.")
+	}
+	defer util.Close(rows)
+	ret := []int{}
+	for rows.Next() {
+		var patchset int
+		if err := rows.Scan(&patchset); err != nil {
+			return nil, skerr.Wrapf(err, "Failed to read patchset.")
+		}
+		ret = append(ret, patchset)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, skerr.Wrapf(err, "Failed to read patchsets.")
+	}
+	return ret, nil
+}
+
+// Confirm we implement the interface.
+var _ trybot.Store = (*SQLTryBotStore)(nil)
@@ -0,0 +1,159 @@
+// Package trybot stores the results of trybot, i.e. pre-submit, runs and
+// compares them against the results of the same traces at tip-of-tree.
+//
+// Trybot results are stored separately from the master branch traces, keyed
+// by the CL and patchset they were generated for.
+package trybot
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"go.skia.org/infra/go/query"
+	"go.skia.org/infra/go/vec32"
+	"go.skia.org/infra/perf/go/types"
+)
+
+const (
+	// DEFAULT_NUM_BASELINE_COMMITS is the default number of master commits
+	// that trybot results are compared against.
+	DEFAULT_NUM_BASELINE_COMMITS = 20
+
+	// DEFAULT_ALPHA is the default significance level used in Compare.
+	DEFAULT_ALPHA = 0.05
+
+	// MIN_BASELINE_POINTS is the smallest number of baseline values needed
+	// before a difference can be significant.
+	MIN_BASELINE_POINTS = 3
+)
+
+// Store persists trybot results.
+type Store interface {
+	// Write the values for the given traces, found in the file 'source', for
+	// the given CL and patchset. The traceIDs and values slices are parallel.
+	// Writing the same source again replaces the values from that source.
+	Write(ctx context.Context, cl string, patchset int, traceIDs []string, values []float32, source string, ts time.Time) error
+
+	// Get returns all the values for each trace for the given CL and
+	// patchset, as a map from trace id to values. There can be more than one
+	// value per trace if more than one trybot run produced it.
+	Get(ctx context.Context, cl string, patchset int) (map[string][]float32, error)
+
+	// Patchsets returns the patchsets of the given CL that have results,
+	// sorted in ascending order.
+	Patchsets(ctx context.Context, cl string) ([]int, error)
+}
+
+// TraceDelta is the comparison of the trybot results for a single trace
+// against the baseline values of that trace at tip-of-tree.
+type TraceDelta struct {
+	TraceID        string            `json:"trace_id"`
+	Params         map[string]string `json:"params"`
+	TryMean        float32           `json:"try_mean"`
+	NumTry         int               `json:"num_try"`
+	BaselineMean   float32           `json:"baseline_mean"`
+	BaselineStdDev float32           `json:"baseline_stddev"`
+	NumBaseline    int               `json:"num_baseline"`
+	Delta          float32           `json:"delta"`         // TryMean - BaselineMean.
+	DeltaPercent   float32           `json:"delta_percent"` // Delta as a percent of BaselineMean, 0 if BaselineMean is 0.
+	PValue         float64           `json:"p_value"`
+	Significant    bool              `json:"significant"` // True if PValue < alpha.
+}
+
+// meanAndVariance returns the mean and sample variance of the values.
+func meanAndVariance(values []float32) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += float64(v)
+	}
+	mean := sum / float64(len(values))
+	if len(values) == 1 {
+		return mean, 0
+	}
+	sumSq := 0.0
+	for _, v := range values {
+		sumSq += (float64(v) - mean) * (float64(v) - mean)
+	}
+	return mean, sumSq / float64(len(values)-1)
+}
+
+// pValue returns the two-sided p-value that the trybot values have the same
+// mean as the baseline values.
+//
+// Trybot runs usually only produce one or two values per trace, which is too
+// few to estimate their spread, so both means are presumed to have the
+// spread of the baseline and the difference is tested with a z-test.
+func pValue(delta, baselineVariance float64, numTry, numBaseline int) float64 {
+	if numTry == 0 || numBaseline < MIN_BASELINE_POINTS {
+		return 1
+	}
+	if baselineVariance == 0 {
+		if delta == 0 {
+			return 1
+		}
+		return 0
+	}
+	z := math.Abs(delta) / math.Sqrt(baselineVariance*(1/float64(numTry)+1/float64(numBaseline)))
+	return math.Erfc(z / math.Sqrt2)
+}
+
+// Compare compares the trybot results in 'try', as returned from Store.Get,
+// against the 'baseline' values of the same traces at tip-of-tree, and returns
+// a TraceDelta for each trybot trace. A difference is significant if its
+// p-value is less than 'alpha'.
+//
+// The results are sorted with the significant differences first, then by the
+// magnitude of DeltaPercent, largest first.
+func Compare(try map[string][]float32, baseline types.TraceSet, alpha float64) []*TraceDelta {
+	ret := make([]*TraceDelta, 0, len(try))
+	for traceID, tryValues := range try {
+		params, err := query.ParseKey(traceID)
+		if err != nil {
+			params = map[string]string{}
+		}
+		baselineValues := []float32{}
+		for _, v := range baseline[traceID] {
+			if v != vec32.MISSING_DATA_SENTINEL {
+				baselineValues = append(baselineValues, v)
+			}
+		}
+		tryMean, _ := meanAndVariance(tryValues)
+		baselineMean, baselineVariance := meanAndVariance(baselineValues)
+		delta := tryMean - baselineMean
+		td := &TraceDelta{
+			TraceID:        traceID,
+			Params:         params,
+			TryMean:        float32(tryMean),
+			NumTry:         len(tryValues),
+			BaselineMean:   float32(baselineMean),
+			BaselineStdDev: float32(math.Sqrt(baselineVariance)),
+			NumBaseline:    len(baselineValues),
+			Delta:          float32(delta),
+			PValue:         pValue(delta, baselineVariance, len(tryValues), len(baselineValues)),
+		}
+		if len(baselineValues) == 0 {
+			td.Delta = 0
+		} else if baselineMean != 0 {
+			td.DeltaPercent = float32(100 * delta / math.Abs(baselineMean))
+		}
+		td.Significant = td.PValue < alpha
+		ret = append(ret, td)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Significant != ret[j].Significant {
+			return ret[i].Significant
+		}
+		a := math.Abs(float64(ret[i].DeltaPercent))
+		b := math.Abs(float64(ret[j].DeltaPercent))
+		if a != b {
+			return a > b
+		}
+		return ret[i].TraceID < ret[j].TraceID
+	})
+	return ret
+}
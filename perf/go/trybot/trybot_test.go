@@ -0,0 +1,83 @@
+package trybot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/go/vec32"
+	"go.skia.org/infra/perf/go/types"
+)
+
+func TestCompare(t *testing.T) {
+	unittest.SmallTest(t)
+	e := vec32.MISSING_DATA_SENTINEL
+	baseline := types.TraceSet{
+		",name=noisy,":   types.Trace{10, 12, 8, 11, 9, e},
+		",name=steady,":  types.Trace{10, 10.1, 9.9, 10, 10, 10},
+		",name=regress,": types.Trace{10, 10.1, 9.9, 10, 10, 10},
+		",name=short,":   types.Trace{10, e, e},
+	}
+	try := map[string][]float32{
+		",name=noisy,":   {11},
+		",name=steady,":  {10},
+		",name=regress,": {12, 12.2},
+		",name=short,":   {20},
+		",name=new,":     {5},
+	}
+
+	deltas := Compare(try, baseline, DEFAULT_ALPHA)
+	require.Len(t, deltas, 5)
+	byID := map[string]*TraceDelta{}
+	for _, d := range deltas {
+		byID[d.TraceID] = d
+	}
+
+	// The only significant change sorts first.
+	assert.Equal(t, ",name=regress,", deltas[0].TraceID)
+	regress := byID[",name=regress,"]
+	assert.True(t, regress.Significant)
+	assert.Equal(t, map[string]string{"name": "regress"}, regress.Params)
+	assert.Equal(t, 2, regress.NumTry)
+	assert.Equal(t, 6, regress.NumBaseline)
+	assert.InDelta(t, 12.1, regress.TryMean, 0.001)
+	assert.InDelta(t, 10, regress.BaselineMean, 0.001)
+	assert.InDelta(t, 2.1, regress.Delta, 0.001)
+	assert.InDelta(t, 21, regress.DeltaPercent, 0.01)
+	assert.Less(t, regress.PValue, 0.001)
+
+	// Within the noise.
+	noisy := byID[",name=noisy,"]
+	assert.False(t, noisy.Significant)
+	assert.Equal(t, 5, noisy.NumBaseline)
+	assert.InDelta(t, 10, noisy.DeltaPercent, 0.01)
+
+	assert.False(t, byID[",name=steady,"].Significant)
+	assert.Equal(t, float32(0), byID[",name=steady,"].Delta)
+
+	// Not enough baseline data to be significant.
+	short := byID[",name=short,"]
+	assert.False(t, short.Significant)
+	assert.Equal(t, 1.0, short.PValue)
+	assert.InDelta(t, 100, short.DeltaPercent, 0.01)
+
+	// No baseline data at all.
+	newTrace := byID[",name=new,"]
+	assert.False(t, newTrace.Significant)
+	assert.Equal(t, 0, newTrace.NumBaseline)
+	assert.Equal(t, float32(0), newTrace.Delta)
+	assert.Equal(t, float32(0), newTrace.DeltaPercent)
+}
+
+func TestPValue(t *testing.T) {
+	unittest.SmallTest(t)
+	assert.Equal(t, 1.0, pValue(1, 1, 0, 10))
+	assert.Equal(t, 1.0, pValue(1, 1, 1, MIN_BASELINE_POINTS-1))
+	assert.Equal(t, 0.0, pValue(1, 0, 1, 10))
+	assert.Equal(t, 1.0, pValue(0, 0, 1, 10))
+	// A difference of zero is never significant.
+	assert.Equal(t, 1.0, pValue(0, 4, 1, 10))
+	// Larger differences are more significant.
+	assert.Greater(t, pValue(1, 4, 1, 10), pValue(2, 4, 1, 10))
+}
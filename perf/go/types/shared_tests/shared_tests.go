@@ -0,0 +1,222 @@
+// Package shared_tests contains tests that every implementation of
+// types.TraceStore should pass.
+package shared_tests
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/paramtools"
+	"go.skia.org/infra/go/query"
+	"go.skia.org/infra/go/sktest"
+	"go.skia.org/infra/go/vec32"
+	"go.skia.org/infra/perf/go/types"
+)
+
+// TILE_SIZE is the tile size that the TraceStore passed to TestTraceStore
+// must be configured with.
+const TILE_SIZE = 256
+
+// TestTraceStore exercises all the methods of types.TraceStore. The store must
+// be empty and have a tile size of TILE_SIZE.
+func TestTraceStore(t sktest.TestingT, store types.TraceStore) {
+	ctx := context.Background()
+	now := time.Now()
+	require.Equal(t, int32(TILE_SIZE), store.TileSize())
+
+	tileNumber := types.TileNumber(1)
+	assert.Equal(t, tileNumber, store.TileNumber(257))
+	assert.Equal(t, int32(1), store.OffsetFromIndex(257))
+	assert.Equal(t, types.CommitNumber(256), store.CommitNumberOfTileStart(257))
+
+	// Start empty.
+	ops, err := store.GetOrderedParamSet(ctx, tileNumber)
+	require.NoError(t, err)
+	assert.Empty(t, ops.KeyOrder)
+	indexCount, err := store.CountIndices(ctx, tileNumber)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), indexCount)
+
+	paramset := paramtools.ParamSet{
+		"config": []string{"8888", "565"},
+		"cpu":    []string{"x86", "arm"},
+	}
+	expectedParams := []paramtools.Params{
+		{"cpu": "x86", "config": "8888"},
+		{"cpu": "x86", "config": "565"},
+		{"cpu": "arm", "config": "8888"},
+		{"cpu": "arm", "config": "565"},
+	}
+	values := []float32{
+		1.0,
+		1.1,
+		1.2,
+		1.3,
+	}
+	err = store.WriteTraces(257, expectedParams, values, paramset, "gs://some/test/location", now)
+	require.NoError(t, err)
+
+	latest, err := store.GetLatestTile()
+	require.NoError(t, err)
+	assert.Equal(t, tileNumber, latest)
+
+	ops, err = store.GetOrderedParamSet(ctx, tileNumber)
+	require.NoError(t, err)
+	ops.ParamSet.Normalize()
+	assert.Equal(t, paramtools.ParamSet{
+		"config": []string{"565", "8888"},
+		"cpu":    []string{"arm", "x86"},
+	}, ops.ParamSet)
+
+	// Two key=value pairs for each of the four traces.
+	indexCount, err = store.CountIndices(ctx, tileNumber)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), indexCount)
+
+	traceCount, err := store.TraceCount(ctx, tileNumber)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), traceCount)
+
+	q, err := query.New(url.Values{"config": []string{"8888"}})
+	require.NoError(t, err)
+
+	count, err := store.QueryCount(ctx, tileNumber, q)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	vec1 := vec32.New(TILE_SIZE)
+	vec1[1] = 1.0
+	vec2 := vec32.New(TILE_SIZE)
+	vec2[1] = 1.2
+	expected := types.TraceSet{
+		",config=8888,cpu=x86,": vec1,
+		",config=8888,cpu=arm,": vec2,
+	}
+	results, err := store.QueryTracesByIndex(ctx, tileNumber, q)
+	require.NoError(t, err)
+	assert.Equal(t, expected, results)
+
+	outCh, err := store.QueryTracesIDOnlyByIndex(ctx, tileNumber, q)
+	require.NoError(t, err)
+	keys := []string{}
+	for p := range outCh {
+		key, err := query.MakeKeyFast(p)
+		require.NoError(t, err)
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{",config=8888,cpu=arm,", ",config=8888,cpu=x86,"}, keys)
+
+	// The empty query matches all traces.
+	emptyQuery, err := query.New(url.Values{})
+	require.NoError(t, err)
+	results, err = store.QueryTracesByIndex(ctx, tileNumber, emptyQuery)
+	require.NoError(t, err)
+	assert.Len(t, results, 4)
+
+	// A query for a value that doesn't exist in the tile matches nothing.
+	q2, err := query.New(url.Values{"config": []string{"gpu"}})
+	require.NoError(t, err)
+	results, err = store.QueryTracesByIndex(ctx, tileNumber, q2)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// Now overwrite a value.
+	overWriteParams := []paramtools.Params{
+		{"cpu": "x86", "config": "8888"},
+	}
+	values = []float32{
+		2.0,
+	}
+	err = store.WriteTraces(257, overWriteParams, values, paramset, "gs://some/other/test/location", now)
+	require.NoError(t, err)
+	indexCount, err = store.CountIndices(ctx, tileNumber)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), indexCount)
+
+	vec1 = vec32.New(TILE_SIZE)
+	vec1[1] = 2.0
+	expected = types.TraceSet{
+		",config=8888,cpu=x86,": vec1,
+		",config=8888,cpu=arm,": vec2,
+	}
+	results, err = store.QueryTracesByIndex(ctx, tileNumber, q)
+	require.NoError(t, err)
+	assert.Equal(t, expected, results)
+
+	// Write in the next column.
+	writeParams := []paramtools.Params{
+		{"cpu": "x86", "config": "8888"},
+	}
+	values = []float32{
+		3.0,
+	}
+	err = store.WriteTraces(258, writeParams, values, paramset, "gs://some/other/test/location", now)
+	require.NoError(t, err)
+
+	vec1 = vec32.New(TILE_SIZE)
+	vec1[1] = 2.0
+	vec1[2] = 3.0
+	expected = types.TraceSet{
+		",config=8888,cpu=x86,": vec1,
+		",config=8888,cpu=arm,": vec2,
+	}
+	results, err = store.QueryTracesByIndex(ctx, tileNumber, q)
+	require.NoError(t, err)
+	assert.Equal(t, expected, results)
+
+	// ReadTraces only returns the traces asked for, and ignores unknown traces.
+	traces, err := store.ReadTraces(tileNumber, []string{",config=8888,cpu=x86,", ",config=8888,cpu=risc-v,"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]float32{
+		",config=8888,cpu=x86,": vec1,
+	}, traces)
+
+	// Write to a new trace.
+	writeParams = []paramtools.Params{
+		{"cpu": "risc-v", "config": "8888"},
+	}
+	values = []float32{
+		2.0,
+	}
+	paramset.AddParams(writeParams[0])
+	err = store.WriteTraces(258, writeParams, values, paramset, "gs://some/other/test/location", now)
+	require.NoError(t, err)
+	indexCount, err = store.CountIndices(ctx, tileNumber)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), indexCount)
+
+	count, err = store.QueryCount(ctx, tileNumber, q)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	// Rewriting the indices doesn't change them.
+	err = store.WriteIndices(ctx, tileNumber)
+	require.NoError(t, err)
+	indexCount, err = store.CountIndices(ctx, tileNumber)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), indexCount)
+
+	// Confirm we can get the source file location back.
+	traceId, err := query.MakeKey(paramtools.Params{"cpu": "x86", "config": "8888"})
+	require.NoError(t, err)
+	s, err := store.GetSource(ctx, 258, traceId)
+	require.NoError(t, err)
+	assert.Equal(t, "gs://some/other/test/location", s)
+
+	// Confirm we get an error trying to retrieve a source file that doesn't exist.
+	s, err = store.GetSource(ctx, 259, traceId)
+	assert.Error(t, err)
+	assert.Equal(t, "", s)
+
+	// Writing to a later tile moves the latest tile.
+	err = store.WriteTraces(TILE_SIZE*3, writeParams, values, paramset, "gs://some/other/test/location", now)
+	require.NoError(t, err)
+	latest, err = store.GetLatestTile()
+	require.NoError(t, err)
+	assert.Equal(t, types.TileNumber(3), latest)
+}
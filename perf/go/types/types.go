@@ -90,6 +90,16 @@ const (
 
 	// COHEN_STEP uses Cohen's d method to detect a change. https://en.wikipedia.org/wiki/Effect_size#Cohen's_d
 	COHEN_STEP StepDetection = "cohen"
+
+	// MANNWHITNEYU_STEP uses the Mann-Whitney U test to detect if the values
+	// before and after the mid-point come from different distributions.
+	// https://en.wikipedia.org/wiki/Mann%E2%80%93Whitney_U_test
+	MANNWHITNEYU_STEP StepDetection = "mannwhitneyu"
+
+	// PELT_STEP finds all the change points in the trace using the PELT
+	// method, and flags a step if one of them is at the mid-point.
+	// https://arxiv.org/abs/1101.1438
+	PELT_STEP StepDetection = "pelt"
 )
 
 var (
@@ -105,6 +115,8 @@ var (
 		ABSOLUTE_STEP,
 		PERCENT_STEP,
 		COHEN_STEP,
+		MANNWHITNEYU_STEP,
+		PELT_STEP,
 	}
 )
 
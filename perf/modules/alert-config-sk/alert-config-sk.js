@@ -25,6 +25,23 @@ const _groupByChoices = (ele) => {
   return ele._paramkeys.map((p) => html`<div ?selected=${groups.indexOf(p) !== -1}>${p}</div>`);
 };
 
+const _notificationTypes = [
+  ['email', 'Email: Comma separated list of email addresses.'],
+  ['webhook', 'Webhook: https URL that a JSON payload is POSTed to.'],
+  ['chat', 'Chat: Name of the chat room.'],
+  ['issue', 'Bug: Issue tracker component to file bugs in.'],
+];
+
+const _notifications = (ele) => ele._notifications().map((n, i) => html`
+  <div class=notification>
+    <select-sk @selection-changed=${(e) => n.type = _notificationTypes[e.detail.selection][0]}>
+      ${_notificationTypes.map((t) => html`<div ?selected=${n.type === t[0]}>${t[1]}</div>`)}
+    </select-sk>
+    <input .value=${n.target} @input=${(e) => n.target = e.target.value}>
+    <button @click=${() => ele._removeNotification(i)}>Remove</button>
+  </div>
+`);
+
 const template = (ele) => html`
   <h3>Display Name</h3>
   <label for=display-name>Display Name</label>
@@ -53,10 +70,12 @@ const template = (ele) => html`
     <div value=absolute ?selected=${ele._config.step === 'absolute'}>A change in absolute magnitude. Threshold is the minimum difference to trigger an alert.</div>
     <div value=percent ?selected=${ele._config.step === 'percent'}>A change by percent. Threshold is a value in [0.0, 1.0] and the minimum difference to trigger an alert.</div>
     <div value=cohen ?selected=${ele._config.step === 'cohen'}>Use Cohen's d method to detect a change. Threshold is the standard deviations that the mean must move to trigger an alert.</div>
+    <div value=mannwhitneyu ?selected=${ele._config.step === 'mannwhitneyu'}>Use the Mann-Whitney U test to detect a change. Threshold is the p-value below which an alert is triggered, e.g. 0.05.</div>
+    <div value=pelt ?selected=${ele._config.step === 'pelt'}>Use PELT change point detection, which only alerts if a change point is found at the commit. Threshold is the number of standard deviations of the noise that the mean must move to trigger an alert.</div>
   </select-sk>
   <h4>Threshold</h4>
-  <label for=threshold>The threshold for Step Detection to trigger an alert. The meaning of the value and meaningful range depends on the algorithm chosen for <em>Step Detection</em>. </label>
-  <input id=threshold type=number min=1 max=500 .value=${ele._config.interesting} @input=${(e) => ele._config.interesting = +e.target.value}>
+  <label for=threshold>The threshold for Step Detection to trigger an alert. The meaning of the value and meaningful range depends on the algorithm chosen for <em>Step Detection</em>. For Mann-Whitney U the threshold is a p-value and must be in (0, 1).</label>
+  <input id=threshold type=number min=0 max=500 step=any .value=${ele._config.interesting} @input=${(e) => ele._config.interesting = +e.target.value}>
 
   <h4>K</h4>
   <label for=k>The number of clusters. Only used when Grouping is K-Means. 0 = use a server chosen value.</label>
@@ -83,6 +102,11 @@ const template = (ele) => html`
   <h3>Where are alerts sent</h3>
   <label for=sent>Alert Destination: Comma separated list of email addresses.</label>
   <input id=sent .value=${ele._config.alert} @input=${(e) => ele._config.alert = e.target.value}>
+  <label for=notifications>Additional Alert Destinations. Follow-up notifications are sent when a regression is triaged or recovers.</label>
+  <div id=notifications>
+    ${_notifications(ele)}
+  </div>
+  <button @click=${ele._addNotification}>Add Destination</button>
   <button @click=${ele._testAlert}>Test</button>
   <spinner-sk id=alertSpinner></spinner-sk>
 
@@ -135,6 +159,7 @@ define('alert-config-sk', class extends ElementSk {
       sparse: false,
       minimum_num: 0,
       category: 'Experimental',
+      notifications: [],
     };
     this._key_order = window.sk.perf.key_order;
   }
@@ -171,10 +196,31 @@ define('alert-config-sk', class extends ElementSk {
     });
   }
 
+  _notifications() {
+    if (!this._config.notifications) {
+      this._config.notifications = [];
+    }
+    return this._config.notifications;
+  }
+
+  _addNotification() {
+    this._notifications().push({
+      type: 'email',
+      target: '',
+    });
+    this._render();
+  }
+
+  _removeNotification(index) {
+    this._notifications().splice(index, 1);
+    this._render();
+  }
+
   _testAlert() {
     this._alertSpinner.active = true;
     const body = {
       alert: this.config.alert,
+      notifications: this._notifications(),
     };
     fetch('/_/alert/notify/try', {
       method: 'POST',
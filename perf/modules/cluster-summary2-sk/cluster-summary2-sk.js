@@ -59,6 +59,8 @@ const template = (ele) => html`
     <div class=labelled>Cluster Size: <span>${ele._summary.num}</span></div>
     <div class=labelled>Least Squares Error: <span>${_trunc(ele._summary.step_fit.least_squares)}</span></div>
     <div class=labelled>Step Size: <span>${_trunc(ele._summary.step_fit.step_size)}</span></div>
+    <div class="labelled ${ele._summary.step_fit.p_value ? '' : 'hidden'}">Statistic: <span>${_trunc(ele._summary.step_fit.statistic)}</span></div>
+    <div class="labelled ${ele._summary.step_fit.p_value ? '' : 'hidden'}">p-value: <span>${_trunc(ele._summary.step_fit.p_value)}</span></div>
   </div>
   <plot-simple-sk class=plot width=500 height=350 specialevents @trace_selected=${ele._traceSelected}></plot-simple-sk>
   <div id=status class=${ele._hiddenClass()}>
@@ -88,6 +90,8 @@ export class ClusterSummary2Sk extends ElementSk {
         regression: 0,
         least_squares: 0,
         step_size: 0,
+        statistic: 0,
+        p_value: 0,
       },
       param_summaries2: [],
     };
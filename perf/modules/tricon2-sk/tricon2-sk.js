@@ -5,7 +5,7 @@
  * The triage state icons.
  *
  * @attr {string} value - A string representing the triage status, one of
- *     "untriaged", "positive", or "negative".
+ *     "untriaged", "positive", "negative", or "recovered".
  *
  */
 import { define } from 'elements-sk/define';
@@ -14,6 +14,7 @@ import { ElementSk } from '../../../infra-sk/modules/ElementSk';
 import 'elements-sk/icon/check-circle-icon-sk';
 import 'elements-sk/icon/cancel-icon-sk';
 import 'elements-sk/icon/help-icon-sk';
+import 'elements-sk/icon/restore-icon-sk';
 import 'elements-sk/styles/buttons';
 
 const template = (ele) => {
@@ -22,6 +23,8 @@ const template = (ele) => {
       return html`<check-circle-icon-sk title='Positive'></check-circle-icon-sk>`;
     case 'negative':
       return html`<cancel-icon-sk title='Negative'></cancel-icon-sk>`;
+    case 'recovered':
+      return html`<restore-icon-sk title='Recovered'></restore-icon-sk>`;
     default:
       return html`<help-icon-sk title='Untriaged'></help-icon-sk>`;
   }
@@ -16,6 +16,9 @@ import (
 const (
 	// Template used for creating unique IDs for instances of triggers.
 	TRIGGER_TS = "2006-01-02"
+	// The cron trigger is sent every few minutes, so its IDs need a finer
+	// resolution.
+	CRON_TRIGGER_TS = "2006-01-02T15:04"
 )
 
 var (
@@ -36,7 +39,11 @@ func main() {
 	// significantly delayed, we might end up sending the same message twice
 	// with different dates. It also doesn't allow for periods smaller than
 	// 24 hours.
-	id := fmt.Sprintf("%s-%s", *trigger, time.Now().UTC().Format(TRIGGER_TS))
+	tsFormat := TRIGGER_TS
+	if *trigger == periodic.TRIGGER_CRON {
+		tsFormat = CRON_TRIGGER_TS
+	}
+	id := fmt.Sprintf("%s-%s", *trigger, time.Now().UTC().Format(tsFormat))
 	if err := periodic.Trigger(context.Background(), *trigger, id, ts); err != nil {
 		sklog.Fatal(err)
 	}
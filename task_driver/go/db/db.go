@@ -191,6 +191,11 @@ func (t *TaskDriverRun) UpdateFromMessage(m *td.Message) error {
 		if m.Error != "" {
 			step.Errors = append(step.Errors, m.Error)
 		}
+	case td.MSG_TYPE_STEP_TIMEOUT:
+		step.Result = td.STEP_RESULT_TIMEOUT
+		if m.Error != "" {
+			step.Errors = append(step.Errors, m.Error)
+		}
 	}
 	return nil
 }
@@ -2,11 +2,81 @@ package display
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/task_driver/go/db"
+	"go.skia.org/infra/task_driver/go/td"
 )
 
+func TestTaskDriverForDisplayTimeout(t *testing.T) {
+	unittest.SmallTest(t)
+
+	// Build a Task Driver run in which one step timed out.
+	const taskId = "fake-task-id"
+	ts := time.Unix(1573000000, 0).UTC()
+	run := &db.TaskDriverRun{
+		TaskId: taskId,
+	}
+	msgIndex := 0
+	update := func(m *td.Message) {
+		msgIndex++
+		m.Index = msgIndex
+		m.TaskId = taskId
+		m.Timestamp = ts.Add(time.Duration(msgIndex) * time.Second)
+		require.NoError(t, m.Validate())
+		require.NoError(t, run.UpdateFromMessage(m))
+	}
+	update(&td.Message{
+		Type: td.MSG_TYPE_RUN_STARTED,
+		Run:  &td.RunProperties{Local: true},
+	})
+	update(&td.Message{
+		Type:   td.MSG_TYPE_STEP_STARTED,
+		StepId: td.STEP_ID_ROOT,
+		Step: &td.StepProperties{
+			Id:   td.STEP_ID_ROOT,
+			Name: "root",
+		},
+	})
+	update(&td.Message{
+		Type:   td.MSG_TYPE_STEP_STARTED,
+		StepId: "slow",
+		Step: &td.StepProperties{
+			Id:      "slow",
+			Name:    "slow",
+			Timeout: time.Second,
+			Parent:  td.STEP_ID_ROOT,
+		},
+	})
+	update(&td.Message{
+		Type:   td.MSG_TYPE_STEP_TIMEOUT,
+		StepId: "slow",
+		Error:  "context deadline exceeded",
+	})
+	update(&td.Message{
+		Type:   td.MSG_TYPE_STEP_FINISHED,
+		StepId: "slow",
+	})
+	update(&td.Message{
+		Type:   td.MSG_TYPE_STEP_FINISHED,
+		StepId: td.STEP_ID_ROOT,
+	})
+
+	disp, err := TaskDriverForDisplay(run)
+	require.NoError(t, err)
+	require.Equal(t, td.STEP_RESULT_SUCCESS, disp.Result)
+	require.Equal(t, 1, len(disp.Steps))
+	s := disp.Steps[0]
+	require.Equal(t, "slow", s.Name)
+	require.Equal(t, time.Second, s.Timeout)
+	require.Equal(t, td.STEP_RESULT_TIMEOUT, s.Result)
+	require.Equal(t, []string{"context deadline exceeded"}, s.Errors)
+	require.Equal(t, ts.Add(5*time.Second), s.Finished)
+}
+
 func TestTruncateError(t *testing.T) {
 	unittest.SmallTest(t)
 
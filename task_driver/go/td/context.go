@@ -2,6 +2,7 @@ package td
 
 import (
 	"context"
+	"time"
 
 	"go.skia.org/infra/go/exec"
 )
@@ -25,6 +26,15 @@ type Context struct {
 	// Environment variables, set via WithEnv.
 	env []string
 
+	// cancel releases the resources associated with the current step's
+	// timeout, if any. Called when the step finishes.
+	cancel context.CancelFunc
+
+	// deadline is the time at which the current step's own timeout
+	// expires. Zero if the step has no timeout or if an ancestor's timeout
+	// expires first.
+	deadline time.Time
+
 	// execRun provides a Run function to be called by execCtx. This is used
 	// for testing, where we may want to mock out subprocess invocations.
 	execRun func(context.Context, *exec.Command) error
@@ -56,6 +66,8 @@ func withChildCtx(ctx context.Context, child *Context) context.Context {
 	if child.step == nil {
 		child.step = parent.step
 		child.env = MergeEnv(parent.env, child.env)
+		child.cancel = parent.cancel
+		child.deadline = parent.deadline
 	} else {
 		child.step.Environ = MergeEnv(parent.env, child.step.Environ)
 		// Override child.env; it shouldn't be set when adding a step.
@@ -15,6 +15,7 @@ const (
 	MSG_TYPE_STEP_DATA      MessageType = "STEP_DATA"
 	MSG_TYPE_STEP_FAILED    MessageType = "STEP_FAILED"
 	MSG_TYPE_STEP_EXCEPTION MessageType = "STEP_EXCEPTION"
+	MSG_TYPE_STEP_TIMEOUT   MessageType = "STEP_TIMEOUT"
 
 	DATA_TYPE_LOG           DataType = "log"
 	DATA_TYPE_COMMAND       DataType = "command"
@@ -59,7 +60,8 @@ type Message struct {
 	Step *StepProperties `json:"step,omitempty"`
 
 	// Error is any error which might have occurred. Required for
-	// MSG_TYPE_STEP_FAILED and MSG_TYPE_STEP_EXCEPTION.
+	// MSG_TYPE_STEP_FAILED, MSG_TYPE_STEP_EXCEPTION, and
+	// MSG_TYPE_STEP_TIMEOUT.
 	Error string `json:"error,omitempty"`
 
 	// Data is arbitrary additional data about the step. Required for
@@ -135,6 +137,13 @@ func (m *Message) Validate() error {
 		if m.Error == "" {
 			return fmt.Errorf("Error is required for %s", m.Type)
 		}
+	case MSG_TYPE_STEP_TIMEOUT:
+		if m.StepId == "" {
+			return fmt.Errorf("StepId is required for %s", m.Type)
+		}
+		if m.Error == "" {
+			return fmt.Errorf("Error is required for %s", m.Type)
+		}
 	default:
 		return fmt.Errorf("Invalid message Type %q", m.Type)
 	}
@@ -94,6 +94,16 @@ func TestMessageValidation(t *testing.T) {
 			Error:     "exception",
 		}
 	}
+	msgStepTimeout := func() *Message {
+		return &Message{
+			Index:     int(atomic.AddInt32(&msgIndex, 1)),
+			StepId:    "fake-step-id",
+			TaskId:    "fake-task-id",
+			Timestamp: now,
+			Type:      MSG_TYPE_STEP_TIMEOUT,
+			Error:     "context deadline exceeded",
+		}
+	}
 
 	// Validate a few messages.
 	checkValid(msgRunStarted())
@@ -107,6 +117,7 @@ func TestMessageValidation(t *testing.T) {
 	checkValid(msgStepData())
 	checkValid(msgStepFailed())
 	checkValid(msgStepException())
+	checkValid(msgStepTimeout())
 
 	// Check that we catch missing fields.
 	checkNotValid(func() *Message {
@@ -195,6 +206,21 @@ func TestMessageValidation(t *testing.T) {
 		m.Error = ""
 		return m
 	}, fmt.Sprintf("Error is required for %s", MSG_TYPE_STEP_EXCEPTION))
+	checkNotValid(func() *Message {
+		m := msgStepTimeout()
+		m.StepId = ""
+		return m
+	}, fmt.Sprintf("StepId is required for %s", MSG_TYPE_STEP_TIMEOUT))
+	checkNotValid(func() *Message {
+		m := msgStepTimeout()
+		m.Error = ""
+		return m
+	}, fmt.Sprintf("Error is required for %s", MSG_TYPE_STEP_TIMEOUT))
+	checkNotValid(func() *Message {
+		m := msgStepStarted()
+		m.Step.Timeout = -time.Second
+		return m
+	}, "Timeout must not be negative.")
 	checkNotValid(func() *Message {
 		m := msgStepException()
 		m.Type = "invalid"
@@ -105,6 +105,8 @@ func (r *DebugReceiver) HandleMessage(m *Message) error {
 		glog.Infof("STEP_EXCEPTION: %s", m.StepId)
 	case MSG_TYPE_STEP_FAILED:
 		glog.Infof("STEP_FAILED: %s", m.StepId)
+	case MSG_TYPE_STEP_TIMEOUT:
+		glog.Infof("STEP_TIMEOUT: %s", m.StepId)
 	case MSG_TYPE_STEP_DATA:
 		b, err := json.MarshalIndent(m.Data, "", " ")
 		if err != nil {
@@ -240,6 +242,13 @@ func (r *ReportReceiver) HandleMessage(m *Message) error {
 		}
 		s.Exceptions = append(s.Exceptions, m.Error)
 		s.Result = STEP_RESULT_EXCEPTION
+	case MSG_TYPE_STEP_TIMEOUT:
+		s, err := r.findStep(m.StepId)
+		if err != nil {
+			return err
+		}
+		s.Errors = append(s.Errors, m.Error)
+		s.Result = STEP_RESULT_TIMEOUT
 	case MSG_TYPE_STEP_DATA:
 		s, err := r.findStep(m.StepId)
 		if err != nil {
@@ -316,6 +316,17 @@ func (r *run) Failed(id string, err error) {
 	r.send(msg)
 }
 
+// Send a Message indicating that the current step has exceeded its timeout and
+// failed with the given error.
+func (r *run) TimedOut(id string, err error) {
+	msg := &Message{
+		Type:   MSG_TYPE_STEP_TIMEOUT,
+		StepId: id,
+		Error:  err.Error(),
+	}
+	r.send(msg)
+}
+
 // Send a Message indicating that the current step has finished.
 func (r *run) Finish(id string) {
 	msg := &Message{
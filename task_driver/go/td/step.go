@@ -11,6 +11,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	multierror "github.com/hashicorp/go-multierror"
@@ -27,6 +28,7 @@ const (
 	STEP_RESULT_SUCCESS   StepResult = "SUCCESS"
 	STEP_RESULT_FAILURE   StepResult = "FAILURE"
 	STEP_RESULT_EXCEPTION StepResult = "EXCEPTION"
+	STEP_RESULT_TIMEOUT   StepResult = "TIMEOUT"
 
 	// PATH_PLACEHOLDER is a placeholder for any existing value of PATH,
 	// used when merging environments to avoid overriding the PATH
@@ -93,13 +95,30 @@ func newStep(ctx context.Context, id string, parent *StepProperties, props *Step
 
 		props.Parent = parent.Id
 	}
-	ctx = withChildCtx(ctx, &Context{
+	child := &Context{
 		step: props,
-	})
+	}
+	if props.Timeout > 0 {
+		// Only record our own deadline if it expires before that of
+		// an ancestor; otherwise the ancestor is the one which timed
+		// out.
+		deadline := time.Now().Add(props.Timeout)
+		if existing, ok := ctx.Deadline(); !ok || deadline.Before(existing) {
+			child.deadline = deadline
+		}
+		ctx, child.cancel = context.WithDeadline(ctx, deadline)
+	}
+	ctx = withChildCtx(ctx, child)
 	getCtx(ctx).run.Start(props)
 	return ctx
 }
 
+// timedOut returns true iff the current step's own timeout has expired.
+func timedOut(ctx context.Context) bool {
+	deadline := getCtx(ctx).deadline
+	return !deadline.IsZero() && ctx.Err() == context.DeadlineExceeded && !time.Now().Before(deadline)
+}
+
 // Create a step.
 func StartStep(ctx context.Context, props *StepProperties) context.Context {
 	parent := getCtx(ctx).step
@@ -133,12 +152,17 @@ func InfraError(err error) error {
 //		return FailStep(ctx, err)
 //	}
 //
+// If the step has exceeded its timeout, it is marked as timed out instead.
 func FailStep(ctx context.Context, err error) error {
 	props := getCtx(ctx).step
 	if props.IsInfra {
 		err = InfraError(err)
 	}
-	getCtx(ctx).run.Failed(props.Id, err)
+	if timedOut(ctx) {
+		getCtx(ctx).run.TimedOut(props.Id, err)
+	} else {
+		getCtx(ctx).run.Failed(props.Id, err)
+	}
 	return err
 }
 
@@ -158,6 +182,9 @@ func EndStep(ctx context.Context) {
 func finishStep(ctx context.Context, recovered interface{}) {
 	props := getCtx(ctx).step
 	e := getCtx(ctx).run
+	if cancel := getCtx(ctx).cancel; cancel != nil {
+		defer cancel()
+	}
 	if recovered != nil {
 		// If the panic is an error, use the original error, otherwise
 		// create an error.
@@ -188,6 +215,93 @@ func Do(ctx context.Context, props *StepProperties, fn func(context.Context) err
 	return nil
 }
 
+// Group runs steps concurrently and waits for them to finish. Each step is a
+// child of the step associated with the context passed to NewGroup. Intended
+// to be used like this:
+//
+//	g := td.NewGroup(ctx)
+//	g.Go(td.Props("compile"), compile)
+//	g.Go(td.Props("upload"), upload)
+//	if err := g.Wait(); err != nil {
+//		return err
+//	}
+//
+type Group struct {
+	ctx       context.Context
+	wg        sync.WaitGroup
+	mtx       sync.Mutex
+	errs      *multierror.Error
+	recovered interface{}
+}
+
+// NewGroup returns a Group whose steps are children of the current step.
+func NewGroup(ctx context.Context) *Group {
+	return &Group{
+		ctx: ctx,
+	}
+}
+
+// Go runs the given function as a child step in a new goroutine.
+func (g *Group) Go(props *StepProperties, fn func(context.Context) error) {
+	g.goFn(func(ctx context.Context) error {
+		return Do(ctx, props, fn)
+	})
+}
+
+// goFn runs the given function in a new goroutine, collecting any error or
+// panic which occurs.
+func (g *Group) goFn(fn func(context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			// The panic has already been recorded for the step in
+			// which it occurred; hold on to it so that Wait can
+			// re-raise it in the caller's goroutine.
+			if rec := recover(); rec != nil {
+				g.mtx.Lock()
+				defer g.mtx.Unlock()
+				if g.recovered == nil {
+					g.recovered = rec
+				}
+			}
+		}()
+		if err := fn(g.ctx); err != nil {
+			g.mtx.Lock()
+			defer g.mtx.Unlock()
+			g.errs = multierror.Append(g.errs, err)
+		}
+	}()
+}
+
+// Wait for all steps in the Group to finish. Returns an error containing the
+// errors from all failed steps, or nil if all of them succeeded. If any step
+// panicked, Wait re-raises the panic.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	if g.recovered != nil {
+		panic(g.recovered)
+	}
+	return g.errs.ErrorOrNil()
+}
+
+// Parallel runs the given functions concurrently within a new step with the
+// given properties, waiting for all of them to finish. The functions do not
+// run as steps themselves, but any steps they create are children of the new
+// step. Returns an error containing the errors from all of the functions which
+// failed, or nil if all of them succeeded.
+func Parallel(ctx context.Context, props *StepProperties, fns ...func(context.Context) error) error {
+	return Do(ctx, props, func(ctx context.Context) error {
+		g := NewGroup(ctx)
+		for _, fn := range fns {
+			g.goFn(fn)
+		}
+		return g.Wait()
+	})
+}
+
 // Fatal is a substitute for sklog.Fatal which logs an error and panics.
 // sklog.Fatal does not panic but calls os.Exit, which prevents the Task Driver
 // from properly reporting errors.
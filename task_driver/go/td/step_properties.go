@@ -2,6 +2,7 @@ package td
 
 import (
 	"errors"
+	"time"
 
 	"go.skia.org/infra/go/util"
 )
@@ -22,6 +23,10 @@ type StepProperties struct {
 	// variables.
 	Environ []string `json:"environment,omitempty"`
 
+	// If non-zero, the step's context is canceled after this amount of
+	// time and, if the step fails as a result, it is marked as timed out.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
 	// Parent step ID. This is set by the framework and should not be set
 	// by callers.
 	Parent string `json:"parent,omitempty"`
@@ -49,6 +54,13 @@ func (p *StepProperties) Env(env []string) *StepProperties {
 	return p
 }
 
+// WithTimeout limits the step to the given amount of time, after which its
+// context is canceled.
+func (p *StepProperties) WithTimeout(timeout time.Duration) *StepProperties {
+	p.Timeout = timeout
+	return p
+}
+
 // Copy returns a deep copy of the StepProperties.
 func (p *StepProperties) Copy() *StepProperties {
 	if p == nil {
@@ -59,6 +71,7 @@ func (p *StepProperties) Copy() *StepProperties {
 		Name:    p.Name,
 		IsInfra: p.IsInfra,
 		Environ: util.CopyStringSlice(p.Environ),
+		Timeout: p.Timeout,
 		Parent:  p.Parent,
 	}
 }
@@ -69,6 +82,8 @@ func (p *StepProperties) Validate() error {
 		return errors.New("Id is required.")
 	} else if p.Id != STEP_ID_ROOT && p.Parent == "" {
 		return errors.New("Non-root steps must have a parent.")
+	} else if p.Timeout < 0 {
+		return errors.New("Timeout must not be negative.")
 	}
 	return nil
 }
@@ -0,0 +1,22 @@
+package td
+
+import (
+	"testing"
+	"time"
+
+	"go.skia.org/infra/go/deepequal/assertdeep"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+func TestCopyStepProperties(t *testing.T) {
+	unittest.SmallTest(t)
+	p := &StepProperties{
+		Id:      "id",
+		Name:    "name",
+		IsInfra: true,
+		Environ: []string{"k=v"},
+		Timeout: time.Minute,
+		Parent:  "parent",
+	}
+	assertdeep.Copy(t, p, p.Copy())
+}
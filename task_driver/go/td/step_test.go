@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.skia.org/infra/go/deepequal/assertdeep"
@@ -401,3 +403,178 @@ func TestEnvInheritance(t *testing.T) {
 	require.NotNil(t, data)
 	assertdeep.Equal(t, data.Env, expect)
 }
+
+func TestTimeout(t *testing.T) {
+	unittest.SmallTest(t)
+
+	// A step which exceeds its timeout is marked as timed out.
+	var stepErr error
+	res := RunTestSteps(t, false, func(ctx context.Context) error {
+		stepErr = Do(ctx, Props("slow").WithTimeout(time.Millisecond), func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		return nil
+	})
+	require.Equal(t, context.DeadlineExceeded, stepErr)
+	require.Equal(t, STEP_RESULT_SUCCESS, res.Result)
+	require.Equal(t, 1, len(res.Steps))
+	s := res.Steps[0]
+	require.Equal(t, time.Millisecond, s.Timeout)
+	require.Equal(t, STEP_RESULT_TIMEOUT, s.Result)
+	require.Equal(t, []string{context.DeadlineExceeded.Error()}, s.Errors)
+
+	// A step which finishes in time succeeds, and its context is canceled
+	// when it finishes.
+	var stepCtx context.Context
+	res = RunTestSteps(t, false, func(ctx context.Context) error {
+		return Do(ctx, Props("fast").WithTimeout(time.Hour), func(ctx context.Context) error {
+			stepCtx = ctx
+			return nil
+		})
+	})
+	require.Equal(t, STEP_RESULT_SUCCESS, res.Result)
+	require.Equal(t, STEP_RESULT_SUCCESS, res.Steps[0].Result)
+	require.Equal(t, context.Canceled, stepCtx.Err())
+
+	// A step which fails for some other reason is not marked as timed out.
+	res = RunTestSteps(t, false, func(ctx context.Context) error {
+		return Do(ctx, Props("fails").WithTimeout(time.Hour), func(ctx context.Context) error {
+			return errors.New("whoops")
+		})
+	})
+	require.Equal(t, STEP_RESULT_FAILURE, res.Steps[0].Result)
+
+	// When a parent step times out, the child steps fail but only the
+	// parent is marked as timed out.
+	res = RunTestSteps(t, false, func(ctx context.Context) error {
+		return Do(ctx, Props("parent").WithTimeout(time.Millisecond), func(ctx context.Context) error {
+			return Do(ctx, Props("child").WithTimeout(time.Hour), func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+		})
+	})
+	parent := res.Steps[0]
+	require.Equal(t, STEP_RESULT_TIMEOUT, parent.Result)
+	require.Equal(t, 1, len(parent.Steps))
+	require.Equal(t, STEP_RESULT_FAILURE, parent.Steps[0].Result)
+}
+
+func TestParallel(t *testing.T) {
+	unittest.SmallTest(t)
+
+	// All functions run concurrently; none of them can finish until all of
+	// them have started.
+	n := 3
+	var wg sync.WaitGroup
+	wg.Add(n)
+	fns := make([]func(context.Context) error, 0, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("step %d", i)
+		fns = append(fns, func(ctx context.Context) error {
+			return Do(ctx, Props(name), func(ctx context.Context) error {
+				wg.Done()
+				wg.Wait()
+				if name == "step 1" {
+					return nil
+				}
+				return fmt.Errorf("%s failed", name)
+			})
+		})
+	}
+	var parallelErr error
+	res := RunTestSteps(t, false, func(ctx context.Context) error {
+		parallelErr = Parallel(ctx, Props("parallel"), fns...)
+		return nil
+	})
+	require.Error(t, parallelErr)
+	require.Contains(t, parallelErr.Error(), "step 0 failed")
+	require.Contains(t, parallelErr.Error(), "step 2 failed")
+	require.NotContains(t, parallelErr.Error(), "step 1 failed")
+
+	// Verify the step hierarchy and results.
+	require.Equal(t, 1, len(res.Steps))
+	parallel := res.Steps[0]
+	require.Equal(t, "parallel", parallel.Name)
+	require.Equal(t, STEP_RESULT_FAILURE, parallel.Result)
+	require.Equal(t, n, len(parallel.Steps))
+	results := map[string]StepResult{}
+	for _, s := range parallel.Steps {
+		require.Equal(t, parallel.Id, s.Parent)
+		results[s.Name] = s.Result
+	}
+	require.Equal(t, map[string]StepResult{
+		"step 0": STEP_RESULT_FAILURE,
+		"step 1": STEP_RESULT_SUCCESS,
+		"step 2": STEP_RESULT_FAILURE,
+	}, results)
+
+	// A Parallel step with a timeout cancels all of its children.
+	res = RunTestSteps(t, false, func(ctx context.Context) error {
+		wait := func(ctx context.Context) error {
+			return Do(ctx, Props("wait"), func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+		}
+		return Parallel(ctx, Props("parallel").WithTimeout(time.Millisecond), wait, wait)
+	})
+	parallel = res.Steps[0]
+	require.Equal(t, STEP_RESULT_TIMEOUT, parallel.Result)
+	require.Equal(t, 2, len(parallel.Steps))
+	for _, s := range parallel.Steps {
+		require.Equal(t, STEP_RESULT_FAILURE, s.Result)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	unittest.SmallTest(t)
+
+	// Steps run as children of the current step.
+	res := RunTestSteps(t, false, func(ctx context.Context) error {
+		return Do(ctx, Props("parent").Infra(), func(ctx context.Context) error {
+			g := NewGroup(ctx)
+			g.Go(Props("a"), func(ctx context.Context) error {
+				return nil
+			})
+			g.Go(Props("b"), func(ctx context.Context) error {
+				return errors.New("b failed")
+			})
+			err := g.Wait()
+			require.EqualError(t, err, "1 error occurred:\n\t* b failed\n\n")
+			return nil
+		})
+	})
+	require.Equal(t, STEP_RESULT_SUCCESS, res.Result)
+	parent := res.Steps[0]
+	require.Equal(t, STEP_RESULT_SUCCESS, parent.Result)
+	require.Equal(t, 2, len(parent.Steps))
+	for _, s := range parent.Steps {
+		require.Equal(t, parent.Id, s.Parent)
+		// Children inherit the infra status of their parent.
+		require.True(t, s.IsInfra)
+		if s.Name == "a" {
+			require.Equal(t, STEP_RESULT_SUCCESS, s.Result)
+		} else {
+			require.Equal(t, STEP_RESULT_EXCEPTION, s.Result)
+		}
+	}
+
+	// An empty Group succeeds.
+	_ = RunTestSteps(t, false, func(ctx context.Context) error {
+		require.NoError(t, NewGroup(ctx).Wait())
+		return nil
+	})
+
+	// Panics are re-raised by Wait.
+	res = RunTestSteps(t, true, func(ctx context.Context) error {
+		g := NewGroup(ctx)
+		g.Go(Props("panics"), func(ctx context.Context) error {
+			panic("halp")
+		})
+		return g.Wait()
+	})
+	require.Equal(t, STEP_RESULT_EXCEPTION, res.Result)
+	require.Equal(t, STEP_RESULT_EXCEPTION, res.Steps[0].Result)
+}
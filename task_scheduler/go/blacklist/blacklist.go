@@ -2,6 +2,7 @@ package blacklist
 
 import (
 	"context"
+	"crypto/sha1"
 	"errors"
 	"fmt"
 	"regexp"
@@ -28,6 +29,9 @@ const (
 	TIMEOUT_PUT = 10 * time.Second
 
 	MAX_NAME_CHARS = 50
+
+	// Prefix used for the names of quarantine Rules.
+	QUARANTINE_RULE_PREFIX = "quarantine-"
 )
 
 var (
@@ -135,7 +139,7 @@ func (b *Blacklist) Match(taskSpec, commit string) bool {
 
 // MatchRule determines whether the given taskSpec/commit pair matches one of the
 // Rules in the Blacklist. Returns the name of the matched Rule or the empty
-// string if no Rules match.
+// string if no Rules match. Quarantine Rules are not considered.
 func (b *Blacklist) MatchRule(taskSpec, commit string) string {
 	if b == nil {
 		return ""
@@ -143,7 +147,24 @@ func (b *Blacklist) MatchRule(taskSpec, commit string) string {
 	b.mtx.RLock()
 	defer b.mtx.RUnlock()
 	for _, rule := range b.rules {
-		if rule.Match(taskSpec, commit) {
+		if !rule.Quarantine && rule.Match(taskSpec, commit) {
+			return rule.Name
+		}
+	}
+	return ""
+}
+
+// MatchQuarantine determines whether the given taskSpec/commit pair matches
+// one of the quarantine Rules in the Blacklist. Returns the name of the matched
+// Rule or the empty string if no quarantine Rules match.
+func (b *Blacklist) MatchQuarantine(taskSpec, commit string) string {
+	if b == nil {
+		return ""
+	}
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	for _, rule := range b.rules {
+		if rule.Quarantine && rule.Match(taskSpec, commit) {
 			return rule.Name
 		}
 	}
@@ -225,6 +246,26 @@ func NewCommitRangeRule(ctx context.Context, name, user, description string, tas
 	return rule, nil
 }
 
+// NewQuarantineRule creates a new Rule which quarantines the given TaskSpec.
+func NewQuarantineRule(taskSpec, user, description string, added time.Time) *Rule {
+	return &Rule{
+		Added:            added.UTC(),
+		AddedBy:          user,
+		TaskSpecPatterns: []string{"^" + regexp.QuoteMeta(taskSpec) + "$"},
+		Commits:          []string{},
+		Description:      description,
+		Name:             QuarantineRuleName(taskSpec),
+		Quarantine:       true,
+	}
+}
+
+// QuarantineRuleName returns the name of the Rule which quarantines the given
+// TaskSpec. TaskSpec names may be longer than MAX_NAME_CHARS, so the name is
+// derived from a hash of the TaskSpec name.
+func QuarantineRuleName(taskSpec string) string {
+	return fmt.Sprintf("%s%x", QUARANTINE_RULE_PREFIX, sha1.Sum([]byte(taskSpec)))[:MAX_NAME_CHARS]
+}
+
 // RemoveRule removes the Rule from the Blacklist.
 func (b *Blacklist) RemoveRule(id string) error {
 	if b == nil {
@@ -264,12 +305,17 @@ func (b *Blacklist) GetRules() []*Rule {
 // empty, the Rule applies for all commits.
 //
 // A Rule should specify TaskSpecPatterns or Commits or both.
+//
+// If Quarantine is true, matching tasks are still scheduled, but they are
+// given an extra attempt, so that flaky TaskSpecs don't cause Jobs to fail.
 type Rule struct {
-	AddedBy          string   `json:"added_by"`
-	TaskSpecPatterns []string `json:"task_spec_patterns"`
-	Commits          []string `json:"commits"`
-	Description      string   `json:"description"`
-	Name             string   `json:"name"`
+	Added            time.Time `json:"added"`
+	AddedBy          string    `json:"added_by"`
+	TaskSpecPatterns []string  `json:"task_spec_patterns"`
+	Commits          []string  `json:"commits"`
+	Description      string    `json:"description"`
+	Name             string    `json:"name"`
+	Quarantine       bool      `json:"quarantine"`
 }
 
 // ValidateRule returns an error if the given Rule is not valid.
@@ -338,10 +384,12 @@ func (r *Rule) Match(taskSpec, commit string) bool {
 // Copy returns a deep copy of the Rule.
 func (r *Rule) Copy() *Rule {
 	return &Rule{
+		Added:            r.Added,
 		AddedBy:          r.AddedBy,
 		TaskSpecPatterns: util.CopyStringSlice(r.TaskSpecPatterns),
 		Commits:          util.CopyStringSlice(r.Commits),
 		Description:      r.Description,
 		Name:             r.Name,
+		Quarantine:       r.Quarantine,
 	}
 }
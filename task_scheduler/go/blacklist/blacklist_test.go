@@ -59,15 +59,37 @@ func TestAddRemove(t *testing.T) {
 func TestRuleCopy(t *testing.T) {
 	unittest.SmallTest(t)
 	r := &Rule{
+		Added:            time.Unix(1580000000, 0),
 		AddedBy:          "me@google.com",
 		TaskSpecPatterns: []string{"a", "b"},
 		Commits:          []string{"abc123", "def456"},
 		Description:      "this is a rule",
 		Name:             "example",
+		Quarantine:       true,
 	}
 	assertdeep.Copy(t, r, r.Copy())
 }
 
+func TestQuarantineRules(t *testing.T) {
+	unittest.SmallTest(t)
+	taskSpec := "Test-Ubuntu18-Clang-Golo-GPU-QuadroP400-x86_64-Release-All-Vulkan"
+	r := NewQuarantineRule(taskSpec, "task-scheduler", "flaky", time.Now())
+	require.True(t, r.Quarantine)
+	require.Len(t, r.Name, MAX_NAME_CHARS)
+	require.NotEqual(t, r.Name, QuarantineRuleName(taskSpec+"-ASAN"))
+	require.True(t, r.Match(taskSpec, "abc123"))
+	require.False(t, r.Match(taskSpec+"-ASAN", "abc123"))
+
+	b := &Blacklist{
+		rules: map[string]*Rule{
+			r.Name: r,
+		},
+	}
+	require.Equal(t, "", b.MatchRule(taskSpec, "abc123"))
+	require.Equal(t, r.Name, b.MatchQuarantine(taskSpec, "abc123"))
+	require.Equal(t, "", b.MatchQuarantine("Build-Debian9-Clang-x86_64-Debug", "abc123"))
+}
+
 func TestRules(t *testing.T) {
 	unittest.SmallTest(t)
 	type testCase struct {
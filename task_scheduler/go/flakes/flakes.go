@@ -1,13 +1,30 @@
 package flakes
 
 /*
-   Find flakily-failed tasks in a time window.
+   Find flakily-failed tasks in a time window and quarantine flaky TaskSpecs.
 */
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.skia.org/infra/go/metrics2"
+	"go.skia.org/infra/go/sklog"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/task_scheduler/go/blacklist"
+	"go.skia.org/infra/task_scheduler/go/db"
 	"go.skia.org/infra/task_scheduler/go/types"
 )
 
+const (
+	// User name used for quarantine Rules added by the Quarantiner.
+	QUARANTINE_USER = "task-scheduler"
+
+	MEASUREMENT_QUARANTINED = "task_scheduler_quarantined_task_specs"
+)
+
 // Find flakily-failed tasks in the given slice of tasks.
 func FindFlakes(tasks []*types.Task) []*types.Task {
 	tasksMap := map[types.TaskKey][]*types.Task{}
@@ -42,3 +59,199 @@ func FindFlakes(tasks []*types.Task) []*types.Task {
 	}
 	return flaky
 }
+
+// Stats contains flake statistics for a single TaskSpec.
+type Stats struct {
+	// Number of finished tasks.
+	Runs int
+	// Number of flakily-failed tasks, as determined by FindFlakes.
+	Flakes int
+}
+
+// Rate returns the fraction of finished tasks which were flaky.
+func (s *Stats) Rate() float64 {
+	if s.Runs == 0 {
+		return 0.0
+	}
+	return float64(s.Flakes) / float64(s.Runs)
+}
+
+// FindStats returns flake statistics for each TaskSpec in the given slice of
+// tasks, keyed by TaskSpec name.
+func FindStats(tasks []*types.Task) map[string]*Stats {
+	rv := map[string]*Stats{}
+	get := func(name string) *Stats {
+		s, ok := rv[name]
+		if !ok {
+			s = &Stats{}
+			rv[name] = s
+		}
+		return s
+	}
+	for _, task := range tasks {
+		if task.Done() {
+			get(task.Name).Runs++
+		}
+	}
+	for _, task := range FindFlakes(tasks) {
+		get(task.Name).Flakes++
+	}
+	return rv
+}
+
+// QuarantineConfig determines when TaskSpecs are quarantined.
+type QuarantineConfig struct {
+	// Flake rates are computed over tasks created within this window.
+	Window time.Duration
+	// TaskSpecs whose flake rate is at least Threshold are quarantined.
+	Threshold float64
+	// TaskSpecs with fewer than MinRuns finished tasks in the window are
+	// not quarantined, to avoid acting on too little data.
+	MinRuns int
+	// Quarantine is lifted once this many consecutive tasks for the
+	// TaskSpec have succeeded since it was quarantined.
+	GreenRuns int
+}
+
+// consecutiveGreenRuns returns the number of most recent, consecutive,
+// finished non-try-job tasks for the given TaskSpec which were created after
+// the given time and succeeded. The tasks must be sorted by Created
+// timestamp.
+func consecutiveGreenRuns(tasks []*types.Task, name string, since time.Time) int {
+	green := 0
+	for i := len(tasks) - 1; i >= 0; i-- {
+		task := tasks[i]
+		if task.Created.Before(since) {
+			break
+		}
+		if task.Name != name || task.IsTryJob() || !task.Done() {
+			continue
+		}
+		if !task.Success() {
+			break
+		}
+		green++
+	}
+	return green
+}
+
+// FindQuarantineChanges returns the quarantine Rules which should be added and
+// the names of existing quarantine Rules which should be removed, based on the
+// given tasks, which must be sorted by Created timestamp, and the existing
+// Rules.
+func FindQuarantineChanges(tasks []*types.Task, rules []*blacklist.Rule, cfg QuarantineConfig, now time.Time) ([]*blacklist.Rule, []string) {
+	quarantined := map[string]*blacklist.Rule{}
+	for _, r := range rules {
+		if r.Quarantine {
+			quarantined[r.Name] = r
+		}
+	}
+
+	// Quarantine TaskSpecs whose flake rate exceeds the threshold. The flakes
+	// which caused a quarantine are still in the window after the quarantine
+	// is lifted, so TaskSpecs which are currently on a streak of GreenRuns
+	// successful runs are not quarantined (again) until they flake again.
+	stats := FindStats(tasks)
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	add := []*blacklist.Rule{}
+	for _, name := range names {
+		s := stats[name]
+		if _, ok := quarantined[blacklist.QuarantineRuleName(name)]; ok {
+			continue
+		}
+		if cfg.GreenRuns > 0 && consecutiveGreenRuns(tasks, name, time.Time{}) >= cfg.GreenRuns {
+			continue
+		}
+		if s.Runs >= cfg.MinRuns && s.Runs > 0 && s.Rate() >= cfg.Threshold {
+			desc := fmt.Sprintf("Automatically quarantined: %d of %d runs of %s in the last %s were flaky (%.0f%%, threshold %.0f%%). The quarantine is lifted after %d consecutive successful runs.", s.Flakes, s.Runs, name, cfg.Window, s.Rate()*100, cfg.Threshold*100, cfg.GreenRuns)
+			add = append(add, blacklist.NewQuarantineRule(name, QUARANTINE_USER, desc, now))
+		}
+	}
+
+	// Lift quarantine for TaskSpecs which have been green for long enough, or
+	// which have no tasks in the window at all, since there is no evidence
+	// left that they are flaky. Only Rules added by the Quarantiner are lifted
+	// automatically.
+	taskSpecs := map[string]string{}
+	for _, task := range tasks {
+		taskSpecs[blacklist.QuarantineRuleName(task.Name)] = task.Name
+	}
+	remove := []string{}
+	for _, r := range rules {
+		if !r.Quarantine || r.AddedBy != QUARANTINE_USER {
+			continue
+		}
+		name, ok := taskSpecs[r.Name]
+		if !ok || consecutiveGreenRuns(tasks, name, r.Added) >= cfg.GreenRuns {
+			remove = append(remove, r.Name)
+		}
+	}
+	sort.Strings(remove)
+	return add, remove
+}
+
+// Quarantiner periodically quarantines flaky TaskSpecs by adding quarantine
+// Rules to the Blacklist, and lifts the quarantine once they are green again.
+type Quarantiner struct {
+	bl  *blacklist.Blacklist
+	cfg QuarantineConfig
+	db  db.TaskReader
+}
+
+// NewQuarantiner returns a Quarantiner instance.
+func NewQuarantiner(d db.TaskReader, bl *blacklist.Blacklist, cfg QuarantineConfig) *Quarantiner {
+	return &Quarantiner{
+		bl:  bl,
+		cfg: cfg,
+		db:  d,
+	}
+}
+
+// Update loads the tasks in the configured window and adds and removes
+// quarantine Rules as needed.
+func (q *Quarantiner) Update(ctx context.Context, now time.Time) error {
+	defer metrics2.FuncTimer().Stop()
+
+	tasks, err := q.db.GetTasksFromDateRange(now.Add(-q.cfg.Window), now, "")
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve tasks: %s", err)
+	}
+	add, remove := FindQuarantineChanges(tasks, q.bl.GetRules(), q.cfg, now)
+	for _, r := range add {
+		sklog.Warningf("Quarantining %v: %s", r.TaskSpecPatterns, r.Description)
+		if err := q.bl.AddRule(r, nil); err != nil {
+			return fmt.Errorf("Failed to add quarantine rule: %s", err)
+		}
+	}
+	for _, name := range remove {
+		sklog.Infof("Lifting quarantine rule %s", name)
+		if err := q.bl.RemoveRule(name); err != nil {
+			return fmt.Errorf("Failed to remove quarantine rule: %s", err)
+		}
+	}
+	numQuarantined := 0
+	for _, r := range q.bl.GetRules() {
+		if r.Quarantine {
+			numQuarantined++
+		}
+	}
+	metrics2.GetInt64Metric(MEASUREMENT_QUARANTINED, nil).Update(int64(numQuarantined))
+	return nil
+}
+
+// Start periodically updates the quarantine Rules in a goroutine until the
+// given context is canceled.
+func (q *Quarantiner) Start(ctx context.Context, period time.Duration) {
+	lv := metrics2.NewLiveness("last_successful_flake_quarantine_update")
+	go util.RepeatCtx(period, ctx, func(ctx context.Context) {
+		if err := q.Update(ctx, time.Now()); err != nil {
+			sklog.Errorf("Failed to update flake quarantine: %s", err)
+		} else {
+			lv.Reset()
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package flakes
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/task_scheduler/go/blacklist"
+	"go.skia.org/infra/task_scheduler/go/types"
+)
+
+// makeTask returns a finished task for the given TaskSpec and commit.
+func makeTask(ts time.Time, name, commit string, status types.TaskStatus) *types.Task {
+	t := types.MakeTestTask(ts, []string{commit})
+	t.Name = name
+	t.Status = status
+	return t
+}
+
+func TestFindStats(t *testing.T) {
+	unittest.SmallTest(t)
+	now := time.Now()
+	tasks := []*types.Task{
+		// A failure followed by a success for the same commit is a flake.
+		makeTask(now, "Flaky", "a", types.TASK_STATUS_FAILURE),
+		makeTask(now, "Flaky", "a", types.TASK_STATUS_SUCCESS),
+		makeTask(now, "Flaky", "b", types.TASK_STATUS_SUCCESS),
+		// Mishaps are always flakes.
+		makeTask(now, "Flaky", "c", types.TASK_STATUS_MISHAP),
+		// A consistent failure is not a flake.
+		makeTask(now, "Broken", "a", types.TASK_STATUS_FAILURE),
+		makeTask(now, "Broken", "a", types.TASK_STATUS_FAILURE),
+		// Unfinished tasks aren't counted.
+		makeTask(now, "Broken", "b", types.TASK_STATUS_RUNNING),
+	}
+	stats := FindStats(tasks)
+	require.Equal(t, map[string]*Stats{
+		"Flaky":  {Runs: 4, Flakes: 2},
+		"Broken": {Runs: 2, Flakes: 0},
+	}, stats)
+	require.Equal(t, 0.5, stats["Flaky"].Rate())
+	require.Equal(t, 0.0, stats["Broken"].Rate())
+	require.Equal(t, 0.0, (&Stats{}).Rate())
+}
+
+func TestFindQuarantineChanges(t *testing.T) {
+	unittest.SmallTest(t)
+	now := time.Unix(1580000000, 0).UTC()
+	cfg := QuarantineConfig{
+		Window:    24 * time.Hour,
+		Threshold: 0.25,
+		MinRuns:   4,
+		GreenRuns: 3,
+	}
+
+	// Two TaskSpecs with the same flake rate; only one has enough runs.
+	tasks := []*types.Task{}
+	ts := now.Add(-10 * time.Hour)
+	for i := 0; i < 4; i++ {
+		commit := fmt.Sprintf("c%d", i)
+		ts = ts.Add(time.Minute)
+		tasks = append(tasks, makeTask(ts, "Flaky", commit, types.TASK_STATUS_SUCCESS))
+		if i%2 == 0 {
+			ts = ts.Add(time.Minute)
+			tasks = append(tasks, makeTask(ts, "Flaky", commit, types.TASK_STATUS_FAILURE))
+		}
+	}
+	ts = ts.Add(time.Minute)
+	tasks = append(tasks, makeTask(ts, "Rare", "c0", types.TASK_STATUS_MISHAP))
+	ts = ts.Add(time.Minute)
+	tasks = append(tasks, makeTask(ts, "Rare", "c0", types.TASK_STATUS_SUCCESS))
+
+	add, remove := FindQuarantineChanges(tasks, []*blacklist.Rule{}, cfg, now)
+	require.Len(t, add, 1)
+	require.Empty(t, remove)
+	rule := add[0]
+	require.True(t, rule.Quarantine)
+	require.Equal(t, blacklist.QuarantineRuleName("Flaky"), rule.Name)
+	require.Equal(t, QUARANTINE_USER, rule.AddedBy)
+	require.Equal(t, now, rule.Added)
+	require.True(t, rule.Match("Flaky", "c0"))
+	require.Contains(t, rule.Description, "2 of 6 runs of Flaky")
+
+	// Don't add the same rule twice.
+	add, remove = FindQuarantineChanges(tasks, []*blacklist.Rule{rule}, cfg, now)
+	require.Empty(t, add)
+	require.Empty(t, remove)
+
+	// The quarantine is lifted after enough consecutive green runs.
+	rule.Added = ts
+	rules := []*blacklist.Rule{rule}
+	for i := 0; i < cfg.GreenRuns; i++ {
+		add, remove = FindQuarantineChanges(tasks, rules, cfg, now)
+		require.Empty(t, add)
+		require.Empty(t, remove)
+		ts = ts.Add(time.Minute)
+		tasks = append(tasks, makeTask(ts, "Flaky", fmt.Sprintf("d%d", i), types.TASK_STATUS_SUCCESS))
+	}
+	add, remove = FindQuarantineChanges(tasks, rules, cfg, now)
+	require.Empty(t, add)
+	require.Equal(t, []string{rule.Name}, remove)
+
+	// A failure resets the count.
+	ts = ts.Add(time.Minute)
+	tasks = append(tasks, makeTask(ts, "Flaky", "e", types.TASK_STATUS_FAILURE))
+	add, remove = FindQuarantineChanges(tasks, rules, cfg, now)
+	require.Empty(t, add)
+	require.Empty(t, remove)
+
+	// Quarantine rules added by users are not lifted automatically.
+	rule.AddedBy = "me@google.com"
+	for i := 0; i < cfg.GreenRuns; i++ {
+		ts = ts.Add(time.Minute)
+		tasks = append(tasks, makeTask(ts, "Flaky", fmt.Sprintf("f%d", i), types.TASK_STATUS_SUCCESS))
+	}
+	add, remove = FindQuarantineChanges(tasks, rules, cfg, now)
+	require.Empty(t, add)
+	require.Empty(t, remove)
+}
+
+func TestFindQuarantineChanges_LiftedNotQuarantinedAgain(t *testing.T) {
+	unittest.SmallTest(t)
+	now := time.Unix(1580000000, 0).UTC()
+	cfg := QuarantineConfig{
+		Window:    24 * time.Hour,
+		Threshold: 0.25,
+		MinRuns:   4,
+		GreenRuns: 3,
+	}
+
+	// Flaky enough to be quarantined.
+	tasks := []*types.Task{}
+	ts := now.Add(-10 * time.Hour)
+	for i := 0; i < 2; i++ {
+		commit := fmt.Sprintf("c%d", i)
+		ts = ts.Add(time.Minute)
+		tasks = append(tasks, makeTask(ts, "Flaky", commit, types.TASK_STATUS_FAILURE))
+		ts = ts.Add(time.Minute)
+		tasks = append(tasks, makeTask(ts, "Flaky", commit, types.TASK_STATUS_SUCCESS))
+	}
+	add, _ := FindQuarantineChanges(tasks, []*blacklist.Rule{}, cfg, ts)
+	require.Len(t, add, 1)
+	rule := add[0]
+
+	// The quarantine is lifted after enough consecutive green runs.
+	for i := 0; i < cfg.GreenRuns; i++ {
+		ts = ts.Add(time.Minute)
+		tasks = append(tasks, makeTask(ts, "Flaky", fmt.Sprintf("d%d", i), types.TASK_STATUS_SUCCESS))
+	}
+	add, remove := FindQuarantineChanges(tasks, []*blacklist.Rule{rule}, cfg, now)
+	require.Empty(t, add)
+	require.Equal(t, []string{rule.Name}, remove)
+
+	// The flakes are still in the window, but the TaskSpec is not quarantined
+	// again while it stays green.
+	add, remove = FindQuarantineChanges(tasks, []*blacklist.Rule{}, cfg, now)
+	require.Empty(t, add)
+	require.Empty(t, remove)
+
+	// It is quarantined again once it flakes again.
+	ts = ts.Add(time.Minute)
+	tasks = append(tasks, makeTask(ts, "Flaky", "e", types.TASK_STATUS_FAILURE))
+	ts = ts.Add(time.Minute)
+	tasks = append(tasks, makeTask(ts, "Flaky", "e", types.TASK_STATUS_SUCCESS))
+	add, remove = FindQuarantineChanges(tasks, []*blacklist.Rule{}, cfg, now)
+	require.Len(t, add, 1)
+	require.Equal(t, rule.Name, add[0].Name)
+	require.Empty(t, remove)
+}
+
+func TestFindQuarantineChanges_NoRunsInWindow_Lifted(t *testing.T) {
+	unittest.SmallTest(t)
+	now := time.Unix(1580000000, 0).UTC()
+	cfg := QuarantineConfig{
+		Window:    24 * time.Hour,
+		Threshold: 0.25,
+		MinRuns:   4,
+		GreenRuns: 3,
+	}
+
+	added := now.Add(-48 * time.Hour)
+	auto := blacklist.NewQuarantineRule("Gone", QUARANTINE_USER, "flaky", added)
+	manual := blacklist.NewQuarantineRule("AlsoGone", "me@google.com", "flaky", added)
+	other := makeTask(now.Add(-time.Hour), "Other", "c0", types.TASK_STATUS_SUCCESS)
+
+	// Only the Rule added by the Quarantiner is lifted.
+	add, remove := FindQuarantineChanges([]*types.Task{other}, []*blacklist.Rule{auto, manual}, cfg, now)
+	require.Empty(t, add)
+	require.Equal(t, []string{auto.Name}, remove)
+}
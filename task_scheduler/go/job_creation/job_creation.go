@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"path"
+	"strings"
 	"time"
 
 	"go.skia.org/infra/go/common"
@@ -47,6 +49,14 @@ var (
 	}
 )
 
+const (
+	// Jobs with cron triggers are only triggered if their most recent
+	// scheduled time is within this window. This prevents us from
+	// triggering jobs which were scheduled long ago, eg. when a job is
+	// first added or after extended downtime.
+	CRON_LOOKBACK = 24 * time.Hour
+)
+
 // JobCreator is a struct used for creating Jobs based on new commits, tryjobs,
 // and timed triggers.
 type JobCreator struct {
@@ -210,6 +220,12 @@ func (jc *JobCreator) gatherNewJobs(ctx context.Context, repoUrl string, repo *r
 					} else if isAncestor {
 						shouldRun = true
 					}
+				} else if strings.HasPrefix(spec.Trigger, specs.TRIGGER_BRANCH_PREFIX) {
+					onBranch, err := isOnMatchingBranch(r, c, spec.BranchPattern())
+					if err != nil {
+						return err
+					}
+					shouldRun = onBranch
 				}
 			}
 			if shouldRun {
@@ -359,9 +375,34 @@ func (jc *JobCreator) initCaches(ctx context.Context) error {
 	return g.Wait()
 }
 
+// isOnMatchingBranch returns true iff the given commit is reachable from any
+// branch whose name matches the given glob pattern.
+func isOnMatchingBranch(r *repograph.Graph, c *repograph.Commit, pattern string) (bool, error) {
+	for _, branch := range r.Branches() {
+		match, err := path.Match(pattern, branch)
+		if err != nil {
+			return false, skerr.Wrapf(err, "invalid branch pattern %q", pattern)
+		}
+		if !match {
+			continue
+		}
+		isAncestor, err := r.IsAncestor(c.Hash, branch)
+		if err != nil {
+			return false, err
+		} else if isAncestor {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // MaybeTriggerPeriodicJobs triggers all periodic jobs with the given trigger
 // name, if those jobs haven't already been triggered.
 func (jc *JobCreator) MaybeTriggerPeriodicJobs(ctx context.Context, triggerName string) error {
+	if triggerName == specs.TRIGGER_CRON {
+		return jc.maybeTriggerCronJobs(ctx, time.Now())
+	}
+
 	// We'll search the jobs we've already triggered to ensure that we don't
 	// trigger the same jobs multiple times in a day/week/whatever. Search a
 	// window that is not quite the size of the trigger interval, to allow
@@ -451,3 +492,88 @@ func (jc *JobCreator) MaybeTriggerPeriodicJobs(ctx context.Context, triggerName
 	sklog.Infof("Created %d periodic jobs for trigger %q", len(jobs), triggerName)
 	return nil
 }
+
+// maybeTriggerCronJobs triggers all jobs with cron triggers whose most recent
+// scheduled time at or before the given time has not yet been triggered.
+func (jc *JobCreator) maybeTriggerCronJobs(ctx context.Context, now time.Time) error {
+	// Find the job specs whose schedule is due and create Job instances.
+	jobs := []*types.Job{}
+	scheduled := map[string]time.Time{}
+	start := now
+	for repoUrl, repo := range jc.repos {
+		master := repo.Get("master")
+		if master == nil {
+			return fmt.Errorf("Failed to retrieve branch 'master' for %s", repoUrl)
+		}
+		rs := types.RepoState{
+			Repo:     repoUrl,
+			Revision: master.Hash,
+		}
+		cfg, err := jc.taskCfgCache.Get(ctx, rs)
+		if err != nil {
+			return fmt.Errorf("Failed to retrieve TaskCfg from %s: %s", repoUrl, err)
+		}
+		for name, js := range cfg.Jobs {
+			sched, err := js.CronSchedule()
+			if err != nil {
+				sklog.Errorf("Invalid cron trigger for %s in %s: %s", name, repoUrl, err)
+				continue
+			} else if sched == nil {
+				continue
+			}
+			prev, ok := sched.Prev(now, CRON_LOOKBACK)
+			if !ok {
+				continue
+			}
+			job, err := jc.taskCfgCache.MakeJob(ctx, rs, name)
+			if err != nil {
+				return fmt.Errorf("Failed to create job: %s", err)
+			}
+			job.Requested = job.Created
+			jobs = append(jobs, job)
+			scheduled[name] = prev
+			if prev.Before(start) {
+				start = prev
+			}
+		}
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	// Filter out any jobs which we've already triggered since their most
+	// recent scheduled time.
+	names := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		names = append(names, job.Name)
+	}
+	existing, err := jc.jCache.GetMatchingJobsFromDateRange(names, start, now.Add(time.Minute))
+	if err != nil {
+		return err
+	}
+	jobsToInsert := make([]*types.Job, 0, len(jobs))
+	for _, job := range jobs {
+		var prev *types.Job = nil
+		for _, existingJob := range existing[job.Name] {
+			if !existingJob.IsTryJob() && !existingJob.IsForce && !existingJob.Created.Before(scheduled[job.Name]) {
+				prev = existingJob
+				break
+			}
+		}
+		if prev == nil {
+			jobsToInsert = append(jobsToInsert, job)
+		} else {
+			sklog.Debugf("Already triggered a job for %s at %s (eg. id %s at %s); not triggering again.", job.Name, scheduled[job.Name], prev.Id, prev.Created)
+		}
+	}
+	if len(jobsToInsert) == 0 {
+		return nil
+	}
+
+	// Insert the new jobs into the DB.
+	if err := jc.putJobsInChunks(jobsToInsert); err != nil {
+		return fmt.Errorf("Failed to add cron jobs: %s", err)
+	}
+	sklog.Infof("Created %d cron jobs", len(jobsToInsert))
+	return nil
+}
@@ -28,6 +28,7 @@ import (
 	"go.skia.org/infra/task_scheduler/go/specs"
 	"go.skia.org/infra/task_scheduler/go/task_cfg_cache"
 	tcc_testutils "go.skia.org/infra/task_scheduler/go/task_cfg_cache/testutils"
+	"go.skia.org/infra/task_scheduler/go/task_executor"
 	swarming_testutils "go.skia.org/infra/task_scheduler/go/testutils"
 	"go.skia.org/infra/task_scheduler/go/tryjobs"
 	"go.skia.org/infra/task_scheduler/go/types"
@@ -170,6 +171,29 @@ func TestGatherNewJobs(t *testing.T) {
 	gb.CommitMsgAt(ctx, "abcd", time.Now())
 	updateRepos(t, ctx, jc)
 	testGatherNewJobs(72)
+
+	// Mark the same jobs to only run on release branches. Ensure that we
+	// don't pick them up on the non-master branch.
+	for name, jobSpec := range cfg.Jobs {
+		if name != tcc_testutils.BuildTaskName {
+			jobSpec.Trigger = specs.TRIGGER_BRANCH_PREFIX + "release/*"
+		}
+	}
+	cfgBytes, err = specs.EncodeTasksCfg(cfg)
+	require.NoError(t, err)
+	gb.Add(ctx, "infra/bots/tasks.json", string(cfgBytes))
+	gb.CommitMsgAt(ctx, "efgh", time.Now())
+	updateRepos(t, ctx, jc)
+	testGatherNewJobs(73)
+
+	// Create a release branch from master. Ensure that we pick up all of
+	// the jobs for the new commit.
+	gb.CheckoutBranch(ctx, "master")
+	gb.CreateBranchTrackBranch(ctx, "release/m80", "master")
+	gb.Add(ctx, "infra/bots/tasks.json", string(cfgBytes))
+	gb.CommitMsgAt(ctx, "ijkl", time.Now())
+	updateRepos(t, ctx, jc)
+	testGatherNewJobs(76)
 }
 
 func TestPeriodicJobs(t *testing.T) {
@@ -264,6 +288,93 @@ func TestPeriodicJobs(t *testing.T) {
 	require.Equal(t, weeklyName, jobs[weeklyName][0].Name)
 }
 
+func TestCronJobs(t *testing.T) {
+	ctx, gb, _, jc, _, cleanup := setup(t)
+	defer cleanup()
+
+	// Rewrite tasks.json with cron jobs.
+	dailyName := "Daily-Job"
+	neverName := "Never-Job"
+	names := []string{dailyName, neverName}
+	taskName := "Periodic-Task"
+	cfg := &specs.TasksCfg{
+		Jobs: map[string]*specs.JobSpec{
+			dailyName: {
+				Priority:  1.0,
+				TaskSpecs: []string{taskName},
+				Trigger:   specs.TRIGGER_CRON_PREFIX + "0 3 * * *",
+			},
+			neverName: {
+				Priority:  1.0,
+				TaskSpecs: []string{taskName},
+				Trigger:   specs.TRIGGER_CRON_PREFIX + "0 0 30 2 *",
+			},
+		},
+		Tasks: map[string]*specs.TaskSpec{
+			taskName: {
+				CipdPackages: []*specs.CipdPackage{},
+				Dependencies: []string{},
+				Dimensions: []string{
+					"pool:Skia",
+					"os:Mac",
+					"gpu:my-gpu",
+				},
+				ExecutionTimeout: 40 * time.Minute,
+				Expiration:       2 * time.Hour,
+				IoTimeout:        3 * time.Minute,
+				Isolate:          "compile_skia.isolate",
+				Priority:         1.0,
+			},
+		},
+	}
+	gb.Add(ctx, specs.TASKS_CFG_FILE, testutils.MarshalJSON(t, &cfg))
+	gb.Commit(ctx)
+	updateRepos(t, ctx, jc)
+
+	// Trigger the cron jobs. Make sure that we inserted the new Job for
+	// the daily schedule, which is always due within the lookback window.
+	require.NoError(t, jc.MaybeTriggerPeriodicJobs(ctx, specs.TRIGGER_CRON))
+	require.NoError(t, jc.jCache.Update())
+	start := time.Now().Add(-10 * time.Minute)
+	end := time.Now().Add(10 * time.Minute)
+	jobs, err := jc.jCache.GetMatchingJobsFromDateRange(names, start, end)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(jobs[dailyName]))
+	require.Equal(t, dailyName, jobs[dailyName][0].Name)
+	require.Equal(t, 0, len(jobs[neverName]))
+
+	// Ensure that we don't trigger another.
+	require.NoError(t, jc.MaybeTriggerPeriodicJobs(ctx, specs.TRIGGER_CRON))
+	require.NoError(t, jc.jCache.Update())
+	jobs, err = jc.jCache.GetMatchingJobsFromDateRange(names, start, end)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(jobs[dailyName]))
+	require.Equal(t, 0, len(jobs[neverName]))
+
+	// Hack the old Job's created time to simulate it having been
+	// triggered for the previous scheduled time.
+	oldJob := jobs[dailyName][0]
+	oldJob.Created = start.Add(-24 * time.Hour)
+	require.NoError(t, jc.db.PutJob(oldJob))
+	jc.jCache.AddJobs([]*types.Job{oldJob})
+	require.NoError(t, jc.jCache.Update())
+	require.NoError(t, jc.MaybeTriggerPeriodicJobs(ctx, specs.TRIGGER_CRON))
+	require.NoError(t, jc.jCache.Update())
+	jobs, err = jc.jCache.GetMatchingJobsFromDateRange(names, start, end)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(jobs[dailyName]))
+	require.Equal(t, dailyName, jobs[dailyName][0].Name)
+	require.Equal(t, 0, len(jobs[neverName]))
+
+	// Make sure we don't confuse cron jobs with other periodic triggers.
+	require.NoError(t, jc.MaybeTriggerPeriodicJobs(ctx, specs.TRIGGER_NIGHTLY))
+	require.NoError(t, jc.jCache.Update())
+	jobs, err = jc.jCache.GetMatchingJobsFromDateRange(names, start, end)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(jobs[dailyName]))
+	require.Equal(t, 0, len(jobs[neverName]))
+}
+
 func TestTaskSchedulerIntegration(t *testing.T) {
 	unittest.LargeTest(t)
 
@@ -281,7 +392,7 @@ func TestTaskSchedulerIntegration(t *testing.T) {
 	swarmingClient := swarming_testutils.NewTestClient()
 	urlMock := mockhttpclient.NewURLMock()
 
-	ts, err := scheduling.NewTaskScheduler(ctx, d, nil, time.Duration(math.MaxInt64), 0, jc.repos, isolateClient, swarmingClient, urlMock.Client(), 1.0, swarming.POOLS_PUBLIC, "", jc.taskCfgCache, jc.isolateCache, nil, mem_gcsclient.New("fake"), "testing")
+	ts, err := scheduling.NewTaskScheduler(ctx, d, nil, time.Duration(math.MaxInt64), 0, jc.repos, task_executor.NewSwarmingExecutor(swarmingClient, isolateClient), urlMock.Client(), 1.0, swarming.POOLS_PUBLIC, "", jc.taskCfgCache, jc.isolateCache, nil, mem_gcsclient.New("fake"), "testing")
 	require.NoError(t, err)
 
 	jc.Start(ctx, false)
@@ -7,6 +7,7 @@ package main
 import (
 	"flag"
 	"io/ioutil"
+	"time"
 
 	"go.skia.org/infra/go/common"
 	"go.skia.org/infra/go/sklog"
@@ -32,4 +33,17 @@ func main() {
 	for name, t := range cfg.Tasks {
 		sklog.Infof("  %s: %v", name, t)
 	}
+	sklog.Infof("Jobs:")
+	now := time.Now()
+	for name, j := range cfg.Jobs {
+		if sched, err := j.CronSchedule(); err != nil {
+			sklog.Fatal(err)
+		} else if sched != nil {
+			sklog.Infof("  %s: trigger %q; next at %s", name, j.Trigger, sched.Next(now))
+		} else if j.Trigger != "" {
+			sklog.Infof("  %s: trigger %q", name, j.Trigger)
+		} else {
+			sklog.Infof("  %s", name)
+		}
+	}
 }
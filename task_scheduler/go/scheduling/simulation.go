@@ -0,0 +1,644 @@
+package scheduling
+
+/*
+	Simulation of the Task Scheduler against a fixed bot fleet, used to
+	estimate the effects of scheduling changes before rolling them out.
+*/
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	swarming_api "go.chromium.org/luci/common/api/swarming/swarming/v1"
+	"go.skia.org/infra/go/util"
+	"go.skia.org/infra/task_scheduler/go/specs"
+	"go.skia.org/infra/task_scheduler/go/task_executor"
+	"go.skia.org/infra/task_scheduler/go/types"
+)
+
+// SimulatedCommit is a commit which lands during a simulation.
+type SimulatedCommit struct {
+	Hash      string
+	Timestamp time.Time
+}
+
+// SimulationConfig describes a simulation of the Task Scheduler.
+//
+// The simulation replays the given commits on a linear history, creates Jobs
+// for every commit as the JobCreator would, and schedules tasks on the given
+// bots using the same scoring as the TaskScheduler. All tasks are assumed to
+// succeed. Commits which landed before Start are considered to be tested.
+type SimulationConfig struct {
+	// Bots in the simulated fleet. Each bot runs one task at a time.
+	Bots []*task_executor.LocalBot
+
+	// Commits which land during the simulation, in chronological order.
+	Commits []*SimulatedCommit
+
+	// Duration of tasks whose TaskSpec is not in Durations.
+	DefaultDuration time.Duration
+
+	// Duration of tasks, keyed by TaskSpec name. See TaskDurations.
+	Durations map[string]time.Duration
+
+	// Start and end of the simulated time period.
+	End   time.Time
+	Start time.Time
+
+	// Overrides for JobSpec priorities, keyed by JobSpec name.
+	JobPriorities map[string]float64
+
+	// How often the simulated scheduler runs.
+	Period time.Duration
+
+	// Repo used in TaskKeys.
+	Repo string
+
+	// Tasks and Jobs to simulate. Periodic and cron Jobs are ignored.
+	TasksCfg *specs.TasksCfg
+
+	// Desired time decay of candidate scores after 24 hours; see
+	// NewTaskScheduler.
+	TimeDecayAmt24Hr float64
+
+	// Scheduling window; commits older than this are not scheduled.
+	Window time.Duration
+}
+
+// DistributionStats summarizes a set of values.
+type DistributionStats struct {
+	Count int
+	Mean  float64
+	P50   float64
+	P90   float64
+	Max   float64
+}
+
+// newDistributionStats returns DistributionStats for the given values.
+func newDistributionStats(values []float64) DistributionStats {
+	rv := DistributionStats{
+		Count: len(values),
+	}
+	if len(values) == 0 {
+		return rv
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	percentile := func(p float64) float64 {
+		idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		return sorted[idx]
+	}
+	rv.Mean = sum / float64(len(sorted))
+	rv.P50 = percentile(0.5)
+	rv.P90 = percentile(0.9)
+	rv.Max = sorted[len(sorted)-1]
+	return rv
+}
+
+// SimulationResult contains the results of a simulation.
+type SimulationResult struct {
+	// Lengths of the blamelists of all tasks at the end of the simulation.
+	BlamelistLength DistributionStats
+
+	// Fraction of the simulated time each bot spent running tasks, keyed
+	// by bot ID.
+	BotUtilization map[string]float64
+
+	// Number of candidates which were ready to run at the end of the
+	// simulation.
+	QueueLengthAtEnd int
+
+	// Time in minutes between a task becoming ready to run, ie. its
+	// commit landed and its dependencies finished, and the task starting.
+	QueueLatencyMinutes DistributionStats
+
+	// Number of tasks triggered, keyed by TaskSpec name.
+	TasksBySpec map[string]int
+
+	// Number of commit and TaskSpec pairs which were not covered by any
+	// task at the end of the simulation.
+	UntestedCommits int
+
+	// TaskSpecs which no bot in the fleet can run.
+	UnrunnableSpecs []string
+}
+
+// NumTasks returns the total number of tasks triggered in the simulation.
+func (r *SimulationResult) NumTasks() int {
+	rv := 0
+	for _, n := range r.TasksBySpec {
+		rv += n
+	}
+	return rv
+}
+
+// MeanBotUtilization returns the mean utilization across all bots.
+func (r *SimulationResult) MeanBotUtilization() float64 {
+	if len(r.BotUtilization) == 0 {
+		return 0.0
+	}
+	sum := 0.0
+	for _, u := range r.BotUtilization {
+		sum += u
+	}
+	return sum / float64(len(r.BotUtilization))
+}
+
+// TaskDurations returns the median duration of successful tasks for each
+// TaskSpec in the given tasks, for use as SimulationConfig.Durations.
+func TaskDurations(tasks []*types.Task) map[string]time.Duration {
+	byName := map[string][]time.Duration{}
+	for _, t := range tasks {
+		if !t.Done() || !t.Success() || util.TimeIsZero(t.Started) || util.TimeIsZero(t.Finished) {
+			continue
+		}
+		byName[t.Name] = append(byName[t.Name], t.Finished.Sub(t.Started))
+	}
+	rv := make(map[string]time.Duration, len(byName))
+	for name, durations := range byName {
+		sort.Slice(durations, func(i, j int) bool {
+			return durations[i] < durations[j]
+		})
+		rv[name] = durations[len(durations)/2]
+	}
+	return rv
+}
+
+// simTask is a task in a simulation. Its blamelist consists of the commits
+// with indexes [first, rev].
+type simTask struct {
+	bot      string
+	first    int
+	finished time.Time
+	name     string
+	ready    time.Time
+	rev      int
+	started  time.Time
+}
+
+// simulation holds the state of a running simulation.
+type simulation struct {
+	cfg *SimulationConfig
+
+	// Bots.
+	botInfos     map[string]*swarming_api.SwarmingRpcsBotInfo
+	busyUntil    map[string]time.Time
+	matchingBots map[string][]string
+
+	// TaskSpecs to schedule, sorted by name, and their priorities.
+	names      []string
+	priorities map[string]float64
+
+	// Number of commits which have landed.
+	head int
+
+	// Per-TaskSpec state, indexed by commit. cover contains the index of
+	// the task whose blamelist includes the commit, or -1. taskAt
+	// contains the index of the task which ran at the commit, or -1.
+	cover  map[string][]int
+	taskAt map[string][]int
+
+	tasks []*simTask
+}
+
+// Simulate runs a simulation of the Task Scheduler.
+func Simulate(cfg *SimulationConfig) (*SimulationResult, error) {
+	if cfg.Period <= 0 {
+		return nil, fmt.Errorf("Period must be positive.")
+	}
+	if !cfg.Start.Before(cfg.End) {
+		return nil, fmt.Errorf("Start must be before End.")
+	}
+	if len(cfg.Bots) == 0 {
+		return nil, fmt.Errorf("At least one bot is required.")
+	}
+	s := &simulation{
+		cfg:          cfg,
+		botInfos:     make(map[string]*swarming_api.SwarmingRpcsBotInfo, len(cfg.Bots)),
+		busyUntil:    make(map[string]time.Time, len(cfg.Bots)),
+		matchingBots: map[string][]string{},
+		priorities:   map[string]float64{},
+		cover:        map[string][]int{},
+		taskAt:       map[string][]int{},
+	}
+	for _, b := range cfg.Bots {
+		dims := make([]*swarming_api.SwarmingRpcsStringListPair, 0, len(b.Dimensions))
+		for k, v := range b.Dimensions {
+			dims = append(dims, &swarming_api.SwarmingRpcsStringListPair{
+				Key:   k,
+				Value: util.CopyStringSlice(v),
+			})
+		}
+		s.botInfos[b.Id] = &swarming_api.SwarmingRpcsBotInfo{
+			BotId:      b.Id,
+			Dimensions: dims,
+		}
+	}
+	if err := s.findTaskSpecs(); err != nil {
+		return nil, err
+	}
+	return s.run(), nil
+}
+
+// findTaskSpecs determines which TaskSpecs need to run at every commit, their
+// priorities, and the bots which can run them.
+func (s *simulation) findTaskSpecs() error {
+	cfg := s.cfg.TasksCfg
+	// 1 - priority; see processTaskCandidate.
+	inversePriorityProduct := map[string]float64{}
+	jobNames := make([]string, 0, len(cfg.Jobs))
+	for name := range cfg.Jobs {
+		jobNames = append(jobNames, name)
+	}
+	sort.Strings(jobNames)
+	for _, jobName := range jobNames {
+		js := cfg.Jobs[jobName]
+		if js.Trigger != specs.TRIGGER_ANY_BRANCH && js.Trigger != specs.TRIGGER_MASTER_ONLY {
+			continue
+		}
+		priority := specs.DEFAULT_JOB_SPEC_PRIORITY
+		if js.Priority <= 1 && js.Priority > 0 {
+			priority = js.Priority
+		}
+		if p, ok := s.cfg.JobPriorities[jobName]; ok {
+			priority = p
+		}
+		visited := map[string]bool{}
+		var visit func(string) error
+		visit = func(name string) error {
+			if visited[name] {
+				return nil
+			}
+			visited[name] = true
+			ts, ok := cfg.Tasks[name]
+			if !ok {
+				return fmt.Errorf("Job %s depends on unknown task %s", jobName, name)
+			}
+			if _, ok := inversePriorityProduct[name]; !ok {
+				inversePriorityProduct[name] = 1.0
+			}
+			inversePriorityProduct[name] *= 1 - priority
+			for _, dep := range ts.Dependencies {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for _, name := range js.TaskSpecs {
+			if err := visit(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	botIds := make([]string, 0, len(s.botInfos))
+	for id := range s.botInfos {
+		botIds = append(botIds, id)
+	}
+	sort.Strings(botIds)
+	for name, inv := range inversePriorityProduct {
+		s.names = append(s.names, name)
+		s.priorities[name] = 1 - inv
+		cover := make([]int, len(s.cfg.Commits))
+		taskAt := make([]int, len(s.cfg.Commits))
+		for i := range cover {
+			cover[i] = -1
+			taskAt[i] = -1
+		}
+		s.cover[name] = cover
+		s.taskAt[name] = taskAt
+		for _, id := range botIds {
+			if botMatchesTaskSpec(s.botInfos[id], cfg.Tasks[name]) {
+				s.matchingBots[name] = append(s.matchingBots[name], id)
+			}
+		}
+	}
+	sort.Strings(s.names)
+	return nil
+}
+
+// botMatchesTaskSpec returns true iff the bot has all of the dimensions
+// required by the TaskSpec.
+func botMatchesTaskSpec(bot *swarming_api.SwarmingRpcsBotInfo, ts *specs.TaskSpec) bool {
+	if len(ts.Dimensions) == 0 {
+		return false
+	}
+	for _, d := range ts.Dimensions {
+		split := strings.SplitN(d, ":", 2)
+		if len(split) != 2 {
+			return false
+		}
+		found := false
+		for _, dim := range bot.Dimensions {
+			if dim.Key == split[0] && util.In(split[1], dim.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// duration returns the duration of tasks for the given TaskSpec.
+func (s *simulation) duration(name string) time.Duration {
+	if d, ok := s.cfg.Durations[name]; ok {
+		return d
+	}
+	return s.cfg.DefaultDuration
+}
+
+// readyTime returns the time at which a task for the given TaskSpec at the
+// given commit is ready to run, and false if it is not ready at the given
+// time.
+func (s *simulation) readyTime(name string, idx int, now time.Time) (time.Time, bool) {
+	rv := s.cfg.Commits[idx].Timestamp
+	for _, dep := range s.cfg.TasksCfg.Tasks[name].Dependencies {
+		taskIdx := s.taskAt[dep][idx]
+		if taskIdx < 0 {
+			return time.Time{}, false
+		}
+		t := s.tasks[taskIdx]
+		if t.finished.After(now) {
+			return time.Time{}, false
+		}
+		if t.finished.After(rv) {
+			rv = t.finished
+		}
+	}
+	return rv, true
+}
+
+// blamelist returns the index of the first commit in the blamelist of a task
+// for the given TaskSpec at the given commit, and the length of the
+// blamelist of the task it would steal commits from, if any. This mirrors
+// ComputeBlamelist for a linear history.
+func (s *simulation) blamelist(name string, idx, windowStart int) (int, int) {
+	cover := s.cover[name]
+	if prev := cover[idx]; prev >= 0 {
+		// This is a bisect; steal commits from the previous task.
+		t := s.tasks[prev]
+		return t.first, t.rev - t.first + 1
+	}
+	first := idx
+	for first > windowStart && cover[first-1] < 0 {
+		first--
+		if idx-first+1 > MAX_BLAMELIST_COMMITS {
+			return idx, 0
+		}
+	}
+	return first, 0
+}
+
+// run runs the simulation.
+func (s *simulation) run() *SimulationResult {
+	dirty := true
+	for now := s.cfg.Start; !now.After(s.cfg.End); now = now.Add(s.cfg.Period) {
+		// Land new commits.
+		for s.head < len(s.cfg.Commits) && !s.cfg.Commits[s.head].Timestamp.After(now) {
+			s.head++
+			dirty = true
+		}
+		// Free up bots whose tasks have finished.
+		free := util.StringSet{}
+		for id := range s.botInfos {
+			until, ok := s.busyUntil[id]
+			if ok && !until.After(now) {
+				delete(s.busyUntil, id)
+				dirty = true
+				ok = false
+			}
+			if !ok {
+				free[id] = true
+			}
+		}
+		if !dirty || len(free) == 0 {
+			continue
+		}
+		s.schedule(now, free)
+		dirty = false
+	}
+	return s.results()
+}
+
+// windowStart returns the index of the first commit in the scheduling window.
+func (s *simulation) windowStart(now time.Time) int {
+	if s.cfg.Window <= 0 {
+		return 0
+	}
+	start := now.Add(-s.cfg.Window)
+	return sort.Search(s.head, func(i int) bool {
+		return !s.cfg.Commits[i].Timestamp.Before(start)
+	})
+}
+
+// candidates returns the scored candidates which are ready to run at the
+// given time. If free is not nil, only candidates which can run on the given
+// bots are returned.
+func (s *simulation) candidates(now time.Time, free util.StringSet) []*taskCandidate {
+	windowStart := s.windowStart(now)
+	rv := []*taskCandidate{}
+	for _, name := range s.names {
+		if free != nil {
+			canRun := false
+			for _, id := range s.matchingBots[name] {
+				if free[id] {
+					canRun = true
+					break
+				}
+			}
+			if !canRun {
+				continue
+			}
+		}
+		for idx := windowStart; idx < s.head; idx++ {
+			if s.taskAt[name][idx] >= 0 {
+				continue
+			}
+			if _, ok := s.readyTime(name, idx, now); !ok {
+				continue
+			}
+			first, stoleFrom := s.blamelist(name, idx, windowStart)
+			score := testednessIncrease(idx-first+1, stoleFrom)
+			if s.cfg.TimeDecayAmt24Hr != 1.0 {
+				score *= timeDecay24Hr(s.cfg.TimeDecayAmt24Hr, now.Sub(s.cfg.Commits[idx].Timestamp))
+			}
+			score *= s.priorities[name]
+			rv = append(rv, &taskCandidate{
+				Score: score,
+				TaskKey: types.TaskKey{
+					RepoState: types.RepoState{
+						Repo:     s.cfg.Repo,
+						Revision: s.cfg.Commits[idx].Hash,
+					},
+					Name: name,
+				},
+				TaskSpec: s.cfg.TasksCfg.Tasks[name],
+			})
+		}
+	}
+	sort.Sort(taskCandidateSlice(rv))
+	return rv
+}
+
+// schedule triggers tasks on the given free bots.
+func (s *simulation) schedule(now time.Time, free util.StringSet) {
+	bots := make([]*swarming_api.SwarmingRpcsBotInfo, 0, len(free))
+	for id := range free {
+		bots = append(bots, s.botInfos[id])
+	}
+	commitIdx := make(map[string]int, s.head)
+	for i := 0; i < s.head; i++ {
+		commitIdx[s.cfg.Commits[i].Hash] = i
+	}
+	windowStart := s.windowStart(now)
+	candidates, chosenBots := matchCandidatesToBots(bots, s.candidates(now, free))
+	for _, c := range candidates {
+		idx := commitIdx[c.Revision]
+		// Use the bot which the scheduler chose for this candidate, so
+		// that we don't diverge from the real bot assignment.
+		bot := chosenBots[c]
+		delete(free, bot)
+
+		// Insert the task, stealing commits from a previous task if
+		// necessary.
+		first, _ := s.blamelist(c.Name, idx, windowStart)
+		cover := s.cover[c.Name]
+		if prev := cover[idx]; prev >= 0 {
+			s.tasks[prev].first = idx + 1
+		}
+		ready, _ := s.readyTime(c.Name, idx, now)
+		t := &simTask{
+			bot:      bot,
+			first:    first,
+			finished: now.Add(s.duration(c.Name)),
+			name:     c.Name,
+			ready:    ready,
+			rev:      idx,
+			started:  now,
+		}
+		s.tasks = append(s.tasks, t)
+		taskIdx := len(s.tasks) - 1
+		for i := first; i <= idx; i++ {
+			cover[i] = taskIdx
+		}
+		s.taskAt[c.Name][idx] = taskIdx
+		s.busyUntil[bot] = t.finished
+	}
+}
+
+// results computes the results of the simulation.
+func (s *simulation) results() *SimulationResult {
+	rv := &SimulationResult{
+		BotUtilization:  make(map[string]float64, len(s.botInfos)),
+		TasksBySpec:     map[string]int{},
+		UnrunnableSpecs: []string{},
+	}
+	total := s.cfg.End.Sub(s.cfg.Start)
+	busy := map[string]time.Duration{}
+	blamelists := make([]float64, 0, len(s.tasks))
+	latencies := make([]float64, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		rv.TasksBySpec[t.name]++
+		blamelists = append(blamelists, float64(t.rev-t.first+1))
+		latencies = append(latencies, t.started.Sub(t.ready).Minutes())
+		end := t.finished
+		if end.After(s.cfg.End) {
+			end = s.cfg.End
+		}
+		busy[t.bot] += end.Sub(t.started)
+	}
+	for id := range s.botInfos {
+		rv.BotUtilization[id] = float64(busy[id]) / float64(total)
+	}
+	rv.BlamelistLength = newDistributionStats(blamelists)
+	rv.QueueLatencyMinutes = newDistributionStats(latencies)
+	for _, name := range s.names {
+		if len(s.matchingBots[name]) == 0 {
+			rv.UnrunnableSpecs = append(rv.UnrunnableSpecs, name)
+		}
+		for i := 0; i < s.head; i++ {
+			if s.cover[name][i] < 0 {
+				rv.UntestedCommits++
+			}
+		}
+	}
+	rv.QueueLengthAtEnd = len(s.candidates(s.cfg.End, nil))
+	return rv
+}
+
+// WriteSimulationResults writes a human-readable summary of the given
+// simulation results to the given writer, one column per result.
+func WriteSimulationResults(w io.Writer, labels []string, results []*SimulationResult) error {
+	if len(labels) != len(results) {
+		return fmt.Errorf("Got %d labels for %d results.", len(labels), len(results))
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	row := func(name string, fn func(*SimulationResult) string) {
+		cols := []string{name}
+		for _, r := range results {
+			cols = append(cols, fn(r))
+		}
+		_, _ = fmt.Fprintln(tw, strings.Join(cols, "\t"))
+	}
+	row("", func(r *SimulationResult) string {
+		for i, other := range results {
+			if other == r {
+				return labels[i]
+			}
+		}
+		return ""
+	})
+	row("Tasks", func(r *SimulationResult) string {
+		return fmt.Sprintf("%d", r.NumTasks())
+	})
+	row("Mean bot utilization", func(r *SimulationResult) string {
+		return fmt.Sprintf("%.1f%%", 100*r.MeanBotUtilization())
+	})
+	dist := func(name string, fn func(*SimulationResult) DistributionStats) {
+		row(name+" (mean)", func(r *SimulationResult) string {
+			return fmt.Sprintf("%.1f", fn(r).Mean)
+		})
+		row(name+" (p50)", func(r *SimulationResult) string {
+			return fmt.Sprintf("%.1f", fn(r).P50)
+		})
+		row(name+" (p90)", func(r *SimulationResult) string {
+			return fmt.Sprintf("%.1f", fn(r).P90)
+		})
+		row(name+" (max)", func(r *SimulationResult) string {
+			return fmt.Sprintf("%.1f", fn(r).Max)
+		})
+	}
+	dist("Queue latency in minutes", func(r *SimulationResult) DistributionStats {
+		return r.QueueLatencyMinutes
+	})
+	dist("Blamelist length", func(r *SimulationResult) DistributionStats {
+		return r.BlamelistLength
+	})
+	row("Untested commits", func(r *SimulationResult) string {
+		return fmt.Sprintf("%d", r.UntestedCommits)
+	})
+	row("Queue length at end", func(r *SimulationResult) string {
+		return fmt.Sprintf("%d", r.QueueLengthAtEnd)
+	})
+	row("Unrunnable TaskSpecs", func(r *SimulationResult) string {
+		return fmt.Sprintf("%d", len(r.UnrunnableSpecs))
+	})
+	return tw.Flush()
+}
@@ -0,0 +1,210 @@
+package scheduling
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/task_scheduler/go/specs"
+	"go.skia.org/infra/task_scheduler/go/task_executor"
+	"go.skia.org/infra/task_scheduler/go/types"
+)
+
+// simulationConfig returns a SimulationConfig with the given number of
+// commits, all of which land at the start of the simulation.
+func simulationConfig(numCommits int) *SimulationConfig {
+	start := time.Unix(1580000000, 0).UTC()
+	commits := make([]*SimulatedCommit, 0, numCommits)
+	for i := 0; i < numCommits; i++ {
+		commits = append(commits, &SimulatedCommit{
+			Hash:      fmt.Sprintf("c%d", i),
+			Timestamp: start,
+		})
+	}
+	return &SimulationConfig{
+		Bots: []*task_executor.LocalBot{{
+			Id: "bot1",
+			Dimensions: map[string][]string{
+				"pool": {"Skia"},
+				"os":   {"Linux"},
+			},
+		}},
+		Commits:         commits,
+		DefaultDuration: 10 * time.Minute,
+		End:             start.Add(time.Hour),
+		Period:          time.Minute,
+		Repo:            "fake.git",
+		Start:           start,
+		TasksCfg: &specs.TasksCfg{
+			Tasks: map[string]*specs.TaskSpec{
+				"Build": {
+					Dimensions: []string{"pool:Skia", "os:Linux"},
+				},
+			},
+			Jobs: map[string]*specs.JobSpec{
+				"Build": {
+					TaskSpecs: []string{"Build"},
+				},
+			},
+		},
+		TimeDecayAmt24Hr: 1.0,
+	}
+}
+
+func TestSimulateBisect(t *testing.T) {
+	unittest.SmallTest(t)
+	cfg := simulationConfig(3)
+	res, err := Simulate(cfg)
+	require.NoError(t, err)
+
+	// The first task covers all three commits. The remaining commits are
+	// bisected, one task at a time.
+	require.Equal(t, map[string]int{"Build": 3}, res.TasksBySpec)
+	require.Equal(t, 3, res.NumTasks())
+	require.Equal(t, 0, res.UntestedCommits)
+	require.Equal(t, 0, res.QueueLengthAtEnd)
+	require.Empty(t, res.UnrunnableSpecs)
+	require.Equal(t, DistributionStats{
+		Count: 3,
+		Mean:  1,
+		P50:   1,
+		P90:   1,
+		Max:   1,
+	}, res.BlamelistLength)
+	require.Equal(t, DistributionStats{
+		Count: 3,
+		Mean:  10,
+		P50:   10,
+		P90:   20,
+		Max:   20,
+	}, res.QueueLatencyMinutes)
+	require.Equal(t, map[string]float64{"bot1": 0.5}, res.BotUtilization)
+	require.Equal(t, 0.5, res.MeanBotUtilization())
+
+	// A second bot halves the queue latency.
+	cfg.Bots = append(cfg.Bots, &task_executor.LocalBot{
+		Id:         "bot2",
+		Dimensions: cfg.Bots[0].Dimensions,
+	})
+	res2, err := Simulate(cfg)
+	require.NoError(t, err)
+	require.Equal(t, 3, res2.NumTasks())
+	require.True(t, res2.QueueLatencyMinutes.Mean < res.QueueLatencyMinutes.Mean)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSimulationResults(&buf, []string{"one bot", "two bots"}, []*SimulationResult{res, res2}))
+	require.Contains(t, buf.String(), "two bots")
+	require.Contains(t, buf.String(), "Queue latency in minutes (mean)")
+	require.Error(t, WriteSimulationResults(&buf, []string{"one bot"}, []*SimulationResult{res, res2}))
+}
+
+func TestSimulateDependencies(t *testing.T) {
+	unittest.SmallTest(t)
+	cfg := simulationConfig(1)
+	cfg.TasksCfg.Tasks["Test"] = &specs.TaskSpec{
+		Dependencies: []string{"Build"},
+		Dimensions:   []string{"pool:Skia", "os:Linux"},
+	}
+	cfg.TasksCfg.Tasks["Perf"] = &specs.TaskSpec{
+		Dimensions: []string{"pool:Skia", "os:Android"},
+	}
+	cfg.TasksCfg.Jobs["Test"] = &specs.JobSpec{
+		TaskSpecs: []string{"Test"},
+	}
+	cfg.TasksCfg.Jobs["Perf"] = &specs.JobSpec{
+		TaskSpecs: []string{"Perf"},
+	}
+	// Periodic Jobs are not simulated.
+	cfg.TasksCfg.Tasks["Nightly"] = &specs.TaskSpec{
+		Dimensions: []string{"pool:Skia", "os:Linux"},
+	}
+	cfg.TasksCfg.Jobs["Nightly"] = &specs.JobSpec{
+		TaskSpecs: []string{"Nightly"},
+		Trigger:   specs.TRIGGER_NIGHTLY,
+	}
+	cfg.Durations = map[string]time.Duration{
+		"Test": 5 * time.Minute,
+	}
+	res, err := Simulate(cfg)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"Build": 1, "Test": 1}, res.TasksBySpec)
+	require.Equal(t, []string{"Perf"}, res.UnrunnableSpecs)
+	require.Equal(t, 1, res.UntestedCommits)
+	require.Equal(t, 1, res.QueueLengthAtEnd)
+	// Test becomes ready when Build finishes, so neither task waits.
+	require.Equal(t, 0.0, res.QueueLatencyMinutes.Max)
+	require.Equal(t, 0.25, res.MeanBotUtilization())
+
+	// Unknown TaskSpecs are an error.
+	cfg.TasksCfg.Jobs["Bad"] = &specs.JobSpec{
+		TaskSpecs: []string{"Missing"},
+	}
+	_, err = Simulate(cfg)
+	require.EqualError(t, err, "Job Bad depends on unknown task Missing")
+}
+
+func TestTaskDurations(t *testing.T) {
+	unittest.SmallTest(t)
+	now := time.Unix(1580000000, 0).UTC()
+	makeTask := func(name string, d time.Duration, status types.TaskStatus) *types.Task {
+		t := types.MakeTestTask(now, []string{"a"})
+		t.Name = name
+		t.Started = now
+		t.Finished = now.Add(d)
+		t.Status = status
+		return t
+	}
+	require.Equal(t, map[string]time.Duration{
+		"Build": 3 * time.Minute,
+		"Test":  time.Minute,
+	}, TaskDurations([]*types.Task{
+		makeTask("Build", 5*time.Minute, types.TASK_STATUS_SUCCESS),
+		makeTask("Build", 1*time.Minute, types.TASK_STATUS_SUCCESS),
+		makeTask("Build", 3*time.Minute, types.TASK_STATUS_SUCCESS),
+		makeTask("Build", 60*time.Minute, types.TASK_STATUS_FAILURE),
+		makeTask("Test", time.Minute, types.TASK_STATUS_SUCCESS),
+	}))
+}
+
+func TestSimulateSpecializedBots(t *testing.T) {
+	unittest.SmallTest(t)
+	cfg := simulationConfig(1)
+	cfg.Bots = []*task_executor.LocalBot{
+		{
+			Id: "bot-gpu",
+			Dimensions: map[string][]string{
+				"pool": {"Skia"},
+				"os":   {"Linux"},
+				"gpu":  {"nvidia"},
+			},
+		},
+		{
+			Id: "bot-linux",
+			Dimensions: map[string][]string{
+				"pool": {"Skia"},
+				"os":   {"Linux"},
+			},
+		},
+	}
+	cfg.TasksCfg.Tasks["Test"] = &specs.TaskSpec{
+		Dimensions: []string{"pool:Skia", "os:Linux", "gpu:nvidia"},
+	}
+	cfg.TasksCfg.Jobs["Test"] = &specs.JobSpec{
+		TaskSpecs: []string{"Test"},
+	}
+	res, err := Simulate(cfg)
+	require.NoError(t, err)
+
+	// Every candidate chosen by the scheduler runs, on the bot that the
+	// scheduler chose for it.
+	require.Equal(t, map[string]int{"Build": 1, "Test": 1}, res.TasksBySpec)
+	require.Equal(t, 0, res.UntestedCommits)
+	require.Equal(t, 0, res.QueueLengthAtEnd)
+	// The scheduler gives Build the lowest-ID matching bot, so Test has to
+	// wait for it to become free again.
+	require.InDelta(t, 1.0/3.0, res.BotUtilization["bot-gpu"], 0.0001)
+	require.Equal(t, 0.0, res.BotUtilization["bot-linux"])
+}
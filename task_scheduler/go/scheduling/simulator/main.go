@@ -0,0 +1,191 @@
+package main
+
+/*
+	Simulate the Task Scheduler on historical commits using a hypothetical
+	bot fleet, to estimate the effects of adding TaskSpecs, changing
+	priorities, or adding and removing bots.
+*/
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"go.skia.org/infra/go/auth"
+	"go.skia.org/infra/go/common"
+	"go.skia.org/infra/go/git/repograph"
+	"go.skia.org/infra/go/human"
+	"go.skia.org/infra/go/sklog"
+	"go.skia.org/infra/task_scheduler/go/db/firestore"
+	"go.skia.org/infra/task_scheduler/go/scheduling"
+	"go.skia.org/infra/task_scheduler/go/specs"
+	"go.skia.org/infra/task_scheduler/go/task_executor"
+)
+
+var (
+	bots              = flag.String("bots", "", "JSON file describing the simulated bot fleet, in the same format as the local task executor config.")
+	compareTasksCfg   = flag.String("compare_tasks_cfg", "", "Optional second tasks.json file to simulate and compare against --tasks_cfg.")
+	defaultDuration   = flag.Duration("default_duration", 10*time.Minute, "Duration of tasks which have no history in the DB.")
+	end               = flag.String("end", "", "End of the simulated time period, in RFC3339 format. Defaults to now.")
+	firestoreInstance = flag.String("firestore_instance", "", "Firestore instance from which to read task durations, eg. \"production\". If not set, all tasks use --default_duration.")
+	local             = flag.Bool("local", true, "Whether we're running on a dev machine vs in production.")
+	period            = flag.Duration("period", time.Minute, "How often the simulated scheduler runs.")
+	priorities        = common.NewMultiStringFlag("priority", nil, "Override the priority of a JobSpec, in the form \"JobName=0.5\".")
+	repoUrl           = flag.String("repo", common.REPO_SKIA, "Repository to simulate.")
+	simDuration       = flag.String("duration", "7d", "Length of the simulated time period, eg. \"7d\".")
+	tasksCfg          = flag.String("tasks_cfg", "", "tasks.json file to simulate.")
+	timeDecay         = flag.Float64("time_decay", 0.9, "Desired time decay of candidate scores after 24 hours.")
+	timeWindow        = flag.String("window", "4d", "Scheduling window; commits older than this are not scheduled.")
+	workdir           = flag.String("workdir", "workdir", "Working directory, containing a checkout of --repo.")
+)
+
+// readTasksCfg reads a TasksCfg from the given file.
+func readTasksCfg(file string) *specs.TasksCfg {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		sklog.Fatal(err)
+	}
+	cfg, err := specs.ParseTasksCfg(string(b))
+	if err != nil {
+		sklog.Fatalf("Failed to parse %s: %s", file, err)
+	}
+	return cfg
+}
+
+func main() {
+	common.Init()
+	ctx := context.Background()
+
+	if *tasksCfg == "" {
+		sklog.Fatal("--tasks_cfg is required.")
+	}
+	if *bots == "" {
+		sklog.Fatal("--bots is required.")
+	}
+	botCfg, err := task_executor.LoadLocalConfig(*bots)
+	if err != nil {
+		sklog.Fatal(err)
+	}
+	jobPriorities := make(map[string]float64, len(*priorities))
+	for _, p := range *priorities {
+		split := strings.SplitN(p, "=", 2)
+		if len(split) != 2 {
+			sklog.Fatalf("Invalid --priority %q; expected \"JobName=priority\".", p)
+		}
+		priority, err := strconv.ParseFloat(split[1], 64)
+		if err != nil {
+			sklog.Fatalf("Invalid --priority %q: %s", p, err)
+		}
+		jobPriorities[split[0]] = priority
+	}
+
+	// Determine the simulated time period.
+	endTime := time.Now()
+	if *end != "" {
+		endTime, err = time.Parse(time.RFC3339, *end)
+		if err != nil {
+			sklog.Fatalf("Invalid --end: %s", err)
+		}
+	}
+	d, err := human.ParseDuration(*simDuration)
+	if err != nil {
+		sklog.Fatalf("Invalid --duration: %s", err)
+	}
+	startTime := endTime.Add(-d)
+	w, err := human.ParseDuration(*timeWindow)
+	if err != nil {
+		sklog.Fatalf("Invalid --window: %s", err)
+	}
+
+	// Find the commits which landed on master during the time period.
+	repo, err := repograph.NewLocalGraph(ctx, *repoUrl, *workdir)
+	if err != nil {
+		sklog.Fatal(err)
+	}
+	if err := repo.Update(ctx); err != nil {
+		sklog.Fatal(err)
+	}
+	head := repo.Get("master")
+	if head == nil {
+		sklog.Fatal("Could not find HEAD of master.")
+	}
+	commits := []*scheduling.SimulatedCommit{}
+	if err := head.RecurseFirstParent(func(c *repograph.Commit) error {
+		if c.Timestamp.Before(startTime) {
+			return repograph.ErrStopRecursing
+		}
+		if !c.Timestamp.After(endTime) {
+			commits = append(commits, &scheduling.SimulatedCommit{
+				Hash:      c.Hash,
+				Timestamp: c.Timestamp,
+			})
+		}
+		return nil
+	}); err != nil {
+		sklog.Fatal(err)
+	}
+	// Commit timestamps on first-parent history are not necessarily
+	// monotonic.
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Timestamp.Before(commits[j].Timestamp)
+	})
+	sklog.Infof("Simulating %d commits from %s to %s", len(commits), startTime, endTime)
+
+	// Load historical task durations.
+	var durations map[string]time.Duration
+	if *firestoreInstance != "" {
+		ts, err := auth.NewDefaultTokenSource(*local, datastore.ScopeDatastore)
+		if err != nil {
+			sklog.Fatal(err)
+		}
+		tsDb, err := firestore.NewDBWithParams(ctx, firestore.FIRESTORE_PROJECT, *firestoreInstance, ts)
+		if err != nil {
+			sklog.Fatal(err)
+		}
+		tasks, err := tsDb.GetTasksFromDateRange(startTime, endTime, *repoUrl)
+		if err != nil {
+			sklog.Fatal(err)
+		}
+		durations = scheduling.TaskDurations(tasks)
+		sklog.Infof("Found durations for %d TaskSpecs from %d tasks.", len(durations), len(tasks))
+	}
+
+	simulate := func(file string) *scheduling.SimulationResult {
+		res, err := scheduling.Simulate(&scheduling.SimulationConfig{
+			Bots:             botCfg.Bots,
+			Commits:          commits,
+			DefaultDuration:  *defaultDuration,
+			Durations:        durations,
+			End:              endTime,
+			JobPriorities:    jobPriorities,
+			Period:           *period,
+			Repo:             *repoUrl,
+			Start:            startTime,
+			TasksCfg:         readTasksCfg(file),
+			TimeDecayAmt24Hr: *timeDecay,
+			Window:           w,
+		})
+		if err != nil {
+			sklog.Fatal(err)
+		}
+		for _, name := range res.UnrunnableSpecs {
+			sklog.Warningf("No bots can run %s (%s).", name, file)
+		}
+		return res
+	}
+	labels := []string{*tasksCfg}
+	results := []*scheduling.SimulationResult{simulate(*tasksCfg)}
+	if *compareTasksCfg != "" {
+		labels = append(labels, *compareTasksCfg)
+		results = append(results, simulate(*compareTasksCfg))
+	}
+	if err := scheduling.WriteSimulationResults(os.Stdout, labels, results); err != nil {
+		sklog.Fatal(err)
+	}
+}
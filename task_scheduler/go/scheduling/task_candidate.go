@@ -11,7 +11,9 @@ import (
 
 	swarming_api "go.chromium.org/luci/common/api/swarming/swarming/v1"
 	"go.skia.org/infra/go/common"
+	"go.skia.org/infra/go/git/repograph"
 	"go.skia.org/infra/go/isolate"
+	"go.skia.org/infra/go/sklog"
 	"go.skia.org/infra/go/swarming"
 	"go.skia.org/infra/go/util"
 	"go.skia.org/infra/task_scheduler/go/db/cache"
@@ -19,6 +21,12 @@ import (
 	"go.skia.org/infra/task_scheduler/go/types"
 )
 
+const (
+	// When resolving a RepoDependency at the last known good revision,
+	// search at most this many commits back from the head of master.
+	REPO_DEPENDENCY_LKGR_MAX_COMMITS = 100
+)
+
 // taskCandidate is a struct used for determining which tasks to schedule.
 type taskCandidate struct {
 	Attempt int `json:"attempt"`
@@ -30,11 +38,13 @@ type taskCandidate struct {
 	IsolatedInput      string   `json:"isolatedInput"`
 	IsolatedHashes     []string `json:"isolatedHashes"`
 	// Jobs must be kept in sorted order; see AddJob.
-	Jobs           []*types.Job `json:"jobs"`
-	ParentTaskIds  []string     `json:"parentTaskIds"`
-	RetryOf        string       `json:"retryOf"`
-	Score          float64      `json:"score"`
-	StealingFromId string       `json:"stealingFromId"`
+	Jobs          []*types.Job `json:"jobs"`
+	ParentTaskIds []string     `json:"parentTaskIds"`
+	// Name of the blacklist rule quarantining this task, if any.
+	QuarantinedByRule string  `json:"quarantinedByRule,omitempty"`
+	RetryOf           string  `json:"retryOf"`
+	Score             float64 `json:"score"`
+	StealingFromId    string  `json:"stealingFromId"`
 	types.TaskKey
 	TaskSpec    *specs.TaskSpec           `json:"taskSpec"`
 	Diagnostics *taskCandidateDiagnostics `json:"diagnostics,omitempty"`
@@ -53,6 +63,7 @@ func (c *taskCandidate) CopyNoDiagnostics() *taskCandidate {
 		IsolatedHashes:     util.CopyStringSlice(c.IsolatedHashes),
 		Jobs:               jobs,
 		ParentTaskIds:      util.CopyStringSlice(c.ParentTaskIds),
+		QuarantinedByRule:  c.QuarantinedByRule,
 		RetryOf:            c.RetryOf,
 		Score:              c.Score,
 		StealingFromId:     c.StealingFromId,
@@ -139,22 +150,31 @@ func (c *taskCandidate) MakeTask() *types.Task {
 	sort.Strings(jobs)
 	parentTaskIds := make([]string, len(c.ParentTaskIds))
 	copy(parentTaskIds, c.ParentTaskIds)
-	maxAttempts := c.TaskSpec.MaxAttempts
-	if maxAttempts == 0 {
-		maxAttempts = specs.DEFAULT_TASK_SPEC_MAX_ATTEMPTS
-	}
 	return &types.Task{
 		Attempt:       c.Attempt,
 		Commits:       commits,
 		Id:            "", // Filled in when the task is inserted into the DB.
 		Jobs:          jobs,
-		MaxAttempts:   maxAttempts,
+		MaxAttempts:   c.MaxAttempts(),
 		ParentTaskIds: parentTaskIds,
 		RetryOf:       c.RetryOf,
 		TaskKey:       c.TaskKey.Copy(),
 	}
 }
 
+// MaxAttempts returns the maximum number of attempts for the taskCandidate.
+// Quarantined tasks are given an extra attempt.
+func (c *taskCandidate) MaxAttempts() int {
+	maxAttempts := c.TaskSpec.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = specs.DEFAULT_TASK_SPEC_MAX_ATTEMPTS
+	}
+	if c.QuarantinedByRule != "" {
+		maxAttempts++
+	}
+	return maxAttempts
+}
+
 // getPatchStorage returns "gerrit" or "" based on the Server URL.
 func getPatchStorage(server string) string {
 	if server == "" {
@@ -332,9 +352,10 @@ func (c *taskCandidate) MakeTaskRequest(id, isolateServer, pubSubTopic string) *
 
 // allDepsMet determines whether all dependencies for the given task candidate
 // have been satisfied, and if so, returns a map of whose keys are task IDs and
-// values are their isolated outputs.
-func (c *taskCandidate) allDepsMet(cache cache.TaskCache) (bool, map[string]string, error) {
-	rv := make(map[string]string, len(c.TaskSpec.Dependencies))
+// values are their isolated outputs. Dependencies on tasks in other repos are
+// resolved using the given repos.
+func (c *taskCandidate) allDepsMet(cache cache.TaskCache, repos repograph.Map) (bool, map[string]string, error) {
+	rv := make(map[string]string, len(c.TaskSpec.Dependencies)+len(c.TaskSpec.RepoDependencies))
 	var missingDeps []string
 	for _, depName := range c.TaskSpec.Dependencies {
 		key := c.TaskKey.Copy()
@@ -345,7 +366,7 @@ func (c *taskCandidate) allDepsMet(cache cache.TaskCache) (bool, map[string]stri
 		}
 		ok := false
 		for _, t := range byKey {
-			if t.Done() && t.Success() && t.IsolatedOutput != "" {
+			if isUsableDependency(t) {
 				rv[t.Id] = t.IsolatedOutput
 				ok = true
 				break
@@ -355,6 +376,17 @@ func (c *taskCandidate) allDepsMet(cache cache.TaskCache) (bool, map[string]stri
 			missingDeps = append(missingDeps, depName)
 		}
 	}
+	for _, dep := range c.TaskSpec.RepoDependencies {
+		t, err := findRepoDependency(cache, repos, dep)
+		if err != nil {
+			return false, nil, err
+		}
+		if t == nil {
+			missingDeps = append(missingDeps, dep.String())
+		} else {
+			rv[t.Id] = t.IsolatedOutput
+		}
+	}
 	if len(missingDeps) > 0 {
 		c.GetDiagnostics().Filtering = &taskCandidateFilteringDiagnostics{
 			UnmetDependencies: missingDeps,
@@ -364,6 +396,76 @@ func (c *taskCandidate) allDepsMet(cache cache.TaskCache) (bool, map[string]stri
 	return true, rv, nil
 }
 
+// isUsableDependency returns true iff the given task may be used to satisfy a
+// dependency.
+func isUsableDependency(t *types.Task) bool {
+	return t != nil && t.Done() && t.Success() && t.IsolatedOutput != ""
+}
+
+// findRepoDependency returns the task which satisfies the given dependency on
+// a task in another repo, or nil if there is no such task.
+func findRepoDependency(cache cache.TaskCache, repos repograph.Map, dep *specs.RepoDependency) (*types.Task, error) {
+	repo, ok := repos[dep.Repo]
+	if !ok {
+		// The upstream repo may have been removed from the Task
+		// Scheduler; treat the dependency as unmet rather than
+		// preventing all other tasks from being scheduled.
+		sklog.Warningf("Unknown repo in dependency %s", dep)
+		return nil, nil
+	}
+	if !dep.IsLKGR() {
+		commit := repo.Get(dep.Revision)
+		if commit == nil {
+			sklog.Warningf("Unknown revision in dependency %s", dep)
+			return nil, nil
+		}
+		tasks, err := cache.GetTasksByKey(&types.TaskKey{
+			RepoState: types.RepoState{
+				Repo:     dep.Repo,
+				Revision: commit.Hash,
+			},
+			Name: dep.Task,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tasks {
+			if isUsableDependency(t) {
+				return t, nil
+			}
+		}
+		return nil, nil
+	}
+
+	// Find the most recent successful task on master. Each commit is
+	// covered by the blamelist of at most one task, so the first usable
+	// task we find going back from the head of master is the most recent.
+	head := repo.Get("master")
+	if head == nil {
+		return nil, nil
+	}
+	var rv *types.Task
+	numCommits := 0
+	if err := head.RecurseFirstParent(func(c *repograph.Commit) error {
+		t, err := cache.GetTaskForCommit(dep.Repo, c.Hash, dep.Task)
+		if err != nil {
+			return err
+		}
+		if isUsableDependency(t) {
+			rv = t
+			return repograph.ErrStopRecursing
+		}
+		numCommits++
+		if numCommits >= REPO_DEPENDENCY_LKGR_MAX_COMMITS {
+			return repograph.ErrStopRecursing
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
 // taskCandidateSlice is an alias used for sorting a slice of taskCandidates.
 type taskCandidateSlice []*taskCandidate
 
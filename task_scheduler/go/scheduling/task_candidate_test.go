@@ -1,15 +1,23 @@
 package scheduling
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.skia.org/infra/go/deepequal/assertdeep"
+	"go.skia.org/infra/go/git/repograph"
+	"go.skia.org/infra/go/git/testutils/mem_git"
+	"go.skia.org/infra/go/gitstore"
+	"go.skia.org/infra/go/gitstore/mem_gitstore"
 	"go.skia.org/infra/go/testutils/unittest"
+	"go.skia.org/infra/task_scheduler/go/db/cache"
+	"go.skia.org/infra/task_scheduler/go/db/memory"
 	"go.skia.org/infra/task_scheduler/go/specs"
 	"go.skia.org/infra/task_scheduler/go/types"
+	"go.skia.org/infra/task_scheduler/go/window"
 )
 
 func fullTaskCandidate() *taskCandidate {
@@ -23,10 +31,11 @@ func fullTaskCandidate() *taskCandidate {
 		Jobs: []*types.Job{{
 			Id: "dummy",
 		}},
-		ParentTaskIds:  []string{"38", "39", "40"},
-		RetryOf:        "41",
-		Score:          99,
-		StealingFromId: "rich",
+		ParentTaskIds:     []string{"38", "39", "40"},
+		QuarantinedByRule: "quarantine-abc",
+		RetryOf:           "41",
+		Score:             99,
+		StealingFromId:    "rich",
 		TaskKey: types.TaskKey{
 			RepoState: types.RepoState{
 				Repo:     "nou.git",
@@ -55,6 +64,18 @@ func TestTaskCandidateJSON(t *testing.T) {
 	assertdeep.JSONRoundTripEqual(t, v)
 }
 
+func TestTaskCandidateMaxAttempts(t *testing.T) {
+	unittest.SmallTest(t)
+	c := fullTaskCandidate()
+	require.Equal(t, specs.DEFAULT_TASK_SPEC_MAX_ATTEMPTS+1, c.MaxAttempts())
+	c.TaskSpec.MaxAttempts = 5
+	require.Equal(t, 6, c.MaxAttempts())
+	require.Equal(t, 6, c.MakeTask().MaxAttempts)
+	c.QuarantinedByRule = ""
+	require.Equal(t, 5, c.MaxAttempts())
+	require.Equal(t, 5, c.MakeTask().MaxAttempts)
+}
+
 func TestTaskCandidateId(t *testing.T) {
 	unittest.SmallTest(t)
 	t1 := makeTaskCandidate("task1", []string{"k:v"})
@@ -193,3 +214,92 @@ func TestTaskCandidateJobs(t *testing.T) {
 		last = j.Created
 	}
 }
+
+func TestAllDepsMetRepoDependencies(t *testing.T) {
+	unittest.SmallTest(t)
+	ctx := context.Background()
+
+	// Set up an upstream repo with a few commits on master.
+	upstream := "https://upstream.git"
+	gs := mem_gitstore.New()
+	gb := mem_git.New(t, gs)
+	hashes := gb.CommitN(ctx, 4) // Newest first.
+	ri, err := gitstore.NewGitStoreRepoImpl(ctx, gs)
+	require.NoError(t, err)
+	repo, err := repograph.NewWithRepoImpl(ctx, ri)
+	require.NoError(t, err)
+	repos := repograph.Map{upstream: repo}
+	require.NoError(t, repos.Update(ctx))
+
+	d := memory.NewInMemoryTaskDB()
+	w, err := window.New(24*time.Hour, 0, nil)
+	require.NoError(t, err)
+	tCache, err := cache.NewTaskCache(ctx, d, w, nil)
+	require.NoError(t, err)
+
+	c := &taskCandidate{
+		TaskKey: types.TaskKey{
+			RepoState: types.RepoState{
+				Repo:     "https://downstream.git",
+				Revision: "abc123",
+			},
+			Name: "Test",
+		},
+		TaskSpec: &specs.TaskSpec{
+			RepoDependencies: []*specs.RepoDependency{
+				{Repo: upstream, Task: "Build"},
+			},
+		},
+	}
+
+	// No upstream tasks yet.
+	ok, _, err := c.allDepsMet(tCache, repos)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, []string{upstream + "@lkgr:Build"}, c.Diagnostics.Filtering.UnmetDependencies)
+
+	// Add a successful task at the oldest commit and a failed task at
+	// the newest commit, covering the commits in between.
+	makeTask := func(status types.TaskStatus, output string, commits ...string) *types.Task {
+		t := types.MakeTestTask(time.Now(), commits)
+		t.Repo = upstream
+		t.Name = "Build"
+		t.Status = status
+		t.IsolatedOutput = output
+		return t
+	}
+	green := makeTask(types.TASK_STATUS_SUCCESS, "green-output", hashes[3])
+	red := makeTask(types.TASK_STATUS_FAILURE, "", hashes[0], hashes[1], hashes[2])
+	require.NoError(t, d.PutTasks([]*types.Task{green, red}))
+	tCache.AddTasks([]*types.Task{green, red})
+
+	c.Diagnostics = nil
+	ok, idsToHashes, err := c.allDepsMet(tCache, repos)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, map[string]string{green.Id: "green-output"}, idsToHashes)
+
+	// Pin the dependency to a specific revision, at which there is no
+	// successful task.
+	c.TaskSpec.RepoDependencies[0].Revision = hashes[0]
+	ok, _, err = c.allDepsMet(tCache, repos)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// The dependency may also name a branch.
+	c.TaskSpec.RepoDependencies[0].Revision = "master"
+	fixed := makeTask(types.TASK_STATUS_SUCCESS, "fixed-output", hashes[0])
+	require.NoError(t, d.PutTasks([]*types.Task{fixed}))
+	tCache.AddTasks([]*types.Task{fixed})
+	c.Diagnostics = nil
+	ok, idsToHashes, err = c.allDepsMet(tCache, repos)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, map[string]string{fixed.Id: "fixed-output"}, idsToHashes)
+
+	// Dependencies on unknown repos are never met.
+	c.TaskSpec.RepoDependencies[0].Repo = "https://unknown.git"
+	ok, _, err = c.allDepsMet(tCache, repos)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
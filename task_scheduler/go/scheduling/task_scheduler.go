@@ -22,7 +22,6 @@ import (
 	"go.skia.org/infra/go/firestore"
 	"go.skia.org/infra/go/gcs"
 	"go.skia.org/infra/go/git/repograph"
-	"go.skia.org/infra/go/isolate"
 	"go.skia.org/infra/go/metrics2"
 	"go.skia.org/infra/go/skerr"
 	"go.skia.org/infra/go/sklog"
@@ -35,6 +34,7 @@ import (
 	"go.skia.org/infra/task_scheduler/go/isolate_cache"
 	"go.skia.org/infra/task_scheduler/go/specs"
 	"go.skia.org/infra/task_scheduler/go/task_cfg_cache"
+	"go.skia.org/infra/task_scheduler/go/task_executor"
 	"go.skia.org/infra/task_scheduler/go/types"
 	"go.skia.org/infra/task_scheduler/go/window"
 	"golang.org/x/oauth2"
@@ -95,8 +95,8 @@ type TaskScheduler struct {
 	db                  db.DB
 	diagClient          gcs.GCSClient
 	diagInstance        string
+	executor            task_executor.TaskExecutor
 	isolateCache        *isolate_cache.Cache
-	isolateClient       *isolate.Client
 	jCache              cache.JobCache
 	lastScheduled       time.Time // protected by queueMtx.
 
@@ -109,7 +109,6 @@ type TaskScheduler struct {
 	queue        []*taskCandidate // protected by queueMtx.
 	queueMtx     sync.RWMutex
 	repos        repograph.Map
-	swarming     swarming.ApiClient
 	taskCfgCache *task_cfg_cache.TaskCfgCache
 	tCache       cache.TaskCache
 	// testWaitGroup keeps track of any goroutines the TaskScheduler methods
@@ -121,7 +120,7 @@ type TaskScheduler struct {
 	window                *window.Window
 }
 
-func NewTaskScheduler(ctx context.Context, d db.DB, bl *blacklist.Blacklist, period time.Duration, numCommits int, repos repograph.Map, isolateClient *isolate.Client, swarmingClient swarming.ApiClient, c *http.Client, timeDecayAmt24Hr float64, pools []string, pubsubTopic string, taskCfgCache *task_cfg_cache.TaskCfgCache, isolateCache *isolate_cache.Cache, ts oauth2.TokenSource, diagClient gcs.GCSClient, diagInstance string) (*TaskScheduler, error) {
+func NewTaskScheduler(ctx context.Context, d db.DB, bl *blacklist.Blacklist, period time.Duration, numCommits int, repos repograph.Map, executor task_executor.TaskExecutor, c *http.Client, timeDecayAmt24Hr float64, pools []string, pubsubTopic string, taskCfgCache *task_cfg_cache.TaskCfgCache, isolateCache *isolate_cache.Cache, ts oauth2.TokenSource, diagClient gcs.GCSClient, diagInstance string) (*TaskScheduler, error) {
 	// Repos must be updated before window is initialized; otherwise the repos may be uninitialized,
 	// resulting in the window being too short, causing the caches to be loaded with incomplete data.
 	for _, r := range repos {
@@ -152,8 +151,8 @@ func NewTaskScheduler(ctx context.Context, d db.DB, bl *blacklist.Blacklist, per
 		db:                    d,
 		diagClient:            diagClient,
 		diagInstance:          diagInstance,
+		executor:              executor,
 		isolateCache:          isolateCache,
-		isolateClient:         isolateClient,
 		jCache:                jCache,
 		pendingInsert:         map[string]bool{},
 		pools:                 pools,
@@ -162,7 +161,6 @@ func NewTaskScheduler(ctx context.Context, d db.DB, bl *blacklist.Blacklist, per
 		queue:                 []*taskCandidate{},
 		queueMtx:              sync.RWMutex{},
 		repos:                 repos,
-		swarming:              swarmingClient,
 		taskCfgCache:          taskCfgCache,
 		tCache:                tCache,
 		timeDecayAmt24Hr:      timeDecayAmt24Hr,
@@ -564,6 +562,7 @@ func (s *TaskScheduler) filterTaskCandidates(preFilterCandidates map[types.TaskK
 			c.GetDiagnostics().Filtering = &taskCandidateFilteringDiagnostics{BlacklistedByRule: rule}
 			continue
 		}
+		c.QuarantinedByRule = s.bl.MatchQuarantine(c.Name, c.Revision)
 
 		// Reject tasks for too-old commits, as long as they aren't try jobs.
 		if !c.IsTryJob() {
@@ -600,10 +599,7 @@ func (s *TaskScheduler) filterTaskCandidates(preFilterCandidates map[types.TaskK
 			// TaskSpec. Fortunately, TaskCache.GetTasksByKey sorts
 			// by creation time, and we've selected the last of the
 			// results.
-			maxAttempts := c.TaskSpec.MaxAttempts
-			if maxAttempts == 0 {
-				maxAttempts = specs.DEFAULT_TASK_SPEC_MAX_ATTEMPTS
-			}
+			maxAttempts := c.MaxAttempts()
 			// Special case for tasks created before arbitrary
 			// numbers of attempts were possible.
 			previousAttempt := previous.Attempt
@@ -623,7 +619,7 @@ func (s *TaskScheduler) filterTaskCandidates(preFilterCandidates map[types.TaskK
 		}
 
 		// Don't consider candidates whose dependencies are not met.
-		depsMet, idsToHashes, err := c.allDepsMet(s.tCache)
+		depsMet, idsToHashes, err := c.allDepsMet(s.tCache, s.repos)
 		if err != nil {
 			return nil, err
 		}
@@ -967,6 +963,14 @@ func (s *TaskScheduler) regenerateTaskQueue(ctx context.Context, now time.Time)
 // Assumes that the tasks are sorted in decreasing order by score.
 func getCandidatesToSchedule(bots []*swarming_api.SwarmingRpcsBotInfo, tasks []*taskCandidate) []*taskCandidate {
 	defer metrics2.FuncTimer().Stop()
+	rv, _ := matchCandidatesToBots(bots, tasks)
+	return rv
+}
+
+// matchCandidatesToBots is the implementation of getCandidatesToSchedule. In
+// addition to the candidates which should be run, it returns the ID of the bot
+// chosen for each of them.
+func matchCandidatesToBots(bots []*swarming_api.SwarmingRpcsBotInfo, tasks []*taskCandidate) ([]*taskCandidate, map[*taskCandidate]string) {
 	// Create a bots-by-swarming-dimension mapping.
 	botsByDim := map[string]util.StringSet{}
 	for _, b := range bots {
@@ -992,6 +996,7 @@ func getCandidatesToSchedule(bots []*swarming_api.SwarmingRpcsBotInfo, tasks []*
 	// match so that less-specialized tasks don't "steal" more-specialized
 	// bots which they don't actually need.
 	rv := make([]*taskCandidate, 0, len(bots))
+	chosenBots := make(map[*taskCandidate]string, len(bots))
 	countByTaskSpec := make(map[string]int, len(bots))
 	for _, c := range tasks {
 		diag := &taskCandidateSchedulingDiagnostics{}
@@ -1069,11 +1074,12 @@ func getCandidatesToSchedule(bots []*swarming_api.SwarmingRpcsBotInfo, tasks []*
 
 			// Add the task to the scheduling list.
 			rv = append(rv, c)
+			chosenBots[c] = chosenBot
 			countByTaskSpec[c.Name]++
 		}
 	}
 	sort.Sort(taskCandidateSlice(rv))
-	return rv
+	return rv, chosenBots
 }
 
 // isolateCandidates uploads inputs for the taskCandidates to the Isolate
@@ -1101,12 +1107,12 @@ func (s *TaskScheduler) isolateCandidates(ctx context.Context, candidates []*tas
 		isolatedFiles = append(isolatedFiles, isolatedFile)
 		isolatedCandidates = append(isolatedCandidates, c)
 	}
-	hashes, err := s.isolateClient.ReUploadIsolatedFiles(ctx, isolatedFiles)
+	hashes, err := s.executor.UploadIsolatedFiles(ctx, isolatedFiles)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to re-upload Isolated files: %s", err)
 	}
 	if len(hashes) != len(isolatedFiles) {
-		return nil, fmt.Errorf("ReUploadIsolatedFiles returned incorrect number of hashes (%d but wanted %d)", len(hashes), len(isolatedFiles))
+		return nil, fmt.Errorf("UploadIsolatedFiles returned incorrect number of hashes (%d but wanted %d)", len(hashes), len(isolatedFiles))
 	}
 	for idx, c := range isolatedCandidates {
 		c.IsolatedInput = hashes[idx]
@@ -1115,7 +1121,7 @@ func (s *TaskScheduler) isolateCandidates(ctx context.Context, candidates []*tas
 	return isolatedCandidates, errs.ErrorOrNil()
 }
 
-// triggerTasks triggers the given slice of tasks to run on Swarming and returns
+// triggerTasks triggers the given slice of tasks to run on the TaskExecutor and returns
 // a channel of the successfully-triggered tasks which is closed after all tasks
 // have been triggered or failed. Each failure is sent to errCh.
 func (s *TaskScheduler) triggerTasks(candidates []*taskCandidate, errCh chan<- error) <-chan *types.Task {
@@ -1139,14 +1145,14 @@ func (s *TaskScheduler) triggerTasks(candidates []*taskCandidate, errCh chan<- e
 				return
 			}
 			diag.TaskId = t.Id
-			req := candidate.MakeTaskRequest(t.Id, s.isolateClient.ServerURL(), s.pubsubTopic)
+			req := candidate.MakeTaskRequest(t.Id, s.executor.IsolateServer(), s.pubsubTopic)
 			s.pendingInsertMtx.Lock()
 			s.pendingInsert[t.Id] = true
 			s.pendingInsertMtx.Unlock()
 			var resp *swarming_api.SwarmingRpcsTaskRequestMetadata
 			if err := timeout.Run(func() error {
 				var err error
-				resp, err = s.swarming.TriggerTask(req)
+				resp, err = s.executor.TriggerTask(req)
 				return err
 			}, time.Minute); err != nil {
 				s.pendingInsertMtx.Lock()
@@ -1198,7 +1204,7 @@ func (s *TaskScheduler) triggerTasks(candidates []*taskCandidate, errCh chan<- e
 	return triggered
 }
 
-// scheduleTasks queries for free Swarming bots and triggers tasks according
+// scheduleTasks matches free bots with tasks and triggers tasks according
 // to relative priorities in the queue.
 func (s *TaskScheduler) scheduleTasks(ctx context.Context, bots []*swarming_api.SwarmingRpcsBotInfo, queue []*taskCandidate) error {
 	defer metrics2.FuncTimer().Stop()
@@ -1331,7 +1337,7 @@ func (s *TaskScheduler) MainLoop(ctx context.Context) error {
 		defer wg.Done()
 
 		var err error
-		bots, err = getFreeSwarmingBots(s.swarming, s.busyBots, s.pools)
+		bots, err = getFreeBots(s.executor, s.busyBots, s.pools)
 		if err != nil {
 			getSwarmingBotsErr = err
 			return
@@ -1364,7 +1370,7 @@ func (s *TaskScheduler) MainLoop(ctx context.Context) error {
 
 	wg.Wait()
 	if getSwarmingBotsErr != nil {
-		return fmt.Errorf("Failed to retrieve free Swarming bots: %s", getSwarmingBotsErr)
+		return fmt.Errorf("Failed to retrieve free bots: %s", getSwarmingBotsErr)
 	}
 
 	sklog.Infof("Task Scheduler scheduling tasks...")
@@ -1479,23 +1485,22 @@ func testednessIncrease(blamelistLength, stoleFromBlamelistLength int) float64 {
 	}
 }
 
-// getFreeSwarmingBots returns a slice of free swarming bots.
-func getFreeSwarmingBots(s swarming.ApiClient, busy *busyBots, pools []string) ([]*swarming_api.SwarmingRpcsBotInfo, error) {
+// getFreeBots returns a slice of free bots.
+func getFreeBots(e task_executor.TaskExecutor, busy *busyBots, pools []string) ([]*swarming_api.SwarmingRpcsBotInfo, error) {
 	defer metrics2.FuncTimer().Stop()
 
-	// Query for free Swarming bots and pending Swarming tasks in all pools.
+	// Query for free bots and pending tasks in all pools.
 	var wg sync.WaitGroup
 	bots := []*swarming_api.SwarmingRpcsBotInfo{}
 	pending := []*swarming_api.SwarmingRpcsTaskResult{}
 	errs := []error{}
 	var mtx sync.Mutex
-	t := time.Time{}
 	for _, pool := range pools {
 		// Free bots.
 		wg.Add(1)
 		go func(pool string) {
 			defer wg.Done()
-			b, err := s.ListFreeBots(pool)
+			b, err := e.ListFreeBots(pool)
 			mtx.Lock()
 			defer mtx.Unlock()
 			if err != nil {
@@ -1509,7 +1514,7 @@ func getFreeSwarmingBots(s swarming.ApiClient, busy *busyBots, pools []string) (
 		wg.Add(1)
 		go func(pool string) {
 			defer wg.Done()
-			t, err := s.ListTaskResults(t, t, []string{fmt.Sprintf("pool:%s", pool)}, "PENDING", false)
+			t, err := e.ListPendingTasks(pool)
 			mtx.Lock()
 			defer mtx.Unlock()
 			if err != nil {
@@ -1522,7 +1527,7 @@ func getFreeSwarmingBots(s swarming.ApiClient, busy *busyBots, pools []string) (
 
 	wg.Wait()
 	if len(errs) > 0 {
-		return nil, fmt.Errorf("Got errors loading bots and tasks from Swarming: %v", errs)
+		return nil, fmt.Errorf("Got errors loading bots and tasks: %v", errs)
 	}
 
 	rv := make([]*swarming_api.SwarmingRpcsBotInfo, 0, len(bots))
@@ -1542,7 +1547,7 @@ func getFreeSwarmingBots(s swarming.ApiClient, busy *busyBots, pools []string) (
 	return busy.Filter(rv), nil
 }
 
-// updateUnfinishedTasks queries Swarming for all unfinished tasks and updates
+// updateUnfinishedTasks queries the TaskExecutor for all unfinished tasks and updates
 // their status in the DB.
 func (s *TaskScheduler) updateUnfinishedTasks() error {
 	defer metrics2.FuncTimer().Stop()
@@ -1557,13 +1562,13 @@ func (s *TaskScheduler) updateUnfinishedTasks() error {
 	}
 	sort.Sort(types.TaskSlice(tasks))
 
-	// Query Swarming for all unfinished tasks.
+	// Query the TaskExecutor for all unfinished tasks.
 	sklog.Infof("Querying states of %d unfinished tasks.", len(tasks))
 	ids := make([]string, 0, len(tasks))
 	for _, t := range tasks {
 		ids = append(ids, t.SwarmingTaskId)
 	}
-	states, err := s.swarming.GetStates(ids)
+	states, err := s.executor.GetStates(ids)
 	if err != nil {
 		return err
 	}
@@ -1584,7 +1589,7 @@ func (s *TaskScheduler) updateUnfinishedTasks() error {
 			wg.Add(1)
 			go func(idx int, t *types.Task) {
 				defer wg.Done()
-				swarmTask, err := s.swarming.GetTask(t.SwarmingTaskId, false)
+				swarmTask, err := s.executor.GetTask(t.SwarmingTaskId)
 				if err != nil {
 					errs[idx] = fmt.Errorf("Failed to update unfinished task; failed to get updated task from swarming: %s", err)
 					return
@@ -1849,7 +1854,7 @@ func (s *TaskScheduler) HandleSwarmingPubSub(msg *swarming.PubSubTaskMessage) bo
 	}
 
 	// Obtain the Swarming task data.
-	res, err := s.swarming.GetTask(msg.SwarmingTaskId, false)
+	res, err := s.executor.GetTask(msg.SwarmingTaskId)
 	if err != nil {
 		sklog.Errorf("pubsub: Failed to retrieve task from Swarming: %s", err)
 		return true
@@ -24,6 +24,10 @@ const (
 
 	TASKS_CFG_FILE = "infra/bots/tasks.json"
 
+	// RepoDependency.Revision indicating that the most recent successful
+	// upstream task on the master branch should be used.
+	REPO_DEPENDENCY_LKGR = "lkgr"
+
 	// Triggering configuration for jobs.
 
 	// By default, all jobs trigger on any branch for which they are
@@ -32,6 +36,17 @@ const (
 	// Run this job on the master branch only, even if it is defined on
 	// others.
 	TRIGGER_MASTER_ONLY = "master"
+	// Run this job for commits on branches whose names match the glob
+	// pattern following this prefix, eg. "branch:release/*". Patterns use
+	// the syntax of path.Match.
+	TRIGGER_BRANCH_PREFIX = "branch:"
+	// Trigger this job at HEAD of master on the cron schedule following
+	// this prefix, eg. "cron:0 */4 * * 1-5". Schedules are in UTC; see
+	// periodic.CronSchedule for the syntax.
+	TRIGGER_CRON_PREFIX = "cron:"
+	// Name of the periodic trigger which triggers the jobs with
+	// TRIGGER_CRON_PREFIX whose schedule is due.
+	TRIGGER_CRON = periodic.TRIGGER_CRON
 	// Trigger this job every night.
 	TRIGGER_NIGHTLY = periodic.TRIGGER_NIGHTLY
 	// Don't trigger this job automatically. It will only be run when
@@ -201,6 +216,12 @@ func (c *TasksCfg) Validate() error {
 		}
 	}
 
+	for name, j := range c.Jobs {
+		if err := j.Validate(); err != nil {
+			return fmt.Errorf("Invalid TasksCfg: job %s: %s", name, err)
+		}
+	}
+
 	if err := findCycles(c.Tasks, c.Jobs); err != nil {
 		return fmt.Errorf("Invalid TasksCfg: %s", err)
 	}
@@ -275,6 +296,11 @@ type TaskSpec struct {
 	// This field is ignored.
 	Priority float64 `json:"priority,omitempty"`
 
+	// RepoDependencies are tasks in other repos which need to succeed
+	// before this task can run. As with Dependencies, their isolated
+	// outputs are used as inputs to this task.
+	RepoDependencies []*RepoDependency `json:"repo_dependencies,omitempty"`
+
 	// ServiceAccount indicates the Swarming service account to use for the
 	// task. If not specified, we will attempt to choose a suitable default.
 	ServiceAccount string `json:"service_account,omitempty"`
@@ -302,6 +328,12 @@ func (t *TaskSpec) Validate(cfg *TasksCfg) error {
 		return fmt.Errorf("Isolate file is required.")
 	}
 
+	for _, d := range t.RepoDependencies {
+		if err := d.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -339,6 +371,13 @@ func (t *TaskSpec) Copy() *TaskSpec {
 	extraArgs := util.CopyStringSlice(t.ExtraArgs)
 	extraTags := util.CopyStringMap(t.ExtraTags)
 	outputs := util.CopyStringSlice(t.Outputs)
+	var repoDeps []*RepoDependency
+	if len(t.RepoDependencies) > 0 {
+		repoDeps = make([]*RepoDependency, 0, len(t.RepoDependencies))
+		for _, d := range t.RepoDependencies {
+			repoDeps = append(repoDeps, d.Copy())
+		}
+	}
 	return &TaskSpec{
 		Caches:           caches,
 		CipdPackages:     cipdPackages,
@@ -357,10 +396,63 @@ func (t *TaskSpec) Copy() *TaskSpec {
 		MaxAttempts:      t.MaxAttempts,
 		Outputs:          outputs,
 		Priority:         t.Priority,
+		RepoDependencies: repoDeps,
 		ServiceAccount:   t.ServiceAccount,
 	}
 }
 
+// RepoDependency describes a dependency of a TaskSpec on a task defined in
+// another repo. The other repo must be one of the repos for which the Task
+// Scheduler schedules tasks.
+type RepoDependency struct {
+	// Repo is the URL of the repo which defines the upstream TaskSpec.
+	Repo string `json:"repo"`
+
+	// Revision is the commit hash or branch name of Repo at which the
+	// upstream task must have run. If it is REPO_DEPENDENCY_LKGR or not
+	// specified, the most recent successful upstream task on the master
+	// branch is used.
+	Revision string `json:"revision,omitempty"`
+
+	// Task is the name of the upstream TaskSpec.
+	Task string `json:"task"`
+}
+
+// Validate returns an error if the RepoDependency is not valid.
+func (d *RepoDependency) Validate() error {
+	if d.Repo == "" {
+		return fmt.Errorf("Repo dependencies must specify a repo.")
+	}
+	if d.Task == "" {
+		return fmt.Errorf("Repo dependency on %s must specify a task.", d.Repo)
+	}
+	return nil
+}
+
+// Copy returns a copy of the RepoDependency.
+func (d *RepoDependency) Copy() *RepoDependency {
+	return &RepoDependency{
+		Repo:     d.Repo,
+		Revision: d.Revision,
+		Task:     d.Task,
+	}
+}
+
+// IsLKGR returns true iff the RepoDependency refers to the last known good
+// revision of the upstream task.
+func (d *RepoDependency) IsLKGR() bool {
+	return d.Revision == "" || d.Revision == REPO_DEPENDENCY_LKGR
+}
+
+// String returns a human-readable description of the RepoDependency.
+func (d *RepoDependency) String() string {
+	rev := d.Revision
+	if d.IsLKGR() {
+		rev = REPO_DEPENDENCY_LKGR
+	}
+	return fmt.Sprintf("%s@%s:%s", d.Repo, rev, d.Task)
+}
+
 // Cache is a struct representing a named cache which is used by a task.
 type Cache struct {
 	Name string `json:"name"`
@@ -391,10 +483,47 @@ type JobSpec struct {
 	Priority float64 `json:"priority,omitempty"`
 	// The names of TaskSpecs that are direct dependencies of this JobSpec.
 	TaskSpecs []string `json:"tasks"`
-	// One of the TRIGGER_* constants; see documentation above.
+	// One of the TRIGGER_* constants, or a trigger starting with
+	// TRIGGER_BRANCH_PREFIX or TRIGGER_CRON_PREFIX; see documentation above.
 	Trigger string `json:"trigger,omitempty"`
 }
 
+// Validate returns an error if the JobSpec is not valid.
+func (j *JobSpec) Validate() error {
+	if strings.HasPrefix(j.Trigger, TRIGGER_BRANCH_PREFIX) {
+		pattern := j.BranchPattern()
+		if pattern == "" {
+			return fmt.Errorf("Trigger %q has no branch pattern", j.Trigger)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Trigger %q has an invalid branch pattern: %s", j.Trigger, err)
+		}
+	} else if strings.HasPrefix(j.Trigger, TRIGGER_CRON_PREFIX) {
+		if _, err := j.CronSchedule(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BranchPattern returns the glob pattern of a JobSpec with a
+// TRIGGER_BRANCH_PREFIX trigger, or the empty string for other triggers.
+func (j *JobSpec) BranchPattern() string {
+	if !strings.HasPrefix(j.Trigger, TRIGGER_BRANCH_PREFIX) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(j.Trigger, TRIGGER_BRANCH_PREFIX))
+}
+
+// CronSchedule returns the schedule of a JobSpec with a TRIGGER_CRON_PREFIX
+// trigger, or nil for other triggers.
+func (j *JobSpec) CronSchedule() (*periodic.CronSchedule, error) {
+	if !strings.HasPrefix(j.Trigger, TRIGGER_CRON_PREFIX) {
+		return nil, nil
+	}
+	return periodic.ParseCron(strings.TrimPrefix(j.Trigger, TRIGGER_CRON_PREFIX))
+}
+
 // Copy returns a copy of the JobSpec.
 func (j *JobSpec) Copy() *JobSpec {
 	var taskSpecs []string
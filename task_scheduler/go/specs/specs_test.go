@@ -40,12 +40,19 @@ func fakeTaskSpec() *TaskSpec {
 		ExtraTags: map[string]string{
 			"dummy_tag": "dummy_val",
 		},
-		Idempotent:     true,
-		IoTimeout:      10 * time.Minute,
-		Isolate:        "abc123",
-		MaxAttempts:    5,
-		Outputs:        []string{"out"},
-		Priority:       19.0,
+		Idempotent:  true,
+		IoTimeout:   10 * time.Minute,
+		Isolate:     "abc123",
+		MaxAttempts: 5,
+		Outputs:     []string{"out"},
+		Priority:    19.0,
+		RepoDependencies: []*RepoDependency{
+			{
+				Repo:     "https://skia.googlesource.com/skia.git",
+				Revision: REPO_DEPENDENCY_LKGR,
+				Task:     "Build",
+			},
+		},
 		ServiceAccount: "fake-account@gmail.com",
 	}
 }
@@ -231,3 +238,78 @@ func TestGetTaskSpecDAG(t *testing.T) {
 		"g": {"d", "e", "f"},
 	}, []string{"a", "g"})
 }
+
+func TestJobSpecTriggers(t *testing.T) {
+	unittest.SmallTest(t)
+
+	parse := func(trigger string) error {
+		cfg := &TasksCfg{
+			Tasks: map[string]*TaskSpec{
+				"a": {Isolate: "abc123"},
+			},
+			Jobs: map[string]*JobSpec{
+				"j": {
+					TaskSpecs: []string{"a"},
+					Trigger:   trigger,
+				},
+			},
+		}
+		_, err := ParseTasksCfg(testutils.MarshalIndentJSON(t, cfg))
+		return err
+	}
+	require.NoError(t, parse(TRIGGER_ANY_BRANCH))
+	require.NoError(t, parse(TRIGGER_NIGHTLY))
+	require.NoError(t, parse("branch:release/*"))
+	require.NoError(t, parse("cron:0 */4 * * 1-5"))
+	require.EqualError(t, parse("branch:"), "Invalid TasksCfg: job j: Trigger \"branch:\" has no branch pattern")
+	require.EqualError(t, parse("branch:release/["), "Invalid TasksCfg: job j: Trigger \"branch:release/[\" has an invalid branch pattern: syntax error in pattern")
+	require.EqualError(t, parse("cron:0 */4 * *"), "Invalid TasksCfg: job j: Expected 5 fields in cron expression \"0 */4 * *\" but got 4")
+
+	j := &JobSpec{Trigger: "branch:release/*"}
+	require.Equal(t, "release/*", j.BranchPattern())
+	sched, err := j.CronSchedule()
+	require.NoError(t, err)
+	require.Nil(t, sched)
+	j.Trigger = "cron:30 2 * * *"
+	require.Equal(t, "", j.BranchPattern())
+	sched, err = j.CronSchedule()
+	require.NoError(t, err)
+	require.Equal(t, "30 2 * * *", sched.String())
+}
+
+func TestRepoDependencies(t *testing.T) {
+	unittest.SmallTest(t)
+
+	cfg := &TasksCfg{
+		Tasks: map[string]*TaskSpec{
+			"a": {
+				Isolate: "abc123",
+				RepoDependencies: []*RepoDependency{
+					{
+						Repo: "https://skia.googlesource.com/skia.git",
+						Task: "Build",
+					},
+				},
+			},
+		},
+		Jobs: map[string]*JobSpec{
+			"j": {TaskSpecs: []string{"a"}},
+		},
+	}
+	_, err := ParseTasksCfg(testutils.MarshalIndentJSON(t, cfg))
+	require.NoError(t, err)
+	d := cfg.Tasks["a"].RepoDependencies[0]
+	require.True(t, d.IsLKGR())
+	require.Equal(t, "https://skia.googlesource.com/skia.git@lkgr:Build", d.String())
+	d.Revision = "master"
+	require.False(t, d.IsLKGR())
+	require.Equal(t, "https://skia.googlesource.com/skia.git@master:Build", d.String())
+
+	d.Task = ""
+	_, err = ParseTasksCfg(testutils.MarshalIndentJSON(t, cfg))
+	require.EqualError(t, err, "Invalid TasksCfg: Repo dependency on https://skia.googlesource.com/skia.git must specify a task.")
+	d.Task = "Build"
+	d.Repo = ""
+	_, err = ParseTasksCfg(testutils.MarshalIndentJSON(t, cfg))
+	require.EqualError(t, err, "Invalid TasksCfg: Repo dependencies must specify a repo.")
+}
@@ -28,9 +28,11 @@ import (
 	"go.skia.org/infra/go/util"
 	"go.skia.org/infra/task_scheduler/go/blacklist"
 	"go.skia.org/infra/task_scheduler/go/db/firestore"
+	"go.skia.org/infra/task_scheduler/go/flakes"
 	"go.skia.org/infra/task_scheduler/go/isolate_cache"
 	"go.skia.org/infra/task_scheduler/go/scheduling"
 	"go.skia.org/infra/task_scheduler/go/task_cfg_cache"
+	"go.skia.org/infra/task_scheduler/go/task_executor"
 	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 )
@@ -44,6 +46,9 @@ const (
 
 	// PubSub subscriber ID used for GitStore.
 	GITSTORE_SUBSCRIBER_ID = APP_NAME
+
+	// How often to update the flake quarantine.
+	FLAKE_QUARANTINE_PERIOD = 10 * time.Minute
 )
 
 var (
@@ -55,6 +60,7 @@ var (
 	gitstoreTable     = flag.String("gitstore_bt_table", "git-repos2", "BigTable table used for GitStore.")
 	isolateServer     = flag.String("isolate_server", isolate.ISOLATE_SERVER_URL, "Which Isolate server to use.")
 	local             = flag.Bool("local", false, "Whether we're running on a dev machine vs in production.")
+	localBots         = flag.String("local_bots", "", "If set, tasks are run as subprocesses on this machine instead of on Swarming, using the bots described in this JSON file. See task_executor.LocalConfig.")
 	repoUrls          = common.NewMultiStringFlag("repo", nil, "Repositories for which to schedule tasks.")
 	scoreDecay24Hr    = flag.Float64("scoreDecay24Hr", 0.9, "Task candidate scores are penalized using linear time decay. This is the desired value after 24 hours. Setting it to 1.0 causes commits not to be prioritized according to commit time.")
 	swarmingPools     = common.NewMultiStringFlag("pool", nil, "Which Swarming pools to use.")
@@ -65,6 +71,11 @@ var (
 	workdir           = flag.String("workdir", "workdir", "Working directory to use.")
 	promPort          = flag.String("prom_port", ":20000", "Metrics service address (e.g., ':10110')")
 
+	flakeThreshold = flag.Float64("flake_quarantine_threshold", 0, "If non-zero, TaskSpecs whose flake rate over --flake_quarantine_window is at least this fraction are quarantined and given an extra attempt.")
+	flakeWindow    = flag.Duration("flake_quarantine_window", 24*time.Hour, "Window over which flake rates are computed.")
+	flakeMinRuns   = flag.Int("flake_quarantine_min_runs", 10, "Minimum number of finished tasks in --flake_quarantine_window before a TaskSpec may be quarantined.")
+	flakeGreenRuns = flag.Int("flake_quarantine_green_runs", 10, "Number of consecutive successful tasks after which the quarantine of a TaskSpec is lifted.")
+
 	pubsubTopicName      = flag.String("pubsub_topic", swarming.PUBSUB_TOPIC_SWARMING_TASKS, "Pub/Sub topic to use for Swarming tasks.")
 	pubsubSubscriberName = flag.String("pubsub_subscriber", PUBSUB_SUBSCRIBER_TASK_SCHEDULER, "Pub/Sub subscriber name.")
 )
@@ -95,17 +106,12 @@ func main() {
 	if *local {
 		isolateServerUrl = isolate.ISOLATE_SERVER_URL_FAKE
 	}
-	var isolateClient *isolate.Client
 	var tokenSource oauth2.TokenSource
 	gitcookiesPath := "/tmp/.gitcookies"
 	tokenSource, err = auth.NewDefaultTokenSource(*local, auth.SCOPE_USERINFO_EMAIL, auth.SCOPE_GERRIT, auth.SCOPE_READ_WRITE, pubsub.ScopePubSub, datastore.ScopeDatastore, bigtable.Scope, swarming.AUTH_SCOPE)
 	if err != nil {
 		sklog.Fatalf("Failed to create token source: %s", err)
 	}
-	isolateClient, err = isolate.NewClientWithServiceAccount(wdAbs, isolateServerUrl, os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
-	if err != nil {
-		sklog.Fatal(err)
-	}
 	if _, err := gitauth.New(tokenSource, gitcookiesPath, true, ""); err != nil {
 		sklog.Fatalf("Failed to create git cookie updater: %s", err)
 	}
@@ -144,12 +150,29 @@ func main() {
 	}
 	repos := autoUpdateRepos.Map
 
-	// Initialize Swarming client.
-	cfg := httputils.DefaultClientConfig().WithTokenSource(tokenSource).WithDialTimeout(time.Minute).With2xxOnly()
-	cfg.RequestTimeout = time.Minute
-	swarm, err := swarming.NewApiClient(cfg.Client(), *swarmingServer)
-	if err != nil {
-		sklog.Fatal(err)
+	// Initialize the task executor.
+	var executor task_executor.TaskExecutor
+	if *localBots != "" {
+		localCfg, err := task_executor.LoadLocalConfig(*localBots)
+		if err != nil {
+			sklog.Fatal(err)
+		}
+		executor, err = task_executor.NewLocalExecutor(ctx, filepath.Join(wdAbs, "local_tasks"), localCfg)
+		if err != nil {
+			sklog.Fatal(err)
+		}
+	} else {
+		isolateClient, err := isolate.NewClientWithServiceAccount(wdAbs, isolateServerUrl, os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+		if err != nil {
+			sklog.Fatal(err)
+		}
+		cfg := httputils.DefaultClientConfig().WithTokenSource(tokenSource).WithDialTimeout(time.Minute).With2xxOnly()
+		cfg.RequestTimeout = time.Minute
+		swarm, err := swarming.NewApiClient(cfg.Client(), *swarmingServer)
+		if err != nil {
+			sklog.Fatal(err)
+		}
+		executor = task_executor.NewSwarmingExecutor(swarm, isolateClient)
 	}
 
 	storageClient, err := storage.NewClient(ctx, option.WithTokenSource(tokenSource))
@@ -177,15 +200,28 @@ func main() {
 
 	// Create and start the task scheduler.
 	sklog.Infof("Creating task scheduler.")
-	ts, err := scheduling.NewTaskScheduler(ctx, tsDb, bl, period, *commitWindow, repos, isolateClient, swarm, httpClient, *scoreDecay24Hr, *swarmingPools, *pubsubTopicName, taskCfgCache, isolateCache, tokenSource, diagClient, diagInstance)
+	ts, err := scheduling.NewTaskScheduler(ctx, tsDb, bl, period, *commitWindow, repos, executor, httpClient, *scoreDecay24Hr, *swarmingPools, *pubsubTopicName, taskCfgCache, isolateCache, tokenSource, diagClient, diagInstance)
 	if err != nil {
 		sklog.Fatal(err)
 	}
 	cleanup.AtExit(func() {
 		util.LogErr(ts.Close())
 	})
-	if err := swarming.InitPubSub(*pubsubTopicName, *pubsubSubscriberName, ts.HandleSwarmingPubSub); err != nil {
-		sklog.Fatal(err)
+	// Local tasks are only updated by polling.
+	if *localBots == "" {
+		if err := swarming.InitPubSub(*pubsubTopicName, *pubsubSubscriberName, ts.HandleSwarmingPubSub); err != nil {
+			sklog.Fatal(err)
+		}
+	}
+
+	if *flakeThreshold > 0 {
+		q := flakes.NewQuarantiner(tsDb, bl, flakes.QuarantineConfig{
+			Window:    *flakeWindow,
+			Threshold: *flakeThreshold,
+			MinRuns:   *flakeMinRuns,
+			GreenRuns: *flakeGreenRuns,
+		})
+		q.Start(ctx, FLAKE_QUARANTINE_PERIOD)
 	}
 
 	sklog.Infof("Created task scheduler. Starting loop.")
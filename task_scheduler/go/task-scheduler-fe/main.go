@@ -211,12 +211,15 @@ func jsonBlacklistHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		defer util.Close(r.Body)
 		rule.AddedBy = login.LoggedInAs(r)
+		rule.Added = time.Now().UTC()
 		if len(rule.Commits) == 2 {
 			rangeRule, err := blacklist.NewCommitRangeRule(context.Background(), rule.Name, rule.AddedBy, rule.Description, rule.TaskSpecPatterns, rule.Commits[0], rule.Commits[1], repos)
 			if err != nil {
 				httputils.ReportError(w, err, fmt.Sprintf("Failed to create commit range rule: %s", err), http.StatusInternalServerError)
 				return
 			}
+			rangeRule.Added = rule.Added
+			rangeRule.Quarantine = rule.Quarantine
 			rule = *rangeRule
 		}
 		if err := bl.AddRule(&rule, repos); err != nil {
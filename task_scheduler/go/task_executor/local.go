@@ -0,0 +1,707 @@
+package task_executor
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	swarming_api "go.chromium.org/luci/common/api/swarming/swarming/v1"
+	"go.chromium.org/luci/common/isolated"
+	"go.skia.org/infra/go/isolate"
+	"go.skia.org/infra/go/skerr"
+	"go.skia.org/infra/go/sklog"
+	"go.skia.org/infra/go/swarming"
+	"go.skia.org/infra/go/util"
+)
+
+const (
+	// LOCAL_ISOLATE_SERVER is reported as the Isolate server by the
+	// LocalExecutor. Isolated files are kept in memory and never leave the
+	// local machine.
+	LOCAL_ISOLATE_SERVER = "local"
+
+	// ISOLATED_OUTDIR is replaced with the output directory of the task in
+	// its command and environment, as on Swarming.
+	ISOLATED_OUTDIR = "${ISOLATED_OUTDIR}"
+
+	// Names of the files and directories created for each task inside the
+	// LocalExecutor's working directory.
+	localRunDir     = "run"
+	localOutDir     = "out"
+	localLogFile    = "output.log"
+	localResultFile = "result.json"
+
+	// localTaskIdPrefix is the prefix of the IDs of local tasks.
+	localTaskIdPrefix = "local-"
+
+	// How often a running task's output is checked for its IO timeout.
+	localIoPollPeriod = time.Second
+)
+
+// LocalBot is a bot of the LocalExecutor. Each bot runs one task at a time.
+type LocalBot struct {
+	Id         string              `json:"id"`
+	Dimensions map[string][]string `json:"dimensions"`
+}
+
+// LocalConfig configures a LocalExecutor.
+type LocalConfig struct {
+	// Bots on which tasks may run. Tasks are only scheduled on bots whose
+	// dimensions include all of the task's dimensions, so bots should
+	// include the "pool" dimension.
+	Bots []*LocalBot `json:"bots"`
+
+	// InputDir is optional. If set, the top-level entries of this directory,
+	// typically a checkout of the repo, are linked into the working
+	// directory of every task. The files listed in the isolated inputs of a
+	// task are never retrieved from an Isolate server; only the outputs of
+	// other local tasks are copied in.
+	InputDir string `json:"input_dir,omitempty"`
+}
+
+// LoadLocalConfig reads a LocalConfig from the given JSON file.
+func LoadLocalConfig(file string) (*LocalConfig, error) {
+	var rv LocalConfig
+	if err := util.WithReadFile(file, func(f io.Reader) error {
+		return json.NewDecoder(f).Decode(&rv)
+	}); err != nil {
+		return nil, skerr.Wrapf(err, "failed to read local executor config from %s", file)
+	}
+	return &rv, nil
+}
+
+// localTask holds the state of a task triggered on the LocalExecutor.
+type localTask struct {
+	expires time.Time
+	req     *swarming_api.SwarmingRpcsNewTaskRequest
+	result  *swarming_api.SwarmingRpcsTaskResult
+}
+
+// LocalExecutor is a TaskExecutor which runs task commands as subprocesses on
+// the local machine, on a configured set of bots. CIPD packages and named
+// caches are not installed; their directories are created empty. The state of
+// the tasks is kept in memory, so tasks which were triggered before the
+// LocalExecutor was restarted are reported as BOT_DIED.
+type LocalExecutor struct {
+	bots     []*LocalBot
+	ctx      context.Context
+	inputDir string
+	isolated map[string]*isolated.Isolated
+	mtx      sync.Mutex
+	pending  []string
+	running  map[string]string
+	tasks    map[string]*localTask
+	wg       sync.WaitGroup
+	workdir  string
+}
+
+// NewLocalExecutor returns a LocalExecutor which runs tasks in subdirectories
+// of the given working directory. Running tasks are killed when the context
+// is canceled.
+func NewLocalExecutor(ctx context.Context, workdir string, cfg *LocalConfig) (*LocalExecutor, error) {
+	if len(cfg.Bots) == 0 {
+		return nil, skerr.Fmt("at least one bot is required")
+	}
+	ids := util.StringSet{}
+	for _, b := range cfg.Bots {
+		if b.Id == "" {
+			return nil, skerr.Fmt("bots must have an ID")
+		}
+		if ids[b.Id] {
+			return nil, skerr.Fmt("duplicate bot ID %q", b.Id)
+		}
+		ids[b.Id] = true
+	}
+	absWorkdir, err := filepath.Abs(workdir)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	if err := os.MkdirAll(absWorkdir, os.ModePerm); err != nil {
+		return nil, skerr.Wrapf(err, "failed to create working directory")
+	}
+	inputDir := cfg.InputDir
+	if inputDir != "" {
+		inputDir, err = filepath.Abs(inputDir)
+		if err != nil {
+			return nil, skerr.Wrap(err)
+		}
+	}
+	return &LocalExecutor{
+		bots:     cfg.Bots,
+		ctx:      ctx,
+		inputDir: inputDir,
+		isolated: map[string]*isolated.Isolated{},
+		running:  map[string]string{},
+		tasks:    map[string]*localTask{},
+		workdir:  absWorkdir,
+	}, nil
+}
+
+// See documentation for TaskExecutor interface.
+func (e *LocalExecutor) ListFreeBots(pool string) ([]*swarming_api.SwarmingRpcsBotInfo, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	rv := []*swarming_api.SwarmingRpcsBotInfo{}
+	for _, b := range e.bots {
+		if !util.In(pool, b.Dimensions[swarming.DIMENSION_POOL_KEY]) {
+			continue
+		}
+		if e.running[b.Id] != "" {
+			continue
+		}
+		rv = append(rv, &swarming_api.SwarmingRpcsBotInfo{
+			BotId:      b.Id,
+			Dimensions: botDimensions(b),
+		})
+	}
+	return rv, nil
+}
+
+// See documentation for TaskExecutor interface.
+func (e *LocalExecutor) ListPendingTasks(pool string) ([]*swarming_api.SwarmingRpcsTaskResult, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.expirePending(time.Now())
+	rv := []*swarming_api.SwarmingRpcsTaskResult{}
+	for _, id := range e.pending {
+		t := e.tasks[id]
+		for _, dim := range t.req.TaskSlices[0].Properties.Dimensions {
+			if dim.Key == swarming.DIMENSION_POOL_KEY && dim.Value == pool {
+				rv = append(rv, copyResult(t.result))
+				break
+			}
+		}
+	}
+	return rv, nil
+}
+
+// See documentation for TaskExecutor interface.
+func (e *LocalExecutor) IsolateServer() string {
+	return LOCAL_ISOLATE_SERVER
+}
+
+// See documentation for TaskExecutor interface.
+func (e *LocalExecutor) UploadIsolatedFiles(_ context.Context, isolatedFiles []*isolated.Isolated) ([]string, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	rv := make([]string, 0, len(isolatedFiles))
+	for _, f := range isolatedFiles {
+		b, err := json.Marshal(f)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "failed to encode isolated file")
+		}
+		hash := fmt.Sprintf("%x", sha1.Sum(b))
+		e.isolated[hash] = f
+		rv = append(rv, hash)
+	}
+	return rv, nil
+}
+
+// See documentation for TaskExecutor interface.
+func (e *LocalExecutor) TriggerTask(req *swarming_api.SwarmingRpcsNewTaskRequest) (*swarming_api.SwarmingRpcsTaskRequestMetadata, error) {
+	if len(req.TaskSlices) != 1 || req.TaskSlices[0].Properties == nil {
+		return nil, skerr.Fmt("expected exactly one task slice with properties")
+	}
+	slice := req.TaskSlices[0]
+	if slice.Properties.InputsRef != nil && slice.Properties.InputsRef.Isolated != "" && slice.Properties.InputsRef.Isolatedserver != LOCAL_ISOLATE_SERVER {
+		return nil, skerr.Fmt("inputs must be uploaded to the local executor, not %q", slice.Properties.InputsRef.Isolatedserver)
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	now := time.Now()
+	// Task IDs must be unique across restarts of the LocalExecutor, since
+	// the task scheduler persists them.
+	id := localTaskIdPrefix + uuid.New().String()
+	t := &localTask{
+		req: req,
+		result: &swarming_api.SwarmingRpcsTaskResult{
+			CreatedTs: formatTimestamp(now),
+			Name:      req.Name,
+			State:     swarming.TASK_STATE_PENDING,
+			Tags:      util.CopyStringSlice(req.Tags),
+			TaskId:    id,
+		},
+	}
+	if slice.ExpirationSecs > 0 {
+		t.expires = now.Add(time.Duration(slice.ExpirationSecs) * time.Second)
+	}
+	// Clear anything left over in the task's directory, and record the
+	// task so that it can be reported after a restart.
+	taskDir := filepath.Join(e.workdir, id)
+	if err := os.RemoveAll(taskDir); err != nil {
+		return nil, skerr.Wrapf(err, "failed to clear task dir")
+	}
+	if err := os.MkdirAll(taskDir, os.ModePerm); err != nil {
+		return nil, skerr.Wrapf(err, "failed to create task dir")
+	}
+	if err := e.writeResult(id, t.result); err != nil {
+		return nil, err
+	}
+	e.tasks[id] = t
+
+	// Reject the task if no bot could ever run it, as Swarming does.
+	canRun := false
+	for _, b := range e.bots {
+		if botMatches(b, slice.Properties.Dimensions) {
+			canRun = true
+			break
+		}
+	}
+	if canRun {
+		e.wg.Add(1)
+		e.pending = append(e.pending, id)
+		e.schedule(now)
+	} else {
+		t.result.State = swarming.TASK_STATE_NO_RESOURCE
+		t.result.AbandonedTs = t.result.CreatedTs
+	}
+	return &swarming_api.SwarmingRpcsTaskRequestMetadata{
+		Request: &swarming_api.SwarmingRpcsTaskRequest{
+			CreatedTs: t.result.CreatedTs,
+			Name:      req.Name,
+			Tags:      util.CopyStringSlice(req.Tags),
+		},
+		TaskId:     id,
+		TaskResult: copyResult(t.result),
+	}, nil
+}
+
+// See documentation for TaskExecutor interface.
+func (e *LocalExecutor) GetStates(ids []string) ([]string, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.expirePending(time.Now())
+	rv := make([]string, 0, len(ids))
+	for _, id := range ids {
+		rv = append(rv, e.getTask(id).result.State)
+	}
+	return rv, nil
+}
+
+// See documentation for TaskExecutor interface.
+func (e *LocalExecutor) GetTask(id string) (*swarming_api.SwarmingRpcsTaskResult, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.expirePending(time.Now())
+	return copyResult(e.getTask(id).result), nil
+}
+
+// getTask returns the task with the given ID. Tasks which are not known, eg.
+// because they were triggered before the LocalExecutor was restarted, are
+// loaded from the result written to the task's directory, if any. Tasks which
+// had not finished, or which have no result, are reported as BOT_DIED.
+// Assumes that the caller holds e.mtx.
+func (e *LocalExecutor) getTask(id string) *localTask {
+	if t, ok := e.tasks[id]; ok {
+		return t
+	}
+	result := &swarming_api.SwarmingRpcsTaskResult{
+		TaskId: id,
+	}
+	if strings.HasPrefix(id, localTaskIdPrefix) && filepath.Base(id) == id {
+		if err := util.WithReadFile(filepath.Join(e.workdir, id, localResultFile), func(f io.Reader) error {
+			return json.NewDecoder(f).Decode(result)
+		}); err != nil && !os.IsNotExist(err) {
+			sklog.Errorf("Failed to read result of local task %s: %s", id, err)
+		}
+	}
+	if result.State == "" || result.State == swarming.TASK_STATE_PENDING || result.State == swarming.TASK_STATE_RUNNING {
+		result.State = swarming.TASK_STATE_BOT_DIED
+		result.AbandonedTs = formatTimestamp(time.Now())
+	}
+	t := &localTask{result: result}
+	e.tasks[id] = t
+	return t
+}
+
+// writeResult writes the given result of a task into the task's directory.
+func (e *LocalExecutor) writeResult(id string, result *swarming_api.SwarmingRpcsTaskResult) error {
+	if err := util.WithWriteFile(filepath.Join(e.workdir, id, localResultFile), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(result)
+	}); err != nil {
+		return skerr.Wrapf(err, "failed to write result of task %s", id)
+	}
+	return nil
+}
+
+// Wait waits for all pending and running tasks to finish.
+func (e *LocalExecutor) Wait() {
+	e.wg.Wait()
+}
+
+// expirePending marks pending tasks whose expiration has passed as expired.
+// Assumes that the caller holds e.mtx.
+func (e *LocalExecutor) expirePending(now time.Time) {
+	stillPending := make([]string, 0, len(e.pending))
+	for _, id := range e.pending {
+		t := e.tasks[id]
+		if !util.TimeIsZero(t.expires) && now.After(t.expires) {
+			t.result.State = swarming.TASK_STATE_EXPIRED
+			t.result.AbandonedTs = formatTimestamp(now)
+			e.wg.Done()
+		} else {
+			stillPending = append(stillPending, id)
+		}
+	}
+	e.pending = stillPending
+}
+
+// schedule starts pending tasks, in the order in which they were triggered,
+// on free bots with matching dimensions. Assumes that the caller holds e.mtx.
+func (e *LocalExecutor) schedule(now time.Time) {
+	e.expirePending(now)
+	stillPending := make([]string, 0, len(e.pending))
+	for _, id := range e.pending {
+		t := e.tasks[id]
+		props := t.req.TaskSlices[0].Properties
+		var bot *LocalBot
+		for _, b := range e.bots {
+			if e.running[b.Id] == "" && botMatches(b, props.Dimensions) {
+				bot = b
+				break
+			}
+		}
+		if bot == nil {
+			stillPending = append(stillPending, id)
+			continue
+		}
+		e.running[bot.Id] = id
+		t.result.BotDimensions = botDimensions(bot)
+		t.result.BotId = bot.Id
+		t.result.StartedTs = formatTimestamp(now)
+		t.result.State = swarming.TASK_STATE_RUNNING
+		var inputs *isolated.Isolated
+		if props.InputsRef != nil && props.InputsRef.Isolated != "" {
+			inputs = e.isolated[props.InputsRef.Isolated]
+		}
+		go e.runTask(id, bot.Id, props, inputs)
+	}
+	e.pending = stillPending
+}
+
+// localResult is the outcome of running a task on the LocalExecutor.
+type localResult struct {
+	exitCode   int64
+	failure    bool
+	hasOutputs bool
+	state      string
+}
+
+// runTask runs the given task on the given bot, records its result and starts
+// the next pending tasks.
+func (e *LocalExecutor) runTask(id, botId string, props *swarming_api.SwarmingRpcsTaskProperties, inputs *isolated.Isolated) {
+	defer e.wg.Done()
+	res := e.execute(id, botId, props, inputs)
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	now := time.Now()
+	t := e.tasks[id]
+	t.result.ExitCode = res.exitCode
+	t.result.Failure = res.failure
+	t.result.State = res.state
+	if res.state == swarming.TASK_STATE_COMPLETED || res.state == swarming.TASK_STATE_TIMED_OUT {
+		t.result.CompletedTs = formatTimestamp(now)
+	} else {
+		t.result.AbandonedTs = formatTimestamp(now)
+	}
+	if started, err := swarming.ParseTimestamp(t.result.StartedTs); err == nil {
+		t.result.Duration = now.Sub(started).Seconds()
+	}
+	if res.hasOutputs {
+		t.result.OutputsRef = &swarming_api.SwarmingRpcsFilesRef{
+			Isolated:       id,
+			Isolatedserver: LOCAL_ISOLATE_SERVER,
+			Namespace:      isolate.DEFAULT_NAMESPACE,
+		}
+	}
+	if err := e.writeResult(id, t.result); err != nil {
+		sklog.Errorf("Failed to record result of local task: %s", err)
+	}
+	delete(e.running, botId)
+	e.schedule(now)
+}
+
+// execute runs the command of the given task and waits for it to finish,
+// enforcing the execution and IO timeouts of the task.
+func (e *LocalExecutor) execute(id, botId string, props *swarming_api.SwarmingRpcsTaskProperties, inputs *isolated.Isolated) localResult {
+	taskDir := filepath.Join(e.workdir, id)
+	outDir := filepath.Join(taskDir, localOutDir)
+	cwd, err := e.prepare(taskDir, props, inputs)
+	if err != nil {
+		sklog.Errorf("Failed to set up local task %s: %s", id, err)
+		return localResult{exitCode: -1, failure: true, state: swarming.TASK_STATE_BOT_DIED}
+	}
+	hasOutputs := func() bool {
+		files, err := ioutil.ReadDir(outDir)
+		return err == nil && len(files) > 0
+	}
+
+	args := make([]string, 0, len(props.Command)+len(props.ExtraArgs))
+	for _, arg := range props.Command {
+		args = append(args, strings.Replace(arg, ISOLATED_OUTDIR, outDir, -1))
+	}
+	for _, arg := range props.ExtraArgs {
+		args = append(args, strings.Replace(arg, ISOLATED_OUTDIR, outDir, -1))
+	}
+	if len(args) == 0 {
+		sklog.Errorf("Local task %s has no command.", id)
+		return localResult{exitCode: -1, failure: true, state: swarming.TASK_STATE_COMPLETED}
+	}
+
+	logFile, err := os.Create(filepath.Join(taskDir, localLogFile))
+	if err != nil {
+		sklog.Errorf("Failed to create log file for local task %s: %s", id, err)
+		return localResult{exitCode: -1, failure: true, state: swarming.TASK_STATE_BOT_DIED}
+	}
+	defer util.Close(logFile)
+
+	// The command writes directly to the log file, rather than through a
+	// pipe, so that subprocesses which outlive it don't prevent Wait()
+	// from returning.
+	cmd := osexec.Command(args[0], args[1:]...)
+	cmd.Dir = cwd
+	cmd.Env = makeEnv(id, botId, filepath.Join(taskDir, localRunDir), outDir, props)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	setProcessGroup(cmd)
+	sklog.Infof("Running local task %s on %s: %s", id, botId, strings.Join(args, " "))
+	if err := cmd.Start(); err != nil {
+		_, _ = fmt.Fprintf(logFile, "Failed to start command: %s\n", err)
+		return localResult{exitCode: -1, failure: true, state: swarming.TASK_STATE_COMPLETED}
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var executionTimeout <-chan time.Time
+	if props.ExecutionTimeoutSecs > 0 {
+		timer := time.NewTimer(time.Duration(props.ExecutionTimeoutSecs) * time.Second)
+		defer timer.Stop()
+		executionTimeout = timer.C
+	}
+	ioTimeout := time.Duration(props.IoTimeoutSecs) * time.Second
+	ticker := time.NewTicker(localIoPollPeriod)
+	defer ticker.Stop()
+	lastActivity := time.Now()
+	var lastSize int64
+	for {
+		var state string
+		select {
+		case err := <-done:
+			rv := localResult{hasOutputs: hasOutputs(), state: swarming.TASK_STATE_COMPLETED}
+			if err != nil {
+				rv.failure = true
+				rv.exitCode = -1
+				if exitErr, ok := err.(*osexec.ExitError); ok {
+					rv.exitCode = int64(exitErr.ExitCode())
+				}
+			}
+			return rv
+		case <-executionTimeout:
+			state = swarming.TASK_STATE_TIMED_OUT
+		case <-e.ctx.Done():
+			state = swarming.TASK_STATE_KILLED
+		case now := <-ticker.C:
+			if ioTimeout == 0 {
+				continue
+			}
+			if fi, err := logFile.Stat(); err == nil && fi.Size() != lastSize {
+				lastSize = fi.Size()
+				lastActivity = now
+			} else if now.Sub(lastActivity) >= ioTimeout {
+				state = swarming.TASK_STATE_TIMED_OUT
+			}
+		}
+		if state != "" {
+			sklog.Warningf("Killing local task %s: %s", id, state)
+			killProcessGroup(cmd)
+			<-done
+			return localResult{exitCode: -1, failure: true, hasOutputs: hasOutputs(), state: state}
+		}
+	}
+}
+
+// prepare creates the run and output directories of a task, populates the
+// run directory with its inputs and returns the directory in which its command
+// should run.
+func (e *LocalExecutor) prepare(taskDir string, props *swarming_api.SwarmingRpcsTaskProperties, inputs *isolated.Isolated) (string, error) {
+	runDir := filepath.Join(taskDir, localRunDir)
+	for _, dir := range []string{runDir, filepath.Join(taskDir, localOutDir)} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return "", skerr.Wrap(err)
+		}
+	}
+	cwd := runDir
+	if inputs != nil {
+		// The outputs of the task's dependencies are included by their
+		// task IDs; see runTask.
+		for _, inc := range inputs.Includes {
+			src := filepath.Join(e.workdir, string(inc), localOutDir)
+			if _, err := os.Stat(src); os.IsNotExist(err) {
+				sklog.Warningf("Skipping isolated input %s which is not the output of a local task.", inc)
+				continue
+			}
+			if err := copyTree(src, runDir); err != nil {
+				return "", skerr.Wrapf(err, "failed to copy outputs of %s", inc)
+			}
+		}
+		cwd = filepath.Join(runDir, inputs.RelativeCwd)
+	}
+	if e.inputDir != "" {
+		entries, err := ioutil.ReadDir(e.inputDir)
+		if err != nil {
+			return "", skerr.Wrapf(err, "failed to read input dir")
+		}
+		for _, entry := range entries {
+			dst := filepath.Join(runDir, entry.Name())
+			if _, err := os.Lstat(dst); err == nil {
+				continue
+			}
+			if err := os.Symlink(filepath.Join(e.inputDir, entry.Name()), dst); err != nil {
+				return "", skerr.Wrap(err)
+			}
+		}
+	}
+	// CIPD packages and caches are not installed, but their directories
+	// are created so that tasks which expect them to exist can run.
+	dirs := []string{cwd}
+	for _, c := range props.Caches {
+		dirs = append(dirs, filepath.Join(runDir, c.Path))
+	}
+	if props.CipdInput != nil {
+		for _, p := range props.CipdInput.Packages {
+			dirs = append(dirs, filepath.Join(runDir, p.Path))
+		}
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return "", skerr.Wrap(err)
+		}
+	}
+	return cwd, nil
+}
+
+// makeEnv returns the environment for the command of a task, which is the
+// environment of the current process plus the variables of the task.
+func makeEnv(id, botId, runDir, outDir string, props *swarming_api.SwarmingRpcsTaskProperties) []string {
+	vars := map[string]string{
+		"SWARMING_BOT_ID":  botId,
+		"SWARMING_TASK_ID": id,
+	}
+	for _, v := range props.Env {
+		vars[v.Key] = strings.Replace(v.Value, ISOLATED_OUTDIR, outDir, -1)
+	}
+	for _, p := range props.EnvPrefixes {
+		paths := make([]string, 0, len(p.Value)+1)
+		for _, v := range p.Value {
+			paths = append(paths, filepath.Join(runDir, v))
+		}
+		existing, ok := vars[p.Key]
+		if !ok {
+			existing = os.Getenv(p.Key)
+		}
+		if existing != "" {
+			paths = append(paths, existing)
+		}
+		vars[p.Key] = strings.Join(paths, string(os.PathListSeparator))
+	}
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	env := os.Environ()
+	for _, k := range keys {
+		env = append(env, fmt.Sprintf("%s=%s", k, vars[k]))
+	}
+	return env
+}
+
+// copyTree copies the contents of the src directory into the dst directory.
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode())
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		if err := util.CopyFile(path, target); err != nil {
+			return err
+		}
+		return os.Chmod(target, info.Mode())
+	})
+}
+
+// botMatches returns true if the bot has all of the given dimensions.
+func botMatches(b *LocalBot, dims []*swarming_api.SwarmingRpcsStringPair) bool {
+	for _, d := range dims {
+		if !util.In(d.Value, b.Dimensions[d.Key]) {
+			return false
+		}
+	}
+	return true
+}
+
+// botDimensions returns the dimensions of the bot in the form used by the
+// Swarming API, sorted by key.
+func botDimensions(b *LocalBot) []*swarming_api.SwarmingRpcsStringListPair {
+	rv := make([]*swarming_api.SwarmingRpcsStringListPair, 0, len(b.Dimensions))
+	for k, v := range b.Dimensions {
+		rv = append(rv, &swarming_api.SwarmingRpcsStringListPair{
+			Key:   k,
+			Value: util.CopyStringSlice(v),
+		})
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].Key < rv[j].Key
+	})
+	return rv
+}
+
+// copyResult returns a copy of the given task result which is safe to hand to
+// callers of the LocalExecutor.
+func copyResult(r *swarming_api.SwarmingRpcsTaskResult) *swarming_api.SwarmingRpcsTaskResult {
+	rv := new(swarming_api.SwarmingRpcsTaskResult)
+	*rv = *r
+	rv.Tags = util.CopyStringSlice(r.Tags)
+	if r.OutputsRef != nil {
+		ref := *r.OutputsRef
+		rv.OutputsRef = &ref
+	}
+	return rv
+}
+
+// formatTimestamp formats the given time the way the Swarming API does.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(swarming.TIMESTAMP_FORMAT)
+}
+
+// Assert that LocalExecutor implements TaskExecutor.
+var _ TaskExecutor = &LocalExecutor{}
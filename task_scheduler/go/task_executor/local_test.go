@@ -0,0 +1,321 @@
+package task_executor
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	swarming_api "go.chromium.org/luci/common/api/swarming/swarming/v1"
+	"go.chromium.org/luci/common/isolated"
+	"go.skia.org/infra/go/swarming"
+	"go.skia.org/infra/go/testutils"
+	"go.skia.org/infra/go/testutils/unittest"
+)
+
+func setupLocal(t *testing.T) (*LocalExecutor, string, func()) {
+	wd, cleanup := testutils.TempDir(t)
+	e, err := NewLocalExecutor(context.Background(), filepath.Join(wd, "tasks"), &LocalConfig{
+		Bots: []*LocalBot{
+			{
+				Id: "linux-bot",
+				Dimensions: map[string][]string{
+					"pool": {"Skia"},
+					"os":   {"Linux", "Debian"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	return e, wd, func() {
+		e.Wait()
+		cleanup()
+	}
+}
+
+func makeRequest(name string, props *swarming_api.SwarmingRpcsTaskProperties) *swarming_api.SwarmingRpcsNewTaskRequest {
+	if props.Dimensions == nil {
+		props.Dimensions = []*swarming_api.SwarmingRpcsStringPair{
+			{Key: "pool", Value: "Skia"},
+			{Key: "os", Value: "Debian"},
+		}
+	}
+	return &swarming_api.SwarmingRpcsNewTaskRequest{
+		Name: name,
+		Tags: []string{"name:" + name},
+		TaskSlices: []*swarming_api.SwarmingRpcsTaskSlice{
+			{
+				ExpirationSecs: 3600,
+				Properties:     props,
+			},
+		},
+	}
+}
+
+func trigger(t *testing.T, e *LocalExecutor, name string, props *swarming_api.SwarmingRpcsTaskProperties) string {
+	resp, err := e.TriggerTask(makeRequest(name, props))
+	require.NoError(t, err)
+	require.NotEqual(t, swarming.TASK_STATE_NO_RESOURCE, resp.TaskResult.State)
+	return resp.TaskId
+}
+
+func TestLocalExecutor_Success(t *testing.T) {
+	unittest.MediumTest(t)
+	e, _, cleanup := setupLocal(t)
+	defer cleanup()
+
+	id := trigger(t, e, "Build", &swarming_api.SwarmingRpcsTaskProperties{
+		Command:   []string{"sh", "-c", "echo $GREETING > ${ISOLATED_OUTDIR}/out.txt"},
+		ExtraArgs: []string{"ignored"},
+		Env:       []*swarming_api.SwarmingRpcsStringPair{{Key: "GREETING", Value: "hello"}},
+		Caches:    []*swarming_api.SwarmingRpcsCacheEntry{{Name: "git", Path: "cache/git"}},
+	})
+	e.Wait()
+
+	res, err := e.GetTask(id)
+	require.NoError(t, err)
+	assert.Equal(t, swarming.TASK_STATE_COMPLETED, res.State)
+	assert.False(t, res.Failure)
+	assert.Equal(t, "linux-bot", res.BotId)
+	assert.Equal(t, []string{"name:Build"}, res.Tags)
+	assert.NotEqual(t, "", res.StartedTs)
+	assert.NotEqual(t, "", res.CompletedTs)
+	require.NotNil(t, res.OutputsRef)
+	assert.Equal(t, id, res.OutputsRef.Isolated)
+
+	b, err := ioutil.ReadFile(filepath.Join(e.workdir, id, localOutDir, "out.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(b))
+	// The cache directory is created, but empty.
+	_, err = os.Stat(filepath.Join(e.workdir, id, localRunDir, "cache", "git"))
+	assert.NoError(t, err)
+
+	states, err := e.GetStates([]string{id})
+	require.NoError(t, err)
+	assert.Equal(t, []string{swarming.TASK_STATE_COMPLETED}, states)
+}
+
+func TestLocalExecutor_Failure(t *testing.T) {
+	unittest.MediumTest(t)
+	e, _, cleanup := setupLocal(t)
+	defer cleanup()
+
+	id := trigger(t, e, "Test", &swarming_api.SwarmingRpcsTaskProperties{
+		Command: []string{"sh", "-c", "exit 3"},
+	})
+	e.Wait()
+
+	res, err := e.GetTask(id)
+	require.NoError(t, err)
+	assert.Equal(t, swarming.TASK_STATE_COMPLETED, res.State)
+	assert.True(t, res.Failure)
+	assert.Equal(t, int64(3), res.ExitCode)
+	assert.Nil(t, res.OutputsRef)
+}
+
+func TestLocalExecutor_NoMatchingBot(t *testing.T) {
+	unittest.SmallTest(t)
+	e, _, cleanup := setupLocal(t)
+	defer cleanup()
+
+	resp, err := e.TriggerTask(makeRequest("Test-Win", &swarming_api.SwarmingRpcsTaskProperties{
+		Command: []string{"true"},
+		Dimensions: []*swarming_api.SwarmingRpcsStringPair{
+			{Key: "pool", Value: "Skia"},
+			{Key: "os", Value: "Windows"},
+		},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, swarming.TASK_STATE_NO_RESOURCE, resp.TaskResult.State)
+	assert.NotEqual(t, "", resp.Request.CreatedTs)
+}
+
+func TestLocalExecutor_OneTaskPerBot(t *testing.T) {
+	unittest.MediumTest(t)
+	e, wd, cleanup := setupLocal(t)
+	defer cleanup()
+
+	// The first task runs until the marker file exists.
+	marker := filepath.Join(wd, "marker")
+	first := trigger(t, e, "First", &swarming_api.SwarmingRpcsTaskProperties{
+		Command: []string{"sh", "-c", "while [ ! -e " + marker + " ]; do sleep 0.05; done"},
+	})
+	second := trigger(t, e, "Second", &swarming_api.SwarmingRpcsTaskProperties{
+		Command: []string{"true"},
+	})
+
+	states, err := e.GetStates([]string{first, second})
+	require.NoError(t, err)
+	assert.Equal(t, []string{swarming.TASK_STATE_RUNNING, swarming.TASK_STATE_PENDING}, states)
+	bots, err := e.ListFreeBots("Skia")
+	require.NoError(t, err)
+	assert.Empty(t, bots)
+	pending, err := e.ListPendingTasks("Skia")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, second, pending[0].TaskId)
+	pending, err = e.ListPendingTasks("OtherPool")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	require.NoError(t, ioutil.WriteFile(marker, []byte{}, os.ModePerm))
+	e.Wait()
+	states, err = e.GetStates([]string{first, second})
+	require.NoError(t, err)
+	assert.Equal(t, []string{swarming.TASK_STATE_COMPLETED, swarming.TASK_STATE_COMPLETED}, states)
+	bots, err = e.ListFreeBots("Skia")
+	require.NoError(t, err)
+	require.Len(t, bots, 1)
+	assert.Equal(t, "linux-bot", bots[0].BotId)
+	assert.Equal(t, []*swarming_api.SwarmingRpcsStringListPair{
+		{Key: "os", Value: []string{"Linux", "Debian"}},
+		{Key: "pool", Value: []string{"Skia"}},
+	}, bots[0].Dimensions)
+}
+
+func TestLocalExecutor_Restart(t *testing.T) {
+	unittest.MediumTest(t)
+	e, wd, cleanup := setupLocal(t)
+	defer cleanup()
+
+	marker := filepath.Join(wd, "marker")
+	done := trigger(t, e, "Done", &swarming_api.SwarmingRpcsTaskProperties{
+		Command: []string{"true"},
+	})
+	e.Wait()
+	// This task runs until the marker file exists, so the next one stays
+	// pending.
+	running := trigger(t, e, "Running", &swarming_api.SwarmingRpcsTaskProperties{
+		Command: []string{"sh", "-c", "while [ ! -e " + marker + " ]; do sleep 0.05; done"},
+	})
+	pending, err := e.GetTask(trigger(t, e, "Pending", &swarming_api.SwarmingRpcsTaskProperties{
+		Command: []string{"true"},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, swarming.TASK_STATE_PENDING, pending.State)
+	defer func() {
+		require.NoError(t, ioutil.WriteFile(marker, []byte{}, os.ModePerm))
+	}()
+
+	// A new LocalExecutor using the same working directory knows nothing
+	// about the running tasks.
+	restarted, err := NewLocalExecutor(context.Background(), filepath.Join(wd, "tasks"), &LocalConfig{
+		Bots: e.bots,
+	})
+	require.NoError(t, err)
+	states, err := restarted.GetStates([]string{done, running, pending.TaskId, "local-unknown"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		swarming.TASK_STATE_COMPLETED,
+		swarming.TASK_STATE_BOT_DIED,
+		swarming.TASK_STATE_BOT_DIED,
+		swarming.TASK_STATE_BOT_DIED,
+	}, states)
+
+	// The task can still be matched to its request.
+	res, err := restarted.GetTask(pending.TaskId)
+	require.NoError(t, err)
+	assert.Equal(t, pending.TaskId, res.TaskId)
+	assert.Equal(t, pending.CreatedTs, res.CreatedTs)
+	assert.Equal(t, []string{"name:Pending"}, res.Tags)
+	assert.NotEqual(t, "", res.AbandonedTs)
+	res, err = restarted.GetTask("local-unknown")
+	require.NoError(t, err)
+	assert.Equal(t, "local-unknown", res.TaskId)
+	assert.Equal(t, swarming.TASK_STATE_BOT_DIED, res.State)
+
+	// Task IDs are not reused.
+	id := trigger(t, restarted, "New", &swarming_api.SwarmingRpcsTaskProperties{
+		Command: []string{"true"},
+	})
+	restarted.Wait()
+	assert.NotContains(t, []string{done, running, pending.TaskId}, id)
+}
+
+func TestLocalExecutor_Timeouts(t *testing.T) {
+	unittest.MediumTest(t)
+	e, _, cleanup := setupLocal(t)
+	defer cleanup()
+
+	// This task keeps writing output, so only the execution timeout applies.
+	execution := trigger(t, e, "Execution", &swarming_api.SwarmingRpcsTaskProperties{
+		Command:              []string{"sh", "-c", "while true; do echo waiting; sleep 0.1; done"},
+		ExecutionTimeoutSecs: 1,
+	})
+	io := trigger(t, e, "IO", &swarming_api.SwarmingRpcsTaskProperties{
+		Command:       []string{"sleep", "600"},
+		IoTimeoutSecs: 1,
+	})
+	e.Wait()
+
+	for _, id := range []string{execution, io} {
+		res, err := e.GetTask(id)
+		require.NoError(t, err)
+		assert.Equal(t, swarming.TASK_STATE_TIMED_OUT, res.State, id)
+		assert.True(t, res.Failure)
+		assert.NotEqual(t, "", res.CompletedTs)
+	}
+}
+
+func TestLocalExecutor_DependencyOutputs(t *testing.T) {
+	unittest.MediumTest(t)
+	e, _, cleanup := setupLocal(t)
+	defer cleanup()
+
+	compile := trigger(t, e, "Compile", &swarming_api.SwarmingRpcsTaskProperties{
+		Command: []string{"sh", "-c", "mkdir ${ISOLATED_OUTDIR}/out && echo binary > ${ISOLATED_OUTDIR}/out/dm"},
+	})
+	e.Wait()
+
+	ctx := context.Background()
+	hashes, err := e.UploadIsolatedFiles(ctx, []*isolated.Isolated{
+		{
+			Includes:    []isolated.HexDigest{isolated.HexDigest(compile)},
+			RelativeCwd: "out",
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, hashes, 1)
+	test := trigger(t, e, "Test", &swarming_api.SwarmingRpcsTaskProperties{
+		Command: []string{"cp", "dm", "${ISOLATED_OUTDIR}/copied"},
+		InputsRef: &swarming_api.SwarmingRpcsFilesRef{
+			Isolated:       hashes[0],
+			Isolatedserver: e.IsolateServer(),
+		},
+	})
+	e.Wait()
+
+	res, err := e.GetTask(test)
+	require.NoError(t, err)
+	assert.Equal(t, swarming.TASK_STATE_COMPLETED, res.State)
+	assert.False(t, res.Failure)
+	b, err := ioutil.ReadFile(filepath.Join(e.workdir, test, localOutDir, "copied"))
+	require.NoError(t, err)
+	assert.Equal(t, "binary\n", string(b))
+
+	// Inputs from another Isolate server can't be used.
+	_, err = e.TriggerTask(makeRequest("Remote", &swarming_api.SwarmingRpcsTaskProperties{
+		Command: []string{"true"},
+		InputsRef: &swarming_api.SwarmingRpcsFilesRef{
+			Isolated:       hashes[0],
+			Isolatedserver: "https://isolateserver.appspot.com",
+		},
+	}))
+	require.Error(t, err)
+}
+
+func TestNewLocalExecutor_InvalidConfig(t *testing.T) {
+	unittest.SmallTest(t)
+	wd, cleanup := testutils.TempDir(t)
+	defer cleanup()
+
+	_, err := NewLocalExecutor(context.Background(), wd, &LocalConfig{})
+	assert.Error(t, err)
+	_, err = NewLocalExecutor(context.Background(), wd, &LocalConfig{
+		Bots: []*LocalBot{{Id: "a"}, {Id: "a"}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,25 @@
+// +build !windows
+
+package task_executor
+
+import (
+	osexec "os/exec"
+	"syscall"
+
+	"go.skia.org/infra/go/sklog"
+)
+
+// setProcessGroup runs the command in its own process group, so that its
+// subprocesses can be killed along with it.
+func setProcessGroup(cmd *osexec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+}
+
+// killProcessGroup kills the started command and all of its subprocesses.
+func killProcessGroup(cmd *osexec.Cmd) {
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		sklog.Errorf("Failed to kill process group %d: %s", cmd.Process.Pid, err)
+	}
+}
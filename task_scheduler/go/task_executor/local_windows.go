@@ -0,0 +1,18 @@
+package task_executor
+
+import (
+	osexec "os/exec"
+
+	"go.skia.org/infra/go/sklog"
+)
+
+// setProcessGroup is a no-op on Windows.
+func setProcessGroup(cmd *osexec.Cmd) {}
+
+// killProcessGroup kills the started command. Its subprocesses are not killed
+// on Windows.
+func killProcessGroup(cmd *osexec.Cmd) {
+	if err := cmd.Process.Kill(); err != nil {
+		sklog.Errorf("Failed to kill process %d: %s", cmd.Process.Pid, err)
+	}
+}
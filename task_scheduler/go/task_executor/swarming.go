@@ -0,0 +1,66 @@
+package task_executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	swarming_api "go.chromium.org/luci/common/api/swarming/swarming/v1"
+	"go.chromium.org/luci/common/isolated"
+	"go.skia.org/infra/go/isolate"
+	"go.skia.org/infra/go/swarming"
+)
+
+// swarmingExecutor is a TaskExecutor which runs tasks on Swarming, using
+// inputs from an Isolate server.
+type swarmingExecutor struct {
+	isolate  *isolate.Client
+	swarming swarming.ApiClient
+}
+
+// NewSwarmingExecutor returns a TaskExecutor which runs tasks on Swarming.
+func NewSwarmingExecutor(s swarming.ApiClient, i *isolate.Client) TaskExecutor {
+	return &swarmingExecutor{
+		isolate:  i,
+		swarming: s,
+	}
+}
+
+// See documentation for TaskExecutor interface.
+func (e *swarmingExecutor) ListFreeBots(pool string) ([]*swarming_api.SwarmingRpcsBotInfo, error) {
+	return e.swarming.ListFreeBots(pool)
+}
+
+// See documentation for TaskExecutor interface.
+func (e *swarmingExecutor) ListPendingTasks(pool string) ([]*swarming_api.SwarmingRpcsTaskResult, error) {
+	t := time.Time{}
+	return e.swarming.ListTaskResults(t, t, []string{fmt.Sprintf("%s:%s", swarming.DIMENSION_POOL_KEY, pool)}, swarming.TASK_STATE_PENDING, false)
+}
+
+// See documentation for TaskExecutor interface.
+func (e *swarmingExecutor) IsolateServer() string {
+	return e.isolate.ServerURL()
+}
+
+// See documentation for TaskExecutor interface.
+func (e *swarmingExecutor) UploadIsolatedFiles(ctx context.Context, isolatedFiles []*isolated.Isolated) ([]string, error) {
+	return e.isolate.ReUploadIsolatedFiles(ctx, isolatedFiles)
+}
+
+// See documentation for TaskExecutor interface.
+func (e *swarmingExecutor) TriggerTask(req *swarming_api.SwarmingRpcsNewTaskRequest) (*swarming_api.SwarmingRpcsTaskRequestMetadata, error) {
+	return e.swarming.TriggerTask(req)
+}
+
+// See documentation for TaskExecutor interface.
+func (e *swarmingExecutor) GetStates(ids []string) ([]string, error) {
+	return e.swarming.GetStates(ids)
+}
+
+// See documentation for TaskExecutor interface.
+func (e *swarmingExecutor) GetTask(id string) (*swarming_api.SwarmingRpcsTaskResult, error) {
+	return e.swarming.GetTask(id, false)
+}
+
+// Assert that swarmingExecutor implements TaskExecutor.
+var _ TaskExecutor = &swarmingExecutor{}
@@ -0,0 +1,43 @@
+// Package task_executor contains the interface through which the Task
+// Scheduler runs tasks, along with a Swarming-backed implementation and a
+// local implementation which runs tasks as subprocesses.
+//
+// The interface uses the Swarming API types, since those are what the Task
+// Scheduler was originally written against; other implementations translate
+// their own state into those types.
+package task_executor
+
+import (
+	"context"
+
+	swarming_api "go.chromium.org/luci/common/api/swarming/swarming/v1"
+	"go.chromium.org/luci/common/isolated"
+)
+
+// TaskExecutor runs tasks on behalf of the Task Scheduler.
+type TaskExecutor interface {
+	// ListFreeBots returns the bots in the given pool which are available
+	// to run a task.
+	ListFreeBots(pool string) ([]*swarming_api.SwarmingRpcsBotInfo, error)
+
+	// ListPendingTasks returns the tasks in the given pool which have been
+	// triggered but have not yet started.
+	ListPendingTasks(pool string) ([]*swarming_api.SwarmingRpcsTaskResult, error)
+
+	// IsolateServer returns the URL of the Isolate server from which tasks
+	// obtain their inputs.
+	IsolateServer() string
+
+	// UploadIsolatedFiles uploads the given isolated files and returns
+	// their hashes, in the same order, for use as task inputs.
+	UploadIsolatedFiles(ctx context.Context, isolatedFiles []*isolated.Isolated) ([]string, error)
+
+	// TriggerTask triggers a task with the given request.
+	TriggerTask(req *swarming_api.SwarmingRpcsNewTaskRequest) (*swarming_api.SwarmingRpcsTaskRequestMetadata, error)
+
+	// GetStates returns the states of the given tasks, in the same order.
+	GetStates(ids []string) ([]string, error)
+
+	// GetTask returns the result of the given task.
+	GetTask(id string) (*swarming_api.SwarmingRpcsTaskResult, error)
+}